@@ -792,7 +792,7 @@ func main() {
 		{
 			Name:    "show",
 			Aliases: []string{"show"},
-			Usage:   "show streams | show tables | show rules | show plugins $plugin_type | show services | show service_funcs | show schemas $schema_type | show scripts",
+			Usage:   "show streams | show tables | show rules | show connections | show plugins $plugin_type | show services | show service_funcs | show schemas $schema_type | show scripts",
 
 			Subcommands: []cli.Command{
 				{
@@ -825,6 +825,64 @@ func main() {
 						return nil
 					},
 				},
+				{
+					Name:  "connections",
+					Usage: "show connections [-a] [-n namespace] [-t type] [-s status] [-l selector] [--sort key] [--limit count] [--offset count]",
+					Flags: []cli.Flag{
+						cli.BoolFlag{
+							Name:  "all, a",
+							Usage: "include the anonymous connections of rules",
+						},
+						cli.StringFlag{
+							Name:  "namespace, n",
+							Usage: "the namespace of the connections",
+						},
+						cli.StringFlag{
+							Name:  "type, t",
+							Usage: "the connection type",
+						},
+						cli.StringFlag{
+							Name:  "status, s",
+							Usage: "the last known status such as connected",
+						},
+						cli.StringFlag{
+							Name:  "selector, l",
+							Usage: "the label selector such as site=plant1,env!=dev",
+						},
+						cli.StringFlag{
+							Name:  "sort",
+							Usage: "the sort key: id, type, status, refCount or createdAt, prefix - for descending",
+						},
+						cli.IntFlag{
+							Name:  "limit",
+							Usage: "the max count of connections, 0 means all",
+						},
+						cli.IntFlag{
+							Name:  "offset",
+							Usage: "the count of connections to skip",
+						},
+					},
+					Action: func(c *cli.Context) error {
+						args := &model.ListConnectionsDesc{
+							Namespace: c.String("namespace"),
+							Typ:       c.String("type"),
+							Status:    c.String("status"),
+							Selector:  c.String("selector"),
+							Sort:      c.String("sort"),
+							ForceAll:  c.Bool("all"),
+							Limit:     c.Int("limit"),
+							Offset:    c.Int("offset"),
+						}
+						var reply string
+						err = client.Call("Server.ShowConnections", args, &reply)
+						if err != nil {
+							fmt.Println(err)
+						} else {
+							fmt.Println(reply)
+						}
+						return nil
+					},
+				},
 				{
 					Name:  "plugins",
 					Usage: "show plugins $plugin_type",
@@ -1198,7 +1256,7 @@ func main() {
 		{
 			Name:    "import",
 			Aliases: []string{"import"},
-			Usage:   "import ruleset | data -f file -p partial -s stop",
+			Usage:   "import ruleset | data | store -f file -p partial -s stop",
 			Subcommands: []cli.Command{
 				{
 					Name:  "ruleset",
@@ -1276,12 +1334,55 @@ func main() {
 						return nil
 					},
 				},
+				{
+					Name:  "store",
+					Usage: "\"import store -f backup_file [-p prefixes] [-r replace]",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "file, f",
+							Usage: "the location of the config store backup file",
+						},
+						cli.StringFlag{
+							Name:  "prefixes, p",
+							Usage: "the comma separated key prefixes to restore, such as connections,sources",
+						},
+						cli.StringFlag{
+							Name:  "replace, r",
+							Usage: "delete the keys not in the backup to restore to the backup state",
+						},
+					},
+					Action: func(c *cli.Context) error {
+						sfile := c.String("file")
+						if sfile == "" {
+							fmt.Print("Required config store backup file to import")
+							return nil
+						}
+						r := c.String("replace")
+						if r != "" && r != "true" && r != "false" {
+							fmt.Printf("Expect r flag to be a boolean value.\n")
+							return nil
+						}
+						args := &model.RestoreStoreDesc{
+							FileName: sfile,
+							Prefixes: splitPrefixes(c.String("prefixes")),
+							Replace:  r == "true",
+						}
+						var reply string
+						err = client.Call("Server.RestoreConfigStore", args, &reply)
+						if err != nil {
+							fmt.Println(err)
+						} else {
+							fmt.Println(reply)
+						}
+						return nil
+					},
+				},
 			},
 		},
 		{
 			Name:    "export",
 			Aliases: []string{"export"},
-			Usage:   "export ruleset | data $ruleset_file [ -r rules ]",
+			Usage:   "export ruleset | data | store $ruleset_file [ -r rules ]",
 			Subcommands: []cli.Command{
 				{
 					Name:  "ruleset",
@@ -1358,6 +1459,133 @@ func main() {
 						return nil
 					},
 				},
+				{
+					Name:  "store",
+					Usage: "export store $backup_file [ -p prefixes ]",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "prefixes, p",
+							Usage: "the comma separated key prefixes to back up, such as connections,sources",
+						},
+					},
+					Action: func(c *cli.Context) error {
+						if len(c.Args()) != 1 {
+							fmt.Printf("Expect backup file.\n")
+							return nil
+						}
+						args := &model.BackupStoreDesc{
+							FileName: c.Args()[0],
+							Prefixes: splitPrefixes(c.String("prefixes")),
+						}
+						var reply string
+						err = client.Call("Server.BackupConfigStore", args, &reply)
+						if err != nil {
+							fmt.Println(err)
+						} else {
+							fmt.Println(reply)
+						}
+						return nil
+					},
+				},
+			},
+		},
+		{
+			Name:    "reconcile",
+			Aliases: []string{"reconcile"},
+			Usage:   "reconcile connections",
+			Subcommands: []cli.Command{
+				{
+					Name:  "connections",
+					Usage: "reconcile connections [-r]",
+					Flags: []cli.Flag{
+						cli.BoolFlag{
+							Name:  "repair, r",
+							Usage: "repair the orphan connections found",
+						},
+					},
+					Action: func(c *cli.Context) error {
+						var reply string
+						err = client.Call("Server.ReconcileConnections", c.Bool("repair"), &reply)
+						if err != nil {
+							fmt.Println(err)
+						} else {
+							fmt.Println(reply)
+						}
+						return nil
+					},
+				},
+			},
+		},
+		{
+			Name:    "migrate",
+			Aliases: []string{"migrate"},
+			Usage:   "migrate store | traces | compression",
+			Subcommands: []cli.Command{
+				{
+					Name:  "store",
+					Usage: "migrate store -t target_type [-s source_type] [-p prefixes]",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "source, s",
+							Usage: "the source config store type, default to sqlite which is the built-in store",
+						},
+						cli.StringFlag{
+							Name:  "target, t",
+							Usage: "the target config store type, such as redis or etcd",
+						},
+						cli.StringFlag{
+							Name:  "prefixes, p",
+							Usage: "the comma separated key prefixes to migrate, such as connections,sources",
+						},
+					},
+					Action: func(c *cli.Context) error {
+						args := &model.MigrateStoreDesc{
+							From:     c.String("source"),
+							To:       c.String("target"),
+							Prefixes: splitPrefixes(c.String("prefixes")),
+						}
+						if args.To == "" {
+							fmt.Printf("Expect target config store type.\n")
+							return nil
+						}
+						var reply string
+						err = client.Call("Server.MigrateConfigStore", args, &reply)
+						if err != nil {
+							fmt.Println(err)
+						} else {
+							fmt.Println(reply)
+						}
+						return nil
+					},
+				},
+				{
+					Name:  "traces",
+					Usage: "migrate traces",
+					Action: func(c *cli.Context) error {
+						var reply string
+						err = client.Call("Server.MigrateTraceStore", 0, &reply)
+						if err != nil {
+							fmt.Println(err)
+						} else {
+							fmt.Println(reply)
+						}
+						return nil
+					},
+				},
+				{
+					Name:  "compression",
+					Usage: "migrate compression",
+					Action: func(c *cli.Context) error {
+						var reply string
+						err = client.Call("Server.RecompressTraceStore", 0, &reply)
+						if err != nil {
+							fmt.Println(err)
+						} else {
+							fmt.Println(reply)
+						}
+						return nil
+					},
+				},
 			},
 		},
 	}
@@ -1415,3 +1643,16 @@ func readDef(sfile string, t string) ([]byte, error) {
 		return rule, nil
 	}
 }
+
+func splitPrefixes(s string) []string {
+	if s == "" {
+		return nil
+	}
+	var prefixes []string
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			prefixes = append(prefixes, p)
+		}
+	}
+	return prefixes
+}
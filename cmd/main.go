@@ -34,6 +34,7 @@ var (
 	dataPath     string
 	logPath      string
 	pluginsPath  string
+	selfTest     bool
 )
 
 func init() {
@@ -43,6 +44,7 @@ func init() {
 	fs.StringVar(&dataPath, "data", "", "data indicates the path of data dir")
 	fs.StringVar(&logPath, "log", "", "log indicates the path of log dir")
 	fs.StringVar(&pluginsPath, "plugins", "", "plugins indicates the path of plugins dir")
+	fs.BoolVar(&selfTest, "self-test", false, "self-test validates the storage, connections and tracer endpoint, then exits")
 	_ = fs.Parse(os.Args[1:])
 
 	if len(loadFileType) > 0 {
@@ -65,5 +67,8 @@ func init() {
 }
 
 func Main() {
+	if selfTest {
+		os.Exit(server.SelfTest(Version))
+	}
 	server.StartUp(Version)
 }
@@ -82,6 +82,9 @@ func (m *influxSink) Provision(ctx api.StreamContext, props map[string]any) erro
 	if err != nil {
 		return fmt.Errorf("error configuring tls: %s", err)
 	}
+	if err := cert.CheckEndpoint(m.conf.Addr); err != nil {
+		return err
+	}
 	m.tlsconf = tlsConf
 	return nil
 }
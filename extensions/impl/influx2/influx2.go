@@ -128,6 +128,9 @@ func (m *influxSink2) Provision(ctx api.StreamContext, props map[string]any) err
 	if err != nil {
 		return fmt.Errorf("error configuring tls: %s", err)
 	}
+	if err := cert.CheckEndpoint(m.conf.Addr); err != nil {
+		return err
+	}
 	m.tlsconf = tlsConf
 	if m.conf.BatchSize <= 0 {
 		m.conf.BatchSize = 1
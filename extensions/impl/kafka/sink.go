@@ -28,11 +28,13 @@ import (
 
 	"github.com/lf-edge/ekuiper/v2/internal/pkg/util"
 	"github.com/lf-edge/ekuiper/v2/internal/topo/node/metric"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/node/tracenode"
 	"github.com/lf-edge/ekuiper/v2/metrics"
 	"github.com/lf-edge/ekuiper/v2/pkg/cast"
 	"github.com/lf-edge/ekuiper/v2/pkg/cert"
 	"github.com/lf-edge/ekuiper/v2/pkg/model"
 	"github.com/lf-edge/ekuiper/v2/pkg/timex"
+	"github.com/lf-edge/ekuiper/v2/pkg/tracer"
 )
 
 const (
@@ -151,6 +153,9 @@ func (k *KafkaSink) Provision(ctx api.StreamContext, configs map[string]any) err
 	if err != nil {
 		return err
 	}
+	if err := checkPlaintextBrokers(c.Brokers, tlsConfig); err != nil {
+		return err
+	}
 	mechanism, err := k.saslConf.GetMechanism()
 	failpoint.Inject("kafkaErr", func(val failpoint.Value) {
 		err = mockKakfaSourceErr(val.(int), mechanismErr)
@@ -367,6 +372,13 @@ func (k *KafkaSink) buildMsg(ctx api.StreamContext, item api.RawTuple) (kafkago.
 	if err != nil {
 		return kafkago.Message{}, fmt.Errorf("parse kafka headers error: %v", err)
 	}
+	traced, spanCtx, span := tracenode.TraceInput(ctx, item, fmt.Sprintf("%s_emit", ctx.GetOpId()))
+	if traced {
+		defer span.End()
+		for key, value := range tracer.InjectProps(spanCtx, nil) {
+			headers = append(headers, kafkago.Header{Key: key, Value: []byte(value)})
+		}
+	}
 	msg.Headers = headers
 	return msg, nil
 }
@@ -34,6 +34,7 @@ import (
 	"github.com/lf-edge/ekuiper/v2/pkg/cast"
 	"github.com/lf-edge/ekuiper/v2/pkg/cert"
 	"github.com/lf-edge/ekuiper/v2/pkg/timex"
+	"github.com/lf-edge/ekuiper/v2/pkg/tracer"
 )
 
 type KafkaSource struct {
@@ -107,6 +108,9 @@ func (k *KafkaSource) Provision(ctx api.StreamContext, configs map[string]any) e
 		conf.Log.Errorf("kafka tls conf error: %v", err)
 		return err
 	}
+	if err := checkPlaintextBrokers(kConf.Brokers, tlsConfig); err != nil {
+		return err
+	}
 	k.tlsConfig = tlsConfig
 	saslConf, err := getSaslConf(configs)
 	failpoint.Inject("kafkaErr", func(val failpoint.Value) {
@@ -214,10 +218,22 @@ func (k *KafkaSource) Subscribe(ctx api.StreamContext, ingest api.BytesIngest, i
 		KafkaSourceCounter.WithLabelValues(LblMsg, ctx.GetRuleId(), ctx.GetOpId()).Inc()
 		KafkaSourceCounter.WithLabelValues(LblBytes, ctx.GetRuleId(), ctx.GetOpId()).Add(float64(len(msg.Value)))
 		KafkaSourceGauge.WithLabelValues(LblOffset, ctx.GetRuleId(), ctx.GetOpId()).Set(float64(msg.Offset))
-		ingest(ctx, msg.Value, nil, timex.GetNow())
+		ingest(ctx, msg.Value, traceMeta(msg.Headers), timex.GetNow())
 	}
 }
 
+// traceMeta extracts the upstream trace context in the headers to the metadata
+func traceMeta(headers []kafkago.Header) map[string]any {
+	if len(headers) == 0 {
+		return nil
+	}
+	carrier := make(tracer.MetaCarrier, len(headers))
+	for _, h := range headers {
+		carrier[h.Key] = h.Value
+	}
+	return tracer.CopyToMeta(carrier, nil)
+}
+
 func (k *KafkaSource) Rewind(offset interface{}) error {
 	conf.Log.Infof("set kafka source offset: %v", offset)
 	offsetV := k.offset //nolint:staticcheck
@@ -309,6 +325,19 @@ func (c *saslConf) GetMechanism() (sasl.Mechanism, error) {
 	return mechanism, nil
 }
 
+// checkPlaintextBrokers rejects the brokers connected without TLS in compliance mode
+func checkPlaintextBrokers(brokers string, tlsConfig *tls.Config) error {
+	if tlsConfig != nil {
+		return nil
+	}
+	for _, broker := range strings.Split(brokers, ",") {
+		if err := cert.CheckPlaintext(broker); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 const (
 	mockErrStart int = iota
 	castConfErr
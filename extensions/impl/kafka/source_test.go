@@ -19,6 +19,7 @@ import (
 	"testing"
 
 	"github.com/pingcap/failpoint"
+	kafkago "github.com/segmentio/kafka-go"
 	"github.com/stretchr/testify/require"
 
 	mockContext "github.com/lf-edge/ekuiper/v2/pkg/mock/context"
@@ -116,3 +117,13 @@ func TestKafkaPassword(t *testing.T) {
 		require.Equal(t, tc.expectPassword, sconf.SaslPassword)
 	}
 }
+
+func TestTraceMeta(t *testing.T) {
+	require.Nil(t, traceMeta(nil))
+	require.Nil(t, traceMeta([]kafkago.Header{{Key: "k", Value: []byte("v")}}))
+	meta := traceMeta([]kafkago.Header{
+		{Key: "k", Value: []byte("v")},
+		{Key: "traceparent", Value: []byte("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")},
+	})
+	require.Equal(t, map[string]any{"traceId": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}, meta)
+}
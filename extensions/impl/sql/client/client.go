@@ -16,14 +16,36 @@ package client
 
 import (
 	"database/sql"
+	"database/sql/driver"
+	"errors"
 	"fmt"
 
 	"github.com/lf-edge/ekuiper/contract/v2/api"
 
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
 	"github.com/lf-edge/ekuiper/v2/pkg/modules"
 	"github.com/lf-edge/ekuiper/v2/pkg/syncx"
 )
 
+func init() {
+	errorx.RegisterClassifier("sql", classifySQLError)
+}
+
+// classifySQLError classifies the errors of database/sql. The network failures of the drivers are classified by
+// errorx by their types.
+func classifySQLError(err error) (errorx.ErrorKind, bool) {
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) {
+		return errorx.KindTransient, true
+	}
+	return errorx.KindUnknown, false
+}
+
+// ConnectionConf is the props of the sql connection. The url takes precedence over the legacy dburl.
+type ConnectionConf struct {
+	URL   string `json:"url"`
+	DBUrl string `json:"dburl"`
+}
+
 type SQLConnection struct {
 	syncx.RWMutex
 	url    string
@@ -83,6 +105,15 @@ func (s *SQLConnection) GetDB() *sql.DB {
 	return s.db
 }
 
+// ResourceUsage reports the open connections of the database pool
+func (s *SQLConnection) ResourceUsage() modules.ResourceUsage {
+	db := s.GetDB()
+	if db == nil {
+		return modules.ResourceUsage{}
+	}
+	return modules.ResourceUsage{OpenFiles: int64(db.Stats().OpenConnections)}
+}
+
 func (s *SQLConnection) Ping(ctx api.StreamContext) error {
 	s.Lock()
 	defer s.Unlock()
@@ -95,6 +126,18 @@ func (s *SQLConnection) Ping(ctx api.StreamContext) error {
 	return s.db.Ping()
 }
 
+func (s *SQLConnection) CanSubscribe() bool {
+	return true
+}
+
+func (s *SQLConnection) CanPublish() bool {
+	return true
+}
+
+func (s *SQLConnection) CanQuery() bool {
+	return true
+}
+
 func (s *SQLConnection) DetachSub(ctx api.StreamContext, props map[string]any) {
 	// do nothing
 }
@@ -118,7 +161,8 @@ func CreateConnection(ctx api.StreamContext) modules.Connection {
 func (s *SQLConnection) dial(ctx api.StreamContext) error {
 	db, err := openDB(s.url)
 	if err != nil {
-		return fmt.Errorf("create connection err:%v", err)
+		// the url or the driver is invalid, which won't be fixed by retry
+		return errorx.NewPermanent(fmt.Errorf("create connection err:%w", err))
 	}
 	s.db = db
 	return s.db.Ping()
@@ -15,12 +15,16 @@
 package client
 
 import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
 	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/require"
 
 	"github.com/lf-edge/ekuiper/v2/extensions/impl/sql/testx"
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
 	mockContext "github.com/lf-edge/ekuiper/v2/pkg/mock/context"
 )
 
@@ -51,3 +55,16 @@ func TestSQLClient(t *testing.T) {
 	require.NoError(t, conn.Ping(ctx))
 	conn.Close(ctx)
 }
+
+func TestSQLErrorKind(t *testing.T) {
+	require.True(t, errorx.IsRetryable(fmt.Errorf("query: %w", driver.ErrBadConn)))
+	require.True(t, errorx.IsRetryable(sql.ErrConnDone))
+	require.Equal(t, errorx.KindUnknown, errorx.KindOf(errors.New("syntax error")))
+
+	ctx := mockContext.NewMockContext("1", "2")
+	conn := &SQLConnection{}
+	require.NoError(t, conn.Provision(ctx, "id", map[string]any{"dburl": "unknown://localhost/db"}))
+	err := conn.Dial(ctx)
+	require.Error(t, err)
+	require.Equal(t, errorx.KindPermanent, errorx.KindOf(err))
+}
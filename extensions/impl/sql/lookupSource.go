@@ -32,6 +32,7 @@ import (
 type SqlLookupSource struct {
 	conf          *SQLConf
 	conn          *client2.SQLConnection
+	cw            *connection.ConnWrapper
 	props         map[string]any
 	driver        string
 	table         string
@@ -92,16 +93,26 @@ func (s *SqlLookupSource) Connect(ctx api.StreamContext, sc api.StatusChangeHand
 		return err
 	}
 	s.conId = cw.ID
+	s.cw = cw
 	conn, err := cw.Wait(ctx)
 	if conn == nil {
 		return fmt.Errorf("sql client not ready: %v", err)
 	}
-	cli = conn.(*client2.SQLConnection)
+	cli, err = connection.AsConnection[*client2.SQLConnection](cw.ID, conn)
+	if err != nil {
+		return err
+	}
 	s.conn = cli
-	return err
+	return nil
 }
 
 func (s *SqlLookupSource) Lookup(ctx api.StreamContext, fields []string, keys []string, values []any) ([]map[string]any, error) {
+	// the connection is replaced once the shared connection is updated
+	cli, err := connection.CurrentConnection[*client2.SQLConnection](ctx, s.cw)
+	if err != nil {
+		return nil, err
+	}
+	s.conn = cli
 	if s.needReconnect {
 		err := s.conn.Reconnect()
 		if err != nil {
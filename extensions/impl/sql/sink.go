@@ -147,7 +147,7 @@ func (s *SQLSinkConnector) Connect(ctx api.StreamContext, sc api.StatusChangeHan
 	if conn == nil {
 		return fmt.Errorf("sql client not ready: %v", err)
 	}
-	s.conn = conn.(*client.SQLConnection)
+	s.conn, err = connection.AsConnection[*client.SQLConnection](cw.ID, conn)
 	return err
 }
 
@@ -292,6 +292,12 @@ func (s *SQLSinkConnector) save(ctx api.StreamContext, table string, data map[st
 
 func (s *SQLSinkConnector) writeToDB(ctx api.StreamContext, sqlStr string) error {
 	ctx.GetLogger().Debugf(sqlStr)
+	// the connection is replaced once the shared connection is updated
+	cli, err := connection.CurrentConnection[*client.SQLConnection](ctx, s.cw)
+	if err != nil {
+		return errorx.NewIOErr(err.Error())
+	}
+	s.conn = cli
 	if s.needReconnect {
 		metrics.IOCounter.WithLabelValues(LblSql, metrics.LblSinkIO, LblReconn, ctx.GetRuleId(), ctx.GetOpId()).Inc()
 		err := s.conn.Reconnect()
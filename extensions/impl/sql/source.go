@@ -39,6 +39,7 @@ type SQLSourceConnector struct {
 	conf          *SQLConf
 	Query         sqlgen.SqlQueryGenerator
 	conn          *client2.SQLConnection
+	cw            *connection.ConnWrapper
 	props         map[string]any
 	needReconnect bool
 	conId         string
@@ -85,6 +86,7 @@ type SQLConf struct {
 
 func init() {
 	modules.RegisterConnection("sql", client2.CreateConnection)
+	modules.RegisterConnectionDescriptor(modules.ConnectionDescriptor{Type: "sql", DisplayName: "SQL", Props: modules.PropsFromStruct(&client2.ConnectionConf{})})
 }
 
 func (s *SQLSourceConnector) Provision(ctx api.StreamContext, props map[string]any) error {
@@ -130,15 +132,19 @@ func (s *SQLSourceConnector) Connect(ctx api.StreamContext, sc api.StatusChangeH
 		return err
 	}
 	s.conId = cw.ID
+	s.cw = cw
 	conn, err := cw.Wait(ctx)
 	if conn == nil {
 		return fmt.Errorf("sql client not ready: %v", err)
 	}
-	cli = conn.(*client2.SQLConnection)
+	cli, err = connection.AsConnection[*client2.SQLConnection](cw.ID, conn)
+	if err != nil {
+		return err
+	}
 	s.conn = cli
 	s.ruleID = ctx.GetRuleId()
 	s.opID = ctx.GetOpId()
-	return err
+	return nil
 }
 
 func (s *SQLSourceConnector) Close(ctx api.StreamContext) error {
@@ -157,6 +163,14 @@ func (s *SQLSourceConnector) Pull(ctx api.StreamContext, recvTime time.Time, ing
 func (s *SQLSourceConnector) queryData(ctx api.StreamContext, rcvTime time.Time, ingest api.TupleIngest, ingestError api.ErrorIngest) {
 	s.resetStats()
 	logger := ctx.GetLogger()
+	// the connection is replaced once the shared connection is updated
+	cli, err := connection.CurrentConnection[*client2.SQLConnection](ctx, s.cw)
+	if err != nil {
+		logger.Errorf("get sql connection error %v", err)
+		ingestError(ctx, err)
+		return
+	}
+	s.conn = cli
 	if s.needReconnect {
 		SqlSourceCounter.WithLabelValues(LblRecon, ctx.GetRuleId(), ctx.GetOpId()).Inc()
 		err := s.conn.Reconnect()
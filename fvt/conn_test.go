@@ -267,7 +267,7 @@ func (s *ConnectionTestSuite) TestSourcePing() {
 			props: map[string]any{
 				"server": "tcp://127.0.0.1:1883",
 			},
-			err: "{\"error\":1003,\"message\":\"found error when connecting for tcp://127.0.0.1:1883: network Error : dial tcp 127.0.0.1:1883: connect: connection refused\"}\n",
+			err: "{\"error\":1003,\"code\":\"IO\",\"category\":\"transient\",\"message\":\"found error when connecting for tcp://127.0.0.1:1883: network Error : dial tcp 127.0.0.1:1883: connect: connection refused\"}\n",
 		},
 		{
 			name: "httppull",
@@ -416,7 +416,7 @@ func (s *ConnectionTestSuite) TestSinkPing() {
 			props: map[string]any{
 				"server": "tcp://127.0.0.1:1883",
 			},
-			err: "{\"error\":1003,\"message\":\"found error when connecting for tcp://127.0.0.1:1883: network Error : dial tcp 127.0.0.1:1883: connect: connection refused\"}\n",
+			err: "{\"error\":1003,\"code\":\"IO\",\"category\":\"transient\",\"message\":\"found error when connecting for tcp://127.0.0.1:1883: network Error : dial tcp 127.0.0.1:1883: connect: connection refused\"}\n",
 		},
 		{
 			name: "rest",
@@ -19,8 +19,12 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/stretchr/testify/require"
+
 	"github.com/lf-edge/ekuiper/v2/internal/binder"
 	"github.com/lf-edge/ekuiper/v2/internal/binder/mock"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/context"
+	"github.com/lf-edge/ekuiper/v2/pkg/connection"
 	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
 	"github.com/lf-edge/ekuiper/v2/pkg/modules"
 )
@@ -257,3 +261,23 @@ func TestLookupSource(t *testing.T) {
 		})
 	}
 }
+
+func TestConnectionDescriptors(t *testing.T) {
+	for _, typ := range []string{"mqtt", "nng", "httppush", "websocket", "sse"} {
+		desc, ok := modules.GetConnectionDescriptor(typ)
+		require.True(t, ok, typ)
+		require.NotEmpty(t, desc.Props, typ)
+	}
+	require.NoError(t, connection.InitConnectionManager4Test())
+	ctx := context.Background()
+	_, err := connection.CreateNamedConnection(ctx, "badmqtt", "mqtt", map[string]any{"protocolVersion": "6", "username": 1})
+	code, _ := errorx.GetErrorCode(err)
+	require.Equal(t, errorx.ConnectionPropsErr, code)
+	require.Equal(t, []errorx.FieldError{
+		{Field: "server", Message: "is required"},
+		{Field: "protocolVersion", Message: "must be one of [3.1 3.1.1 4 5]"},
+		{Field: "username", Message: "must be string"},
+	}, errorx.GetFieldErrors(err))
+	_, err = connection.GetConnectionDetail(ctx, "badmqtt")
+	require.Error(t, err)
+}
@@ -65,6 +65,11 @@ func init() {
 	modules.RegisterConnection("httppush", httpserver.CreateConnection)
 	modules.RegisterConnection("websocket", httpserver.CreateWebsocketConnection)
 	modules.RegisterConnection("sse", httpserver.CreateSSEConnection)
+	modules.RegisterConnectionDescriptor(modules.ConnectionDescriptor{Type: "mqtt", DisplayName: "MQTT", Props: mqtt.ConnectionProps()})
+	modules.RegisterConnectionDescriptor(modules.ConnectionDescriptor{Type: "nng", DisplayName: "NNG", Props: modules.PropsFromStruct(&nng.SockConf{})})
+	modules.RegisterConnectionDescriptor(modules.ConnectionDescriptor{Type: "httppush", DisplayName: "HTTP Push", Props: httpserver.ConnectionProps()})
+	modules.RegisterConnectionDescriptor(modules.ConnectionDescriptor{Type: "websocket", DisplayName: "WebSocket", Props: httpserver.WebsocketConnectionProps()})
+	modules.RegisterConnectionDescriptor(modules.ConnectionDescriptor{Type: "sse", DisplayName: "SSE", Props: httpserver.SSEConnectionProps()})
 }
 
 type Manager struct{}
@@ -24,6 +24,7 @@ import (
 
 func init() {
 	modules.RegisterConnection("edgex", edgexCon.GetConnection)
+	modules.RegisterConnectionDescriptor(modules.ConnectionDescriptor{Type: "edgex", DisplayName: "EdgeX", Props: modules.PropsFromStruct(&edgexCon.EdgexConf{})})
 	modules.RegisterSource("edgex", edgex.GetSource)
 	modules.RegisterSink("edgex", edgex.GetSink)
 }
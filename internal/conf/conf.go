@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"math"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -54,50 +55,14 @@ var (
 )
 
 func InitConf() {
-	cpath, err := GetConfLoc()
-	if err != nil {
-		panic(err)
-	}
-	kc := model.KuiperConf{
-		Rule: def.RuleOption{
-			LateTol:            cast.DurationConf(time.Second),
-			Concurrency:        1,
-			BufferLength:       1024,
-			CheckpointInterval: cast.DurationConf(5 * time.Minute), // 5 minutes
-			SendError:          false,
-			RestartStrategy: &def.RestartStrategy{
-				Attempts: 0,
-			},
-		},
-	}
-
-	err = LoadConfigFromPath(filepath.Join(cpath, ConfFileName), &kc)
+	kc, err := loadConf()
 	if err != nil {
 		Log.Fatal(err)
 		panic(err)
 	}
-	Config = &kc
-	if len(Config.Basic.Ip) == 0 {
-		Config.Basic.Ip = "0.0.0.0"
-	}
-	if len(Config.Basic.RestIp) == 0 {
-		Config.Basic.RestIp = "0.0.0.0"
-	}
-
-	if time.Duration(Config.Basic.RulePatrolInterval) < time.Second {
-		Log.Warnf("rule patrol interval %v is less than 1 second, set it to 10 seconds", Config.Basic.RulePatrolInterval)
-		Config.Basic.RulePatrolInterval = cast.DurationConf(10 * time.Second)
-	}
-
-	if time.Duration(Config.Connection.BackoffMaxElapsedDuration) < 1 {
-		Config.Connection.BackoffMaxElapsedDuration = cast.DurationConf(3 * time.Minute)
-	}
-
-	if Config.Basic.LogLevel == "" {
-		Config.Basic.LogLevel = InfoLogLevel
-	}
+	Config = kc
 	SetLogLevel(Config.Basic.LogLevel, Config.Basic.Debug)
-	SetLogFormat(Config.Basic.LogDisableTimestamp)
+	SetLogFormat(Config.Basic.LogFormat, Config.Basic.LogDisableTimestamp)
 	if err := SetConsoleAndFileLog(Config.Basic.ConsoleLog, Config.Basic.FileLog); err != nil {
 		log.Fatal(err)
 	}
@@ -117,10 +82,6 @@ func InitConf() {
 		}
 	}
 
-	if time.Duration(Config.Basic.GracefulShutdownTimeout) < 1 {
-		Config.Basic.GracefulShutdownTimeout = cast.DurationConf(3 * time.Second)
-	}
-
 	if Config.Basic.TimeZone != "" {
 		if err := cast.SetTimeZone(Config.Basic.TimeZone); err != nil {
 			Log.Fatal(err)
@@ -131,58 +92,253 @@ func InitConf() {
 		}
 	}
 
-	if Config.Basic.AesKey != "" {
-		key, err := base64.StdEncoding.DecodeString(Config.Basic.AesKey)
+	ekruntime.SetAppConf(Config)
+}
+
+// loadConf loads the configuration file with the defaults. It has no side effect so that the file can be reloaded
+// and compared with the running configuration.
+func loadConf() (*model.KuiperConf, error) {
+	cpath, err := GetConfLoc()
+	if err != nil {
+		return nil, err
+	}
+	c := &model.KuiperConf{
+		Rule: def.RuleOption{
+			LateTol:            cast.DurationConf(time.Second),
+			Concurrency:        1,
+			BufferLength:       1024,
+			CheckpointInterval: cast.DurationConf(5 * time.Minute), // 5 minutes
+			SendError:          false,
+			RestartStrategy: &def.RestartStrategy{
+				Attempts: 0,
+			},
+		},
+	}
+	if err := LoadConfigFromPath(filepath.Join(cpath, ConfFileName), c); err != nil {
+		return nil, err
+	}
+	if len(c.Basic.Ip) == 0 {
+		c.Basic.Ip = "0.0.0.0"
+	}
+	if len(c.Basic.RestIp) == 0 {
+		c.Basic.RestIp = "0.0.0.0"
+	}
+
+	if time.Duration(c.Basic.RulePatrolInterval) < time.Second {
+		Log.Warnf("rule patrol interval %v is less than 1 second, set it to 10 seconds", c.Basic.RulePatrolInterval)
+		c.Basic.RulePatrolInterval = cast.DurationConf(10 * time.Second)
+	}
+
+	if time.Duration(c.Connection.BackoffMaxElapsedDuration) < 1 {
+		c.Connection.BackoffMaxElapsedDuration = cast.DurationConf(3 * time.Minute)
+	}
+	if c.Connection.OperationTimeout <= 0 {
+		c.Connection.OperationTimeout = cast.DurationConf(10 * time.Second)
+	}
+	if c.Connection.Retry.Policy == "" {
+		c.Connection.Retry.Policy = "exponential"
+	}
+	if c.Connection.CircuitBreaker.Threshold > 0 && c.Connection.CircuitBreaker.Cooldown <= 0 {
+		c.Connection.CircuitBreaker.Cooldown = cast.DurationConf(30 * time.Second)
+	}
+	if c.ConfigSync.Source == "" {
+		c.ConfigSync.Source = "http"
+	}
+	if c.ConfigSync.Path == "" {
+		c.ConfigSync.Path = "connections.yaml"
+	}
+	if time.Duration(c.ConfigSync.Interval) < time.Second {
+		c.ConfigSync.Interval = cast.DurationConf(5 * time.Minute)
+	}
+	for i, k := range c.ManagementAuth.APIKeys {
+		if k.Scope != "read" && k.Scope != "admin" {
+			Log.Warnf("api key %s has invalid scope %s, set to read", k.Name, k.Scope)
+			c.ManagementAuth.APIKeys[i].Scope = "read"
+		}
+	}
+	if c.ManagementAuth.RateLimit > 0 && c.ManagementAuth.Burst <= 0 {
+		c.ManagementAuth.Burst = int(math.Ceil(c.ManagementAuth.RateLimit))
+	}
+	if c.AuditLog.MaxRecords < 0 {
+		c.AuditLog.MaxRecords = 0
+	}
+	if c.Connection.LeaderElection.LeaseTTL <= 0 {
+		c.Connection.LeaderElection.LeaseTTL = cast.DurationConf(15 * time.Second)
+	}
+	if c.Connection.LeaderElection.NodeID == "" {
+		host, _ := os.Hostname()
+		c.Connection.LeaderElection.NodeID = fmt.Sprintf("%s-%d", host, os.Getpid())
+	}
+	if time.Duration(c.Discovery.RefreshInterval) < time.Second {
+		c.Discovery.RefreshInterval = cast.DurationConf(30 * time.Second)
+	}
+	if c.Discovery.FailureCooldown <= 0 {
+		c.Discovery.FailureCooldown = cast.DurationConf(30 * time.Second)
+	}
+
+	if c.Basic.LogLevel == "" {
+		c.Basic.LogLevel = InfoLogLevel
+	}
+
+	if time.Duration(c.Basic.GracefulShutdownTimeout) < 1 {
+		c.Basic.GracefulShutdownTimeout = cast.DurationConf(3 * time.Second)
+	}
+
+	if c.Basic.AesKey != "" {
+		key, err := base64.StdEncoding.DecodeString(c.Basic.AesKey)
 		if err != nil {
-			Log.Fatal(err)
+			return nil, err
 		}
-		Config.AesKey = key
+		c.AesKey = key
 	}
 
-	if Config.Store.ExtStateType == "" {
-		Config.Store.ExtStateType = "sqlite"
+	if c.Store.ExtStateType == "" {
+		c.Store.ExtStateType = "sqlite"
+	}
+	if c.Store.Encryption.KeyEnv == "" {
+		c.Store.Encryption.KeyEnv = "KUIPER_STORE_ENCRYPTION_KEY"
+	}
+	if c.Store.ConfigWatchInterval <= 0 {
+		c.Store.ConfigWatchInterval = cast.DurationConf(10 * time.Second)
 	}
 
-	if Config.Portable.PythonBin == "" {
-		Config.Portable.PythonBin = "python"
+	if c.Portable.PythonBin == "" {
+		c.Portable.PythonBin = "python"
 	}
-	if Config.Portable.InitTimeout <= 0 {
-		Config.Portable.InitTimeout = 5000
+	if c.Portable.InitTimeout <= 0 {
+		c.Portable.InitTimeout = 5000
 	}
-	if Config.Portable.SendTimeout <= 0 {
-		Config.Portable.SendTimeout = 5 * time.Second
+	if c.Portable.SendTimeout <= 0 {
+		c.Portable.SendTimeout = 5 * time.Second
 	}
-	if Config.Portable.RecvTimeout <= 0 {
-		Config.Portable.RecvTimeout = 5 * time.Second
+	if c.Portable.RecvTimeout <= 0 {
+		c.Portable.RecvTimeout = 5 * time.Second
 	}
-	if Config.Source == nil {
-		Config.Source = &model.SourceConf{}
+	if c.Source == nil {
+		c.Source = &model.SourceConf{}
 	}
 
-	if Config.Basic.MetricsDumpConfig.RetainedDuration < 1 {
-		Config.Basic.MetricsDumpConfig.RetainedDuration = 6 * time.Hour
+	if c.Basic.MetricsDumpConfig.RetainedDuration < 1 {
+		c.Basic.MetricsDumpConfig.RetainedDuration = 6 * time.Hour
 	}
 
-	_ = Config.Source.Validate(Log)
-	if Config.Sink == nil {
-		Config.Sink = &model.SinkConf{}
+	_ = c.Source.Validate(Log)
+	if c.Sink == nil {
+		c.Sink = &model.SinkConf{}
 	}
-	_ = Config.Sink.Validate(Log)
+	_ = c.Sink.Validate(Log)
 
-	if Config.Basic.Syslog != nil {
-		_ = Config.Basic.Syslog.Validate()
+	if c.Basic.Syslog != nil {
+		_ = c.Basic.Syslog.Validate()
 	}
 
-	if Config.OpenTelemetry.RemoteEndpoint == "" {
-		Config.OpenTelemetry.RemoteEndpoint = "localhost:4318"
+	if c.OpenTelemetry.RemoteEndpoint == "" {
+		c.OpenTelemetry.RemoteEndpoint = "localhost:4318"
 	}
 
-	if Config.OpenTelemetry.LocalTraceCapacity < 1 {
-		Config.OpenTelemetry.LocalTraceCapacity = 2048
+	c.OpenTelemetry.RemoteProtocol = strings.ToLower(c.OpenTelemetry.RemoteProtocol)
+	switch c.OpenTelemetry.RemoteProtocol {
+	case "otlphttp", "otlpgrpc", "jaeger", "zipkin":
+	case "":
+		c.OpenTelemetry.RemoteProtocol = "otlphttp"
+	default:
+		Log.Warnf("unknown openTelemetry.remoteProtocol %s, use otlphttp", c.OpenTelemetry.RemoteProtocol)
+		c.OpenTelemetry.RemoteProtocol = "otlphttp"
 	}
 
-	_ = ValidateRuleOption(&Config.Rule)
-	ekruntime.SetAppConf(Config)
+	sp := &c.OpenTelemetry.SpanPipeline
+	if sp.QueueSize < 1 {
+		sp.QueueSize = 2048
+	}
+	if sp.BatchSize < 1 {
+		sp.BatchSize = 512
+	}
+	if sp.BatchSize > sp.QueueSize {
+		sp.BatchSize = sp.QueueSize
+	}
+	if sp.FlushInterval <= 0 {
+		sp.FlushInterval = cast.DurationConf(time.Second)
+	}
+	if sp.DropWarnRatio <= 0 || sp.DropWarnRatio > 1 {
+		sp.DropWarnRatio = 0.05
+	}
+
+	sc := &c.OpenTelemetry.SpanCompression
+	validateCompression("openTelemetry.spanCompression.storage", &sc.Storage)
+	validateCompression("openTelemetry.spanCompression.export", &sc.Export)
+	if sc.Export.Codec == "zstd" {
+		Log.Warnf("openTelemetry.spanCompression.export does not support zstd, use gzip")
+		sc.Export = model.CompressionConf{Codec: "gzip"}
+	}
+
+	re := &c.OpenTelemetry.RemoteExport
+	if re.QueueSize < 1 {
+		re.QueueSize = 2048
+	}
+	if re.BatchSize < 1 {
+		re.BatchSize = 512
+	}
+	if re.BatchSize > re.QueueSize {
+		re.BatchSize = re.QueueSize
+	}
+	if re.FlushInterval <= 0 {
+		re.FlushInterval = cast.DurationConf(5 * time.Second)
+	}
+	if re.InitialInterval <= 0 {
+		re.InitialInterval = cast.DurationConf(time.Second)
+	}
+	if re.MaxInterval <= 0 {
+		re.MaxInterval = cast.DurationConf(30 * time.Second)
+	}
+	if re.MaxElapsed <= 0 {
+		re.MaxElapsed = cast.DurationConf(time.Minute)
+	}
+
+	if c.OpenTelemetry.LocalTraceCapacity < 1 {
+		c.OpenTelemetry.LocalTraceCapacity = 2048
+	}
+
+	if c.OpenTelemetry.LocalTraceRetention <= 0 {
+		c.OpenTelemetry.LocalTraceRetention = cast.DurationConf(24 * time.Hour)
+	}
+
+	if c.OpenTelemetry.LocalTraceCleanupInterval <= 0 {
+		c.OpenTelemetry.LocalTraceCleanupInterval = cast.DurationConf(time.Hour)
+	}
+	if c.OpenTelemetry.LocalTraceMaxSpans < 0 {
+		c.OpenTelemetry.LocalTraceMaxSpans = 0
+	}
+	if c.OpenTelemetry.LocalTraceMaxBytes < 0 {
+		c.OpenTelemetry.LocalTraceMaxBytes = 0
+	}
+
+	c.OpenTelemetry.LocalStorage = strings.ToLower(c.OpenTelemetry.LocalStorage)
+	switch c.OpenTelemetry.LocalStorage {
+	case "", "memory", "sqlite", "file":
+	default:
+		Log.Warnf("unknown openTelemetry.localStorage %s, use the default", c.OpenTelemetry.LocalStorage)
+		c.OpenTelemetry.LocalStorage = ""
+	}
+
+	_ = c.OpenTelemetry.Sampling.Validate(Log)
+	for k, r := range c.OpenTelemetry.Redaction {
+		if r == nil {
+			delete(c.OpenTelemetry.Redaction, k)
+			continue
+		}
+		_ = r.Validate(Log)
+	}
+
+	if c.OpenTelemetry.RecordMode != "error" {
+		c.OpenTelemetry.RecordMode = "all"
+	}
+
+	if c.OpenTelemetry.Metrics.Interval <= 0 {
+		c.OpenTelemetry.Metrics.Interval = cast.DurationConf(30 * time.Second)
+	}
+
+	_ = ValidateRuleOption(&c.Rule)
+	return c, nil
 }
 
 func SetLogLevel(level string, debug bool) {
@@ -259,8 +415,30 @@ func SetConsoleAndFileLog(consoleLog, fileLog bool) error {
 	return nil
 }
 
-func SetLogFormat(disableTimestamp bool) {
-	Log.Formatter.(*logrus.TextFormatter).DisableTimestamp = disableTimestamp
+// validateCompression resets the unknown codec to none and the invalid level to the default of the codec
+func validateCompression(key string, c *model.CompressionConf) {
+	c.Codec = strings.ToLower(c.Codec)
+	switch c.Codec {
+	case "":
+		c.Codec = "none"
+	case "none":
+	case "gzip":
+		if c.Level < -2 || c.Level > 9 {
+			Log.Warnf("invalid %s.level %d of gzip, use the default level", key, c.Level)
+			c.Level = 0
+		}
+	case "zstd":
+		if c.Level < 0 || c.Level > 22 {
+			Log.Warnf("invalid %s.level %d of zstd, use the default level", key, c.Level)
+			c.Level = 0
+		}
+	default:
+		Log.Warnf("unknown %s.codec %s, use none", key, c.Codec)
+		c.Codec = "none"
+	}
+	if c.Codec == "none" {
+		c.Level = 0
+	}
 }
 
 func ValidateRuleOption(option *def.RuleOption) error {
@@ -287,6 +465,11 @@ func ValidateRuleOption(option *def.RuleOption) error {
 			errs = errors.Join(errs, errors.New("invalidRestartAttempts:restart attempts must be greater than 0"))
 		}
 	}
+	if option.WarmUp < 0 {
+		option.WarmUp = 0
+		Log.Warnf("warmUp is negative, set to 0")
+		errs = errors.Join(errs, errors.New("invalidWarmUp:warmUp must not be negative"))
+	}
 	if err := schedule.ValidateRanges(option.CronDatetimeRange); err != nil {
 		errs = errors.Join(errs, fmt.Errorf("validate cronDatetimeRange failed, err:%v", err))
 	}
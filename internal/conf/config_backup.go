@@ -0,0 +1,131 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conf
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// CfgBackupVersion is the version of the config store backup archive
+const CfgBackupVersion = 1
+
+// CfgBackup is the content of the backup archive. The archive is a gzipped json of it.
+type CfgBackup struct {
+	Version   int                               `json:"version"`
+	CreatedAt time.Time                         `json:"createdAt"`
+	Prefixes  []string                          `json:"prefixes,omitempty"`
+	Data      map[string]map[string]interface{} `json:"data"`
+}
+
+// BackupCfgStorage writes the configs with any of the prefixes into the writer as an archive.
+// Empty prefixes mean the whole storage. It returns the number of the exported keys.
+func BackupCfgStorage(w io.Writer, prefixes []string) (int, error) {
+	data, err := getCfgByPrefixes(prefixes)
+	if err != nil {
+		return 0, err
+	}
+	b := &CfgBackup{
+		Version:   CfgBackupVersion,
+		CreatedAt: time.Now().UTC(),
+		Prefixes:  prefixes,
+		Data:      data,
+	}
+	zw := gzip.NewWriter(w)
+	if err := json.NewEncoder(zw).Encode(b); err != nil {
+		return 0, err
+	}
+	if err := zw.Close(); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+// ReadCfgBackup reads the backup archive
+func ReadCfgBackup(r io.Reader) (*CfgBackup, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("invalid backup archive: %v", err)
+	}
+	defer zr.Close()
+	b := &CfgBackup{}
+	if err := json.NewDecoder(zr).Decode(b); err != nil {
+		return nil, fmt.Errorf("invalid backup archive: %v", err)
+	}
+	if b.Version > CfgBackupVersion {
+		return nil, fmt.Errorf("backup archive version %d is not supported", b.Version)
+	}
+	return b, nil
+}
+
+// RestoreCfgStorage restores the configs with any of the prefixes from the archive. Empty prefixes mean all the
+// prefixes of the archive. If replace is true, the keys under the prefixes but not in the archive are deleted so
+// that the storage returns to the state when the backup was taken. The changes are applied in one batch.
+// It returns the number of the restored keys.
+func RestoreCfgStorage(r io.Reader, prefixes []string, replace bool) (int, error) {
+	b, err := ReadCfgBackup(r)
+	if err != nil {
+		return 0, err
+	}
+	if len(prefixes) == 0 {
+		prefixes = b.Prefixes
+	}
+	ops := make([]ConfigOp, 0, len(b.Data))
+	for key, props := range b.Data {
+		if hasAnyPrefix(key, prefixes) {
+			ops = append(ops, ConfigOp{Key: key, Props: props})
+		}
+	}
+	restored := len(ops)
+	if replace {
+		current, err := getCfgByPrefixes(prefixes)
+		if err != nil {
+			return 0, err
+		}
+		for key := range current {
+			if _, ok := b.Data[key]; !ok {
+				ops = append(ops, ConfigOp{Key: key, Delete: true})
+			}
+		}
+	}
+	if err := BatchCfgInKVStorage(ops); err != nil {
+		return 0, err
+	}
+	return restored, nil
+}
+
+func getCfgByPrefixes(prefixes []string) (map[string]map[string]interface{}, error) {
+	kvStorage, err := getKVStorage()
+	if err != nil {
+		return nil, err
+	}
+	return readCfgByPrefixes(kvStorage, prefixes)
+}
+
+func hasAnyPrefix(key string, prefixes []string) bool {
+	if len(prefixes) == 0 {
+		return true
+	}
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
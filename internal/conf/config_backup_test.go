@@ -0,0 +1,72 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conf
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackupRestoreCfgStorage(t *testing.T) {
+	IsTesting = true
+	defer func() {
+		IsTesting = false
+	}()
+	kvStore = nil
+	mockMemoryKVStore = nil
+	require.NoError(t, WriteCfgIntoKVStorage("connections", "mqtt", "c1", map[string]interface{}{"server": "tcp://a:1883"}))
+	require.NoError(t, WriteCfgIntoKVStorage("sources", "mqtt", "s1", map[string]interface{}{"qos": "1"}))
+	buf := &bytes.Buffer{}
+	n, err := BackupCfgStorage(buf, []string{"connections"})
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	archive := buf.Bytes()
+	b, err := ReadCfgBackup(bytes.NewReader(archive))
+	require.NoError(t, err)
+	require.Equal(t, CfgBackupVersion, b.Version)
+	require.Equal(t, []string{"connections"}, b.Prefixes)
+
+	// changes after the backup
+	require.NoError(t, WriteCfgIntoKVStorage("connections", "mqtt", "c1", map[string]interface{}{"server": "tcp://b:1883"}))
+	require.NoError(t, WriteCfgIntoKVStorage("connections", "mqtt", "c2", map[string]interface{}{"server": "tcp://c:1883"}))
+	require.NoError(t, DropCfgKeyFromStorage("sources", "mqtt", "s1"))
+
+	// merge keeps the new keys
+	n, err = RestoreCfgStorage(bytes.NewReader(archive), nil, false)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	got, err := GetCfgFromKVStorage("connections", "", "")
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	require.Equal(t, "tcp://a:1883", got["connections.mqtt.c1"]["server"])
+
+	// replace returns to the backup state of the prefixes
+	_, err = RestoreCfgStorage(bytes.NewReader(archive), nil, true)
+	require.NoError(t, err)
+	got, err = GetCfgFromKVStorage("connections", "", "")
+	require.NoError(t, err)
+	require.Equal(t, map[string]map[string]interface{}{
+		"connections.mqtt.c1": {"server": "tcp://a:1883"},
+	}, got)
+	// other prefixes are untouched
+	got, err = GetCfgFromKVStorage("sources", "", "")
+	require.NoError(t, err)
+	require.Len(t, got, 0)
+
+	_, err = RestoreCfgStorage(bytes.NewReader([]byte("invalid")), nil, true)
+	require.Error(t, err)
+}
@@ -0,0 +1,134 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conf
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// migrateBatchSize is the max number of the keys written in one batch, which is below the default max operations of
+// an etcd transaction
+const migrateBatchSize = 100
+
+// CfgMigration is the result of copying the config store to another backend
+type CfgMigration struct {
+	From     string   `json:"from"`
+	To       string   `json:"to"`
+	Copied   int      `json:"copied"`
+	Verified int      `json:"verified"`
+	Mismatch []string `json:"mismatch,omitempty"`
+}
+
+// MigrateCfgStorage copies the configs with any of the prefixes from the config store backend from to the backend to,
+// and then reads them back from the target to verify. Empty prefixes mean the whole storage. The existing keys in the
+// target are overwritten and other keys are kept. The running engine keeps using the configured backend, so switch
+// store.configStoreType and restart after the migration.
+func MigrateCfgStorage(from, to string, prefixes []string) (*CfgMigration, error) {
+	if from == "" {
+		from = ConfigStoreSqlite
+	}
+	if to == "" {
+		to = ConfigStoreSqlite
+	}
+	if from == to {
+		return nil, fmt.Errorf("the source and target config store are both %s", from)
+	}
+	src, err := openConfigStore(from)
+	if err != nil {
+		return nil, fmt.Errorf("open source config store %s error: %v", from, err)
+	}
+	dst, err := openConfigStore(to)
+	if err != nil {
+		return nil, fmt.Errorf("open target config store %s error: %v", to, err)
+	}
+	return migrateCfgStorage(src, dst, from, to, prefixes)
+}
+
+func migrateCfgStorage(src, dst ConfigStore, from, to string, prefixes []string) (*CfgMigration, error) {
+	data, err := readCfgByPrefixes(src, prefixes)
+	if err != nil {
+		return nil, fmt.Errorf("read source config store error: %v", err)
+	}
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	r := &CfgMigration{From: from, To: to}
+	for i := 0; i < len(keys); i += migrateBatchSize {
+		batch := keys[i:min(i+migrateBatchSize, len(keys))]
+		ops := make([]ConfigOp, 0, len(batch))
+		for _, k := range batch {
+			ops = append(ops, ConfigOp{Key: k, Props: data[k]})
+		}
+		if err := dst.Batch(ops); err != nil {
+			return r, fmt.Errorf("write target config store error: %v", err)
+		}
+		r.Copied += len(ops)
+	}
+	for i := 0; i < len(keys); i += migrateBatchSize {
+		batch := keys[i:min(i+migrateBatchSize, len(keys))]
+		got, err := dst.GetBatch(batch)
+		if err != nil {
+			return r, fmt.Errorf("verify target config store error: %v", err)
+		}
+		for _, k := range batch {
+			if sameCfg(data[k], got[k]) {
+				r.Verified++
+			} else {
+				r.Mismatch = append(r.Mismatch, k)
+			}
+		}
+	}
+	if len(r.Mismatch) > 0 {
+		return r, fmt.Errorf("%d config keys mismatch after migration", len(r.Mismatch))
+	}
+	return r, nil
+}
+
+func readCfgByPrefixes(s ConfigStore, prefixes []string) (map[string]map[string]interface{}, error) {
+	if len(prefixes) == 0 {
+		return s.GetByPrefix("")
+	}
+	r := make(map[string]map[string]interface{})
+	for _, prefix := range prefixes {
+		data, err := s.GetByPrefix(prefix)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range data {
+			r[k] = v
+		}
+	}
+	return r, nil
+}
+
+// sameCfg compares the props by the json form because the backends may decode the numbers into different types
+func sameCfg(a, b map[string]interface{}) bool {
+	if b == nil {
+		return false
+	}
+	ja, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	jb, err := json.Marshal(b)
+	if err != nil {
+		return false
+	}
+	return string(ja) == string(jb)
+}
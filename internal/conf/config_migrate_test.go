@@ -0,0 +1,68 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conf
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// lossyStore drops a prop on write to simulate a broken target
+type lossyStore struct {
+	*kvMemory
+}
+
+func (s lossyStore) Batch(ops []ConfigOp) error {
+	for i := range ops {
+		if ops[i].Key == "connections.mqtt.c2" {
+			ops[i].Props = map[string]interface{}{}
+		}
+	}
+	return s.kvMemory.Batch(ops)
+}
+
+func TestMigrateCfgStorage(t *testing.T) {
+	newStore := func() *kvMemory {
+		return &kvMemory{store: make(map[string]map[string]interface{})}
+	}
+	src := newStore()
+	require.NoError(t, src.Set("connections.mqtt.c1", map[string]interface{}{"server": "tcp://a:1883", "qos": 1}))
+	require.NoError(t, src.Set("connections.mqtt.c2", map[string]interface{}{"server": "tcp://b:1883"}))
+	require.NoError(t, src.Set("sources.mqtt.s1", map[string]interface{}{"a": "b"}))
+
+	dst := newStore()
+	require.NoError(t, dst.Set("connections.mqtt.c3", map[string]interface{}{"server": "tcp://c:1883"}))
+	r, err := migrateCfgStorage(src, dst, "sqlite", "etcd", []string{"connections"})
+	require.NoError(t, err)
+	require.Equal(t, &CfgMigration{From: "sqlite", To: "etcd", Copied: 2, Verified: 2}, r)
+	got, err := dst.GetByPrefix("")
+	require.NoError(t, err)
+	require.Len(t, got, 3)
+	require.Equal(t, "tcp://a:1883", got["connections.mqtt.c1"]["server"])
+
+	// numbers decoded as float64 by the target are the same
+	require.True(t, sameCfg(src.store["connections.mqtt.c1"], map[string]interface{}{"server": "tcp://a:1883", "qos": float64(1)}))
+	require.False(t, sameCfg(src.store["connections.mqtt.c1"], nil))
+
+	r, err = migrateCfgStorage(src, lossyStore{newStore()}, "sqlite", "redis", nil)
+	require.Error(t, err)
+	require.Equal(t, 3, r.Copied)
+	require.Equal(t, 2, r.Verified)
+	require.Equal(t, []string{"connections.mqtt.c2"}, r.Mismatch)
+
+	_, err = MigrateCfgStorage("", "sqlite", nil)
+	require.EqualError(t, err, "the source and target config store are both sqlite")
+}
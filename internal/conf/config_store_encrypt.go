@@ -0,0 +1,185 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conf
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// encryptedValueKey is the only key of the stored props when the value is encrypted
+const encryptedValueKey = "$encrypted"
+
+const (
+	KeyProviderEnv  = "env"
+	KeyProviderFile = "file"
+)
+
+// KeyProvider returns the AES key to encrypt the config store values. The key must be 16, 24 or 32 bytes.
+type KeyProvider func() ([]byte, error)
+
+var keyProviders = map[string]KeyProvider{
+	KeyProviderEnv: func() ([]byte, error) {
+		name := Config.Store.Encryption.KeyEnv
+		v := os.Getenv(name)
+		if v == "" {
+			return nil, fmt.Errorf("encryption key env %s is not set", name)
+		}
+		return base64.StdEncoding.DecodeString(v)
+	},
+	KeyProviderFile: func() ([]byte, error) {
+		data, err := os.ReadFile(Config.Store.Encryption.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		return base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	},
+}
+
+// RegisterKeyProvider registers a key provider such as a KMS client
+func RegisterKeyProvider(name string, provider KeyProvider) {
+	keyProviders[name] = provider
+}
+
+// GetEncryptionKey returns the AES key from the key provider, such as for the encryption of other secrets
+func GetEncryptionKey(name string) ([]byte, error) {
+	return getEncryptionKey(name)
+}
+
+func getEncryptionKey(name string) ([]byte, error) {
+	if name == "" {
+		name = KeyProviderEnv
+	}
+	provider, ok := keyProviders[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown encryption key provider: %s", name)
+	}
+	return provider()
+}
+
+// encryptedConfigStore encrypts the values of the wrapped store with AES-GCM. The values written before
+// the encryption is enabled are still readable and will be encrypted when they are written again.
+type encryptedConfigStore struct {
+	ConfigStore
+	gcm cipher.AEAD
+}
+
+func newEncryptedConfigStore(s ConfigStore, key []byte) (*encryptedConfigStore, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &encryptedConfigStore{ConfigStore: s, gcm: gcm}, nil
+}
+
+func (s *encryptedConfigStore) Set(key string, v map[string]interface{}) error {
+	ev, err := s.encrypt(v)
+	if err != nil {
+		return err
+	}
+	return s.ConfigStore.Set(key, ev)
+}
+
+func (s *encryptedConfigStore) GetByPrefix(prefix string) (map[string]map[string]interface{}, error) {
+	r, err := s.ConfigStore.GetByPrefix(prefix)
+	if err != nil {
+		return nil, err
+	}
+	return s.decryptAll(r)
+}
+
+func (s *encryptedConfigStore) GetBatch(keys []string) (map[string]map[string]interface{}, error) {
+	r, err := s.ConfigStore.GetBatch(keys)
+	if err != nil {
+		return nil, err
+	}
+	return s.decryptAll(r)
+}
+
+func (s *encryptedConfigStore) Batch(ops []ConfigOp) error {
+	encOps := make([]ConfigOp, 0, len(ops))
+	for _, op := range ops {
+		if !op.Delete {
+			ev, err := s.encrypt(op.Props)
+			if err != nil {
+				return err
+			}
+			op.Props = ev
+		}
+		encOps = append(encOps, op)
+	}
+	return s.ConfigStore.Batch(encOps)
+}
+
+func (s *encryptedConfigStore) encrypt(v map[string]interface{}) (map[string]interface{}, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, s.gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	sealed := s.gcm.Seal(nonce, nonce, buf.Bytes(), nil)
+	return map[string]interface{}{encryptedValueKey: base64.StdEncoding.EncodeToString(sealed)}, nil
+}
+
+func (s *encryptedConfigStore) decrypt(v map[string]interface{}) (map[string]interface{}, error) {
+	str, ok := v[encryptedValueKey].(string)
+	if !ok || len(v) != 1 {
+		// written before the encryption is enabled
+		return v, nil
+	}
+	sealed, err := base64.StdEncoding.DecodeString(str)
+	if err != nil {
+		return nil, err
+	}
+	nonceSize := s.gcm.NonceSize()
+	if len(sealed) < nonceSize {
+		return nil, fmt.Errorf("encrypted value too short")
+	}
+	data, err := s.gcm.Open(nil, sealed[:nonceSize], sealed[nonceSize:], nil)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt config value failed: %v", err)
+	}
+	props := map[string]interface{}{}
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&props); err != nil {
+		return nil, err
+	}
+	return props, nil
+}
+
+func (s *encryptedConfigStore) decryptAll(r map[string]map[string]interface{}) (map[string]map[string]interface{}, error) {
+	for key, v := range r {
+		props, err := s.decrypt(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", key, err)
+		}
+		r[key] = props
+	}
+	return r, nil
+}
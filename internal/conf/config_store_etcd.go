@@ -0,0 +1,347 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conf
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lf-edge/ekuiper/v2/pkg/syncx"
+)
+
+const defaultEtcdTimeout = 5 * time.Second
+
+func init() {
+	RegisterConfigStore(ConfigStoreEtcd, func() (ConfigStore, error) {
+		c := Config.Store.Etcd
+		if len(c.Endpoints) == 0 {
+			return nil, fmt.Errorf("etcd endpoints are not set")
+		}
+		timeout := time.Duration(c.Timeout)
+		if timeout <= 0 {
+			timeout = defaultEtcdTimeout
+		}
+		return newEtcdConfigStore(c.Endpoints, c.Username, c.Password, c.Prefix, timeout), nil
+	})
+}
+
+// etcdConfigStore saves the configurations in etcd through its v3 JSON gateway so that no client library is required.
+// The endpoints are tried in order until one responds.
+type etcdConfigStore struct {
+	endpoints []string
+	username  string
+	password  string
+	prefix    string
+	client    *http.Client
+
+	mu    syncx.Mutex
+	token string
+}
+
+func newEtcdConfigStore(endpoints []string, username, password, prefix string, timeout time.Duration) *etcdConfigStore {
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	return &etcdConfigStore{
+		endpoints: endpoints,
+		username:  username,
+		password:  password,
+		prefix:    prefix,
+		client:    &http.Client{Timeout: timeout},
+	}
+}
+
+type etcdKeyValue struct {
+	Key   []byte `json:"key,omitempty"`
+	Value []byte `json:"value,omitempty"`
+}
+
+type etcdRangeRequest struct {
+	Key      []byte `json:"key"`
+	RangeEnd []byte `json:"range_end,omitempty"`
+}
+
+type etcdRangeResponse struct {
+	Kvs []etcdKeyValue `json:"kvs"`
+}
+
+func (s *etcdConfigStore) Set(key string, v map[string]interface{}) error {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return err
+	}
+	return s.call("/v3/kv/put", etcdKeyValue{Key: []byte(s.prefix + key), Value: buf.Bytes()}, nil)
+}
+
+func (s *etcdConfigStore) Delete(key string) error {
+	return s.call("/v3/kv/deleterange", etcdRangeRequest{Key: []byte(s.prefix + key)}, nil)
+}
+
+func (s *etcdConfigStore) GetByPrefix(prefix string) (map[string]map[string]interface{}, error) {
+	key := []byte(s.prefix + prefix)
+	resp := &etcdRangeResponse{}
+	if err := s.call("/v3/kv/range", etcdRangeRequest{Key: key, RangeEnd: prefixRangeEnd(key)}, resp); err != nil {
+		return nil, err
+	}
+	r := make(map[string]map[string]interface{}, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		props := map[string]interface{}{}
+		if err := gob.NewDecoder(bytes.NewReader(kv.Value)).Decode(&props); err != nil {
+			return nil, err
+		}
+		r[strings.TrimPrefix(string(kv.Key), s.prefix)] = props
+	}
+	return r, nil
+}
+
+type etcdRequestOp struct {
+	RequestPut         *etcdKeyValue     `json:"request_put,omitempty"`
+	RequestDeleteRange *etcdRangeRequest `json:"request_delete_range,omitempty"`
+	RequestRange       *etcdRangeRequest `json:"request_range,omitempty"`
+}
+
+type etcdCompare struct {
+	Key    []byte `json:"key"`
+	Target string `json:"target"`
+	Result string `json:"result"`
+	Value  []byte `json:"value,omitempty"`
+	// CreateRevision is an int64 which is encoded as string in the JSON gateway
+	CreateRevision string `json:"create_revision,omitempty"`
+}
+
+type etcdTxnRequest struct {
+	Compare []etcdCompare   `json:"compare,omitempty"`
+	Success []etcdRequestOp `json:"success"`
+}
+
+type etcdTxnResponse struct {
+	Succeeded bool `json:"succeeded"`
+	Responses []struct {
+		ResponseRange *etcdRangeResponse `json:"response_range"`
+	} `json:"responses"`
+}
+
+// GetBatch gets the keys in one transaction so that the values are from the same revision
+func (s *etcdConfigStore) GetBatch(keys []string) (map[string]map[string]interface{}, error) {
+	r := make(map[string]map[string]interface{}, len(keys))
+	if len(keys) == 0 {
+		return r, nil
+	}
+	txn := etcdTxnRequest{Success: make([]etcdRequestOp, 0, len(keys))}
+	for _, key := range keys {
+		txn.Success = append(txn.Success, etcdRequestOp{RequestRange: &etcdRangeRequest{Key: []byte(s.prefix + key)}})
+	}
+	resp := &etcdTxnResponse{}
+	if err := s.call("/v3/kv/txn", txn, resp); err != nil {
+		return nil, err
+	}
+	for _, res := range resp.Responses {
+		if res.ResponseRange == nil {
+			continue
+		}
+		for _, kv := range res.ResponseRange.Kvs {
+			props := map[string]interface{}{}
+			if err := gob.NewDecoder(bytes.NewReader(kv.Value)).Decode(&props); err != nil {
+				return nil, err
+			}
+			r[strings.TrimPrefix(string(kv.Key), s.prefix)] = props
+		}
+	}
+	return r, nil
+}
+
+// Batch applies the operations in one etcd transaction
+func (s *etcdConfigStore) Batch(ops []ConfigOp) error {
+	txn := etcdTxnRequest{Success: make([]etcdRequestOp, 0, len(ops))}
+	for _, op := range ops {
+		key := []byte(s.prefix + op.Key)
+		if op.Delete {
+			txn.Success = append(txn.Success, etcdRequestOp{RequestDeleteRange: &etcdRangeRequest{Key: key}})
+			continue
+		}
+		var buf bytes.Buffer
+		if err := gob.NewEncoder(&buf).Encode(op.Props); err != nil {
+			return err
+		}
+		txn.Success = append(txn.Success, etcdRequestOp{RequestPut: &etcdKeyValue{Key: key, Value: buf.Bytes()}})
+	}
+	return s.call("/v3/kv/txn", txn, nil)
+}
+
+// AcquireLease writes the lease with a compare-and-swap transaction so that only one node wins when the lease is
+// absent or expired
+func (s *etcdConfigStore) AcquireLease(key, owner string, ttl time.Duration) (string, error) {
+	fullKey := []byte(s.prefix + key)
+	resp := &etcdRangeResponse{}
+	if err := s.call("/v3/kv/range", etcdRangeRequest{Key: fullKey}, resp); err != nil {
+		return "", err
+	}
+	now := time.Now()
+	cmp := etcdCompare{Key: fullKey, Target: "CREATE", Result: "EQUAL", CreateRevision: "0"}
+	if len(resp.Kvs) > 0 {
+		cur := resp.Kvs[0].Value
+		if holder := parseLease(string(cur), now); holder != "" && holder != owner {
+			return holder, nil
+		}
+		cmp = etcdCompare{Key: fullKey, Target: "VALUE", Result: "EQUAL", Value: cur}
+	}
+	txn := etcdTxnRequest{
+		Compare: []etcdCompare{cmp},
+		Success: []etcdRequestOp{{RequestPut: &etcdKeyValue{Key: fullKey, Value: []byte(formatLease(owner, now.Add(ttl)))}}},
+	}
+	txnResp := &etcdTxnResponse{}
+	if err := s.call("/v3/kv/txn", txn, txnResp); err != nil {
+		return "", err
+	}
+	if !txnResp.Succeeded {
+		// another node took the lease in the meantime
+		return s.leaseHolder(fullKey)
+	}
+	return owner, nil
+}
+
+func (s *etcdConfigStore) ReleaseLease(key, owner string) error {
+	fullKey := []byte(s.prefix + key)
+	resp := &etcdRangeResponse{}
+	if err := s.call("/v3/kv/range", etcdRangeRequest{Key: fullKey}, resp); err != nil {
+		return err
+	}
+	if len(resp.Kvs) == 0 || parseLease(string(resp.Kvs[0].Value), time.Now()) != owner {
+		return nil
+	}
+	txn := etcdTxnRequest{
+		Compare: []etcdCompare{{Key: fullKey, Target: "VALUE", Result: "EQUAL", Value: resp.Kvs[0].Value}},
+		Success: []etcdRequestOp{{RequestDeleteRange: &etcdRangeRequest{Key: fullKey}}},
+	}
+	return s.call("/v3/kv/txn", txn, nil)
+}
+
+func (s *etcdConfigStore) leaseHolder(fullKey []byte) (string, error) {
+	resp := &etcdRangeResponse{}
+	if err := s.call("/v3/kv/range", etcdRangeRequest{Key: fullKey}, resp); err != nil {
+		return "", err
+	}
+	if len(resp.Kvs) == 0 {
+		return "", nil
+	}
+	return parseLease(string(resp.Kvs[0].Value), time.Now()), nil
+}
+
+// prefixRangeEnd returns the range end to get all the keys with the prefix
+func prefixRangeEnd(prefix []byte) []byte {
+	end := make([]byte, len(prefix))
+	copy(end, prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	// the prefix is empty or all 0xff, get all the keys
+	return []byte{0}
+}
+
+func (s *etcdConfigStore) call(path string, req any, resp any) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	var lastErr error
+	for _, endpoint := range s.endpoints {
+		lastErr = s.post(strings.TrimSuffix(endpoint, "/")+path, body, resp, true)
+		if lastErr == nil {
+			return nil
+		}
+	}
+	return lastErr
+}
+
+func (s *etcdConfigStore) post(url string, body []byte, resp any, retryAuth bool) error {
+	token, err := s.authToken(url)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+	r, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+	if r.StatusCode == http.StatusUnauthorized && retryAuth && s.username != "" {
+		// the token may expire, authenticate again
+		s.mu.Lock()
+		s.token = ""
+		s.mu.Unlock()
+		return s.post(url, body, resp, false)
+	}
+	if r.StatusCode != http.StatusOK {
+		return fmt.Errorf("etcd request %s failed with status %d: %s", url, r.StatusCode, string(data))
+	}
+	if resp != nil {
+		return json.Unmarshal(data, resp)
+	}
+	return nil
+}
+
+// authToken gets the auth token if the username is set. The token is cached until it is rejected.
+func (s *etcdConfigStore) authToken(url string) (string, error) {
+	if s.username == "" {
+		return "", nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token != "" {
+		return s.token, nil
+	}
+	body, err := json.Marshal(map[string]string{"name": s.username, "password": s.password})
+	if err != nil {
+		return "", err
+	}
+	authURL := url[:strings.Index(url, "/v3/")] + "/v3/auth/authenticate"
+	r, err := s.client.Post(authURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("etcd authenticate failed with status %d", r.StatusCode)
+	}
+	result := struct {
+		Token string `json:"token"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&result); err != nil {
+		return "", err
+	}
+	s.token = result.Token
+	return s.token, nil
+}
@@ -0,0 +1,164 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build redisdb || !core
+
+package conf
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+)
+
+const redisConfigKeyPrefix = "CONF:STORE:"
+
+func init() {
+	RegisterConfigStore(ConfigStoreRedis, func() (ConfigStore, error) {
+		c := Config.Store.Redis
+		return newRedisConfigStore(redis.NewClient(&redis.Options{
+			Addr:        cast.JoinHostPortInt(c.Host, c.Port),
+			Password:    c.Password,
+			DialTimeout: time.Duration(c.Timeout),
+		})), nil
+	})
+}
+
+// redisConfigStore saves the configurations in redis so that they can be shared by multiple nodes
+type redisConfigStore struct {
+	client *redis.Client
+}
+
+func newRedisConfigStore(client *redis.Client) *redisConfigStore {
+	return &redisConfigStore{client: client}
+}
+
+func (s *redisConfigStore) Set(key string, v map[string]interface{}) error {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return err
+	}
+	return s.client.Set(context.Background(), redisConfigKeyPrefix+key, buf.Bytes(), 0).Err()
+}
+
+func (s *redisConfigStore) Delete(key string) error {
+	return s.client.Del(context.Background(), redisConfigKeyPrefix+key).Err()
+}
+
+func (s *redisConfigStore) GetByPrefix(prefix string) (map[string]map[string]interface{}, error) {
+	ctx := context.Background()
+	r := make(map[string]map[string]interface{})
+	iter := s.client.Scan(ctx, 0, redisConfigKeyPrefix+escapeRedisPattern(prefix)+"*", 0).Iterator()
+	for iter.Next(ctx) {
+		fullKey := iter.Val()
+		val, err := s.client.Get(ctx, fullKey).Bytes()
+		if err == redis.Nil {
+			// deleted during the scan
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		props := map[string]interface{}{}
+		if err := gob.NewDecoder(bytes.NewReader(val)).Decode(&props); err != nil {
+			return nil, err
+		}
+		r[strings.TrimPrefix(fullKey, redisConfigKeyPrefix)] = props
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (s *redisConfigStore) GetBatch(keys []string) (map[string]map[string]interface{}, error) {
+	r := make(map[string]map[string]interface{}, len(keys))
+	if len(keys) == 0 {
+		return r, nil
+	}
+	fullKeys := make([]string, 0, len(keys))
+	for _, key := range keys {
+		fullKeys = append(fullKeys, redisConfigKeyPrefix+key)
+	}
+	values, err := s.client.MGet(context.Background(), fullKeys...).Result()
+	if err != nil {
+		return nil, err
+	}
+	for i, v := range values {
+		str, ok := v.(string)
+		if !ok {
+			continue
+		}
+		props := map[string]interface{}{}
+		if err := gob.NewDecoder(strings.NewReader(str)).Decode(&props); err != nil {
+			return nil, err
+		}
+		r[keys[i]] = props
+	}
+	return r, nil
+}
+
+// Batch applies the operations in a MULTI/EXEC transaction
+func (s *redisConfigStore) Batch(ops []ConfigOp) error {
+	_, err := s.client.TxPipelined(context.Background(), func(pipe redis.Pipeliner) error {
+		for _, op := range ops {
+			if op.Delete {
+				pipe.Del(context.Background(), redisConfigKeyPrefix+op.Key)
+				continue
+			}
+			var buf bytes.Buffer
+			if err := gob.NewEncoder(&buf).Encode(op.Props); err != nil {
+				return err
+			}
+			pipe.Set(context.Background(), redisConfigKeyPrefix+op.Key, buf.Bytes(), 0)
+		}
+		return nil
+	})
+	return err
+}
+
+func escapeRedisPattern(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`, `]`, `\]`).Replace(s)
+}
+
+// acquireLeaseScript sets the lease with expiration if it is absent or held by the owner, and returns the holder
+var acquireLeaseScript = redis.NewScript(`
+local cur = redis.call('GET', KEYS[1])
+if cur == false or cur == ARGV[1] then
+  redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+  return ARGV[1]
+end
+return cur
+`)
+
+var releaseLeaseScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+  return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+func (s *redisConfigStore) AcquireLease(key, owner string, ttl time.Duration) (string, error) {
+	return acquireLeaseScript.Run(context.Background(), s.client, []string{redisConfigKeyPrefix + key}, owner, ttl.Milliseconds()).Text()
+}
+
+func (s *redisConfigStore) ReleaseLease(key, owner string) error {
+	return releaseLeaseScript.Run(context.Background(), s.client, []string{redisConfigKeyPrefix + key}, owner).Err()
+}
@@ -0,0 +1,78 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build redisdb || !core
+
+package conf
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisConfigStore(t *testing.T) {
+	server, err := miniredis.Run()
+	require.NoError(t, err)
+	defer server.Close()
+	s := newRedisConfigStore(redis.NewClient(&redis.Options{Addr: server.Addr()}))
+	require.NoError(t, s.Set("connections.mqtt.c1", map[string]interface{}{"server": "tcp://a:1883"}))
+	require.NoError(t, s.Set("connections.mqtt.c2", map[string]interface{}{"server": "tcp://b:1883"}))
+	require.NoError(t, s.Set("sources.mqtt.c1", map[string]interface{}{"a": "b"}))
+	got, err := s.GetByPrefix("connections.")
+	require.NoError(t, err)
+	require.Equal(t, map[string]map[string]interface{}{
+		"connections.mqtt.c1": {"server": "tcp://a:1883"},
+		"connections.mqtt.c2": {"server": "tcp://b:1883"},
+	}, got)
+	require.NoError(t, s.Delete("connections.mqtt.c1"))
+	got, err = s.GetByPrefix("")
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	require.NoError(t, s.Batch([]ConfigOp{
+		{Key: "connections.mqtt.c3", Props: map[string]interface{}{"a": "c"}},
+		{Key: "connections.mqtt.c2", Delete: true},
+	}))
+	got, err = s.GetBatch([]string{"connections.mqtt.c2", "connections.mqtt.c3"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]map[string]interface{}{
+		"connections.mqtt.c3": {"a": "c"},
+	}, got)
+}
+
+func TestRedisLease(t *testing.T) {
+	server, err := miniredis.Run()
+	require.NoError(t, err)
+	defer server.Close()
+	s := newRedisConfigStore(redis.NewClient(&redis.Options{Addr: server.Addr()}))
+	holder, err := s.AcquireLease("l1", "n1", time.Second)
+	require.NoError(t, err)
+	require.Equal(t, "n1", holder)
+	holder, err = s.AcquireLease("l1", "n2", time.Second)
+	require.NoError(t, err)
+	require.Equal(t, "n1", holder)
+	// not held by n2, no effect
+	require.NoError(t, s.ReleaseLease("l1", "n2"))
+	server.FastForward(2 * time.Second)
+	holder, err = s.AcquireLease("l1", "n2", time.Second)
+	require.NoError(t, err)
+	require.Equal(t, "n2", holder)
+	require.NoError(t, s.ReleaseLease("l1", "n2"))
+	holder, err = s.AcquireLease("l1", "n1", time.Second)
+	require.NoError(t, err)
+	require.Equal(t, "n1", holder)
+}
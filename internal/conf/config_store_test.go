@@ -0,0 +1,256 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conf
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/store"
+)
+
+// fakeEtcd implements the etcd v3 JSON gateway kv APIs used by the config store
+type fakeEtcd struct {
+	kvs map[string][]byte
+}
+
+func (f *fakeEtcd) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	req := &etcdRangeRequest{}
+	kv := &etcdKeyValue{}
+	switch r.URL.Path {
+	case "/v3/kv/put":
+		_ = json.NewDecoder(r.Body).Decode(kv)
+		f.kvs[string(kv.Key)] = kv.Value
+	case "/v3/kv/deleterange":
+		_ = json.NewDecoder(r.Body).Decode(req)
+		delete(f.kvs, string(req.Key))
+	case "/v3/kv/range":
+		_ = json.NewDecoder(r.Body).Decode(req)
+		_ = json.NewEncoder(w).Encode(f.rangeKeys(req))
+		return
+	case "/v3/kv/txn":
+		txn := &etcdTxnRequest{}
+		_ = json.NewDecoder(r.Body).Decode(txn)
+		resp := map[string][]map[string]any{"responses": {}}
+		for _, op := range txn.Success {
+			switch {
+			case op.RequestPut != nil:
+				f.kvs[string(op.RequestPut.Key)] = op.RequestPut.Value
+			case op.RequestDeleteRange != nil:
+				delete(f.kvs, string(op.RequestDeleteRange.Key))
+			case op.RequestRange != nil:
+				resp["responses"] = append(resp["responses"], map[string]any{"response_range": f.rangeKeys(op.RequestRange)})
+			}
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	_, _ = w.Write([]byte("{}"))
+}
+
+func (f *fakeEtcd) rangeKeys(req *etcdRangeRequest) *etcdRangeResponse {
+	resp := &etcdRangeResponse{}
+	keys := make([]string, 0, len(f.kvs))
+	for k := range f.kvs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if k == string(req.Key) || (len(req.RangeEnd) > 0 && k >= string(req.Key) && k < string(req.RangeEnd)) {
+			resp.Kvs = append(resp.Kvs, etcdKeyValue{Key: []byte(k), Value: f.kvs[k]})
+		}
+	}
+	return resp
+}
+
+func TestEtcdConfigStore(t *testing.T) {
+	fake := &fakeEtcd{kvs: map[string][]byte{}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	// the first endpoint is down
+	s := newEtcdConfigStore([]string{"http://127.0.0.1:1", server.URL}, "", "", "ekuiper", time.Second)
+	require.NoError(t, s.Set("connections.mqtt.c1", map[string]interface{}{"server": "tcp://a:1883", "qos": 1}))
+	require.NoError(t, s.Set("connections.mqtt.c2", map[string]interface{}{"server": "tcp://b:1883"}))
+	require.NoError(t, s.Set("sources.mqtt.c1", map[string]interface{}{"a": "b"}))
+	_, ok := fake.kvs["ekuiper/connections.mqtt.c1"]
+	require.True(t, ok)
+	got, err := s.GetByPrefix("connections.mqtt")
+	require.NoError(t, err)
+	require.Equal(t, map[string]map[string]interface{}{
+		"connections.mqtt.c1": {"server": "tcp://a:1883", "qos": 1},
+		"connections.mqtt.c2": {"server": "tcp://b:1883"},
+	}, got)
+	require.NoError(t, s.Delete("connections.mqtt.c1"))
+	got, err = s.GetByPrefix("connections")
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	require.NoError(t, s.Batch([]ConfigOp{
+		{Key: "connections.mqtt.c3", Props: map[string]interface{}{"a": "c"}},
+		{Key: "connections.mqtt.c2", Delete: true},
+	}))
+	got, err = s.GetBatch([]string{"connections.mqtt.c2", "connections.mqtt.c3", "sources.mqtt.c1"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]map[string]interface{}{
+		"connections.mqtt.c3": {"a": "c"},
+		"sources.mqtt.c1":     {"a": "b"},
+	}, got)
+}
+
+func TestBatchCfgInKVStorage(t *testing.T) {
+	dataDir, err := GetDataLoc()
+	require.NoError(t, err)
+	require.NoError(t, store.SetupDefault(dataDir))
+	kvStore = nil
+	defer func() {
+		kvStore = nil
+	}()
+	require.NoError(t, WriteCfgIntoKVStorage("connections", "mqtt", "b1", map[string]interface{}{"a": 1}))
+	require.NoError(t, BatchCfgInKVStorage([]ConfigOp{
+		NewCfgSetOp("connections", "mqtt", "b2", map[string]interface{}{"a": 2}),
+		NewCfgSetOp("sources", "mqtt", "b2", map[string]interface{}{"b": 2}),
+		NewCfgDeleteOp("connections", "mqtt", "b1"),
+		NewCfgDeleteOp("connections", "mqtt", "missing"),
+	}))
+	got, err := GetCfgBatchFromKVStorage([]string{"connections.mqtt.b1", "connections.mqtt.b2", "sources.mqtt.b2"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]map[string]interface{}{
+		"connections.mqtt.b2": {"a": 2},
+		"sources.mqtt.b2":     {"b": 2},
+	}, got)
+	require.NoError(t, BatchCfgInKVStorage([]ConfigOp{
+		NewCfgDeleteOp("connections", "mqtt", "b2"),
+		NewCfgDeleteOp("sources", "mqtt", "b2"),
+	}))
+}
+
+func TestPrefixRangeEnd(t *testing.T) {
+	require.Equal(t, []byte("ab"), prefixRangeEnd([]byte("aa")))
+	require.Equal(t, []byte("b"), prefixRangeEnd([]byte{'a', 0xff}))
+	require.Equal(t, []byte{0}, prefixRangeEnd(nil))
+}
+
+func TestNewConfigStore(t *testing.T) {
+	_, err := newConfigStore("unknown")
+	require.EqualError(t, err, "unknown config store type: unknown")
+}
+
+func TestWatchCfg(t *testing.T) {
+	IsTesting = true
+	defer func() {
+		IsTesting = false
+	}()
+	kvStore = nil
+	require.NoError(t, saveCfgKeyToKV("connections.mqtt.c1", map[string]interface{}{"a": 1}))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch, err := WatchCfg(ctx, "connections.", time.Hour)
+	require.NoError(t, err)
+	// local writes are notified immediately
+	require.NoError(t, saveCfgKeyToKV("connections.mqtt.c2", map[string]interface{}{"a": 1}))
+	require.Equal(t, ConfigEvent{Type: ConfigEventAdd, Key: "connections.mqtt.c2", Props: map[string]interface{}{"a": 1}}, <-ch)
+	require.NoError(t, saveCfgKeyToKV("connections.mqtt.c1", map[string]interface{}{"a": 2}))
+	require.Equal(t, ConfigEvent{Type: ConfigEventUpdate, Key: "connections.mqtt.c1", Props: map[string]interface{}{"a": 2}}, <-ch)
+	require.NoError(t, delCfgKeyInStorage("connections.mqtt.c2"))
+	require.Equal(t, ConfigEvent{Type: ConfigEventDelete, Key: "connections.mqtt.c2"}, <-ch)
+	cancel()
+	_, ok := <-ch
+	require.False(t, ok)
+}
+
+func TestEncryptedConfigStore(t *testing.T) {
+	plain := &kvMemory{store: map[string]map[string]interface{}{
+		"connections.mqtt.old": {"password": "plain"},
+	}}
+	key := []byte("0123456789abcdef0123456789abcdef")
+	s, err := newEncryptedConfigStore(plain, key)
+	require.NoError(t, err)
+	require.NoError(t, s.Set("connections.mqtt.c1", map[string]interface{}{"password": "secret", "port": 1883}))
+	require.NoError(t, s.Batch([]ConfigOp{{Key: "connections.mqtt.c2", Props: map[string]interface{}{"password": "secret2"}}}))
+	// stored values are encrypted
+	for _, k := range []string{"connections.mqtt.c1", "connections.mqtt.c2"} {
+		raw := plain.store[k]
+		require.Len(t, raw, 1)
+		require.NotContains(t, raw[encryptedValueKey], "secret")
+	}
+	got, err := s.GetByPrefix("connections.")
+	require.NoError(t, err)
+	require.Equal(t, map[string]map[string]interface{}{
+		"connections.mqtt.old": {"password": "plain"},
+		"connections.mqtt.c1":  {"password": "secret", "port": 1883},
+		"connections.mqtt.c2":  {"password": "secret2"},
+	}, got)
+	got, err = s.GetBatch([]string{"connections.mqtt.c1"})
+	require.NoError(t, err)
+	require.Equal(t, "secret", got["connections.mqtt.c1"]["password"])
+	// wrong key can't decrypt
+	other, err := newEncryptedConfigStore(plain, []byte("fedcba9876543210fedcba9876543210"))
+	require.NoError(t, err)
+	_, err = other.GetBatch([]string{"connections.mqtt.c1"})
+	require.Error(t, err)
+}
+
+func TestGetEncryptionKey(t *testing.T) {
+	InitConf()
+	t.Setenv(Config.Store.Encryption.KeyEnv, "MDEyMzQ1Njc4OWFiY2RlZg==")
+	key, err := getEncryptionKey("")
+	require.NoError(t, err)
+	require.Equal(t, []byte("0123456789abcdef"), key)
+	_, err = getEncryptionKey("kms")
+	require.EqualError(t, err, "unknown encryption key provider: kms")
+	RegisterKeyProvider("kms", func() ([]byte, error) {
+		return []byte("0123456789abcdef"), nil
+	})
+	defer delete(keyProviders, "kms")
+	key, err = getEncryptionKey("kms")
+	require.NoError(t, err)
+	require.Equal(t, []byte("0123456789abcdef"), key)
+}
+
+func TestLease(t *testing.T) {
+	IsTesting = true
+	holder, err := AcquireLease("test", "n1", 100*time.Millisecond)
+	require.NoError(t, err)
+	require.Equal(t, "n1", holder)
+	// renew
+	holder, err = AcquireLease("test", "n1", 100*time.Millisecond)
+	require.NoError(t, err)
+	require.Equal(t, "n1", holder)
+	holder, err = AcquireLease("test", "n2", 100*time.Millisecond)
+	require.NoError(t, err)
+	require.Equal(t, "n1", holder)
+	time.Sleep(150 * time.Millisecond)
+	holder, err = AcquireLease("test", "n2", time.Second)
+	require.NoError(t, err)
+	require.Equal(t, "n2", holder)
+	require.NoError(t, ReleaseLease("test", "n1"))
+	holder, err = AcquireLease("test", "n1", time.Second)
+	require.NoError(t, err)
+	require.Equal(t, "n2", holder)
+	require.NoError(t, ReleaseLease("test", "n2"))
+	holder, err = AcquireLease("test", "n1", time.Second)
+	require.NoError(t, err)
+	require.Equal(t, "n1", holder)
+	require.NoError(t, ReleaseLease("test", "n1"))
+}
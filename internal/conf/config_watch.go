@@ -0,0 +1,128 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conf
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"github.com/lf-edge/ekuiper/v2/pkg/syncx"
+)
+
+type ConfigEventType string
+
+const (
+	ConfigEventAdd    ConfigEventType = "add"
+	ConfigEventUpdate ConfigEventType = "update"
+	ConfigEventDelete ConfigEventType = "delete"
+)
+
+// ConfigEvent is a change of a config key. Props is nil for the delete event.
+type ConfigEvent struct {
+	Type  ConfigEventType
+	Key   string
+	Props map[string]interface{}
+}
+
+var (
+	watchMu      syncx.Mutex
+	watchTrigger = make(map[chan struct{}]struct{})
+)
+
+// notifyCfgChanged wakes up all the watchers to check the storage immediately after a local write
+func notifyCfgChanged() {
+	watchMu.Lock()
+	defer watchMu.Unlock()
+	for ch := range watchTrigger {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// WatchCfg emits the changes of the keys with the prefix. The storage is checked when the local node writes it
+// and periodically by the interval to find out the changes made by other nodes or external tools.
+// The channel is closed when the context is done.
+func WatchCfg(ctx context.Context, prefix string, interval time.Duration) (<-chan ConfigEvent, error) {
+	snapshot, err := getCfgByPrefix(prefix)
+	if err != nil {
+		return nil, err
+	}
+	trigger := make(chan struct{}, 1)
+	watchMu.Lock()
+	watchTrigger[trigger] = struct{}{}
+	watchMu.Unlock()
+	ch := make(chan ConfigEvent)
+	go func() {
+		defer func() {
+			watchMu.Lock()
+			delete(watchTrigger, trigger)
+			watchMu.Unlock()
+			close(ch)
+		}()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-trigger:
+			}
+			current, err := getCfgByPrefix(prefix)
+			if err != nil {
+				Log.Warnf("watch config %s error: %v", prefix, err)
+				continue
+			}
+			for _, ev := range diffCfg(snapshot, current) {
+				select {
+				case ch <- ev:
+				case <-ctx.Done():
+					return
+				}
+			}
+			snapshot = current
+		}
+	}()
+	return ch, nil
+}
+
+func getCfgByPrefix(prefix string) (map[string]map[string]interface{}, error) {
+	kvStorage, err := getKVStorage()
+	if err != nil {
+		return nil, err
+	}
+	return kvStorage.GetByPrefix(prefix)
+}
+
+func diffCfg(old, current map[string]map[string]interface{}) []ConfigEvent {
+	var events []ConfigEvent
+	for key, props := range current {
+		oldProps, ok := old[key]
+		if !ok {
+			events = append(events, ConfigEvent{Type: ConfigEventAdd, Key: key, Props: props})
+		} else if !reflect.DeepEqual(oldProps, props) {
+			events = append(events, ConfigEvent{Type: ConfigEventUpdate, Key: key, Props: props})
+		}
+	}
+	for key := range old {
+		if _, ok := current[key]; !ok {
+			events = append(events, ConfigEvent{Type: ConfigEventDelete, Key: key})
+		}
+	}
+	return events
+}
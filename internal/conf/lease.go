@@ -0,0 +1,112 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conf
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrLeaseUnsupported is returned when the config store backend cannot hold leases such as the node-local sqlite store
+var ErrLeaseUnsupported = errors.New("the config store does not support lease")
+
+const leaseKeyPrefix = "$$lease."
+
+// LeaseStore is implemented by the config stores shared by multiple nodes. A lease is held by one owner until it
+// expires or is released, which is the base of the leader election.
+type LeaseStore interface {
+	// AcquireLease acquires or renews the lease for the owner. It returns the current holder of the lease which is
+	// the owner itself if succeeded.
+	AcquireLease(key, owner string, ttl time.Duration) (string, error)
+	// ReleaseLease releases the lease if it is held by the owner
+	ReleaseLease(key, owner string) error
+}
+
+// AcquireLease acquires or renews the named lease in the config store and returns the holder
+func AcquireLease(name, owner string, ttl time.Duration) (string, error) {
+	s, err := getLeaseStore()
+	if err != nil {
+		return "", err
+	}
+	return s.AcquireLease(leaseKeyPrefix+name, owner, ttl)
+}
+
+// ReleaseLease releases the named lease if it is held by the owner so that other nodes can take over immediately
+func ReleaseLease(name, owner string) error {
+	s, err := getLeaseStore()
+	if err != nil {
+		return err
+	}
+	return s.ReleaseLease(leaseKeyPrefix+name, owner)
+}
+
+func getLeaseStore() (LeaseStore, error) {
+	s, err := getKVStorage()
+	if err != nil {
+		return nil, err
+	}
+	if es, ok := s.(*encryptedConfigStore); ok {
+		s = es.ConfigStore
+	}
+	ls, ok := s.(LeaseStore)
+	if !ok {
+		return nil, ErrLeaseUnsupported
+	}
+	return ls, nil
+}
+
+// formatLease encodes the lease value for the backends without native expiration
+func formatLease(owner string, expire time.Time) string {
+	return fmt.Sprintf("%s@%d", owner, expire.UnixMilli())
+}
+
+// parseLease decodes the lease value. The owner is empty if the lease is expired.
+func parseLease(v string, now time.Time) string {
+	i := strings.LastIndex(v, "@")
+	if i < 0 {
+		return ""
+	}
+	expire, err := strconv.ParseInt(v[i+1:], 10, 64)
+	if err != nil || expire <= now.UnixMilli() {
+		return ""
+	}
+	return v[:i]
+}
+
+func (m *kvMemory) AcquireLease(key, owner string, ttl time.Duration) (string, error) {
+	m.leaseMu.Lock()
+	defer m.leaseMu.Unlock()
+	if m.leases == nil {
+		m.leases = make(map[string]string)
+	}
+	now := time.Now()
+	if holder := parseLease(m.leases[key], now); holder != "" && holder != owner {
+		return holder, nil
+	}
+	m.leases[key] = formatLease(owner, now.Add(ttl))
+	return owner, nil
+}
+
+func (m *kvMemory) ReleaseLease(key, owner string) error {
+	m.leaseMu.Lock()
+	defer m.leaseMu.Unlock()
+	if parseLease(m.leases[key], time.Now()) == owner {
+		delete(m.leases, key)
+	}
+	return nil
+}
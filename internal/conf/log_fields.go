@@ -0,0 +1,73 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conf
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+// The standard structured log field keys to correlate the logs in the log backends
+const (
+	LogFieldRule       = "rule"
+	LogFieldConnection = "connection"
+	LogFieldTraceID    = "trace_id"
+	LogFieldSpanID     = "span_id"
+)
+
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+// LogWithFields returns the logger with the structured fields. The kvs are key-value pairs.
+// The non-string keys are formatted and the value of the odd key is empty.
+func LogWithFields(l logrus.FieldLogger, kvs ...any) *logrus.Entry {
+	return l.WithFields(ToLogFields(kvs...))
+}
+
+// ToLogFields converts the key-value pairs to logrus fields
+func ToLogFields(kvs ...any) logrus.Fields {
+	fields := make(logrus.Fields, (len(kvs)+1)/2)
+	for i := 0; i < len(kvs); i += 2 {
+		k, ok := kvs[i].(string)
+		if !ok {
+			k = fmt.Sprint(kvs[i])
+		}
+		if i+1 < len(kvs) {
+			fields[k] = kvs[i+1]
+		} else {
+			fields[k] = ""
+		}
+	}
+	return fields
+}
+
+// SetLogFormat sets the log format to text or json. The json format is suitable for the log backends to parse the fields.
+func SetLogFormat(format string, disableTimestamp bool) {
+	switch format {
+	case LogFormatJSON:
+		Log.SetFormatter(&logrus.JSONFormatter{
+			DisableTimestamp: disableTimestamp,
+		})
+	default:
+		Log.SetFormatter(&logrus.TextFormatter{
+			DisableColors:    true,
+			FullTimestamp:    true,
+			DisableTimestamp: disableTimestamp,
+		})
+	}
+}
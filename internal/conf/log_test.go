@@ -15,12 +15,15 @@
 package conf
 
 import (
+	"bytes"
+	"encoding/json"
 	"os"
 	"path/filepath"
 	"runtime"
 	"testing"
 	"time"
 
+	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/require"
 )
 
@@ -140,3 +143,29 @@ func TestValidateLogSymlink(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, "nonexistent.log", target)
 }
+
+func TestLogWithFields(t *testing.T) {
+	require.Equal(t, logrus.Fields{"rule": "r1", "connection": "c1", "1": 2, "odd": ""}, ToLogFields(LogFieldRule, "r1", LogFieldConnection, "c1", 1, 2, "odd"))
+
+	out := Log.Out
+	formatter := Log.Formatter
+	defer func() {
+		Log.SetOutput(out)
+		Log.SetFormatter(formatter)
+	}()
+	var buf bytes.Buffer
+	Log.SetOutput(&buf)
+	SetLogFormat(LogFormatJSON, true)
+	LogWithFields(Log, LogFieldConnection, "c1", LogFieldTraceID, "t1").Info("hello")
+	m := map[string]any{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &m))
+	require.Equal(t, "c1", m["connection"])
+	require.Equal(t, "t1", m["trace_id"])
+	require.Equal(t, "hello", m["msg"])
+	require.NotContains(t, m, "time")
+
+	buf.Reset()
+	SetLogFormat(LogFormatText, true)
+	LogWithFields(Log, LogFieldConnection, "c1").Info("hello")
+	require.Contains(t, buf.String(), "connection=c1")
+}
@@ -0,0 +1,216 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conf
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+	"github.com/lf-edge/ekuiper/v2/pkg/model"
+	"github.com/lf-edge/ekuiper/v2/pkg/syncx"
+)
+
+// ReloadReport is the result of reloading the config file. The fields are in the form of section.field such as
+// connection.retry.
+type ReloadReport struct {
+	Applied         []string `json:"applied"`
+	RequiresRestart []string `json:"requiresRestart"`
+	Errors          []string `json:"errors,omitempty"`
+}
+
+// Reloader applies the changes of a section of the reloaded config to the running config old. It copies the applied
+// fields into old and returns them, along with the changed fields which require restart.
+type Reloader func(old, c *model.KuiperConf) (applied, restart []string, err error)
+
+var (
+	reloaders  = map[string]Reloader{"basic": reloadBasic}
+	reloadLock syncx.Mutex
+)
+
+// RegisterReloader registers the reloader of the section by its yaml name
+func RegisterReloader(section string, r Reloader) {
+	reloaders[section] = r
+}
+
+// ReloadConf reloads the config file and applies the changes by the registered reloaders. The changes of the other
+// sections require restart and are not applied.
+func ReloadConf() (*ReloadReport, error) {
+	reloadLock.Lock()
+	defer reloadLock.Unlock()
+	c, err := loadConf()
+	if err != nil {
+		return nil, err
+	}
+	r := &ReloadReport{Applied: []string{}, RequiresRestart: []string{}}
+	ov, nv := reflect.ValueOf(Config).Elem(), reflect.ValueOf(c).Elem()
+	for i := 0; i < ov.NumField(); i++ {
+		f := ov.Type().Field(i)
+		// derived from basic.aesKey
+		if f.Name == "AesKey" {
+			continue
+		}
+		if reflect.DeepEqual(ov.Field(i).Interface(), nv.Field(i).Interface()) {
+			continue
+		}
+		section := yamlName(f)
+		reloader, ok := reloaders[section]
+		if !ok {
+			r.RequiresRestart = append(r.RequiresRestart, ChangedFields(section, ov.Field(i).Interface(), nv.Field(i).Interface())...)
+			continue
+		}
+		applied, restart, err := reloader(Config, c)
+		r.Applied = append(r.Applied, applied...)
+		r.RequiresRestart = append(r.RequiresRestart, restart...)
+		if err != nil {
+			r.Errors = append(r.Errors, err.Error())
+		}
+	}
+	sort.Strings(r.Applied)
+	sort.Strings(r.RequiresRestart)
+	Log.Infof("config reloaded, applied: %v, requires restart: %v, errors: %v", r.Applied, r.RequiresRestart, r.Errors)
+	return r, nil
+}
+
+// ChangedFields returns the yaml names of the changed fields of the struct with the prefix. If the values are not
+// structs, it returns the prefix if changed.
+func ChangedFields(prefix string, old, c any) []string {
+	ov, nv := reflect.Indirect(reflect.ValueOf(old)), reflect.Indirect(reflect.ValueOf(c))
+	if !ov.IsValid() || !nv.IsValid() || ov.Kind() != reflect.Struct || ov.Type() != nv.Type() {
+		if reflect.DeepEqual(old, c) {
+			return nil
+		}
+		return []string{prefix}
+	}
+	var result []string
+	for i := 0; i < ov.NumField(); i++ {
+		f := ov.Type().Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		if !reflect.DeepEqual(ov.Field(i).Interface(), nv.Field(i).Interface()) {
+			result = append(result, prefix+"."+yamlName(f))
+		}
+	}
+	return result
+}
+
+// yamlName returns the key of the field in the config file
+func yamlName(f reflect.StructField) string {
+	if tag, ok := f.Tag.Lookup("yaml"); ok {
+		if name, _, _ := strings.Cut(tag, ","); name != "" && name != "-" {
+			return name
+		}
+	}
+	return strings.ToLower(f.Name)
+}
+
+// reloadBasic applies the log settings and the timezone like the config PATCH API. Other basic settings require restart.
+func reloadBasic(old, c *model.KuiperConf) (applied, restart []string, err error) {
+	var resetLevel, resetLog bool
+	for _, f := range ChangedFields("basic", old.Basic, c.Basic) {
+		switch f {
+		case "basic.logLevel":
+			old.Basic.LogLevel = c.Basic.LogLevel
+			resetLevel = true
+		case "basic.debug":
+			old.Basic.Debug = c.Basic.Debug
+			resetLevel = true
+		case "basic.consoleLog", "basic.fileLog":
+			// applied together below
+			resetLog = true
+			continue
+		case "basic.timezone":
+			if e := cast.SetTimeZone(c.Basic.TimeZone); e != nil {
+				err = e
+				continue
+			}
+			old.Basic.TimeZone = c.Basic.TimeZone
+		default:
+			restart = append(restart, f)
+			continue
+		}
+		applied = append(applied, f)
+	}
+	if resetLevel {
+		SetLogLevel(old.Basic.LogLevel, old.Basic.Debug)
+	}
+	if resetLog {
+		if e := SetConsoleAndFileLog(c.Basic.ConsoleLog, c.Basic.FileLog); e != nil {
+			return applied, restart, e
+		}
+		if old.Basic.ConsoleLog != c.Basic.ConsoleLog {
+			applied = append(applied, "basic.consoleLog")
+		}
+		if old.Basic.FileLog != c.Basic.FileLog {
+			applied = append(applied, "basic.fileLog")
+		}
+		old.Basic.ConsoleLog, old.Basic.FileLog = c.Basic.ConsoleLog, c.Basic.FileLog
+	}
+	return applied, restart, err
+}
+
+// WatchConfFile reloads the config file when it is changed until the context is done. The directory is watched
+// because editors usually replace the file instead of writing it. The events are debounced by the delay.
+func WatchConfFile(ctx context.Context, delay time.Duration) error {
+	cpath, err := GetConfLoc()
+	if err != nil {
+		return err
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	if err := watcher.Add(cpath); err != nil {
+		_ = watcher.Close()
+		return err
+	}
+	go func() {
+		defer watcher.Close()
+		timer := time.NewTimer(delay)
+		timer.Stop()
+		defer timer.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Base(event.Name) != ConfFileName || event.Op&(fsnotify.Write|fsnotify.Create) == 0 {
+					continue
+				}
+				timer.Reset(delay)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				Log.Warnf("watch config file error: %v", err)
+			case <-timer.C:
+				if _, err := ReloadConf(); err != nil {
+					Log.Errorf("reload config file error: %v", err)
+				}
+			}
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,56 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conf
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/pkg/model"
+)
+
+func TestChangedFields(t *testing.T) {
+	o := model.KuiperConf{}
+	n := model.KuiperConf{}
+	require.Empty(t, ChangedFields("connection", o.Connection, n.Connection))
+	n.Connection.Retry.Policy = "constant"
+	n.Connection.OperationTimeout = 1
+	require.Equal(t, []string{"connection.operationTimeout", "connection.retry"}, ChangedFields("connection", o.Connection, n.Connection))
+	require.Equal(t, []string{"a"}, ChangedFields("a", 1, 2))
+	require.Empty(t, ChangedFields("a", "b", "b"))
+}
+
+func TestReloadConf(t *testing.T) {
+	SetupEnv()
+	InitConf()
+	old := Config
+	defer func() {
+		Config = old
+	}()
+	c, err := loadConf()
+	require.NoError(t, err)
+	Config = c
+	Config.Basic.Debug = !Config.Basic.Debug
+	Config.Basic.RestPort = 1
+	Config.Connection.Retry.Policy = "constant"
+	r, err := ReloadConf()
+	require.NoError(t, err)
+	require.Equal(t, []string{"basic.debug"}, r.Applied)
+	require.Equal(t, []string{"basic.restPort", "connection.retry"}, r.RequiresRestart)
+	require.Equal(t, old.Basic.Debug, Config.Basic.Debug)
+	require.Equal(t, 1, Config.Basic.RestPort)
+	require.Equal(t, "constant", Config.Connection.Retry.Policy)
+}
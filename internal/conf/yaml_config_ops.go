@@ -70,18 +70,18 @@ type ConfigKeys struct {
 }
 
 func (c *ConfigKeys) saveCfgKeysIntoKVStorage(cfgType string) error {
+	ops := make([]ConfigOp, 0, len(c.saveCfgKey)+len(c.delCfgKey))
 	for key := range c.saveCfgKey {
-		if err := saveCfgKeyToKV(buildKey(cfgType, c.pluginName, key), c.dataCfg[key]); err != nil {
-			return err
-		}
-		delete(c.saveCfgKey, key)
+		ops = append(ops, NewCfgSetOp(cfgType, c.pluginName, key, c.dataCfg[key]))
 	}
 	for key := range c.delCfgKey {
-		if err := delCfgKeyInStorage(buildKey(cfgType, c.pluginName, key)); err != nil {
-			return err
-		}
-		delete(c.delCfgKey, key)
+		ops = append(ops, NewCfgDeleteOp(cfgType, c.pluginName, key))
+	}
+	if err := BatchCfgInKVStorage(ops); err != nil {
+		return err
 	}
+	clear(c.saveCfgKey)
+	clear(c.delCfgKey)
 	return nil
 }
 
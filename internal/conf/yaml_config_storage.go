@@ -24,21 +24,83 @@ import (
 	"github.com/pingcap/failpoint"
 
 	"github.com/lf-edge/ekuiper/v2/internal/pkg/store"
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
 	"github.com/lf-edge/ekuiper/v2/pkg/kv"
+	"github.com/lf-edge/ekuiper/v2/pkg/syncx"
 )
 
 const (
 	cfgStoreKVStorage = "kv"
 )
 
-type cfgKVStorage interface {
+// ConfigStore is the storage of the sources, sinks and connections configurations.
+// The backend is selected by store.configStoreType.
+type ConfigStore interface {
 	Set(string, map[string]interface{}) error
 	Delete(string) error
 	GetByPrefix(string) (map[string]map[string]interface{}, error)
+	// GetBatch gets the values of the keys. The missing keys are absent in the result.
+	GetBatch(keys []string) (map[string]map[string]interface{}, error)
+	// Batch applies all the operations atomically if the backend supports transactions
+	Batch(ops []ConfigOp) error
+}
+
+// ConfigOp is a write operation in a batch. The key is deleted if Delete is true, otherwise the props are set.
+type ConfigOp struct {
+	Key    string
+	Props  map[string]interface{}
+	Delete bool
+}
+
+// NewCfgSetOp creates the operation to set the config key
+func NewCfgSetOp(typ string, plugin string, confKey string, props map[string]interface{}) ConfigOp {
+	return ConfigOp{Key: buildKey(typ, plugin, confKey), Props: props}
+}
+
+// NewCfgDeleteOp creates the operation to delete the config key
+func NewCfgDeleteOp(typ string, plugin string, confKey string) ConfigOp {
+	return ConfigOp{Key: buildKey(typ, plugin, confKey), Delete: true}
+}
+
+// ConfigStoreBuilder creates the config store by the global configuration
+type ConfigStoreBuilder func() (ConfigStore, error)
+
+// configStoreBuilders is the registry of the config store backends. The backends with extra dependencies register
+// themselves in the build tagged files.
+var configStoreBuilders = map[string]ConfigStoreBuilder{
+	ConfigStoreSqlite: func() (ConfigStore, error) {
+		return NewSqliteKVStore("confKVStorage")
+	},
+}
+
+const (
+	// ConfigStoreSqlite saves the configurations in the built-in store which is decided by store.type
+	ConfigStoreSqlite = "sqlite"
+	ConfigStoreRedis  = "redis"
+	ConfigStoreEtcd   = "etcd"
+)
+
+// RegisterConfigStore registers a config store backend
+func RegisterConfigStore(typ string, builder ConfigStoreBuilder) {
+	configStoreBuilders[typ] = builder
+}
+
+func newConfigStore(typ string) (ConfigStore, error) {
+	if typ == "" {
+		typ = ConfigStoreSqlite
+	}
+	builder, ok := configStoreBuilders[typ]
+	if !ok {
+		return nil, fmt.Errorf("unknown config store type: %s", typ)
+	}
+	return builder()
 }
 
 type kvMemory struct {
 	store map[string]map[string]interface{}
+
+	leaseMu syncx.Mutex
+	leases  map[string]string
 }
 
 func (m *kvMemory) Set(key string, v map[string]interface{}) error {
@@ -61,9 +123,30 @@ func (m *kvMemory) GetByPrefix(prefix string) (map[string]map[string]interface{}
 	return rm, nil
 }
 
+func (m *kvMemory) GetBatch(keys []string) (map[string]map[string]interface{}, error) {
+	rm := make(map[string]map[string]interface{}, len(keys))
+	for _, key := range keys {
+		if value, ok := m.store[key]; ok {
+			rm[key] = value
+		}
+	}
+	return rm, nil
+}
+
+func (m *kvMemory) Batch(ops []ConfigOp) error {
+	for _, op := range ops {
+		if op.Delete {
+			delete(m.store, op.Key)
+		} else {
+			m.store[op.Key] = op.Props
+		}
+	}
+	return nil
+}
+
 var (
 	mockMemoryKVStore *kvMemory
-	kvStore           *sqlKVStore
+	kvStore           ConfigStore
 )
 
 // GetYamlConfigAllKeys get all plugin keys about sources/sinks/connections
@@ -90,7 +173,7 @@ func GetYamlConfigAllKeys(typ string) (map[string]struct{}, error) {
 	return s1, nil
 }
 
-func getKVStorage() (s cfgKVStorage, err error) {
+func getKVStorage() (s ConfigStore, err error) {
 	defer func() {
 		failpoint.Inject("storageErr", func() {
 			err = errors.New("storageErr")
@@ -104,15 +187,44 @@ func getKVStorage() (s cfgKVStorage, err error) {
 		return mockMemoryKVStore, nil
 	}
 	if kvStore == nil {
-		sqliteKVStorage, err := NewSqliteKVStore("confKVStorage")
+		var typ string
+		if Config != nil {
+			typ = Config.Store.ConfigStoreType
+		}
+		cs, err := openConfigStore(typ)
 		if err != nil {
 			return nil, err
 		}
-		kvStore = sqliteKVStorage
+		kvStore = cs
 	}
 	return kvStore, nil
 }
 
+// openConfigStore creates the config store of the type with the encryption if enabled
+func openConfigStore(typ string) (ConfigStore, error) {
+	cs, err := newConfigStore(typ)
+	if err != nil {
+		return nil, err
+	}
+	if Config != nil && Config.Store.Encryption.Enable {
+		key, err := getEncryptionKey(Config.Store.Encryption.KeyProvider)
+		if err != nil {
+			return nil, err
+		}
+		ecs, err := newEncryptedConfigStore(cs, key)
+		if err != nil {
+			return nil, err
+		}
+		return ecs, nil
+	}
+	return cs, nil
+}
+
+// OpenConfigStore opens a new config store of the type, which may differ from the one used by the node
+func OpenConfigStore(typ string) (ConfigStore, error) {
+	return openConfigStore(typ)
+}
+
 // SaveCfgKeyToKV ...
 func SaveCfgKeyToKV(key string, cfg map[string]interface{}) error {
 	return saveCfgKeyToKV(key, cfg)
@@ -137,7 +249,11 @@ func saveCfgKeyToKV(key string, cfg map[string]interface{}) error {
 		return err
 	}
 	Log.Infof("write conf key:%v ", key)
-	return kvStorage.Set(key, cfg)
+	if err := kvStorage.Set(key, cfg); err != nil {
+		return err
+	}
+	notifyCfgChanged()
+	return nil
 }
 
 func delCfgKeyInStorage(key string) error {
@@ -146,7 +262,11 @@ func delCfgKeyInStorage(key string) error {
 		return err
 	}
 	Log.Infof("del conf key:%v ", key)
-	return kvStorage.Delete(key)
+	if err := kvStorage.Delete(key); err != nil {
+		return err
+	}
+	notifyCfgChanged()
+	return nil
 }
 
 func getCfgKeyFromStorageByPrefix(prefix string) (map[string]map[string]interface{}, error) {
@@ -224,6 +344,74 @@ func (s *sqlKVStore) GetByPrefix(prefix string) (map[string]map[string]interface
 	return r, nil
 }
 
+func (s *sqlKVStore) GetBatch(keys []string) (map[string]map[string]interface{}, error) {
+	r := make(map[string]map[string]interface{}, len(keys))
+	for _, key := range keys {
+		props := map[string]interface{}{}
+		ok, err := s.kv.Get(key, &props)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			r[key] = props
+		}
+	}
+	return r, nil
+}
+
+// Batch applies the operations in one transaction if the underlying store supports it.
+// Otherwise, the operations are applied one by one.
+func (s *sqlKVStore) Batch(ops []ConfigOp) error {
+	if b, ok := s.kv.(kv.Batcher); ok {
+		kvOps := make([]kv.Op, 0, len(ops))
+		for _, op := range ops {
+			kvOps = append(kvOps, kv.Op{Key: op.Key, Value: op.Props, Delete: op.Delete})
+		}
+		return b.Batch(kvOps)
+	}
+	for _, op := range ops {
+		var err error
+		if op.Delete {
+			err = s.kv.Delete(op.Key)
+			if code, ok := errorx.GetErrorCode(err); ok && code == errorx.NOT_FOUND {
+				err = nil
+			}
+		} else {
+			err = s.kv.Set(op.Key, op.Props)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BatchCfgInKVStorage applies the config writes and deletes atomically. Use it for the compound or bulk changes.
+func BatchCfgInKVStorage(ops []ConfigOp) error {
+	if len(ops) == 0 {
+		return nil
+	}
+	kvStorage, err := getKVStorage()
+	if err != nil {
+		return err
+	}
+	Log.Infof("batch write %d conf keys", len(ops))
+	if err := kvStorage.Batch(ops); err != nil {
+		return err
+	}
+	notifyCfgChanged()
+	return nil
+}
+
+// GetCfgBatchFromKVStorage gets the configs of the keys built by type, plugin and conf key.
+func GetCfgBatchFromKVStorage(keys []string) (map[string]map[string]interface{}, error) {
+	kvStorage, err := getKVStorage()
+	if err != nil {
+		return nil, err
+	}
+	return kvStorage.GetBatch(keys)
+}
+
 // WriteCfgIntoKVStorage ...
 func WriteCfgIntoKVStorage(typ string, plugin string, confKey string, confData map[string]interface{}) error {
 	key := buildKey(typ, plugin, confKey)
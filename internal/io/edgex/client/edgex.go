@@ -76,6 +76,14 @@ func (es *Client) Ping(_ api.StreamContext) error {
 	return fmt.Errorf("client is nil")
 }
 
+func (es *Client) CanSubscribe() bool {
+	return true
+}
+
+func (es *Client) CanPublish() bool {
+	return true
+}
+
 func (es *Client) DetachSub(ctx api.StreamContext, props map[string]any) {
 	topic, ok := props["topic"]
 	ctx.GetLogger().Infof("detach edgex sub %v", topic)
@@ -99,7 +107,7 @@ type EdgexConf struct {
 	Server   string            `json:"server"`
 	Host     string            `json:"host"`
 	Port     int               `json:"port"`
-	Type     string            `json:"type"`
+	Type     string            `json:"type" enum:"mqtt,nats-core,nats-jetstream"`
 	Optional map[string]string `json:"optional"`
 }
 
@@ -499,7 +499,13 @@ func (ems *EdgexMsgBusSink) doCollect(ctx api.StreamContext, item any) error {
 			env.ContentType = v
 		}
 	}
-	e := ems.cli.Publish(env, topic)
+	// the client is replaced once the shared connection is updated
+	cli, err := connection.CurrentConnection[*client.Client](ctx, ems.cw)
+	if err != nil {
+		return errorx.NewIOErr(err.Error())
+	}
+	ems.cli = cli
+	e := cli.Publish(env, topic)
 	if e != nil {
 		ctx.GetLogger().Errorf("%s: found error %s when publish to EdgeX message bus.\n", e.Error(), e.Error())
 		return errorx.NewIOErr(e.Error())
@@ -18,6 +18,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"strconv"
+	"sync/atomic"
 
 	v4 "github.com/edgexfoundry/go-mod-core-contracts/v4/common"
 	"github.com/edgexfoundry/go-mod-core-contracts/v4/dtos"
@@ -28,11 +29,14 @@ import (
 	"github.com/lf-edge/ekuiper/v2/internal/io/edgex/client"
 	"github.com/lf-edge/ekuiper/v2/pkg/cast"
 	"github.com/lf-edge/ekuiper/v2/pkg/connection"
+	"github.com/lf-edge/ekuiper/v2/pkg/modules"
 	"github.com/lf-edge/ekuiper/v2/pkg/timex"
 )
 
 type Source struct {
-	cli *client.Client
+	// cli is replaced once the shared connection is updated
+	cli atomic.Pointer[client.Client]
+	cw  *connection.ConnWrapper
 
 	config      map[string]any
 	topic       string
@@ -86,12 +90,13 @@ func (es *Source) Connect(ctx api.StreamContext, sc api.StatusChangeHandler) err
 		return err
 	}
 	es.conId = cw.ID
+	es.cw = cw
 	conn, err := cw.Wait(ctx)
 	if conn == nil {
 		return fmt.Errorf("edgex client not ready: %v", err)
 	}
 	cli = conn.(*client.Client)
-	es.cli = cli
+	es.cli.Store(cli)
 	return err
 }
 
@@ -110,7 +115,20 @@ func (es *Source) Subscribe(ctx api.StreamContext, ingest api.TupleIngest, inges
 	message := make(chan types.MessageEnvelope, es.buflen)
 	errChan := make(chan error)
 
-	if e := es.cli.Subscribe(message, es.topic, errChan); e != nil {
+	// move the subscription to the new client once the shared connection is updated
+	es.cw.OnRebind(ctx, func(conn modules.Connection) {
+		cli, ok := conn.(*client.Client)
+		if !ok || cli == nil {
+			return
+		}
+		if old := es.cli.Swap(cli); old != nil {
+			old.DetachSub(ctx, es.config)
+		}
+		if e := cli.Subscribe(message, es.topic, errChan); e != nil {
+			log.Errorf("Failed to resubscribe to edgex messagebus topic %s.", e)
+		}
+	})
+	if e := es.cli.Load().Subscribe(message, es.topic, errChan); e != nil {
 		log.Errorf("Failed to subscribe to edgex messagebus topic %s.", e)
 		return e
 	} else {
@@ -304,9 +322,9 @@ func convertFloatArray(v string, bitSize int) (any, error) {
 func (es *Source) Close(ctx api.StreamContext) error {
 	log := ctx.GetLogger()
 	log.Infof("EdgeX Source instance %d Done.", ctx.GetInstanceId())
-	if es.cli != nil {
-		es.cli.DetachSub(ctx, es.config)
-		_ = es.cli.Disconnect()
+	if cli := es.cli.Load(); cli != nil {
+		cli.DetachSub(ctx, es.config)
+		_ = cli.Disconnect()
 	}
 	return connection.DetachConnection(ctx, es.conId)
 }
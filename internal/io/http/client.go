@@ -91,6 +91,8 @@ type RawConf struct {
 	Compression  string `json:"compression"` // Compression specifies the algorithms used to payload compression
 
 	DebugResp bool `json:"debugResp"`
+	// Proxy overrides the global proxy. "none" means connecting directly
+	Proxy string `json:"proxy"`
 }
 
 const (
@@ -203,7 +205,14 @@ func (cc *ClientConf) InitConf(ctx api.StreamContext, device string, props map[s
 	if err != nil {
 		return err
 	}
+	if err := cert.CheckEndpoint(c.Url); err != nil {
+		return err
+	}
 	tr := newTransport(tlscfg, conf.Log)
+	tr.Proxy, err = httpx.ProxyFunc(c.Proxy)
+	if err != nil {
+		return err
+	}
 	tr.DialContext = httpx.GetSSRFDialContext(time.Duration(c.Timeout))
 	cc.client = &http.Client{
 		Transport: tr,
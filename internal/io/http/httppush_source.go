@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"sync"
 
 	"github.com/lf-edge/ekuiper/contract/v2/api"
 
@@ -27,15 +28,20 @@ import (
 	"github.com/lf-edge/ekuiper/v2/pkg/cast"
 	"github.com/lf-edge/ekuiper/v2/pkg/connection"
 	"github.com/lf-edge/ekuiper/v2/pkg/infra"
+	"github.com/lf-edge/ekuiper/v2/pkg/modules"
 	"github.com/lf-edge/ekuiper/v2/pkg/timex"
 )
 
 type HttpPushSource struct {
+	mu       sync.Mutex
 	topic    string
 	sourceID string
 	ch       <-chan any
-	conf     *PushConf
-	props    map[string]any
+	cw       *connection.ConnWrapper
+	// subCh passes the subscription of the new topic once the connection is updated
+	subCh chan chan any
+	conf  *PushConf
+	props map[string]any
 }
 
 type PushConf struct {
@@ -67,7 +73,9 @@ func (h *HttpPushSource) Provision(ctx api.StreamContext, configs map[string]any
 }
 
 func (h *HttpPushSource) Close(ctx api.StreamContext) error {
+	h.mu.Lock()
 	pubsub.CloseSourceConsumerChannel(h.topic, h.sourceID)
+	h.mu.Unlock()
 	// TODO if supports to be resource, this should change to the unique conn id
 	return connection.DetachConnection(ctx, h.conf.DataSource)
 }
@@ -77,6 +85,7 @@ func (h *HttpPushSource) Connect(ctx api.StreamContext, sch api.StatusChangeHand
 	if err != nil {
 		return err
 	}
+	h.cw = cw
 	c, err := cw.Wait(ctx)
 	if c == nil {
 		return fmt.Errorf("http push endpoint not ready: %v", err)
@@ -94,11 +103,32 @@ func (h *HttpPushSource) Connect(ctx api.StreamContext, sch api.StatusChangeHand
 func (h *HttpPushSource) Subscribe(ctx api.StreamContext, ingest api.BytesIngest, ingestError api.ErrorIngest) error {
 	ch := pubsub.CreateSub(h.topic, nil, h.sourceID, 1024)
 	h.ch = ch
+	h.subCh = make(chan chan any)
+	// move the subscription once the updated connection receives from another topic
+	h.cw.OnRebind(ctx, func(conn modules.Connection) {
+		hc, ok := conn.(*httpserver.HttpPushConnection)
+		if !ok {
+			return
+		}
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if hc.GetTopic() == h.topic {
+			return
+		}
+		pubsub.CloseSourceConsumerChannel(h.topic, h.sourceID)
+		h.topic = hc.GetTopic()
+		select {
+		case h.subCh <- pubsub.CreateSub(h.topic, nil, h.sourceID, 1024):
+		case <-ctx.Done():
+		}
+	})
 	go func(ctx api.StreamContext) {
 		for {
 			select {
 			case <-ctx.Done():
 				return
+			case ch := <-h.subCh:
+				h.ch = ch
 			case v := <-h.ch:
 				data := v.([]byte)
 				e := infra.SafeRun(func() error {
@@ -54,6 +54,11 @@ func (h *HttpPushConnection) Dial(ctx api.StreamContext) error {
 	return nil
 }
 
+// ConnectionProps describes the props of the http push connection
+func ConnectionProps() []modules.ConnectionPropDescriptor {
+	return modules.PropsFromStruct(&connectionCfg{})
+}
+
 type connectionCfg struct {
 	Datasource string `json:"datasource"`
 	Method     string `json:"method"`
@@ -67,6 +72,10 @@ func (h *HttpPushConnection) Ping(ctx api.StreamContext) error {
 	return nil
 }
 
+func (h *HttpPushConnection) CanSubscribe() bool {
+	return true
+}
+
 func (h *HttpPushConnection) DetachSub(ctx api.StreamContext) {
 	UnregisterEndpoint(h.endpoint, h.method)
 }
@@ -29,6 +29,11 @@ type SSEConnection struct {
 	cfg       *sseConfig
 }
 
+// SSEConnectionProps describes the props of the sse connection
+func SSEConnectionProps() []modules.ConnectionPropDescriptor {
+	return modules.PropsFromStruct(&sseConfig{})
+}
+
 type sseConfig struct {
 	Path       string `json:"path"`
 	Datasource string `json:"datasource"`
@@ -66,6 +71,10 @@ func (s *SSEConnection) Ping(ctx api.StreamContext) error {
 	return nil
 }
 
+func (s *SSEConnection) CanPublish() bool {
+	return true
+}
+
 func (s *SSEConnection) Close(ctx api.StreamContext) error {
 	if s.cfg != nil {
 		UnRegisterSSEEndpoint(s.cfg.Datasource)
@@ -15,8 +15,11 @@
 package httpserver
 
 import (
+	"time"
+
 	"github.com/lf-edge/ekuiper/contract/v2/api"
 
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/httpx"
 	"github.com/lf-edge/ekuiper/v2/pkg/cast"
 	"github.com/lf-edge/ekuiper/v2/pkg/cert"
 	"github.com/lf-edge/ekuiper/v2/pkg/modules"
@@ -66,7 +69,17 @@ func (w *WebsocketConnection) Dial(ctx api.StreamContext) error {
 		if err != nil {
 			return err
 		}
-		c := NewWebsocketClient(w.cfg.Scheme, w.cfg.Addr, w.cfg.Path, tlsConfig, w.cfg.RequestHeader)
+		if err := cert.CheckEndpoint(w.cfg.Scheme + "://" + w.cfg.Addr); err != nil {
+			return err
+		}
+		proxy, err := httpx.ProxyFunc(w.cfg.Proxy)
+		if err != nil {
+			return err
+		}
+		c := NewWebsocketClient(w.cfg.Scheme, w.cfg.Addr, w.cfg.Path, tlsConfig, w.cfg.RequestHeader, proxy)
+		if w.cfg.Proxy != "" && w.cfg.Proxy != httpx.ProxyNone {
+			c.netDial = httpx.GetSSRFDialContext(3 * time.Second)
+		}
 		if err := c.Connect(); err != nil {
 			return err
 		}
@@ -76,18 +89,42 @@ func (w *WebsocketConnection) Dial(ctx api.StreamContext) error {
 	return nil
 }
 
+// WebsocketConnectionProps describes the props of the websocket connection
+func WebsocketConnectionProps() []modules.ConnectionPropDescriptor {
+	return modules.PropsFromStruct(&wscConfig{})
+}
+
 type wscConfig struct {
 	Path          string              `json:"path"`
 	Datasource    string              `json:"datasource"`
 	Addr          string              `json:"addr"`
-	Scheme        string              `json:"scheme"`
+	Scheme        string              `json:"scheme" enum:"ws,wss"`
 	RequestHeader map[string][]string `json:"requestHeader"`
+	// Proxy overrides the global proxy of the client. "none" means connecting directly
+	Proxy string `json:"proxy"`
 }
 
 func (w *WebsocketConnection) Ping(ctx api.StreamContext) error {
 	return nil
 }
 
+// ResourceUsage reports the socket and the receiving and sending goroutines of the client. The endpoint of the server
+// mode is served by the shared http server.
+func (w *WebsocketConnection) ResourceUsage() modules.ResourceUsage {
+	if w.isServer || w.client == nil {
+		return modules.ResourceUsage{}
+	}
+	return modules.ResourceUsage{Goroutines: 2, OpenFiles: 1}
+}
+
+func (w *WebsocketConnection) CanSubscribe() bool {
+	return true
+}
+
+func (w *WebsocketConnection) CanPublish() bool {
+	return true
+}
+
 func (w *WebsocketConnection) Close(ctx api.StreamContext) error {
 	if w.isServer {
 		UnRegisterWebSocketEndpoint(w.cfg.Datasource)
@@ -18,6 +18,8 @@ import (
 	"context"
 	"crypto/tls"
 	"fmt"
+	"net"
+	"net/http"
 	"net/url"
 	"sync"
 	"time"
@@ -37,12 +39,15 @@ type WebsocketClient struct {
 	addr          string
 	path          string
 	tlsConfig     *tls.Config
-	conn          *websocket.Conn
-	wg            *sync.WaitGroup
-	cancel        context.CancelFunc
+	proxy         func(*http.Request) (*url.URL, error)
+	// netDial checks the explicit proxy of the connection which is not trusted like the global proxy
+	netDial func(ctx context.Context, network, addr string) (net.Conn, error)
+	conn    *websocket.Conn
+	wg      *sync.WaitGroup
+	cancel  context.CancelFunc
 }
 
-func NewWebsocketClient(scheme, addr, path string, tlsConfig *tls.Config, requestHeader map[string][]string) *WebsocketClient {
+func NewWebsocketClient(scheme, addr, path string, tlsConfig *tls.Config, requestHeader map[string][]string, proxy func(*http.Request) (*url.URL, error)) *WebsocketClient {
 	if scheme == "" {
 		scheme = "ws"
 	}
@@ -52,6 +57,7 @@ func NewWebsocketClient(scheme, addr, path string, tlsConfig *tls.Config, reques
 		addr:          addr,
 		path:          path,
 		tlsConfig:     tlsConfig,
+		proxy:         proxy,
 		wg:            &sync.WaitGroup{},
 	}
 }
@@ -60,6 +66,8 @@ func (c *WebsocketClient) Connect() error {
 	d := &websocket.Dialer{
 		HandshakeTimeout: 3 * time.Second,
 		TLSClientConfig:  c.tlsConfig,
+		Proxy:            c.proxy,
+		NetDialContext:   c.netDial,
 	}
 	if len(c.addr) < 1 {
 		return fmt.Errorf("addr should be defined")
@@ -17,12 +17,15 @@ package http
 import (
 	"errors"
 	"fmt"
+	"maps"
 	"strings"
 
 	"github.com/lf-edge/ekuiper/contract/v2/api"
 	"github.com/pingcap/failpoint"
 
+	"github.com/lf-edge/ekuiper/v2/internal/topo/node/tracenode"
 	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+	"github.com/lf-edge/ekuiper/v2/pkg/tracer"
 )
 
 type RestSink struct {
@@ -130,6 +133,15 @@ func (r *RestSink) Collect(ctx api.StreamContext, item api.RawTuple) error {
 		headers["Content-Encoding"] = "gzip"
 	}
 
+	traced, spanCtx, span := tracenode.TraceInput(ctx, item, fmt.Sprintf("%s_emit", ctx.GetOpId()))
+	if traced {
+		defer span.End()
+		// copy to not change the shared headers
+		h := make(map[string]string, len(headers)+2)
+		maps.Copy(h, headers)
+		headers = tracer.InjectProps(spanCtx, h)
+	}
+
 	resp, err := r.Send(ctx, bodyType, method, u, headers, formData, r.config.FileFieldName, item.Raw())
 	failpoint.Inject("recoverAbleErr", func() {
 		err = errors.New("connection reset by peer")
@@ -34,8 +34,8 @@ type SubscriptionInfo struct {
 }
 
 type CommonConfig struct {
-	Server   string `json:"server"`
-	PVersion string `json:"protocolVersion"`
+	Server   string `json:"server" required:"true"`
+	PVersion string `json:"protocolVersion" enum:"3.1,3.1.1,4,5"`
 }
 
 type (
@@ -31,6 +31,21 @@ import (
 	"github.com/lf-edge/ekuiper/v2/pkg/syncx"
 )
 
+// ConnectionProps describes the props of the mqtt connection. The props of both protocol versions are merged.
+func ConnectionProps() []modules.ConnectionPropDescriptor {
+	var props []modules.ConnectionPropDescriptor
+	seen := make(map[string]bool)
+	for _, c := range []any{&client.CommonConfig{}, &v4client.ConnectionConfig{}, &v5client.ConnectionConfig{}} {
+		for _, p := range modules.PropsFromStruct(c) {
+			if !seen[p.Name] {
+				seen[p.Name] = true
+				props = append(props, p)
+			}
+		}
+	}
+	return props
+}
+
 type Connection struct {
 	mu syncx.Mutex
 	client.Client
@@ -41,6 +56,8 @@ type Connection struct {
 	scHandler api.StatusChangeHandler
 	// key is the topic. Each topic will have only one connector map[string]*client.SubscriptionInfo
 	subscriptions sync.Map
+	// pending is the bytes of the messages being published, which pile up if the broker is slow
+	pending atomic.Int64
 }
 
 func CreateConnection(_ api.StreamContext) modules.Connection {
@@ -194,6 +211,19 @@ func (conn *Connection) Ping(ctx api.StreamContext) error {
 	return conn.Dial(ctx)
 }
 
+func (conn *Connection) CanSubscribe() bool {
+	return true
+}
+
+func (conn *Connection) CanPublish() bool {
+	return true
+}
+
+// CanThrottle returns true as the sink acquires the throttle before each publish
+func (conn *Connection) CanThrottle() bool {
+	return true
+}
+
 // MQTT features
 
 func (conn *Connection) Publish(ctx api.StreamContext, topic string, qos byte, retained bool, payload []byte, properties map[string]string) error {
@@ -201,6 +231,8 @@ func (conn *Connection) Publish(ctx api.StreamContext, topic string, qos byte, r
 	if conn == nil || !conn.connected.Load() {
 		return errorx.NewIOErr("mqtt client is not connected")
 	}
+	conn.pending.Add(int64(len(payload)))
+	defer conn.pending.Add(-int64(len(payload)))
 	err := conn.Client.Publish(ctx, topic, qos, retained, payload, properties)
 	if err != nil {
 		return errorx.NewIOErr(fmt.Sprintf("publish to mqtt broker failed: %s", err))
@@ -208,6 +240,15 @@ func (conn *Connection) Publish(ctx api.StreamContext, topic string, qos byte, r
 	return nil
 }
 
+// ResourceUsage reports the messages being published and the socket to the broker
+func (conn *Connection) ResourceUsage() modules.ResourceUsage {
+	usage := modules.ResourceUsage{BufferedBytes: conn.pending.Load()}
+	if conn.connected.Load() {
+		usage.OpenFiles = 1
+	}
+	return usage
+}
+
 func (conn *Connection) Subscribe(ctx api.StreamContext, topic string, qos byte, callback client.MessageHandler) error {
 	conn.subscriptions.Store(topic, &client.SubscriptionInfo{
 		Qos:     qos,
@@ -25,6 +25,8 @@ import (
 	"github.com/lf-edge/ekuiper/v2/internal/topo/node/tracenode"
 	"github.com/lf-edge/ekuiper/v2/pkg/cast"
 	"github.com/lf-edge/ekuiper/v2/pkg/connection"
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+	"github.com/lf-edge/ekuiper/v2/pkg/tracer"
 )
 
 // AdConf is the advanced configuration for the mqtt sink
@@ -42,7 +44,6 @@ type Sink struct {
 	cw     *connection.ConnWrapper
 	adconf *AdConf
 	config map[string]interface{}
-	cli    *Connection
 }
 
 func (ms *Sink) Provision(ctx api.StreamContext, ps map[string]any) error {
@@ -84,15 +85,26 @@ func (ms *Sink) Connect(ctx api.StreamContext, sch api.StatusChangeHandler) erro
 	if conn == nil {
 		return fmt.Errorf("mqtt client not ready: %v", err)
 	}
-	c, ok := conn.(*Connection)
-	if !ok {
+	if _, ok := conn.(*Connection); !ok {
 		return fmt.Errorf("connection %s should be mqtt connection", ms.adconf.SelId)
 	}
-	ms.cli = c
 	conf.Log.Info("mqtt sink client ready")
 	return err
 }
 
+// client gets the current client of the shared connection, which is replaced once the connection is updated
+func (ms *Sink) client(ctx api.StreamContext) (*Connection, error) {
+	conn, err := ms.cw.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c, ok := conn.(*Connection)
+	if !ok || c == nil {
+		return nil, fmt.Errorf("mqtt client not ready")
+	}
+	return c, nil
+}
+
 func validateMQTTSinkTopic(topic string) error {
 	if strings.Contains(topic, "#") || strings.Contains(topic, "+") {
 		return fmt.Errorf("mqtt sink topic shouldn't contain # or +")
@@ -120,18 +132,23 @@ func (ms *Sink) Collect(ctx api.StreamContext, item api.RawTuple) error {
 		}
 		props = newProps
 	}
-	traced, _, span := tracenode.TraceInput(ctx, item, fmt.Sprintf("%s_emit", ctx.GetOpId()))
+	traced, spanCtx, span := tracenode.TraceInput(ctx, item, fmt.Sprintf("%s_emit", ctx.GetOpId()))
 	if traced {
 		defer span.End()
-		traceID := span.SpanContext().TraceID()
-		spanID := span.SpanContext().SpanID()
-		if props == nil {
-			props = make(map[string]string)
-		}
-		props["traceparent"] = tracenode.BuildTraceParentId(traceID, spanID)
+		props = tracer.InjectProps(spanCtx, props)
+	}
+	// wait for the throttle of the shared connection
+	release, err := ms.cw.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	cli, err := ms.client(ctx)
+	if err != nil {
+		return errorx.NewIOErr(err.Error())
 	}
 	ctx.GetLogger().Debugf("publishing to topic %s", tpc)
-	return ms.cli.Publish(ctx, tpc, ms.adconf.Qos, ms.adconf.Retained, item.Raw(), props)
+	return cli.Publish(ctx, tpc, ms.adconf.Qos, ms.adconf.Retained, item.Raw(), props)
 }
 
 func (ms *Sink) Close(ctx api.StreamContext) error {
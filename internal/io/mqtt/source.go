@@ -18,13 +18,17 @@ import (
 	"bytes"
 	"encoding/base64"
 	"fmt"
+	"sync/atomic"
 
 	"github.com/lf-edge/ekuiper/contract/v2/api"
+	"go.opentelemetry.io/otel/propagation"
 
 	"github.com/lf-edge/ekuiper/v2/internal/pkg/util"
 	"github.com/lf-edge/ekuiper/v2/pkg/cast"
 	"github.com/lf-edge/ekuiper/v2/pkg/connection"
+	"github.com/lf-edge/ekuiper/v2/pkg/modules"
 	"github.com/lf-edge/ekuiper/v2/pkg/timex"
+	"github.com/lf-edge/ekuiper/v2/pkg/tracer"
 )
 
 // SourceConnector is the connector for mqtt source
@@ -34,7 +38,9 @@ type SourceConnector struct {
 	cfg   *Conf
 	props map[string]any
 
-	cli        *Connection
+	// cli is replaced once the shared connection is updated
+	cli        atomic.Pointer[Connection]
+	cw         *connection.ConnWrapper
 	conId      string
 	eof        api.EOFIngest
 	eofPayload []byte
@@ -93,44 +99,57 @@ func (ms *SourceConnector) Connect(ctx api.StreamContext, sch api.StatusChangeHa
 		return err
 	}
 	ms.conId = cw.ID
+	ms.cw = cw
 	// wait for connection
 	conn, err := cw.Wait(ctx)
 	if conn == nil {
 		return fmt.Errorf("mqtt client not ready: %v", err)
 	}
 	cli = conn.(*Connection)
-	ms.cli = cli
+	ms.cli.Store(cli)
 	return err
 }
 
 // Subscribe is a one time only operation for source. It connects to the mqtt broker and subscribe to the topic
 // Run open before subscribe
 func (ms *SourceConnector) Subscribe(ctx api.StreamContext, ingest api.BytesIngest, _ api.ErrorIngest) error {
-	return ms.cli.Subscribe(ctx, ms.tpc, byte(ms.cfg.Qos), func(ctx api.StreamContext, message any) {
+	handler := func(ctx api.StreamContext, message any) {
 		ms.onMessage(ctx, message, ingest)
+	}
+	// move the subscription to the new client once the shared connection is updated
+	ms.cw.OnRebind(ctx, func(conn modules.Connection) {
+		cli, ok := conn.(*Connection)
+		if !ok || cli == nil {
+			return
+		}
+		if old := ms.cli.Swap(cli); old != nil {
+			old.DetachSub(ctx, ms.props)
+		}
+		if err := cli.Subscribe(ctx, ms.tpc, byte(ms.cfg.Qos), handler); err != nil {
+			ctx.GetLogger().Errorf("resubscribe to topic %s: %v", ms.tpc, err)
+		}
 	})
+	return ms.cli.Load().Subscribe(ctx, ms.tpc, byte(ms.cfg.Qos), handler)
 }
 
 func (ms *SourceConnector) onMessage(ctx api.StreamContext, msg any, ingest api.BytesIngest) {
 	rcvTime := timex.GetNow()
-	payload, meta, props := ms.cli.ParseMsg(ctx, msg)
+	payload, meta, props := ms.cli.Load().ParseMsg(ctx, msg)
 	if ms.eof != nil && ms.eofPayload != nil && bytes.Equal(ms.eofPayload, payload) {
 		ms.eof(ctx, "")
 		return
 	}
-	// extract trace id
+	// extract the upstream trace context
 	if props != nil {
-		if tid, ok := props["traceparent"]; ok {
-			meta["traceId"] = tid
-		}
+		meta = tracer.CopyToMeta(propagation.MapCarrier(props), meta)
 	}
 	ingest(ctx, payload, meta, rcvTime)
 }
 
 func (ms *SourceConnector) Close(ctx api.StreamContext) error {
 	ctx.GetLogger().Infof("Closing mqtt source connector to topic %s.", ms.tpc)
-	if ms.cli != nil {
-		ms.cli.DetachSub(ctx, ms.props)
+	if cli := ms.cli.Load(); cli != nil {
+		cli.DetachSub(ctx, ms.props)
 	}
 	return connection.DetachConnection(ctx, ms.conId)
 }
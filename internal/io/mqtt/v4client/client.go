@@ -164,6 +164,9 @@ func ValidateConfig(ctx api.StreamContext, props map[string]any) (*ConnectionCon
 	if err != nil {
 		return nil, err
 	}
+	if err := cert.CheckEndpoint(c.Server); err != nil {
+		return nil, err
+	}
 	c.tls = tlsConfig
 	return c, nil
 }
@@ -291,6 +291,9 @@ func ValidateConfig(ctx api.StreamContext, props map[string]any) (*ConnectionCon
 	if err != nil {
 		return nil, err
 	}
+	if err := cert.CheckEndpoint(c.Server); err != nil {
+		return nil, err
+	}
 	c.tls = tlsConfig
 	if c.EnableClientSession && len(c.ClientStatePath) == 0 {
 		return nil, errors.New("missing client state path")
@@ -100,6 +100,12 @@ func (s *sink) Connect(ctx api.StreamContext, sc api.StatusChangeHandler) error
 
 func (s *sink) Collect(ctx api.StreamContext, data api.MessageTuple) error {
 	ctx.GetLogger().Debugf("receive %+v", data)
+	// the socket is replaced once the shared connection is updated
+	cli, err := connection.CurrentConnection[*nng.Sock](ctx, s.cw)
+	if err != nil {
+		return errorx.NewIOErr(err.Error())
+	}
+	s.cli = cli
 	if s.c.Raw {
 		m := data.ToMap()
 		r, err := json.Marshal(m)
@@ -18,6 +18,7 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	"github.com/lf-edge/ekuiper/contract/v2/api"
@@ -26,6 +27,7 @@ import (
 	"github.com/lf-edge/ekuiper/v2/internal/topo/node/tracenode"
 	"github.com/lf-edge/ekuiper/v2/pkg/connection"
 	"github.com/lf-edge/ekuiper/v2/pkg/infra"
+	"github.com/lf-edge/ekuiper/v2/pkg/modules"
 	"github.com/lf-edge/ekuiper/v2/pkg/nng"
 	"github.com/lf-edge/ekuiper/v2/pkg/timex"
 )
@@ -45,8 +47,10 @@ var (
 )
 
 type source struct {
-	c     *nng.SockConf
-	cli   *nng.Sock
+	c *nng.SockConf
+	// cli is replaced once the shared connection is updated
+	cli   atomic.Pointer[nng.Sock]
+	cw    *connection.ConnWrapper
 	props map[string]any
 	conId string
 }
@@ -82,16 +86,24 @@ func (s *source) Connect(ctx api.StreamContext, sc api.StatusChangeHandler) erro
 		return err
 	}
 	s.conId = cw.ID
+	s.cw = cw
 	cli, err := cw.Wait(ctx)
 	if cli == nil {
 		return fmt.Errorf("neuron client not ready: %v", err)
 	}
-	s.cli = cli.(*nng.Sock)
+	s.cli.Store(cli.(*nng.Sock))
 	return nil
 }
 
 func (s *source) Subscribe(ctx api.StreamContext, ingest api.BytesIngest, ingestErr api.ErrorIngest) error {
 	ctx.GetLogger().Infof("neuron source receiving loop started")
+	// receive from the new socket once the shared connection is updated. The pending receiving of the old socket
+	// returns once it is closed.
+	s.cw.OnRebind(ctx, func(conn modules.Connection) {
+		if cli, ok := conn.(*nng.Sock); ok && cli != nil {
+			s.cli.Store(cli)
+		}
+	})
 	go func() {
 		err := infra.SafeRun(func() error {
 			connected := true
@@ -101,13 +113,21 @@ func (s *source) Subscribe(ctx api.StreamContext, ingest api.BytesIngest, ingest
 					ctx.GetLogger().Infof("neuron source receiving loop stopped")
 					return nil
 				default:
+					cli := s.cli.Load()
+					if cli == nil {
+						return nil
+					}
 					// no receiving deadline, will wait until the socket closed
-					if msg, err := s.cli.Recv(); err == nil {
+					if msg, err := cli.Recv(); err == nil {
 						connected = true
 						ctx.GetLogger().Debugf("nng received message %s", string(msg))
 						rawData, meta := extractTraceMeta(ctx, msg)
 						ingest(ctx, rawData, meta, timex.GetNow())
 					} else if err == mangos.ErrClosed {
+						if s.cli.Load() != cli {
+							// the socket is replaced
+							continue
+						}
 						if connected {
 							ctx.GetLogger().Infof("neuron connection closed, retry after 1 second")
 							ingestErr(ctx, errors.New("neuron connection closed"))
@@ -129,7 +149,7 @@ func (s *source) Subscribe(ctx api.StreamContext, ingest api.BytesIngest, ingest
 func (s *source) Close(ctx api.StreamContext) error {
 	ctx.GetLogger().Infof("closing neuron source")
 	_ = connection.DetachConnection(ctx, s.conId)
-	s.cli = nil
+	s.cli.Store(nil)
 	return nil
 }
 
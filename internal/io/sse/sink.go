@@ -17,6 +17,7 @@ package sse
 import (
 	"fmt"
 	"strings"
+	"sync"
 
 	"github.com/lf-edge/ekuiper/contract/v2/api"
 
@@ -24,6 +25,7 @@ import (
 	"github.com/lf-edge/ekuiper/v2/internal/io/memory/pubsub"
 	"github.com/lf-edge/ekuiper/v2/pkg/cast"
 	"github.com/lf-edge/ekuiper/v2/pkg/connection"
+	"github.com/lf-edge/ekuiper/v2/pkg/modules"
 )
 
 type SseConfig struct {
@@ -34,6 +36,7 @@ type SSESink struct {
 	cw    *connection.ConnWrapper
 	cfg   *SseConfig
 	props map[string]any
+	mu    sync.RWMutex
 	topic string
 }
 
@@ -51,7 +54,9 @@ func (s *SSESink) Provision(ctx api.StreamContext, configs map[string]any) error
 }
 
 func (s *SSESink) Close(ctx api.StreamContext) error {
+	s.mu.RLock()
 	pubsub.RemovePub(s.topic)
+	s.mu.RUnlock()
 	return connection.DetachConnection(ctx, buildSseEpID(s.cfg.Endpoint))
 }
 
@@ -75,6 +80,21 @@ func (s *SSESink) Connect(ctx api.StreamContext, sch api.StatusChangeHandler) er
 	}
 	s.topic = c.SendTopic
 	pubsub.CreatePub(s.topic)
+	// move the publisher once the updated connection sends to another topic
+	s.cw.OnRebind(ctx, func(conn modules.Connection) {
+		c, ok := conn.(*httpserver.SSEConnection)
+		if !ok {
+			return
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if c.SendTopic == s.topic {
+			return
+		}
+		pubsub.RemovePub(s.topic)
+		s.topic = c.SendTopic
+		pubsub.CreatePub(s.topic)
+	})
 	return err
 }
 
@@ -83,6 +103,8 @@ func (s *SSESink) Collect(ctx api.StreamContext, item api.RawTuple) error {
 }
 
 func (s *SSESink) collect(ctx api.StreamContext, data []byte) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	pubsub.ProduceAny(ctx, s.topic, data)
 	return nil
 }
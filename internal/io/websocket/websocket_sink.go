@@ -17,6 +17,7 @@ package websocket
 import (
 	"fmt"
 	"strings"
+	"sync"
 
 	"github.com/lf-edge/ekuiper/contract/v2/api"
 
@@ -24,12 +25,14 @@ import (
 	"github.com/lf-edge/ekuiper/v2/internal/io/memory/pubsub"
 	"github.com/lf-edge/ekuiper/v2/pkg/cast"
 	"github.com/lf-edge/ekuiper/v2/pkg/connection"
+	"github.com/lf-edge/ekuiper/v2/pkg/modules"
 )
 
 type WebsocketSink struct {
 	cw    *connection.ConnWrapper
 	cfg   *WebsocketConfig
 	props map[string]any
+	mu    sync.RWMutex
 	topic string
 }
 
@@ -48,7 +51,9 @@ func (w *WebsocketSink) Provision(ctx api.StreamContext, configs map[string]any)
 }
 
 func (w *WebsocketSink) Close(ctx api.StreamContext) error {
+	w.mu.RLock()
 	pubsub.RemovePub(w.topic)
+	w.mu.RUnlock()
 	return connection.DetachConnection(ctx, buildWebsocketEpID(w.cfg.Endpoint))
 }
 
@@ -72,6 +77,21 @@ func (w *WebsocketSink) Connect(ctx api.StreamContext, sch api.StatusChangeHandl
 	}
 	w.topic = c.SendTopic
 	pubsub.CreatePub(w.topic)
+	// move the publisher once the updated connection sends to another topic
+	w.cw.OnRebind(ctx, func(conn modules.Connection) {
+		c, ok := conn.(*httpserver.WebsocketConnection)
+		if !ok {
+			return
+		}
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		if c.SendTopic == w.topic {
+			return
+		}
+		pubsub.RemovePub(w.topic)
+		w.topic = c.SendTopic
+		pubsub.CreatePub(w.topic)
+	})
 	return err
 }
 
@@ -80,6 +100,8 @@ func (w *WebsocketSink) Collect(ctx api.StreamContext, item api.RawTuple) error
 }
 
 func (w *WebsocketSink) collect(ctx api.StreamContext, data []byte) error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
 	pubsub.ProduceAny(ctx, w.topic, data)
 	return nil
 }
@@ -17,6 +17,7 @@ package websocket
 import (
 	"fmt"
 	"strings"
+	"sync"
 
 	"github.com/lf-edge/ekuiper/contract/v2/api"
 
@@ -25,11 +26,16 @@ import (
 	"github.com/lf-edge/ekuiper/v2/pkg/cast"
 	"github.com/lf-edge/ekuiper/v2/pkg/connection"
 	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+	"github.com/lf-edge/ekuiper/v2/pkg/modules"
 	"github.com/lf-edge/ekuiper/v2/pkg/timex"
 )
 
 type WebsocketSource struct {
-	topic         string
+	mu    sync.Mutex
+	topic string
+	cw    *connection.ConnWrapper
+	// subCh passes the subscription of the new topic once the connection is updated
+	subCh         chan chan any
 	cfg           *WebsocketConfig
 	props         map[string]any
 	connectionTyp string
@@ -55,7 +61,9 @@ func (w *WebsocketSource) Provision(ctx api.StreamContext, configs map[string]an
 }
 
 func (w *WebsocketSource) Close(ctx api.StreamContext) error {
+	w.mu.Lock()
 	pubsub.CloseSourceConsumerChannel(w.topic, w.sourceID)
+	w.mu.Unlock()
 	return connection.DetachConnection(ctx, buildWebsocketEpID(w.cfg.Endpoint))
 }
 
@@ -64,6 +72,7 @@ func (w *WebsocketSource) Connect(ctx api.StreamContext, sc api.StatusChangeHand
 	if err != nil {
 		return err
 	}
+	w.cw = cw
 	conn, err := cw.Wait(ctx)
 	if err != nil {
 		return err
@@ -82,11 +91,31 @@ func (w *WebsocketSource) Connect(ctx api.StreamContext, sc api.StatusChangeHand
 
 func (w *WebsocketSource) Subscribe(ctx api.StreamContext, ingest api.BytesIngest, ingestError api.ErrorIngest) error {
 	ch := pubsub.CreateSub(w.topic, nil, w.sourceID, 1024)
+	w.subCh = make(chan chan any)
+	// move the subscription once the updated connection receives from another topic
+	w.cw.OnRebind(ctx, func(conn modules.Connection) {
+		c, ok := conn.(*httpserver.WebsocketConnection)
+		if !ok {
+			return
+		}
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		if c.RecvTopic == w.topic {
+			return
+		}
+		pubsub.CloseSourceConsumerChannel(w.topic, w.sourceID)
+		w.topic = c.RecvTopic
+		select {
+		case w.subCh <- pubsub.CreateSub(w.topic, nil, w.sourceID, 1024):
+		case <-ctx.Done():
+		}
+	})
 	go func() {
 		for {
 			select {
 			case <-ctx.Done():
 				return
+			case ch = <-w.subCh:
 			case d := <-ch:
 				switch recv := d.(type) {
 				case error:
@@ -17,6 +17,8 @@ package meta
 import (
 	"fmt"
 	"strings"
+
+	"github.com/lf-edge/ekuiper/v2/pkg/modules"
 )
 
 func GetConnectionMeta(connectionName, language string) (ptrSourceProperty *uiSource, err error) {
@@ -84,3 +86,101 @@ func GetConnectionPlugins() (sources []*pluginfo) {
 	}
 	return sources
 }
+
+// GetConnectionDescriptor returns the descriptor of the connection type. The descriptor registered by the connection
+// type is preferred, and its missing descriptions and defaults are filled by the metadata file. Otherwise, it is
+// generated from the connection related properties in the metadata file.
+func GetConnectionDescriptor(connectionName, language string) (*modules.ConnectionDescriptor, error) {
+	if desc, ok := modules.GetConnectionDescriptor(connectionName); ok {
+		if ui, err := GetConnectionMeta(connectionName, language); err == nil {
+			fillConnectionDescriptor(&desc, newConnectionDescriptor(connectionName, ui, language))
+		}
+		return &desc, nil
+	}
+	if ui, err := GetConnectionMeta(connectionName, language); err == nil {
+		return newConnectionDescriptor(connectionName, ui, language), nil
+	}
+	if _, ok := modules.GetConnectionProvider(connectionName); ok {
+		return &modules.ConnectionDescriptor{Type: connectionName, DisplayName: connectionName, Props: []modules.ConnectionPropDescriptor{}}, nil
+	}
+	return nil, fmt.Errorf(`%s%s`, getMsg(language, source, "not_found_plugin"), connectionName)
+}
+
+// GetConnectionDescriptors returns the descriptors of all the registered connection types
+func GetConnectionDescriptors(language string) []*modules.ConnectionDescriptor {
+	names := modules.ConnectionTypes()
+	result := make([]*modules.ConnectionDescriptor, 0, len(names))
+	for _, name := range names {
+		if desc, err := GetConnectionDescriptor(name, language); err == nil {
+			result = append(result, desc)
+		}
+	}
+	return result
+}
+
+// fillConnectionDescriptor fills the missing descriptions, defaults and example of the registered descriptor
+func fillConnectionDescriptor(desc, fromMeta *modules.ConnectionDescriptor) {
+	if desc.Description == "" {
+		desc.Description = fromMeta.Description
+	}
+	if len(desc.Example) == 0 {
+		desc.Example = fromMeta.Example
+	}
+	metaProps := make(map[string]modules.ConnectionPropDescriptor, len(fromMeta.Props))
+	for _, p := range fromMeta.Props {
+		metaProps[p.Name] = p
+	}
+	// copy the props so that the registered descriptor is not changed
+	props := make([]modules.ConnectionPropDescriptor, len(desc.Props))
+	for i, p := range desc.Props {
+		if mp, ok := metaProps[p.Name]; ok {
+			if p.Description == "" {
+				p.Description = mp.Description
+			}
+			if p.Default == nil {
+				p.Default = mp.Default
+			}
+		}
+		props[i] = p
+	}
+	desc.Props = props
+}
+
+func newConnectionDescriptor(connectionName string, ui *uiSource, language string) *modules.ConnectionDescriptor {
+	desc := &modules.ConnectionDescriptor{
+		Type:        connectionName,
+		DisplayName: connectionName,
+		Props:       []modules.ConnectionPropDescriptor{},
+		Example:     map[string]any{},
+	}
+	if ui.About != nil {
+		desc.Description = localize(ui.About.Description, language)
+	}
+	for _, f := range ui.ConfKeys["default"] {
+		p := modules.ConnectionPropDescriptor{
+			Name:        f.Name,
+			Type:        f.Type,
+			Required:    !f.Optional,
+			Default:     f.Default,
+			Description: localize(f.Hint, language),
+		}
+		if values, ok := f.Values.([]any); ok {
+			p.Values = values
+		}
+		desc.Props = append(desc.Props, p)
+		if f.Default != nil {
+			desc.Example[f.Name] = f.Default
+		}
+	}
+	return desc
+}
+
+func localize(l *language, lang string) string {
+	if l == nil {
+		return ""
+	}
+	if strings.HasPrefix(lang, "zh") && l.Chinese != "" {
+		return l.Chinese
+	}
+	return l.English
+}
@@ -18,7 +18,11 @@ import (
 	"path"
 	"testing"
 
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+	"github.com/stretchr/testify/require"
+
 	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/pkg/modules"
 )
 
 func TestGetMqttConnectionMeta(t *testing.T) {
@@ -42,3 +46,50 @@ func TestGetMqttConnectionMeta(t *testing.T) {
 		t.Errorf("default fields %v", fields)
 	}
 }
+
+func TestGetConnectionDescriptor(t *testing.T) {
+	modules.RegisterConnection("descConn", func(_ api.StreamContext) modules.Connection { return nil })
+	modules.RegisterConnection("plainConn", func(_ api.StreamContext) modules.Connection { return nil })
+	defer modules.UnregisterConnection("descConn")
+	defer modules.UnregisterConnection("plainConn")
+	modules.RegisterConnectionDescriptor(modules.ConnectionDescriptor{
+		Type: "descConn",
+		Props: []modules.ConnectionPropDescriptor{
+			{Name: "server", Type: "string", Required: true, Description: "the server address"},
+		},
+		Example: map[string]any{"server": "tcp://127.0.0.1:1883"},
+	})
+	desc, err := GetConnectionDescriptor("descConn", "en_US")
+	require.NoError(t, err)
+	require.Equal(t, "descConn", desc.DisplayName)
+	require.Len(t, desc.Props, 1)
+	// registered type without descriptor
+	desc, err = GetConnectionDescriptor("plainConn", "en_US")
+	require.NoError(t, err)
+	require.Equal(t, &modules.ConnectionDescriptor{Type: "plainConn", DisplayName: "plainConn", Props: []modules.ConnectionPropDescriptor{}}, desc)
+	_, err = GetConnectionDescriptor("notExist", "en_US")
+	require.Error(t, err)
+	all := GetConnectionDescriptors("en_US")
+	names := make([]string, 0, len(all))
+	for _, d := range all {
+		names = append(names, d.Type)
+	}
+	require.Contains(t, names, "descConn")
+	require.Contains(t, names, "plainConn")
+
+	// generated from the metadata file
+	confDir, err := conf.GetConfLoc()
+	require.NoError(t, err)
+	require.NoError(t, ReadSourceMetaFile(path.Join(confDir, "mqtt_source.json"), true, false))
+	desc, err = GetConnectionDescriptor("mqtt", "en_US")
+	require.NoError(t, err)
+	require.NotEmpty(t, desc.Props)
+	var server *modules.ConnectionPropDescriptor
+	for i, p := range desc.Props {
+		if p.Name == "server" {
+			server = &desc.Props[i]
+		}
+	}
+	require.NotNil(t, server)
+	require.NotEmpty(t, server.Description)
+}
@@ -0,0 +1,252 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package audit is the append-only event log of the configuration changes shared by the subsystems.
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/store"
+	"github.com/lf-edge/ekuiper/v2/pkg/kv"
+	"github.com/lf-edge/ekuiper/v2/pkg/syncx"
+)
+
+const (
+	SubsystemConnection = "connection"
+	SubsystemTracer     = "tracer"
+	SubsystemConfKey    = "confKey"
+
+	ActionCreate  = "create"
+	ActionUpdate  = "update"
+	ActionDelete  = "delete"
+	ActionRestore = "restore"
+	ActionPause   = "pause"
+	ActionResume  = "resume"
+	ActionRotate  = "rotate"
+	ActionImport  = "import"
+)
+
+const table = "auditLog"
+
+// Event is a configuration change. Before is empty for the creation and After is empty for the deletion.
+type Event struct {
+	ID        string `json:"id"`
+	Time      int64  `json:"time"`
+	Actor     string `json:"actor"`
+	Subsystem string `json:"subsystem"`
+	Action    string `json:"action"`
+	Target    string `json:"target"`
+	// TargetType is the type of the target such as the connection type
+	TargetType string         `json:"targetType,omitempty"`
+	Before     map[string]any `json:"before,omitempty"`
+	After      map[string]any `json:"after,omitempty"`
+	// Changes are the changed fields between before and after
+	Changes []Change `json:"changes,omitempty"`
+}
+
+// Query filters the events. The empty fields match all. Start and End are unix milliseconds, 0 means no bound.
+type Query struct {
+	Subsystem string
+	Actor     string
+	Target    string
+	// TargetType matches the type of the target such as mqtt
+	TargetType string
+	Action     string
+	Start      int64
+	End        int64
+	// Limit is the max count of the latest events to return, 0 means all
+	Limit int
+}
+
+func (q *Query) match(e *Event) bool {
+	switch {
+	case q.Subsystem != "" && q.Subsystem != e.Subsystem,
+		q.Actor != "" && q.Actor != e.Actor,
+		q.Target != "" && q.Target != e.Target,
+		q.TargetType != "" && q.TargetType != e.TargetType,
+		q.Action != "" && q.Action != e.Action,
+		q.Start > 0 && e.Time < q.Start,
+		q.End > 0 && e.Time > q.End:
+		return false
+	}
+	return true
+}
+
+var (
+	mu syncx.Mutex
+	db kv.KeyValue
+	// last is the latest key to keep the keys unique and ordered when the clock does not move
+	last int64
+)
+
+func open() (kv.KeyValue, error) {
+	if db == nil {
+		s, err := store.GetKV(table)
+		if err != nil {
+			return nil, err
+		}
+		db = s
+	}
+	return db, nil
+}
+
+// Enabled reports whether the events are recorded
+func Enabled() bool {
+	return conf.Config != nil && conf.Config.AuditLog.Enable
+}
+
+// Record appends the change into the event log if enabled. The failure is logged and does not fail the change.
+func Record(actor, subsystem, action, target string, before, after map[string]any) {
+	RecordEvent(Event{
+		Actor:     actor,
+		Subsystem: subsystem,
+		Action:    action,
+		Target:    target,
+		Before:    before,
+		After:     after,
+	})
+}
+
+// RecordEvent appends the event into the event log if enabled like Record. The id and the time are assigned by the
+// log.
+func RecordEvent(e Event) {
+	if !Enabled() {
+		return
+	}
+	if err := record(&e); err != nil {
+		conf.Log.Warnf("record audit event of %s %s error: %v", e.Subsystem, e.Target, err)
+	}
+}
+
+func record(e *Event) error {
+	mu.Lock()
+	defer mu.Unlock()
+	s, err := open()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	id := now.UnixNano()
+	if id <= last {
+		id = last + 1
+	}
+	last = id
+	e.ID = fmt.Sprintf("%020d", id)
+	e.Time = now.UnixMilli()
+	bs, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if err := s.Set(e.ID, string(bs)); err != nil {
+		return err
+	}
+	if limit := conf.Config.AuditLog.MaxRecords; limit > 0 {
+		keys, err := sortedKeys(s)
+		if err != nil || len(keys) <= limit {
+			return nil
+		}
+		for _, k := range keys[:len(keys)-limit] {
+			_ = s.Delete(k)
+		}
+	}
+	return nil
+}
+
+// Find returns the matched events from the latest
+func Find(q Query) ([]*Event, error) {
+	result := make([]*Event, 0)
+	err := scan(true, func(e *Event) bool {
+		if q.match(e) {
+			result = append(result, e)
+		}
+		return q.Limit <= 0 || len(result) < q.Limit
+	})
+	return result, err
+}
+
+// Export writes the matched events from the oldest into the writer as json lines. It returns the count of the events.
+func Export(w io.Writer, q Query) (int, error) {
+	var (
+		n    int
+		werr error
+	)
+	enc := json.NewEncoder(w)
+	err := scan(false, func(e *Event) bool {
+		if !q.match(e) {
+			return true
+		}
+		if werr = enc.Encode(e); werr != nil {
+			return false
+		}
+		n++
+		return true
+	})
+	if err == nil {
+		err = werr
+	}
+	return n, err
+}
+
+// scan calls fn for each event in the time order until it returns false
+func scan(desc bool, fn func(e *Event) bool) error {
+	mu.Lock()
+	s, err := open()
+	if err != nil {
+		mu.Unlock()
+		return err
+	}
+	keys, err := sortedKeys(s)
+	mu.Unlock()
+	if err != nil {
+		return err
+	}
+	for i := range keys {
+		k := keys[i]
+		if desc {
+			k = keys[len(keys)-1-i]
+		}
+		var v string
+		ok, err := s.Get(k, &v)
+		if err != nil {
+			return err
+		}
+		// trimmed by the retention
+		if !ok {
+			continue
+		}
+		e := &Event{}
+		if err := json.Unmarshal([]byte(v), e); err != nil {
+			return err
+		}
+		if !fn(e) {
+			return nil
+		}
+	}
+	return nil
+}
+
+func sortedKeys(s kv.KeyValue) ([]string, error) {
+	keys, err := s.Keys()
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
@@ -0,0 +1,85 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/testx"
+)
+
+func TestAuditLog(t *testing.T) {
+	testx.InitEnv("audit")
+	s, err := open()
+	require.NoError(t, err)
+	require.NoError(t, s.Clean())
+
+	// disabled by default
+	Record("ops", SubsystemConnection, ActionCreate, "c1", nil, map[string]any{"typ": "mqtt"})
+	events, err := Find(Query{})
+	require.NoError(t, err)
+	require.Len(t, events, 0)
+
+	old := conf.Config.AuditLog
+	defer func() {
+		conf.Config.AuditLog = old
+	}()
+	conf.Config.AuditLog.Enable = true
+	Record("ops", SubsystemConnection, ActionCreate, "c1", nil, map[string]any{"typ": "mqtt"})
+	Record("ops", SubsystemConnection, ActionUpdate, "c1", map[string]any{"typ": "mqtt"}, map[string]any{"typ": "mqtt", "props": map[string]any{"qos": 1}})
+	Record("dashboard", SubsystemTracer, ActionUpdate, "tracer", nil, map[string]any{"serviceName": "kuiper"})
+
+	events, err = Find(Query{})
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	// from the latest
+	require.Equal(t, SubsystemTracer, events[0].Subsystem)
+	require.Less(t, events[1].ID, events[0].ID)
+
+	events, err = Find(Query{Subsystem: SubsystemConnection, Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.Equal(t, ActionUpdate, events[0].Action)
+	require.Equal(t, map[string]any{"typ": "mqtt"}, events[0].Before)
+
+	events, err = Find(Query{Actor: "dashboard"})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	events, err = Find(Query{Start: events[0].Time + 1000})
+	require.NoError(t, err)
+	require.Len(t, events, 0)
+
+	buf := &bytes.Buffer{}
+	n, err := Export(buf, Query{Target: "c1"})
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	dec := json.NewDecoder(buf)
+	e := &Event{}
+	require.NoError(t, dec.Decode(e))
+	require.Equal(t, ActionCreate, e.Action)
+
+	// keep the latest records only
+	conf.Config.AuditLog.MaxRecords = 2
+	Record("ops", SubsystemConnection, ActionDelete, "c1", map[string]any{"typ": "mqtt"}, nil)
+	events, err = Find(Query{})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	require.Equal(t, ActionDelete, events[0].Action)
+}
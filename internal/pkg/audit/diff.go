@@ -0,0 +1,84 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"reflect"
+	"sort"
+)
+
+// The operations of the changed fields
+const (
+	ChangeAdded    = "added"
+	ChangeRemoved  = "removed"
+	ChangeModified = "modified"
+)
+
+// Change is a changed field of the target. The values of the secret fields are not recorded, only that they are
+// changed.
+type Change struct {
+	Field  string `json:"field"`
+	Op     string `json:"op"`
+	Old    any    `json:"old,omitempty"`
+	New    any    `json:"new,omitempty"`
+	Secret bool   `json:"secret,omitempty"`
+}
+
+// Diff compares the fields of before and after, sorted by the field. The nested maps are compared field by field and
+// the nested fields are joined by dots such as tls.certFile. The isSecret tells whether the field holds a secret by
+// its key. The values must be compared before they are masked, otherwise the changes of the secrets are lost.
+func Diff(before, after map[string]any, isSecret func(key string) bool) []Change {
+	var changes []Change
+	diff("", before, after, isSecret, &changes)
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Field < changes[j].Field
+	})
+	return changes
+}
+
+func diff(prefix string, before, after map[string]any, isSecret func(key string) bool, changes *[]Change) {
+	for k, ov := range before {
+		field := prefix + k
+		secret := isSecret != nil && isSecret(k)
+		nv, ok := after[k]
+		if !ok {
+			*changes = append(*changes, newChange(field, ChangeRemoved, ov, nil, secret))
+			continue
+		}
+		if !secret {
+			om, isOldMap := ov.(map[string]any)
+			nm, isNewMap := nv.(map[string]any)
+			if isOldMap && isNewMap {
+				diff(field+".", om, nm, isSecret, changes)
+				continue
+			}
+		}
+		if !reflect.DeepEqual(ov, nv) {
+			*changes = append(*changes, newChange(field, ChangeModified, ov, nv, secret))
+		}
+	}
+	for k, nv := range after {
+		if _, ok := before[k]; !ok {
+			*changes = append(*changes, newChange(prefix+k, ChangeAdded, nil, nv, isSecret != nil && isSecret(k)))
+		}
+	}
+}
+
+func newChange(field, op string, oldValue, newValue any, secret bool) Change {
+	if secret {
+		return Change{Field: field, Op: op, Secret: true}
+	}
+	return Change{Field: field, Op: op, Old: oldValue, New: newValue}
+}
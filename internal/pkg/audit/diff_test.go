@@ -0,0 +1,49 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	isSecret := func(k string) bool { return k == "password" }
+	before := map[string]any{
+		"server":   "tcp://a:1883",
+		"password": "p1",
+		"qos":      1,
+		"tls":      map[string]any{"certFile": "/a.pem", "skipVerify": false},
+	}
+	after := map[string]any{
+		"server":   "tcp://b:1883",
+		"password": "p2",
+		"tls":      map[string]any{"certFile": "/a.pem", "skipVerify": true},
+		"clientid": "c1",
+	}
+	require.Equal(t, []Change{
+		{Field: "clientid", Op: ChangeAdded, New: "c1"},
+		{Field: "password", Op: ChangeModified, Secret: true},
+		{Field: "qos", Op: ChangeRemoved, Old: 1},
+		{Field: "server", Op: ChangeModified, Old: "tcp://a:1883", New: "tcp://b:1883"},
+		{Field: "tls.skipVerify", Op: ChangeModified, Old: false, New: true},
+	}, Diff(before, after, isSecret))
+
+	// the unchanged secret is not reported
+	require.Empty(t, Diff(map[string]any{"password": "p1"}, map[string]any{"password": "p1"}, isSecret))
+	require.Equal(t, []Change{{Field: "password", Op: ChangeAdded, Secret: true}}, Diff(nil, map[string]any{"password": "p1"}, isSecret))
+	require.Equal(t, []Change{{Field: "server", Op: ChangeRemoved, Old: "s"}}, Diff(map[string]any{"server": "s"}, nil, nil))
+}
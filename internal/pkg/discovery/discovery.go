@@ -0,0 +1,211 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package discovery resolves the endpoints specified as DNS SRV names or address lists, and selects a healthy
+// address to connect.
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lf-edge/ekuiper/v2/pkg/syncx"
+)
+
+const srvScheme = "srv"
+
+// lookupTimeout bounds the DNS SRV lookup
+const lookupTimeout = 5 * time.Second
+
+// lookupSRV is replaceable in the tests
+var lookupSRV = net.DefaultResolver.LookupSRV
+
+// IsDiscoverable returns whether the endpoint is a SRV name like srv://_mqtt._tcp.example.com and
+// tcp+srv://_mqtt._tcp.example.com, or a comma separated address list.
+func IsDiscoverable(spec string) bool {
+	_, _, ok := parseSRV(spec)
+	return ok || strings.Contains(spec, ",")
+}
+
+// parseSRV parses the SRV endpoint. The scheme is prepended to the resolved addresses if set by scheme+srv://.
+func parseSRV(spec string) (scheme, name string, ok bool) {
+	prefix, rest, found := strings.Cut(spec, "://")
+	if !found {
+		return "", "", false
+	}
+	prefix = strings.ToLower(prefix)
+	if prefix == srvScheme {
+		return "", rest, true
+	}
+	if s, found := strings.CutSuffix(prefix, "+"+srvScheme); found && s != "" {
+		return s, rest, true
+	}
+	return "", "", false
+}
+
+// Resolver resolves a discoverable endpoint to the addresses. The addresses which failed recently are skipped in the
+// selection until their cooldown passes.
+type Resolver struct {
+	spec     string
+	scheme   string
+	name     string
+	path     string
+	cooldown time.Duration
+
+	mu     syncx.Mutex
+	addrs  []string
+	failed map[string]time.Time
+}
+
+// NewResolver resolves the endpoint for the first time. It fails if no address is found.
+func NewResolver(spec string, cooldown time.Duration) (*Resolver, error) {
+	r := &Resolver{spec: spec, cooldown: cooldown, failed: make(map[string]time.Time)}
+	if scheme, name, ok := parseSRV(spec); ok {
+		r.scheme = scheme
+		r.name, r.path, _ = strings.Cut(name, "/")
+		if r.path != "" {
+			r.path = "/" + r.path
+		}
+		if r.name == "" {
+			return nil, fmt.Errorf("invalid SRV endpoint %s", spec)
+		}
+	}
+	if _, err := r.Refresh(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Refresh re-resolves the endpoint and returns whether the addresses change. The previous addresses are kept if the
+// lookup fails or finds nothing.
+func (r *Resolver) Refresh() (bool, error) {
+	addrs, err := r.resolve()
+	if err != nil {
+		return false, err
+	}
+	if len(addrs) == 0 {
+		return false, fmt.Errorf("no address found for %s", r.spec)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// the records of the same priority are randomized by weight, keep the order if the set is the same to avoid
+	// switching between the refreshes
+	if sameSet(addrs, r.addrs) {
+		return false, nil
+	}
+	r.addrs = addrs
+	for addr := range r.failed {
+		if !slices.Contains(addrs, addr) {
+			delete(r.failed, addr)
+		}
+	}
+	return true, nil
+}
+
+func (r *Resolver) resolve() ([]string, error) {
+	if r.name == "" {
+		var addrs []string
+		for _, a := range strings.Split(r.spec, ",") {
+			if a = strings.TrimSpace(a); a != "" {
+				addrs = append(addrs, a)
+			}
+		}
+		return addrs, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), lookupTimeout)
+	defer cancel()
+	// the records are sorted by priority
+	_, records, err := lookupSRV(ctx, "", "", r.name)
+	if err != nil {
+		return nil, fmt.Errorf("lookup SRV %s error: %v", r.name, err)
+	}
+	addrs := make([]string, 0, len(records))
+	for _, rec := range records {
+		addr := net.JoinHostPort(strings.TrimSuffix(rec.Target, "."), strconv.Itoa(int(rec.Port)))
+		if r.scheme != "" {
+			addr = r.scheme + "://" + addr + r.path
+		}
+		addrs = append(addrs, addr)
+	}
+	return addrs, nil
+}
+
+func sameSet(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, v := range a {
+		if !slices.Contains(b, v) {
+			return false
+		}
+	}
+	return true
+}
+
+// Addrs returns the current addresses in the order of preference
+func (r *Resolver) Addrs() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.addrs)
+}
+
+// Contains returns whether the address is still discovered
+func (r *Resolver) Contains(addr string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Contains(r.addrs, addr)
+}
+
+// Select returns the first address not in the failure cooldown. If all of them failed, the one whose cooldown ends
+// first is returned.
+func (r *Resolver) Select() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	var (
+		fallback string
+		earliest time.Time
+	)
+	for _, addr := range r.addrs {
+		until, ok := r.failed[addr]
+		if !ok || !now.Before(until) {
+			return addr
+		}
+		if fallback == "" || until.Before(earliest) {
+			fallback, earliest = addr, until
+		}
+	}
+	return fallback
+}
+
+// MarkFailed puts the address into the cooldown so that the other addresses are preferred
+func (r *Resolver) MarkFailed(addr string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if slices.Contains(r.addrs, addr) {
+		r.failed[addr] = time.Now().Add(r.cooldown)
+	}
+}
+
+// MarkHealthy clears the failure of the address
+func (r *Resolver) MarkHealthy(addr string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.failed, addr)
+}
@@ -0,0 +1,108 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package discovery
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsDiscoverable(t *testing.T) {
+	require.True(t, IsDiscoverable("srv://_mqtt._tcp.example.com"))
+	require.True(t, IsDiscoverable("tcp+srv://_mqtt._tcp.example.com"))
+	require.True(t, IsDiscoverable("tcp://a:1883,tcp://b:1883"))
+	require.False(t, IsDiscoverable("tcp://a:1883"))
+	require.False(t, IsDiscoverable("+srv://a"))
+	require.False(t, IsDiscoverable(""))
+}
+
+func TestAddressList(t *testing.T) {
+	r, err := NewResolver(" a:1883, b:1883 ,", time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, []string{"a:1883", "b:1883"}, r.Addrs())
+	require.Equal(t, "a:1883", r.Select())
+	r.MarkFailed("a:1883")
+	require.Equal(t, "b:1883", r.Select())
+	// all failed, the earliest to recover is selected
+	r.MarkFailed("b:1883")
+	require.Equal(t, "a:1883", r.Select())
+	r.MarkHealthy("b:1883")
+	require.Equal(t, "b:1883", r.Select())
+	changed, err := r.Refresh()
+	require.NoError(t, err)
+	require.False(t, changed)
+
+	_, err = NewResolver(",", time.Hour)
+	require.Error(t, err)
+}
+
+func TestSRV(t *testing.T) {
+	origin := lookupSRV
+	defer func() {
+		lookupSRV = origin
+	}()
+	var (
+		records []*net.SRV
+		lookErr error
+	)
+	lookupSRV = func(_ context.Context, _, _, name string) (string, []*net.SRV, error) {
+		require.Equal(t, "_mqtt._tcp.example.com", name)
+		return "", records, lookErr
+	}
+	records = []*net.SRV{{Target: "b.example.com.", Port: 1883, Priority: 1}, {Target: "a.example.com.", Port: 1884, Priority: 2}}
+	r, err := NewResolver("tcp+srv://_mqtt._tcp.example.com", time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, []string{"tcp://b.example.com:1883", "tcp://a.example.com:1884"}, r.Addrs())
+
+	// the same set in another order is not a change
+	records = []*net.SRV{records[1], records[0]}
+	changed, err := r.Refresh()
+	require.NoError(t, err)
+	require.False(t, changed)
+	require.Equal(t, "tcp://b.example.com:1883", r.Select())
+
+	// the removed address is dropped and the failures are forgotten
+	r.MarkFailed("tcp://b.example.com:1883")
+	records = []*net.SRV{{Target: "c.example.com.", Port: 1883}}
+	changed, err = r.Refresh()
+	require.NoError(t, err)
+	require.True(t, changed)
+	require.False(t, r.Contains("tcp://b.example.com:1883"))
+	require.Equal(t, "tcp://c.example.com:1883", r.Select())
+
+	// the addresses are kept if the lookup fails
+	lookErr = errors.New("dns down")
+	_, err = r.Refresh()
+	require.Error(t, err)
+	require.Equal(t, []string{"tcp://c.example.com:1883"}, r.Addrs())
+
+	lookErr = nil
+	records = []*net.SRV{{Target: "ws.example.com.", Port: 443}}
+	lookupSRV = func(_ context.Context, _, _, name string) (string, []*net.SRV, error) {
+		require.Equal(t, "_ws._tcp.example.com", name)
+		return "", records, nil
+	}
+	r, err = NewResolver("wss+srv://_ws._tcp.example.com/ws/path", time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, "wss://ws.example.com:443/ws/path", r.Select())
+	r, err = NewResolver("srv://_ws._tcp.example.com", time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, "ws.example.com:443", r.Select())
+}
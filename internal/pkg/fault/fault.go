@@ -0,0 +1,137 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package fault injects the runtime faults into the connection pool and the config store for the resilience tests.
+// The faults only take effect in the builds with the fault or test tag; otherwise Inject is a no-op.
+package fault
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+)
+
+// The injection points
+const (
+	FetchConnection = "connection.fetch"
+	DialConnection  = "connection.dial"
+	PingConnection  = "connection.ping"
+	StoreConnection = "connection.store"
+	DropConnection  = "connection.drop"
+)
+
+var points = map[string]string{
+	FetchConnection: "fail to fetch a connection for the rule",
+	DialConnection:  "fail to dial the connection",
+	PingConnection:  "fail to ping the stateless connection",
+	StoreConnection: "fail to save the named connection to the config store",
+	DropConnection:  "fail to delete the named connection from the config store",
+}
+
+var ErrDisabled = errors.New("fault injection is not supported in this build, rebuild with the fault tag")
+
+// Fault is an injected fault of a point
+type Fault struct {
+	Name string `json:"name"`
+	// Probability is the chance to fail each call in (0, 1]. Default to 1.
+	Probability float64 `json:"probability,omitempty"`
+	// Duration is how long the fault lasts. 0 means until it is removed.
+	Duration cast.DurationConf `json:"duration,omitempty"`
+	// Error is the message of the injected error
+	Error string `json:"error,omitempty"`
+	// Kind is the error kind: transient, permanent, timeout, quota, auth or io. Default to transient.
+	Kind string `json:"kind,omitempty"`
+	// Connections are the ids of the connections to fail. Empty means all the connections.
+	Connections []string `json:"connections,omitempty"`
+	// Delay slows down each hit call before it fails. A fault with only the delay slows the calls without failing them.
+	Delay    cast.DurationConf `json:"delay,omitempty"`
+	ExpireAt time.Time         `json:"expireAt,omitempty"`
+	Hits     int64             `json:"hits"`
+}
+
+// Point describes an injection point
+type Point struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+func (f *Fault) validate() error {
+	if _, ok := points[f.Name]; !ok {
+		return fmt.Errorf("unknown fault point %s", f.Name)
+	}
+	if f.Probability < 0 || f.Probability > 1 {
+		return fmt.Errorf("probability must be in (0, 1]")
+	}
+	if f.Probability == 0 {
+		f.Probability = 1
+	}
+	if f.Duration < 0 {
+		return fmt.Errorf("duration must not be negative")
+	}
+	if f.Delay < 0 {
+		return fmt.Errorf("delay must not be negative")
+	}
+	if _, ok := kinds[f.Kind]; !ok && f.Kind != kindIO {
+		return fmt.Errorf("unknown error kind %s", f.Kind)
+	}
+	for _, id := range f.Connections {
+		if id == "" {
+			return fmt.Errorf("connection id must not be empty")
+		}
+	}
+	if f.Error == "" && (f.Delay == 0 || f.Kind != "") {
+		f.Error = "injected fault " + f.Name
+	}
+	return nil
+}
+
+var kinds = map[string]errorx.ErrorKind{
+	"":          errorx.KindTransient,
+	"transient": errorx.KindTransient,
+	"permanent": errorx.KindPermanent,
+	"timeout":   errorx.KindTimeout,
+	"quota":     errorx.KindQuota,
+	"auth":      errorx.KindAuth,
+}
+
+// kindIO fails with an IO error like a broken network
+const kindIO = "io"
+
+func (f *Fault) err() error {
+	switch {
+	case f.Error == "":
+		// delay only
+		return nil
+	case f.Kind == kindIO:
+		return errorx.NewIOErr(f.Error)
+	default:
+		return errorx.WithKind(errors.New(f.Error), kinds[f.Kind])
+	}
+}
+
+// Points returns the injection points sorted by name
+func Points() []Point {
+	result := make([]Point, 0, len(points))
+	for name, desc := range points {
+		result = append(result, Point{Name: name, Description: desc})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
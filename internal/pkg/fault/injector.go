@@ -0,0 +1,163 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build fault || test
+
+package fault
+
+import (
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/lf-edge/ekuiper/v2/pkg/syncx"
+)
+
+var (
+	mu     syncx.Mutex
+	faults = make(map[faultKey]*Fault)
+)
+
+// faultKey identifies a fault of a point for a connection. The connection is empty for the fault of all connections.
+type faultKey struct {
+	name       string
+	connection string
+}
+
+// Supported returns whether the faults can be injected in this build
+func Supported() bool {
+	return true
+}
+
+// Set injects the fault to its point and replaces the previous one of the same connections.
+// A fault for several connections is split into one fault per connection so that they can be removed separately.
+func Set(f Fault) (Fault, error) {
+	if err := f.validate(); err != nil {
+		return f, err
+	}
+	f.Hits = 0
+	f.ExpireAt = time.Time{}
+	if f.Duration > 0 {
+		f.ExpireAt = time.Now().Add(time.Duration(f.Duration))
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(f.Connections) == 0 {
+		faults[faultKey{name: f.Name}] = &f
+		return f, nil
+	}
+	for _, id := range f.Connections {
+		cf := f
+		cf.Connections = []string{id}
+		faults[faultKey{name: f.Name, connection: id}] = &cf
+	}
+	return f, nil
+}
+
+// Remove removes all the faults of the point
+func Remove(name string) {
+	mu.Lock()
+	defer mu.Unlock()
+	for k := range faults {
+		if k.name == name {
+			delete(faults, k)
+		}
+	}
+}
+
+// RemoveFor removes the fault of the point for the connection. An empty id removes the fault of all connections.
+func RemoveFor(name, id string) {
+	mu.Lock()
+	defer mu.Unlock()
+	delete(faults, faultKey{name: name, connection: id})
+}
+
+// Reset removes all the faults
+func Reset() {
+	mu.Lock()
+	defer mu.Unlock()
+	faults = make(map[faultKey]*Fault)
+}
+
+// List returns the active faults sorted by name and connection
+func List() []Fault {
+	mu.Lock()
+	defer mu.Unlock()
+	result := make([]Fault, 0, len(faults))
+	now := time.Now()
+	for k, f := range faults {
+		if expired(f, now) {
+			delete(faults, k)
+			continue
+		}
+		result = append(result, *f)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Name != result[j].Name {
+			return result[i].Name < result[j].Name
+		}
+		return len(result[i].Connections) == 0 || (len(result[j].Connections) > 0 && result[i].Connections[0] < result[j].Connections[0])
+	})
+	return result
+}
+
+// Inject returns the error of the point if the fault of all connections is hit
+func Inject(name string) error {
+	return InjectFor(name, "")
+}
+
+// InjectFor returns the error of the point for the connection if the fault is hit. The fault of the connection
+// takes precedence over the fault of all connections. The delay of the fault is slept before returning.
+func InjectFor(name, id string) error {
+	delay, err := hit(name, id)
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+	return err
+}
+
+func hit(name, id string) (time.Duration, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	now := time.Now()
+	keys := []faultKey{{name: name}}
+	if id != "" {
+		keys = []faultKey{{name: name, connection: id}, {name: name}}
+	}
+	var f *Fault
+	for _, k := range keys {
+		cf, ok := faults[k]
+		if !ok {
+			continue
+		}
+		if expired(cf, now) {
+			delete(faults, k)
+			continue
+		}
+		f = cf
+		break
+	}
+	if f == nil {
+		return 0, nil
+	}
+	if f.Probability < 1 && rand.Float64() >= f.Probability {
+		return 0, nil
+	}
+	f.Hits++
+	return time.Duration(f.Delay), f.err()
+}
+
+func expired(f *Fault, now time.Time) bool {
+	return !f.ExpireAt.IsZero() && now.After(f.ExpireAt)
+}
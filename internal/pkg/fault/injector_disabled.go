@@ -0,0 +1,45 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !fault && !test
+
+package fault
+
+func Supported() bool {
+	return false
+}
+
+func Set(f Fault) (Fault, error) {
+	return f, ErrDisabled
+}
+
+func Remove(_ string) {}
+
+func RemoveFor(_, _ string) {}
+
+func Reset() {}
+
+func List() []Fault {
+	return nil
+}
+
+// Inject is a no-op without the fault tag
+func Inject(_ string) error {
+	return nil
+}
+
+// InjectFor is a no-op without the fault tag
+func InjectFor(_, _ string) error {
+	return nil
+}
@@ -0,0 +1,122 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build fault || test
+
+package fault
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+)
+
+func TestInject(t *testing.T) {
+	defer Reset()
+	require.True(t, Supported())
+	require.NoError(t, Inject(DialConnection))
+
+	_, err := Set(Fault{Name: "unknown"})
+	require.EqualError(t, err, "unknown fault point unknown")
+	_, err = Set(Fault{Name: DialConnection, Probability: 2})
+	require.Error(t, err)
+	_, err = Set(Fault{Name: DialConnection, Kind: "bad"})
+	require.Error(t, err)
+
+	f, err := Set(Fault{Name: DialConnection})
+	require.NoError(t, err)
+	require.Equal(t, 1.0, f.Probability)
+	err = Inject(DialConnection)
+	require.EqualError(t, err, "injected fault connection.dial")
+	require.True(t, errorx.IsRetryable(err))
+	require.NoError(t, Inject(PingConnection))
+
+	_, err = Set(Fault{Name: StoreConnection, Error: "disk full", Kind: "permanent"})
+	require.NoError(t, err)
+	err = Inject(StoreConnection)
+	require.EqualError(t, err, "disk full")
+	require.False(t, errorx.IsRetryable(err))
+
+	list := List()
+	require.Len(t, list, 2)
+	require.Equal(t, DialConnection, list[0].Name)
+	require.Equal(t, int64(1), list[0].Hits)
+
+	Remove(DialConnection)
+	require.NoError(t, Inject(DialConnection))
+
+	_, err = Set(Fault{Name: PingConnection, Duration: cast.DurationConf(20 * time.Millisecond)})
+	require.NoError(t, err)
+	require.Error(t, Inject(PingConnection))
+	time.Sleep(30 * time.Millisecond)
+	require.NoError(t, Inject(PingConnection))
+	require.Len(t, List(), 1)
+}
+
+func TestProbability(t *testing.T) {
+	defer Reset()
+	_, err := Set(Fault{Name: FetchConnection, Probability: 0.5})
+	require.NoError(t, err)
+	hits := 0
+	for i := 0; i < 1000; i++ {
+		if Inject(FetchConnection) != nil {
+			hits++
+		}
+	}
+	require.Greater(t, hits, 300)
+	require.Less(t, hits, 700)
+}
+
+func TestInjectFor(t *testing.T) {
+	defer Reset()
+	_, err := Set(Fault{Name: PingConnection, Connections: []string{""}})
+	require.Error(t, err)
+	_, err = Set(Fault{Name: PingConnection, Delay: cast.DurationConf(-time.Second)})
+	require.Error(t, err)
+
+	_, err = Set(Fault{Name: PingConnection, Connections: []string{"c1", "c2"}, Kind: "io"})
+	require.NoError(t, err)
+	require.NoError(t, Inject(PingConnection))
+	require.NoError(t, InjectFor(PingConnection, "c3"))
+	err = InjectFor(PingConnection, "c1")
+	require.EqualError(t, err, "injected fault connection.ping")
+	require.ErrorIs(t, err, errorx.ErrIO)
+	require.True(t, errorx.IsRetryable(err))
+	require.Error(t, InjectFor(PingConnection, "c2"))
+	require.Len(t, List(), 2)
+
+	// the fault of the connection takes precedence over the fault of all connections
+	_, err = Set(Fault{Name: PingConnection, Error: "all down"})
+	require.NoError(t, err)
+	require.EqualError(t, InjectFor(PingConnection, "c3"), "all down")
+	require.EqualError(t, InjectFor(PingConnection, "c1"), "injected fault connection.ping")
+	RemoveFor(PingConnection, "c1")
+	require.EqualError(t, InjectFor(PingConnection, "c1"), "all down")
+	Remove(PingConnection)
+	require.NoError(t, InjectFor(PingConnection, "c2"))
+	require.Empty(t, List())
+
+	// a fault with only the delay slows down without failing
+	f, err := Set(Fault{Name: DialConnection, Connections: []string{"c1"}, Delay: cast.DurationConf(20 * time.Millisecond)})
+	require.NoError(t, err)
+	require.Empty(t, f.Error)
+	start := time.Now()
+	require.NoError(t, InjectFor(DialConnection, "c1"))
+	require.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+	require.Equal(t, int64(1), List()[0].Hits)
+}
@@ -212,9 +212,14 @@ func ReadFile(uri string) (io.ReadCloser, error) {
 	case "http", "https":
 		// Get the data
 		timeout := 5 * time.Minute
+		proxy, err := ProxyFunc("")
+		if err != nil {
+			return nil, err
+		}
 		client := &http.Client{
 			Timeout: timeout,
 			Transport: &http.Transport{
+				Proxy:           proxy,
 				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
 				DialContext:     GetSSRFDialContext(timeout),
 			},
@@ -233,8 +238,15 @@ func ReadFile(uri string) (io.ReadCloser, error) {
 	return src, nil
 }
 
+// GetSSRFDialContext returns the dialer which rejects the internal network unless enablePrivateNet is set. The
+// global and environment proxies are always allowed.
 func GetSSRFDialContext(timeout time.Duration) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	allowed := proxyAddrs()
 	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if _, ok := allowed[addr]; ok {
+			d := net.Dialer{Timeout: timeout}
+			return d.DialContext(ctx, network, addr)
+		}
 		d := net.Dialer{
 			Timeout: timeout,
 			Control: func(network, address string, c syscall.RawConn) error {
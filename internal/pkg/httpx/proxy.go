@@ -0,0 +1,123 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpx
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/http/httpproxy"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/pkg/model"
+)
+
+func init() {
+	conf.RegisterReloader("proxy", reloadProxy)
+}
+
+// reloadProxy applies the proxy to the clients created afterward
+func reloadProxy(old, c *model.KuiperConf) (applied, restart []string, err error) {
+	applied = conf.ChangedFields("proxy", old.Proxy, c.Proxy)
+	old.Proxy = c.Proxy
+	return applied, nil, nil
+}
+
+// ProxyNone disables the proxy of a connection even if the proxy is configured globally or by the environment
+const ProxyNone = "none"
+
+// ProxyFunc returns the proxy selector of the http and websocket clients. The explicit proxy of the connection takes
+// precedence. Otherwise, the global proxy config is used and its empty fields fall back to the HTTP_PROXY,
+// HTTPS_PROXY and NO_PROXY environment variables. A nil function means no proxy.
+func ProxyFunc(explicit string) (func(*http.Request) (*url.URL, error), error) {
+	switch explicit {
+	case "":
+		pf := proxyConfig().ProxyFunc()
+		return func(r *http.Request) (*url.URL, error) {
+			return pf(r.URL)
+		}, nil
+	case ProxyNone:
+		return nil, nil
+	default:
+		u, err := parseProxy(explicit)
+		if err != nil {
+			return nil, err
+		}
+		return http.ProxyURL(u), nil
+	}
+}
+
+func proxyConfig() *httpproxy.Config {
+	c := httpproxy.FromEnvironment()
+	if conf.Config != nil {
+		p := conf.Config.Proxy
+		if p.HttpProxy != "" {
+			c.HTTPProxy = p.HttpProxy
+		}
+		if p.HttpsProxy != "" {
+			c.HTTPSProxy = p.HttpsProxy
+		}
+		if p.NoProxy != "" {
+			c.NoProxy = p.NoProxy
+		}
+	}
+	return c
+}
+
+// parseProxy parses the proxy url. The scheme defaults to http like the environment variables.
+func parseProxy(p string) (*url.URL, error) {
+	if !strings.Contains(p, "://") {
+		p = "http://" + p
+	}
+	u, err := url.Parse(p)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy %s: %v", p, err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("invalid proxy %s: unsupported scheme %s", p, u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy %s: host is required", p)
+	}
+	return u, nil
+}
+
+// proxyAddrs are the addresses of the global and environment proxies. They are allowed to dial even if they are in the
+// internal network, because the proxies of the factory networks usually are. The proxy of a connection is set by the
+// rule authors, so it is not trusted.
+func proxyAddrs() map[string]struct{} {
+	c := proxyConfig()
+	result := make(map[string]struct{})
+	for _, p := range []string{c.HTTPProxy, c.HTTPSProxy} {
+		if p == "" || p == ProxyNone {
+			continue
+		}
+		u, err := parseProxy(p)
+		if err != nil {
+			continue
+		}
+		port := u.Port()
+		if port == "" {
+			port = map[string]string{"http": "80", "https": "443", "socks5": "1080"}[u.Scheme]
+		}
+		result[net.JoinHostPort(u.Hostname(), port)] = struct{}{}
+	}
+	return result
+}
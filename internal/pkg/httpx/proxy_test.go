@@ -0,0 +1,100 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpx
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/pkg/model"
+)
+
+func TestProxyFunc(t *testing.T) {
+	origConfig := conf.Config
+	defer func() { conf.Config = origConfig }()
+	conf.Config = &model.KuiperConf{}
+	for _, k := range []string{"HTTP_PROXY", "HTTPS_PROXY", "NO_PROXY", "http_proxy", "https_proxy", "no_proxy"} {
+		t.Setenv(k, "")
+	}
+	t.Setenv("HTTPS_PROXY", "http://env.proxy:3128")
+	conf.Config.Proxy.HttpProxy = "conf.proxy:8080"
+	conf.Config.Proxy.NoProxy = ".internal"
+
+	pf, err := ProxyFunc("")
+	require.NoError(t, err)
+	tests := []struct {
+		url   string
+		proxy string
+	}{
+		{"http://example.com/a", "http://conf.proxy:8080"},
+		{"https://example.com/a", "http://env.proxy:3128"},
+		{"https://a.internal/a", ""},
+		{"http://localhost:9081", ""},
+	}
+	for _, tt := range tests {
+		req, err := http.NewRequest(http.MethodGet, tt.url, nil)
+		require.NoError(t, err)
+		u, err := pf(req)
+		require.NoError(t, err)
+		if tt.proxy == "" {
+			require.Nil(t, u, tt.url)
+		} else {
+			require.Equal(t, tt.proxy, u.String(), tt.url)
+		}
+	}
+
+	pf, err = ProxyFunc("socks5://conn.proxy")
+	require.NoError(t, err)
+	req, err := http.NewRequest(http.MethodGet, "https://a.internal", nil)
+	require.NoError(t, err)
+	u, err := pf(req)
+	require.NoError(t, err)
+	require.Equal(t, "socks5://conn.proxy", u.String())
+
+	pf, err = ProxyFunc(ProxyNone)
+	require.NoError(t, err)
+	require.Nil(t, pf)
+
+	_, err = ProxyFunc("ftp://a")
+	require.EqualError(t, err, "invalid proxy ftp://a: unsupported scheme ftp")
+
+	require.Equal(t, map[string]struct{}{
+		"conf.proxy:8080": {},
+		"env.proxy:3128":  {},
+	}, proxyAddrs())
+}
+
+func TestSSRFDialProxy(t *testing.T) {
+	origConfig := conf.Config
+	defer func() { conf.Config = origConfig }()
+	conf.Config = &model.KuiperConf{}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	_, err = GetSSRFDialContext(time.Second)(context.Background(), "tcp", l.Addr().String())
+	require.ErrorContains(t, err, "in internal network")
+	// the global proxy in the private network is allowed
+	conf.Config.Proxy.HttpProxy = "http://" + l.Addr().String()
+	conn, err := GetSSRFDialContext(time.Second)(context.Background(), "tcp", l.Addr().String())
+	require.NoError(t, err)
+	conn.Close()
+}
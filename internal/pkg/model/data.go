@@ -34,7 +34,34 @@ type ImportDataDesc struct {
 	Partial  bool
 }
 
+// ListConnectionsDesc is the filter, the sort key and the page of the listed connections
+type ListConnectionsDesc struct {
+	Namespace, Typ, Status, Selector, Sort string
+	ForceAll                               bool
+	Limit, Offset                          int
+}
+
 type ExportDataDesc struct {
 	Rules    []string
 	FileName string
 }
+
+// BackupStoreDesc is the argument to back up the config store into a file
+type BackupStoreDesc struct {
+	FileName string
+	Prefixes []string
+}
+
+// RestoreStoreDesc is the argument to restore the config store from a backup file
+type RestoreStoreDesc struct {
+	FileName string
+	Prefixes []string
+	Replace  bool
+}
+
+// MigrateStoreDesc is the argument to copy the config store to another backend
+type MigrateStoreDesc struct {
+	From     string
+	To       string
+	Prefixes []string
+}
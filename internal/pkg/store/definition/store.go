@@ -15,6 +15,8 @@
 package definition
 
 import (
+	"context"
+
 	"github.com/lf-edge/ekuiper/v2/pkg/kv"
 )
 
@@ -25,3 +27,9 @@ type StoreBuilder interface {
 type TsBuilder interface {
 	CreateTs(table string) (kv.Tskv, error)
 }
+
+// Pinger is implemented by the store builders of the remote databases to check they are reachable without
+// creating or writing any table
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
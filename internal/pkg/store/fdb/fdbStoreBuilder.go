@@ -17,6 +17,8 @@
 package fdb
 
 import (
+	"context"
+
 	"github.com/apple/foundationdb/bindings/go/src/fdb"
 
 	"github.com/lf-edge/ekuiper/v2/pkg/kv"
@@ -39,3 +41,14 @@ func NewStoreBuilder(fdb *fdb.Database) StoreBuilder {
 func (b StoreBuilder) CreateStore(table string) (kv.KeyValue, error) {
 	return createFdbKvStore(b.database, b.namespace, table)
 }
+
+// Ping reads a key in a read only transaction. The fdb client has no context, so the ctx is only checked before.
+func (b StoreBuilder) Ping(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	_, err := b.database.ReadTransact(func(tr fdb.ReadTransaction) (interface{}, error) {
+		return tr.Get(fdb.Key(b.namespace)).Get()
+	})
+	return err
+}
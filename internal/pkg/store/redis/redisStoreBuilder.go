@@ -17,6 +17,8 @@
 package redis
 
 import (
+	"context"
+
 	"github.com/redis/go-redis/v9"
 
 	"github.com/lf-edge/ekuiper/v2/pkg/kv"
@@ -35,3 +37,7 @@ func NewStoreBuilder(redis *redis.Client) StoreBuilder {
 func (b StoreBuilder) CreateStore(table string) (kv.KeyValue, error) {
 	return createRedisKvStore(b.database, table)
 }
+
+func (b StoreBuilder) Ping(ctx context.Context) error {
+	return b.database.Ping(ctx).Err()
+}
@@ -94,6 +94,28 @@ func Setup(config definition.Config, setupCheckpointDB bool) error {
 	TraceStores = db
 	return TraceStores.Apply(func(db *sql.DB) error {
 		_, err := db.Exec(`CREATE TABLE IF NOT EXISTS trace (traceID TEXT PRIMARY KEY, ruleID TEXT NOT NULL, value BLOB,createdtimestamp TIMESTAMP DEFAULT CURRENT_TIMESTAMP);`)
+		if err != nil {
+			return err
+		}
+		_, err = db.Exec(`CREATE TABLE IF NOT EXISTS trace_attr (traceID TEXT NOT NULL, attrKey TEXT NOT NULL, attrValue TEXT NOT NULL, startTime INTEGER, createdtimestamp TIMESTAMP DEFAULT CURRENT_TIMESTAMP);`)
+		if err != nil {
+			return err
+		}
+		_, err = db.Exec(`CREATE INDEX IF NOT EXISTS idx_trace_attr ON trace_attr (attrKey, attrValue, startTime);`)
+		if err != nil {
+			return err
+		}
+		// the latest traces of each rule by the latest start time of their spans
+		_, err = db.Exec(`CREATE TABLE IF NOT EXISTS trace_rule (ruleID TEXT NOT NULL, traceID TEXT NOT NULL, startTime INTEGER, PRIMARY KEY (ruleID, traceID));`)
+		if err != nil {
+			return err
+		}
+		_, err = db.Exec(`CREATE INDEX IF NOT EXISTS idx_trace_rule ON trace_rule (ruleID, startTime);`)
+		if err != nil {
+			return err
+		}
+		// index the traces saved before the rule index by their creation time
+		_, err = db.Exec(`INSERT OR IGNORE INTO trace_rule (ruleID, traceID, startTime) SELECT ruleID, traceID, CAST(strftime('%s', createdtimestamp) AS INTEGER) * 1000000000 FROM trace WHERE ruleID != '' AND NOT EXISTS (SELECT 1 FROM trace_rule);`)
 		return err
 	})
 }
@@ -23,6 +23,7 @@ import (
 
 	kvEncoding "github.com/lf-edge/ekuiper/v2/internal/pkg/store/encoding"
 	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+	st "github.com/lf-edge/ekuiper/v2/pkg/kv"
 )
 
 type sqlKvStore struct {
@@ -207,6 +208,34 @@ func (kv *sqlKvStore) GetByPrefix(prefix string) (map[string][]byte, error) {
 	return result, err
 }
 
+func (kv *sqlKvStore) Batch(ops []st.Op) error {
+	return kv.database.Apply(func(db *sql.DB) error {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+		setStmt := tx.Stmt(kv.preparedSetStmt)
+		deleteStmt := tx.Stmt(kv.preparedDeleteStmt)
+		for _, op := range ops {
+			if op.Delete {
+				_, err = deleteStmt.Exec(op.Key)
+			} else {
+				var b []byte
+				b, err = kvEncoding.Encode(op.Value)
+				if err != nil {
+					return err
+				}
+				_, err = setStmt.Exec(op.Key, b)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return tx.Commit()
+	})
+}
+
 func (kv *sqlKvStore) Keys() ([]string, error) {
 	keys := make([]string, 0)
 	err := kv.database.Apply(func(db *sql.DB) error {
@@ -16,6 +16,8 @@ package sql
 
 import (
 	"bytes"
+	"context"
+	"database/sql"
 	"encoding/gob"
 	"os"
 	"path"
@@ -165,6 +167,19 @@ func deleteIfExists(abs string) error {
 	return nil
 }
 
+func TestSqlPing(t *testing.T) {
+	_, db, abs := setupSqlKv()
+	defer cleanSqlKv(db, abs)
+	builder := NewStoreBuilder(db.(Database))
+	require.NoError(t, builder.Ping(context.Background()))
+	// the ping does not create any table
+	var count int
+	require.NoError(t, db.(Database).Apply(func(d *sql.DB) error {
+		return d.QueryRow("SELECT count(*) FROM sqlite_master WHERE type='table' AND name='healthz'").Scan(&count)
+	}))
+	require.Equal(t, 0, count)
+}
+
 func setupSqlKv() (kv.KeyValue, definition.Database, string) {
 	absPath, err := filepath.Abs("test")
 	if err != nil {
@@ -206,3 +221,28 @@ func cleanSqlKv(db definition.Database, abs string) {
 		panic(err)
 	}
 }
+
+func TestSqlKvBatch(t *testing.T) {
+	ks, db, abs := setupSqlKv()
+	defer cleanSqlKv(db, abs)
+	b, ok := ks.(kv.Batcher)
+	require.True(t, ok)
+	require.NoError(t, ks.Set("pk1", "pv1"))
+	require.NoError(t, b.Batch([]kv.Op{
+		{Key: "pk2", Value: "pv2"},
+		{Key: "pk3", Value: "pv3"},
+		{Key: "pk1", Delete: true},
+		{Key: "missing", Delete: true},
+	}))
+	keys, err := ks.Keys()
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"pk2", "pk3"}, keys)
+	// invalid value rolls back the whole batch
+	require.Error(t, b.Batch([]kv.Op{
+		{Key: "pk4", Value: "pv4"},
+		{Key: "pk5", Value: func() {}},
+	}))
+	keys, err = ks.Keys()
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"pk2", "pk3"}, keys)
+}
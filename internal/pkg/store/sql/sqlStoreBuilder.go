@@ -15,6 +15,9 @@
 package sql
 
 import (
+	"context"
+	"database/sql"
+
 	"github.com/lf-edge/ekuiper/v2/pkg/kv"
 )
 
@@ -31,3 +34,11 @@ func NewStoreBuilder(d Database) StoreBuilder {
 func (b StoreBuilder) CreateStore(table string) (kv.KeyValue, error) {
 	return createSqlKvStore(b.database, table)
 }
+
+// Ping runs a read only query on the database
+func (b StoreBuilder) Ping(ctx context.Context) error {
+	return b.database.Apply(func(db *sql.DB) error {
+		var n int
+		return db.QueryRowContext(ctx, "SELECT 1").Scan(&n)
+	})
+}
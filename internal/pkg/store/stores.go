@@ -15,6 +15,7 @@
 package store
 
 import (
+	"context"
 	"fmt"
 	"path"
 	"strings"
@@ -154,6 +155,18 @@ func GetKV(table string) (kv.KeyValue, error) {
 	return globalStores.GetKV(table)
 }
 
+// Ping checks the global storage is reachable by a read only check of the database. The embedded databases without
+// the check are always reachable.
+func Ping(ctx context.Context) error {
+	if globalStores == nil {
+		return fmt.Errorf("global stores are not initialized")
+	}
+	if p, ok := globalStores.kvBuilder.(definition.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
 func GetTS(table string) (kv.Tskv, error) {
 	if checkpointStores != nil {
 		return checkpointStores.GetTS(table)
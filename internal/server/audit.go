@@ -0,0 +1,245 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/audit"
+	"github.com/lf-edge/ekuiper/v2/internal/server/middleware"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/context"
+	"github.com/lf-edge/ekuiper/v2/pkg/connection"
+	"github.com/lf-edge/ekuiper/v2/pkg/model"
+	"github.com/lf-edge/ekuiper/v2/pkg/replace"
+	"github.com/lf-edge/ekuiper/v2/pkg/tracer"
+)
+
+// auditHandler queries the audit events from the latest
+func auditHandler(w http.ResponseWriter, r *http.Request) {
+	q, ok := parseAuditQuery(w, r)
+	if !ok {
+		return
+	}
+	events, err := audit.Find(q)
+	if err != nil {
+		handleError(w, err, "", logger)
+		return
+	}
+	jsonResponse(events, w, logger)
+}
+
+// auditExportHandler downloads the audit events from the oldest as json lines
+func auditExportHandler(w http.ResponseWriter, r *http.Request) {
+	q, ok := parseAuditQuery(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set(ContentType, "application/x-ndjson")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=audit_%d.jsonl", time.Now().Unix()))
+	count, err := audit.Export(w, q)
+	if err != nil && count == 0 {
+		handleError(w, err, "", logger)
+		return
+	}
+	if err != nil {
+		// the response is partially written, only log the error
+		logger.Errorf("export audit events err after %d events: %v", count, err)
+	}
+}
+
+func parseAuditQuery(w http.ResponseWriter, r *http.Request) (audit.Query, bool) {
+	v := r.URL.Query()
+	q := audit.Query{
+		Subsystem:  v.Get("subsystem"),
+		Actor:      v.Get("actor"),
+		Target:     v.Get("target"),
+		TargetType: v.Get("targetType"),
+		Action:     v.Get("action"),
+	}
+	start, end, ok := parseTimeRange(w, r)
+	if !ok {
+		return q, false
+	}
+	if !start.IsZero() {
+		q.Start = start.UnixMilli()
+	}
+	if !end.IsZero() {
+		q.End = end.UnixMilli()
+	}
+	if l := v.Get("limit"); l != "" {
+		limit, err := strconv.Atoi(l)
+		if err != nil {
+			handleError(w, err, "Invalid limit", logger)
+			return q, false
+		}
+		q.Limit = limit
+	}
+	return q, true
+}
+
+// connectionAuditSnapshot is the definition of a named connection to record. The props are not masked so that the
+// changes of the secrets can be found, and they are masked once recorded.
+type connectionAuditSnapshot struct {
+	typ   string
+	props map[string]any
+}
+
+// connectionAuditState returns the definition of the named connection, nil if not found
+func connectionAuditState(id string) *connectionAuditSnapshot {
+	if !audit.Enabled() {
+		return nil
+	}
+	meta, err := connection.GetConnectionDetail(context.Background(), id)
+	if err != nil || !meta.Named {
+		return nil
+	}
+	return &connectionAuditSnapshot{typ: meta.Typ, props: meta.Props}
+}
+
+// connectionAuditStates returns the definitions of all the named connections by id
+func connectionAuditStates() map[string]*connectionAuditSnapshot {
+	if !audit.Enabled() {
+		return nil
+	}
+	result := make(map[string]*connectionAuditSnapshot)
+	for _, meta := range connection.GetAllConnectionsMeta(false) {
+		result[meta.ID] = &connectionAuditSnapshot{typ: meta.Typ, props: meta.Props}
+	}
+	return result
+}
+
+func (s *connectionAuditSnapshot) state() map[string]any {
+	if s == nil {
+		return nil
+	}
+	return map[string]any{"typ": s.typ, "props": connection.MaskSecrets(s.props)}
+}
+
+func (s *connectionAuditSnapshot) propsOf() map[string]any {
+	if s == nil {
+		return nil
+	}
+	return s.props
+}
+
+// recordConnectionAudit records the change of the named connection with its state before the change and the changed
+// props. The values of the secret props are masked and only their changes are recorded.
+func recordConnectionAudit(actor, action, id string, before *connectionAuditSnapshot) {
+	if !audit.Enabled() {
+		return
+	}
+	var after *connectionAuditSnapshot
+	if action != audit.ActionDelete {
+		after = connectionAuditState(id)
+	}
+	e := audit.Event{
+		Actor:     actor,
+		Subsystem: audit.SubsystemConnection,
+		Action:    action,
+		Target:    id,
+		Before:    before.state(),
+		After:     after.state(),
+		Changes:   audit.Diff(before.propsOf(), after.propsOf(), connection.IsSecretProp),
+	}
+	if after != nil {
+		e.TargetType = after.typ
+	} else if before != nil {
+		e.TargetType = before.typ
+	}
+	audit.RecordEvent(e)
+}
+
+// confKeyAuditState returns the masked props of the source, sink or connection conf key, nil if not found
+func confKeyAuditState(typ, plugin, confKey string) map[string]any {
+	if !audit.Enabled() {
+		return nil
+	}
+	data, err := conf.GetCfgFromKVStorage(typ, plugin, confKey)
+	if err != nil {
+		return nil
+	}
+	for _, props := range data {
+		return replace.HidePassword(props)
+	}
+	return nil
+}
+
+// recordConfKeyAudit records the change of the conf key with its props before the change
+func recordConfKeyAudit(r *http.Request, typ, plugin, confKey string, before map[string]any) {
+	if !audit.Enabled() {
+		return
+	}
+	action := audit.ActionDelete
+	var after map[string]any
+	if r.Method != http.MethodDelete {
+		action = audit.ActionUpdate
+		if before == nil {
+			action = audit.ActionCreate
+		}
+		after = confKeyAuditState(typ, plugin, confKey)
+	}
+	audit.Record(middleware.Actor(r), audit.SubsystemConfKey, action, fmt.Sprintf("%s.%s.%s", typ, plugin, confKey), before, after)
+}
+
+// tracerAuditState returns the saved tracer config, nil if the tracer is not available
+func tracerAuditState() map[string]any {
+	if !audit.Enabled() {
+		return nil
+	}
+	c, err := tracer.GetTracerConfig()
+	if err != nil || c == nil {
+		return nil
+	}
+	return map[string]any{
+		"enableRemoteCollector": c.EnableRemoteCollector,
+		"serviceName":           c.ServiceName,
+		"remoteEndpoint":        c.RemoteEndpoint,
+	}
+}
+
+func ruleTraceAuditState(c *tracer.RuleTraceConf) map[string]any {
+	if !audit.Enabled() || c == nil {
+		return nil
+	}
+	m := map[string]any{"enabled": c.Enabled, "strategy": c.Strategy, "maxDepth": c.MaxDepth}
+	if c.Ratio != nil {
+		m["ratio"] = *c.Ratio
+	}
+	if len(c.Alerts) > 0 {
+		m["alerts"] = c.Alerts
+	}
+	if c.Payload != nil {
+		m["payload"] = c.Payload
+	}
+	return m
+}
+
+func samplingAuditState(c model.SamplingConf) map[string]any {
+	if !audit.Enabled() {
+		return nil
+	}
+	m := map[string]any{"rateLimit": c.RateLimit}
+	if c.Ratio != nil {
+		m["ratio"] = *c.Ratio
+	}
+	if len(c.Rules) > 0 {
+		m["rules"] = c.Rules
+	}
+	return m
+}
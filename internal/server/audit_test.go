@@ -0,0 +1,89 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/audit"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/store"
+	"github.com/lf-edge/ekuiper/v2/pkg/connection"
+)
+
+func (suite *RestTestSuite) TestAuditLog() {
+	require.NoError(suite.T(), connection.InitConnectionManager4Test())
+	// the events of the previous runs are kept in the store
+	auditDb, err := store.GetKV("auditLog")
+	require.NoError(suite.T(), err)
+	require.NoError(suite.T(), auditDb.Clean())
+	old := conf.Config.AuditLog
+	defer func() {
+		conf.Config.AuditLog = old
+	}()
+	conf.Config.AuditLog.Enable = true
+
+	serve := func(method, url, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+		req.RemoteAddr = "10.0.0.1:5000"
+		w := httptest.NewRecorder()
+		suite.r.ServeHTTP(w, req)
+		return w
+	}
+	w := serve(http.MethodPost, "http://localhost:8080/connections", `{"id":"audit1","typ":"mock","props":{"datasource":"/a","password":"secret"}}`)
+	require.Equal(suite.T(), http.StatusCreated, w.Code)
+	w = serve(http.MethodPut, "http://localhost:8080/connections/audit1", `{"typ":"mock","props":{"datasource":"/b"}}`)
+	require.Equal(suite.T(), http.StatusOK, w.Code)
+	w = serve(http.MethodDelete, "http://localhost:8080/connections/audit1", "")
+	require.Equal(suite.T(), http.StatusOK, w.Code)
+
+	w = serve(http.MethodGet, "http://localhost:8080/audit?target=audit1", "")
+	require.Equal(suite.T(), http.StatusOK, w.Code)
+	var events []*audit.Event
+	require.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), &events))
+	require.Len(suite.T(), events, 3)
+	require.Equal(suite.T(), audit.ActionDelete, events[0].Action)
+	require.Nil(suite.T(), events[0].After)
+	require.Equal(suite.T(), audit.ActionUpdate, events[1].Action)
+	require.Equal(suite.T(), "/b", events[1].After["props"].(map[string]any)["datasource"])
+	require.Equal(suite.T(), "mock", events[1].TargetType)
+	require.Equal(suite.T(), []audit.Change{
+		{Field: "datasource", Op: audit.ChangeModified, Old: "/a", New: "/b"},
+		{Field: "password", Op: audit.ChangeRemoved, Secret: true},
+	}, events[1].Changes)
+	create := events[2]
+	require.Equal(suite.T(), audit.ActionCreate, create.Action)
+	require.Equal(suite.T(), "client:10.0.0.1", create.Actor)
+	require.Equal(suite.T(), audit.SubsystemConnection, create.Subsystem)
+	require.Equal(suite.T(), connection.HiddenSecret, create.After["props"].(map[string]any)["password"])
+
+	w = serve(http.MethodGet, "http://localhost:8080/audit/export?subsystem=connection&target=audit1", "")
+	require.Equal(suite.T(), http.StatusOK, w.Code)
+	require.Equal(suite.T(), 3, bytes.Count(w.Body.Bytes(), []byte("\n")))
+
+	w = serve(http.MethodGet, "http://localhost:8080/audit?targetType=mock&action=update", "")
+	require.Equal(suite.T(), http.StatusOK, w.Code)
+	events = nil
+	require.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), &events))
+	require.Len(suite.T(), events, 1)
+
+	w = serve(http.MethodGet, "http://localhost:8080/audit?limit=a", "")
+	require.Equal(suite.T(), http.StatusBadRequest, w.Code)
+}
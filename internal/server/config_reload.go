@@ -0,0 +1,64 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+)
+
+// configWatchDelay debounces the multiple write events of saving the config file
+const configWatchDelay = time.Second
+
+// startConfigReload reloads the config file by SIGHUP and by the file changes if watchConfigFile is enabled
+func startConfigReload(ctx context.Context) {
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(sighup)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-sighup:
+				conf.Log.Info("reload config by SIGHUP")
+				if _, err := conf.ReloadConf(); err != nil {
+					conf.Log.Errorf("reload config error: %v", err)
+				}
+			}
+		}
+	}()
+	if conf.Config.Basic.WatchConfigFile {
+		if err := conf.WatchConfFile(ctx, configWatchDelay); err != nil {
+			conf.Log.Errorf("watch config file error: %v", err)
+		}
+	}
+}
+
+// configReloadHandler reloads the config file and reports the applied changes and the changes requiring restart
+func configReloadHandler(w http.ResponseWriter, _ *http.Request) {
+	report, err := conf.ReloadConf()
+	if err != nil {
+		handleError(w, err, "reload config failed", logger)
+		return
+	}
+	jsonResponse(report, w, logger)
+}
@@ -0,0 +1,359 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/store"
+	topoContext "github.com/lf-edge/ekuiper/v2/internal/topo/context"
+	"github.com/lf-edge/ekuiper/v2/pkg/connection"
+	"github.com/lf-edge/ekuiper/v2/pkg/kv"
+	"github.com/lf-edge/ekuiper/v2/pkg/model"
+	"github.com/lf-edge/ekuiper/v2/pkg/syncx"
+	"github.com/lf-edge/ekuiper/v2/pkg/tracer"
+)
+
+// The config sync agent pulls the connection and tracer definitions periodically and applies the changes. Each
+// applied revision is recorded in the audit table.
+
+const maxSyncAuditRecords = 100
+
+type syncDocument struct {
+	connection.Declaration `yaml:",inline"`
+	Tracer                 *syncTracer `yaml:"tracer"`
+}
+
+type syncTracer struct {
+	EnableRemoteCollector bool   `yaml:"enableRemoteCollector"`
+	ServiceName           string `yaml:"serviceName"`
+	RemoteEndpoint        string `yaml:"remoteEndpoint"`
+}
+
+// syncAuditRecord is the result of applying a revision
+type syncAuditRecord struct {
+	Time          int64                       `json:"time"`
+	Source        string                      `json:"source"`
+	Revision      string                      `json:"revision"`
+	Connections   *connection.ReconcileReport `json:"connections,omitempty"`
+	TracerChanged bool                        `json:"tracerChanged,omitempty"`
+	Error         string                      `json:"error,omitempty"`
+}
+
+type syncStatus struct {
+	Enabled      bool               `json:"enabled"`
+	Source       string             `json:"source,omitempty"`
+	URL          string             `json:"url,omitempty"`
+	LastRevision string             `json:"lastRevision,omitempty"`
+	LastSync     int64              `json:"lastSync,omitempty"`
+	LastError    string             `json:"lastError,omitempty"`
+	Records      []*syncAuditRecord `json:"records"`
+}
+
+// syncFetcher gets the document and its revision. The content is nil if it is not modified since the last fetch.
+type syncFetcher interface {
+	fetch(ctx context.Context) (content []byte, revision string, err error)
+}
+
+type configSyncAgent struct {
+	c       model.ConfigSyncConf
+	fetcher syncFetcher
+	audit   kv.KeyValue
+	trigger chan struct{}
+
+	mu           syncx.Mutex
+	lastRevision string
+	lastSync     int64
+	lastError    string
+}
+
+var syncAgent *configSyncAgent
+
+func newConfigSyncAgent(c model.ConfigSyncConf) (*configSyncAgent, error) {
+	if c.URL == "" {
+		return nil, fmt.Errorf("config sync url is not set")
+	}
+	var f syncFetcher
+	switch c.Source {
+	case "http":
+		f = &httpSyncFetcher{url: c.URL, headers: c.Headers, client: &http.Client{Timeout: 30 * time.Second}}
+	case "git":
+		dataDir, err := conf.GetDataLoc()
+		if err != nil {
+			return nil, err
+		}
+		f = &gitSyncFetcher{url: c.URL, branch: c.Branch, path: c.Path, dir: filepath.Join(dataDir, "configsync")}
+	default:
+		return nil, fmt.Errorf("unknown config sync source %s", c.Source)
+	}
+	audit, err := store.GetKV("configSyncAudit")
+	if err != nil {
+		return nil, err
+	}
+	return &configSyncAgent{c: c, fetcher: f, audit: audit, trigger: make(chan struct{}, 1)}, nil
+}
+
+func startConfigSync(ctx context.Context) {
+	if !conf.Config.ConfigSync.Enable {
+		return
+	}
+	a, err := newConfigSyncAgent(conf.Config.ConfigSync)
+	if err != nil {
+		conf.Log.Errorf("start config sync failed: %v", err)
+		return
+	}
+	syncAgent = a
+	go a.run(ctx)
+}
+
+func (a *configSyncAgent) run(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(a.c.Interval))
+	defer ticker.Stop()
+	a.sync(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-a.trigger:
+		}
+		a.sync(ctx)
+	}
+}
+
+// sync applies the document if its revision changes. A failed revision is not retried until the document changes
+// to avoid flooding the audit records.
+func (a *configSyncAgent) sync(ctx context.Context) *syncAuditRecord {
+	content, revision, err := a.fetcher.fetch(ctx)
+	a.mu.Lock()
+	a.lastSync = time.Now().UnixMilli()
+	if err != nil {
+		a.lastError = err.Error()
+		a.mu.Unlock()
+		conf.Log.Warnf("config sync fetch failed: %v", err)
+		return nil
+	}
+	if content == nil || revision == a.lastRevision {
+		a.lastError = ""
+		a.mu.Unlock()
+		return nil
+	}
+	a.mu.Unlock()
+	record := a.apply(content, revision)
+	a.mu.Lock()
+	a.lastError = record.Error
+	a.lastRevision = revision
+	a.mu.Unlock()
+	a.saveRecord(record)
+	return record
+}
+
+func (a *configSyncAgent) apply(content []byte, revision string) *syncAuditRecord {
+	record := &syncAuditRecord{Time: time.Now().UnixMilli(), Source: a.c.URL, Revision: revision}
+	doc := &syncDocument{}
+	if err := yaml.Unmarshal(content, doc); err != nil {
+		record.Error = fmt.Sprintf("invalid document: %v", err)
+		return record
+	}
+	record.Connections = connection.Reconcile(topoContext.Background(), &doc.Declaration)
+	if doc.Tracer != nil {
+		desired := &tracer.TracerConfig{
+			EnableRemoteCollector: doc.Tracer.EnableRemoteCollector,
+			ServiceName:           doc.Tracer.ServiceName,
+			RemoteEndpoint:        doc.Tracer.RemoteEndpoint,
+		}
+		if cur, err := tracer.GetTracerConfig(); err != nil || *cur != *desired {
+			if err := tracer.SetTracer(desired); err != nil {
+				record.Error = fmt.Sprintf("set tracer failed: %v", err)
+			} else {
+				record.TracerChanged = true
+			}
+		}
+	}
+	if record.Error == "" && len(record.Connections.Failed) > 0 {
+		record.Error = fmt.Sprintf("%d connections failed to apply", len(record.Connections.Failed))
+	}
+	conf.Log.Infof("config sync applied revision %s from %s: %d created, %d updated, %d pruned, tracer changed: %v, error: %s",
+		revision, a.c.URL, len(record.Connections.Created), len(record.Connections.Updated), len(record.Connections.Pruned), record.TracerChanged, record.Error)
+	return record
+}
+
+func (a *configSyncAgent) saveRecord(r *syncAuditRecord) {
+	if err := a.audit.Set(fmt.Sprintf("%020d", time.Now().UnixNano()), r); err != nil {
+		conf.Log.Warnf("save config sync audit record failed: %v", err)
+		return
+	}
+	keys, err := a.audit.Keys()
+	if err != nil || len(keys) <= maxSyncAuditRecords {
+		return
+	}
+	sort.Strings(keys)
+	for _, k := range keys[:len(keys)-maxSyncAuditRecords] {
+		_ = a.audit.Delete(k)
+	}
+}
+
+// records returns the audit records from the latest
+func (a *configSyncAgent) records() []*syncAuditRecord {
+	keys, err := a.audit.Keys()
+	if err != nil {
+		return nil
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(keys)))
+	result := make([]*syncAuditRecord, 0, len(keys))
+	for _, k := range keys {
+		r := &syncAuditRecord{}
+		if ok, err := a.audit.Get(k, r); err == nil && ok {
+			result = append(result, r)
+		}
+	}
+	return result
+}
+
+func (a *configSyncAgent) status() *syncStatus {
+	a.mu.Lock()
+	s := &syncStatus{
+		Enabled:      true,
+		Source:       a.c.Source,
+		URL:          a.c.URL,
+		LastRevision: a.lastRevision,
+		LastSync:     a.lastSync,
+		LastError:    a.lastError,
+	}
+	a.mu.Unlock()
+	s.Records = a.records()
+	return s
+}
+
+// configSyncHandler shows the sync status and audit records, or triggers a sync immediately by POST
+func configSyncHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	if syncAgent == nil {
+		jsonResponse(&syncStatus{Records: []*syncAuditRecord{}}, w, logger)
+		return
+	}
+	if r.Method == http.MethodPost {
+		select {
+		case syncAgent.trigger <- struct{}{}:
+		default:
+		}
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte("success"))
+		return
+	}
+	jsonResponse(syncAgent.status(), w, logger)
+}
+
+type httpSyncFetcher struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+	etag    string
+}
+
+func (f *httpSyncFetcher) fetch(ctx context.Context) ([]byte, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, f.url, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	for k, v := range f.headers {
+		req.Header.Set(k, v)
+	}
+	if f.etag != "" {
+		req.Header.Set("If-None-Match", f.etag)
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, f.etag, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("fetch %s failed with status %d", f.url, resp.StatusCode)
+	}
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	f.etag = resp.Header.Get("ETag")
+	sum := sha256.Sum256(content)
+	return content, hex.EncodeToString(sum[:]), nil
+}
+
+// gitSyncFetcher keeps a shallow clone of the repo by the git command line
+type gitSyncFetcher struct {
+	url    string
+	branch string
+	path   string
+	dir    string
+}
+
+func (f *gitSyncFetcher) fetch(ctx context.Context) ([]byte, string, error) {
+	if _, err := os.Stat(filepath.Join(f.dir, ".git")); err != nil {
+		args := []string{"clone", "--depth", "1"}
+		if f.branch != "" {
+			args = append(args, "--branch", f.branch)
+		}
+		if _, err := runGit(ctx, "", append(args, f.url, f.dir)...); err != nil {
+			return nil, "", err
+		}
+	} else {
+		ref := "HEAD"
+		if f.branch != "" {
+			ref = f.branch
+		}
+		if _, err := runGit(ctx, f.dir, "fetch", "--depth", "1", "origin", ref); err != nil {
+			return nil, "", err
+		}
+		if _, err := runGit(ctx, f.dir, "reset", "--hard", "FETCH_HEAD"); err != nil {
+			return nil, "", err
+		}
+	}
+	revision, err := runGit(ctx, f.dir, "rev-parse", "HEAD")
+	if err != nil {
+		return nil, "", err
+	}
+	content, err := os.ReadFile(filepath.Join(f.dir, filepath.Clean(f.path)))
+	if err != nil {
+		return nil, "", err
+	}
+	return content, revision, nil
+}
+
+func runGit(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("git %s failed: %v, %s", args[0], err, strings.TrimSpace(string(out)))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,94 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	topoContext "github.com/lf-edge/ekuiper/v2/internal/topo/context"
+	"github.com/lf-edge/ekuiper/v2/pkg/connection"
+	"github.com/lf-edge/ekuiper/v2/pkg/model"
+)
+
+func TestConfigSyncHttp(t *testing.T) {
+	require.NoError(t, connection.InitConnectionManager4Test())
+	var (
+		mu   sync.Mutex
+		doc  = "connections:\n  mock:\n    sync1:\n      qos: 1\n"
+		etag = `"v1"`
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		_, _ = w.Write([]byte(doc))
+	}))
+	defer srv.Close()
+	a, err := newConfigSyncAgent(model.ConfigSyncConf{Source: "http", URL: srv.URL, Headers: map[string]string{"Authorization": "Bearer token"}})
+	require.NoError(t, err)
+	ctx := context.Background()
+	defer connection.DropNameConnection(topoContext.Background(), "sync1")
+
+	r := a.sync(ctx)
+	require.NotNil(t, r)
+	require.Empty(t, r.Error)
+	require.Equal(t, []string{"sync1"}, r.Connections.Created)
+	// not modified
+	require.Nil(t, a.sync(ctx))
+
+	mu.Lock()
+	doc = "connections:\n  mock:\n    sync1:\n      qos: 2\n"
+	etag = `"v2"`
+	mu.Unlock()
+	r = a.sync(ctx)
+	require.NotNil(t, r)
+	require.Equal(t, []string{"sync1"}, r.Connections.Updated)
+
+	mu.Lock()
+	doc = "connections: [1"
+	etag = `"v3"`
+	mu.Unlock()
+	r = a.sync(ctx)
+	require.NotNil(t, r)
+	require.Contains(t, r.Error, "invalid document")
+
+	s := a.status()
+	require.True(t, s.Enabled)
+	require.Equal(t, r.Error, s.LastError)
+	require.Equal(t, r.Revision, s.LastRevision)
+	require.GreaterOrEqual(t, len(s.Records), 3)
+	require.Equal(t, r.Revision, s.Records[0].Revision)
+}
+
+func TestNewConfigSyncAgent(t *testing.T) {
+	_, err := newConfigSyncAgent(model.ConfigSyncConf{Source: "http"})
+	require.Error(t, err)
+	_, err = newConfigSyncAgent(model.ConfigSyncConf{Source: "ftp", URL: "ftp://a"})
+	require.Error(t, err)
+}
@@ -16,14 +16,22 @@ package server
 
 import (
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
+	"path/filepath"
 	"strconv"
+	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/lf-edge/ekuiper/contract/v2/api"
 
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/audit"
+	"github.com/lf-edge/ekuiper/v2/internal/server/middleware"
 	"github.com/lf-edge/ekuiper/v2/internal/topo/context"
 	"github.com/lf-edge/ekuiper/v2/pkg/connection"
+	"github.com/lf-edge/ekuiper/v2/pkg/modules"
 	"github.com/lf-edge/ekuiper/v2/pkg/validate"
 )
 
@@ -31,6 +39,25 @@ type ConnectionRequest struct {
 	ID    string                 `json:"id"`
 	Typ   string                 `json:"typ"`
 	Props map[string]interface{} `json:"props"`
+	// Labels organize the connections for filtering, they replace the labels in the props if set
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// propsWithLabels returns the props with the labels of the request
+func (req *ConnectionRequest) propsWithLabels() map[string]any {
+	if req.Labels == nil {
+		return req.Props
+	}
+	props := make(map[string]any, len(req.Props)+1)
+	for k, v := range req.Props {
+		props[k] = v
+	}
+	labels := make(map[string]any, len(req.Labels))
+	for k, v := range req.Labels {
+		labels[k] = v
+	}
+	props[connection.LabelsPropKey] = labels
+	return props
 }
 
 func connectionsHandler(w http.ResponseWriter, r *http.Request) {
@@ -51,19 +78,26 @@ func connectionsHandler(w http.ResponseWriter, r *http.Request) {
 			handleError(w, err, "", logger)
 			return
 		}
-		_, err = connection.CreateNamedConnection(context.Background(), req.ID, req.Typ, req.Props)
+		_, err = connection.CreateNamedConnection(namespaceContext(r), req.ID, req.Typ, req.propsWithLabels())
 		if err != nil {
 			handleError(w, err, "create connection failed", logger)
 			return
 		}
+		recordConnectionAudit(middleware.Actor(r), audit.ActionCreate, req.ID, nil)
 		w.WriteHeader(http.StatusCreated)
 		w.Write([]byte("success"))
 	case http.MethodGet:
 		forceAll, _ := strconv.ParseBool(r.URL.Query().Get("forceAll"))
-		metaList := connection.GetAllConnectionsMeta(forceAll)
-		resp := make([]*ConnectionResponse, 0)
-		for _, meta := range metaList {
-			resp = append(resp, getConnectionRespByMeta(meta))
+		sel, err := connection.ParseLabelSelector(r.URL.Query().Get("selector"))
+		if err != nil {
+			handleError(w, err, "", logger)
+			return
+		}
+		metaList := connection.FilterConnectionsMeta(connection.GetConnectionsMetaInNamespace(requestNamespace(r), forceAll), sel)
+		statuses := connection.GetConnectionsStatus(r.Context(), metaList)
+		resp := make([]*ConnectionResponse, 0, len(metaList))
+		for i, meta := range metaList {
+			resp = append(resp, connectionRespWithStatus(meta, statuses[i].Status, statuses[i].Err))
 		}
 		w.WriteHeader(http.StatusOK)
 		jsonResponse(resp, w, logger)
@@ -78,6 +112,16 @@ type ConnectionResponse struct {
 	Status   string         `json:"status,omitempty"`
 	Err      string         `json:"err,omitempty"`
 	RefCount int            `json:"refCount,omitempty"`
+	// Resources are the goroutines, buffered bytes and open files attributable to the connection if any
+	Resources *modules.ResourceUsage `json:"resources,omitempty"`
+	// Stats are the runtime metrics like the reconnects and the ping latency if requested
+	Stats *connection.ConnStats `json:"stats,omitempty"`
+	// Labels are the labels to organize the connection
+	Labels map[string]string `json:"labels,omitempty"`
+	// ActiveEndpoint is the failover endpoint in use if the connection has failover endpoints, 0 is the primary
+	ActiveEndpoint *int `json:"activeEndpoint,omitempty"`
+	// DriverStatus is the status reported by the connection driver with the details like the session if supported
+	DriverStatus *modules.ConnectionStatus `json:"driverStatus,omitempty"`
 }
 
 func connectionHandler(w http.ResponseWriter, r *http.Request) {
@@ -89,18 +133,28 @@ func connectionHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	switch r.Method {
 	case http.MethodGet:
-		meta, err := connection.GetConnectionDetail(context.Background(), id)
+		meta, err := connection.GetConnectionDetail(namespaceContext(r), id)
 		if err != nil {
 			handleError(w, err, "", logger)
 			return
 		}
 		res := getConnectionRespByMeta(meta)
+		if withStats, _ := strconv.ParseBool(r.URL.Query().Get("stats")); withStats {
+			stats := meta.Stats()
+			res.Stats = &stats
+		}
 		jsonResponse(res, w, logger)
 	case http.MethodDelete:
-		if err := connection.DropNameConnection(context.Background(), id); err != nil {
+		before := connectionAuditState(id)
+		drop := connection.DropNameConnection
+		if permanent, _ := strconv.ParseBool(r.URL.Query().Get("permanent")); permanent {
+			drop = connection.DropNameConnectionPermanently
+		}
+		if err := drop(namespaceContext(r), id); err != nil {
 			handleError(w, err, "drop connection failed", logger)
 			return
 		}
+		recordConnectionAudit(middleware.Actor(r), audit.ActionDelete, id, before)
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("success"))
 	case http.MethodPut:
@@ -114,26 +168,462 @@ func connectionHandler(w http.ResponseWriter, r *http.Request) {
 			handleError(w, err, "Invalid body", logger)
 			return
 		}
-		_, err = connection.UpdateConnection(context.Background(), id, req.Typ, req.Props)
+		before := connectionAuditState(id)
+		err = updateConnection(namespaceContext(r), id, req.Typ, req.propsWithLabels())
 		if err != nil {
 			handleError(w, err, "update connection failed", logger)
 			return
 		}
+		recordConnectionAudit(middleware.Actor(r), audit.ActionUpdate, id, before)
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("success"))
 	}
 }
 
+// namespaceContext returns the context in the namespace of the request
+func namespaceContext(r *http.Request) api.StreamContext {
+	return connection.WithNamespace(context.Background(), requestNamespace(r))
+}
+
+// requestNamespace returns the namespace bound to the api key of the request. The namespace parameter is only used by
+// the keys not bound to a namespace, and the default namespace is used if not set. The api key middleware rejects the
+// parameter which differs from the bound namespace.
+func requestNamespace(r *http.Request) string {
+	if ns, ok := middleware.Namespace(r); ok {
+		return ns
+	}
+	return r.URL.Query().Get("namespace")
+}
+
+// updateConnection replaces the connection in use by the rules in place if the type is not changed. Otherwise, the
+// connection is dropped and recreated, which fails if it is in use.
+func updateConnection(ctx api.StreamContext, id, typ string, props map[string]any) error {
+	if meta, err := connection.GetConnectionDetail(ctx, id); err == nil && meta.Named && meta.GetRefCount() > 0 && (typ == "" || typ == meta.Typ) {
+		_, err = connection.UpdateNamedConnection(ctx, id, props)
+		return err
+	}
+	_, err := connection.UpdateConnection(ctx, id, typ, props)
+	return err
+}
+
+// connectionExportHandler exports the named connections in json or yaml. The secrets are masked unless mask=false.
+func connectionExportHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	format := r.URL.Query().Get("format")
+	mask := true
+	if v := r.URL.Query().Get("mask"); v != "" {
+		var err error
+		if mask, err = strconv.ParseBool(v); err != nil {
+			handleError(w, err, "invalid mask parameter", logger)
+			return
+		}
+	}
+	data, err := connection.ExportConnections(format, mask)
+	if err != nil {
+		handleError(w, err, "export connections failed", logger)
+		return
+	}
+	if format == connection.FormatYAML {
+		w.Header().Set(ContentType, "application/yaml")
+	} else {
+		w.Header().Set(ContentType, ContentTypeJSON)
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// connectionImportHandler imports the named connections of the json or yaml document in the body
+func connectionImportHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	overwrite, _ := strconv.ParseBool(r.URL.Query().Get("overwrite"))
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		handleError(w, err, "read the connections document failed", logger)
+		return
+	}
+	before := connectionAuditStates()
+	report, err := connection.ImportConnections(context.Background(), data, overwrite)
+	if err != nil {
+		handleError(w, err, "import connections failed", logger)
+		return
+	}
+	actor := middleware.Actor(r)
+	for _, id := range report.Created {
+		recordConnectionAudit(actor, audit.ActionImport, id, nil)
+	}
+	for _, id := range report.Updated {
+		recordConnectionAudit(actor, audit.ActionImport, id, before[id])
+	}
+	jsonResponse(report, w, logger)
+}
+
+// connectionValidateHandler validates the connection in the body and tests the reachability without creating it
+func connectionValidateHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	req := &ConnectionRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		handleError(w, err, "Invalid body", logger)
+		return
+	}
+	result, err := connection.ValidateConnection(context.Background(), req.Typ, req.Props)
+	if err != nil {
+		handleError(w, err, "validate connection failed", logger)
+		return
+	}
+	jsonResponse(result, w, logger)
+}
+
+// connectionReconcileHandler reports the orphan connections and repairs them if the repair query is true
+func connectionReconcileHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	repair := false
+	if v := r.URL.Query().Get("repair"); v != "" {
+		var err error
+		if repair, err = strconv.ParseBool(v); err != nil {
+			handleError(w, fmt.Errorf("invalid repair %s", v), "", logger)
+			return
+		}
+	}
+	report, err := connection.ReconcileConnections(repair)
+	if err != nil {
+		handleError(w, err, "reconcile connections failed", logger)
+		return
+	}
+	jsonResponse(report, w, logger)
+}
+
+// connectionSnapshotHandler dumps the connection pool to hand over to a standby node
+func connectionSnapshotHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	snap, err := connection.SnapshotPool()
+	if err != nil {
+		handleError(w, err, "snapshot connection pool failed", logger)
+		return
+	}
+	jsonResponse(snap, w, logger)
+}
+
+// connectionSnapshotRestoreHandler creates the connections of the pool snapshot in the body. The hold query is the
+// duration to keep the anonymous connections for the rules to attach.
+func connectionSnapshotRestoreHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	var hold time.Duration
+	if v := r.URL.Query().Get("hold"); v != "" {
+		var err error
+		if hold, err = time.ParseDuration(v); err != nil || hold < 0 {
+			handleError(w, fmt.Errorf("invalid hold %s", v), "", logger)
+			return
+		}
+	}
+	snap := &connection.PoolSnapshot{}
+	if err := json.NewDecoder(r.Body).Decode(snap); err != nil {
+		handleError(w, err, "Invalid body", logger)
+		return
+	}
+	result, err := connection.RestorePool(namespaceContext(r), snap, hold)
+	if err != nil {
+		handleError(w, err, "restore connection pool failed", logger)
+		return
+	}
+	actor := middleware.Actor(r)
+	for _, id := range result.Restored {
+		recordConnectionAudit(actor, audit.ActionCreate, id, nil)
+	}
+	jsonResponse(result, w, logger)
+}
+
+// connectionSummaryHandler lists the brief of the connections by page without pinging them. The query parameters are
+// all optional: namespace, forceAll, selector, type, status, sort, limit and offset.
+func connectionSummaryHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	q := r.URL.Query()
+	sel, err := connection.ParseLabelSelector(q.Get("selector"))
+	if err != nil {
+		handleError(w, err, "", logger)
+		return
+	}
+	forceAll, _ := strconv.ParseBool(q.Get("forceAll"))
+	var limit, offset int
+	if v := q.Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil {
+			handleError(w, err, "Invalid limit", logger)
+			return
+		}
+	}
+	if v := q.Get("offset"); v != "" {
+		if offset, err = strconv.Atoi(v); err != nil {
+			handleError(w, err, "Invalid offset", logger)
+			return
+		}
+	}
+	filter := connection.ConnectionFilter{
+		Namespace: requestNamespace(r),
+		Typ:       q.Get("type"),
+		Status:    q.Get("status"),
+		Selector:  sel,
+		ForceAll:  forceAll,
+	}
+	page, err := connection.ListConnections(filter, q.Get("sort"), limit, offset)
+	if err != nil {
+		handleError(w, err, "", logger)
+		return
+	}
+	jsonResponse(page, w, logger)
+}
+
+// connectionTrashHandler lists the dropped named connections which can be restored
+func connectionTrashHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	list, err := connection.ListTrashedConnections()
+	if err != nil {
+		handleError(w, err, "list connection trash failed", logger)
+		return
+	}
+	for _, t := range list {
+		t.Props = connection.MaskSecrets(t.Props)
+	}
+	jsonResponse(list, w, logger)
+}
+
+func trashedConnectionHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	id := mux.Vars(r)["id"]
+	if err := validate.ValidateID(id); err != nil {
+		handleError(w, err, "", logger)
+		return
+	}
+	if err := connection.PurgeTrashedConnection(id); err != nil {
+		handleError(w, err, "purge connection failed", logger)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("success"))
+}
+
+func restoreConnectionHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	id := mux.Vars(r)["id"]
+	if err := validate.ValidateID(id); err != nil {
+		handleError(w, err, "", logger)
+		return
+	}
+	if _, err := connection.RestoreConnection(context.Background(), id); err != nil {
+		handleError(w, err, "restore connection failed", logger)
+		return
+	}
+	recordConnectionAudit(middleware.Actor(r), audit.ActionRestore, id, nil)
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("success"))
+}
+
+// connectionAttachersHandler lists the rule components holding the connection
+func connectionAttachersHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	id := mux.Vars(r)["id"]
+	if err := validate.ValidateID(id); err != nil {
+		handleError(w, err, "", logger)
+		return
+	}
+	if err := connection.CheckNamespace(namespaceContext(r), id); err != nil {
+		handleError(w, err, "", logger)
+		return
+	}
+	attachers, err := connection.GetConnectionAttachers(id)
+	if err != nil {
+		handleError(w, err, "get connection attachers failed", logger)
+		return
+	}
+	jsonResponse(attachers, w, logger)
+}
+
+// connectionRefAuditHandler returns the attaches and detaches of the connection recorded in the ref audit mode
+func connectionRefAuditHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	id := mux.Vars(r)["id"]
+	if err := validate.ValidateID(id); err != nil {
+		handleError(w, err, "", logger)
+		return
+	}
+	if err := connection.CheckNamespace(namespaceContext(r), id); err != nil {
+		handleError(w, err, "", logger)
+		return
+	}
+	refAudit, err := connection.GetConnectionRefAudit(id)
+	if err != nil {
+		handleError(w, err, "get connection ref audit failed", logger)
+		return
+	}
+	jsonResponse(refAudit, w, logger)
+}
+
+// connectionEventsHandler returns the latest lifecycle events of the connection
+func connectionEventsHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	id := mux.Vars(r)["id"]
+	if err := validate.ValidateID(id); err != nil {
+		handleError(w, err, "", logger)
+		return
+	}
+	if err := connection.CheckNamespace(namespaceContext(r), id); err != nil {
+		handleError(w, err, "", logger)
+		return
+	}
+	limit := 0
+	if l := r.URL.Query().Get("limit"); l != "" {
+		var err error
+		if limit, err = strconv.Atoi(l); err != nil || limit < 0 {
+			handleError(w, fmt.Errorf("invalid limit %s", l), "", logger)
+			return
+		}
+	}
+	events, err := connection.GetConnectionEvents(id, limit)
+	if err != nil {
+		handleError(w, err, "get connection events failed", logger)
+		return
+	}
+	jsonResponse(events, w, logger)
+}
+
+// connectionLabelsHandler replaces the labels of the named connection without reconnecting
+func connectionLabelsHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	id := mux.Vars(r)["id"]
+	if err := validate.ValidateID(id); err != nil {
+		handleError(w, err, "", logger)
+		return
+	}
+	labels := make(map[string]string)
+	if err := json.NewDecoder(r.Body).Decode(&labels); err != nil {
+		handleError(w, err, "Invalid body", logger)
+		return
+	}
+	before := connectionAuditState(id)
+	if err := connection.SetConnectionLabels(namespaceContext(r), id, labels); err != nil {
+		handleError(w, err, "set connection labels failed", logger)
+		return
+	}
+	recordConnectionAudit(middleware.Actor(r), audit.ActionUpdate, id, before)
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("success"))
+}
+
+// connectionPauseHandler closes the transport of the named connection for maintenance
+func connectionPauseHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	id := mux.Vars(r)["id"]
+	if err := validate.ValidateID(id); err != nil {
+		handleError(w, err, "", logger)
+		return
+	}
+	if err := connection.PauseConnection(namespaceContext(r), id); err != nil {
+		handleError(w, err, "pause connection failed", logger)
+		return
+	}
+	recordConnectionAudit(middleware.Actor(r), audit.ActionPause, id, nil)
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("success"))
+}
+
+// connectionResumeHandler connects the paused connection again
+func connectionResumeHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	id := mux.Vars(r)["id"]
+	if err := validate.ValidateID(id); err != nil {
+		handleError(w, err, "", logger)
+		return
+	}
+	if err := connection.ResumeConnection(namespaceContext(r), id); err != nil {
+		handleError(w, err, "resume connection failed", logger)
+		return
+	}
+	recordConnectionAudit(middleware.Actor(r), audit.ActionResume, id, nil)
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("success"))
+}
+
+// connectionRotateHandler reloads the cert files of the named connection and connects it again
+func connectionRotateHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	id := mux.Vars(r)["id"]
+	if err := validate.ValidateID(id); err != nil {
+		handleError(w, err, "", logger)
+		return
+	}
+	if err := connection.RotateConnectionCerts(namespaceContext(r), id); err != nil {
+		handleError(w, err, "rotate connection certs failed", logger)
+		return
+	}
+	recordConnectionAudit(middleware.Actor(r), audit.ActionRotate, id, nil)
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("success"))
+}
+
 func getConnectionRespByMeta(meta *connection.Meta) *ConnectionResponse {
 	status, e := meta.GetStatus()
+	return connectionRespWithStatus(meta, status, e)
+}
+
+func connectionRespWithStatus(meta *connection.Meta, status, e string) *ConnectionResponse {
 	r := &ConnectionResponse{
 		Typ:      meta.Typ,
 		ID:       meta.ID,
-		Props:    meta.Props,
+		Props:    connection.MaskSecrets(meta.Props),
 		IsNamed:  meta.Named,
 		RefCount: meta.GetRefCount(),
 		Status:   status,
 		Err:      e,
+		Labels:   meta.Labels(),
+	}
+	if res := meta.Resources(); res != (modules.ResourceUsage{}) {
+		r.Resources = &res
+	}
+	if active, ok := meta.ActiveEndpoint(); ok {
+		r.ActiveEndpoint = &active
+	}
+	if ds, ok := meta.DriverStatus(); ok {
+		r.DriverStatus = &ds
 	}
 	return r
 }
+
+// connectionTuningHandler reads or changes the connection defaults at runtime. The changes are persisted and
+// only the fields in the request body are changed.
+func connectionTuningHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	switch r.Method {
+	case http.MethodGet:
+		jsonResponse(connection.GetTuning(), w, logger)
+	case http.MethodPatch:
+		t := connection.GetTuning()
+		if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+			handleError(w, err, "Invalid body", logger)
+			return
+		}
+		if err := connection.SetTuning(t); err != nil {
+			handleError(w, err, "set connection tuning failed", logger)
+			return
+		}
+		jsonResponse(t, w, logger)
+	}
+}
+
+// reconcileDeclaredConnections makes the named connections match etc/connections.yaml if it exists
+func reconcileDeclaredConnections() {
+	loc, err := conf.GetConfLoc()
+	if err != nil {
+		conf.Log.Warnf("get conf location failed: %v", err)
+		return
+	}
+	d, err := connection.LoadDeclaration(filepath.Join(loc, connection.DeclaredConnectionsFile))
+	if err != nil {
+		conf.Log.Errorf("load declared connections failed: %v", err)
+		return
+	}
+	if d == nil {
+		return
+	}
+	report := connection.Reconcile(context.Background(), d)
+	for id, e := range report.Failed {
+		conf.Log.Warnf("reconcile declared connection %s failed: %s", id, e)
+	}
+}
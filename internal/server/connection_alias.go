@@ -0,0 +1,121 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/audit"
+	"github.com/lf-edge/ekuiper/v2/internal/server/middleware"
+	"github.com/lf-edge/ekuiper/v2/pkg/connection"
+	"github.com/lf-edge/ekuiper/v2/pkg/validate"
+)
+
+// CloneRequest is the named connection to clone with the props to override. A prop overridden by null is removed.
+type CloneRequest struct {
+	ID    string         `json:"id"`
+	Props map[string]any `json:"props"`
+}
+
+// connectionCloneHandler creates a named connection by copying the connection in the path
+func connectionCloneHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	srcId := mux.Vars(r)["id"]
+	if err := validate.ValidateID(srcId); err != nil {
+		handleError(w, err, "", logger)
+		return
+	}
+	req := &CloneRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		handleError(w, err, "Invalid body", logger)
+		return
+	}
+	if err := validate.ValidateID(req.ID); err != nil {
+		handleError(w, err, "", logger)
+		return
+	}
+	if _, err := connection.CloneConnection(namespaceContext(r), srcId, req.ID, req.Props); err != nil {
+		handleError(w, err, "clone connection failed", logger)
+		return
+	}
+	recordConnectionAudit(middleware.Actor(r), audit.ActionCreate, req.ID, nil)
+	w.WriteHeader(http.StatusCreated)
+	w.Write([]byte("success"))
+}
+
+func connectionAliasesHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	switch r.Method {
+	case http.MethodGet:
+		list := connection.ListAliases()
+		if ns, ok := middleware.Namespace(r); ok {
+			filtered := make([]connection.ConnectionAlias, 0, len(list))
+			for _, a := range list {
+				if connection.ConnectionNamespace(a.Alias) == ns {
+					filtered = append(filtered, a)
+				}
+			}
+			list = filtered
+		}
+		jsonResponse(list, w, logger)
+	case http.MethodPost:
+		a := &connection.ConnectionAlias{}
+		if err := json.NewDecoder(r.Body).Decode(a); err != nil {
+			handleError(w, err, "Invalid body", logger)
+			return
+		}
+		if err := validate.ValidateID(a.Alias); err != nil {
+			handleError(w, err, "", logger)
+			return
+		}
+		if err := connection.AliasConnection(namespaceContext(r), a.Alias, a.Target); err != nil {
+			handleError(w, err, "create connection alias failed", logger)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("success"))
+	}
+}
+
+func connectionAliasHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	alias := mux.Vars(r)["alias"]
+	if err := validate.ValidateID(alias); err != nil {
+		handleError(w, err, "", logger)
+		return
+	}
+	switch r.Method {
+	case http.MethodPut:
+		a := &connection.ConnectionAlias{}
+		if err := json.NewDecoder(r.Body).Decode(a); err != nil {
+			handleError(w, err, "Invalid body", logger)
+			return
+		}
+		if err := connection.AliasConnection(namespaceContext(r), alias, a.Target); err != nil {
+			handleError(w, err, "switch connection alias failed", logger)
+			return
+		}
+	case http.MethodDelete:
+		if err := connection.DeleteAlias(namespaceContext(r), alias); err != nil {
+			handleError(w, err, "delete connection alias failed", logger)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("success"))
+}
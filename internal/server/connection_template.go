@@ -0,0 +1,135 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/audit"
+	"github.com/lf-edge/ekuiper/v2/internal/server/middleware"
+	"github.com/lf-edge/ekuiper/v2/pkg/connection"
+	"github.com/lf-edge/ekuiper/v2/pkg/validate"
+)
+
+// TemplateInstanceRequest is the named connection to instantiate from a template
+type TemplateInstanceRequest struct {
+	ID     string         `json:"id"`
+	Params map[string]any `json:"params"`
+}
+
+func connectionTemplatesHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	switch r.Method {
+	case http.MethodGet:
+		list, err := connection.ListConnectionTemplates()
+		if err != nil {
+			handleError(w, err, "list connection templates failed", logger)
+			return
+		}
+		for _, t := range list {
+			t.Props = connection.MaskSecrets(t.Props)
+		}
+		jsonResponse(list, w, logger)
+	case http.MethodPost:
+		t := &connection.ConnectionTemplate{}
+		if err := json.NewDecoder(r.Body).Decode(t); err != nil {
+			handleError(w, err, "Invalid body", logger)
+			return
+		}
+		if err := validate.ValidateID(t.ID); err != nil {
+			handleError(w, err, "", logger)
+			return
+		}
+		if err := connection.CreateConnectionTemplate(t); err != nil {
+			handleError(w, err, "create connection template failed", logger)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("success"))
+	}
+}
+
+func connectionTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	id := mux.Vars(r)["id"]
+	if err := validate.ValidateID(id); err != nil {
+		handleError(w, err, "", logger)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		t, err := connection.GetConnectionTemplate(id)
+		if err != nil {
+			handleError(w, err, "", logger)
+			return
+		}
+		t.Props = connection.MaskSecrets(t.Props)
+		jsonResponse(t, w, logger)
+	case http.MethodPut:
+		t := &connection.ConnectionTemplate{}
+		if err := json.NewDecoder(r.Body).Decode(t); err != nil {
+			handleError(w, err, "Invalid body", logger)
+			return
+		}
+		propagate, _ := strconv.ParseBool(r.URL.Query().Get("propagate"))
+		result, err := connection.UpdateConnectionTemplate(namespaceContext(r), id, t.Props, propagate)
+		if err != nil {
+			handleError(w, err, "update connection template failed", logger)
+			return
+		}
+		actor := middleware.Actor(r)
+		for _, cid := range result.Updated {
+			recordConnectionAudit(actor, audit.ActionUpdate, cid, nil)
+		}
+		jsonResponse(result, w, logger)
+	case http.MethodDelete:
+		if err := connection.DropConnectionTemplate(id); err != nil {
+			handleError(w, err, "drop connection template failed", logger)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("success"))
+	}
+}
+
+// connectionTemplateInstantiateHandler creates a named connection from the template with the param values
+func connectionTemplateInstantiateHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	templateId := mux.Vars(r)["id"]
+	if err := validate.ValidateID(templateId); err != nil {
+		handleError(w, err, "", logger)
+		return
+	}
+	req := &TemplateInstanceRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		handleError(w, err, "Invalid body", logger)
+		return
+	}
+	if err := validate.ValidateID(req.ID); err != nil {
+		handleError(w, err, "", logger)
+		return
+	}
+	if _, err := connection.InstantiateConnectionTemplate(namespaceContext(r), templateId, req.ID, req.Params); err != nil {
+		handleError(w, err, "instantiate connection template failed", logger)
+		return
+	}
+	recordConnectionAudit(middleware.Actor(r), audit.ActionCreate, req.ID, nil)
+	w.WriteHeader(http.StatusCreated)
+	w.Write([]byte("success"))
+}
@@ -20,10 +20,13 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"time"
 
+	"github.com/gorilla/websocket"
 	"github.com/stretchr/testify/require"
 
+	topoContext "github.com/lf-edge/ekuiper/v2/internal/topo/context"
 	"github.com/lf-edge/ekuiper/v2/pkg/connection"
 	"github.com/lf-edge/ekuiper/v2/pkg/modules"
 )
@@ -76,6 +79,38 @@ func (suite *RestTestSuite) TestGetConnectionStatus() {
 	require.Equal(suite.T(), `{"id":"conn1","typ":"mock","props":{"datasource":"/test1","method":"post"},"isNamed":true,"status":"connected"}`, string(returnVal))
 	require.Equal(suite.T(), w.Header().Get("Content-Type"), "application/json")
 
+	req, _ = http.NewRequest(http.MethodGet, "http://localhost:8080/connections/conn1?stats=true", bytes.NewBufferString("any"))
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusOK, w.Code)
+	resp := &ConnectionResponse{}
+	require.NoError(suite.T(), json.NewDecoder(w.Result().Body).Decode(resp))
+	require.NotNil(suite.T(), resp.Stats)
+	require.NotZero(suite.T(), resp.Stats.CreatedAt)
+	require.NotZero(suite.T(), resp.Stats.ConnectedAt)
+
+	req, _ = http.NewRequest(http.MethodGet, "http://localhost:8080/connections/conn1/attachers", bytes.NewBufferString("any"))
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusOK, w.Code)
+	returnVal, _ = io.ReadAll(w.Result().Body)
+	require.Equal(suite.T(), `[]`, string(returnVal))
+	req, _ = http.NewRequest(http.MethodGet, "http://localhost:8080/connections/nonexist/attachers", bytes.NewBufferString("any"))
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusNotFound, w.Code)
+
+	req, _ = http.NewRequest(http.MethodPost, "http://localhost:8080/connections/validate", bytes.NewBufferString(`{"typ":"mock","props":{"method":"post"}}`))
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusOK, w.Code)
+	returnVal, _ = io.ReadAll(w.Result().Body)
+	require.Equal(suite.T(), `{"valid":true}`, string(returnVal))
+	req, _ = http.NewRequest(http.MethodPost, "http://localhost:8080/connections/validate", bytes.NewBufferString(`{"typ":"nonexist"}`))
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
 	connJson = `
 {
   "id": "conn1",
@@ -99,6 +134,25 @@ func (suite *RestTestSuite) TestGetConnectionStatus() {
 	returnVal, _ = io.ReadAll(w.Result().Body)
 	require.Equal(suite.T(), `{"id":"conn1","typ":"mock","props":{"datasource":"/test2","method":"post"},"isNamed":true,"status":"connected"}`, string(returnVal))
 	require.Equal(suite.T(), w.Header().Get("Content-Type"), "application/json")
+
+	req, _ = http.NewRequest(http.MethodPut, "http://localhost:8080/connections/conn1/labels", bytes.NewBufferString(`{"site":"plant1"}`))
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusOK, w.Code)
+	req, _ = http.NewRequest(http.MethodGet, "http://localhost:8080/connections?selector=site%3Dplant1", bytes.NewBufferString("any"))
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusOK, w.Code)
+	var labeled []*ConnectionResponse
+	require.NoError(suite.T(), json.NewDecoder(w.Result().Body).Decode(&labeled))
+	require.Len(suite.T(), labeled, 1)
+	require.Equal(suite.T(), map[string]string{"site": "plant1"}, labeled[0].Labels)
+	req, _ = http.NewRequest(http.MethodGet, "http://localhost:8080/connections?selector=site%3Dplant2", bytes.NewBufferString("any"))
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusOK, w.Code)
+	returnVal, _ = io.ReadAll(w.Result().Body)
+	require.Equal(suite.T(), `[]`, string(returnVal))
 }
 
 func (suite *RestTestSuite) TestEditInternalConn() {
@@ -140,3 +194,36 @@ func (suite *RestTestSuite) TestEditInternalConn() {
 	suite.r.ServeHTTP(w, req)
 	require.Equal(suite.T(), http.StatusBadRequest, w.Code)
 }
+
+func (suite *RestTestSuite) TestConnectionStatusWebsocket() {
+	require.NoError(suite.T(), connection.InitConnectionManager4Test())
+	ctx := topoContext.Background()
+	_, err := connection.CreateNamedConnection(ctx, "ws1", "mock", map[string]any{})
+	require.NoError(suite.T(), err)
+	_, err = connection.CreateNamedConnection(ctx, "ws2", "mock", map[string]any{})
+	require.NoError(suite.T(), err)
+	defer connection.DropNameConnection(ctx, "ws2")
+
+	ts := httptest.NewServer(suite.r)
+	defer ts.Close()
+	c, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/connections/status/ws?id=ws1", nil)
+	require.NoError(suite.T(), err)
+	defer c.Close()
+	_ = c.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	ev := &connectionStatusEvent{}
+	require.NoError(suite.T(), c.ReadJSON(ev))
+	require.Equal(suite.T(), connection.ReplicaPut, ev.Type)
+	require.Equal(suite.T(), "ws1", ev.ID)
+
+	require.NoError(suite.T(), connection.DropNameConnection(ctx, "ws2"))
+	require.NoError(suite.T(), connection.DropNameConnection(ctx, "ws1"))
+	for {
+		ev = &connectionStatusEvent{}
+		require.NoError(suite.T(), c.ReadJSON(ev))
+		require.Equal(suite.T(), "ws1", ev.ID)
+		if ev.Type == connection.ReplicaDelete {
+			break
+		}
+	}
+}
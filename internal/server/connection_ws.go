@@ -0,0 +1,100 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/pkg/connection"
+)
+
+const (
+	statusWsWriteWait    = 10 * time.Second
+	statusWsPingInterval = 30 * time.Second
+)
+
+var statusUpgrader = websocket.Upgrader{
+	ReadBufferSize:  256,
+	WriteBufferSize: 1024,
+	// the dashboard may be served from another origin, the same as the CORS setting of the rest api
+	CheckOrigin: func(r *http.Request) bool {
+		return true
+	},
+}
+
+// connectionStatusWsHandler pushes the named connection changes to the websocket client. It sends the current
+// connections first and then the status changes, so the dashboard does not need to poll the connection status.
+// The connections to watch can be filtered by the id query parameters.
+func connectionStatusWsHandler(w http.ResponseWriter, r *http.Request) {
+	c, err := statusUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		conf.Log.Errorf("connection status websocket upgrade error: %v", err)
+		return
+	}
+	defer c.Close()
+	filter := make(map[string]struct{})
+	for _, id := range r.URL.Query()["id"] {
+		filter[id] = struct{}{}
+	}
+	send := func(ev connection.ReplicaEvent) error {
+		if _, ok := filter[ev.ID]; len(filter) > 0 && !ok {
+			return nil
+		}
+		_ = c.SetWriteDeadline(time.Now().Add(statusWsWriteWait))
+		return c.WriteJSON(&connectionStatusEvent{Type: ev.Type, ID: ev.ID, Status: ev.Status, Err: ev.LastError})
+	}
+	snapshot, ch, cancel := connection.SubscribeReplica(replicationBuffer)
+	defer cancel()
+	// read the client messages to handle the control frames and detect the close
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := c.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+	for _, ev := range snapshot {
+		if err := send(ev); err != nil {
+			return
+		}
+	}
+	ticker := time.NewTicker(statusWsPingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-closed:
+			return
+		case <-ticker.C:
+			if err := c.WriteControl(websocket.PingMessage, nil, time.Now().Add(statusWsWriteWait)); err != nil {
+				return
+			}
+		case ev, ok := <-ch:
+			if !ok {
+				// too slow to consume, let the client reconnect to get a new snapshot
+				_ = c.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "too slow"), time.Now().Add(statusWsWriteWait))
+				return
+			}
+			if err := send(ev); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build fault || test
+
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/fault"
+)
+
+// The fault injection API is only served in the non-production builds with the fault or test tag
+
+func init() {
+	components["fault"] = faultComp{}
+}
+
+type faultComp struct{}
+
+func (f faultComp) register() {
+	// do nothing
+}
+
+func (f faultComp) rest(r *mux.Router) {
+	r.HandleFunc("/faults", faultsHandler).Methods(http.MethodGet, http.MethodDelete)
+	r.HandleFunc("/faults/{name}", faultHandler).Methods(http.MethodPut, http.MethodDelete)
+}
+
+type faultsResponse struct {
+	Points []fault.Point `json:"points"`
+	Faults []fault.Fault `json:"faults"`
+}
+
+// faultsHandler lists the injection points and the active faults, or removes all the faults
+func faultsHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	switch r.Method {
+	case http.MethodGet:
+		jsonResponse(&faultsResponse{Points: fault.Points(), Faults: fault.List()}, w, logger)
+	case http.MethodDelete:
+		fault.Reset()
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("success"))
+	}
+}
+
+func faultHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	name := mux.Vars(r)["name"]
+	switch r.Method {
+	case http.MethodPut:
+		f := fault.Fault{}
+		if err := json.NewDecoder(r.Body).Decode(&f); err != nil {
+			handleError(w, err, "Invalid body", logger)
+			return
+		}
+		f.Name = name
+		f, err := fault.Set(f)
+		if err != nil {
+			handleError(w, err, "inject fault error", logger)
+			return
+		}
+		logger.Warnf("fault %s is injected", name)
+		jsonResponse(f, w, logger)
+	case http.MethodDelete:
+		if r.URL.Query().Has("connection") {
+			fault.RemoveFor(name, r.URL.Query().Get("connection"))
+		} else {
+			fault.Remove(name)
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("success"))
+	}
+}
@@ -0,0 +1,383 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/audit"
+	"github.com/lf-edge/ekuiper/v2/internal/server/middleware"
+	topoContext "github.com/lf-edge/ekuiper/v2/internal/topo/context"
+	"github.com/lf-edge/ekuiper/v2/pkg/connection"
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+	"github.com/lf-edge/ekuiper/v2/pkg/syncx"
+	"github.com/lf-edge/ekuiper/v2/pkg/tracer"
+	"github.com/lf-edge/ekuiper/v2/pkg/validate"
+)
+
+// The gRPC management API mirrors the REST APIs of connections and traces for the fleet management platforms. The
+// messages are the same JSON as the REST APIs by the json codec, so the clients set the content subtype to json.
+
+const managementServiceName = "kuiper.Management"
+
+type connectionIDRequest struct {
+	ID string `json:"id"`
+}
+
+type listConnectionsRequest struct {
+	ForceAll bool `json:"forceAll"`
+}
+
+type listConnectionsResponse struct {
+	Connections []*ConnectionResponse `json:"connections"`
+}
+
+type watchConnectionStatusRequest struct {
+	// IDs filters the connections to watch. Empty means all named connections.
+	IDs []string `json:"ids,omitempty"`
+}
+
+// connectionStatusEvent is the status change of a named connection. Type is put, delete or status.
+type connectionStatusEvent struct {
+	Type   string `json:"type"`
+	ID     string `json:"id"`
+	Status string `json:"status,omitempty"`
+	Err    string `json:"err,omitempty"`
+}
+
+type getTraceRequest struct {
+	TraceID string `json:"traceId"`
+}
+
+type listRuleTracesRequest struct {
+	RuleID string `json:"ruleId"`
+	Limit  int64  `json:"limit"`
+}
+
+type findTracesRequest struct {
+	Key   string    `json:"key"`
+	Value string    `json:"value"`
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	Limit int64     `json:"limit"`
+}
+
+type traceIDsResponse struct {
+	TraceIDs []string `json:"traceIds"`
+}
+
+type emptyMessage struct{}
+
+// managementService is the handler type of the service. The methods are bound by closures.
+type managementService interface{}
+
+func unaryMethod[Req any](name string, call func(ctx context.Context, req *Req) (any, error)) grpc.MethodDesc {
+	return grpc.MethodDesc{
+		MethodName: name,
+		Handler: func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+			req := new(Req)
+			if err := dec(req); err != nil {
+				return nil, err
+			}
+			handler := func(ctx context.Context, r any) (any, error) {
+				resp, err := call(ctx, r.(*Req))
+				return resp, toGrpcError(err)
+			}
+			if interceptor == nil {
+				return handler(ctx, req)
+			}
+			info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + managementServiceName + "/" + name}
+			return interceptor(ctx, req, info, handler)
+		},
+	}
+}
+
+var managementServiceDesc = grpc.ServiceDesc{
+	ServiceName: managementServiceName,
+	HandlerType: (*managementService)(nil),
+	Methods: []grpc.MethodDesc{
+		unaryMethod("ListConnections", func(ctx context.Context, req *listConnectionsRequest) (any, error) {
+			metas := connection.GetAllConnectionsMeta(req.ForceAll)
+			statuses := connection.GetConnectionsStatus(ctx, metas)
+			resp := &listConnectionsResponse{Connections: make([]*ConnectionResponse, 0, len(metas))}
+			for i, meta := range metas {
+				resp.Connections = append(resp.Connections, connectionRespWithStatus(meta, statuses[i].Status, statuses[i].Err))
+			}
+			return resp, nil
+		}),
+		unaryMethod("GetConnection", func(_ context.Context, req *connectionIDRequest) (any, error) {
+			return getConnectionResp(req.ID)
+		}),
+		unaryMethod("CreateConnection", func(ctx context.Context, req *ConnectionRequest) (any, error) {
+			if err := validate.ValidateID(req.ID); err != nil {
+				return nil, status.Error(codes.InvalidArgument, err.Error())
+			}
+			if _, err := connection.CreateNamedConnection(topoContext.Background(), req.ID, req.Typ, req.Props); err != nil {
+				return nil, err
+			}
+			recordConnectionAudit(grpcActor(ctx), audit.ActionCreate, req.ID, nil)
+			return getConnectionResp(req.ID)
+		}),
+		unaryMethod("UpdateConnection", func(ctx context.Context, req *ConnectionRequest) (any, error) {
+			if err := validate.ValidateID(req.ID); err != nil {
+				return nil, status.Error(codes.InvalidArgument, err.Error())
+			}
+			before := connectionAuditState(req.ID)
+			if err := updateConnection(topoContext.Background(), req.ID, req.Typ, req.Props); err != nil {
+				return nil, err
+			}
+			recordConnectionAudit(grpcActor(ctx), audit.ActionUpdate, req.ID, before)
+			return getConnectionResp(req.ID)
+		}),
+		unaryMethod("DeleteConnection", func(ctx context.Context, req *connectionIDRequest) (any, error) {
+			before := connectionAuditState(req.ID)
+			if err := connection.DropNameConnection(topoContext.Background(), req.ID); err != nil {
+				return nil, err
+			}
+			recordConnectionAudit(grpcActor(ctx), audit.ActionDelete, req.ID, before)
+			return &emptyMessage{}, nil
+		}),
+		unaryMethod("RestoreConnection", func(ctx context.Context, req *connectionIDRequest) (any, error) {
+			if _, err := connection.RestoreConnection(topoContext.Background(), req.ID); err != nil {
+				return nil, err
+			}
+			recordConnectionAudit(grpcActor(ctx), audit.ActionRestore, req.ID, nil)
+			return getConnectionResp(req.ID)
+		}),
+		unaryMethod("GetTrace", func(_ context.Context, req *getTraceRequest) (any, error) {
+			root, err := tracer.GetTraceByID(req.TraceID)
+			if err != nil {
+				return nil, err
+			}
+			if root == nil {
+				return nil, errorx.NewWithCode(errorx.NOT_FOUND, fmt.Sprintf("trace %s is not found", req.TraceID))
+			}
+			return root, nil
+		}),
+		unaryMethod("ListRuleTraces", func(_ context.Context, req *listRuleTracesRequest) (any, error) {
+			ids, err := tracer.GetLatestTraceIDsByRule(req.RuleID, req.Limit)
+			if err != nil {
+				return nil, err
+			}
+			return &traceIDsResponse{TraceIDs: ids}, nil
+		}),
+		unaryMethod("FindTraces", func(_ context.Context, req *findTracesRequest) (any, error) {
+			if req.Key == "" {
+				return nil, status.Error(codes.InvalidArgument, "key is required")
+			}
+			ids, err := tracer.GetTraceIDListByAttribute(req.Key, req.Value, req.Start, req.End, req.Limit)
+			if err != nil {
+				return nil, err
+			}
+			return &traceIDsResponse{TraceIDs: ids}, nil
+		}),
+	},
+	Streams: []grpc.StreamDesc{{
+		StreamName:    "WatchConnectionStatus",
+		ServerStreams: true,
+		Handler: func(_ any, ss grpc.ServerStream) error {
+			req := &watchConnectionStatusRequest{}
+			if err := ss.RecvMsg(req); err != nil {
+				return err
+			}
+			return watchConnectionStatus(req, ss)
+		},
+	}},
+}
+
+func getConnectionResp(id string) (*ConnectionResponse, error) {
+	meta, err := connection.GetConnectionDetail(topoContext.Background(), id)
+	if err != nil {
+		return nil, err
+	}
+	return getConnectionRespByMeta(meta), nil
+}
+
+// watchConnectionStatus sends the current status of the connections and then their changes
+func watchConnectionStatus(req *watchConnectionStatusRequest, ss grpc.ServerStream) error {
+	filter := make(map[string]struct{}, len(req.IDs))
+	for _, id := range req.IDs {
+		filter[id] = struct{}{}
+	}
+	send := func(ev connection.ReplicaEvent) error {
+		if _, ok := filter[ev.ID]; len(filter) > 0 && !ok {
+			return nil
+		}
+		return ss.SendMsg(&connectionStatusEvent{Type: ev.Type, ID: ev.ID, Status: ev.Status, Err: ev.LastError})
+	}
+	snapshot, ch, cancel := connection.SubscribeReplica(replicationBuffer)
+	defer cancel()
+	for _, ev := range snapshot {
+		if err := send(ev); err != nil {
+			return err
+		}
+	}
+	for {
+		select {
+		case <-ss.Context().Done():
+			return nil
+		case ev, ok := <-ch:
+			if !ok {
+				return status.Error(codes.ResourceExhausted, "the client is too slow to receive the status")
+			}
+			if err := send(ev); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// toGrpcError converts the error to the gRPC status by the error code like the REST status code
+func toGrpcError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	c := codes.InvalidArgument
+	code, _ := errorx.GetErrorCode(err)
+	switch {
+	case code == errorx.NOT_FOUND:
+		c = codes.NotFound
+	case code == errorx.ConnectionExistErr:
+		c = codes.AlreadyExists
+	case code == errorx.ConnectionInUseErr || code == errorx.ConnectionReadOnlyErr:
+		c = codes.FailedPrecondition
+	case code == errorx.ConnectionConflictErr:
+		c = codes.Aborted
+	case code == errorx.TracerDisabledErr:
+		c = codes.Unimplemented
+	case errorx.KindOf(err) == errorx.KindQuota:
+		c = codes.ResourceExhausted
+	}
+	return status.Error(c, err.Error())
+}
+
+// readOnlyMethods can be called by the api keys with the read scope
+var readOnlyMethods = map[string]bool{
+	"ListConnections":       true,
+	"GetConnection":         true,
+	"WatchConnectionStatus": true,
+	"GetTrace":              true,
+	"ListRuleTraces":        true,
+	"FindTraces":            true,
+}
+
+func firstMetadata(md metadata.MD, key string) string {
+	if v := md.Get(key); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+// grpcActor returns who calls the method for the audit log
+func grpcActor(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	var client string
+	if p, ok := peer.FromContext(ctx); ok {
+		client = p.Addr.String()
+	}
+	return middleware.ActorOf(firstMetadata(md, "authorization"), firstMetadata(md, strings.ToLower(middleware.APIKeyHeader)), client)
+}
+
+func authenticate(ctx context.Context, fullMethod string) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if conf.Config.Basic.Authentication {
+		if err := middleware.ValidateToken(firstMetadata(md, "authorization")); err != nil {
+			return status.Error(codes.Unauthenticated, err.Error())
+		}
+	}
+	var client string
+	if p, ok := peer.FromContext(ctx); ok {
+		client, _, _ = net.SplitHostPort(p.Addr.String())
+	}
+	method := fullMethod[strings.LastIndex(fullMethod, "/")+1:]
+	code, err := middleware.AuthorizeAPIKey(firstMetadata(md, strings.ToLower(middleware.APIKeyHeader)), client, readOnlyMethods[method])
+	switch code {
+	case 0:
+		return nil
+	case http.StatusUnauthorized:
+		return status.Error(codes.Unauthenticated, err.Error())
+	case http.StatusForbidden:
+		return status.Error(codes.PermissionDenied, err.Error())
+	default:
+		return status.Error(codes.ResourceExhausted, err.Error())
+	}
+}
+
+var (
+	managementMu  syncx.Mutex
+	managementSrv *grpc.Server
+)
+
+// startManagementGrpc serves the gRPC management API on the address and returns the listening address
+func startManagementGrpc(addr string) (net.Addr, error) {
+	managementMu.Lock()
+	defer managementMu.Unlock()
+	if managementSrv != nil {
+		return nil, errors.New("grpc management api is already started")
+	}
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	s := grpc.NewServer(
+		grpc.ForceServerCodec(jsonCodec{}),
+		grpc.UnaryInterceptor(func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			if err := authenticate(ctx, info.FullMethod); err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.StreamInterceptor(func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if err := authenticate(ss.Context(), info.FullMethod); err != nil {
+				return err
+			}
+			return handler(srv, ss)
+		}),
+	)
+	s.RegisterService(&managementServiceDesc, struct{}{})
+	managementSrv = s
+	go func() {
+		if err := s.Serve(lis); err != nil {
+			conf.Log.Errorf("grpc management api stopped with error: %v", err)
+		}
+	}()
+	conf.Log.Infof("grpc management api listening on %s", lis.Addr().String())
+	return lis.Addr(), nil
+}
+
+func stopManagementGrpc() {
+	managementMu.Lock()
+	defer managementMu.Unlock()
+	if managementSrv != nil {
+		managementSrv.Stop()
+		managementSrv = nil
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/pkg/connection"
+)
+
+func TestManagementGrpc(t *testing.T) {
+	conf.InitConf()
+	require.NoError(t, connection.InitConnectionManager4Test())
+	addr, err := startManagementGrpc("127.0.0.1:0")
+	require.NoError(t, err)
+	defer stopManagementGrpc()
+
+	cc, err := grpc.NewClient(addr.String(),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultCallOptions(grpc.ForceCodec(jsonCodec{})))
+	require.NoError(t, err)
+	defer cc.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	method := func(name string) string {
+		return "/" + managementServiceName + "/" + name
+	}
+
+	stream, err := cc.NewStream(ctx, &managementServiceDesc.Streams[0], method("WatchConnectionStatus"))
+	require.NoError(t, err)
+	require.NoError(t, stream.SendMsg(&watchConnectionStatusRequest{IDs: []string{"grpc1"}}))
+	require.NoError(t, stream.CloseSend())
+
+	created := &ConnectionResponse{}
+	require.NoError(t, cc.Invoke(ctx, method("CreateConnection"), &ConnectionRequest{ID: "grpc1", Typ: "mock", Props: map[string]any{"a": "b"}}, created))
+	require.Equal(t, "grpc1", created.ID)
+	err = cc.Invoke(ctx, method("CreateConnection"), &ConnectionRequest{ID: "grpc1", Typ: "mock", Props: map[string]any{"a": "b"}}, &ConnectionResponse{})
+	require.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	list := &listConnectionsResponse{}
+	require.NoError(t, cc.Invoke(ctx, method("ListConnections"), &listConnectionsRequest{}, list))
+	require.Len(t, list.Connections, 1)
+
+	got := &ConnectionResponse{}
+	require.NoError(t, cc.Invoke(ctx, method("GetConnection"), &connectionIDRequest{ID: "grpc1"}, got))
+	require.Equal(t, map[string]any{"a": "b"}, got.Props)
+
+	ev := &connectionStatusEvent{}
+	require.NoError(t, stream.RecvMsg(ev))
+	require.Equal(t, "grpc1", ev.ID)
+
+	require.NoError(t, cc.Invoke(ctx, method("DeleteConnection"), &connectionIDRequest{ID: "grpc1"}, &emptyMessage{}))
+	for {
+		ev = &connectionStatusEvent{}
+		require.NoError(t, stream.RecvMsg(ev))
+		if ev.Type == connection.ReplicaDelete {
+			require.Equal(t, "grpc1", ev.ID)
+			break
+		}
+	}
+	err = cc.Invoke(ctx, method("GetConnection"), &connectionIDRequest{ID: "grpc1"}, &ConnectionResponse{})
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
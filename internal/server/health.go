@@ -0,0 +1,120 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/store"
+	"github.com/lf-edge/ekuiper/v2/pkg/connection"
+	"github.com/lf-edge/ekuiper/v2/pkg/tracer"
+)
+
+const (
+	HealthUp       = "up"
+	HealthDegraded = "degraded"
+	HealthDown     = "down"
+)
+
+type storageHealth struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type healthReport struct {
+	Status      string                 `json:"status"`
+	Connections *connection.PoolHealth `json:"connections"`
+	Tracer      *tracer.ExporterHealth `json:"tracer"`
+	Storage     storageHealth          `json:"storage"`
+}
+
+// healthTimeout bounds the whole health check so that a hanging storage can't hang the probe
+var healthTimeout = 5 * time.Second
+
+// checkHealth aggregates the health of the components checked concurrently. The server is down if the storage is
+// unreachable in time, and degraded if any connection is disconnected or the latest span export failed.
+func checkHealth(ctx context.Context) *healthReport {
+	ctx, cancel := context.WithTimeout(ctx, healthTimeout)
+	defer cancel()
+	var (
+		wg         sync.WaitGroup
+		conns      *connection.PoolHealth
+		tracerH    *tracer.ExporterHealth
+		storageErr = make(chan error, 1)
+	)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		conns = connection.Health()
+	}()
+	go func() {
+		defer wg.Done()
+		tracerH = tracer.Health()
+	}()
+	go func() {
+		storageErr <- store.Ping(ctx)
+	}()
+	report := &healthReport{Status: HealthUp, Storage: storageHealth{Status: HealthUp}}
+	var err error
+	select {
+	case err = <-storageErr:
+	case <-ctx.Done():
+		err = fmt.Errorf("storage ping timeout: %w", ctx.Err())
+	}
+	wg.Wait()
+	report.Connections, report.Tracer = conns, tracerH
+	if report.Connections.States[api.ConnectionDisconnected] > 0 || !report.Tracer.Healthy() {
+		report.Status = HealthDegraded
+	}
+	if err != nil {
+		report.Storage = storageHealth{Status: HealthDown, Error: err.Error()}
+		report.Status = HealthDown
+	}
+	return report
+}
+
+// healthzHandler reports the readiness of the server. It responds 503 when the server is down so that
+// orchestrators can take it out of service. Use /ping for liveness.
+func healthzHandler(w http.ResponseWriter, r *http.Request) {
+	report := checkHealth(r.Context())
+	w.Header().Set(ContentType, ContentTypeJSON)
+	if report.Status == HealthDown {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		conf.Log.Errorf("write health report error: %v", err)
+	}
+}
+
+// readyHandler reports the readiness of the connections by the configured policy. It responds 503 when the
+// connections required by the policy are down.
+func readyHandler(w http.ResponseWriter, r *http.Request) {
+	report := connection.GetPoolHealth(namespaceContext(r))
+	w.Header().Set(ContentType, ContentTypeJSON)
+	if !report.Ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		conf.Log.Errorf("write readiness report error: %v", err)
+	}
+}
@@ -26,8 +26,10 @@ import (
 	"github.com/gorilla/mux"
 
 	"github.com/lf-edge/ekuiper/v2/internal/meta"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/context"
 	"github.com/lf-edge/ekuiper/v2/internal/topo/node"
 	"github.com/lf-edge/ekuiper/v2/pkg/ast"
+	"github.com/lf-edge/ekuiper/v2/pkg/modules"
 	"github.com/lf-edge/ekuiper/v2/pkg/validate"
 )
 
@@ -56,6 +58,10 @@ func (m metaComp) rest(r *mux.Router) {
 	r.HandleFunc("/metadata/sinks/{name}/confKeys/{confKey}", sinkConfKeyHandler).Methods(http.MethodDelete, http.MethodPut)
 
 	r.HandleFunc("/metadata/connections", connectionsMetaHandler).Methods(http.MethodGet)
+	r.HandleFunc("/metadata/connections/capabilities", connectionCapabilitiesHandler).Methods(http.MethodGet)
+	r.HandleFunc("/metadata/connections/types", connectionTypesHandler).Methods(http.MethodGet)
+	r.HandleFunc("/metadata/connections/descriptors", connectionDescriptorsHandler).Methods(http.MethodGet)
+	r.HandleFunc("/metadata/connections/descriptors/{name}", connectionDescriptorHandler).Methods(http.MethodGet)
 	r.HandleFunc("/metadata/connections/{name}", connectionMetaHandler).Methods(http.MethodGet)
 	r.HandleFunc("/metadata/connections/yaml/{name}", connectionConfHandler).Methods(http.MethodGet)
 	r.HandleFunc("/metadata/connections/{name}/confKeys/{confKey}", connectionConfKeyHandler).Methods(http.MethodDelete, http.MethodPut)
@@ -133,6 +139,35 @@ func connectionsMetaHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// connectionCapabilitiesHandler returns the capabilities of the connection types which declare them
+func connectionCapabilitiesHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	jsonResponse(modules.ConnectionCapabilities(context.Background()), w, logger)
+}
+
+// connectionTypesHandler returns the metadata of all the registered connection types
+func connectionTypesHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	jsonResponse(modules.ListConnectionTypes(context.Background()), w, logger)
+}
+
+// connectionDescriptorsHandler returns the descriptors of all connection types to render the forms
+func connectionDescriptorsHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	jsonResponse(meta.GetConnectionDescriptors(getLanguage(r)), w, logger)
+}
+
+func connectionDescriptorHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	vars := mux.Vars(r)
+	ret, err := meta.GetConnectionDescriptor(vars["name"], getLanguage(r))
+	if err != nil {
+		handleError(w, err, "", logger)
+		return
+	}
+	jsonResponse(ret, w, logger)
+}
+
 // Get source metadata when creating stream
 func sourceMetaHandler(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
@@ -237,6 +272,7 @@ func sourceConfKeyHandler(w http.ResponseWriter, r *http.Request) {
 		handleError(w, err, "Invalid confKey", logger)
 		return
 	}
+	before := confKeyAuditState("sources", pluginName, confKey)
 	switch r.Method {
 	case http.MethodDelete:
 		err = meta.DelSourceConfKey(pluginName, confKey, language)
@@ -252,6 +288,7 @@ func sourceConfKeyHandler(w http.ResponseWriter, r *http.Request) {
 		handleError(w, err, "", logger)
 		return
 	}
+	recordConfKeyAudit(r, "sources", pluginName, confKey, before)
 }
 
 // Add  del confkey
@@ -266,6 +303,7 @@ func sinkConfKeyHandler(w http.ResponseWriter, r *http.Request) {
 		handleError(w, err, "Invalid confKey", logger)
 		return
 	}
+	before := confKeyAuditState("sinks", pluginName, confKey)
 	switch r.Method {
 	case http.MethodDelete:
 		err = meta.DelSinkConfKey(pluginName, confKey, language)
@@ -281,6 +319,7 @@ func sinkConfKeyHandler(w http.ResponseWriter, r *http.Request) {
 		handleError(w, err, "", logger)
 		return
 	}
+	recordConfKeyAudit(r, "sinks", pluginName, confKey, before)
 }
 
 // Add  del confkey
@@ -295,6 +334,7 @@ func connectionConfKeyHandler(w http.ResponseWriter, r *http.Request) {
 		handleError(w, err, "Invalid confKey", logger)
 		return
 	}
+	before := confKeyAuditState("connections", pluginName, confKey)
 	switch r.Method {
 	case http.MethodDelete:
 		err = meta.DelConnectionConfKey(pluginName, confKey, language)
@@ -316,6 +356,7 @@ func connectionConfKeyHandler(w http.ResponseWriter, r *http.Request) {
 		handleError(w, err, "", logger)
 		return
 	}
+	recordConfKeyAudit(r, "connections", pluginName, confKey, before)
 }
 
 // get updatable resources
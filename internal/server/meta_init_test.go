@@ -138,6 +138,26 @@ func (suite *MetaTestSuite) TestConnectionsMetaHandler() {
 	assert.Equal(suite.T(), http.StatusOK, w.Code)
 }
 
+func (suite *MetaTestSuite) TestConnectionTypesHandler() {
+	modules.RegisterConnection("typesmock", connection.CreateMockConnection)
+	defer modules.UnregisterConnection("typesmock")
+	req, _ := http.NewRequest(http.MethodGet, "/metadata/connections/types", bytes.NewBufferString("any"))
+	w := httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusOK, w.Code)
+	var types []modules.ConnectionTypeInfo
+	require.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), &types))
+	found := false
+	for _, info := range types {
+		if info.Type == "typesmock" {
+			found = true
+			require.True(suite.T(), info.Ping)
+			require.False(suite.T(), info.Stateful)
+		}
+	}
+	require.True(suite.T(), found)
+}
+
 func (suite *MetaTestSuite) TestSourceMetaHandler() {
 	req, _ := http.NewRequest(http.MethodGet, "/metadata/sources/mqtt", bytes.NewBufferString("any"))
 	w := httptest.NewRecorder()
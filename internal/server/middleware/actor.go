@@ -0,0 +1,54 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package middleware
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/jwt"
+)
+
+// Actor returns who sends the request for the audit log
+func Actor(r *http.Request) string {
+	key := r.Header.Get(APIKeyHeader)
+	if key == "" {
+		key = r.URL.Query().Get("apiKey")
+	}
+	return ActorOf(r.Header.Get("Authorization"), key, r.RemoteAddr)
+}
+
+// ActorOf returns the api key name, the jwt subject or issuer, or the client address in order. It is shared by the
+// REST and gRPC APIs.
+func ActorOf(token, apiKey, client string) string {
+	if conf.Config != nil {
+		if k := findAPIKey(conf.Config.ManagementAuth.APIKeys, apiKey); k != nil {
+			return "apikey:" + k.Name
+		}
+	}
+	if token != "" {
+		if tk, err := jwt.ParseToken(token); err == nil {
+			if tk.RegisteredClaims.Subject != "" {
+				return "jwt:" + tk.RegisteredClaims.Subject
+			}
+			return "jwt:" + tk.RegisteredClaims.Issuer
+		}
+	}
+	if host, _, err := net.SplitHostPort(client); err == nil {
+		client = host
+	}
+	return "client:" + client
+}
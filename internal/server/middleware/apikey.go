@@ -0,0 +1,165 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package middleware
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"golang.org/x/time/rate"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/pkg/model"
+	"github.com/lf-edge/ekuiper/v2/pkg/syncx"
+)
+
+const (
+	APIKeyHeader = "X-API-Key"
+	ScopeRead    = "read"
+	ScopeAdmin   = "admin"
+	// maxLimiters bounds the limiters of the client ips
+	maxLimiters = 10000
+)
+
+// managementPaths are the prefixes of the connection and tracer management APIs protected by the api keys
+var managementPaths = []string{"/connections", "/tracer", "/trace/", "/configs/connection", "/configs/sync", "/configs/reload", "/audit"}
+
+type limiters struct {
+	syncx.Mutex
+	m map[string]*rate.Limiter
+}
+
+var apiLimiters = &limiters{m: make(map[string]*rate.Limiter)}
+
+func (l *limiters) allow(key string, r float64, burst int) bool {
+	l.Lock()
+	defer l.Unlock()
+	lim, ok := l.m[key]
+	if !ok || lim.Limit() != rate.Limit(r) || lim.Burst() != burst {
+		if len(l.m) >= maxLimiters {
+			l.m = make(map[string]*rate.Limiter)
+		}
+		lim = rate.NewLimiter(rate.Limit(r), burst)
+		l.m[key] = lim
+	}
+	return lim.Allow()
+}
+
+func findAPIKey(keys []model.APIKeyConf, key string) *model.APIKeyConf {
+	if key == "" {
+		return nil
+	}
+	for i := range keys {
+		if subtle.ConstantTimeCompare([]byte(keys[i].Key), []byte(key)) == 1 {
+			return &keys[i]
+		}
+	}
+	return nil
+}
+
+// AuthorizeAPIKey checks the api key scope and the rate limit of a management request. The client is used to limit
+// the rate when no api key is required. It returns the http status code along with the error. It is shared by the
+// REST and gRPC APIs.
+func AuthorizeAPIKey(key, client string, readOnly bool) (int, error) {
+	c := conf.Config.ManagementAuth
+	limitKey := "client:" + client
+	if len(c.APIKeys) > 0 {
+		k := findAPIKey(c.APIKeys, key)
+		if k == nil {
+			return http.StatusUnauthorized, errors.New("invalid_api_key")
+		}
+		if !readOnly && k.Scope != ScopeAdmin {
+			return http.StatusForbidden, fmt.Errorf("api key %s is read-only", k.Name)
+		}
+		limitKey = "key:" + k.Name
+	}
+	if c.RateLimit > 0 && !apiLimiters.allow(limitKey, c.RateLimit, c.Burst) {
+		return http.StatusTooManyRequests, errors.New("rate limit exceeded")
+	}
+	return 0, nil
+}
+
+// Namespace returns the connection namespace bound to the api key of the request. It returns false if the key is not
+// bound to a namespace, then the request can operate in any namespace.
+func Namespace(r *http.Request) (string, bool) {
+	key := r.Header.Get(APIKeyHeader)
+	if key == "" {
+		key = r.URL.Query().Get("apiKey")
+	}
+	if conf.Config == nil || key == "" {
+		return "", false
+	}
+	k := findAPIKey(conf.Config.ManagementAuth.APIKeys, key)
+	if k == nil || k.Namespace == "" {
+		return "", false
+	}
+	return k.Namespace, true
+}
+
+// Unbound rejects the api keys bound to a namespace for the APIs across the namespaces
+func Unbound(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if ns, ok := Namespace(r); ok {
+			http.Error(w, fmt.Sprintf("api key of the namespace %s is not allowed", ns), http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	}
+}
+
+func isManagementPath(p string) bool {
+	for _, prefix := range managementPaths {
+		if strings.HasPrefix(p, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+var APIKey = func(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isManagementPath(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		key := r.Header.Get(APIKeyHeader)
+		if key == "" && strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+			key = r.URL.Query().Get("apiKey")
+		}
+		client, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			client = r.RemoteAddr
+		}
+		readOnly := r.Method == http.MethodGet || r.Method == http.MethodHead
+		if code, err := AuthorizeAPIKey(key, client, readOnly); err != nil {
+			if code == http.StatusTooManyRequests {
+				w.Header().Set("Retry-After", "1")
+			}
+			http.Error(w, err.Error(), code)
+			return
+		}
+		if ns, ok := Namespace(r); ok {
+			if q := r.URL.Query().Get("namespace"); q != "" && q != ns {
+				http.Error(w, fmt.Sprintf("api key is not allowed in the namespace %s", q), http.StatusForbidden)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,100 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/pkg/model"
+)
+
+func TestAPIKey(t *testing.T) {
+	conf.InitConf()
+	origin := conf.Config.ManagementAuth
+	defer func() {
+		conf.Config.ManagementAuth = origin
+	}()
+	conf.Config.ManagementAuth = model.ManagementAuthConf{
+		APIKeys: []model.APIKeyConf{
+			{Name: "viewer", Key: "k1", Scope: ScopeRead},
+			{Name: "admin", Key: "k2", Scope: ScopeAdmin},
+			{Name: "tenant", Key: "k4", Scope: ScopeAdmin, Namespace: "a"},
+		},
+		RateLimit: 1,
+		Burst:     2,
+	}
+	handler := APIKey(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		key      string
+		wantCode int
+	}{
+		{name: "not management path", method: http.MethodGet, path: "/streams", wantCode: http.StatusOK},
+		{name: "no key", method: http.MethodGet, path: "/connections", wantCode: http.StatusUnauthorized},
+		{name: "wrong key", method: http.MethodGet, path: "/connections", key: "k3", wantCode: http.StatusUnauthorized},
+		{name: "read", method: http.MethodGet, path: "/connections", key: "k1", wantCode: http.StatusOK},
+		{name: "own namespace", method: http.MethodGet, path: "/connections?namespace=a", key: "k4", wantCode: http.StatusOK},
+		{name: "other namespace", method: http.MethodGet, path: "/connections?namespace=b", key: "k4", wantCode: http.StatusForbidden},
+		{name: "read only", method: http.MethodDelete, path: "/connections/conn1", key: "k1", wantCode: http.StatusForbidden},
+		{name: "admin", method: http.MethodPost, path: "/tracer", key: "k2", wantCode: http.StatusOK},
+		{name: "admin burst", method: http.MethodDelete, path: "/connections/conn1", key: "k2", wantCode: http.StatusOK},
+		{name: "rate limited", method: http.MethodGet, path: "/trace/abc", key: "k2", wantCode: http.StatusTooManyRequests},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "http://127.0.0.1:9081"+tt.path, nil)
+			if tt.key != "" {
+				req.Header.Set(APIKeyHeader, tt.key)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			require.Equal(t, tt.wantCode, w.Code)
+		})
+	}
+	req := httptest.NewRequest(http.MethodGet, "http://127.0.0.1:9081/connections", nil)
+	req.Header.Set(APIKeyHeader, "k4")
+	ns, ok := Namespace(req)
+	require.True(t, ok)
+	require.Equal(t, "a", ns)
+	req.Header.Set(APIKeyHeader, "k2")
+	_, ok = Namespace(req)
+	require.False(t, ok)
+}
+
+func TestRateLimitByClient(t *testing.T) {
+	conf.InitConf()
+	origin := conf.Config.ManagementAuth
+	defer func() {
+		conf.Config.ManagementAuth = origin
+	}()
+	conf.Config.ManagementAuth = model.ManagementAuthConf{RateLimit: 1, Burst: 1}
+	code, err := AuthorizeAPIKey("", "10.0.0.1", false)
+	require.NoError(t, err)
+	require.Equal(t, 0, code)
+	code, _ = AuthorizeAPIKey("", "10.0.0.1", true)
+	require.Equal(t, http.StatusTooManyRequests, code)
+	code, err = AuthorizeAPIKey("", "10.0.0.2", true)
+	require.NoError(t, err)
+	require.Equal(t, 0, code)
+}
@@ -15,8 +15,10 @@
 package middleware
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/lf-edge/ekuiper/v2/internal/conf"
 	"github.com/lf-edge/ekuiper/v2/internal/pkg/jwt"
@@ -41,29 +43,33 @@ var Auth = func(next http.Handler) http.Handler {
 			}
 		}
 
-		tokenHeader := r.Header.Get("Authorization")
-
-		if tokenHeader == "" {
-			http.Error(w, "missing_token", http.StatusUnauthorized)
-			return
+		token := r.Header.Get("Authorization")
+		// the browsers cannot set the header for websocket, so the token can be passed by the query parameter
+		if token == "" && strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+			token = r.URL.Query().Get("token")
 		}
-		tk, err := jwt.ParseToken(tokenHeader)
-		if err != nil {
+		if err := ValidateToken(token); err != nil {
 			http.Error(w, err.Error(), http.StatusUnauthorized)
 			return
 		}
-		hit := false
-		for _, value := range tk.RegisteredClaims.Audience {
-			if value == "eKuiper" {
-				hit = true
-				break
-			}
-		}
-		if !hit {
-			http.Error(w, fmt.Sprintf("audience field should contain eKuiper, but got %s", tk.RegisteredClaims.Audience), http.StatusUnauthorized)
-			return
-		}
 
 		next.ServeHTTP(w, r)
 	})
 }
+
+// ValidateToken checks the jwt token is valid and issued for eKuiper. It is shared by the REST and gRPC APIs.
+func ValidateToken(tokenHeader string) error {
+	if tokenHeader == "" {
+		return errors.New("missing_token")
+	}
+	tk, err := jwt.ParseToken(tokenHeader)
+	if err != nil {
+		return err
+	}
+	for _, value := range tk.RegisteredClaims.Audience {
+		if value == "eKuiper" {
+			return nil
+		}
+	}
+	return fmt.Errorf("audience field should contain eKuiper, but got %s", tk.RegisteredClaims.Audience)
+}
@@ -0,0 +1,496 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+
+	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+	"github.com/lf-edge/ekuiper/v2/pkg/connection"
+	"github.com/lf-edge/ekuiper/v2/pkg/model"
+	"github.com/lf-edge/ekuiper/v2/pkg/tracer"
+)
+
+// The OpenAPI 3 document of the connection management and trace query APIs. The schemas are generated from the
+// response types by reflection so that they never drift from the implementation.
+
+type openAPIDoc = map[string]any
+
+var (
+	durationType = reflect.TypeOf(cast.DurationConf(0))
+	timeType     = reflect.TypeOf(time.Time{})
+)
+
+// schemaGenerator converts the go types to the json schemas. The named struct types are added to the components and
+// referenced so that the recursive types like LocalSpan are supported.
+type schemaGenerator struct {
+	components map[string]any
+	names      map[reflect.Type]string
+}
+
+func newSchemaGenerator() *schemaGenerator {
+	return &schemaGenerator{components: make(map[string]any), names: make(map[reflect.Type]string)}
+}
+
+// define adds the type as a component with the name and returns the reference
+func (g *schemaGenerator) define(name string, v any) map[string]any {
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	g.names[t] = name
+	g.components[name] = g.structSchema(t)
+	return ref(name)
+}
+
+func ref(name string) map[string]any {
+	return map[string]any{"$ref": "#/components/schemas/" + name}
+}
+
+func (g *schemaGenerator) schema(t reflect.Type) map[string]any {
+	switch t {
+	case durationType:
+		return map[string]any{"type": "string", "example": "10s"}
+	case timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return g.schema(t.Elem())
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case reflect.Struct:
+		if name, ok := g.names[t]; ok {
+			return ref(name)
+		}
+		return g.structSchema(t)
+	default:
+		// any value
+		return map[string]any{}
+	}
+}
+
+func (g *schemaGenerator) structSchema(t reflect.Type) map[string]any {
+	props := make(map[string]any)
+	var required []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		props[name] = g.schema(f.Type)
+		if !strings.Contains(opts, "omitempty") && f.Type.Kind() != reflect.Pointer {
+			required = append(required, name)
+		}
+	}
+	s := map[string]any{"type": "object", "properties": props}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s
+}
+
+func buildOpenAPI() openAPIDoc {
+	g := newSchemaGenerator()
+	g.components["ConnectionStatus"] = map[string]any{
+		"type": "string",
+		"enum": []string{api.ConnectionConnecting, api.ConnectionConnected, api.ConnectionDisconnected},
+	}
+	connReq := g.define("ConnectionRequest", ConnectionRequest{})
+	connMeta := g.define("ConnectionMeta", ConnectionResponse{})
+	g.components["ConnectionMeta"].(map[string]any)["properties"].(map[string]any)["status"] = ref("ConnectionStatus")
+	tuning := g.define("ConnectionTuning", connection.Tuning{})
+	trashed := g.define("TrashedConnection", connection.TrashedConnection{})
+	template := g.define("ConnectionTemplate", connection.ConnectionTemplate{})
+	templateInstance := g.define("TemplateInstanceRequest", TemplateInstanceRequest{})
+	propagation := g.define("TemplatePropagation", connection.TemplatePropagation{})
+	attacher := g.define("ConnectionAttacher", connection.Attacher{})
+	g.define("ConnectionRefAuditRecord", connection.RefAuditRecord{})
+	refAudit := g.define("ConnectionRefAudit", connection.RefAudit{})
+	connEvent := g.define("ConnectionEvent", connection.ConnectionEvent{})
+	importReport := g.define("ConnectionImportReport", connection.ReconcileReport{})
+	validation := g.define("ConnectionValidationResult", connection.ValidationResult{})
+	orphans := g.define("ConnectionOrphanReport", connection.OrphanReport{})
+	g.define("ConnectionSnapshot", connection.ConnectionSnapshot{})
+	poolSnapshot := g.define("PoolSnapshot", connection.PoolSnapshot{})
+	restoreResult := g.define("PoolRestoreResult", connection.RestoreResult{})
+	cloneReq := g.define("CloneRequest", CloneRequest{})
+	alias := g.define("ConnectionAlias", connection.ConnectionAlias{})
+	g.define("ConnectionSummary", connection.ConnectionSummary{})
+	connPage := g.define("ConnectionPage", connection.ConnectionPage{})
+	g.define("LocalLink", tracer.LocalLink{})
+	g.define("SpanLink", tracer.SpanLink{})
+	traceLinks := g.define("TraceLinks", tracer.TraceLinks{})
+	span := g.define("LocalSpan", tracer.LocalSpan{})
+	traceSearch := g.define("TraceSearchResult", tracer.TraceSearchResult{})
+	spanMetrics := g.define("SpanMetrics", tracer.SpanMetrics{})
+	cleanup := g.define("TraceCleanupResult", tracer.CleanupResult{})
+	tracerReq := g.define("TracerRequest", SetTracerRequest{})
+	sampling := g.define("SamplingConf", model.SamplingConf{})
+	ruleTraceReq := g.define("RuleTraceRequest", EnableRuleTraceRequest{})
+	g.define("SpanAlert", tracer.SpanAlert{})
+	ruleTrace := g.define("RuleTraceConf", tracer.RuleTraceConf{})
+	alertEvent := g.define("SpanAlertEvent", tracer.SpanAlertEvent{})
+	g.define("SpanDiff", tracer.SpanDiff{})
+	comparison := g.define("TraceComparison", tracer.TraceComparison{})
+	traceSwitch := g.define("TraceSwitch", tracer.TraceSwitch{})
+	tracerStats := g.define("TracerStatistics", tracer.TracerStatistics{})
+	health := g.define("HealthReport", healthReport{})
+	g.define("ConnectionTypeHealth", connection.TypeHealth{})
+	readiness := g.define("PoolReadiness", connection.PoolReadiness{})
+	g.components["Error"] = map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"error":   map[string]any{"type": "integer"},
+			"message": map[string]any{"type": "string"},
+		},
+	}
+
+	idParam := pathParam("id", "The connection id")
+	nsParam := queryParam("namespace", "The tenant namespace of the caller", "string")
+	timeRange := []any{
+		queryParam("start", "The start time in RFC3339 format", "string"),
+		queryParam("end", "The end time in RFC3339 format", "string"),
+	}
+	paths := map[string]any{
+		"/connections": map[string]any{
+			"get": operation("List the connections", nil, []any{
+				queryParam("forceAll", "Include the anonymous connections of rules", "boolean"), nsParam,
+				queryParam("selector", "The label selector such as site=plant1,env!=dev", "string"),
+			},
+				jsonResponseOf(map[string]any{"type": "array", "items": connMeta})),
+			"post": operation("Create a named connection", connReq, []any{nsParam}, textResponse(http.StatusCreated)),
+		},
+		"/connections/{id}": map[string]any{
+			"get": operation("Get the connection detail and status", nil,
+				[]any{idParam, queryParam("stats", "Include the runtime metrics", "boolean"), nsParam}, jsonResponseOf(connMeta)),
+			"put": operation("Update the named connection", connReq, []any{idParam, nsParam}, textResponse(http.StatusOK)),
+			"delete": operation("Drop the named connection", nil,
+				[]any{idParam, queryParam("permanent", "Drop without retaining in the trash", "boolean"), nsParam}, textResponse(http.StatusOK)),
+		},
+		"/connections/{id}/attachers": map[string]any{
+			"get": operation("List the rule components holding the connection", nil, []any{idParam},
+				jsonResponseOf(map[string]any{"type": "array", "items": attacher})),
+		},
+		"/connections/{id}/events": map[string]any{
+			"get": operation("Get the latest lifecycle events of the connection", nil,
+				[]any{idParam, queryParam("limit", "The max count of the latest events, 0 means all", "integer")},
+				jsonResponseOf(map[string]any{"type": "array", "items": connEvent})),
+		},
+		"/connections/{id}/labels": map[string]any{
+			"put": operation("Replace the labels of the named connection", map[string]any{"type": "object", "additionalProperties": map[string]any{"type": "string"}},
+				[]any{idParam, nsParam}, textResponse(http.StatusOK)),
+		},
+		"/connections/{id}/pause": map[string]any{
+			"post": operation("Close the transport of the named connection while keeping the references", nil,
+				[]any{idParam, nsParam}, textResponse(http.StatusOK)),
+		},
+		"/connections/{id}/resume": map[string]any{
+			"post": operation("Connect the paused connection again", nil, []any{idParam, nsParam}, textResponse(http.StatusOK)),
+		},
+		"/connections/{id}/rotate": map[string]any{
+			"post": operation("Reload the cert files of the named connection and connect it again without detaching the rules", nil,
+				[]any{idParam, nsParam}, textResponse(http.StatusOK)),
+		},
+		"/connections/{id}/clone": map[string]any{
+			"post": operation("Create a named connection by copying the connection with the props overridden", cloneReq,
+				[]any{idParam, nsParam}, textResponse(http.StatusCreated)),
+		},
+		"/connections/{id}/refaudit": map[string]any{
+			"get": operation("Get the attaches and detaches of the connection recorded in the ref audit mode", nil, []any{idParam},
+				jsonResponseOf(refAudit)),
+		},
+		"/connections/status/ws": map[string]any{
+			"get": operation("Watch the connection status changes over WebSocket", nil, nil, textResponse(http.StatusSwitchingProtocols)),
+		},
+		"/connections/export": map[string]any{
+			"get": operation("Export the named connections", nil, []any{
+				queryParam("format", "json or yaml, default to json", "string"),
+				queryParam("mask", "Mask the secret props, default to true", "boolean"),
+			}, jsonResponseOf(map[string]any{"type": "object"})),
+		},
+		"/connections/import": map[string]any{
+			"post": operation("Import the named connections from the json or yaml document in the body",
+				map[string]any{"type": "object"}, []any{queryParam("overwrite", "Overwrite the existing connections", "boolean")},
+				jsonResponseOf(importReport)),
+		},
+		"/connections/validate": map[string]any{
+			"post": operation("Validate the props and test the reachability of a connection without creating it", connReq, nil,
+				jsonResponseOf(validation)),
+		},
+		"/connections/reconcile": map[string]any{
+			"post": operation("Find the inconsistencies between the stored and the live connections", nil,
+				[]any{queryParam("repair", "Repair the inconsistencies found", "boolean")}, jsonResponseOf(orphans)),
+		},
+		"/connections/snapshot": map[string]any{
+			"get": operation("Dump the connection pool including the anonymous connections", nil, nil,
+				jsonResponseOf(poolSnapshot)),
+		},
+		"/connections/snapshot/restore": map[string]any{
+			"post": operation("Create the connections of the pool snapshot", poolSnapshot,
+				[]any{queryParam("hold", "Duration to hold the anonymous connections for the rules to attach, like 1m", "string")},
+				jsonResponseOf(restoreResult)),
+		},
+		"/connections/templates": map[string]any{
+			"get": operation("List the connection templates", nil, nil,
+				jsonResponseOf(map[string]any{"type": "array", "items": template})),
+			"post": operation("Create a connection template", template, nil, textResponse(http.StatusCreated)),
+		},
+		"/connections/templates/{id}": map[string]any{
+			"get": operation("Get the connection template", nil, []any{pathParam("id", "The template id")}, jsonResponseOf(template)),
+			"put": operation("Update the connection template", template, []any{
+				pathParam("id", "The template id"),
+				queryParam("propagate", "Update the connections derived from the template", "boolean"),
+			}, jsonResponseOf(propagation)),
+			"delete": operation("Drop the connection template without derived connections", nil,
+				[]any{pathParam("id", "The template id")}, textResponse(http.StatusOK)),
+		},
+		"/connections/templates/{id}/instantiate": map[string]any{
+			"post": operation("Create a named connection from the template", templateInstance,
+				[]any{pathParam("id", "The template id"), nsParam}, textResponse(http.StatusCreated)),
+		},
+		"/connections/aliases": map[string]any{
+			"get": operation("List the connection aliases", nil, nil,
+				jsonResponseOf(map[string]any{"type": "array", "items": alias})),
+			"post": operation("Create an alias of the named connection", alias, []any{nsParam}, textResponse(http.StatusCreated)),
+		},
+		"/connections/aliases/{alias}": map[string]any{
+			"put": operation("Switch the alias to another named connection", alias,
+				[]any{pathParam("alias", "The alias"), nsParam}, textResponse(http.StatusOK)),
+			"delete": operation("Delete the connection alias", nil, []any{pathParam("alias", "The alias"), nsParam}, textResponse(http.StatusOK)),
+		},
+		"/connections/summary": map[string]any{
+			"get": operation("List the brief of the connections by page with the last known status", nil, []any{
+				queryParam("forceAll", "Include the anonymous connections of rules", "boolean"), nsParam,
+				queryParam("selector", "The label selector such as site=plant1,env!=dev", "string"),
+				queryParam("type", "The connection type", "string"),
+				queryParam("status", "The last known status", "string"),
+				queryParam("sort", "The sort key: id, type, status, refCount or createdAt, prefix - for descending", "string"),
+				queryParam("limit", "The max count of connections, 0 means all", "integer"),
+				queryParam("offset", "The count of connections to skip", "integer"),
+			}, jsonResponseOf(connPage)),
+		},
+		"/connections/trash": map[string]any{
+			"get": operation("List the dropped named connections in the trash", nil, nil,
+				jsonResponseOf(map[string]any{"type": "array", "items": trashed})),
+		},
+		"/connections/trash/{id}": map[string]any{
+			"delete": operation("Purge the connection from the trash", nil, []any{idParam}, textResponse(http.StatusOK)),
+		},
+		"/connections/trash/{id}/restore": map[string]any{
+			"post": operation("Restore the connection from the trash", nil, []any{idParam}, textResponse(http.StatusOK)),
+		},
+		"/configs/connection": map[string]any{
+			"get":   operation("Get the connection tuning", nil, nil, jsonResponseOf(tuning)),
+			"patch": operation("Change the connection tuning", tuning, nil, jsonResponseOf(tuning)),
+		},
+		"/healthz": map[string]any{
+			"get": operation("Check the health of the storage, connections and tracer", nil, nil, jsonResponseOf(health)),
+		},
+		"/ready": map[string]any{
+			"get": operation("Check the readiness of the connections by the readiness policy", nil, nil, jsonResponseOf(readiness)),
+		},
+		"/tracer": map[string]any{
+			"post": operation("Start or stop the remote collector of the tracer", tracerReq, nil, textResponse(http.StatusOK)),
+		},
+		"/tracer/sampling": map[string]any{
+			"get": operation("Get the sampling config of the tracer", nil, nil, jsonResponseOf(sampling)),
+			"put": operation("Replace the sampling config of the tracer at runtime", sampling, nil, textResponse(http.StatusOK)),
+		},
+		"/tracer/switch": map[string]any{
+			"get": operation("Get the global switch of the span collection", nil, nil, jsonResponseOf(traceSwitch)),
+			"put": operation("Turn the span collection on or off for all rules", traceSwitch, nil, textResponse(http.StatusOK)),
+		},
+		"/tracer/stats": map[string]any{
+			"get": operation("Get the statistics of the tracing subsystem itself", nil, nil, jsonResponseOf(tracerStats)),
+		},
+		"/rules/{name}/trace": map[string]any{
+			"get": operation("Get the persisted trace setting of the rule", nil, []any{pathParam("name", "The rule id")}, jsonResponseOf(ruleTrace)),
+			"put": operation("Replace the trace setting of the rule and persist it", ruleTrace, []any{pathParam("name", "The rule id")}, textResponse(http.StatusOK)),
+		},
+		"/rules/{name}/trace/start": map[string]any{
+			"post": operation("Start tracing the rule", ruleTraceReq, []any{pathParam("name", "The rule id")}, textResponse(http.StatusOK)),
+		},
+		"/rules/{name}/trace/stop": map[string]any{
+			"post": operation("Stop tracing the rule", nil, []any{pathParam("name", "The rule id")}, textResponse(http.StatusOK)),
+		},
+		"/trace/{id}": map[string]any{
+			"get":    operation("Get the trace tree by trace id", nil, []any{pathParam("id", "The trace id")}, jsonResponseOf(span)),
+			"delete": operation("Delete the spans of the trace from the local storage", nil, []any{pathParam("id", "The trace id")}, textResponse(http.StatusOK)),
+		},
+		"/trace/{id}/export": map[string]any{
+			"get": operation("Download the trace in the jaeger ui or the zipkin v2 json format", nil, []any{
+				pathParam("id", "The trace id"),
+				queryParam("format", "jaeger or zipkin, default to jaeger", "string"),
+			}, jsonResponseOf(map[string]any{"type": "object"})),
+		},
+		"/trace/{id}/links": map[string]any{
+			"get": operation("Get the links between the trace and the other traces", nil, []any{pathParam("id", "The trace id")}, jsonResponseOf(traceLinks)),
+		},
+		"/trace/rule/{ruleID}": map[string]any{
+			"get": operation("List the latest trace ids of the rule", nil,
+				[]any{pathParam("ruleID", "The rule id"), queryParam("limit", "The max count of trace ids", "integer")},
+				jsonResponseOf(map[string]any{"type": "array", "items": map[string]any{"type": "string"}})),
+			"delete": operation("Purge all the traces of the rule from the local storage", nil,
+				[]any{pathParam("ruleID", "The rule id")}, jsonResponseOf(cleanup)),
+		},
+		"/trace/attribute": map[string]any{
+			"get": operation("Find the trace ids by an indexed span attribute", nil,
+				append([]any{
+					queryParam("key", "The attribute key", "string"),
+					queryParam("value", "The attribute value", "string"),
+					queryParam("limit", "The max count of trace ids", "integer"),
+				}, timeRange...),
+				jsonResponseOf(map[string]any{"type": "array", "items": map[string]any{"type": "string"}})),
+		},
+		"/trace/search": map[string]any{
+			"get": operation("Search the root spans of the traces by page, the latest first", nil,
+				append([]any{
+					queryParam("rule", "The rule id", "string"),
+					map[string]any{
+						"name": "attr", "in": "query", "description": "The span attribute in key:value format, repeatable",
+						"schema": map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+					},
+					queryParam("limit", "The max count of traces", "integer"),
+					queryParam("offset", "The count of traces to skip", "integer"),
+				}, timeRange...),
+				jsonResponseOf(traceSearch)),
+		},
+		"/trace/ws": map[string]any{
+			"get": operation("Watch the ended spans over WebSocket", nil, []any{
+				queryParam("rule", "The rule id", "string"),
+				map[string]any{
+					"name": "attr", "in": "query", "description": "The span attribute in key:value format, repeatable",
+					"schema": map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+				},
+			}, textResponse(http.StatusSwitchingProtocols)),
+		},
+		"/trace/metrics": map[string]any{
+			"get": operation("Get the latency, error and throughput metrics derived from the spans", nil,
+				[]any{queryParam("rule", "The rule id, default to all the rules", "string")},
+				jsonResponseOf(map[string]any{"type": "array", "items": spanMetrics})),
+		},
+		"/trace/alerts": map[string]any{
+			"get": operation("Get the recent events fired by the span alerts of the rules, the latest first", nil,
+				[]any{queryParam("rule", "The rule id, default to all the rules", "string")},
+				jsonResponseOf(map[string]any{"type": "array", "items": alertEvent})),
+		},
+		"/trace/compare": map[string]any{
+			"get": operation("Compare a trace against a baseline trace of the same rule by the latency of each span", nil,
+				[]any{
+					queryParam("base", "The baseline trace id", "string"),
+					queryParam("trace", "The trace id to compare", "string"),
+				},
+				jsonResponseOf(comparison)),
+		},
+		"/trace/bundle": map[string]any{
+			"get": operation("Download the traces with the trace settings of their rules, the node resource and the tracer config as a gzipped tar archive", nil,
+				[]any{queryParam("ids", "The comma separated trace ids", "string")},
+				map[string]any{"200": map[string]any{
+					"description": "OK",
+					"content":     map[string]any{"application/gzip": map[string]any{"schema": map[string]any{"type": "string", "format": "binary"}}},
+				}}),
+		},
+		"/trace/export": map[string]any{
+			"get": operation("Export the spans started in the time range", nil,
+				append(timeRange, queryParam("format", "The format of the spans: json, jsonl or binary. Default to json", "string")),
+				map[string]any{"200": map[string]any{
+					"description": "OK",
+					"content": map[string]any{
+						ContentTypeJSON:            map[string]any{"schema": map[string]any{"type": "array", "items": span}},
+						"application/x-ndjson":     map[string]any{"schema": map[string]any{"type": "string"}},
+						"application/octet-stream": map[string]any{"schema": map[string]any{"type": "string", "format": "binary"}},
+					},
+				}}),
+		},
+	}
+	return openAPIDoc{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   "eKuiper connection and trace API",
+			"version": version,
+		},
+		"paths":      paths,
+		"components": map[string]any{"schemas": g.components},
+	}
+}
+
+func operation(summary string, body map[string]any, params []any, responses map[string]any) map[string]any {
+	op := map[string]any{"summary": summary, "responses": responses}
+	if body != nil {
+		op["requestBody"] = map[string]any{
+			"required": true,
+			"content":  map[string]any{ContentTypeJSON: map[string]any{"schema": body}},
+		}
+	}
+	if len(params) > 0 {
+		op["parameters"] = params
+	}
+	responses["default"] = map[string]any{
+		"description": "Error",
+		"content":     map[string]any{ContentTypeJSON: map[string]any{"schema": ref("Error")}},
+	}
+	return op
+}
+
+func pathParam(name, desc string) map[string]any {
+	return map[string]any{"name": name, "in": "path", "required": true, "description": desc, "schema": map[string]any{"type": "string"}}
+}
+
+func queryParam(name, desc, typ string) map[string]any {
+	return map[string]any{"name": name, "in": "query", "description": desc, "schema": map[string]any{"type": typ}}
+}
+
+func jsonResponseOf(schema map[string]any) map[string]any {
+	return map[string]any{"200": map[string]any{
+		"description": "OK",
+		"content":     map[string]any{ContentTypeJSON: map[string]any{"schema": schema}},
+	}}
+}
+
+func textResponse(code int) map[string]any {
+	return map[string]any{strconv.Itoa(code): map[string]any{
+		"description": http.StatusText(code),
+		"content":     map[string]any{"text/plain": map[string]any{"schema": map[string]any{"type": "string"}}},
+	}}
+}
+
+func openAPIHandler(w http.ResponseWriter, _ *http.Request) {
+	jsonResponse(buildOpenAPI(), w, logger)
+}
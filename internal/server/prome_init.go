@@ -21,6 +21,7 @@ import (
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 
 	"github.com/lf-edge/ekuiper/v2/internal/conf"
@@ -36,6 +37,13 @@ type promeComp struct {
 	s *http.Server
 }
 
+// metricsHandler is the same as promhttp.Handler but enables OpenMetrics format so that the exemplars
+// like the trace id of the latency histograms can be exposed.
+func metricsHandler() http.Handler {
+	return promhttp.InstrumentMetricHandler(prometheus.DefaultRegisterer,
+		promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{EnableOpenMetrics: true}))
+}
+
 func (p *promeComp) register() {
 	// Do nothing
 }
@@ -44,7 +52,7 @@ func (p *promeComp) rest(r *mux.Router) {
 	portPrometheus := conf.Config.Basic.PrometheusPort
 	portRest := conf.Config.Basic.RestPort
 	if portPrometheus == portRest {
-		r.Handle("/metrics", promhttp.Handler())
+		r.Handle("/metrics", metricsHandler())
 		msg := fmt.Sprintf("Register prometheus metrics to http://localhost:%d/metrics", portPrometheus)
 		logger.Info(msg)
 		fmt.Println(msg)
@@ -61,7 +69,7 @@ func (p *promeComp) serve() {
 		portRest := conf.Config.Basic.RestPort
 		if portPrometheus != portRest {
 			mux := http.NewServeMux()
-			mux.Handle("/metrics", promhttp.Handler())
+			mux.Handle("/metrics", metricsHandler())
 			srvPrometheus := &http.Server{
 				Addr:         fmt.Sprintf("0.0.0.0:%d", portPrometheus),
 				WriteTimeout: time.Second * 15,
@@ -0,0 +1,325 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"net"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/pkg/cert"
+	"github.com/lf-edge/ekuiper/v2/pkg/connection"
+	"github.com/lf-edge/ekuiper/v2/pkg/syncx"
+	"github.com/lf-edge/ekuiper/v2/pkg/tracer"
+)
+
+// Hot-standby replication. The active node streams the named connection changes, their status and the tracer
+// config to the standby nodes through a gRPC server stream. The messages are encoded as JSON so that no generated
+// protobuf code is required. The stream runs over TLS and the standby nodes authenticate by the shared token.
+
+const (
+	replicationMethod      = "/kuiper.Replication/Stream"
+	replicationBuffer      = 1024
+	replicationRetryPeriod = time.Second
+	replicationTokenKey    = "authorization"
+)
+
+type replicationRequest struct {
+	NodeID string `json:"nodeId"`
+}
+
+// replicationMessage is the message in the stream. The first message carries the full snapshot of the connections,
+// and the following ones carry a single change.
+type replicationMessage struct {
+	Full     bool                      `json:"full,omitempty"`
+	Snapshot []connection.ReplicaEvent `json:"snapshot,omitempty"`
+	Event    *connection.ReplicaEvent  `json:"event,omitempty"`
+	Tracer   *tracer.TracerConfig      `json:"tracer,omitempty"`
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+func (jsonCodec) Name() string {
+	return "json"
+}
+
+type replicationService interface {
+	stream(req *replicationRequest, ss grpc.ServerStream) error
+}
+
+var replicationServiceDesc = grpc.ServiceDesc{
+	ServiceName: "kuiper.Replication",
+	HandlerType: (*replicationService)(nil),
+	Streams: []grpc.StreamDesc{{
+		StreamName:    "Stream",
+		ServerStreams: true,
+		Handler: func(srv any, ss grpc.ServerStream) error {
+			req := &replicationRequest{}
+			if err := ss.RecvMsg(req); err != nil {
+				return err
+			}
+			return srv.(replicationService).stream(req, ss)
+		},
+	}},
+}
+
+type replicationServer struct {
+	grpc *grpc.Server
+
+	mu         syncx.Mutex
+	next       int
+	tracerSubs map[int]chan *tracer.TracerConfig
+}
+
+var (
+	replicationMu  syncx.Mutex
+	replicationSrv *replicationServer
+)
+
+func startReplication(ctx context.Context) {
+	c := conf.Config.Connection.Replication
+	if c.ListenAddr != "" {
+		if _, err := startReplicationServer(c.ListenAddr); err != nil {
+			conf.Log.Errorf("start replication server error: %v", err)
+		}
+	}
+	if c.Peer != "" {
+		go runReplicationClient(ctx, c.Peer, conf.Config.Connection.LeaderElection.NodeID)
+	}
+}
+
+// replicationTLS builds the TLS config of the replication from the config. The server requires the client
+// certificate if the root CA is set.
+func replicationTLS(server bool) (*tls.Config, error) {
+	c := conf.Config.Connection.Replication
+	if c.Tls == nil || c.Token == "" {
+		return nil, errors.New("replication requires the tls and the token to be configured")
+	}
+	tc, err := cert.GenTLSConfigForService(c.Tls)
+	if err != nil {
+		return nil, err
+	}
+	if !server {
+		return tc, nil
+	}
+	if len(tc.Certificates) == 0 {
+		return nil, errors.New("replication server requires the certificate and the private key")
+	}
+	if tc.RootCAs != nil {
+		tc.ClientCAs = tc.RootCAs
+		tc.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return cert.HardenServerTLS(tc), nil
+}
+
+// authReplication checks the token of the standby node
+func authReplication(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	md, _ := metadata.FromIncomingContext(ss.Context())
+	token := firstMetadata(md, replicationTokenKey)
+	if subtle.ConstantTimeCompare([]byte(token), []byte(conf.Config.Connection.Replication.Token)) != 1 {
+		return status.Error(codes.Unauthenticated, "invalid replication token")
+	}
+	return handler(srv, ss)
+}
+
+func startReplicationServer(addr string) (net.Addr, error) {
+	replicationMu.Lock()
+	defer replicationMu.Unlock()
+	tc, err := replicationTLS(true)
+	if err != nil {
+		return nil, err
+	}
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	s := &replicationServer{
+		grpc: grpc.NewServer(
+			grpc.Creds(credentials.NewTLS(tc)),
+			grpc.ForceServerCodec(jsonCodec{}),
+			grpc.StreamInterceptor(authReplication),
+		),
+		tracerSubs: make(map[int]chan *tracer.TracerConfig),
+	}
+	s.grpc.RegisterService(&replicationServiceDesc, s)
+	tracer.OnConfigChange(s.broadcastTracer)
+	replicationSrv = s
+	go func() {
+		if err := s.grpc.Serve(lis); err != nil {
+			conf.Log.Errorf("replication server stopped with error: %v", err)
+		}
+	}()
+	conf.Log.Infof("replication server listening on %s", lis.Addr().String())
+	return lis.Addr(), nil
+}
+
+func stopReplication() {
+	replicationMu.Lock()
+	defer replicationMu.Unlock()
+	if replicationSrv != nil {
+		tracer.OnConfigChange(nil)
+		replicationSrv.grpc.Stop()
+		replicationSrv = nil
+	}
+}
+
+func (s *replicationServer) stream(req *replicationRequest, ss grpc.ServerStream) error {
+	conf.Log.Infof("standby node %s starts replication", req.NodeID)
+	tch := make(chan *tracer.TracerConfig, 1)
+	s.mu.Lock()
+	id := s.next
+	s.next++
+	s.tracerSubs[id] = tch
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.tracerSubs, id)
+		s.mu.Unlock()
+	}()
+	snapshot, ch, cancel := connection.SubscribeReplica(replicationBuffer)
+	defer cancel()
+	first := &replicationMessage{Full: true, Snapshot: snapshot}
+	if tc, err := tracer.GetTracerConfig(); err == nil {
+		first.Tracer = tc
+	}
+	if err := ss.SendMsg(first); err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-ss.Context().Done():
+			conf.Log.Infof("standby node %s stops replication", req.NodeID)
+			return nil
+		case ev, ok := <-ch:
+			if !ok {
+				// too slow, the standby reconnects to get a new snapshot
+				return status.Error(codes.ResourceExhausted, "the standby node is too slow to replicate")
+			}
+			if err := ss.SendMsg(&replicationMessage{Event: &ev}); err != nil {
+				return err
+			}
+		case tc := <-tch:
+			if err := ss.SendMsg(&replicationMessage{Tracer: tc}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (s *replicationServer) broadcastTracer(c *tracer.TracerConfig) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, ch := range s.tracerSubs {
+		// only the latest config matters
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- c
+	}
+}
+
+// runReplicationClient receives the changes from the active node and applies them while this node is standby.
+// It reconnects until the context is done.
+func runReplicationClient(ctx context.Context, peer, nodeID string) {
+	tc, err := replicationTLS(false)
+	if err != nil {
+		conf.Log.Errorf("create replication client to %s error: %v", peer, err)
+		return
+	}
+	cc, err := grpc.NewClient(peer,
+		grpc.WithTransportCredentials(credentials.NewTLS(tc)),
+		grpc.WithDefaultCallOptions(grpc.ForceCodec(jsonCodec{})))
+	if err != nil {
+		conf.Log.Errorf("create replication client to %s error: %v", peer, err)
+		return
+	}
+	defer cc.Close()
+	for {
+		err := receiveReplication(ctx, cc, nodeID)
+		if ctx.Err() != nil {
+			return
+		}
+		conf.Log.Debugf("replication from %s interrupted: %v", peer, err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(replicationRetryPeriod):
+		}
+	}
+}
+
+func receiveReplication(ctx context.Context, cc *grpc.ClientConn, nodeID string) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	ctx = metadata.AppendToOutgoingContext(ctx, replicationTokenKey, conf.Config.Connection.Replication.Token)
+	stream, err := cc.NewStream(ctx, &replicationServiceDesc.Streams[0], replicationMethod)
+	if err != nil {
+		return err
+	}
+	if err := stream.SendMsg(&replicationRequest{NodeID: nodeID}); err != nil {
+		return err
+	}
+	if err := stream.CloseSend(); err != nil {
+		return err
+	}
+	for {
+		msg := &replicationMessage{}
+		if err := stream.RecvMsg(msg); err != nil {
+			return err
+		}
+		applyReplication(msg)
+	}
+}
+
+func applyReplication(msg *replicationMessage) {
+	// the active node does not follow others
+	if !connection.IsStandby() {
+		return
+	}
+	if msg.Full {
+		connection.ApplyReplicaSnapshot(msg.Snapshot)
+	}
+	if msg.Event != nil {
+		connection.ApplyReplica(*msg.Event)
+	}
+	if msg.Tracer != nil {
+		if cur, err := tracer.GetTracerConfig(); err == nil && *cur == *msg.Tracer {
+			return
+		}
+		if err := tracer.SetTracer(msg.Tracer); err != nil {
+			conf.Log.Warnf("apply replicated tracer config error: %v", err)
+		}
+	}
+}
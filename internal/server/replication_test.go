@@ -0,0 +1,136 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	topoContext "github.com/lf-edge/ekuiper/v2/internal/topo/context"
+	"github.com/lf-edge/ekuiper/v2/pkg/connection"
+	"github.com/lf-edge/ekuiper/v2/pkg/model"
+)
+
+// setReplicationTLS writes a self-signed certificate for 127.0.0.1 which is also the root CA, so that the node
+// verifies itself
+func setReplicationTLS(t *testing.T, token string) {
+	dir := t.TempDir()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "replication"},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tpl, tpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	kb, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	certFile, keyFile := filepath.Join(dir, "node.crt"), filepath.Join(dir, "node.key")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: kb}), 0o600))
+	conf.Config.Connection.Replication.Tls = &model.TlsConfigurationOptions{CertFile: certFile, KeyFile: keyFile, CaFile: certFile}
+	conf.Config.Connection.Replication.Token = token
+	t.Cleanup(func() {
+		conf.Config.Connection.Replication.Tls = nil
+		conf.Config.Connection.Replication.Token = ""
+	})
+}
+
+func TestReplicationRequiresTLS(t *testing.T) {
+	conf.InitConf()
+	_, err := startReplicationServer("127.0.0.1:0")
+	require.Error(t, err)
+}
+
+func TestReplicationStream(t *testing.T) {
+	conf.InitConf()
+	require.NoError(t, connection.InitConnectionManager4Test())
+	ctx := topoContext.Background()
+	_, err := connection.CreateNamedConnection(ctx, "repl1", "mock", map[string]any{"a": "b", "password": "secret"})
+	require.NoError(t, err)
+	defer connection.DropNameConnection(ctx, "repl1")
+	setReplicationTLS(t, "token1")
+	addr, err := startReplicationServer("127.0.0.1:0")
+	require.NoError(t, err)
+	defer stopReplication()
+
+	tc, err := replicationTLS(false)
+	require.NoError(t, err)
+	cc, err := grpc.NewClient(addr.String(),
+		grpc.WithTransportCredentials(credentials.NewTLS(tc)),
+		grpc.WithDefaultCallOptions(grpc.ForceCodec(jsonCodec{})))
+	require.NoError(t, err)
+	defer cc.Close()
+	sctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// rejected without the token
+	stream, err := cc.NewStream(sctx, &replicationServiceDesc.Streams[0], replicationMethod)
+	require.NoError(t, err)
+	require.NoError(t, stream.SendMsg(&replicationRequest{NodeID: "standby"}))
+	require.NoError(t, stream.CloseSend())
+	err = stream.RecvMsg(&replicationMessage{})
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	sctx = metadata.AppendToOutgoingContext(sctx, replicationTokenKey, "token1")
+	stream, err = cc.NewStream(sctx, &replicationServiceDesc.Streams[0], replicationMethod)
+	require.NoError(t, err)
+	require.NoError(t, stream.SendMsg(&replicationRequest{NodeID: "standby"}))
+	require.NoError(t, stream.CloseSend())
+
+	msg := &replicationMessage{}
+	require.NoError(t, stream.RecvMsg(msg))
+	require.True(t, msg.Full)
+	require.Len(t, msg.Snapshot, 1)
+	require.Equal(t, "repl1", msg.Snapshot[0].ID)
+	// the secret is not streamed in plain text
+	require.Equal(t, map[string]any{"a": "b", "password": connection.HiddenSecret}, msg.Snapshot[0].Props)
+
+	require.NoError(t, connection.DropNameConnection(ctx, "repl1"))
+	for {
+		msg = &replicationMessage{}
+		require.NoError(t, stream.RecvMsg(msg))
+		if msg.Event != nil && msg.Event.Type == connection.ReplicaDelete {
+			require.Equal(t, "repl1", msg.Event.ID)
+			break
+		}
+	}
+}
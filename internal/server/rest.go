@@ -35,7 +35,6 @@ import (
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
 	"github.com/lf-edge/ekuiper/contract/v2/api"
-	"go.opentelemetry.io/otel/propagation"
 	"golang.org/x/text/cases"
 	"golang.org/x/text/language"
 
@@ -90,28 +89,60 @@ func handleError(w http.ResponseWriter, err error, prefix string, logger api.Log
 	}
 	message += err.Error()
 	logger.Error(message)
-	var ec int
-	switch e := err.(type) {
-	case *errorx.Error:
-		switch e.Code() {
-		case errorx.NOT_FOUND:
-			ec = http.StatusNotFound
-		default:
-			ec = http.StatusBadRequest
-		}
-	default:
-		ec = http.StatusBadRequest
+	ec := http.StatusBadRequest
+	code, _ := errorx.GetErrorCode(err)
+	switch {
+	case code == errorx.NOT_FOUND:
+		ec = http.StatusNotFound
+	case errorx.KindOf(err) == errorx.KindQuota:
+		ec = http.StatusTooManyRequests
+	}
+	if d, ok := errorx.RetryAfter(err); ok {
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds(d)))
 	}
-
 	http.Error(w, packageInternalErrorCode(err, message), ec)
 }
 
+// errorResponse is the body of the failed requests. The code and category are stable names for the clients to
+// handle the error without parsing the message. They are omitted if the error is not classified.
+type errorResponse struct {
+	Error      errorx.ErrorCode `json:"error"`
+	Code       string           `json:"code,omitempty"`
+	Category   string           `json:"category,omitempty"`
+	RetryAfter int              `json:"retryAfter,omitempty"`
+	Message    string           `json:"message"`
+	// Fields are the errors of each invalid field if the request fails the validation
+	Fields []errorx.FieldError `json:"fields,omitempty"`
+}
+
 func packageInternalErrorCode(err error, msg string) string {
-	errCode := errorx.Undefined_Err
-	if errWithCode, ok := err.(errorx.ErrorWithCode); ok {
-		errCode = errWithCode.Code()
+	resp := errorResponse{
+		Error:   errorx.Undefined_Err,
+		Message: msg,
+	}
+	if code, ok := errorx.GetErrorCode(err); ok && code != errorx.Undefined_Err {
+		resp.Error = code
+		resp.Code = code.String()
+	}
+	if k := errorx.KindOf(err); k != errorx.KindUnknown {
+		resp.Category = k.String()
+	}
+	if d, ok := errorx.RetryAfter(err); ok {
+		resp.RetryAfter = retryAfterSeconds(d)
+	}
+	resp.Fields = errorx.GetFieldErrors(err)
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if e := enc.Encode(resp); e != nil {
+		return fmt.Sprintf(`{"error":%d,"message":%q}`, resp.Error, msg)
 	}
-	return fmt.Sprintf(`{"error":%v,"message":%q}`, errCode, msg)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// retryAfterSeconds rounds up the duration to seconds as required by the Retry-After header
+func retryAfterSeconds(d time.Duration) int {
+	return int((d + time.Second - 1) / time.Second)
 }
 
 func jsonResponse(i interface{}, w http.ResponseWriter, logger api.Logger) {
@@ -145,9 +176,8 @@ func jsonByteResponse(buffer bytes.Buffer, w http.ResponseWriter, logger api.Log
 func traceMiddleware(next http.Handler) http.Handler {
 	t := tracer.GetTracer()
 	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-		propagator := propagation.TraceContext{}
 		originCtx := context.Background()
-		ctx := propagator.Extract(originCtx, propagation.HeaderCarrier(req.Header))
+		ctx := tracer.ExtractHeader(originCtx, req.Header)
 		if ctx != originCtx {
 			_, span := t.Start(ctx, req.URL.Path)
 			defer span.End()
@@ -180,6 +210,9 @@ func createRestServer(ip string, port int, needToken bool) *http.Server {
 	r.HandleFunc("/", rootHandler).Methods(http.MethodGet, http.MethodPost)
 	r.HandleFunc("/stop", stopHandler).Methods(http.MethodGet, http.MethodPost)
 	r.HandleFunc("/ping", pingHandler).Methods(http.MethodGet)
+	r.HandleFunc("/healthz", healthzHandler).Methods(http.MethodGet)
+	r.HandleFunc("/ready", readyHandler).Methods(http.MethodGet)
+	r.HandleFunc("/openapi.json", openAPIHandler).Methods(http.MethodGet)
 	r.HandleFunc("/streams", streamsHandler).Methods(http.MethodGet, http.MethodPost)
 	r.HandleFunc("/streamdetails", streamDetailsHandler).Methods(http.MethodGet)
 	r.HandleFunc("/streams/{name}", streamHandler).Methods(http.MethodGet, http.MethodDelete, http.MethodPut)
@@ -200,6 +233,7 @@ func createRestServer(ip string, port int, needToken bool) *http.Server {
 	r.HandleFunc("/rules/{id}/schema", ruleSchemaHandler).Methods(http.MethodGet)
 	r.HandleFunc("/rules/{name}/trace/start", enableRuleTraceHandler).Methods(http.MethodPost)
 	r.HandleFunc("/rules/{name}/trace/stop", disableRuleTraceHandler).Methods(http.MethodPost)
+	r.HandleFunc("/rules/{name}/trace", ruleTraceHandler).Methods(http.MethodGet, http.MethodPut)
 	r.HandleFunc("/rules/usage/cpu", rulesTopCpuUsageHandler).Methods(http.MethodGet)
 	r.HandleFunc("/rules/validate", validateRuleHandler).Methods(http.MethodPost)
 	r.HandleFunc("/rules/{name}/reset_state", ruleStateHandler).Methods(http.MethodPut)
@@ -209,12 +243,43 @@ func createRestServer(ip string, port int, needToken bool) *http.Server {
 	r.HandleFunc("/ruleset/export", exportHandler).Methods(http.MethodPost)
 	r.HandleFunc("/ruleset/import", importHandler).Methods(http.MethodPost)
 	r.HandleFunc("/configs", configurationUpdateHandler).Methods(http.MethodPatch)
+	r.HandleFunc("/configs/connection", middleware.Unbound(connectionTuningHandler)).Methods(http.MethodGet, http.MethodPatch)
+	r.HandleFunc("/configs/sync", configSyncHandler).Methods(http.MethodGet, http.MethodPost)
+	r.HandleFunc("/configs/reload", configReloadHandler).Methods(http.MethodPost)
+	r.HandleFunc("/audit", auditHandler).Methods(http.MethodGet)
+	r.HandleFunc("/audit/export", auditExportHandler).Methods(http.MethodGet)
 	r.HandleFunc("/config/uploads", fileUploadHandler).Methods(http.MethodPost, http.MethodGet)
 	r.HandleFunc("/config/uploads/{name}", fileDeleteHandler).Methods(http.MethodDelete)
 	r.HandleFunc("/data/export", configurationExportHandler).Methods(http.MethodGet, http.MethodPost)
 	r.HandleFunc("/data/import", configurationImportHandler).Methods(http.MethodPost)
 	r.HandleFunc("/data/import/status", configurationStatusHandler).Methods(http.MethodGet)
+	r.HandleFunc("/data/store/backup", storeBackupHandler).Methods(http.MethodGet)
+	r.HandleFunc("/data/store/restore", storeRestoreHandler).Methods(http.MethodPost)
 	r.HandleFunc("/connections", connectionsHandler).Methods(http.MethodGet, http.MethodPost)
+	r.HandleFunc("/connections/status/ws", middleware.Unbound(connectionStatusWsHandler)).Methods(http.MethodGet)
+	r.HandleFunc("/connections/export", middleware.Unbound(connectionExportHandler)).Methods(http.MethodGet)
+	r.HandleFunc("/connections/import", middleware.Unbound(connectionImportHandler)).Methods(http.MethodPost)
+	r.HandleFunc("/connections/validate", connectionValidateHandler).Methods(http.MethodPost)
+	r.HandleFunc("/connections/reconcile", middleware.Unbound(connectionReconcileHandler)).Methods(http.MethodPost)
+	r.HandleFunc("/connections/snapshot", middleware.Unbound(connectionSnapshotHandler)).Methods(http.MethodGet)
+	r.HandleFunc("/connections/snapshot/restore", middleware.Unbound(connectionSnapshotRestoreHandler)).Methods(http.MethodPost)
+	r.HandleFunc("/connections/templates", middleware.Unbound(connectionTemplatesHandler)).Methods(http.MethodGet, http.MethodPost)
+	r.HandleFunc("/connections/templates/{id}", middleware.Unbound(connectionTemplateHandler)).Methods(http.MethodGet, http.MethodPut, http.MethodDelete)
+	r.HandleFunc("/connections/templates/{id}/instantiate", connectionTemplateInstantiateHandler).Methods(http.MethodPost)
+	r.HandleFunc("/connections/aliases", connectionAliasesHandler).Methods(http.MethodGet, http.MethodPost)
+	r.HandleFunc("/connections/aliases/{alias}", connectionAliasHandler).Methods(http.MethodPut, http.MethodDelete)
+	r.HandleFunc("/connections/summary", middleware.Unbound(connectionSummaryHandler)).Methods(http.MethodGet)
+	r.HandleFunc("/connections/trash", middleware.Unbound(connectionTrashHandler)).Methods(http.MethodGet)
+	r.HandleFunc("/connections/trash/{id}", middleware.Unbound(trashedConnectionHandler)).Methods(http.MethodDelete)
+	r.HandleFunc("/connections/trash/{id}/restore", middleware.Unbound(restoreConnectionHandler)).Methods(http.MethodPost)
+	r.HandleFunc("/connections/{id}/attachers", connectionAttachersHandler).Methods(http.MethodGet)
+	r.HandleFunc("/connections/{id}/refaudit", connectionRefAuditHandler).Methods(http.MethodGet)
+	r.HandleFunc("/connections/{id}/events", connectionEventsHandler).Methods(http.MethodGet)
+	r.HandleFunc("/connections/{id}/labels", connectionLabelsHandler).Methods(http.MethodPut)
+	r.HandleFunc("/connections/{id}/pause", connectionPauseHandler).Methods(http.MethodPost)
+	r.HandleFunc("/connections/{id}/resume", connectionResumeHandler).Methods(http.MethodPost)
+	r.HandleFunc("/connections/{id}/clone", connectionCloneHandler).Methods(http.MethodPost)
+	r.HandleFunc("/connections/{id}/rotate", connectionRotateHandler).Methods(http.MethodPost)
 	r.HandleFunc("/connections/{id}", connectionHandler).Methods(http.MethodGet, http.MethodDelete, http.MethodPut)
 	r.HandleFunc("/ruletest", testRuleHandler).Methods(http.MethodPost)
 	r.HandleFunc("/ruletest/{name}/start", testRuleStartHandler).Methods(http.MethodPost)
@@ -226,9 +291,24 @@ func createRestServer(ip string, port int, needToken bool) *http.Server {
 	r.HandleFunc("/async/data/import", registerDataImportTask).Methods(http.MethodPost)
 	r.HandleFunc("/async/task/{id}", queryAsyncTaskStatus).Methods(http.MethodGet)
 	r.HandleFunc("/async/task/{id}/cancel", asyncTaskCancelHandler).Methods(http.MethodPost)
+	r.HandleFunc("/trace/export", exportTraceHandler).Methods(http.MethodGet)
+	r.HandleFunc("/trace/attribute", getTraceIDByAttribute).Methods(http.MethodGet)
+	r.HandleFunc("/trace/search", searchTraceHandler).Methods(http.MethodGet)
+	r.HandleFunc("/trace/ws", traceWsHandler).Methods(http.MethodGet)
+	r.HandleFunc("/trace/metrics", spanMetricsHandler).Methods(http.MethodGet)
+	r.HandleFunc("/trace/alerts", spanAlertsHandler).Methods(http.MethodGet)
+	r.HandleFunc("/trace/compare", compareTraceHandler).Methods(http.MethodGet)
+	r.HandleFunc("/trace/bundle", traceBundleHandler).Methods(http.MethodGet)
 	r.HandleFunc("/trace/{id}", getTraceByID).Methods(http.MethodGet)
+	r.HandleFunc("/trace/{id}", deleteTraceByID).Methods(http.MethodDelete)
+	r.HandleFunc("/trace/{id}/export", exportTraceByID).Methods(http.MethodGet)
+	r.HandleFunc("/trace/{id}/links", getTraceLinks).Methods(http.MethodGet)
 	r.HandleFunc("/trace/rule/{ruleID}", getTraceIDByRuleID).Methods(http.MethodGet)
+	r.HandleFunc("/trace/rule/{ruleID}", purgeRuleTraceHandler).Methods(http.MethodDelete)
 	r.HandleFunc("/tracer", tracerHandler).Methods(http.MethodPost)
+	r.HandleFunc("/tracer/sampling", samplingHandler).Methods(http.MethodGet, http.MethodPut)
+	r.HandleFunc("/tracer/switch", tracerSwitchHandler).Methods(http.MethodGet, http.MethodPut)
+	r.HandleFunc("/tracer/stats", tracerStatsHandler).Methods(http.MethodGet)
 
 	// dump metrics
 	r.HandleFunc("/metrics/dump", dumpMetricsHandler).Methods(http.MethodGet)
@@ -255,6 +335,7 @@ func createRestServer(ip string, port int, needToken bool) *http.Server {
 	if needToken {
 		r.Use(middleware.Auth)
 	}
+	r.Use(middleware.APIKey)
 	if conf.Config.Basic.EnableRestAuditLog {
 		r.Use(middleware.AuditRestLog)
 	}
@@ -892,7 +973,7 @@ func enableRuleTraceHandler(w http.ResponseWriter, r *http.Request) {
 		handleError(w, err, "Invalid body: Error decoding json", logger)
 		return
 	}
-	err = setIsRuleTraceEnabledHandler(name, true, kctx.StringToStrategy(req.Strategy))
+	err = setIsRuleTraceEnabledHandler(name, true, req.Strategy)
 	if err != nil {
 		handleError(w, err, "", logger)
 		return
@@ -905,7 +986,7 @@ func disableRuleTraceHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	name := vars["name"]
 
-	err := setIsRuleTraceEnabledHandler(name, false, kctx.AlwaysTraceStrategy)
+	err := setIsRuleTraceEnabledHandler(name, false, "")
 	if err != nil {
 		handleError(w, err, "", logger)
 		return
@@ -913,12 +994,28 @@ func disableRuleTraceHandler(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
-func setIsRuleTraceEnabledHandler(name string, isEnabled bool, stra kctx.TraceStrategy) error {
+// setIsRuleTraceEnabledHandler enables or disables the trace of the running rule and persists it so that the rule is
+// traced the same after restart. The ratio and the max depth of the rule are kept.
+func setIsRuleTraceEnabledHandler(name string, isEnabled bool, strategy string) error {
 	rs, ok := registry.load(name)
 	if !ok {
 		return fmt.Errorf("rule %s isn't existed", name)
 	}
-	return rs.SetIsTraceEnabled(isEnabled, stra)
+	if err := rs.SetIsTraceEnabled(isEnabled, kctx.StringToStrategy(strategy)); err != nil {
+		return err
+	}
+	c, err := tracer.GetRuleTrace(name)
+	if err != nil {
+		return err
+	}
+	if c == nil {
+		c = &tracer.RuleTraceConf{}
+	}
+	c.Enabled = isEnabled
+	if isEnabled {
+		c.Strategy = strategy
+	}
+	return tracer.SetRuleTrace(name, c)
 }
 
 // get topo of a rule
@@ -23,6 +23,7 @@ import (
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -37,6 +38,7 @@ import (
 	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
 	"github.com/lf-edge/ekuiper/v2/internal/pkg/store"
 	"github.com/lf-edge/ekuiper/v2/internal/processor"
+	"github.com/lf-edge/ekuiper/v2/internal/server/middleware"
 	"github.com/lf-edge/ekuiper/v2/internal/testx"
 	"github.com/lf-edge/ekuiper/v2/internal/topo/rule"
 	"github.com/lf-edge/ekuiper/v2/pkg/ast"
@@ -73,6 +75,9 @@ func (suite *RestTestSuite) SetupTest() {
 	r := mux.NewRouter()
 	r.HandleFunc("/", rootHandler).Methods(http.MethodGet, http.MethodPost)
 	r.HandleFunc("/ping", pingHandler).Methods(http.MethodGet)
+	r.HandleFunc("/healthz", healthzHandler).Methods(http.MethodGet)
+	r.HandleFunc("/ready", readyHandler).Methods(http.MethodGet)
+	r.HandleFunc("/openapi.json", openAPIHandler).Methods(http.MethodGet)
 	r.HandleFunc("/streams", streamsHandler).Methods(http.MethodGet, http.MethodPost)
 	r.HandleFunc("/streamdetails", streamDetailsHandler).Methods(http.MethodGet)
 	r.HandleFunc("/streams/{name}", streamHandler).Methods(http.MethodGet, http.MethodDelete, http.MethodPut)
@@ -98,12 +103,40 @@ func (suite *RestTestSuite) SetupTest() {
 	r.HandleFunc("/ruleset/export", exportHandler).Methods(http.MethodPost)
 	r.HandleFunc("/ruleset/import", importHandler).Methods(http.MethodPost)
 	r.HandleFunc("/configs", configurationUpdateHandler).Methods(http.MethodPatch)
+	r.HandleFunc("/configs/connection", middleware.Unbound(connectionTuningHandler)).Methods(http.MethodGet, http.MethodPatch)
+	r.HandleFunc("/configs/sync", configSyncHandler).Methods(http.MethodGet, http.MethodPost)
+	r.HandleFunc("/configs/reload", configReloadHandler).Methods(http.MethodPost)
+	r.HandleFunc("/audit", auditHandler).Methods(http.MethodGet)
+	r.HandleFunc("/audit/export", auditExportHandler).Methods(http.MethodGet)
 	r.HandleFunc("/config/uploads", fileUploadHandler).Methods(http.MethodPost, http.MethodGet)
 	r.HandleFunc("/config/uploads/{name}", fileDeleteHandler).Methods(http.MethodDelete)
 	r.HandleFunc("/data/export", configurationExportHandler).Methods(http.MethodGet, http.MethodPost)
 	r.HandleFunc("/data/import", configurationImportHandler).Methods(http.MethodPost)
 	r.HandleFunc("/data/import/status", configurationStatusHandler).Methods(http.MethodGet)
 	r.HandleFunc("/connections", connectionsHandler).Methods(http.MethodGet, http.MethodPost)
+	r.HandleFunc("/connections/status/ws", middleware.Unbound(connectionStatusWsHandler)).Methods(http.MethodGet)
+	r.HandleFunc("/connections/export", middleware.Unbound(connectionExportHandler)).Methods(http.MethodGet)
+	r.HandleFunc("/connections/import", middleware.Unbound(connectionImportHandler)).Methods(http.MethodPost)
+	r.HandleFunc("/connections/validate", connectionValidateHandler).Methods(http.MethodPost)
+	r.HandleFunc("/connections/reconcile", middleware.Unbound(connectionReconcileHandler)).Methods(http.MethodPost)
+	r.HandleFunc("/connections/snapshot", middleware.Unbound(connectionSnapshotHandler)).Methods(http.MethodGet)
+	r.HandleFunc("/connections/snapshot/restore", middleware.Unbound(connectionSnapshotRestoreHandler)).Methods(http.MethodPost)
+	r.HandleFunc("/connections/templates", middleware.Unbound(connectionTemplatesHandler)).Methods(http.MethodGet, http.MethodPost)
+	r.HandleFunc("/connections/templates/{id}", middleware.Unbound(connectionTemplateHandler)).Methods(http.MethodGet, http.MethodPut, http.MethodDelete)
+	r.HandleFunc("/connections/templates/{id}/instantiate", connectionTemplateInstantiateHandler).Methods(http.MethodPost)
+	r.HandleFunc("/connections/aliases", connectionAliasesHandler).Methods(http.MethodGet, http.MethodPost)
+	r.HandleFunc("/connections/aliases/{alias}", connectionAliasHandler).Methods(http.MethodPut, http.MethodDelete)
+	r.HandleFunc("/connections/trash", middleware.Unbound(connectionTrashHandler)).Methods(http.MethodGet)
+	r.HandleFunc("/connections/trash/{id}", middleware.Unbound(trashedConnectionHandler)).Methods(http.MethodDelete)
+	r.HandleFunc("/connections/trash/{id}/restore", middleware.Unbound(restoreConnectionHandler)).Methods(http.MethodPost)
+	r.HandleFunc("/connections/{id}/attachers", connectionAttachersHandler).Methods(http.MethodGet)
+	r.HandleFunc("/connections/{id}/refaudit", connectionRefAuditHandler).Methods(http.MethodGet)
+	r.HandleFunc("/connections/{id}/events", connectionEventsHandler).Methods(http.MethodGet)
+	r.HandleFunc("/connections/{id}/labels", connectionLabelsHandler).Methods(http.MethodPut)
+	r.HandleFunc("/connections/{id}/pause", connectionPauseHandler).Methods(http.MethodPost)
+	r.HandleFunc("/connections/{id}/resume", connectionResumeHandler).Methods(http.MethodPost)
+	r.HandleFunc("/connections/{id}/clone", connectionCloneHandler).Methods(http.MethodPost)
+	r.HandleFunc("/connections/{id}/rotate", connectionRotateHandler).Methods(http.MethodPost)
 	r.HandleFunc("/connections/{id}", connectionHandler).Methods(http.MethodGet, http.MethodDelete, http.MethodPut)
 	r.HandleFunc("/ruletest", testRuleHandler).Methods(http.MethodPost)
 	r.HandleFunc("/ruletest/{name}/start", testRuleStartHandler).Methods(http.MethodPost)
@@ -430,7 +463,7 @@ func (suite *RestTestSuite) Test_rulesManageHandler() {
 	suite.r.ServeHTTP(w1, req1)
 	returnVal, _ = io.ReadAll(w1.Result().Body) //nolint
 	returnStr := string(returnVal)
-	expect = "{\"error\":1002,\"message\":\"explain rules error: Rule rule32211 is not found.\"}\n"
+	expect = "{\"error\":1002,\"code\":\"NOT_FOUND\",\"category\":\"permanent\",\"message\":\"explain rules error: Rule rule32211 is not found.\"}\n"
 	assert.Equal(suite.T(), expect, returnStr)
 
 	// get rule topo
@@ -1024,3 +1057,98 @@ func (suite *RestTestSuite) TestWaitStopRule() {
 	require.True(suite.T(), end.Sub(now) >= 300*time.Millisecond)
 	waitAllRuleStop()
 }
+
+func TestHandleErrorCode(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+		body   string
+		retry  string
+	}{
+		{
+			err:    fmt.Errorf("plain <error>"),
+			status: http.StatusBadRequest,
+			body:   `{"error":1000,"message":"plain <error>"}`,
+		},
+		{
+			err:    errorx.NewWithCode(errorx.ConnectionInUseErr, "in use"),
+			status: http.StatusBadRequest,
+			body:   `{"error":6002,"code":"CONNECTION_IN_USE","category":"permanent","message":"in use"}`,
+		},
+		{
+			err:    errorx.NewWithCode(errorx.NOT_FOUND, "not found"),
+			status: http.StatusNotFound,
+			body:   `{"error":1002,"code":"NOT_FOUND","category":"permanent","message":"not found"}`,
+		},
+		{
+			err:    errorx.NewQuotaWithRetryAfter(errorx.NewIOErr("rate limited"), 1500*time.Millisecond),
+			status: http.StatusTooManyRequests,
+			body:   `{"error":1003,"code":"IO","category":"quota","retryAfter":2,"message":"rate limited"}`,
+			retry:  "2",
+		},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		handleError(w, tt.err, "", conf.Log)
+		require.Equal(t, tt.status, w.Code)
+		require.Equal(t, tt.body+"\n", w.Body.String())
+		require.Equal(t, tt.retry, w.Header().Get("Retry-After"))
+	}
+}
+
+func (suite *RestTestSuite) TestHealthz() {
+	req, _ := http.NewRequest(http.MethodGet, "http://localhost:8080/healthz", bytes.NewBufferString("any"))
+	w := httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusOK, w.Code, w.Body.String())
+	report := &healthReport{}
+	require.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), report))
+	require.NotEqual(suite.T(), HealthDown, report.Status)
+	require.Equal(suite.T(), HealthUp, report.Storage.Status)
+	require.NotNil(suite.T(), report.Connections)
+	require.NotNil(suite.T(), report.Tracer)
+}
+
+func (suite *RestTestSuite) TestReady() {
+	req, _ := http.NewRequest(http.MethodGet, "http://localhost:8080/ready", bytes.NewBufferString("any"))
+	w := httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	report := &connection.PoolReadiness{}
+	require.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), report))
+	if report.Ready {
+		require.Equal(suite.T(), http.StatusOK, w.Code)
+	} else {
+		require.Equal(suite.T(), http.StatusServiceUnavailable, w.Code)
+		require.NotEmpty(suite.T(), report.Down)
+	}
+	require.NotNil(suite.T(), report.Types)
+}
+
+func (suite *RestTestSuite) TestOpenAPI() {
+	req, _ := http.NewRequest(http.MethodGet, "http://localhost:8080/openapi.json", bytes.NewBufferString("any"))
+	w := httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusOK, w.Code)
+	doc := map[string]any{}
+	require.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), &doc))
+	require.Equal(suite.T(), "3.0.3", doc["openapi"])
+	schemas := doc["components"].(map[string]any)["schemas"].(map[string]any)
+	for _, name := range []string{"ConnectionMeta", "ConnectionStatus", "LocalSpan", "ConnectionTuning"} {
+		require.Contains(suite.T(), schemas, name)
+	}
+	spanProps := schemas["LocalSpan"].(map[string]any)["properties"].(map[string]any)
+	require.Equal(suite.T(), map[string]any{"type": "array", "items": map[string]any{"$ref": "#/components/schemas/LocalSpan"}}, spanProps["ChildSpan"])
+	require.Equal(suite.T(), map[string]any{"type": "string", "format": "date-time"}, spanProps["startTime"])
+	// all the connection and trace routes are documented
+	paths := doc["paths"].(map[string]any)
+	require.NoError(suite.T(), suite.r.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		tpl, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		if strings.HasPrefix(tpl, "/connections") || strings.HasPrefix(tpl, "/trace") {
+			require.Contains(suite.T(), paths, tpl)
+		}
+		return nil
+	}))
+}
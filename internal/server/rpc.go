@@ -34,7 +34,9 @@ import (
 	"github.com/lf-edge/ekuiper/v2/internal/pkg/model"
 	"github.com/lf-edge/ekuiper/v2/internal/topo/rule/machine"
 	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+	"github.com/lf-edge/ekuiper/v2/pkg/connection"
 	"github.com/lf-edge/ekuiper/v2/pkg/infra"
+	"github.com/lf-edge/ekuiper/v2/pkg/tracer"
 	"github.com/lf-edge/ekuiper/v2/pkg/validate"
 )
 
@@ -402,6 +404,104 @@ func (t *Server) ExportConfiguration(arg *model.ExportDataDesc, reply *string) e
 	return nil
 }
 
+func (t *Server) BackupConfigStore(arg *model.BackupStoreDesc, reply *string) error {
+	f, err := os.Create(arg.FileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	n, err := conf.BackupCfgStorage(f, arg.Prefixes)
+	if err != nil {
+		return fmt.Errorf("backup config store error: %v", err)
+	}
+	*reply = fmt.Sprintf("backed up %d config keys", n)
+	return nil
+}
+
+func (t *Server) RestoreConfigStore(arg *model.RestoreStoreDesc, reply *string) error {
+	f, err := os.Open(arg.FileName)
+	if err != nil {
+		return fmt.Errorf("fail to read file %s: %v", arg.FileName, err)
+	}
+	defer f.Close()
+	n, err := conf.RestoreCfgStorage(f, arg.Prefixes, arg.Replace)
+	if err != nil {
+		return fmt.Errorf("restore config store error: %v", err)
+	}
+	if err := connection.ReloadNamedConnection(); err != nil {
+		logger.Warnf("reload named connections after restore error: %v", err)
+	}
+	*reply = fmt.Sprintf("restored %d config keys", n)
+	return nil
+}
+
+func (t *Server) MigrateConfigStore(arg *model.MigrateStoreDesc, reply *string) error {
+	r, err := conf.MigrateCfgStorage(arg.From, arg.To, arg.Prefixes)
+	if err != nil {
+		if r != nil && len(r.Mismatch) > 0 {
+			return fmt.Errorf("migrate config store error: %v, mismatched keys: %s", err, strings.Join(r.Mismatch, ","))
+		}
+		return fmt.Errorf("migrate config store error: %v", err)
+	}
+	*reply = fmt.Sprintf("migrated %d config keys from %s to %s, %d verified", r.Copied, r.From, r.To, r.Verified)
+	return nil
+}
+
+func (t *Server) ShowConnections(arg *model.ListConnectionsDesc, reply *string) error {
+	sel, err := connection.ParseLabelSelector(arg.Selector)
+	if err != nil {
+		return fmt.Errorf("show connections error: %v", err)
+	}
+	filter := connection.ConnectionFilter{
+		Namespace: arg.Namespace,
+		Typ:       arg.Typ,
+		Status:    arg.Status,
+		Selector:  sel,
+		ForceAll:  arg.ForceAll,
+	}
+	page, err := connection.ListConnections(filter, arg.Sort, arg.Limit, arg.Offset)
+	if err != nil {
+		return fmt.Errorf("show connections error: %v", err)
+	}
+	s, err := marshalDesc(page)
+	if err != nil {
+		return fmt.Errorf("show connections error: %v", err)
+	}
+	*reply = s
+	return nil
+}
+
+func (t *Server) ReconcileConnections(repair bool, reply *string) error {
+	r, err := connection.ReconcileConnections(repair)
+	if err != nil {
+		return fmt.Errorf("reconcile connections error: %v", err)
+	}
+	s, err := marshalDesc(r)
+	if err != nil {
+		return fmt.Errorf("reconcile connections error: %v", err)
+	}
+	*reply = s
+	return nil
+}
+
+func (t *Server) MigrateTraceStore(_ int, reply *string) error {
+	r, err := tracer.MigrateSpans()
+	if err != nil {
+		return fmt.Errorf("migrate trace store error: %v", err)
+	}
+	*reply = fmt.Sprintf("migrated %d spans of %d traces to sqlite, %d traces verified", r.Spans, r.Traces, r.Verified)
+	return nil
+}
+
+func (t *Server) RecompressTraceStore(_ int, reply *string) error {
+	r, err := tracer.RecompressSpans()
+	if err != nil {
+		return fmt.Errorf("recompress trace store error: %v", err)
+	}
+	*reply = fmt.Sprintf("rewrote %d of %d spans by %s, %d bytes to %d bytes", r.Rewritten, r.Spans, r.Codec, r.BeforeBytes, r.AfterBytes)
+	return nil
+}
+
 func marshalDesc(m interface{}) (string, error) {
 	s, err := json.Marshal(m)
 	if err != nil {
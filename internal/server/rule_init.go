@@ -30,6 +30,7 @@ import (
 	"github.com/lf-edge/ekuiper/v2/internal/topo/rule/machine"
 	"github.com/lf-edge/ekuiper/v2/metrics"
 	"github.com/lf-edge/ekuiper/v2/pkg/ast"
+	"github.com/lf-edge/ekuiper/v2/pkg/connection"
 	"github.com/lf-edge/ekuiper/v2/pkg/timex"
 )
 
@@ -231,6 +232,7 @@ func handleAllScheduleRuleState(now time.Time, rs []ruleWrapper) {
 			if err := handleScheduleRuleState(now, r); err != nil {
 				conf.Log.Errorf("handle schedule rule %v state failed, err:%v", r.rule.Id, err)
 			}
+			handleScheduleRuleWarmUp(now, r)
 		}
 		// handle auto restart rules
 		if r.rule.Options.RestartStrategy != nil && r.rule.Options.RestartStrategy.Attempts > 0 {
@@ -258,6 +260,22 @@ func handleScheduleRuleState(now time.Time, rw ruleWrapper) error {
 	return nil
 }
 
+// handleScheduleRuleWarmUp pre-establishes the connections of the scheduled rule waiting for its next window when the
+// window opens within the warm-up period. The connections are released once the rule runs or the window is over.
+func handleScheduleRuleWarmUp(now time.Time, rw ruleWrapper) {
+	if rw.rule.Options == nil || rw.rule.Options.WarmUp <= 0 || !rw.rule.IsScheduleRule() {
+		return
+	}
+	switch {
+	case rw.state == machine.ScheduledStop && handleScheduleRule(now.Add(time.Duration(rw.rule.Options.WarmUp)), rw) == scheduleRuleActionStart:
+		if n := connection.WarmUp(rw.rule.Id); n > 0 {
+			conf.Log.Infof("warm up %d connections for the next window of rule %v", n, rw.rule.Id)
+		}
+	case rw.state == machine.Running || handleScheduleRule(now, rw) != scheduleRuleActionStart:
+		connection.ReleaseWarmUp(rw.rule.Id)
+	}
+}
+
 type scheduleRuleAction int
 
 const (
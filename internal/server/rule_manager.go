@@ -32,10 +32,12 @@ import (
 	"github.com/lf-edge/ekuiper/v2/internal/topo/rule/machine"
 	"github.com/lf-edge/ekuiper/v2/internal/xsql"
 	"github.com/lf-edge/ekuiper/v2/pkg/ast"
+	"github.com/lf-edge/ekuiper/v2/pkg/connection"
 	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
 	"github.com/lf-edge/ekuiper/v2/pkg/infra"
 	"github.com/lf-edge/ekuiper/v2/pkg/replace"
 	"github.com/lf-edge/ekuiper/v2/pkg/syncx"
+	"github.com/lf-edge/ekuiper/v2/pkg/tracer"
 )
 
 // Rule storage includes kv and in memory registry
@@ -237,6 +239,9 @@ func (rr *RuleRegistry) DeleteRule(name string) error {
 		rs.Delete()
 	}
 	deleteRuleData(name)
+	connection.ForgetWarmUp(name)
+	// forget the runtime trace setting of the rule
+	_ = tracer.SetRuleTrace(name, nil)
 	return err
 }
 
@@ -0,0 +1,136 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/store"
+	topoContext "github.com/lf-edge/ekuiper/v2/internal/topo/context"
+	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+	"github.com/lf-edge/ekuiper/v2/pkg/cert"
+	"github.com/lf-edge/ekuiper/v2/pkg/connection"
+	"github.com/lf-edge/ekuiper/v2/pkg/tracer"
+)
+
+const (
+	checkPass = "pass"
+	checkFail = "fail"
+)
+
+type selfTestCheck struct {
+	// Component is storage, connection, tracer or compliance
+	Component string `json:"component"`
+	Name      string `json:"name"`
+	Status    string `json:"status"`
+	Error     string `json:"error,omitempty"`
+}
+
+type selfTestReport struct {
+	Version string          `json:"version"`
+	Passed  bool            `json:"passed"`
+	Checks  []selfTestCheck `json:"checks"`
+}
+
+func (r *selfTestReport) add(component, name string, err error) {
+	c := selfTestCheck{Component: component, Name: name, Status: checkPass}
+	if err != nil {
+		c.Status = checkFail
+		c.Error = err.Error()
+		r.Passed = false
+	}
+	r.Checks = append(r.Checks, c)
+}
+
+// SelfTest validates the storage, the stored connections and the tracer endpoint without serving, then prints
+// the report to stdout. It returns the exit code which is non-zero if any check fails.
+func SelfTest(Version string) int {
+	version = Version
+	createPaths()
+	setupConf()
+	report := runSelfTest()
+	b, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "marshal self-test report error: %v\n", err)
+		return 1
+	}
+	fmt.Println(string(b))
+	if !report.Passed {
+		return 1
+	}
+	return 0
+}
+
+func runSelfTest() *selfTestReport {
+	report := &selfTestReport{Version: version, Passed: true, Checks: make([]selfTestCheck, 0)}
+	sc, err := getStoreConfigByKuiperConfig(conf.Config)
+	if err == nil {
+		err = store.SetupWithConfig(sc, false)
+	}
+	if err == nil {
+		err = store.Ping(context.Background())
+	}
+	report.add("storage", conf.Config.Store.Type, err)
+	// the connections are stored in the storage
+	if err != nil {
+		return report
+	}
+	results, err := connection.ProbeNamedConnections(topoContext.Background())
+	if err != nil {
+		report.add("connection", "", err)
+	}
+	for _, r := range results {
+		report.add("connection", r.ID, r.Err)
+	}
+	endpoint, err := tracer.CheckRemoteEndpoint(time.Duration(connection.GetTuning().OperationTimeout))
+	if endpoint != "" || err != nil {
+		report.add("tracer", endpoint, err)
+	}
+	if cert.ComplianceEnabled() {
+		checkCompliance(report, endpoint)
+	}
+	return report
+}
+
+// checkCompliance reports the settings violating the compliance mode. The connections are validated by the probes
+// already.
+func checkCompliance(report *selfTestReport, tracerEndpoint string) {
+	var err error
+	if !cert.FIPSEnabled() {
+		err = errors.New("the FIPS 140-3 mode is off, start with GODEBUG=fips140=on")
+	}
+	report.add("compliance", "fips140", err)
+	restAddr := cast.JoinHostPortInt(conf.Config.Basic.RestIp, conf.Config.Basic.RestPort)
+	if conf.Config.Basic.RestTls == nil {
+		err = cert.CheckPlaintext(restAddr)
+	} else {
+		err = nil
+	}
+	report.add("compliance", "rest "+restAddr, err)
+	if tracerEndpoint != "" {
+		if conf.Config.OpenTelemetry.RemoteTls == nil {
+			err = cert.CheckPlaintext(tracerEndpoint)
+		} else {
+			_, err = cert.GenTLSConfigForService(conf.Config.OpenTelemetry.RemoteTls)
+		}
+		report.add("compliance", "tracer "+tracerEndpoint, err)
+	}
+}
@@ -43,6 +43,7 @@ import (
 	"github.com/lf-edge/ekuiper/v2/internal/plugin/portable/runtime"
 	"github.com/lf-edge/ekuiper/v2/internal/processor"
 	"github.com/lf-edge/ekuiper/v2/internal/server/bump"
+	topoContext "github.com/lf-edge/ekuiper/v2/internal/topo/context"
 	"github.com/lf-edge/ekuiper/v2/internal/topo/rule"
 	"github.com/lf-edge/ekuiper/v2/metrics"
 	"github.com/lf-edge/ekuiper/v2/modules/encryptor"
@@ -163,11 +164,8 @@ func canSetupCheckpointDB() (setup bool) {
 	return false
 }
 
-func StartUp(Version string) {
-	version = Version
-	startTimeStamp = time.Now().Unix()
-	createPaths()
-	needSetup := canSetupCheckpointDB()
+// setupConf loads the configuration and initializes the security settings
+func setupConf() {
 	conf.SetupEnv()
 	conf.InitConf()
 	if modules.ConfHook != nil {
@@ -180,7 +178,19 @@ func StartUp(Version string) {
 		if conf.Config.Security.Tls != nil {
 			cert.InitConf(conf.Config.Security.Tls)
 		}
+		cert.SetExpiryWarning(time.Duration(conf.Config.Security.CertExpiryWarning))
+	}
+	if cert.ComplianceEnabled() && !cert.FIPSEnabled() {
+		conf.Log.Warn("compliance mode is enabled but the FIPS 140-3 mode is off, start with GODEBUG=fips140=on")
 	}
+}
+
+func StartUp(Version string) {
+	version = Version
+	startTimeStamp = time.Now().Unix()
+	createPaths()
+	needSetup := canSetupCheckpointDB()
+	setupConf()
 	// Print inited modules
 	for n := range modules.Sources {
 		conf.Log.Infof("register source %s", n)
@@ -224,6 +234,19 @@ func StartUp(Version string) {
 	} else {
 		conf.Log.Infof("tracer init successfully")
 	}
+	if err := tracer.InitMeter(); err != nil {
+		conf.Log.Warnf("meter init error: %v", err)
+	}
+	if addr := conf.Config.OpenTelemetry.OtlpReceiverAddress; addr != "" {
+		if err := tracer.StartOtlpReceiver(addr); err != nil {
+			conf.Log.Warnf("start otlp receiver error: %v", err)
+		}
+	}
+	if addr := conf.Config.OpenTelemetry.OtlpReceiverHttpAddress; addr != "" {
+		if err := tracer.StartOtlpHttpReceiver(addr); err != nil {
+			conf.Log.Warnf("start otlp http receiver error: %v", err)
+		}
+	}
 
 	keyedstate.InitKeyedStateKV()
 
@@ -256,6 +279,18 @@ func StartUp(Version string) {
 	if err := connection.ReloadNamedConnection(); err != nil {
 		conf.Log.Warn(err)
 	}
+	reconcileDeclaredConnections()
+	if _, err := connection.ReconcileConnections(false); err != nil {
+		conf.Log.Warnf("reconcile connections error: %v", err)
+	}
+	startConfigSync(serverCtx)
+	startReplication(serverCtx)
+	startConfigReload(serverCtx)
+	if conf.Config.Basic.ManagementGrpcAddr != "" {
+		if _, err := startManagementGrpc(conf.Config.Basic.ManagementGrpcAddr); err != nil {
+			logger.Errorf("start grpc management api error: %v", err)
+		}
+	}
 	initRuleset()
 
 	registry = &RuleRegistry{internal: make(map[string]*rule.State)}
@@ -294,6 +329,7 @@ func StartUp(Version string) {
 		if conf.Config.Basic.RestTls == nil {
 			err = srvRest.Serve(ln)
 		} else {
+			srvRest.TLSConfig = cert.HardenServerTLS(srvRest.TLSConfig)
 			err = srvRest.ServeTLS(ln, conf.Config.Basic.RestTls.Certfile, conf.Config.Basic.RestTls.Keyfile)
 		}
 		if err != nil && err != http.ErrServerClosed {
@@ -359,8 +395,16 @@ func StartUp(Version string) {
 	case <-ctx.Done():
 		conf.Log.Info("wait rule graceful stop timeout")
 	}
+	// close connections after the rules have drained, the references left by the rules which fail to stop are
+	// waited until the graceful shutdown timeout
+	deadline, _ := ctx.Deadline()
+	connection.ShutdownConnectionManager(topoContext.Background(), time.Until(deadline))
 	// kill all plugin process
 	runtime.GetPluginInsManager().KillAll()
+	stopManagementGrpc()
+	stopReplication()
+	tracer.StopOtlpReceiver()
+	tracer.StopMeter(ctx)
 
 	// close extend services
 	for k, v := range servers {
@@ -0,0 +1,60 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/pkg/connection"
+)
+
+// storeBackupHandler downloads the config store archive. Repeat the prefix query parameter to select the keys,
+// e.g. ?prefix=connections&prefix=sources. All the keys are exported without prefix.
+func storeBackupHandler(w http.ResponseWriter, r *http.Request) {
+	prefixes := r.URL.Query()["prefix"]
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", "attachment; filename=ekuiper_store_backup.json.gz")
+	if _, err := conf.BackupCfgStorage(w, prefixes); err != nil {
+		handleError(w, err, "backup config store error", logger)
+	}
+}
+
+// storeRestoreHandler restores the config store from the archive in the body. The prefix query parameters select
+// the keys to restore. With replace=true, the keys not in the archive are deleted to return to the backup state.
+func storeRestoreHandler(w http.ResponseWriter, r *http.Request) {
+	prefixes := r.URL.Query()["prefix"]
+	replace := false
+	if s := r.URL.Query().Get("replace"); s != "" {
+		var err error
+		replace, err = strconv.ParseBool(s)
+		if err != nil {
+			handleError(w, err, "Invalid replace", logger)
+			return
+		}
+	}
+	n, err := conf.RestoreCfgStorage(r.Body, prefixes, replace)
+	if err != nil {
+		handleError(w, err, "restore config store error", logger)
+		return
+	}
+	if err := connection.ReloadNamedConnection(); err != nil {
+		logger.Warnf("reload named connections after restore error: %v", err)
+	}
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "restored %d config keys", n)
+}
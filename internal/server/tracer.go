@@ -16,30 +16,120 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
 
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/audit"
+	"github.com/lf-edge/ekuiper/v2/internal/server/middleware"
+	kctx "github.com/lf-edge/ekuiper/v2/internal/topo/context"
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+	"github.com/lf-edge/ekuiper/v2/pkg/model"
 	"github.com/lf-edge/ekuiper/v2/pkg/tracer"
 )
 
 func getTraceByID(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
-	root, err := tracer.GetSpanByTraceID(id)
+	root, err := tracer.GetTraceByID(id)
 	if err != nil {
 		handleError(w, err, "", logger)
 		return
 	}
 	if root == nil {
-		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		handleError(w, errorx.NewWithCode(errorx.NOT_FOUND, fmt.Sprintf("trace %s is not found", id)), "", logger)
 		return
 	}
 	jsonResponse(root, w, logger)
 }
 
+// compareTraceHandler compares the trace in the trace query parameter against the baseline trace in the base query
+// parameter to find the regressed spans
+func compareTraceHandler(w http.ResponseWriter, r *http.Request) {
+	var roots [2]*tracer.LocalSpan
+	for i, key := range []string{"base", "trace"} {
+		id := r.URL.Query().Get(key)
+		if id == "" {
+			handleError(w, fmt.Errorf("%s is required", key), "", logger)
+			return
+		}
+		root, err := tracer.GetTraceByID(id)
+		if err != nil {
+			handleError(w, err, "", logger)
+			return
+		}
+		if root == nil {
+			handleError(w, errorx.NewWithCode(errorx.NOT_FOUND, fmt.Sprintf("trace %s is not found", id)), "", logger)
+			return
+		}
+		roots[i] = root
+	}
+	c, err := tracer.CompareTraces(roots[0], roots[1])
+	if err != nil {
+		handleError(w, err, "", logger)
+		return
+	}
+	jsonResponse(c, w, logger)
+}
+
+// exportTraceByID downloads the trace in the jaeger ui json or the zipkin v2 json format by the format query parameter,
+// default to jaeger.
+func exportTraceByID(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	var (
+		data []byte
+		err  error
+	)
+	format := r.URL.Query().Get("format")
+	switch format {
+	case "", tracer.TraceFormatJaeger:
+		format = tracer.TraceFormatJaeger
+		data, err = tracer.ExportTraceAsJaegerJSON(id)
+	case tracer.TraceFormatZipkin:
+		data, err = tracer.ExportTraceAsZipkinJSON(id)
+	default:
+		err = fmt.Errorf("invalid format %s, must be jaeger or zipkin", format)
+	}
+	if err != nil {
+		handleError(w, err, "", logger)
+		return
+	}
+	w.Header().Set(ContentType, ContentTypeJSON)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s-%s.json", id, format))
+	_, _ = w.Write(data)
+}
+
+// getTraceLinks returns the links between the trace and the other traces, such as the input traces of a window
+func getTraceLinks(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	links, err := tracer.GetTraceLinks(id)
+	if err != nil {
+		handleError(w, err, "", logger)
+		return
+	}
+	jsonResponse(links, w, logger)
+}
+
+// deleteTraceByID deletes the spans of the trace from the local storage, such as the traces with the sensitive data
+func deleteTraceByID(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if err := tracer.DeleteTrace(id); err != nil {
+		handleError(w, err, "", logger)
+		return
+	}
+	audit.Record(middleware.Actor(r), audit.SubsystemTracer, audit.ActionDelete, "trace/"+id, nil, nil)
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("success"))
+}
+
 func tracerHandler(w http.ResponseWriter, r *http.Request) {
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
@@ -52,14 +142,101 @@ func tracerHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	enableRemoteCollector := req.Action == "start"
+	before := tracerAuditState()
 	if err := tracer.SetTracer(&tracer.TracerConfig{EnableRemoteCollector: enableRemoteCollector, ServiceName: req.ServiceName, RemoteEndpoint: req.CollectorUrl}); err != nil {
 		handleError(w, err, "", logger)
 		return
 	}
+	audit.Record(middleware.Actor(r), audit.SubsystemTracer, audit.ActionUpdate, "tracer", before, tracerAuditState())
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("success"))
+}
+
+// samplingHandler gets or replaces the sampling config at runtime. The running rules are not restarted and the change
+// is lost after restart.
+func samplingHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		jsonResponse(tracer.GetSampling(), w, logger)
+		return
+	}
+	c := model.SamplingConf{}
+	if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+		handleError(w, err, "Invalid body", logger)
+		return
+	}
+	before := samplingAuditState(tracer.GetSampling())
+	if err := tracer.SetSampling(c); err != nil {
+		handleError(w, err, "Invalid sampling", logger)
+		return
+	}
+	audit.Record(middleware.Actor(r), audit.SubsystemTracer, audit.ActionUpdate, "tracer/sampling", before, samplingAuditState(c))
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("success"))
+}
+
+// ruleTraceHandler gets or sets the runtime trace setting of the rule. The setting is persisted and applied each time
+// the rule starts. It is applied to the running rule at once.
+func ruleTraceHandler(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	rs, ok := registry.load(name)
+	if !ok {
+		handleError(w, errorx.NewWithCode(errorx.NOT_FOUND, fmt.Sprintf("rule %s is not found", name)), "", logger)
+		return
+	}
+	before, err := tracer.GetRuleTrace(name)
+	if err != nil {
+		handleError(w, err, "", logger)
+		return
+	}
+	if r.Method == http.MethodGet {
+		if before == nil {
+			before = &tracer.RuleTraceConf{Enabled: rs.IsTraceEnabled()}
+		}
+		jsonResponse(before, w, logger)
+		return
+	}
+	c := &tracer.RuleTraceConf{}
+	if err := json.NewDecoder(r.Body).Decode(c); err != nil {
+		handleError(w, err, "Invalid body", logger)
+		return
+	}
+	if err := tracer.SetRuleTrace(name, c); err != nil {
+		handleError(w, err, "Invalid rule trace", logger)
+		return
+	}
+	// the stopped rule applies the setting once started
+	_ = rs.SetIsTraceEnabled(c.Enabled, kctx.StringToStrategy(c.Strategy))
+	audit.Record(middleware.Actor(r), audit.SubsystemTracer, audit.ActionUpdate, "rules/"+name+"/trace", ruleTraceAuditState(before), ruleTraceAuditState(c))
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("success"))
+}
+
+// tracerSwitchHandler gets or sets the global switch of the span collection
+func tracerSwitchHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		jsonResponse(&tracer.TraceSwitch{Enabled: tracer.IsTracingEnabled()}, w, logger)
+		return
+	}
+	s := &tracer.TraceSwitch{}
+	if err := json.NewDecoder(r.Body).Decode(s); err != nil {
+		handleError(w, err, "Invalid body", logger)
+		return
+	}
+	before := tracer.IsTracingEnabled()
+	if err := tracer.SetTracingEnabled(s.Enabled); err != nil {
+		handleError(w, err, "", logger)
+		return
+	}
+	audit.Record(middleware.Actor(r), audit.SubsystemTracer, audit.ActionUpdate, "tracer/switch", map[string]any{"enabled": before}, map[string]any{"enabled": s.Enabled})
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("success"))
 }
 
+// tracerStatsHandler gets the statistics of the tracing subsystem itself
+func tracerStatsHandler(w http.ResponseWriter, _ *http.Request) {
+	jsonResponse(tracer.TracerStats(), w, logger)
+}
+
 type SetTracerRequest struct {
 	ServiceName  string `json:"service_name"`
 	Action       string `json:"action"`
@@ -74,14 +251,237 @@ func getTraceIDByRuleID(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		limit = 0
 	}
-	root, err := tracer.GetTraceIDListByRuleID(id, limit)
+	root, err := tracer.GetLatestTraceIDsByRule(id, limit)
 	if err != nil {
 		handleError(w, err, "", logger)
 		return
 	}
 	if root == nil {
-		handleError(w, err, "", logger)
+		handleError(w, errorx.NewWithCode(errorx.NOT_FOUND, fmt.Sprintf("no trace is found for rule %s", id)), "", logger)
 		return
 	}
 	jsonResponse(root, w, logger)
 }
+
+// purgeRuleTraceHandler deletes all the traces of the rule from the local storage on demand
+func purgeRuleTraceHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["ruleID"]
+	result, err := tracer.PurgeRuleTraces(id)
+	if err != nil {
+		handleError(w, err, "", logger)
+		return
+	}
+	audit.Record(middleware.Actor(r), audit.SubsystemTracer, audit.ActionDelete, "trace/rule/"+id, nil, nil)
+	jsonResponse(result, w, logger)
+}
+
+// exportTraceHandler streams all the spans started in the time range. The range is specified by the
+// start and end query parameters in RFC3339 format. Missing parameter means no bound. The format query parameter is
+// json, jsonl or binary, default to json.
+func exportTraceHandler(w http.ResponseWriter, r *http.Request) {
+	start, end, ok := parseTimeRange(w, r)
+	if !ok {
+		return
+	}
+	format, err := tracer.ParseSpanFormat(r.URL.Query().Get("format"))
+	if err != nil {
+		handleError(w, err, "Invalid format", logger)
+		return
+	}
+	switch format {
+	case tracer.SpanFormatJSONLines:
+		w.Header().Add(ContentType, "application/x-ndjson")
+	case tracer.SpanFormatBinary:
+		w.Header().Add(ContentType, "application/octet-stream")
+	default:
+		w.Header().Add(ContentType, ContentTypeJSON)
+	}
+	count, err := tracer.ExportSpansAs(w, format, start, end)
+	if err != nil && count == 0 {
+		handleError(w, err, "", logger)
+		return
+	}
+	if err != nil {
+		// the response is partially written, only log the error
+		logger.Errorf("export trace spans err after %d spans: %v", count, err)
+	}
+}
+
+// traceBundleHandler downloads the traces in the comma separated ids query parameter as a trace bundle for the
+// offline analysis
+func traceBundleHandler(w http.ResponseWriter, r *http.Request) {
+	var ids []string
+	for _, id := range strings.Split(r.URL.Query().Get("ids"), ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		handleError(w, errors.New("ids is required"), "", logger)
+		return
+	}
+	w.Header().Set(ContentType, "application/gzip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=trace_bundle_%d.tar.gz", time.Now().Unix()))
+	if err := tracer.ExportTraceBundle(ids, w); err != nil {
+		// the error before the archive is written can still be responded
+		w.Header().Del(ContentType)
+		w.Header().Del("Content-Disposition")
+		handleError(w, err, "", logger)
+	}
+}
+
+// spanMetricsHandler returns the latency, error and throughput metrics derived from the spans of the optional rule
+func spanMetricsHandler(w http.ResponseWriter, r *http.Request) {
+	jsonResponse(tracer.GetSpanMetrics(r.URL.Query().Get("rule")), w, logger)
+}
+
+// spanAlertsHandler returns the recent span alert events of the rule in the rule query parameter, or of all the rules
+func spanAlertsHandler(w http.ResponseWriter, r *http.Request) {
+	jsonResponse(tracer.GetSpanAlerts(r.URL.Query().Get("rule")), w, logger)
+}
+
+// getTraceIDByAttribute finds the latest trace ids by the value of an indexed span attribute.
+// The key and value query parameters are required, start, end and limit are optional.
+func getTraceIDByAttribute(w http.ResponseWriter, r *http.Request) {
+	key := r.URL.Query().Get("key")
+	if key == "" {
+		handleError(w, errors.New("key is required"), "", logger)
+		return
+	}
+	value := r.URL.Query().Get("value")
+	start, end, ok := parseTimeRange(w, r)
+	if !ok {
+		return
+	}
+	limit, err := strconv.ParseInt(r.URL.Query().Get("limit"), 10, 64)
+	if err != nil {
+		limit = 0
+	}
+	ids, err := tracer.GetTraceIDListByAttribute(key, value, start, end, limit)
+	if err != nil {
+		handleError(w, err, "", logger)
+		return
+	}
+	jsonResponse(ids, w, logger)
+}
+
+// searchTraceHandler finds the root spans of the traces by page, the latest first. The query parameters are all
+// optional: rule, start and end in RFC3339 format, limit, offset, and the repeatable attr in key:value format.
+func searchTraceHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	start, end, ok := parseTimeRange(w, r)
+	if !ok {
+		return
+	}
+	attrs, err := parseAttrFilters(q["attr"])
+	if err != nil {
+		handleError(w, err, "", logger)
+		return
+	}
+	limit, err := strconv.Atoi(q.Get("limit"))
+	if err != nil {
+		limit = 0
+	}
+	offset, err := strconv.Atoi(q.Get("offset"))
+	if err != nil {
+		offset = 0
+	}
+	result, err := tracer.SearchTraces(q.Get("rule"), start, end, attrs, limit, offset)
+	if err != nil {
+		handleError(w, err, "", logger)
+		return
+	}
+	jsonResponse(result, w, logger)
+}
+
+// traceWsHandler pushes the ended spans of the rule and the attr filters to the websocket client, so the
+// debugging ui can watch the spans as the data flows through the rule.
+func traceWsHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	attrs, err := parseAttrFilters(q["attr"])
+	if err != nil {
+		handleError(w, err, "", logger)
+		return
+	}
+	ch, cancel, err := tracer.SubscribeSpans(tracer.SpanFilter{RuleID: q.Get("rule"), Attributes: attrs})
+	if err != nil {
+		handleError(w, err, "", logger)
+		return
+	}
+	defer cancel()
+	c, err := statusUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		conf.Log.Errorf("trace websocket upgrade error: %v", err)
+		return
+	}
+	defer c.Close()
+	// read the client messages to handle the control frames and detect the close
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := c.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+	ticker := time.NewTicker(statusWsPingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-closed:
+			return
+		case <-ticker.C:
+			if err := c.WriteControl(websocket.PingMessage, nil, time.Now().Add(statusWsWriteWait)); err != nil {
+				return
+			}
+		case span, ok := <-ch:
+			if !ok {
+				_ = c.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "too slow"), time.Now().Add(statusWsWriteWait))
+				return
+			}
+			_ = c.SetWriteDeadline(time.Now().Add(statusWsWriteWait))
+			if err := c.WriteJSON(span); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// parseAttrFilters parses the attr query parameters in key:value format
+func parseAttrFilters(values []string) (map[string]string, error) {
+	var attrs map[string]string
+	for _, a := range values {
+		k, v, found := strings.Cut(a, ":")
+		if !found || k == "" {
+			return nil, fmt.Errorf("invalid attr %s, must be in key:value format", a)
+		}
+		if attrs == nil {
+			attrs = make(map[string]string)
+		}
+		attrs[k] = v
+	}
+	return attrs, nil
+}
+
+// parseTimeRange parses the optional start and end query parameters in RFC3339 format.
+// It writes the error response and returns false if any of them is invalid.
+func parseTimeRange(w http.ResponseWriter, r *http.Request) (time.Time, time.Time, bool) {
+	var start, end time.Time
+	var err error
+	if s := r.URL.Query().Get("start"); s != "" {
+		start, err = time.Parse(time.RFC3339, s)
+		if err != nil {
+			handleError(w, err, "Invalid start", logger)
+			return start, end, false
+		}
+	}
+	if e := r.URL.Query().Get("end"); e != "" {
+		end, err = time.Parse(time.RFC3339, e)
+		if err != nil {
+			handleError(w, err, "Invalid end", logger)
+			return start, end, false
+		}
+	}
+	return start, end, true
+}
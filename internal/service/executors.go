@@ -236,7 +236,12 @@ func (h *httpExecutor) invokeFunction(ctx api.FunctionContext, name string, para
 	})
 
 	if h.conn == nil {
+		proxy, err := httpx.ProxyFunc("")
+		if err != nil {
+			return nil, err
+		}
 		tr := &http.Transport{
+			Proxy:           proxy,
 			TLSClientConfig: &tls.Config{InsecureSkipVerify: h.restOpt.InsecureSkipVerify},
 			DialContext:     httpx.GetSSRFDialContext(h.timeout),
 		}
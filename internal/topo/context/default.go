@@ -150,6 +150,36 @@ func (c *DefaultContext) GetLogger() api.Logger {
 	return conf.Log.WithField("caller", "default")
 }
 
+// WithFields returns a copy of the context whose logger carries the structured fields, such as connection or trace id.
+// The kvs are key-value pairs.
+func (c *DefaultContext) WithFields(kvs ...any) *DefaultContext {
+	var l *logrus.Entry
+	if e, ok := c.ctx.Value(LoggerKey).(*logrus.Entry); ok && e != nil {
+		l = e.WithFields(conf.ToLogFields(kvs...))
+	} else {
+		l = conf.LogWithFields(conf.Log, kvs...)
+	}
+	return &DefaultContext{
+		ruleId:         c.ruleId,
+		opId:           c.opId,
+		instanceId:     c.instanceId,
+		runId:          c.runId,
+		ctx:            context.WithValue(c.ctx, LoggerKey, l),
+		store:          c.store,
+		state:          c.state,
+		isTraceEnabled: c.isTraceEnabled,
+		strategy:       c.strategy,
+	}
+}
+
+// WithLogFields adds the structured log fields to the context if supported, otherwise returns the context as is
+func WithLogFields(ctx api.StreamContext, kvs ...any) api.StreamContext {
+	if dc, ok := ctx.(*DefaultContext); ok {
+		return dc.WithFields(kvs...)
+	}
+	return ctx
+}
+
 func (c *DefaultContext) GetRuleId() string {
 	return c.ruleId
 }
@@ -314,6 +344,21 @@ func (c *DefaultContext) WithCancel() (api.StreamContext, context.CancelFunc) {
 	}, cancel
 }
 
+// WithTimeout derives a child context which is cancelled after the timeout or when the parent is done
+func (c *DefaultContext) WithTimeout(d time.Duration) (api.StreamContext, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(c.ctx, d)
+	return &DefaultContext{
+		ruleId:         c.ruleId,
+		opId:           c.opId,
+		instanceId:     c.instanceId,
+		runId:          c.runId,
+		ctx:            ctx,
+		state:          c.state,
+		isTraceEnabled: c.isTraceEnabled,
+		strategy:       c.strategy,
+	}, cancel
+}
+
 func (c *DefaultContext) IncrCounter(key string, amount int) error {
 	for {
 		if v, ok := c.state.Load(key); ok {
@@ -25,6 +25,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/lf-edge/ekuiper/v2/internal/conf"
@@ -278,3 +279,29 @@ func TestRuleBackground(t *testing.T) {
 	ctx := pprof.WithLabels(context.Background(), pprof.Labels("rule", "test"))
 	assert.Equal(t, c.ctx, ctx)
 }
+
+func TestWithFields(t *testing.T) {
+	parent := WithValue(Background(), LoggerKey, conf.Log.WithField("rule", "r1"))
+	c := parent.WithRuleId("r1").(*DefaultContext).WithFields(conf.LogFieldConnection, "c1")
+	assert.Equal(t, "r1", c.GetRuleId())
+	e, ok := c.GetLogger().(*logrus.Entry)
+	assert.True(t, ok)
+	assert.Equal(t, logrus.Fields{"rule": "r1", "connection": "c1"}, e.Data)
+	// parent is not changed
+	assert.Equal(t, logrus.Fields{"rule": "r1"}, parent.GetLogger().(*logrus.Entry).Data)
+
+	c = WithLogFields(Background(), conf.LogFieldTraceID, "t1").(*DefaultContext)
+	assert.Equal(t, "t1", c.GetLogger().(*logrus.Entry).Data[conf.LogFieldTraceID])
+}
+
+func TestWithTimeout(t *testing.T) {
+	c, cancel := Background().WithTimeout(10 * time.Millisecond)
+	defer cancel()
+	_, ok := c.Deadline()
+	assert.True(t, ok)
+	select {
+	case <-c.Done():
+	case <-time.After(time.Second):
+		assert.Fail(t, "should timeout")
+	}
+}
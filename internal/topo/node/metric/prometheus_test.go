@@ -14,8 +14,42 @@
 
 package metric
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	io_prometheus_client "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
+)
 
 func TestNewPrometheus(t *testing.T) {
 	newPrometheusMetrics()
 }
+
+func TestLatencyExemplar(t *testing.T) {
+	hist := prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "test_latency",
+		Buckets: prometheus.ExponentialBuckets(10, 2, 20),
+	})
+	sm := &PrometheusStatManager{
+		DefaultStatManager:  &DefaultStatManager{},
+		pProcessLatency:     prometheus.NewGauge(prometheus.GaugeOpts{Name: "test_latency_gauge"}),
+		pProcessLatencyHist: hist,
+	}
+	sm.ProcessTimeStart()
+	sm.SetTraceID("4bf92f3577b34da6a3ce929d0e0e4736")
+	sm.ProcessTimeEnd()
+	m := &io_prometheus_client.Metric{}
+	require.NoError(t, hist.Write(m))
+	var traceIDs []string
+	for _, b := range m.GetHistogram().GetBucket() {
+		for _, l := range b.GetExemplar().GetLabel() {
+			if l.GetName() == LblTraceID {
+				traceIDs = append(traceIDs, l.GetValue())
+			}
+		}
+	}
+	require.Equal(t, []string{"4bf92f3577b34da6a3ce929d0e0e4736"}, traceIDs)
+	// the trace id is reset after observed
+	require.Equal(t, "", sm.traceID)
+}
@@ -51,6 +51,8 @@ type StatManager interface {
 	ProcessTimeEnd()
 	SetBufferLength(l int64)
 	SetProcessTimeStart(t time.Time)
+	// SetTraceID sets the trace id of the processing message. It will be attached to the latency metrics as exemplar.
+	SetTraceID(traceID string)
 	// 0 is connecting, 1 is connected, -1 is disconnected
 	SetConnectionState(state string, message string)
 	GetMetrics() []any
@@ -77,6 +79,7 @@ type DefaultStatManager struct {
 	opType           string //"source", "op", "sink"
 	prefix           string
 	processTimeStart time.Time
+	traceID          string
 	opId             string
 	instanceId       int
 	syncx.RWMutex
@@ -169,6 +172,13 @@ func (sm *DefaultStatManager) ProcessTimeEnd() {
 	if !sm.processTimeStart.IsZero() {
 		sm.processLatency = int64(time.Since(sm.processTimeStart) / time.Microsecond)
 	}
+	sm.traceID = ""
+}
+
+func (sm *DefaultStatManager) SetTraceID(traceID string) {
+	sm.Lock()
+	defer sm.Unlock()
+	sm.traceID = traceID
 }
 
 func (sm *DefaultStatManager) SetBufferLength(l int64) {
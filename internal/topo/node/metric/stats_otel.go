@@ -0,0 +1,136 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metric
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/lf-edge/ekuiper/v2/pkg/syncx"
+	"github.com/lf-edge/ekuiper/v2/pkg/tracer"
+)
+
+type otelInstruments struct {
+	recordsIn      metric.Int64Counter
+	recordsOut     metric.Int64Counter
+	exceptions     metric.Int64Counter
+	processLatency metric.Int64Histogram
+}
+
+var (
+	instruments   *otelInstruments
+	instrumentsMu syncx.Mutex
+)
+
+func getOtelInstruments() (*otelInstruments, error) {
+	instrumentsMu.Lock()
+	defer instrumentsMu.Unlock()
+	if instruments != nil {
+		return instruments, nil
+	}
+	meter := tracer.Meter()
+	recordsIn, err := meter.Int64Counter("kuiper.records_in", metric.WithDescription("The count of the records received by the operator"))
+	if err != nil {
+		return nil, err
+	}
+	recordsOut, err := meter.Int64Counter("kuiper.records_out", metric.WithDescription("The count of the records sent by the operator"))
+	if err != nil {
+		return nil, err
+	}
+	exceptions, err := meter.Int64Counter("kuiper.exceptions", metric.WithDescription("The count of the exceptions of the operator"))
+	if err != nil {
+		return nil, err
+	}
+	processLatency, err := meter.Int64Histogram("kuiper.process_latency", metric.WithUnit("us"), metric.WithDescription("The latency to process a record by the operator"))
+	if err != nil {
+		return nil, err
+	}
+	instruments = &otelInstruments{
+		recordsIn:      recordsIn,
+		recordsOut:     recordsOut,
+		exceptions:     exceptions,
+		processLatency: processLatency,
+	}
+	return instruments, nil
+}
+
+// OtelStatManager records the throughput and latency to the OTel metrics pipeline besides the wrapped stat manager
+type OtelStatManager struct {
+	StatManager
+	instruments *otelInstruments
+	attrs       metric.MeasurementOption
+	start       time.Time
+	mu          syncx.Mutex
+}
+
+func newOtelStatManager(ctx api.StreamContext, sm StatManager, opType, opId string, instanceId int) (StatManager, error) {
+	ins, err := getOtelInstruments()
+	if err != nil {
+		return sm, err
+	}
+	return &OtelStatManager{
+		StatManager: sm,
+		instruments: ins,
+		attrs: metric.WithAttributeSet(attribute.NewSet(
+			attribute.String("rule", ctx.GetRuleId()),
+			attribute.String("type", opType),
+			attribute.String("op", opId),
+			attribute.String("op_instance", strconv.Itoa(instanceId)),
+		)),
+	}, nil
+}
+
+func (sm *OtelStatManager) IncTotalRecordsIn() {
+	sm.StatManager.IncTotalRecordsIn()
+	sm.instruments.recordsIn.Add(context.Background(), 1, sm.attrs)
+}
+
+func (sm *OtelStatManager) IncTotalRecordsOut() {
+	sm.StatManager.IncTotalRecordsOut()
+	sm.instruments.recordsOut.Add(context.Background(), 1, sm.attrs)
+}
+
+func (sm *OtelStatManager) IncTotalExceptions(err string) {
+	sm.StatManager.IncTotalExceptions(err)
+	sm.instruments.exceptions.Add(context.Background(), 1, sm.attrs)
+}
+
+func (sm *OtelStatManager) ProcessTimeStart() {
+	sm.mu.Lock()
+	sm.start = time.Now()
+	sm.mu.Unlock()
+	sm.StatManager.ProcessTimeStart()
+}
+
+func (sm *OtelStatManager) SetProcessTimeStart(t time.Time) {
+	sm.mu.Lock()
+	sm.start = t
+	sm.mu.Unlock()
+	sm.StatManager.SetProcessTimeStart(t)
+}
+
+func (sm *OtelStatManager) ProcessTimeEnd() {
+	sm.mu.Lock()
+	if !sm.start.IsZero() {
+		sm.instruments.processLatency.Record(context.Background(), time.Since(sm.start).Microseconds(), sm.attrs)
+	}
+	sm.mu.Unlock()
+	sm.StatManager.ProcessTimeEnd()
+}
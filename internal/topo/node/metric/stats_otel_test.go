@@ -0,0 +1,76 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metric
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	mockContext "github.com/lf-edge/ekuiper/v2/pkg/mock/context"
+)
+
+func TestOtelStatManager(t *testing.T) {
+	conf.InitConf()
+	conf.Config.OpenTelemetry.Metrics.Enable = true
+	defer func() {
+		conf.Config.OpenTelemetry.Metrics.Enable = false
+	}()
+	reader := sdkmetric.NewManualReader()
+	otel.SetMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+
+	ctx := mockContext.NewMockContext("rule1", "op1")
+	sm := NewStatManager(ctx, "op")
+	_, ok := sm.(*OtelStatManager)
+	require.True(t, ok)
+	sm.ProcessTimeStart()
+	sm.IncTotalRecordsIn()
+	sm.IncTotalRecordsOut()
+	sm.ProcessTimeEnd()
+	// the wrapped stat manager is still updated
+	a := sm.GetMetrics()
+	require.Equal(t, int64(1), a[0])
+	require.Equal(t, int64(1), a[1])
+
+	rm := metricdata.ResourceMetrics{}
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+	values := map[string]int64{}
+	for _, sc := range rm.ScopeMetrics {
+		for _, m := range sc.Metrics {
+			switch d := m.Data.(type) {
+			case metricdata.Sum[int64]:
+				for _, dp := range d.DataPoints {
+					rule, _ := dp.Attributes.Value("rule")
+					require.Equal(t, "rule1", rule.AsString())
+					values[m.Name] += dp.Value
+				}
+			case metricdata.Histogram[int64]:
+				for _, dp := range d.DataPoints {
+					values[m.Name] += int64(dp.Count)
+				}
+			}
+		}
+	}
+	require.Equal(t, map[string]int64{
+		"kuiper.records_in":      1,
+		"kuiper.records_out":     1,
+		"kuiper.process_latency": 1,
+	}, values)
+}
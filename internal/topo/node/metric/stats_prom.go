@@ -24,6 +24,9 @@ import (
 	"github.com/lf-edge/ekuiper/v2/internal/conf"
 )
 
+// LblTraceID is the exemplar label to link the latency histogram to the trace
+const LblTraceID = "trace_id"
+
 func getStatManager(ctx api.StreamContext, dsm *DefaultStatManager) (StatManager, error) {
 	ctx.GetLogger().Debugf("Create prometheus stat manager")
 	var sm StatManager
@@ -59,6 +62,9 @@ func getStatManager(ctx api.StreamContext, dsm *DefaultStatManager) (StatManager
 	} else {
 		sm = dsm
 	}
+	if conf.Config != nil && conf.Config.OpenTelemetry.Metrics.Enable {
+		return newOtelStatManager(ctx, sm, dsm.opType, dsm.opId, dsm.instanceId)
+	}
 	return sm, nil
 }
 
@@ -109,8 +115,13 @@ func (sm *PrometheusStatManager) ProcessTimeEnd() {
 	if !sm.processTimeStart.IsZero() {
 		sm.processLatency = int64(time.Since(sm.processTimeStart) / time.Microsecond)
 		sm.pProcessLatency.Set(float64(sm.processLatency))
-		sm.pProcessLatencyHist.Observe(float64(sm.processLatency))
+		if eo, ok := sm.pProcessLatencyHist.(prometheus.ExemplarObserver); ok && sm.traceID != "" {
+			eo.ObserveWithExemplar(float64(sm.processLatency), prometheus.Labels{LblTraceID: sm.traceID})
+		} else {
+			sm.pProcessLatencyHist.Observe(float64(sm.processLatency))
+		}
 	}
+	sm.traceID = ""
 }
 
 func (sm *PrometheusStatManager) SetBufferLength(l int64) {
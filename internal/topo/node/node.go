@@ -342,6 +342,9 @@ func (o *defaultNode) onProcessStart(ctx api.StreamContext, val any) {
 
 // onProcessEnd do the common works(metric, trace) after processing a message from upstream
 func (o *defaultNode) onProcessEnd(ctx api.StreamContext) {
+	if o.span != nil {
+		o.statManager.SetTraceID(o.span.SpanContext().TraceID().String())
+	}
 	o.statManager.ProcessTimeEnd()
 	o.statManager.IncTotalMessagesProcessed(1)
 	if o.span != nil {
@@ -24,6 +24,7 @@ import (
 	"github.com/lf-edge/ekuiper/v2/internal/converter"
 	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
 	kctx "github.com/lf-edge/ekuiper/v2/internal/topo/context"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/node/tracenode"
 	"github.com/lf-edge/ekuiper/v2/internal/xsql"
 	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
 	"github.com/lf-edge/ekuiper/v2/pkg/infra"
@@ -105,6 +106,9 @@ func (s *SinkNode) Exec(ctx api.StreamContext, errCh chan<- error) {
 						break
 					}
 					s.onProcessStart(ctx, data)
+					if s.span != nil {
+						tracenode.RecordPayloadSample(ctx, data, s.span)
+					}
 					err = s.doCollect(ctx, s.sink, data)
 					if err != nil { // resend handling when enabling cache. Two cases: 1. send to alter queue with resendOUt. 2. retry (blocking) until success or unrecoverable error if resendInterval is set
 						s.onError(ctx, err)
@@ -32,6 +32,7 @@ import (
 	"github.com/lf-edge/ekuiper/v2/pkg/infra"
 	"github.com/lf-edge/ekuiper/v2/pkg/model"
 	"github.com/lf-edge/ekuiper/v2/pkg/timex"
+	"github.com/lf-edge/ekuiper/v2/pkg/tracer"
 )
 
 // SourceNode is a node that connects to an external source
@@ -98,7 +99,7 @@ func (m *SourceNode) ingestBytes(ctx api.StreamContext, data []byte, meta map[st
 }
 
 func (m *SourceNode) traceStart(ctx api.StreamContext, meta map[string]any, tuple xsql.HasTracerCtx) {
-	if !ctx.IsTraceEnabled() {
+	if !ctx.IsTraceEnabled() || !tracer.IsTracingEnabled() {
 		return
 	}
 	var (
@@ -120,18 +121,20 @@ func (m *SourceNode) traceStart(ctx api.StreamContext, meta map[string]any, tupl
 		opts = append(opts, trace.WithAttributes(attribute.String("span.mytype", "data-processing")))
 	}
 	// If read from parent trace
-	if tid, ok := meta["traceId"]; ok {
-		traced, traceCtx, span = tracenode.StartTraceByID(ctx, tid.(string), opts...)
+	if tid, ok := meta[tracer.MetaTraceParent]; ok {
+		ts, _ := meta[tracer.MetaTraceState].(string)
+		traced, traceCtx, span = tracenode.StartTraceByID(ctx, tid.(string), ts, opts...)
 	} else {
 		strategy := tracenode.ExtractStrategy(ctx)
 		if strategy != topoContext.AlwaysTraceStrategy {
 			return
 		}
 		traced, traceCtx, span = tracenode.StartTraceBackground(ctx, ctx.GetOpId(), opts...)
-		meta["traceId"] = span.SpanContext().TraceID()
+		meta[tracer.MetaTraceParent] = span.SpanContext().TraceID()
 	}
 	if traced {
 		tracenode.RecordRowOrCollection(tuple, span)
+		tracenode.RecordPayloadSample(ctx, tuple, span)
 		m.span = span
 		m.spanCtx = traceCtx
 	}
@@ -200,6 +203,7 @@ func (m *SourceNode) ingestTuple(t *xsql.Tuple, ts time.Time) {
 	traced, spanCtx, span := tracenode.TraceInput(m.ctx, tuple, m.name)
 	if traced {
 		tracenode.RecordRowOrCollection(tuple, span)
+		tracenode.RecordPayloadSample(m.ctx, tuple, span)
 		m.span = span
 		m.spanCtx = spanCtx
 	}
@@ -0,0 +1,65 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracenode
+
+import (
+	"strings"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+)
+
+// DefaultSpanNameKey is the key of the span name template applied to all operators without a specific template
+const DefaultSpanNameKey = "default"
+
+// spanName resolves the span name of the operator by the configured templates. The template is looked up by
+// the operator type such as project, then by the default key. Without any template, the op name is used.
+// Supported placeholders are {op}, {type}, {rule} and {topic}.
+func spanName(ctx api.StreamContext, opName string, data any) string {
+	if conf.Config == nil || len(conf.Config.OpenTelemetry.SpanNameTemplates) == 0 {
+		return opName
+	}
+	templates := conf.Config.OpenTelemetry.SpanNameTemplates
+	opType := operatorType(opName)
+	tpl, ok := templates[opType]
+	if !ok {
+		tpl, ok = templates[DefaultSpanNameKey]
+		if !ok {
+			return opName
+		}
+	}
+	var topic string
+	if m, ok := data.(api.MetaInfo); ok {
+		if t, ok := m.AllMeta()["topic"].(string); ok {
+			topic = t
+		}
+	}
+	return strings.NewReplacer("{op}", opName, "{type}", opType, "{rule}", ctx.GetRuleId(), "{topic}", topic).Replace(tpl)
+}
+
+// operatorType strips the index prefix of the op name like 2_project
+func operatorType(opName string) string {
+	i := strings.IndexByte(opName, '_')
+	if i <= 0 {
+		return opName
+	}
+	for _, c := range opName[:i] {
+		if c < '0' || c > '9' {
+			return opName
+		}
+	}
+	return opName[i+1:]
+}
@@ -52,8 +52,28 @@ func RecordRowOrCollection(input interface{}, span trace.Span) {
 	}
 }
 
+// RecordPayloadSample records the truncated payload sample on the source or sink span if the rule trace setting
+// opts in. The raw tuple is sampled as is, other inputs as json.
+func RecordPayloadSample(ctx api.StreamContext, input any, span trace.Span) {
+	attrs := tracer.SamplePayload(ctx.GetRuleId(), ctx.GetOpId(), func() string {
+		switch d := input.(type) {
+		case xsql.Row:
+			return ToStringRow(d)
+		case api.MessageTupleList:
+			return ToStringCollection(d)
+		case *xsql.RawTuple:
+			return string(d.Raw())
+		default:
+			return fmt.Sprintf("%v", d)
+		}
+	})
+	if len(attrs) > 0 {
+		span.SetAttributes(attrs...)
+	}
+}
+
 func TraceInput(ctx api.StreamContext, d any, opName string, opts ...trace.SpanStartOption) (bool, api.StreamContext, trace.Span) {
-	if !ctx.IsTraceEnabled() {
+	if !ctx.IsTraceEnabled() || !tracer.IsTracingEnabled() {
 		return false, nil, nil
 	}
 	input, ok := d.(xsql.HasTracerCtx)
@@ -63,42 +83,55 @@ func TraceInput(ctx api.StreamContext, d any, opName string, opts ...trace.SpanS
 	if !checkCtxByStrategy(ctx, input.GetTracerCtx()) {
 		return false, nil, nil
 	}
-	spanCtx, span := tracer.GetTracer().Start(input.GetTracerCtx(), opName, opts...)
-	span.SetAttributes(attribute.String(RuleKey, ctx.GetRuleId()))
-	x := topoContext.WithContext(spanCtx)
+	spanCtx, span := tracer.GetTracer().Start(input.GetTracerCtx(), spanName(ctx, opName, d), withRule(ctx, opts)...)
+	x := withTraceLogFields(topoContext.WithContext(spanCtx), ctx.GetRuleId(), span)
 	input.SetTracerCtx(x)
 	return true, x, span
 }
 
 func StartTraceBackground(ctx api.StreamContext, opName string, opts ...trace.SpanStartOption) (bool, api.StreamContext, trace.Span) {
-	if !ctx.IsTraceEnabled() {
+	if !ctx.IsTraceEnabled() || !tracer.IsTracingEnabled() {
 		return false, nil, nil
 	}
 	if !checkCtxByStrategy(ctx, ctx) {
 		return false, nil, nil
 	}
-	spanCtx, span := tracer.GetTracer().Start(context.Background(), opName, opts...)
+	spanCtx, span := tracer.GetTracer().Start(context.Background(), spanName(ctx, opName, nil), withRule(ctx, opts)...)
 	ruleID := ctx.GetRuleId()
-	span.SetAttributes(attribute.String(RuleKey, ruleID))
-	ingestCtx := topoContext.WithContext(spanCtx)
+	ingestCtx := withTraceLogFields(topoContext.WithContext(spanCtx), ruleID, span)
 	return true, ingestCtx, span
 }
 
-func StartTraceByID(ctx api.StreamContext, parentId string, opts ...trace.SpanStartOption) (bool, api.StreamContext, trace.Span) {
-	if !ctx.IsTraceEnabled() {
+// StartTraceByID starts the span as the child of the upstream span of the w3c traceparent and the optional tracestate
+func StartTraceByID(ctx api.StreamContext, parentId string, traceState string, opts ...trace.SpanStartOption) (bool, api.StreamContext, trace.Span) {
+	if !ctx.IsTraceEnabled() || !tracer.IsTracingEnabled() {
 		return false, nil, nil
 	}
-	carrier := map[string]string{
-		"traceparent": parentId,
+	carrier := propagation.MapCarrier{
+		tracer.TraceParentKey: parentId,
 	}
-	propagator := propagation.TraceContext{}
-	traceCtx := propagator.Extract(context.Background(), propagation.MapCarrier(carrier))
-	spanCtx, span := tracer.GetTracer().Start(traceCtx, ctx.GetOpId(), opts...)
-	span.SetAttributes(attribute.String(RuleKey, ctx.GetRuleId()))
-	ingestCtx := topoContext.WithContext(spanCtx)
+	if traceState != "" {
+		carrier[tracer.TraceStateKey] = traceState
+	}
+	spanCtx, span := tracer.StartRemoteSpan(context.Background(), carrier, spanName(ctx, ctx.GetOpId(), nil), withRule(ctx, opts)...)
+	ingestCtx := withTraceLogFields(topoContext.WithContext(spanCtx), ctx.GetRuleId(), span)
 	return true, ingestCtx, span
 }
 
+// withRule sets the rule and op attributes when starting the span so that the sampler can decide by the rule and the
+// connection events can be correlated to the span of the op
+func withRule(ctx api.StreamContext, opts []trace.SpanStartOption) []trace.SpanStartOption {
+	r := make([]trace.SpanStartOption, 0, len(opts)+1)
+	r = append(r, opts...)
+	return append(r, trace.WithAttributes(attribute.String(RuleKey, ctx.GetRuleId()), attribute.String(tracer.OpKey, ctx.GetOpId())))
+}
+
+// withTraceLogFields adds the rule, trace and span id to the logger of the trace context for log correlation
+func withTraceLogFields(ctx *topoContext.DefaultContext, ruleID string, span trace.Span) *topoContext.DefaultContext {
+	sc := span.SpanContext()
+	return ctx.WithFields(conf.LogFieldRule, ruleID, conf.LogFieldTraceID, sc.TraceID().String(), conf.LogFieldSpanID, sc.SpanID().String())
+}
+
 func ToStringRow(r xsql.Row) string {
 	d := r.Clone().ToMap()
 	b, _ := json.Marshal(d)
@@ -121,7 +154,17 @@ func ToStringCollection(r api.MessageTupleList) string {
 }
 
 func BuildTraceParentId(traceID [16]byte, spanID [8]byte) string {
-	return fmt.Sprintf("00-%s-%s-01", hex.EncodeToString(traceID[:]), hex.EncodeToString(spanID[:]))
+	return buildTraceParent(traceID, spanID, trace.FlagsSampled)
+}
+
+// BuildTraceParent builds the w3c traceparent of the span context. The sampled flag follows the local sampling
+// decision so that the downstream systems won't record the unsampled traces.
+func BuildTraceParent(sc trace.SpanContext) string {
+	return buildTraceParent(sc.TraceID(), sc.SpanID(), sc.TraceFlags())
+}
+
+func buildTraceParent(traceID [16]byte, spanID [8]byte, flags trace.TraceFlags) string {
+	return fmt.Sprintf("00-%s-%s-%s", hex.EncodeToString(traceID[:]), hex.EncodeToString(spanID[:]), flags.String())
 }
 
 func checkCtxByStrategy(ctx, tracerCtx api.StreamContext) bool {
@@ -0,0 +1,63 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracenode
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/xsql"
+	mockContext "github.com/lf-edge/ekuiper/v2/pkg/mock/context"
+)
+
+func TestBuildTraceParent(t *testing.T) {
+	traceID, err := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	require.NoError(t, err)
+	spanID, err := trace.SpanIDFromHex("00f067aa0ba902b7")
+	require.NoError(t, err)
+	sampled := trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID, TraceFlags: trace.FlagsSampled})
+	require.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", BuildTraceParent(sampled))
+	unsampled := trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID})
+	require.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", BuildTraceParent(unsampled))
+	require.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", BuildTraceParentId(traceID, spanID))
+}
+
+func TestSpanName(t *testing.T) {
+	conf.InitConf()
+	defer func() {
+		conf.Config.OpenTelemetry.SpanNameTemplates = nil
+	}()
+	ctx := mockContext.NewMockContext("rule1", "op1")
+	tuple := &xsql.Tuple{Metadata: map[string]any{"topic": "devices/1"}}
+	require.Equal(t, "2_project", spanName(ctx, "2_project", tuple))
+	conf.Config.OpenTelemetry.SpanNameTemplates = map[string]string{
+		"project": "{type}:{rule}:{topic}",
+	}
+	require.Equal(t, "project:rule1:devices/1", spanName(ctx, "2_project", tuple))
+	require.Equal(t, "2_filter", spanName(ctx, "2_filter", tuple))
+	conf.Config.OpenTelemetry.SpanNameTemplates[DefaultSpanNameKey] = "{op}@{rule}"
+	require.Equal(t, "2_filter@rule1", spanName(ctx, "2_filter", nil))
+	require.Equal(t, "mqtt_0_emit@rule1", spanName(ctx, "mqtt_0_emit", nil))
+}
+
+func TestOperatorType(t *testing.T) {
+	require.Equal(t, "project", operatorType("2_project"))
+	require.Equal(t, "agg_func", operatorType("10_agg_func"))
+	require.Equal(t, "mqtt_0_emit", operatorType("mqtt_0_emit"))
+	require.Equal(t, "window_op", operatorType("window_op"))
+}
@@ -28,6 +28,7 @@ import (
 	"github.com/lf-edge/ekuiper/v2/internal/topo/node/conf"
 	"github.com/lf-edge/ekuiper/v2/pkg/ast"
 	"github.com/lf-edge/ekuiper/v2/pkg/model"
+	"github.com/lf-edge/ekuiper/v2/pkg/modules"
 )
 
 // SinkPlanner is the planner for sink node. It transforms logical sink plan to multiple physical nodes.
@@ -44,6 +45,9 @@ func buildActions(tp *topo.Topo, rule *def.Rule, inputs []node.Emitter, streamCo
 			if err != nil {
 				return err
 			}
+			if err := validateConnection(tp.GetContext(), props, modules.CapPublish); err != nil {
+				return err
+			}
 			sinkName := fmt.Sprintf("%s_%d", name, i)
 			cn, err := SinkToComp(tp, name, sinkName, copyProps(props), rule, streamCount, schema)
 			if err != nil {
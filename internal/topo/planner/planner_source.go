@@ -30,7 +30,10 @@ import (
 	"github.com/lf-edge/ekuiper/v2/internal/topo/schema"
 	"github.com/lf-edge/ekuiper/v2/pkg/ast"
 	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+	"github.com/lf-edge/ekuiper/v2/pkg/connection"
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
 	"github.com/lf-edge/ekuiper/v2/pkg/model"
+	"github.com/lf-edge/ekuiper/v2/pkg/modules"
 )
 
 func transformSourceNode(ctx api.StreamContext, t *DataSourcePlan, mockSourcesProp map[string]map[string]any, ruleId string, options *def.RuleOption, index int) (node.DataSourceNode, []node.OperatorNode, int, error) {
@@ -81,6 +84,9 @@ func splitSource(ctx api.StreamContext, t *DataSourcePlan, ss api.Source, option
 		}
 	}
 	_ = cast.MapToStruct(props, sp)
+	if err := validateConnection(ctx, props, modules.CapSubscribe); err != nil {
+		return nil, nil, 0, err
+	}
 	// Create the connector node as source node
 	var (
 		err         error
@@ -348,6 +354,9 @@ func planLookupSource(ctx api.StreamContext, t *LookupPlan, ruleOption *def.Rule
 		return nil, fmt.Errorf("lookup source type %s not found", t.options.TYPE)
 	}
 	props := nodeConf.GetSourceConf(t.options.TYPE, t.options)
+	if err := validateConnection(ctx, props, modules.CapQuery); err != nil {
+		return nil, err
+	}
 	switch si.(type) {
 	case api.LookupSource:
 		return node.NewLookupNode(ctx, t.joinExpr.Name, false, t.fields, t.keys, t.joinExpr.JoinType, t.valvars, t.options, ruleOption, props)
@@ -359,3 +368,17 @@ func planLookupSource(ctx api.StreamContext, t *LookupPlan, ruleOption *def.Rule
 	}
 	return nil, fmt.Errorf("lookup source type %s is found but not a valid lookup source", t.options.TYPE)
 }
+
+// validateConnection checks the selected named connection supports the usage in the rule.
+// The missing connection is not validated here, it will fail when connecting.
+func validateConnection(ctx api.StreamContext, props map[string]any, c modules.Capability) error {
+	selId, ok := props[nodeConf.ConnectionSelector].(string)
+	if !ok || selId == "" {
+		return nil
+	}
+	err := connection.CheckConnectionCapability(ctx, selId, c)
+	if code, ok := errorx.GetErrorCode(err); ok && code == errorx.NOT_FOUND {
+		return nil
+	}
+	return err
+}
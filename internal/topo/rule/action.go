@@ -6,10 +6,13 @@ import (
 
 	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
 	"github.com/lf-edge/ekuiper/v2/internal/topo"
+	kctx "github.com/lf-edge/ekuiper/v2/internal/topo/context"
 	"github.com/lf-edge/ekuiper/v2/internal/topo/planner"
 	"github.com/lf-edge/ekuiper/v2/internal/topo/rule/machine"
+	"github.com/lf-edge/ekuiper/v2/pkg/connection"
 	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
 	"github.com/lf-edge/ekuiper/v2/pkg/infra"
+	"github.com/lf-edge/ekuiper/v2/pkg/tracer"
 )
 
 const EOFMessage = "done"
@@ -88,6 +91,10 @@ func (s *State) doStart() error {
 				s.topoGraph = s.topology.GetTopo()
 			}
 		}
+		// apply the trace setting of the rule persisted at runtime
+		if c, err := tracer.GetRuleTrace(s.Rule.Id); err == nil && c != nil {
+			s.topology.EnableTracer(c.Enabled, kctx.StringToStrategy(c.Strategy))
+		}
 		go s.runTopo(s.topology, s.Rule.Id)
 		return nil
 	})
@@ -111,6 +118,7 @@ func (s *State) doStop(stateType machine.RunState, msg string) {
 			s.logger.Errorf("graceful stop error, just cancel forcely: %v", err)
 		}
 		s.topology = nil
+		connection.CheckRuleReferences(s.Rule.Id)
 	}
 	s.transitState(stateType, msg)
 }
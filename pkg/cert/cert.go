@@ -19,14 +19,13 @@ import (
 	"crypto/x509"
 	"encoding/base64"
 	"fmt"
-	"os"
 
 	"github.com/lf-edge/ekuiper/contract/v2/api"
 
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
 	"github.com/lf-edge/ekuiper/v2/modules/encryptor"
 	"github.com/lf-edge/ekuiper/v2/pkg/cast"
 	"github.com/lf-edge/ekuiper/v2/pkg/model"
-	"github.com/lf-edge/ekuiper/v2/pkg/path"
 )
 
 func GenTLSConfig(ctx api.StreamContext, props map[string]interface{}) (*tls.Config, error) {
@@ -55,14 +54,14 @@ func genTlsConfigurationOptions(props map[string]interface{}) (*model.TlsConfigu
 		return nil, nil, err
 	}
 	if opts.Tls != "default" && !opts.SkipCertVerify && (len(opts.CertFile) < 1 && len(opts.KeyFile) < 1 && len(opts.CaFile) < 1) &&
-		(len(opts.CertificationRaw) < 1 && len(opts.PrivateKeyRaw) < 1 && len(opts.RootCARaw) < 1) {
+		(len(opts.CertificationRaw) < 1 && len(opts.PrivateKeyRaw) < 1 && len(opts.RootCARaw) < 1) && len(opts.SpiffeID) < 1 {
 		return nil, nil, nil
 	}
 	keys, err := opts.GenKeys()
 	return opts, keys, err
 }
 
-func getTLSMinVersion(ctx api.StreamContext, userInput string) uint16 {
+func getTLSMinVersion(logger api.Logger, userInput string) uint16 {
 	switch userInput {
 	case "tls1.0":
 		return tls.VersionTLS10
@@ -75,12 +74,12 @@ func getTLSMinVersion(ctx api.StreamContext, userInput string) uint16 {
 	case "":
 		return tls.VersionTLS12
 	default:
-		ctx.GetLogger().Warnf("Unrecognized or unsupported TLS version: %s, defaulting to TLS 1.2", userInput)
+		logger.Warnf("Unrecognized or unsupported TLS version: %s, defaulting to TLS 1.2", userInput)
 		return tls.VersionTLS12
 	}
 }
 
-func getRenegotiationSupport(ctx api.StreamContext, userInput string) tls.RenegotiationSupport {
+func getRenegotiationSupport(logger api.Logger, userInput string) tls.RenegotiationSupport {
 	switch userInput {
 	case "never":
 		return tls.RenegotiateNever
@@ -91,7 +90,7 @@ func getRenegotiationSupport(ctx api.StreamContext, userInput string) tls.Renego
 	case "":
 		return tls.RenegotiateNever
 	default:
-		ctx.GetLogger().Warnf("Invalid renegotiation option: %s, defaulting to \"never\"", userInput)
+		logger.Warnf("Invalid renegotiation option: %s, defaulting to \"never\"", userInput)
 		return tls.RenegotiateNever
 	}
 }
@@ -107,101 +106,100 @@ func GenerateTLSForClient(ctx api.StreamContext, Opts *model.TlsConfigurationOpt
 	if Opts == nil {
 		return nil, nil
 	}
+	return generateTLS(ctx.GetLogger(), ctx.GetRootPath(), Opts, keys)
+}
+
+// GenTLSConfigForService generates the client TLS config for the services running without a rule context like the
+// OTLP exporter. The relative paths are relative to the eKuiper root.
+func GenTLSConfigForService(opts *model.TlsConfigurationOptions) (*tls.Config, error) {
+	if opts == nil {
+		return nil, nil
+	}
+	keys, err := opts.GenKeys()
+	if err != nil {
+		return nil, err
+	}
+	root, _ := conf.GetLoc("")
+	return generateTLS(conf.Log, root, opts, keys)
+}
+
+// generateTLS builds the TLS config. The materials in the files are loaded by the cert manager so that they are
+// rotated without rebuilding the config.
+func generateTLS(logger api.Logger, root string, opts *model.TlsConfigurationOptions, keys *model.TlsKeys) (*tls.Config, error) {
 	tlsConfig := &tls.Config{
-		InsecureSkipVerify: Opts.SkipCertVerify,
-		Renegotiation:      getRenegotiationSupport(ctx, Opts.RenegotiationSupport),
-		MinVersion:         getTLSMinVersion(ctx, Opts.TLSMinVersion),
+		InsecureSkipVerify: opts.SkipCertVerify,
+		Renegotiation:      getRenegotiationSupport(logger, opts.RenegotiationSupport),
+		MinVersion:         getTLSMinVersion(logger, opts.TLSMinVersion),
+	}
+	var (
+		e   *certEntry
+		err error
+	)
+	if len(opts.CertFile) > 0 || len(opts.KeyFile) > 0 || len(opts.CaFile) > 0 {
+		e, err = certManager.load(logger, root, opts)
+		if err != nil {
+			return nil, err
+		}
 	}
-	if !isCertDefined(Opts) {
+	if !isCertDefined(opts) {
 		tlsConfig.Certificates = nil
+	} else if e != nil && e.hasCert() {
+		e.applyCert(tlsConfig)
 	} else {
-		if cert, err := buildCert(ctx, Opts, keys); err != nil {
+		if cert, err := buildCert(opts.Decrypt, keys.RawCertBytes, keys.RawKeyBytes); err != nil {
 			return nil, err
 		} else {
 			tlsConfig.Certificates = []tls.Certificate{cert}
 		}
 	}
 
-	if err := buildCA(ctx, Opts, tlsConfig, keys); err != nil {
+	roots := func() *x509.CertPool { return nil }
+	if e != nil && e.hasCA() {
+		roots = e.roots.Load
+	} else if len(opts.RootCARaw) > 0 {
+		pool := x509.NewCertPool()
+		pool.AppendCertsFromPEM(keys.RawCABytes)
+		roots = func() *x509.CertPool { return pool }
+	}
+	tlsConfig.RootCAs = roots()
+	if opts.SpiffeID != "" && !opts.SkipCertVerify {
+		if err := validateSpiffeID(opts.SpiffeID); err != nil {
+			return nil, err
+		}
+		// SVIDs have no DNS names, so the peer is authorized by the SPIFFE ID instead of the host name
+		tlsConfig.InsecureSkipVerify = true
+		tlsConfig.VerifyPeerCertificate = verifySpiffe(roots, opts.SpiffeID)
+	}
+	if err := applyCompliance(tlsConfig, opts); err != nil {
 		return nil, err
 	}
-
 	return tlsConfig, nil
 }
 
-func buildCert(ctx api.StreamContext, opts *model.TlsConfigurationOptions, keys *model.TlsKeys) (tls.Certificate, error) {
-	var (
-		cpb, kpb []byte
-		err      error
-	)
-	if len(opts.CertFile) > 0 || len(opts.KeyFile) > 0 {
-		cpb, kpb, err = certLoader(ctx, opts.CertFile, opts.KeyFile)
-	} else {
-		cpb, kpb = keys.RawCertBytes, keys.RawKeyBytes
-	}
-	if opts.Decrypt != nil {
-		var key []byte
-		if opts.Decrypt.Key != "" {
-			key, err = base64.StdEncoding.DecodeString(opts.Decrypt.Key)
+func buildCert(decrypt *model.EncryptionConf, cpb, kpb []byte) (tls.Certificate, error) {
+	if decrypt != nil {
+		var (
+			key []byte
+			err error
+		)
+		if decrypt.Key != "" {
+			key, err = base64.StdEncoding.DecodeString(decrypt.Key)
 			if err != nil {
 				return tls.Certificate{}, err
 			}
 		}
-		decryptor, e := encryptor.GetDecryptorWithKey(opts.Decrypt.Algorithm, key, opts.Decrypt.Properties)
-		if e != nil {
-			return tls.Certificate{}, e
-		}
-		cpb, e = decryptor.Decrypt(cpb)
+		decryptor, err := encryptor.GetDecryptorWithKey(decrypt.Algorithm, key, decrypt.Properties)
 		if err != nil {
-			return tls.Certificate{}, e
+			return tls.Certificate{}, err
 		}
-		kpb, e = decryptor.Decrypt(kpb)
-		if e != nil {
-			return tls.Certificate{}, e
+		cpb, err = decryptor.Decrypt(cpb)
+		if err != nil {
+			return tls.Certificate{}, err
 		}
-	}
-	return tls.X509KeyPair(cpb, kpb)
-}
-
-func certLoader(ctx api.StreamContext, certFilePath, keyFilePath string) ([]byte, []byte, error) {
-	cp := path.AbsPath(ctx, certFilePath)
-	kp := path.AbsPath(ctx, keyFilePath)
-	cpb, err := os.ReadFile(cp)
-	if err != nil {
-		return nil, nil, err
-	}
-	kpb, err := os.ReadFile(kp)
-	if err != nil {
-		return nil, nil, err
-	}
-	return cpb, kpb, nil
-}
-
-func buildCA(ctx api.StreamContext, opts *model.TlsConfigurationOptions, tlsConfig *tls.Config, keys *model.TlsKeys) error {
-	if len(opts.CaFile) > 0 {
-		root, err := caLoader(ctx, opts.CaFile)
+		kpb, err = decryptor.Decrypt(kpb)
 		if err != nil {
-			return err
+			return tls.Certificate{}, err
 		}
-		tlsConfig.RootCAs = root
-		return nil
 	}
-	if len(opts.RootCARaw) > 0 {
-		pool := x509.NewCertPool()
-		pool.AppendCertsFromPEM(keys.RawCABytes)
-		tlsConfig.RootCAs = pool
-		return nil
-	}
-	return nil
-}
-
-func caLoader(ctx api.StreamContext, caFilePath string) (*x509.CertPool, error) {
-	cp := path.AbsPath(ctx, caFilePath)
-	pool := x509.NewCertPool()
-	caCrt, err1 := os.ReadFile(cp)
-	if err1 != nil {
-		return nil, err1
-	}
-	pool.AppendCertsFromPEM(caCrt)
-	return pool, err1
+	return tls.X509KeyPair(cpb, kpb)
 }
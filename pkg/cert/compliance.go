@@ -0,0 +1,141 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cert
+
+import (
+	"crypto/fips140"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/pkg/model"
+)
+
+// The approved cipher suites of TLS 1.2 in compliance mode. TLS 1.3 suites are not configurable and are restricted by
+// the FIPS 140-3 module of Go when it is enabled.
+var complianceCipherSuites = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+}
+
+var complianceCurves = []tls.CurveID{tls.CurveP256, tls.CurveP384, tls.CurveP521}
+
+// The schemes which carry TLS
+var secureSchemes = map[string]bool{
+	"https": true, "wss": true, "ssl": true, "tls": true, "mqtts": true, "tcps": true,
+}
+
+// ComplianceEnabled returns whether the compliance mode restricting the cryptography is on.
+func ComplianceEnabled() bool {
+	return conf.Config != nil && conf.Config.Compliance.Enable
+}
+
+// FIPSEnabled returns whether the crypto is running in FIPS 140-3 mode, which is enabled by GODEBUG=fips140=on.
+func FIPSEnabled() bool {
+	return fips140.Enabled()
+}
+
+// applyCompliance restricts the TLS versions, cipher suites and curves of the client config in compliance mode.
+func applyCompliance(tc *tls.Config, opts *model.TlsConfigurationOptions) error {
+	if !ComplianceEnabled() {
+		return nil
+	}
+	if err := checkTlsOptions(opts); err != nil {
+		return err
+	}
+	hardenTLS(tc)
+	return nil
+}
+
+func checkTlsOptions(opts *model.TlsConfigurationOptions) error {
+	if opts == nil {
+		return nil
+	}
+	if opts.SkipCertVerify {
+		return errors.New("compliance mode: insecureSkipVerify is not allowed")
+	}
+	switch opts.TLSMinVersion {
+	case "tls1.0", "tls1.1":
+		return fmt.Errorf("compliance mode: tlsMinVersion %s is not allowed, use tls1.2 or above", opts.TLSMinVersion)
+	}
+	return nil
+}
+
+func hardenTLS(tc *tls.Config) {
+	if tc.MinVersion < tls.VersionTLS12 {
+		tc.MinVersion = tls.VersionTLS12
+	}
+	tc.CipherSuites = complianceCipherSuites
+	tc.CurvePreferences = complianceCurves
+	tc.Renegotiation = tls.RenegotiateNever
+}
+
+// HardenServerTLS restricts the TLS config of the servers like the REST API in compliance mode. The config is
+// created if nil.
+func HardenServerTLS(tc *tls.Config) *tls.Config {
+	if !ComplianceEnabled() {
+		return tc
+	}
+	if tc == nil {
+		tc = &tls.Config{}
+	}
+	hardenTLS(tc)
+	return tc
+}
+
+// CheckEndpoint rejects the endpoint url in compliance mode if its scheme is not secure like http:// or tcp://,
+// unless it is allow-listed by compliance.allowPlaintext.
+func CheckEndpoint(endpoint string) error {
+	if !ComplianceEnabled() {
+		return nil
+	}
+	if u, err := url.Parse(endpoint); err == nil && secureSchemes[strings.ToLower(u.Scheme)] {
+		return nil
+	}
+	return CheckPlaintext(endpoint)
+}
+
+// CheckPlaintext rejects the endpoint which is connected without TLS in compliance mode unless it is allow-listed.
+// The endpoint can be a url or host:port.
+func CheckPlaintext(endpoint string) error {
+	if !ComplianceEnabled() || plaintextAllowed(endpoint, conf.Config.Compliance.AllowPlaintext) {
+		return nil
+	}
+	return fmt.Errorf("compliance mode: plaintext endpoint %s is not allowed, configure TLS or add it to compliance.allowPlaintext", endpoint)
+}
+
+// plaintextAllowed matches the host or host:port of the endpoint against the allow list.
+func plaintextAllowed(endpoint string, allowed []string) bool {
+	hostport := endpoint
+	if u, err := url.Parse(endpoint); err == nil && u.Host != "" {
+		hostport = u.Host
+	}
+	host := hostport
+	if h, _, err := net.SplitHostPort(hostport); err == nil {
+		host = h
+	}
+	for _, a := range allowed {
+		if strings.EqualFold(a, hostport) || strings.EqualFold(a, host) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cert
+
+import (
+	"crypto/tls"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	mockContext "github.com/lf-edge/ekuiper/v2/pkg/mock/context"
+	"github.com/lf-edge/ekuiper/v2/pkg/model"
+)
+
+func TestCompliance(t *testing.T) {
+	conf.InitConf()
+	conf.Config.Compliance.Enable = true
+	conf.Config.Compliance.AllowPlaintext = []string{"localhost", "10.0.0.1:1883"}
+	defer func() {
+		conf.Config.Compliance.Enable = false
+		conf.Config.Compliance.AllowPlaintext = nil
+	}()
+	ctx := mockContext.NewMockContext("testCompliance", "op")
+
+	tc, err := generateTLS(conf.Log, "", &model.TlsConfigurationOptions{TLSMinVersion: "tls1.3", RenegotiationSupport: "freely"}, nil)
+	require.NoError(t, err)
+	require.Equal(t, uint16(tls.VersionTLS13), tc.MinVersion)
+	require.Equal(t, tls.RenegotiateNever, tc.Renegotiation)
+	require.Equal(t, complianceCipherSuites, tc.CipherSuites)
+	_, err = generateTLS(conf.Log, "", &model.TlsConfigurationOptions{TLSMinVersion: "tls1.1"}, nil)
+	require.EqualError(t, err, "compliance mode: tlsMinVersion tls1.1 is not allowed, use tls1.2 or above")
+	_, err = GenTLSConfig(ctx, map[string]any{"insecureSkipVerify": true})
+	require.EqualError(t, err, "compliance mode: insecureSkipVerify is not allowed")
+
+	tests := []struct {
+		endpoint string
+		ok       bool
+	}{
+		{"https://example.com/api", true},
+		{"wss://example.com:443", true},
+		{"ssl://broker:8883", true},
+		{"http://localhost:9081", true},
+		{"tcp://10.0.0.1:1883", true},
+		{"tcp://10.0.0.1:1884", false},
+		{"http://example.com", false},
+		{"ws://example.com:80", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.endpoint, func(t *testing.T) {
+			err := CheckEndpoint(tt.endpoint)
+			if tt.ok {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+			}
+		})
+	}
+	require.NoError(t, CheckPlaintext("localhost:9092"))
+	require.Error(t, CheckPlaintext("kafka:9092"))
+
+	stc := HardenServerTLS(nil)
+	require.Equal(t, uint16(tls.VersionTLS12), stc.MinVersion)
+	require.Equal(t, complianceCurves, stc.CurvePreferences)
+
+	conf.Config.Compliance.Enable = false
+	require.NoError(t, CheckEndpoint("http://example.com"))
+	require.Nil(t, HardenServerTLS(nil))
+}
@@ -24,20 +24,20 @@ import (
 )
 
 // read only
-var conf *model.TlsConfigurationOptions
+var defaultConf *model.TlsConfigurationOptions
 
 // InitConf run in server start up
 func InitConf(tc *model.TlsConfigurationOptions) {
-	conf = tc
+	defaultConf = tc
 }
 
 func GetDefaultTlsConf(ctx api.StreamContext) (*tls.Config, error) {
-	if conf == nil {
+	if defaultConf == nil {
 		return nil, errors.New("default TLS is not configured")
 	}
-	keys, err := conf.GenKeys()
+	keys, err := defaultConf.GenKeys()
 	if err != nil {
 		return nil, err
 	}
-	return GenerateTLSForClient(ctx, conf, keys)
+	return GenerateTLSForClient(ctx, defaultConf, keys)
 }
@@ -0,0 +1,383 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cert
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/pkg/model"
+	"github.com/lf-edge/ekuiper/v2/pkg/syncx"
+)
+
+// DefaultExpiryWarning is how long before the certificates expire to warn by default
+const DefaultExpiryWarning = 7 * 24 * time.Hour
+
+var CertExpiryGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: "kuiper",
+	Subsystem: "cert",
+	Name:      "expiry_seconds",
+	Help:      "seconds until the certificate in the file expires",
+}, []string{"file"})
+
+var (
+	certManager         = &manager{entries: make(map[string]*certEntry)}
+	expiryWarning       atomic.Int64
+	expiryCheckInterval = time.Hour
+)
+
+func init() {
+	prometheus.MustRegister(CertExpiryGauge)
+	expiryWarning.Store(int64(DefaultExpiryWarning))
+}
+
+// SetExpiryWarning sets how long before the certificates expire to warn. 0 means the default.
+func SetExpiryWarning(d time.Duration) {
+	if d <= 0 {
+		d = DefaultExpiryWarning
+	}
+	expiryWarning.Store(int64(d))
+}
+
+// manager caches the TLS materials loaded from the files and reloads them when the files change. The entries are
+// shared by all the connections using the same files.
+type manager struct {
+	syncx.Mutex
+	entries map[string]*certEntry
+	watcher *fsnotify.Watcher
+	dirs    map[string]struct{}
+	// listeners are notified with the reloaded files
+	listeners []func(files []string)
+}
+
+// OnReload registers the listener notified with the files reloaded by the watcher, so that the long-lived
+// connections can handshake again with the new materials.
+func OnReload(fn func(files []string)) {
+	certManager.Lock()
+	defer certManager.Unlock()
+	certManager.listeners = append(certManager.listeners, fn)
+}
+
+// ReloadFiles reloads the TLS materials of the files referenced in the props right away without waiting for the
+// watcher. The relative paths are relative to the root. It returns the absolute paths of the files.
+func ReloadFiles(root string, props map[string]any) ([]string, error) {
+	files, err := Files(root, props)
+	if err != nil || len(files) == 0 {
+		return files, err
+	}
+	certManager.Lock()
+	defer certManager.Unlock()
+	for _, e := range certManager.entries {
+		if !e.uses(files) {
+			continue
+		}
+		if err := e.reload(); err != nil {
+			return files, err
+		}
+		e.checkExpiry(conf.Log, time.Now())
+	}
+	return files, nil
+}
+
+// Files returns the absolute paths of the cert, key and CA files referenced in the props
+func Files(root string, props map[string]any) ([]string, error) {
+	opts, _, err := genTlsConfigurationOptions(props)
+	if err != nil || opts == nil {
+		return nil, err
+	}
+	var result []string
+	for _, f := range []string{opts.CertFile, opts.KeyFile, opts.CaFile} {
+		if f != "" {
+			result = append(result, absPath(root, f))
+		}
+	}
+	return result, nil
+}
+
+func (m *manager) load(logger api.Logger, root string, opts *model.TlsConfigurationOptions) (*certEntry, error) {
+	e := &certEntry{decrypt: opts.Decrypt}
+	if len(opts.CertFile) > 0 || len(opts.KeyFile) > 0 {
+		e.certFile, e.keyFile = absPath(root, opts.CertFile), absPath(root, opts.KeyFile)
+	}
+	if len(opts.CaFile) > 0 {
+		e.caFile = absPath(root, opts.CaFile)
+	}
+	key := fmt.Sprintf("%s|%s|%s|%v", e.certFile, e.keyFile, e.caFile, opts.Decrypt)
+	m.Lock()
+	defer m.Unlock()
+	if c, ok := m.entries[key]; ok {
+		return c, nil
+	}
+	if err := e.reload(); err != nil {
+		return nil, err
+	}
+	e.checkExpiry(logger, time.Now())
+	m.entries[key] = e
+	m.watch(logger, e)
+	return e, nil
+}
+
+// watch adds the directories of the files to the watcher. The directories are watched instead of the files because
+// the files are usually replaced like the secrets mounted in kubernetes.
+func (m *manager) watch(logger api.Logger, e *certEntry) {
+	if m.watcher == nil {
+		w, err := fsnotify.NewWatcher()
+		if err != nil {
+			logger.Warnf("create cert watcher error, the certificates will not be reloaded: %v", err)
+			return
+		}
+		m.watcher = w
+		m.dirs = make(map[string]struct{})
+		go m.run(w)
+	}
+	for _, f := range e.files() {
+		dir := filepath.Dir(f)
+		if _, ok := m.dirs[dir]; ok {
+			continue
+		}
+		if err := m.watcher.Add(dir); err != nil {
+			logger.Warnf("watch cert dir %s error: %v", dir, err)
+			continue
+		}
+		m.dirs[dir] = struct{}{}
+	}
+}
+
+func (m *manager) run(w *fsnotify.Watcher) {
+	ticker := time.NewTicker(expiryCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case event, ok := <-w.Events:
+			if !ok {
+				return
+			}
+			m.reloadDir(filepath.Dir(event.Name))
+		case err, ok := <-w.Errors:
+			if !ok {
+				return
+			}
+			conf.Log.Warnf("cert watcher error: %v", err)
+		case <-ticker.C:
+			m.checkExpiry()
+		}
+	}
+}
+
+// reloadDir reloads the entries with files in the directory. The old materials are kept if the files are invalid,
+// for example, partially written.
+func (m *manager) reloadDir(dir string) {
+	m.Lock()
+	var reloaded []string
+	for _, e := range m.entries {
+		for _, f := range e.files() {
+			if filepath.Dir(f) != dir {
+				continue
+			}
+			if err := e.reload(); err != nil {
+				conf.Log.Warnf("reload cert %s error, keep the old one: %v", f, err)
+			} else {
+				conf.Log.Infof("cert %s reloaded", f)
+				e.checkExpiry(conf.Log, time.Now())
+				reloaded = append(reloaded, e.files()...)
+			}
+			break
+		}
+	}
+	listeners := m.listeners
+	m.Unlock()
+	if len(reloaded) == 0 {
+		return
+	}
+	for _, fn := range listeners {
+		fn(reloaded)
+	}
+}
+
+func (m *manager) checkExpiry() {
+	m.Lock()
+	defer m.Unlock()
+	now := time.Now()
+	for _, e := range m.entries {
+		e.checkExpiry(conf.Log, now)
+	}
+}
+
+// certEntry is the TLS materials loaded from the files
+type certEntry struct {
+	certFile string
+	keyFile  string
+	caFile   string
+	decrypt  *model.EncryptionConf
+
+	cert  atomic.Pointer[tls.Certificate]
+	roots atomic.Pointer[x509.CertPool]
+	// caNotAfter is the earliest expiry of the CAs
+	caNotAfter atomic.Int64
+}
+
+func (e *certEntry) files() []string {
+	var result []string
+	for _, f := range []string{e.certFile, e.keyFile, e.caFile} {
+		if f != "" {
+			result = append(result, f)
+		}
+	}
+	return result
+}
+
+func (e *certEntry) uses(files []string) bool {
+	for _, f := range e.files() {
+		for _, o := range files {
+			if f == o {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (e *certEntry) hasCert() bool {
+	return e.certFile != "" || e.keyFile != ""
+}
+
+func (e *certEntry) hasCA() bool {
+	return e.caFile != ""
+}
+
+func (e *certEntry) reload() error {
+	var (
+		cert  *tls.Certificate
+		roots *x509.CertPool
+		caExp time.Time
+	)
+	if e.hasCert() {
+		cpb, err := os.ReadFile(e.certFile)
+		if err != nil {
+			return err
+		}
+		kpb, err := os.ReadFile(e.keyFile)
+		if err != nil {
+			return err
+		}
+		c, err := buildCert(e.decrypt, cpb, kpb)
+		if err != nil {
+			return err
+		}
+		c.Leaf, err = x509.ParseCertificate(c.Certificate[0])
+		if err != nil {
+			return err
+		}
+		cert = &c
+	}
+	if e.hasCA() {
+		pb, err := os.ReadFile(e.caFile)
+		if err != nil {
+			return err
+		}
+		roots, caExp, err = parseCAs(pb)
+		if err != nil {
+			return fmt.Errorf("invalid CA file %s: %v", e.caFile, err)
+		}
+	}
+	// store after all files are valid to not mix the old and new materials
+	if cert != nil {
+		e.cert.Store(cert)
+	}
+	if roots != nil {
+		e.roots.Store(roots)
+		e.caNotAfter.Store(caExp.Unix())
+	}
+	return nil
+}
+
+// applyCert serves the latest certificate for the new handshakes of both the clients and the servers
+func (e *certEntry) applyCert(c *tls.Config) {
+	c.Certificates = []tls.Certificate{*e.cert.Load()}
+	c.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+		return e.cert.Load(), nil
+	}
+	c.GetCertificate = func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+		return e.cert.Load(), nil
+	}
+}
+
+// checkExpiry updates the expiry metrics and warns if the certificates are about to expire
+func (e *certEntry) checkExpiry(logger api.Logger, now time.Time) {
+	warn := time.Duration(expiryWarning.Load())
+	check := func(file string, notAfter time.Time) {
+		left := notAfter.Sub(now)
+		CertExpiryGauge.WithLabelValues(file).Set(left.Seconds())
+		switch {
+		case left <= 0:
+			logger.Errorf("cert %s expired at %s", file, notAfter.Format(time.RFC3339))
+		case left < warn:
+			logger.Warnf("cert %s will expire at %s", file, notAfter.Format(time.RFC3339))
+		}
+	}
+	if c := e.cert.Load(); c != nil {
+		check(e.certFile, c.Leaf.NotAfter)
+	}
+	if e.hasCA() {
+		check(e.caFile, time.Unix(e.caNotAfter.Load(), 0))
+	}
+}
+
+// parseCAs parses the PEM certificates and returns the earliest expiry
+func parseCAs(pb []byte) (*x509.CertPool, time.Time, error) {
+	pool := x509.NewCertPool()
+	var notAfter time.Time
+	for {
+		var block *pem.Block
+		block, pb = pem.Decode(pb)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		c, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, notAfter, err
+		}
+		pool.AddCert(c)
+		if notAfter.IsZero() || c.NotAfter.Before(notAfter) {
+			notAfter = c.NotAfter
+		}
+	}
+	if notAfter.IsZero() {
+		return nil, notAfter, errors.New("no certificate found")
+	}
+	return pool, notAfter, nil
+}
+
+func absPath(root, p string) string {
+	if p == "" || filepath.IsAbs(p) {
+		return p
+	}
+	return filepath.Join(root, p)
+}
@@ -0,0 +1,193 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cert
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	io_prometheus_client "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	mockContext "github.com/lf-edge/ekuiper/v2/pkg/mock/context"
+	"github.com/lf-edge/ekuiper/v2/pkg/model"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+// genCert generates the cert signed by the parent or self-signed if parent is nil, and writes it to the dir
+func genCert(t *testing.T, dir, name string, serial int64, ttl time.Duration, parent *testCert, id string) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(ttl),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+	}
+	signer, signerKey := tpl, key
+	if parent == nil {
+		tpl.IsCA = true
+		tpl.BasicConstraintsValid = true
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	if id != "" {
+		u, err := url.Parse(id)
+		require.NoError(t, err)
+		tpl.URIs = []*url.URL{u}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tpl, signer, &key.PublicKey, signerKey)
+	require.NoError(t, err)
+	c, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	kb, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name+".crt"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name+".key"), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: kb}), 0o600))
+	return &testCert{cert: c, key: key}
+}
+
+func TestCertReload(t *testing.T) {
+	dir := t.TempDir()
+	ca := genCert(t, dir, "ca", 1, 24*time.Hour, nil, "")
+	genCert(t, dir, "svid", 2, time.Hour, ca, "spiffe://example.org/a")
+	props := map[string]any{
+		"certificationPath": filepath.Join(dir, "svid.crt"),
+		"privateKeyPath":    filepath.Join(dir, "svid.key"),
+		"rootCaPath":        filepath.Join(dir, "ca.crt"),
+		"spiffeId":          "spiffe://example.org",
+	}
+	ctx := mockContext.NewMockContext("certReload", "op1")
+	c, err := GenTLSConfig(ctx, props)
+	require.NoError(t, err)
+	cert, err := c.GetClientCertificate(nil)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), cert.Leaf.SerialNumber.Int64())
+	// authorized by the SPIFFE ID
+	require.True(t, c.InsecureSkipVerify)
+	require.NoError(t, c.VerifyPeerCertificate(cert.Certificate, nil))
+	m := &io_prometheus_client.Metric{}
+	require.NoError(t, CertExpiryGauge.WithLabelValues(filepath.Join(dir, "svid.crt")).Write(m))
+	require.InDelta(t, time.Hour.Seconds(), m.GetGauge().GetValue(), 60)
+
+	// rotated
+	genCert(t, dir, "svid", 3, time.Hour, ca, "spiffe://example.org/b")
+	certManager.reloadDir(dir)
+	cert, err = c.GetClientCertificate(nil)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), cert.Leaf.SerialNumber.Int64())
+	// invalid file keeps the old one
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "svid.crt"), []byte("partial"), 0o600))
+	certManager.reloadDir(dir)
+	cert, err = c.GetClientCertificate(nil)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), cert.Leaf.SerialNumber.Int64())
+	// the entry is shared
+	c2, err := GenTLSConfig(ctx, props)
+	require.NoError(t, err)
+	cert, err = c2.GetClientCertificate(nil)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), cert.Leaf.SerialNumber.Int64())
+
+	// unauthorized SPIFFE ID
+	props["spiffeId"] = "spiffe://example.org/c"
+	c3, err := GenTLSConfig(ctx, props)
+	require.NoError(t, err)
+	require.EqualError(t, c3.VerifyPeerCertificate(cert.Certificate, nil), "peer SPIFFE ID spiffe://example.org/b is not authorized, expect spiffe://example.org/c")
+	// not signed by the bundle
+	other := genCert(t, t.TempDir(), "other", 4, time.Hour, nil, "spiffe://example.org/c")
+	require.Error(t, c3.VerifyPeerCertificate([][]byte{other.cert.Raw}, nil))
+	props["spiffeId"] = "https://example.org"
+	_, err = GenTLSConfig(ctx, props)
+	require.Error(t, err)
+}
+
+func TestReloadFiles(t *testing.T) {
+	dir := t.TempDir()
+	ca := genCert(t, dir, "ca", 1, 24*time.Hour, nil, "")
+	genCert(t, dir, "client", 2, time.Hour, ca, "")
+	props := map[string]any{
+		"certificationPath": "client.crt",
+		"privateKeyPath":    "client.key",
+	}
+	files, err := Files(dir, props)
+	require.NoError(t, err)
+	want := []string{filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key")}
+	require.Equal(t, want, files)
+	files, err = Files(dir, map[string]any{"server": "tcp://127.0.0.1:1883"})
+	require.NoError(t, err)
+	require.Empty(t, files)
+
+	c, err := generateTLS(conf.Log, dir, &model.TlsConfigurationOptions{CertFile: "client.crt", KeyFile: "client.key"}, &model.TlsKeys{})
+	require.NoError(t, err)
+	notified := make(chan []string, 1)
+	OnReload(func(files []string) {
+		if filepath.Dir(files[0]) == dir {
+			notified <- files
+		}
+	})
+	// reloaded on demand without the watcher
+	genCert(t, dir, "client", 3, time.Hour, ca, "")
+	_, err = ReloadFiles(dir, props)
+	require.NoError(t, err)
+	cert, err := c.GetClientCertificate(nil)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), cert.Leaf.SerialNumber.Int64())
+	// the listeners are notified of the files reloaded by the watcher
+	genCert(t, dir, "client", 4, time.Hour, ca, "")
+	certManager.reloadDir(dir)
+	require.Equal(t, want, <-notified)
+	// invalid files
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "client.crt"), []byte("partial"), 0o600))
+	_, err = ReloadFiles(dir, props)
+	require.Error(t, err)
+	cert, err = c.GetClientCertificate(nil)
+	require.NoError(t, err)
+	require.Equal(t, int64(4), cert.Leaf.SerialNumber.Int64())
+}
+
+func TestMatchSpiffeID(t *testing.T) {
+	tests := []struct {
+		peer string
+		id   string
+		want bool
+	}{
+		{"spiffe://example.org/a", "spiffe://example.org/a", true},
+		{"spiffe://example.org/a", "spiffe://example.org", true},
+		{"spiffe://example.org/a", "spiffe://example.org/", true},
+		{"spiffe://example.org/a", "spiffe://example.org/b", false},
+		{"spiffe://example.org.evil/a", "spiffe://example.org", false},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.want, matchSpiffeID(tt.peer, tt.id), tt.peer+" "+tt.id)
+	}
+}
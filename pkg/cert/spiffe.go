@@ -0,0 +1,102 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cert
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// validateSpiffeID checks the configured SPIFFE ID or trust domain
+func validateSpiffeID(id string) error {
+	u, err := url.Parse(id)
+	if err != nil {
+		return fmt.Errorf("invalid spiffeId %s: %v", id, err)
+	}
+	if u.Scheme != "spiffe" || u.Host == "" {
+		return fmt.Errorf("invalid spiffeId %s: must be like spiffe://trust-domain/path", id)
+	}
+	return nil
+}
+
+// verifySpiffe verifies the peer SVID by the trust bundle and authorizes its SPIFFE ID. The roots are loaded for
+// each handshake so that the rotated trust bundle applies to the new connections.
+func verifySpiffe(roots func() *x509.CertPool, id string) func([][]byte, [][]*x509.Certificate) error {
+	return func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		if len(rawCerts) == 0 {
+			return errors.New("no peer certificate")
+		}
+		certs := make([]*x509.Certificate, len(rawCerts))
+		for i, raw := range rawCerts {
+			c, err := x509.ParseCertificate(raw)
+			if err != nil {
+				return err
+			}
+			certs[i] = c
+		}
+		pool := roots()
+		if pool == nil {
+			return errors.New("no trust bundle to verify the peer SVID, please set the root CA")
+		}
+		intermediates := x509.NewCertPool()
+		for _, c := range certs[1:] {
+			intermediates.AddCert(c)
+		}
+		if _, err := certs[0].Verify(x509.VerifyOptions{
+			Roots:         pool,
+			Intermediates: intermediates,
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+		}); err != nil {
+			return err
+		}
+		peer, err := SpiffeIDOf(certs[0])
+		if err != nil {
+			return err
+		}
+		if !matchSpiffeID(peer, id) {
+			return fmt.Errorf("peer SPIFFE ID %s is not authorized, expect %s", peer, id)
+		}
+		return nil
+	}
+}
+
+// SpiffeIDOf returns the SPIFFE ID of the SVID
+func SpiffeIDOf(c *x509.Certificate) (string, error) {
+	var ids []string
+	for _, u := range c.URIs {
+		if u.Scheme == "spiffe" {
+			ids = append(ids, u.String())
+		}
+	}
+	if len(ids) != 1 {
+		return "", fmt.Errorf("the SVID must have exactly one SPIFFE ID, got %d", len(ids))
+	}
+	return ids[0], nil
+}
+
+// matchSpiffeID checks the peer ID equals the expected ID or belongs to the expected trust domain
+func matchSpiffeID(peer, id string) bool {
+	if peer == id {
+		return true
+	}
+	u, err := url.Parse(id)
+	if err != nil || strings.Trim(u.Path, "/") != "" {
+		return false
+	}
+	return strings.HasPrefix(peer, "spiffe://"+u.Host+"/")
+}
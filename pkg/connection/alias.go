@@ -0,0 +1,232 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+	"github.com/lf-edge/ekuiper/v2/pkg/syncx"
+)
+
+// An alias is a stable logical name of a named connection. The rules select the alias as the connection, and it is
+// resolved to the target connection when they attach, so that the target can be switched to another connection
+// without changing the rules. The rules attached already keep the old target until they restart.
+
+// aliasCfgType is the config type of the aliases. It must not have the prefix "connections" so that the aliases are
+// not loaded as connections.
+const aliasCfgType = "connAlias"
+
+type ConnectionAlias struct {
+	Alias  string `json:"alias"`
+	Target string `json:"target"`
+}
+
+var aliases = struct {
+	syncx.RWMutex
+	targets map[string]string
+	// attached is the target resolved for each reference attached by the alias, keyed by alias and ref id, so
+	// that the reference is detached from the same target after the alias is switched
+	attached map[string]string
+}{targets: map[string]string{}, attached: map[string]string{}}
+
+func initAliases() {
+	targets := make(map[string]string)
+	cfgs, err := conf.GetCfgFromKVStorage(aliasCfgType, "", "")
+	if err != nil {
+		conf.Log.Warnf("load connection aliases error: %v", err)
+	}
+	for key, v := range cfgs {
+		names := strings.Split(key, ".")
+		if len(names) != 2 {
+			continue
+		}
+		if target, ok := v["target"].(string); ok {
+			targets[names[1]] = target
+		}
+	}
+	aliases.Lock()
+	aliases.targets = targets
+	aliases.attached = make(map[string]string)
+	aliases.Unlock()
+}
+
+func resolveAlias(id string) (string, bool) {
+	aliases.RLock()
+	defer aliases.RUnlock()
+	target, ok := aliases.targets[id]
+	return target, ok
+}
+
+func aliasesOf(target string) []string {
+	aliases.RLock()
+	defer aliases.RUnlock()
+	var result []string
+	for alias, t := range aliases.targets {
+		if t == target {
+			result = append(result, alias)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+func attachKey(alias, refId string) string {
+	return alias + "\x00" + refId
+}
+
+func recordAliasAttach(alias, refId, target string) {
+	aliases.Lock()
+	defer aliases.Unlock()
+	aliases.attached[attachKey(alias, refId)] = target
+}
+
+// resolveDetach returns the connection to detach the reference from. It is the target resolved when attached if the
+// reference attached by an alias.
+func resolveDetach(id, refId string) string {
+	aliases.Lock()
+	defer aliases.Unlock()
+	key := attachKey(id, refId)
+	if target, ok := aliases.attached[key]; ok {
+		delete(aliases.attached, key)
+		return target
+	}
+	if target, ok := aliases.targets[id]; ok {
+		return target
+	}
+	return id
+}
+
+// ListAliases returns all the aliases ordered by the alias
+func ListAliases() []ConnectionAlias {
+	aliases.RLock()
+	defer aliases.RUnlock()
+	result := make([]ConnectionAlias, 0, len(aliases.targets))
+	for alias, target := range aliases.targets {
+		result = append(result, ConnectionAlias{Alias: alias, Target: target})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Alias < result[j].Alias
+	})
+	return result
+}
+
+// AliasConnection points the alias to the target named connection. If the alias exists, it is switched to the
+// target for the rules attaching afterward.
+func AliasConnection(ctx api.StreamContext, alias, target string) error {
+	if alias == "" || target == "" {
+		return fmt.Errorf("alias and target connection should be defined")
+	}
+	if alias == target {
+		return fmt.Errorf("alias %s can't point to itself", alias)
+	}
+	if err := checkCreateNamespace(ctx, alias); err != nil {
+		return err
+	}
+	if err := CheckNamespace(ctx, target); err != nil {
+		return err
+	}
+	globalConnectionManager.Lock()
+	defer globalConnectionManager.Unlock()
+	if _, ok := globalConnectionManager.connectionPool[alias]; ok {
+		return errorx.NewWithCode(errorx.ConnectionExistErr, fmt.Sprintf("connection %s already exists and can't be an alias", alias))
+	}
+	meta, ok := globalConnectionManager.connectionPool[target]
+	if !ok {
+		return errorx.NewWithCode(errorx.NOT_FOUND, fmt.Sprintf("connection %s not existed", target))
+	}
+	if !meta.Named {
+		return errorx.NewWithCode(errorx.ConnectionReadOnlyErr, fmt.Sprintf("internal connection %s can't be aliased", target))
+	}
+	if err := conf.WriteCfgIntoKVStorage(aliasCfgType, alias, "", map[string]any{"target": target}); err != nil {
+		return err
+	}
+	aliases.Lock()
+	prev, existed := aliases.targets[alias]
+	aliases.targets[alias] = target
+	aliases.Unlock()
+	if existed && prev != target {
+		conf.Log.Infof("connection alias %s is switched from %s to %s", alias, prev, target)
+	} else {
+		conf.Log.Infof("connection alias %s points to %s", alias, target)
+	}
+	return nil
+}
+
+// DeleteAlias removes the alias. The rules attached by it keep the target.
+func DeleteAlias(ctx api.StreamContext, alias string) error {
+	if err := CheckNamespace(ctx, alias); err != nil {
+		return err
+	}
+	if _, ok := resolveAlias(alias); !ok {
+		return errorx.NewWithCode(errorx.NOT_FOUND, fmt.Sprintf("connection alias %s not existed", alias))
+	}
+	if err := conf.DropCfgKeyFromStorage(aliasCfgType, alias, ""); err != nil {
+		return err
+	}
+	aliases.Lock()
+	delete(aliases.targets, alias)
+	aliases.Unlock()
+	return nil
+}
+
+// CloneConnection creates a named connection with the type and the props of the source connection. The overrides
+// are merged into the props, and the props overridden by nil are removed. The clone is not derived from the template
+// of the source.
+func CloneConnection(ctx api.StreamContext, srcId, newId string, overrides map[string]any) (*ConnWrapper, error) {
+	src, err := GetConnectionDetail(ctx, srcId)
+	if err != nil {
+		return nil, err
+	}
+	if !src.Named {
+		return nil, errorx.NewWithCode(errorx.ConnectionReadOnlyErr, fmt.Sprintf("internal connection %s can't be cloned", srcId))
+	}
+	props := make(map[string]any, len(src.Props)+len(overrides))
+	for k, v := range src.Props {
+		props[k] = v
+	}
+	delete(props, TemplatePropKey)
+	for k, v := range overrides {
+		if v == nil {
+			delete(props, k)
+		} else {
+			props[k] = v
+		}
+	}
+	return CreateNamedConnection(ctx, newId, src.Typ, props)
+}
+
+// fetchByAlias attaches the reference to the target of the alias, and records the target to detach from
+func (m *ConnectionManager) fetchByAlias(ctx api.StreamContext, refId, alias, target, typ string, props map[string]any, sc api.StatusChangeHandler) (*ConnWrapper, error) {
+	if err := CheckNamespace(ctx, target); err != nil {
+		return nil, err
+	}
+	resolved := make(map[string]any, len(props))
+	for k, v := range props {
+		resolved[k] = v
+	}
+	resolved["connectionSelector"] = target
+	cw, err := m.Fetch(ctx, refId, typ, resolved, sc)
+	if err != nil {
+		return nil, err
+	}
+	recordAliasAttach(alias, refId, target)
+	return cw, nil
+}
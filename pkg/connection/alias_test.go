@@ -0,0 +1,100 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/topo/context"
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+)
+
+func TestCloneConnection(t *testing.T) {
+	require.NoError(t, InitConnectionManager4Test())
+	ctx := context.Background()
+	_, err := CreateNamedConnection(ctx, "clonesrc", "mock", map[string]any{"a": 1, "b": "x"})
+	require.NoError(t, err)
+	defer func() {
+		_ = DropNameConnectionPermanently(ctx, "clonesrc")
+	}()
+	_, err = CloneConnection(ctx, "clonesrc", "clonedst", map[string]any{"a": 2, "b": nil, "c": true})
+	require.NoError(t, err)
+	defer func() {
+		_ = DropNameConnectionPermanently(ctx, "clonedst")
+	}()
+	meta, err := GetConnectionDetail(ctx, "clonedst")
+	require.NoError(t, err)
+	require.Equal(t, "mock", meta.Typ)
+	require.Equal(t, map[string]any{"a": 2, "c": true}, meta.Props)
+
+	_, err = CloneConnection(ctx, "clonesrc", "clonedst", nil)
+	require.Error(t, err)
+	_, err = CloneConnection(ctx, "nonexist", "clonedst2", nil)
+	require.Error(t, err)
+}
+
+func TestAliasConnection(t *testing.T) {
+	require.NoError(t, InitConnectionManager4Test())
+	ctx := context.Background()
+	for _, id := range []string{"aliasA", "aliasB"} {
+		_, err := CreateNamedConnection(ctx, id, "mock", nil)
+		require.NoError(t, err)
+	}
+	defer func() {
+		_ = DropNameConnectionPermanently(ctx, "aliasA")
+		_ = DropNameConnectionPermanently(ctx, "aliasB")
+	}()
+
+	require.Error(t, AliasConnection(ctx, "line", "nonexist"))
+	require.Error(t, AliasConnection(ctx, "aliasB", "aliasA"))
+	require.NoError(t, AliasConnection(ctx, "line", "aliasA"))
+	defer func() {
+		_ = DeleteAlias(ctx, "line")
+	}()
+	require.Equal(t, []ConnectionAlias{{Alias: "line", Target: "aliasA"}}, ListAliases())
+	_, err := CreateNamedConnection(ctx, "line", "mock", nil)
+	require.Error(t, err)
+
+	refId := extractRefId(ctx)
+	cw, err := FetchConnection(ctx, refId, "mock", map[string]any{"connectionSelector": "line"}, nil)
+	require.NoError(t, err)
+	require.Equal(t, "aliasA", cw.ID)
+	require.Equal(t, 1, globalConnectionManager.getConnectionRef("aliasA"))
+	meta, err := GetConnectionDetail(ctx, "line")
+	require.NoError(t, err)
+	require.Equal(t, "aliasA", meta.ID)
+
+	// the attached ref is detached from the old target after switching
+	require.NoError(t, AliasConnection(ctx, "line", "aliasB"))
+	err = DropNameConnectionPermanently(ctx, "aliasB")
+	require.Error(t, err)
+	code, ok := errorx.GetErrorCode(err)
+	require.True(t, ok)
+	require.Equal(t, errorx.ConnectionInUseErr, code)
+	require.NoError(t, DetachConnection(ctx, "line"))
+	require.Equal(t, 0, globalConnectionManager.getConnectionRef("aliasA"))
+
+	// reload from the storage
+	initAliases()
+	target, isAlias := resolveAlias("line")
+	require.True(t, isAlias)
+	require.Equal(t, "aliasB", target)
+
+	require.NoError(t, DeleteAlias(ctx, "line"))
+	require.Error(t, DeleteAlias(ctx, "line"))
+	require.Empty(t, ListAliases())
+}
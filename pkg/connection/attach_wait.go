@@ -0,0 +1,92 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+)
+
+// AttachWait attaches the existing connection or alias of the id like FetchConnection with the connectionSelector,
+// and blocks until it is connected, so that the rule can start after the shared connection is ready instead of
+// failing and retrying by itself. The connection being created or reconnected is waited for. The reference is
+// released if the connection fails permanently, is detached, or is not connected before the timeout or ctx is done.
+// The timeout of 0 waits until ctx is done. The status handler sc is optional and is notified as the one of
+// FetchConnection.
+func (m *ConnectionManager) AttachWait(ctx api.StreamContext, id string, timeout time.Duration, sc api.StatusChangeHandler) (*ConnWrapper, error) {
+	if id == "" {
+		return nil, errorx.NewWithCode(errorx.ConnectionInvalidErr, "connection id should be defined")
+	}
+	ready := make(chan struct{})
+	var (
+		once sync.Once
+		mu   sync.Mutex
+		last string
+	)
+	handler := func(status string, message string) {
+		mu.Lock()
+		last = status
+		mu.Unlock()
+		if status == api.ConnectionConnected {
+			once.Do(func() { close(ready) })
+		}
+		if sc != nil {
+			sc(status, message)
+		}
+	}
+	cw, err := m.Fetch(ctx, extractRefId(ctx), "", map[string]any{"connectionSelector": id}, handler)
+	if err != nil {
+		return nil, err
+	}
+	var waitCtx context.Context = ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	created := cw.readCh
+	for {
+		select {
+		case <-ready:
+			return cw, nil
+		case <-created:
+			created = nil
+			// the created connection reports connected by the status handler. The permanent failure ends the wait
+			// here, while the others may be recovered by the background reconnection.
+			if _, err := cw.Wait(ctx); isPermanentFailure(err) {
+				_ = m.Detach(ctx, id)
+				return nil, err
+			}
+		case <-cw.detachCh:
+			_ = m.Detach(ctx, id)
+			return nil, fmt.Errorf("connection %s is detached before connected", id)
+		case <-waitCtx.Done():
+			_ = m.Detach(ctx, id)
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			mu.Lock()
+			status := last
+			mu.Unlock()
+			return nil, errorx.NewTimeout(fmt.Errorf("connection %s is not connected in %v, the last status is %s", id, timeout, status))
+		}
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/topo/context"
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+)
+
+func TestAttachWait(t *testing.T) {
+	require.NoError(t, InitConnectionManager4Test())
+	ctx := context.Background()
+	_, err := CreateNamedConnection(ctx, "waitconn", "blockconn", nil)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, DropNameConnectionPermanently(ctx, "waitconn"))
+	}()
+
+	// the reference is released once timeout
+	_, err = AttachWait(ctx, "waitconn", 50*time.Millisecond)
+	require.Error(t, err)
+	require.Equal(t, errorx.KindTimeout, errorx.KindOf(err))
+	require.Equal(t, 0, globalConnectionManager.getConnectionRef("waitconn"))
+
+	type result struct {
+		cw  *ConnWrapper
+		err error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		cw, err := AttachWait(ctx, "waitconn", 5*time.Second)
+		ch <- result{cw: cw, err: err}
+	}()
+	select {
+	case <-ch:
+		require.Fail(t, "attach should wait for the connection")
+	case <-time.After(50 * time.Millisecond):
+	}
+	blockCh <- struct{}{}
+	r := <-ch
+	require.NoError(t, r.err)
+	require.Equal(t, "waitconn", r.cw.ID)
+	require.Equal(t, 1, globalConnectionManager.getConnectionRef("waitconn"))
+	require.NoError(t, DetachConnection(ctx, "waitconn"))
+	require.Equal(t, 0, globalConnectionManager.getConnectionRef("waitconn"))
+
+	// the connected connection returns at once
+	cw, err := AttachWait(ctx, "waitconn", 0)
+	require.NoError(t, err)
+	require.NotNil(t, cw)
+	require.NoError(t, DetachConnection(ctx, "waitconn"))
+
+	_, err = AttachWait(ctx, "notexist", time.Second)
+	require.Error(t, err)
+	_, err = AttachWait(ctx, "", time.Second)
+	require.Error(t, err)
+}
@@ -0,0 +1,177 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+)
+
+// Attacher is the rule component holding a reference of the connection
+type Attacher struct {
+	RefID      string    `json:"refId"`
+	RuleID     string    `json:"ruleId"`
+	OpID       string    `json:"opId"`
+	InstanceID int       `json:"instanceId"`
+	AttachedAt time.Time `json:"attachedAt"`
+}
+
+// addAttacher records the rule component of the context. It is keyed by the same ref id to detach.
+func (meta *Meta) addAttacher(ctx api.StreamContext) {
+	meta.storeAttacher(attacherOf(ctx))
+}
+
+// storeAttacher records the attacher and indexes the connection by its rule op
+func (meta *Meta) storeAttacher(a Attacher) {
+	m := meta.manager()
+	if prev, ok := meta.attachers.Swap(a.RefID, a); ok {
+		m.unindexAttacher(meta, prev.(Attacher))
+	}
+	m.indexAttacher(meta, a)
+}
+
+// deleteAttacher removes the attacher of the ref and its index
+func (meta *Meta) deleteAttacher(refId string) {
+	if prev, ok := meta.attachers.LoadAndDelete(refId); ok {
+		meta.manager().unindexAttacher(meta, prev.(Attacher))
+	}
+}
+
+// opKey is the rule op holding the connections
+type opKey struct {
+	ruleID string
+	opID   string
+}
+
+// indexAttacher counts the refs of the rule op to the connection
+func (m *ConnectionManager) indexAttacher(meta *Meta, a Attacher) {
+	k := opKey{ruleID: a.RuleID, opID: a.OpID}
+	m.opConnsLock.Lock()
+	defer m.opConnsLock.Unlock()
+	metas, ok := m.opConns[k]
+	if !ok {
+		metas = make(map[*Meta]int)
+		m.opConns[k] = metas
+	}
+	metas[meta]++
+}
+
+func (m *ConnectionManager) unindexAttacher(meta *Meta, a Attacher) {
+	k := opKey{ruleID: a.RuleID, opID: a.OpID}
+	m.opConnsLock.Lock()
+	defer m.opConnsLock.Unlock()
+	metas, ok := m.opConns[k]
+	if !ok {
+		return
+	}
+	if metas[meta] > 1 {
+		metas[meta]--
+		return
+	}
+	delete(metas, meta)
+	if len(metas) == 0 {
+		delete(m.opConns, k)
+	}
+}
+
+// unindexMeta removes the remaining attachers of the connection released from the pool
+func (m *ConnectionManager) unindexMeta(meta *Meta) {
+	meta.attachers.Range(func(_, v any) bool {
+		m.unindexAttacher(meta, v.(Attacher))
+		return true
+	})
+}
+
+// opConnections returns the connections in the pool held by the rule op
+func (m *ConnectionManager) opConnections(ruleID, opID string) []*Meta {
+	m.opConnsLock.Lock()
+	metas := m.opConns[opKey{ruleID: ruleID, opID: opID}]
+	result := make([]*Meta, 0, len(metas))
+	for meta := range metas {
+		result = append(result, meta)
+	}
+	m.opConnsLock.Unlock()
+	pool := m.load()
+	n := 0
+	// the dropped or replaced connection may be indexed until it is released
+	for _, meta := range result {
+		if pool[meta.ID] == meta {
+			result[n] = meta
+			n++
+		}
+	}
+	return result[:n]
+}
+
+func attacherOf(ctx api.StreamContext) Attacher {
+	return Attacher{
+		RefID:      extractRefId(ctx),
+		RuleID:     ctx.GetRuleId(),
+		OpID:       ctx.GetOpId(),
+		InstanceID: ctx.GetInstanceId(),
+		AttachedAt: getClock().Now(),
+	}
+}
+
+// attacherOfRef returns the attacher holding the ref, or the rule component of the context if it is not recorded
+func (meta *Meta) attacherOfRef(ctx api.StreamContext, refId string) Attacher {
+	if v, ok := meta.attachers.Load(refId); ok {
+		return v.(Attacher)
+	}
+	a := attacherOf(ctx)
+	a.RefID = refId
+	return a
+}
+
+// Attachers returns the rule components holding the connection sorted by the ref id
+func (meta *Meta) Attachers() []Attacher {
+	result := make([]Attacher, 0)
+	meta.attachers.Range(func(_, v any) bool {
+		result = append(result, v.(Attacher))
+		return true
+	})
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].RefID < result[j].RefID
+	})
+	return result
+}
+
+// attacherRules returns the distinct rules holding the connection
+func (meta *Meta) attacherRules() []string {
+	var rules []string
+	seen := make(map[string]struct{})
+	for _, a := range meta.Attachers() {
+		if _, ok := seen[a.RuleID]; !ok {
+			seen[a.RuleID] = struct{}{}
+			rules = append(rules, a.RuleID)
+		}
+	}
+	return rules
+}
+
+// GetConnectionAttachers returns the rule components holding the connection, so that the rules can be stopped to
+// drop the connection
+func GetConnectionAttachers(id string) ([]Attacher, error) {
+	meta, ok := globalConnectionManager.load()[id]
+	if !ok {
+		return nil, errorx.NewWithCode(errorx.NOT_FOUND, fmt.Sprintf("connection %s not existed", id))
+	}
+	return meta.Attachers(), nil
+}
@@ -0,0 +1,73 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	mockContext "github.com/lf-edge/ekuiper/v2/pkg/mock/context"
+)
+
+func TestConnectionAttachers(t *testing.T) {
+	require.NoError(t, InitConnectionManager4Test())
+	ctx := mockContext.NewMockContext("att", "create")
+	_, err := CreateNamedConnection(ctx, "att1", "mock", nil)
+	require.NoError(t, err)
+	attachers, err := GetConnectionAttachers("att1")
+	require.NoError(t, err)
+	require.Empty(t, attachers)
+
+	props := map[string]any{"connectionSelector": "att1"}
+	ctx1 := mockContext.NewMockContext("rule1", "op1")
+	ctx2 := mockContext.NewMockContext("rule2", "op2")
+	_, err = FetchConnection(ctx1, "ref1", "mock", props, nil)
+	require.NoError(t, err)
+	_, err = FetchConnection(ctx2, "ref2", "mock", props, nil)
+	require.NoError(t, err)
+	attachers, err = GetConnectionAttachers("att1")
+	require.NoError(t, err)
+	require.Len(t, attachers, 2)
+	require.Equal(t, extractRefId(ctx1), attachers[0].RefID)
+	require.Equal(t, "rule1", attachers[0].RuleID)
+	require.Equal(t, "op1", attachers[0].OpID)
+	require.False(t, attachers[0].AttachedAt.IsZero())
+	require.Equal(t, "rule2", attachers[1].RuleID)
+
+	err = DropNameConnection(ctx, "att1")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "[rule1 rule2]")
+
+	require.NoError(t, DetachConnection(ctx1, "att1"))
+	attachers, err = GetConnectionAttachers("att1")
+	require.NoError(t, err)
+	require.Len(t, attachers, 1)
+	require.Equal(t, "rule2", attachers[0].RuleID)
+	require.NoError(t, DetachConnection(ctx2, "att1"))
+	require.NoError(t, DropNameConnection(ctx, "att1"))
+
+	// the anonymous connection created by the rule
+	_, err = FetchConnection(ctx1, "anon1", "mock", nil, nil)
+	require.NoError(t, err)
+	attachers, err = GetConnectionAttachers("anon1")
+	require.NoError(t, err)
+	require.Len(t, attachers, 1)
+	require.Equal(t, "rule1", attachers[0].RuleID)
+	require.NoError(t, DetachConnection(ctx1, "anon1"))
+
+	_, err = GetConnectionAttachers("att1")
+	require.Error(t, err)
+}
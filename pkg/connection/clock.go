@@ -0,0 +1,70 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/benbjohnson/clock"
+)
+
+var realClock = clock.New()
+
+// poolClock is the clock of the pool. Unlike timex.Clock, it is the real clock by default in the tests too.
+var poolClock atomic.Pointer[clock.Clock]
+
+// SetClock replaces the clock of the retry timers, the circuit breaker, the operation timeouts, the leader lease and
+// the periodic jobs of the pool, for example by clock.NewMock() to advance the time deterministically in the tests.
+// Nil restores the real clock. The real clock measures the intervals by the monotonic clock, so that the stepping of
+// the wall clock like NTP corrections does not shorten or prolong the retries.
+func SetClock(c clock.Clock) {
+	if c == nil {
+		poolClock.Store(nil)
+		return
+	}
+	poolClock.Store(&c)
+}
+
+func getClock() clock.Clock {
+	if c := poolClock.Load(); c != nil {
+		return *c
+	}
+	return realClock
+}
+
+// clockTimer adapts the timer of the clock to the backoff timer
+type clockTimer struct {
+	c clock.Clock
+	t *clock.Timer
+}
+
+func (ct *clockTimer) Start(d time.Duration) {
+	if ct.t == nil {
+		ct.t = ct.c.Timer(d)
+	} else {
+		ct.t.Reset(d)
+	}
+}
+
+func (ct *clockTimer) Stop() {
+	if ct.t != nil {
+		ct.t.Stop()
+	}
+}
+
+func (ct *clockTimer) C() <-chan time.Time {
+	return ct.t.C
+}
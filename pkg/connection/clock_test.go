@@ -0,0 +1,74 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/cenkalti/backoff/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMockClock(t *testing.T) {
+	mock := clock.NewMock()
+	SetClock(mock)
+	defer SetClock(nil)
+
+	// the circuit breaker is closed once the mock time passes the cooldown
+	g := newRetryGuard(0, 1, time.Hour)
+	g.onFailure()
+	done := make(chan bool)
+	go func() {
+		done <- g.wait(context.Background())
+	}()
+	select {
+	case <-done:
+		t.Fatal("circuit breaker should be open")
+	case <-time.After(20 * time.Millisecond):
+	}
+	mock.Add(time.Hour)
+	select {
+	case r := <-done:
+		require.True(t, r)
+	case <-time.After(time.Second):
+		t.Fatal("circuit breaker should be closed")
+	}
+
+	// the max elapsed time of the retries is measured by the clock
+	b := withMaxElapsed(backoff.NewConstantBackOff(time.Second), time.Minute)
+	require.Equal(t, time.Second, b.NextBackOff())
+	mock.Add(2 * time.Minute)
+	require.Equal(t, backoff.Stop, b.NextBackOff())
+	b.Reset()
+	require.Equal(t, time.Second, b.NextBackOff())
+
+	// the retry timer fires by the clock
+	timer := newRetryTimer()
+	require.NotNil(t, timer)
+	timer.Start(time.Minute)
+	mock.Add(time.Minute)
+	select {
+	case <-timer.C():
+	case <-time.After(time.Second):
+		t.Fatal("retry timer should fire")
+	}
+	timer.Stop()
+
+	SetClock(nil)
+	require.Nil(t, newRetryTimer())
+}
@@ -15,13 +15,20 @@
 package connection
 
 import (
+	gocontext "context"
+	"errors"
+	"fmt"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/lf-edge/ekuiper/contract/v2/api"
 
 	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/discovery"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/fault"
 	"github.com/lf-edge/ekuiper/v2/internal/topo/context"
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
 	"github.com/lf-edge/ekuiper/v2/pkg/modules"
 	"github.com/lf-edge/ekuiper/v2/pkg/syncx"
 )
@@ -34,13 +41,45 @@ type ConnWrapper struct {
 	l           syncx.RWMutex
 	readCh      chan struct{}
 	detachCh    chan struct{}
+	// cancel aborts the connection creation and ends the connection context
+	cancel gocontext.CancelFunc
+	// throttle limits the operations of the consumers by the throttle prop
+	throttle        atomic.Pointer[throttle]
+	throttleMetrics throttleMetrics
+	// gen is the generation of the connection, increased once swapped
+	gen uint64
+	// leases keeps the swapped out connections until the references holding them move on
+	leases leases
 }
 
-func (cw *ConnWrapper) setConn(conn modules.Connection, err error) {
+// setConn sets the result of the connection creation. It returns false if a connection has been swapped in while
+// creating, and the caller must release the created one.
+func (cw *ConnWrapper) setConn(conn modules.Connection, err error) bool {
 	cw.l.Lock()
 	defer cw.l.Unlock()
+	if cw.initialized {
+		return false
+	}
 	cw.initialized = true
 	cw.conn, cw.err = conn, err
+	return true
+}
+
+// swap replaces the connection with an established one atomically, so that the attached consumers get the new
+// connection when they wait for it again. It returns the previous connection, its cancel func to release and its
+// generation.
+func (cw *ConnWrapper) swap(conn modules.Connection, cancel gocontext.CancelFunc) (modules.Connection, gocontext.CancelFunc, uint64) {
+	cw.l.Lock()
+	defer cw.l.Unlock()
+	old, oldCancel, oldGen := cw.conn, cw.cancel, cw.gen
+	if cw.err != nil {
+		old = nil
+	}
+	cw.initialized = true
+	cw.conn, cw.err = conn, nil
+	cw.cancel = cancel
+	cw.gen++
+	return old, oldCancel, oldGen
 }
 
 // Wait will wait for connection connected or the caller interrupts (like rule exit)
@@ -62,20 +101,191 @@ func (cw *ConnWrapper) IsInitialized() bool {
 	return cw.initialized
 }
 
+// newConnWrapper creates the connection in background. The connection runs in the ownership context of the meta, which
+// is derived from the ctx of the manager on the first call, so that it is aborted when the connection is dropped or
+// the manager is closed.
 func newConnWrapper(ctx api.StreamContext, meta *Meta) *ConnWrapper {
+	return newGatedConnWrapper(ctx, meta, nil)
+}
+
+// newGatedConnWrapper creates the connection after the gate is closed. It creates at once if the gate is nil.
+func newGatedConnWrapper(ctx api.StreamContext, meta *Meta, gate <-chan struct{}) *ConnWrapper {
+	connCtx, cancel := meta.ownerContext(ctx).WithCancel()
 	cw := &ConnWrapper{
 		ID:       meta.ID,
 		readCh:   make(chan struct{}),
 		detachCh: make(chan struct{}),
+		cancel:   cancel,
 	}
+	cw.setThrottle(meta.Props)
+	meta.stats.onCreate()
+	meta.goroutines.Add(1)
 	go func() {
-		conn, err := createConnection(ctx, meta)
-		cw.setConn(conn, err)
+		if gate != nil {
+			select {
+			case <-gate:
+			case <-connCtx.Done():
+			}
+		}
+		var conn modules.Connection
+		var err error
+		if connCtx.Err() == nil {
+			err = safeCall(meta.ID, "create", func() (e error) {
+				conn, e = createConnection(connCtx, meta)
+				return
+			})
+		}
+		var pe *PanicError
+		if errors.As(err, &pe) {
+			conn = nil
+			meta.NotifyStatus(api.ConnectionDisconnected, err.Error())
+		}
+		if connCtx.Err() != nil {
+			// aborted, do not leak the connection dialed in the meantime
+			if conn != nil && err == nil {
+				_ = conn.Close(connCtx)
+			}
+			err = connCtx.Err()
+		}
+		if !cw.setConn(conn, err) && conn != nil && err == nil {
+			_ = conn.Close(connCtx)
+		}
+		meta.goroutines.Add(-1)
 		close(cw.readCh)
 	}()
 	return cw
 }
 
+// stagedConn is a connection established out of the pool to be swapped into a wrapper. It runs in its own context,
+// and its meta holds the endpoint selected for the connection. The members are the connections for the other
+// wrappers of the pooled connection.
+type stagedConn struct {
+	conn    modules.Connection
+	ctx     api.StreamContext
+	cancel  gocontext.CancelFunc
+	meta    *Meta
+	members []*stagedConn
+}
+
+// establish creates the standalone connections of the meta with the props, one for each physical connection, and
+// waits until they are connected within the operation timeout. The connections are owned by the meta to swap into.
+func establish(ctx api.StreamContext, meta *Meta, props map[string]any) (*stagedConn, error) {
+	staged, err := establishOne(ctx, meta, props)
+	if err != nil {
+		return nil, err
+	}
+	for range meta.members {
+		member, err := establishOne(ctx, meta, props)
+		if err != nil {
+			staged.release()
+			return nil, err
+		}
+		staged.members = append(staged.members, member)
+	}
+	return staged, nil
+}
+
+func establishOne(ctx api.StreamContext, meta *Meta, props map[string]any) (*stagedConn, error) {
+	id := meta.ID
+	staged := &Meta{ID: id, Typ: meta.Typ, Props: props}
+	connCtx, cancel := meta.ownerContext(context.Background()).WithCancel()
+	type result struct {
+		conn modules.Connection
+		err  error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		var conn modules.Connection
+		err := safeCall(id, "create", func() (e error) {
+			conn, e = createConnection(connCtx, staged)
+			return
+		})
+		ch <- result{conn: conn, err: err}
+	}()
+	opCtx, opCancel := withTimeout(ctx)
+	defer opCancel()
+	select {
+	case r := <-ch:
+		if r.err == nil && r.conn != nil && connCtx.Err() == nil {
+			return &stagedConn{conn: r.conn, ctx: connCtx, cancel: cancel, meta: staged}, nil
+		}
+		cancel()
+		if r.err == nil {
+			r.err = errors.New("connection is aborted")
+		}
+		return nil, r.err
+	case <-opCtx.Done():
+		cancel()
+		// release the connection dialed after the timeout
+		go func() {
+			if r := <-ch; r.conn != nil && r.err == nil {
+				_ = r.conn.Close(connCtx)
+			}
+		}()
+		return nil, errorx.NewTimeout(fmt.Errorf("connect timeout after %v", time.Duration(GetTuning().OperationTimeout)))
+	}
+}
+
+// release closes the staged connections which are not swapped in
+func (s *stagedConn) release() {
+	_ = safeCall(s.meta.ID, "close", func() error {
+		return s.conn.Close(s.ctx)
+	})
+	s.cancel()
+	for _, m := range s.members {
+		m.release()
+	}
+}
+
+// swapInto replaces the connection of the meta with the staged one for all the attached consumers. The previous
+// connection is closed once no consumer holds it.
+func (s *stagedConn) swapInto(ctx api.StreamContext, meta *Meta) {
+	meta.endpoints.Store(s.meta.endpoints.Load())
+	if addr := s.meta.endpoint.Load(); addr != nil {
+		meta.endpoint.Store(addr)
+	}
+	meta.failover.Store(s.meta.failover.Load())
+	meta.activeEndpoint.Store(s.meta.activeEndpoint.Load())
+	s.swapWrapper(ctx, meta, meta.cw)
+	for i, m := range s.members {
+		if i < len(meta.members) {
+			m.swapWrapper(ctx, meta, meta.members[i])
+		} else {
+			m.release()
+		}
+	}
+	meta.NotifyStatus(api.ConnectionConnected, "")
+}
+
+// swapWrapper swaps the staged connection into the wrapper and rebinds the consumers registered by OnRebind. The
+// previous connection is retired until the consumers holding it move to the new one or detach.
+func (s *stagedConn) swapWrapper(ctx api.StreamContext, meta *Meta, cw *ConnWrapper) {
+	old, oldCancel, oldGen := cw.swap(s.conn, s.cancel)
+	if sc, ok := s.conn.(modules.StatefulDialer); ok {
+		sc.SetStatusChangeHandler(s.ctx, meta.notifyDriverStatus)
+	}
+	rebinders := cw.retire(oldGen, old, oldCancel)
+	refIds := make([]string, 0, len(rebinders))
+	for refId, rebind := range rebinders {
+		_ = safeCall(meta.ID, "rebind "+refId, func() error {
+			rebind(s.conn)
+			return nil
+		})
+		refIds = append(refIds, refId)
+	}
+	closeStale(ctx, meta.ID, cw.rebound(refIds, oldGen+1))
+}
+
+// stop aborts the connection creation if it is still retrying
+func (cw *ConnWrapper) stop() {
+	cw.l.RLock()
+	cancel := cw.cancel
+	cw.l.RUnlock()
+	if cancel != nil {
+		cancel()
+	}
+}
+
 type Meta struct {
 	ID    string         `json:"id"`
 	Typ   string         `json:"typ"`
@@ -85,23 +295,111 @@ type Meta struct {
 
 	refCount atomic.Int32 `json:"-"`
 	ref      sync.Map     `json:"-"`
-	cw       *ConnWrapper `json:"-"`
+	// attachers are the rule components holding the references, keyed by the ref id
+	attachers sync.Map     `json:"-"`
+	cw        *ConnWrapper `json:"-"`
+	// members are the other physical connections of the pooled connection, and memberRefs is the wrapper handed out
+	// to each reference
+	members    []*ConnWrapper `json:"-"`
+	memberRefs sync.Map       `json:"-"`
 	// The first connection status
 	// If connection is stateful, the status will update all the way
 	// For stateless connection, the status needs to ping
 	status    atomic.Value `json:"-"`
 	lastError atomic.Value `json:"-"`
+	// goroutines is the count of the goroutines started by the pool for the connection
+	goroutines  atomic.Int64 `json:"-"`
+	forceClosed atomic.Bool  `json:"-"`
+	// paused means the transport is closed by the user until resumed
+	paused atomic.Bool `json:"-"`
+	// warming means the dialed connection is running its warm-up hook, so it is not reported connected yet
+	warming atomic.Bool `json:"-"`
+	// endpoints resolves the endpoint specified as a DNS SRV name or an address list, and endpoint is the address
+	// in use
+	endpoints atomic.Pointer[discovery.Resolver] `json:"-"`
+	endpoint  atomic.Value                       `json:"-"`
+	// failover selects the endpoint among the primary and the failover endpoints, and activeEndpoint is the index of
+	// the one in use
+	failover       atomic.Pointer[failoverGroup] `json:"-"`
+	activeEndpoint atomic.Int32                  `json:"-"`
+	failingBack    atomic.Bool                   `json:"-"`
+	// stats are the runtime metrics like the reconnects and the ping latency
+	stats connStats `json:"-"`
+	// lastPing is the status of the last ping, and pinging means a ping of the status API is running
+	lastPing atomic.Pointer[StatusResult] `json:"-"`
+	pinging  atomic.Bool                  `json:"-"`
+	// refAudit is the attaches and detaches recorded in the audit mode
+	refAudit refAuditLog `json:"-"`
+	// opLock serializes the slow operations of the connection like closing and swapping. It can be held when taking
+	// the pool lock, but not the reverse.
+	opLock syncx.Mutex `json:"-"`
+	// ctx is the ownership context of the connection, see ownerContext
+	ctx       api.StreamContext    `json:"-"`
+	cancel    gocontext.CancelFunc `json:"-"`
+	ownerOnce sync.Once            `json:"-"`
+	// m is the pool holding the connection
+	m *ConnectionManager `json:"-"`
+}
+
+// manager returns the pool holding the connection. The connections not put into a pool yet belong to the default one.
+func (meta *Meta) manager() *ConnectionManager {
+	if meta.m != nil {
+		return meta.m
+	}
+	return globalConnectionManager
+}
+
+// ownerContext returns the ownership context of the connection. It is derived from the context of the manager when
+// the first connection is created. All the connections of the meta, including the ones swapped in later, are derived
+// from it, so the creation and the retries are aborted when it is released.
+func (meta *Meta) ownerContext(parent api.StreamContext) api.StreamContext {
+	meta.ownerOnce.Do(func() {
+		meta.ctx, meta.cancel = context.WithLogFields(parent, conf.LogFieldConnection, meta.ID).WithCancel()
+	})
+	return meta.ctx
+}
+
+// release cancels the ownership context once the connection is removed from the pool and closed
+func (meta *Meta) release() {
+	meta.ownerContext(context.Background())
+	meta.cancel()
+}
+
+// notifyDriverStatus is the status handler of the stateful connections. The connected status reported by the driver
+// is ignored while warming up, and it is reported once the warm-up is done.
+func (meta *Meta) notifyDriverStatus(status string, s string) {
+	if status == api.ConnectionConnected && meta.warming.Load() {
+		return
+	}
+	meta.NotifyStatus(status, s)
 }
 
 func (meta *Meta) NotifyStatus(status string, s string) {
-	meta.status.Store(status)
+	prev, _ := meta.status.Swap(status).(string)
+	meta.stats.onStatus(prev, status)
+	if meta.Named {
+		switch {
+		case status == api.ConnectionDisconnected:
+			recordEvent(meta.ID, EventDisconnected, s)
+		case status == api.ConnectionConnected && prev == api.ConnectionDisconnected:
+			recordEvent(meta.ID, EventReconnected, "")
+		}
+	}
+	emitStatus(meta, prev, status, s)
 	if s != "" {
 		meta.lastError.Store(s)
 	}
+	if meta.Named {
+		emitReplica(ReplicaEvent{Type: ReplicaStatus, ID: meta.ID, Status: status, LastError: s})
+	}
 	meta.ref.Range(func(refId, sc any) bool {
 		sch := sc.(api.StatusChangeHandler)
 		if sch != nil {
-			sch(status, s)
+			// a panic of one handler must not stop notifying the others
+			_ = safeCall(meta.ID, "status handler "+refId.(string), func() error {
+				sch(status, s)
+				return nil
+			})
 		}
 		return true
 	})
@@ -114,13 +412,24 @@ func (meta *Meta) AddRef(refId string, sc api.StatusChangeHandler) {
 	}
 	meta.ref.Store(refId, sc)
 	c := meta.refCount.Add(1)
-	conf.Log.Infof("conn %s add reference %s to %d refs", meta.ID, refId, c)
+	connLogger(meta.ID).Infof("conn %s add reference %s to %d refs", meta.ID, refId, c)
 }
 
 func (meta *Meta) DeRef(refId string) {
+	for _, cw := range meta.wrappers() {
+		if unheld := cw.release(refId); len(unheld) > 0 {
+			// it may be called with the pool lock
+			go closeStale(context.Background(), meta.ID, unheld)
+		}
+	}
 	meta.ref.Delete(refId)
+	meta.deleteAttacher(refId)
+	meta.memberRefs.Delete(refId)
 	c := meta.refCount.Add(-1)
-	conf.Log.Infof("conn %s dereference %s to %d refs", meta.ID, refId, c)
+	connLogger(meta.ID).Infof("conn %s dereference %s to %d refs", meta.ID, refId, c)
+	if c < 0 {
+		meta.refAnomaly(fmt.Sprintf("ref count is %d after dereference %s", c, refId))
+	}
 }
 
 func (meta *Meta) GetRefCount() int {
@@ -136,6 +445,9 @@ func (meta *Meta) GetRefNames() (result []string) {
 }
 
 func (meta *Meta) GetStatus() (s string, e string) {
+	if meta.paused.Load() {
+		return ConnectionPaused, ""
+	}
 	ee := meta.lastError.Load()
 	if ee != nil {
 		e = ee.(string)
@@ -152,11 +464,27 @@ func (meta *Meta) GetStatus() (s string, e string) {
 				e = ""
 				// if connected, cw, cw.conn should exist
 				if _, isStateful := conn.(modules.StatefulDialer); !isStateful {
-					err := conn.Ping(context.Background())
+					pingCtx, cancel := withTimeout(context.Background())
+					start := getClock().Now()
+					err := fault.InjectFor(fault.PingConnection, meta.ID)
+					if err == nil {
+						err = safeCall(meta.ID, "ping", func() error {
+							return conn.Ping(pingCtx)
+						})
+					}
+					if err == nil {
+						err = meta.pingMembers(pingCtx)
+					}
+					cancel()
+					meta.stats.onPing(getClock().Since(start), err)
 					if err != nil {
 						s = api.ConnectionDisconnected
 						e = err.Error()
 					}
+					if err != nil && meta.Named {
+						recordEvent(meta.ID, EventPingFailed, e)
+					}
+					meta.lastPing.Store(&StatusResult{Status: s, Err: e})
 				}
 			}
 		}
@@ -166,3 +494,76 @@ func (meta *Meta) GetStatus() (s string, e string) {
 		return
 	}
 }
+
+// withTimeout derives a child context from the caller's context with the deadline of the pool operation
+func withTimeout(ctx api.StreamContext) (api.StreamContext, gocontext.CancelFunc) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	d := time.Duration(GetTuning().OperationTimeout)
+	if dc, ok := ctx.(*context.DefaultContext); ok {
+		return dc.WithTimeout(d)
+	}
+	child, cancel := ctx.WithCancel()
+	t := getClock().AfterFunc(d, cancel)
+	return child, func() {
+		t.Stop()
+		cancel()
+	}
+}
+
+// connLogger returns the logger with the connection id field
+func connLogger(id string) api.Logger {
+	return conf.LogWithFields(conf.Log, conf.LogFieldConnection, id)
+}
+
+var reservedPropKeys = []string{RetryPropKey, FailoverPropKey, LabelsPropKey, TemplatePropKey, PriorityPropKey, DependsOnPropKey, ThrottlePropKey, PoolSizePropKey}
+
+// withoutReservedProps removes the reserved props which are consumed by the pool rather than the connection
+func withoutReservedProps(props map[string]any) map[string]any {
+	found := false
+	for _, k := range reservedPropKeys {
+		if _, ok := props[k]; ok {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return props
+	}
+	result := make(map[string]any, len(props))
+	for k, v := range props {
+		result[k] = v
+	}
+	for _, k := range reservedPropKeys {
+		delete(result, k)
+	}
+	return result
+}
+
+// validateReservedProps checks the reserved props of the connection
+func validateReservedProps(typ string, props map[string]any) error {
+	if _, err := connRetryConf(typ, props); err != nil {
+		return err
+	}
+	if _, err := newFailoverGroup(props); err != nil {
+		return err
+	}
+	labels, err := parseLabels(props)
+	if err != nil {
+		return err
+	}
+	if err := validateLabels(labels); err != nil {
+		return err
+	}
+	if err := validateStartupProps(props); err != nil {
+		return err
+	}
+	if err := validateThrottle(typ, props); err != nil {
+		return err
+	}
+	if _, err := parsePoolSize(props); err != nil {
+		return err
+	}
+	return validateTemplateRecord(props)
+}
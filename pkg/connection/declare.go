@@ -0,0 +1,174 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+	"gopkg.in/yaml.v3"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/pkg/validate"
+)
+
+// DeclaredConnectionsFile is the file alongside kuiper.yaml to declare the named connections
+const DeclaredConnectionsFile = "connections.yaml"
+
+// Declaration is the desired state of the named connections. The connections are keyed by type and then id which is
+// the same layout as the config store.
+//
+//	prune: false
+//	connections:
+//	  mqtt:
+//	    broker1:
+//	      server: tcp://127.0.0.1:1883
+type Declaration struct {
+	// Prune drops the named connections which are not declared
+	Prune       bool                                 `json:"prune,omitempty" yaml:"prune,omitempty"`
+	Connections map[string]map[string]map[string]any `json:"connections" yaml:"connections"`
+}
+
+// ReconcileReport is the result of the reconciliation by connection id. Skipped are the existing connections not
+// overwritten by the import.
+type ReconcileReport struct {
+	Created   []string          `json:"created"`
+	Updated   []string          `json:"updated"`
+	Pruned    []string          `json:"pruned"`
+	Unchanged []string          `json:"unchanged"`
+	Skipped   []string          `json:"skipped,omitempty"`
+	Failed    map[string]string `json:"failed"`
+}
+
+// LoadDeclaration reads the declaration file. It returns nil if the file does not exist.
+func LoadDeclaration(path string) (*Declaration, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	d := &Declaration{}
+	if err := yaml.Unmarshal(content, d); err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", path, err)
+	}
+	return d, nil
+}
+
+// Reconcile makes the named connections match the declaration. The missing connections are created and the drifted
+// ones are recreated. The undeclared ones are dropped only if prune is set. The connections referenced by rules are
+// never changed.
+func Reconcile(ctx api.StreamContext, d *Declaration) *ReconcileReport {
+	report := &ReconcileReport{Failed: make(map[string]string)}
+	globalConnectionManager.Lock()
+	defer globalConnectionManager.Unlock()
+	declared := make(map[string]struct{})
+	for typ, conns := range d.Connections {
+		for id, props := range conns {
+			if _, ok := declared[id]; ok {
+				report.Failed[id] = fmt.Sprintf("connection %s is declared more than once", id)
+				continue
+			}
+			declared[id] = struct{}{}
+			applyDeclared(ctx, id, typ, props, report)
+		}
+	}
+	if d.Prune {
+		for id, meta := range globalConnectionManager.connectionPool {
+			if _, ok := declared[id]; ok || !meta.Named {
+				continue
+			}
+			if err := globalConnectionManager.dropAndRecord(ctx, id, 0); err != nil {
+				report.Failed[id] = err.Error()
+			} else {
+				report.Pruned = append(report.Pruned, id)
+			}
+		}
+	}
+	report.sort()
+	conf.Log.Infof("declared connections reconciled, %d created, %d updated, %d pruned, %d unchanged, %d failed",
+		len(report.Created), len(report.Updated), len(report.Pruned), len(report.Unchanged), len(report.Failed))
+	return report
+}
+
+// applyDeclared creates the connection or recreates it if it is drifted. It must be called with the pool lock.
+func applyDeclared(ctx api.StreamContext, id, typ string, props map[string]any, report *ReconcileReport) {
+	if err := validate.ValidateID(id); err != nil {
+		report.Failed[id] = err.Error()
+		return
+	}
+	meta, ok := globalConnectionManager.connectionPool[id]
+	if !ok {
+		if _, err := globalConnectionManager.createNamedConnection(ctx, id, typ, props); err != nil {
+			report.Failed[id] = err.Error()
+		} else {
+			recordEvent(id, EventCreated, "")
+			report.Created = append(report.Created, id)
+		}
+		return
+	}
+	if !meta.Named {
+		report.Failed[id] = fmt.Sprintf("connection %s is an anonymous connection of rules", id)
+		return
+	}
+	if meta.Typ == typ && propsEqual(meta.Props, props) {
+		report.Unchanged = append(report.Unchanged, id)
+		return
+	}
+	if err := globalConnectionManager.checkConnectionQuota(id, typ); err != nil {
+		report.Failed[id] = err.Error()
+		return
+	}
+	if err := globalConnectionManager.dropNameConnection(ctx, id); err != nil {
+		report.Failed[id] = err.Error()
+		return
+	}
+	if _, err := globalConnectionManager.createNamedConnection(ctx, id, typ, props); err != nil {
+		report.Failed[id] = err.Error()
+	} else {
+		recordEvent(id, EventUpdated, "")
+		report.Updated = append(report.Updated, id)
+	}
+}
+
+func (r *ReconcileReport) sort() {
+	sort.Strings(r.Created)
+	sort.Strings(r.Updated)
+	sort.Strings(r.Pruned)
+	sort.Strings(r.Unchanged)
+	sort.Strings(r.Skipped)
+}
+
+// propsEqual compares the props by their json form since the numbers may be decoded in different types from yaml
+// and the store
+func propsEqual(a, b map[string]any) bool {
+	if len(a) == 0 && len(b) == 0 {
+		return true
+	}
+	ja, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	jb, err := json.Marshal(b)
+	if err != nil {
+		return false
+	}
+	return string(ja) == string(jb)
+}
@@ -0,0 +1,101 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/topo/context"
+)
+
+func TestLoadDeclaration(t *testing.T) {
+	dir := t.TempDir()
+	d, err := LoadDeclaration(filepath.Join(dir, DeclaredConnectionsFile))
+	require.NoError(t, err)
+	require.Nil(t, d)
+	p := filepath.Join(dir, DeclaredConnectionsFile)
+	require.NoError(t, os.WriteFile(p, []byte(`
+prune: true
+connections:
+  mock:
+    decl1:
+      server: tcp://127.0.0.1:1883
+      qos: 1
+`), 0o644))
+	d, err = LoadDeclaration(p)
+	require.NoError(t, err)
+	require.Equal(t, &Declaration{
+		Prune: true,
+		Connections: map[string]map[string]map[string]any{
+			"mock": {"decl1": {"server": "tcp://127.0.0.1:1883", "qos": 1}},
+		},
+	}, d)
+	require.NoError(t, os.WriteFile(p, []byte("connections: [1"), 0o644))
+	_, err = LoadDeclaration(p)
+	require.Error(t, err)
+}
+
+func TestReconcile(t *testing.T) {
+	require.NoError(t, InitConnectionManager4Test())
+	ctx := context.Background()
+	_, err := CreateNamedConnection(ctx, "drift", "mock", map[string]any{"qos": 0})
+	require.NoError(t, err)
+	_, err = CreateNamedConnection(ctx, "same", "mock", map[string]any{"qos": int64(1)})
+	require.NoError(t, err)
+	_, err = CreateNamedConnection(ctx, "extra", "mock", nil)
+	require.NoError(t, err)
+	_, err = CreateNamedConnection(ctx, "used", "mock", nil)
+	require.NoError(t, err)
+	_, err = globalConnectionManager.attachConnection("used", "rule1", nil)
+	require.NoError(t, err)
+
+	d := &Declaration{
+		Connections: map[string]map[string]map[string]any{
+			"mock": {
+				"new":   {"qos": 1},
+				"drift": {"qos": 1},
+				"same":  {"qos": 1},
+				"inv/d": nil,
+			},
+		},
+	}
+	report := Reconcile(ctx, d)
+	require.Equal(t, []string{"new"}, report.Created)
+	require.Equal(t, []string{"drift"}, report.Updated)
+	require.Equal(t, []string{"same"}, report.Unchanged)
+	require.Empty(t, report.Pruned)
+	require.Contains(t, report.Failed, "inv/d")
+	m, err := GetConnectionDetail(ctx, "drift")
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"qos": 1}, m.Props)
+
+	// prune the undeclared ones except the referenced one
+	d.Prune = true
+	report = Reconcile(ctx, d)
+	require.Equal(t, []string{"drift", "new", "same"}, report.Unchanged)
+	require.Equal(t, []string{"extra"}, report.Pruned)
+	require.Contains(t, report.Failed, "used")
+	_, err = GetConnectionDetail(ctx, "extra")
+	require.Error(t, err)
+
+	require.NoError(t, globalConnectionManager.detachConnection(ctx, "used"))
+	for _, id := range []string{"new", "drift", "same", "used"} {
+		require.NoError(t, DropNameConnection(ctx, id))
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+)
+
+// The anonymous connections of the rules with the identical type and props can share one physical connection, like
+// a named connection, to save the broker sessions. The shared connection has the id derived from the content hash
+// and is dropped once the last rule detaches. Each rule detaches by its own ref id, so the shared id attached by the
+// reference is recorded in the manager.
+
+// dedupPrefix is the prefix of the ids of the shared anonymous connections
+const dedupPrefix = "$dedup_"
+
+func dedupEnabled() bool {
+	return conf.Config != nil && conf.Config.Connection.DedupAnonymous
+}
+
+// dedupId returns the id of the shared anonymous connection for the type and the props. The props which can't be
+// hashed are not shared.
+func dedupId(typ string, props map[string]any) (string, bool) {
+	if !dedupEnabled() {
+		return "", false
+	}
+	// the map keys are sorted by json
+	b, err := json.Marshal(props)
+	if err != nil {
+		return "", false
+	}
+	h := sha256.New()
+	h.Write([]byte(typ))
+	h.Write([]byte{0})
+	h.Write(b)
+	return dedupPrefix + typ + "_" + hex.EncodeToString(h.Sum(nil)[:8]), true
+}
+
+func (m *ConnectionManager) recordDedupAttach(refId, id string) {
+	m.dedupLock.Lock()
+	defer m.dedupLock.Unlock()
+	m.dedupRefs[refId] = id
+}
+
+// resolveDedup returns the shared connection attached by the reference of the anonymous connection
+func (m *ConnectionManager) resolveDedup(id, refId string) string {
+	if id != refId {
+		return id
+	}
+	m.dedupLock.Lock()
+	defer m.dedupLock.Unlock()
+	if shared, ok := m.dedupRefs[refId]; ok {
+		delete(m.dedupRefs, refId)
+		return shared
+	}
+	return id
+}
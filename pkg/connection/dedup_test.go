@@ -0,0 +1,65 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	mockContext "github.com/lf-edge/ekuiper/v2/pkg/mock/context"
+)
+
+func TestDedupAnonymous(t *testing.T) {
+	require.NoError(t, InitConnectionManager4Test())
+	conf.InitConf()
+	conf.Config.Connection.DedupAnonymous = true
+	defer func() {
+		conf.Config.Connection.DedupAnonymous = false
+	}()
+	ctx1 := mockContext.NewMockContext("dedup1", "op1")
+	ctx2 := mockContext.NewMockContext("dedup2", "op1")
+	ctx3 := mockContext.NewMockContext("dedup3", "op1")
+	props := map[string]any{"server": "tcp://127.0.0.1:1883", "qos": 1}
+	cw1, err := FetchConnection(ctx1, extractRefId(ctx1), "mock", props, nil)
+	require.NoError(t, err)
+	cw2, err := FetchConnection(ctx2, extractRefId(ctx2), "mock", map[string]any{"qos": 1, "server": "tcp://127.0.0.1:1883"}, nil)
+	require.NoError(t, err)
+	require.Same(t, cw1, cw2)
+	cw3, err := FetchConnection(ctx3, extractRefId(ctx3), "mock", map[string]any{"server": "tcp://127.0.0.1:1884"}, nil)
+	require.NoError(t, err)
+	require.NotSame(t, cw1, cw3)
+
+	id, ok := dedupId("mock", props)
+	require.True(t, ok)
+	require.Equal(t, 2, globalConnectionManager.getConnectionRef(id))
+	_, ok = globalConnectionManager.load()[extractRefId(ctx1)]
+	require.False(t, ok)
+
+	// each rule detaches by its own ref id
+	require.NoError(t, DetachConnection(ctx1, extractRefId(ctx1)))
+	require.Equal(t, 1, globalConnectionManager.getConnectionRef(id))
+	require.NoError(t, DetachConnection(ctx2, extractRefId(ctx2)))
+	_, ok = globalConnectionManager.load()[id]
+	require.False(t, ok)
+	require.NoError(t, DetachConnection(ctx3, extractRefId(ctx3)))
+	require.Empty(t, globalConnectionManager.dedupRefs)
+
+	// disabled
+	conf.Config.Connection.DedupAnonymous = false
+	_, ok = dedupId("mock", props)
+	require.False(t, ok)
+}
@@ -0,0 +1,136 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"context"
+	"maps"
+	"strings"
+	"time"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/discovery"
+	"github.com/lf-edge/ekuiper/v2/pkg/syncx"
+)
+
+const (
+	defaultDiscoveryRefresh  = 30 * time.Second
+	defaultDiscoveryCooldown = 30 * time.Second
+)
+
+var (
+	// endpointProps is the prop holding the endpoint of each connection type, which can be specified as a DNS SRV
+	// name or an address list
+	endpointProps = map[string]string{
+		"mqtt":      "server",
+		"websocket": "addr",
+		"nng":       "url",
+	}
+	endpointPropsLock syncx.RWMutex
+)
+
+// RegisterEndpointProp registers the prop holding the endpoint of the connection type so that the endpoint can be
+// discovered by DNS SRV or selected from an address list
+func RegisterEndpointProp(typ, prop string) {
+	endpointPropsLock.Lock()
+	defer endpointPropsLock.Unlock()
+	endpointProps[strings.ToLower(typ)] = prop
+}
+
+func endpointPropOf(typ string) string {
+	endpointPropsLock.RLock()
+	defer endpointPropsLock.RUnlock()
+	return endpointProps[strings.ToLower(typ)]
+}
+
+func discoveryIntervals() (refresh, cooldown time.Duration) {
+	refresh, cooldown = defaultDiscoveryRefresh, defaultDiscoveryCooldown
+	if conf.Config != nil {
+		if conf.Config.Discovery.RefreshInterval > 0 {
+			refresh = time.Duration(conf.Config.Discovery.RefreshInterval)
+		}
+		if conf.Config.Discovery.FailureCooldown > 0 {
+			cooldown = time.Duration(conf.Config.Discovery.FailureCooldown)
+		}
+	}
+	return
+}
+
+// newEndpointResolver resolves the endpoint of the connection. It returns nil if the endpoint is not discoverable.
+func newEndpointResolver(typ string, props map[string]any) (*discovery.Resolver, string, error) {
+	prop := endpointPropOf(typ)
+	if prop == "" {
+		return nil, "", nil
+	}
+	spec, _ := props[prop].(string)
+	if !discovery.IsDiscoverable(spec) {
+		return nil, "", nil
+	}
+	_, cooldown := discoveryIntervals()
+	r, err := discovery.NewResolver(spec, cooldown)
+	if err != nil {
+		return nil, "", err
+	}
+	return r, prop, nil
+}
+
+// withEndpoint copies the props with the endpoint replaced by the selected address
+func withEndpoint(props map[string]any, prop, addr string) map[string]any {
+	result := maps.Clone(props)
+	result[prop] = addr
+	return result
+}
+
+// resolveEndpointProps replaces the discoverable endpoint by the first address for the one-off usages like probing
+func resolveEndpointProps(typ string, props map[string]any) (map[string]any, error) {
+	r, prop, err := newEndpointResolver(typ, props)
+	if err != nil || r == nil {
+		return props, err
+	}
+	return withEndpoint(props, prop, r.Select()), nil
+}
+
+// rediscoverEndpoints re-resolves the discoverable endpoints periodically. The changes apply to the next dial.
+func rediscoverEndpoints(ctx context.Context) {
+	refresh, _ := discoveryIntervals()
+	ticker := getClock().Ticker(refresh)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, meta := range globalConnectionManager.load() {
+				r := meta.endpoints.Load()
+				if r == nil {
+					continue
+				}
+				changed, err := r.Refresh()
+				if err != nil {
+					connLogger(meta.ID).Warnf("rediscover endpoint of connection %s error: %v", meta.ID, err)
+					continue
+				}
+				if !changed {
+					continue
+				}
+				addr, _ := meta.endpoint.Load().(string)
+				connLogger(meta.ID).Infof("endpoint of connection %s changes to %v", meta.ID, r.Addrs())
+				if addr != "" && !r.Contains(addr) {
+					connLogger(meta.ID).Warnf("address %s of connection %s is not discovered anymore", addr, meta.ID)
+				}
+			}
+		}
+	}
+}
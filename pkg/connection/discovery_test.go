@@ -0,0 +1,86 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/context"
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+	"github.com/lf-edge/ekuiper/v2/pkg/modules"
+)
+
+// endpointConnection only dials successfully to the server "good"
+type endpointConnection struct {
+	mockConnection
+	server string
+}
+
+func (e *endpointConnection) Provision(_ api.StreamContext, _ string, props map[string]any) error {
+	e.server, _ = props["server"].(string)
+	return nil
+}
+
+func (e *endpointConnection) Dial(_ api.StreamContext) error {
+	if e.server != "good" {
+		return errorx.NewIOErr("unreachable " + e.server)
+	}
+	return nil
+}
+
+func TestEndpointDiscovery(t *testing.T) {
+	require.NoError(t, InitConnectionManager4Test())
+	conf.InitConf()
+	require.NoError(t, modules.RegisterConnectionType("endpointconn", func(_ api.StreamContext) modules.Connection {
+		return &endpointConnection{}
+	}))
+	defer modules.UnregisterConnection("endpointconn")
+	RegisterEndpointProp("endpointconn", "server")
+	defer func() {
+		endpointPropsLock.Lock()
+		delete(endpointProps, "endpointconn")
+		endpointPropsLock.Unlock()
+	}()
+
+	// not discoverable
+	props := map[string]any{"server": "bad"}
+	p, err := resolveEndpointProps("endpointconn", props)
+	require.NoError(t, err)
+	require.Equal(t, props, p)
+	p, err = resolveEndpointProps("endpointconn", map[string]any{"server": "bad,good"})
+	require.NoError(t, err)
+	require.Equal(t, "bad", p["server"])
+
+	// switch to the healthy address after the first one fails
+	ctx := context.Background()
+	cw, err := CreateNamedConnection(ctx, "ep1", "endpointconn", map[string]any{"server": "bad,good"})
+	require.NoError(t, err)
+	waitCtx, cancel := ctx.WithTimeout(5 * time.Second)
+	defer cancel()
+	conn, err := cw.Wait(waitCtx)
+	require.NoError(t, err)
+	require.Equal(t, "good", conn.(*endpointConnection).server)
+	meta, err := GetConnectionDetail(ctx, "ep1")
+	require.NoError(t, err)
+	require.Equal(t, "good", meta.endpoint.Load())
+	// the original props are not changed
+	require.Equal(t, "bad,good", meta.Props["server"])
+	require.NoError(t, DropNameConnection(ctx, "ep1"))
+}
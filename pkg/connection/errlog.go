@@ -0,0 +1,127 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/pkg/syncx"
+)
+
+// The recurring errors of a flapping connection are throttled. The first occurrence of an error is logged at once,
+// and its repeats within the interval are counted and logged as a summary line once the interval expires. The log
+// lines carry the connection, the type, the operation and the attempt as structured fields.
+
+const defaultErrorLogInterval = time.Minute
+
+// The structured log fields of the connection errors
+const (
+	logFieldType    = "typ"
+	logFieldOp      = "op"
+	logFieldAttempt = "attempt"
+	logFieldError   = "error"
+	logFieldRepeats = "repeats"
+	logFieldFirst   = "first"
+	logFieldLast    = "last"
+)
+
+type errorLogKey struct {
+	id  string
+	op  string
+	msg string
+}
+
+type errorLogEntry struct {
+	typ     string
+	level   logrus.Level
+	attempt int
+	// repeats are the occurrences suppressed since the first one
+	repeats int
+	first   time.Time
+	last    time.Time
+}
+
+var errorLogs = struct {
+	syncx.Mutex
+	entries map[errorLogKey]*errorLogEntry
+}{entries: make(map[errorLogKey]*errorLogEntry)}
+
+func errorLogInterval() time.Duration {
+	if conf.Config != nil && conf.Config.Connection.ErrorLogInterval > 0 {
+		return time.Duration(conf.Config.Connection.ErrorLogInterval)
+	}
+	return defaultErrorLogInterval
+}
+
+// logConnError logs the failed operation of the connection at the level unless the same error is logged within the
+// interval. The delay of the next attempt is logged if positive.
+func logConnError(meta *Meta, level logrus.Level, op string, attempt int, retry time.Duration, err error) {
+	now := getClock().Now()
+	key := errorLogKey{id: meta.ID, op: op, msg: err.Error()}
+	errorLogs.Lock()
+	e, ok := errorLogs.entries[key]
+	if ok && now.Sub(e.first) < errorLogInterval() {
+		e.repeats++
+		e.attempt = attempt
+		e.last = now
+		errorLogs.Unlock()
+		return
+	}
+	if ok {
+		summarizeErrorLog(key, e)
+	}
+	errorLogs.entries[key] = &errorLogEntry{typ: meta.Typ, level: level, attempt: attempt, first: now, last: now}
+	errorLogs.Unlock()
+	entry := errorLogFields(key, meta.Typ, attempt)
+	if retry > 0 {
+		entry.Logf(level, "connection %s failed, retry in %v", op, retry)
+	} else {
+		entry.Log(level, "connection "+op+" failed")
+	}
+}
+
+// flushErrorLogs logs the summaries of the errors whose interval expired and forgets them. It runs in the patrol job.
+func flushErrorLogs() {
+	now := getClock().Now()
+	interval := errorLogInterval()
+	errorLogs.Lock()
+	defer errorLogs.Unlock()
+	for key, e := range errorLogs.entries {
+		if now.Sub(e.first) < interval {
+			continue
+		}
+		summarizeErrorLog(key, e)
+		delete(errorLogs.entries, key)
+	}
+}
+
+func summarizeErrorLog(key errorLogKey, e *errorLogEntry) {
+	if e.repeats == 0 {
+		return
+	}
+	errorLogFields(key, e.typ, e.attempt).WithFields(logrus.Fields{
+		logFieldRepeats: e.repeats,
+		logFieldFirst:   e.first.Format(time.RFC3339),
+		logFieldLast:    e.last.Format(time.RFC3339),
+	}).Log(e.level, "connection "+key.op+" failed repeatedly")
+}
+
+func errorLogFields(key errorLogKey, typ string, attempt int) *logrus.Entry {
+	return conf.LogWithFields(conf.Log, conf.LogFieldConnection, key.id, logFieldType, typ, logFieldOp, key.op,
+		logFieldAttempt, attempt, logFieldError, key.msg)
+}
@@ -0,0 +1,120 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+)
+
+func TestLogConnError(t *testing.T) {
+	mock := clock.NewMock()
+	SetClock(mock)
+	defer SetClock(nil)
+	out, formatter, level := conf.Log.Out, conf.Log.Formatter, conf.Log.GetLevel()
+	defer func() {
+		conf.Log.SetOutput(out)
+		conf.Log.SetFormatter(formatter)
+		conf.Log.SetLevel(level)
+	}()
+	// drop the entries left by the other tests
+	errorLogs.Lock()
+	errorLogs.entries = make(map[errorLogKey]*errorLogEntry)
+	errorLogs.Unlock()
+	conf.Log.SetLevel(logrus.DebugLevel)
+	var buf bytes.Buffer
+	conf.Log.SetOutput(&buf)
+	conf.SetLogFormat(conf.LogFormatJSON, true)
+	lines := func() []map[string]any {
+		var result []map[string]any
+		for _, l := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			if l == "" {
+				continue
+			}
+			m := map[string]any{}
+			require.NoError(t, json.Unmarshal([]byte(l), &m))
+			// ignore the logs of the other connections
+			if m[conf.LogFieldConnection] == "errlog1" {
+				result = append(result, m)
+			}
+		}
+		buf.Reset()
+		return result
+	}
+	meta := &Meta{ID: "errlog1", Typ: "mqtt"}
+	err := errors.New("connection refused")
+
+	// the first occurrence is logged with the fields and the repeats are suppressed
+	for i := 1; i <= 3; i++ {
+		logConnError(meta, logrus.DebugLevel, "dial", i, 0, err)
+		mock.Add(time.Second)
+	}
+	logs := lines()
+	require.Len(t, logs, 1)
+	require.Equal(t, "errlog1", logs[0][conf.LogFieldConnection])
+	require.Equal(t, "mqtt", logs[0]["typ"])
+	require.Equal(t, "dial", logs[0]["op"])
+	require.Equal(t, 1.0, logs[0]["attempt"])
+	require.Equal(t, "connection refused", logs[0]["error"])
+	require.Equal(t, "connection dial failed", logs[0]["msg"])
+	require.Equal(t, "debug", logs[0]["level"])
+
+	// another error is logged at once
+	logConnError(meta, logrus.DebugLevel, "dial", 4, 0, errors.New("timeout"))
+	require.Len(t, lines(), 1)
+
+	// the summary of the repeats is logged once the interval expires
+	mock.Add(time.Minute)
+	flushErrorLogs()
+	logs = lines()
+	require.Len(t, logs, 1)
+	require.Equal(t, "connection dial failed repeatedly", logs[0]["msg"])
+	require.Equal(t, "debug", logs[0]["level"])
+	require.Equal(t, 2.0, logs[0]["repeats"])
+	require.Equal(t, 3.0, logs[0]["attempt"])
+	var keys []errorLogKey
+	errorLogs.Lock()
+	for key := range errorLogs.entries {
+		if key.id == "errlog1" {
+			keys = append(keys, key)
+		}
+	}
+	errorLogs.Unlock()
+	require.Empty(t, keys)
+
+	// logged again after the interval
+	logConnError(meta, logrus.DebugLevel, "dial", 5, 0, err)
+	require.Len(t, lines(), 1)
+	mock.Add(time.Minute)
+	flushErrorLogs()
+	require.Empty(t, lines())
+
+	// the reconnection is logged with the delay of the next attempt
+	logConnError(meta, logrus.WarnLevel, "reconnect", 1, 2*time.Second, err)
+	logs = lines()
+	require.Len(t, logs, 1)
+	require.Equal(t, "connection reconnect failed, retry in 2s", logs[0]["msg"])
+	require.Equal(t, "warning", logs[0]["level"])
+}
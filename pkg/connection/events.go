@@ -0,0 +1,162 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"fmt"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+	"github.com/lf-edge/ekuiper/v2/pkg/syncx"
+)
+
+// The event history keeps the latest lifecycle events of each named connection in a ring buffer persisted in the
+// storage, so that what happened to the connection can be reconstructed after the fact, even after it is dropped or
+// the server restarts.
+
+const (
+	EventCreated         = "created"
+	EventUpdated         = "updated"
+	EventDropped         = "dropped"
+	EventDisconnected    = "disconnected"
+	EventPingFailed      = "pingFailed"
+	EventReconnected     = "reconnected"
+	EventPaused          = "paused"
+	EventResumed         = "resumed"
+	EventCertRotated     = "certRotated"
+	EventReconnectGaveUp = "reconnectGaveUp"
+)
+
+// eventCfgType is the config type of the event history. It must not have the prefix "connections" so that the
+// history is not loaded as connections.
+const eventCfgType = "connEvents"
+
+// ConnectionEvent is a lifecycle event of the connection. The consecutive events of the same type and error are
+// collapsed, Repeated is the count of the collapsed ones and LastTime is the time of the latest one.
+type ConnectionEvent struct {
+	Type string `json:"type"`
+	// Time and LastTime are unix milliseconds
+	Time     int64  `json:"time"`
+	Err      string `json:"err,omitempty"`
+	Repeated int    `json:"repeated,omitempty"`
+	LastTime int64  `json:"lastTime,omitempty"`
+}
+
+func (e ConnectionEvent) toMap() map[string]any {
+	m := map[string]any{"type": e.Type, "time": e.Time}
+	if e.Err != "" {
+		m["err"] = e.Err
+	}
+	if e.Repeated > 0 {
+		m["repeated"] = e.Repeated
+		m["lastTime"] = e.LastTime
+	}
+	return m
+}
+
+type storedEvents struct {
+	Events []ConnectionEvent `json:"events"`
+}
+
+var eventHistory = struct {
+	syncx.Mutex
+	// cache is the history loaded from the storage by the connection id
+	cache map[string][]ConnectionEvent
+}{cache: make(map[string][]ConnectionEvent)}
+
+func eventHistorySize() int {
+	if conf.Config == nil {
+		return 0
+	}
+	return conf.Config.Connection.EventHistorySize
+}
+
+// loadEvents returns the history of the connection. It must be called with the history lock.
+func loadEvents(id string) ([]ConnectionEvent, error) {
+	if events, ok := eventHistory.cache[id]; ok {
+		return events, nil
+	}
+	cfgs, err := conf.GetCfgFromKVStorage(eventCfgType, id, "")
+	if err != nil {
+		return nil, err
+	}
+	var events []ConnectionEvent
+	if v, ok := cfgs[fmt.Sprintf("%s.%s", eventCfgType, id)]; ok {
+		s := &storedEvents{}
+		if err := cast.MapToStruct(v, s); err != nil {
+			return nil, err
+		}
+		events = s.Events
+	}
+	eventHistory.cache[id] = events
+	return events, nil
+}
+
+// recordEvent appends the event into the history of the named connection. The failure is logged and does not fail
+// the operation.
+func recordEvent(id, typ, errMsg string) {
+	size := eventHistorySize()
+	if size <= 0 {
+		return
+	}
+	now := getClock().Now().UnixMilli()
+	eventHistory.Lock()
+	defer eventHistory.Unlock()
+	events, err := loadEvents(id)
+	if err != nil {
+		connLogger(id).Warnf("load the event history of connection %s error: %v", id, err)
+		return
+	}
+	if n := len(events); n > 0 && events[n-1].Type == typ && events[n-1].Err == errMsg {
+		last := events[n-1]
+		last.Repeated++
+		last.LastTime = now
+		events = append(events[:n-1:n-1], last)
+	} else {
+		events = append(events, ConnectionEvent{Type: typ, Time: now, Err: errMsg})
+	}
+	if len(events) > size {
+		events = events[len(events)-size:]
+	}
+	eventHistory.cache[id] = events
+	list := make([]any, 0, len(events))
+	for _, e := range events {
+		list = append(list, e.toMap())
+	}
+	if err := conf.WriteCfgIntoKVStorage(eventCfgType, id, "", map[string]any{"events": list}); err != nil {
+		connLogger(id).Warnf("save the event history of connection %s error: %v", id, err)
+	}
+}
+
+// GetConnectionEvents returns the latest events of the connection in the order of time. 0 limit means all the events
+// in the history. The history of a dropped connection is still available.
+func GetConnectionEvents(id string, limit int) ([]ConnectionEvent, error) {
+	eventHistory.Lock()
+	events, err := loadEvents(id)
+	eventHistory.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if len(events) == 0 {
+		if _, ok := globalConnectionManager.load()[id]; !ok {
+			return nil, errorx.NewWithCode(errorx.NOT_FOUND, fmt.Sprintf("connection %s not existed", id))
+		}
+	}
+	if limit > 0 && len(events) > limit {
+		events = events[len(events)-limit:]
+	}
+	return append(make([]ConnectionEvent, 0, len(events)), events...), nil
+}
@@ -0,0 +1,97 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/context"
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+)
+
+func eventTypes(events []ConnectionEvent) []string {
+	types := make([]string, 0, len(events))
+	for _, e := range events {
+		types = append(types, e.Type)
+	}
+	return types
+}
+
+func TestConnectionEvents(t *testing.T) {
+	conf.InitConf()
+	origin := conf.Config.Connection.EventHistorySize
+	defer func() {
+		conf.Config.Connection.EventHistorySize = origin
+	}()
+	conf.Config.Connection.EventHistorySize = 4
+	require.NoError(t, InitConnectionManager4Test())
+	ctx := context.Background()
+
+	_, err := GetConnectionEvents("evt1", 0)
+	code, _ := errorx.GetErrorCode(err)
+	require.Equal(t, errorx.NOT_FOUND, code)
+
+	_, err = CreateNamedConnection(ctx, "evt1", "mock", map[string]any{"a": 1})
+	require.NoError(t, err)
+	meta, err := GetConnectionDetail(ctx, "evt1")
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		s, _ := meta.status.Load().(string)
+		return s == api.ConnectionConnected
+	}, time.Second, 10*time.Millisecond)
+	meta.NotifyStatus(api.ConnectionDisconnected, "broken")
+	meta.NotifyStatus(api.ConnectionDisconnected, "broken")
+	meta.NotifyStatus(api.ConnectionConnected, "")
+	events, err := GetConnectionEvents("evt1", 0)
+	require.NoError(t, err)
+	require.Equal(t, []string{EventCreated, EventDisconnected, EventReconnected}, eventTypes(events))
+	require.Equal(t, "broken", events[1].Err)
+	require.Equal(t, 1, events[1].Repeated)
+	require.NotZero(t, events[1].LastTime)
+
+	_, err = UpdateConnection(ctx, "evt1", "mock", map[string]any{"a": 2})
+	require.NoError(t, err)
+	require.NoError(t, DropNameConnectionPermanently(ctx, "evt1"))
+	// the oldest is evicted and the history is kept after the drop
+	events, err = GetConnectionEvents("evt1", 0)
+	require.NoError(t, err)
+	require.Equal(t, []string{EventDisconnected, EventReconnected, EventUpdated, EventDropped}, eventTypes(events))
+	events, err = GetConnectionEvents("evt1", 2)
+	require.NoError(t, err)
+	require.Equal(t, []string{EventUpdated, EventDropped}, eventTypes(events))
+
+	// loaded from the storage
+	eventHistory.Lock()
+	delete(eventHistory.cache, "evt1")
+	eventHistory.Unlock()
+	events, err = GetConnectionEvents("evt1", 0)
+	require.NoError(t, err)
+	require.Equal(t, []string{EventDisconnected, EventReconnected, EventUpdated, EventDropped}, eventTypes(events))
+	require.Equal(t, 1, events[0].Repeated)
+
+	// disabled
+	conf.Config.Connection.EventHistorySize = 0
+	_, err = CreateNamedConnection(ctx, "evt2", "mock", nil)
+	require.NoError(t, err)
+	events, err = GetConnectionEvents("evt2", 0)
+	require.NoError(t, err)
+	require.Empty(t, events)
+	require.NoError(t, DropNameConnectionPermanently(ctx, "evt2"))
+}
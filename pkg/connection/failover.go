@@ -0,0 +1,151 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"fmt"
+	"maps"
+	"time"
+
+	"github.com/lf-edge/ekuiper/v2/internal/topo/context"
+	"github.com/lf-edge/ekuiper/v2/pkg/syncx"
+)
+
+// FailoverPropKey is the reserved prop listing the secondary endpoints of the connection in the order of preference.
+// Each of them is a prop-set overlaid on the other props, which are the primary endpoint. For example,
+// {"server": "tcp://primary:1883", "$failover": [{"server": "tcp://secondary:1883"}]}.
+const FailoverPropKey = "$failover"
+
+// failoverGroup selects the endpoint to connect among the primary and the secondary ones. The endpoints which failed
+// recently are skipped until their cooldown passes, so that the connection fails over to the next one.
+type failoverGroup struct {
+	endpoints []map[string]any
+	cooldown  time.Duration
+
+	mu     syncx.Mutex
+	failed map[int]time.Time
+}
+
+// newFailoverGroup parses the failover endpoints of the props. It returns nil if there is no failover endpoint.
+func newFailoverGroup(props map[string]any) (*failoverGroup, error) {
+	v, ok := props[FailoverPropKey]
+	if !ok || v == nil {
+		return nil, nil
+	}
+	list, ok := v.([]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid %s: must be a list of props", FailoverPropKey)
+	}
+	if len(list) == 0 {
+		return nil, nil
+	}
+	primary := withoutReservedProps(props)
+	endpoints := make([]map[string]any, 0, len(list)+1)
+	endpoints = append(endpoints, primary)
+	for i, e := range list {
+		overlay, ok := e.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("invalid %s: endpoint %d must be props", FailoverPropKey, i+1)
+		}
+		if _, ok := overlay[FailoverPropKey]; ok {
+			return nil, fmt.Errorf("invalid %s: endpoint %d must not be nested", FailoverPropKey, i+1)
+		}
+		endpoint := maps.Clone(primary)
+		maps.Copy(endpoint, overlay)
+		endpoints = append(endpoints, endpoint)
+	}
+	_, cooldown := discoveryIntervals()
+	return &failoverGroup{endpoints: endpoints, cooldown: cooldown, failed: make(map[int]time.Time)}, nil
+}
+
+// props returns the props of the endpoint, 0 is the primary
+func (g *failoverGroup) props(i int) map[string]any {
+	return g.endpoints[i]
+}
+
+// selectEndpoint returns the first endpoint not in the failure cooldown. If all of them failed, the one whose
+// cooldown ends first is returned.
+func (g *failoverGroup) selectEndpoint() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	now := getClock().Now()
+	fallback := -1
+	var earliest time.Time
+	for i := range g.endpoints {
+		until, ok := g.failed[i]
+		if !ok || !now.Before(until) {
+			return i
+		}
+		if fallback < 0 || until.Before(earliest) {
+			fallback, earliest = i, until
+		}
+	}
+	return fallback
+}
+
+func (g *failoverGroup) markFailed(i int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.failed[i] = getClock().Now().Add(g.cooldown)
+}
+
+func (g *failoverGroup) markHealthy(i int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.failed, i)
+}
+
+// ActiveEndpoint returns the index of the failover endpoint in use, 0 is the primary and 1 is the first one in the
+// failover list. The bool is false if the connection has no failover endpoints.
+func (meta *Meta) ActiveEndpoint() (int, bool) {
+	if meta.failover.Load() == nil {
+		return 0, false
+	}
+	return int(meta.activeEndpoint.Load()), true
+}
+
+// failBack switches the connection back to the primary endpoint if it is connected to a secondary one and the
+// primary recovers. It runs in the patrol job.
+func failBack(meta *Meta) {
+	if IsStandby() || meta.failover.Load() == nil || meta.activeEndpoint.Load() == 0 {
+		return
+	}
+	if !meta.failingBack.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		defer meta.failingBack.Store(false)
+		ctx := context.Background()
+		g := meta.failover.Load()
+		if err := probeConnection(ctx, meta.ID, meta.Typ, g.props(0)); err != nil {
+			connLogger(meta.ID).Debugf("primary endpoint of connection %s is not recovered: %v", meta.ID, err)
+			return
+		}
+		// the new connection prefers the primary endpoint
+		staged, err := establish(ctx, meta, meta.Props)
+		if err != nil {
+			connLogger(meta.ID).Warnf("fail back connection %s to the primary endpoint failed: %v", meta.ID, err)
+			return
+		}
+		meta.opLock.Lock()
+		defer meta.opLock.Unlock()
+		if !meta.manager().holds(meta) || IsStandby() {
+			staged.release()
+			return
+		}
+		staged.swapInto(ctx, meta)
+		connLogger(meta.ID).Infof("connection %s fails back to endpoint %d", meta.ID, meta.activeEndpoint.Load())
+	}()
+}
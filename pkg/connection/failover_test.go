@@ -0,0 +1,142 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/topo/context"
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+	"github.com/lf-edge/ekuiper/v2/pkg/modules"
+)
+
+// reachableServers are the servers which failoverConnection can dial
+var reachableServers sync.Map
+
+type failoverConnection struct {
+	mockConnection
+	server string
+}
+
+func (f *failoverConnection) Provision(_ api.StreamContext, _ string, props map[string]any) error {
+	f.server, _ = props["server"].(string)
+	return nil
+}
+
+func (f *failoverConnection) Dial(_ api.StreamContext) error {
+	if _, ok := reachableServers.Load(f.server); !ok {
+		return errorx.NewIOErr("unreachable " + f.server)
+	}
+	return nil
+}
+
+func TestFailoverGroup(t *testing.T) {
+	g, err := newFailoverGroup(map[string]any{"server": "primary"})
+	require.NoError(t, err)
+	require.Nil(t, g)
+	_, err = newFailoverGroup(map[string]any{"server": "primary", FailoverPropKey: "secondary"})
+	require.Error(t, err)
+	_, err = newFailoverGroup(map[string]any{"server": "primary", FailoverPropKey: []any{"secondary"}})
+	require.Error(t, err)
+
+	mock := clock.NewMock()
+	SetClock(mock)
+	defer SetClock(nil)
+	g, err = newFailoverGroup(map[string]any{
+		"server":     "primary",
+		"username":   "admin",
+		RetryPropKey: map[string]any{"maxRetries": 3},
+		FailoverPropKey: []any{
+			map[string]any{"server": "secondary"},
+			map[string]any{"server": "tertiary", "username": "guest"},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"server": "primary", "username": "admin"}, g.props(0))
+	require.Equal(t, map[string]any{"server": "secondary", "username": "admin"}, g.props(1))
+	require.Equal(t, map[string]any{"server": "tertiary", "username": "guest"}, g.props(2))
+
+	require.Equal(t, 0, g.selectEndpoint())
+	g.markFailed(0)
+	require.Equal(t, 1, g.selectEndpoint())
+	mock.Add(time.Second)
+	g.markFailed(1)
+	require.Equal(t, 2, g.selectEndpoint())
+	mock.Add(time.Second)
+	g.markFailed(2)
+	// all failed, the one recovers first
+	require.Equal(t, 0, g.selectEndpoint())
+	g.markHealthy(1)
+	require.Equal(t, 1, g.selectEndpoint())
+	mock.Add(time.Hour)
+	require.Equal(t, 0, g.selectEndpoint())
+}
+
+func TestFailoverAndFailBack(t *testing.T) {
+	require.NoError(t, InitConnectionManager4Test())
+	modules.RegisterConnection("failoverconn", func(_ api.StreamContext) modules.Connection {
+		return &failoverConnection{}
+	})
+	reachableServers.Store("secondary", struct{}{})
+	defer reachableServers.Clear()
+	ctx := context.Background()
+	cw, err := CreateNamedConnection(ctx, "fo1", "failoverconn", map[string]any{
+		"server":        "primary",
+		"password":      "secret",
+		RetryPropKey:    map[string]any{"policy": RetryConstant, "interval": "1ms"},
+		FailoverPropKey: []any{map[string]any{"server": "secondary", "password": "secret2"}},
+	})
+	require.NoError(t, err)
+	defer func() {
+		_ = DropNameConnectionPermanently(ctx, "fo1")
+	}()
+	waitCtx, cancel := ctx.WithTimeout(5 * time.Second)
+	defer cancel()
+	conn, err := cw.Wait(waitCtx)
+	require.NoError(t, err)
+	require.Equal(t, "secondary", conn.(*failoverConnection).server)
+	meta, err := GetConnectionDetail(ctx, "fo1")
+	require.NoError(t, err)
+	active, ok := meta.ActiveEndpoint()
+	require.True(t, ok)
+	require.Equal(t, 1, active)
+	masked := MaskSecrets(meta.Props)
+	require.Equal(t, HiddenSecret, masked[FailoverPropKey].([]any)[0].(map[string]any)["password"])
+	require.Equal(t, "secret2", meta.Props[FailoverPropKey].([]any)[0].(map[string]any)["password"])
+
+	// stay on the secondary until the primary recovers
+	failBack(meta)
+	require.Eventually(t, func() bool {
+		return !meta.failingBack.Load()
+	}, time.Second, 10*time.Millisecond)
+	active, _ = meta.ActiveEndpoint()
+	require.Equal(t, 1, active)
+
+	reachableServers.Store("primary", struct{}{})
+	failBack(meta)
+	require.Eventually(t, func() bool {
+		active, _ := meta.ActiveEndpoint()
+		return active == 0 && !meta.failingBack.Load()
+	}, 5*time.Second, 10*time.Millisecond)
+	conn, err = cw.Wait(waitCtx)
+	require.NoError(t, err)
+	require.Equal(t, "primary", conn.(*failoverConnection).server)
+}
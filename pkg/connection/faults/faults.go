@@ -0,0 +1,97 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package faults is the facade to inject the runtime faults into the specific connections of the pool, so that the
+// integration and soak tests can exercise the recovery paths deterministically. The faults only take effect in the
+// builds with the fault or test tag; otherwise the injections return ErrDisabled.
+package faults
+
+import (
+	"time"
+
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/fault"
+	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+)
+
+var ErrDisabled = fault.ErrDisabled
+
+// Option customizes the injected fault
+type Option func(f *fault.Fault)
+
+// For limits how long the fault lasts. The fault lasts until it is cleared by default.
+func For(d time.Duration) Option {
+	return func(f *fault.Fault) {
+		f.Duration = cast.DurationConf(d)
+	}
+}
+
+// WithProbability fails each call by the chance in (0, 1]. Default to 1.
+func WithProbability(p float64) Option {
+	return func(f *fault.Fault) {
+		f.Probability = p
+	}
+}
+
+// WithError sets the message of the injected error
+func WithError(msg string) Option {
+	return func(f *fault.Fault) {
+		f.Error = msg
+	}
+}
+
+// FailPing fails the health check pings of the stateless connections. No id means all the connections.
+func FailPing(ids []string, opts ...Option) error {
+	return inject(fault.Fault{Name: fault.PingConnection, Connections: ids}, opts)
+}
+
+// FailDial fails the creations and the reconnections of the connections with the transient error
+func FailDial(ids []string, opts ...Option) error {
+	return inject(fault.Fault{Name: fault.DialConnection, Connections: ids}, opts)
+}
+
+// FailIO fails the creations and the reconnections of the connections with an IO error like a broken network
+func FailIO(ids []string, opts ...Option) error {
+	return inject(fault.Fault{Name: fault.DialConnection, Kind: "io", Connections: ids}, opts)
+}
+
+// SlowCreate delays the creations and the reconnections of the connections without failing them
+func SlowCreate(ids []string, delay time.Duration, opts ...Option) error {
+	return inject(fault.Fault{Name: fault.DialConnection, Delay: cast.DurationConf(delay), Connections: ids}, opts)
+}
+
+// Clear removes the faults of the connections. No id removes the faults of all the connections.
+func Clear(ids ...string) {
+	if len(ids) == 0 {
+		fault.Reset()
+		return
+	}
+	for _, p := range fault.Points() {
+		for _, id := range ids {
+			fault.RemoveFor(p.Name, id)
+		}
+	}
+}
+
+// Supported returns whether the faults can be injected in this build
+func Supported() bool {
+	return fault.Supported()
+}
+
+func inject(f fault.Fault, opts []Option) error {
+	for _, opt := range opts {
+		opt(&f)
+	}
+	_, err := fault.Set(f)
+	return err
+}
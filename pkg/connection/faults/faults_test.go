@@ -0,0 +1,93 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build fault || test
+
+package faults_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/topo/context"
+	"github.com/lf-edge/ekuiper/v2/pkg/connection"
+	"github.com/lf-edge/ekuiper/v2/pkg/connection/faults"
+	"github.com/lf-edge/ekuiper/v2/pkg/modules"
+)
+
+func TestFailIO(t *testing.T) {
+	require.NoError(t, connection.InitConnectionManager4Test())
+	mock := clock.NewMock()
+	connection.SetClock(mock)
+	defer connection.SetClock(nil)
+	defer faults.Clear()
+	modules.RegisterConnection("mock", connection.CreateMockConnection)
+	require.True(t, faults.Supported())
+	ctx := context.Background()
+
+	require.NoError(t, faults.FailIO([]string{"fi1"}, faults.WithError("network down")))
+	_, err := connection.CreateNamedConnection(ctx, "fi1", "mock", nil)
+	require.NoError(t, err)
+	defer func() {
+		_ = connection.DropNameConnectionPermanently(ctx, "fi1")
+	}()
+	cw2, err := connection.CreateNamedConnection(ctx, "fi2", "mock", nil)
+	require.NoError(t, err)
+	defer func() {
+		_ = connection.DropNameConnectionPermanently(ctx, "fi2")
+	}()
+	// only the targeted connection fails
+	c, err := cw2.Wait(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, c)
+	meta, err := connection.GetConnectionDetail(ctx, "fi1")
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		s, e := meta.GetStatus()
+		return s == api.ConnectionDisconnected && e == "network down"
+	}, time.Second, 10*time.Millisecond)
+
+	// the IO error is retried and recovers once the fault is cleared
+	faults.Clear("fi1")
+	require.Eventually(t, func() bool {
+		mock.Add(time.Minute)
+		s, _ := meta.GetStatus()
+		return s == api.ConnectionConnected
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestSlowCreate(t *testing.T) {
+	require.NoError(t, connection.InitConnectionManager4Test())
+	defer faults.Clear()
+	modules.RegisterConnection("mock", connection.CreateMockConnection)
+	ctx := context.Background()
+
+	require.NoError(t, faults.SlowCreate([]string{"fi3"}, 50*time.Millisecond))
+	start := time.Now()
+	cw, err := connection.CreateNamedConnection(ctx, "fi3", "mock", nil)
+	require.NoError(t, err)
+	defer func() {
+		_ = connection.DropNameConnectionPermanently(ctx, "fi3")
+	}()
+	c, err := cw.Wait(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, c)
+	require.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+
+	require.Error(t, faults.SlowCreate(nil, -time.Second))
+}
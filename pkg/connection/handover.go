@@ -0,0 +1,224 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+)
+
+// The pool snapshot hands over the connections of the active node to a standby node for the warm failover. Unlike
+// the replication which only covers the named connections, it includes the anonymous connections and the rules
+// holding them. The standby node creates the connections ahead of the takeover, and each anonymous connection is held
+// by the warm-up reference of its rules until the rules attach it or the hold expires.
+
+// PoolSnapshot is the serializable state of the connection pool
+type PoolSnapshot struct {
+	// Time is the unix milliseconds when the snapshot is taken
+	Time        int64                `json:"time"`
+	Connections []ConnectionSnapshot `json:"connections"`
+}
+
+// ConnectionSnapshot is the state of a connection in the pool snapshot. The secret props are encrypted if the
+// secret encryption is enabled, otherwise they are masked and the connection can't be restored.
+type ConnectionSnapshot struct {
+	ID        string         `json:"id"`
+	Typ       string         `json:"typ"`
+	Props     map[string]any `json:"props"`
+	Named     bool           `json:"named"`
+	Paused    bool           `json:"paused,omitempty"`
+	Status    string         `json:"status,omitempty"`
+	RefCount  int            `json:"refCount"`
+	Attachers []Attacher     `json:"attachers,omitempty"`
+}
+
+// RestoreResult is the outcome of restoring a pool snapshot
+type RestoreResult struct {
+	// Restored are the ids of the created connections and Skipped are the ones existed already or without rules
+	Restored []string          `json:"restored"`
+	Skipped  []string          `json:"skipped"`
+	Failed   map[string]string `json:"failed,omitempty"`
+}
+
+// SnapshotPool dumps all the connections in the pool sorted by id, including the anonymous connections and the
+// named connections which are not stored
+func (m *ConnectionManager) SnapshotPool() (*PoolSnapshot, error) {
+	// the props are changed with the lock
+	m.RLock()
+	defer m.RUnlock()
+	snap := &PoolSnapshot{
+		Time:        getClock().Now().UnixMilli(),
+		Connections: make([]ConnectionSnapshot, 0, len(m.connectionPool)),
+	}
+	encrypted := secretsEncrypted()
+	for _, meta := range m.connectionPool {
+		props := MaskSecrets(meta.Props)
+		if encrypted {
+			var err error
+			if props, err = encryptSecrets(meta.Props); err != nil {
+				return nil, fmt.Errorf("snapshot connection %s failed: %v", meta.ID, err)
+			}
+		}
+		cs := ConnectionSnapshot{
+			ID:        meta.ID,
+			Typ:       meta.Typ,
+			Props:     props,
+			Named:     meta.Named,
+			Paused:    meta.paused.Load(),
+			RefCount:  meta.GetRefCount(),
+			Attachers: meta.Attachers(),
+		}
+		if s, ok := meta.status.Load().(string); ok {
+			cs.Status = s
+		}
+		snap.Connections = append(snap.Connections, cs)
+	}
+	sort.Slice(snap.Connections, func(i, j int) bool {
+		return snap.Connections[i].ID < snap.Connections[j].ID
+	})
+	return snap, nil
+}
+
+// RestorePool creates the connections of the snapshot which are not in the pool. The named connections are created
+// without storing, like the replicated ones, and their paused status is not restored. The connections out of the
+// namespace of the context, with invalid reserved props or with masked secrets fail to restore. The anonymous connections are
+// held by the warm-up reference of the rules attaching them in the snapshot, and the hold is released after the hold
+// duration, so the connections not attached by the rules in time are closed. Zero hold keeps them until the warm-up
+// of the rules is released.
+func (m *ConnectionManager) RestorePool(ctx api.StreamContext, snap *PoolSnapshot, hold time.Duration) (*RestoreResult, error) {
+	if snap == nil {
+		return nil, errorx.NewWithCode(errorx.ConnectionPropsErr, "pool snapshot should be defined")
+	}
+	result := &RestoreResult{Restored: []string{}, Skipped: []string{}}
+	rules := m.restoreSnapshot(ctx, snap, result)
+	if hold > 0 && len(rules) > 0 {
+		getClock().AfterFunc(hold, func() {
+			for _, ruleId := range rules {
+				m.ReleaseWarmUp(ruleId)
+			}
+		})
+	}
+	conf.Log.Infof("restore pool snapshot with %d connections restored, %d skipped and %d failed", len(result.Restored), len(result.Skipped), len(result.Failed))
+	return result, nil
+}
+
+// restoreSnapshot creates the connections and returns the rules holding the restored anonymous connections
+func (m *ConnectionManager) restoreSnapshot(ctx api.StreamContext, snap *PoolSnapshot, result *RestoreResult) []string {
+	m.warmUps.Lock()
+	defer m.warmUps.Unlock()
+	m.Lock()
+	defer m.Unlock()
+	if m.closed {
+		return nil
+	}
+	var rules []string
+	held := make(map[string]struct{})
+	for _, cs := range snap.Connections {
+		if _, ok := m.connectionPool[cs.ID]; ok {
+			result.Skipped = append(result.Skipped, cs.ID)
+			continue
+		}
+		var holders []string
+		if !cs.Named {
+			holders = snapshotRules(cs.Attachers)
+			// nobody holds it, it would be dropped right away
+			if len(holders) == 0 {
+				result.Skipped = append(result.Skipped, cs.ID)
+				continue
+			}
+		}
+		props, err := m.restorableProps(ctx, cs)
+		if err != nil {
+			if result.Failed == nil {
+				result.Failed = make(map[string]string)
+			}
+			result.Failed[cs.ID] = err.Error()
+			continue
+		}
+		meta := &Meta{
+			ID:    cs.ID,
+			Typ:   cs.Typ,
+			Props: props,
+			Named: cs.Named,
+		}
+		if cs.Named {
+			meta.newWrappers(func() *ConnWrapper {
+				return newNamedConnWrapper(m.ctx, meta)
+			})
+		} else {
+			meta.cw = newConnWrapper(m.ctx, meta)
+		}
+		m.put(cs.ID, meta)
+		for _, ruleId := range holders {
+			ref := warmUpRef(ruleId)
+			meta.AddRef(ref, nil)
+			a := Attacher{RefID: ref, RuleID: ruleId, OpID: "warmup", AttachedAt: getClock().Now()}
+			meta.storeAttacher(a)
+			meta.auditRef(RefAttach, a)
+			m.warmUps.warmed[ruleId] = append(m.warmUps.warmed[ruleId], cs.ID)
+			m.recordFootprint(ruleId, meta)
+			if _, ok := held[ruleId]; !ok {
+				held[ruleId] = struct{}{}
+				rules = append(rules, ruleId)
+			}
+		}
+		result.Restored = append(result.Restored, cs.ID)
+	}
+	return rules
+}
+
+// restorableProps returns the decrypted props of the connection snapshot if it can be restored. It must be called
+// with the lock.
+func (m *ConnectionManager) restorableProps(ctx api.StreamContext, cs ConnectionSnapshot) (map[string]any, error) {
+	if err := CheckNamespace(ctx, cs.ID); err != nil {
+		return nil, err
+	}
+	if k, masked := maskedSecret(cs.Props); masked {
+		return nil, fmt.Errorf("secret prop %s of connection %s is masked", k, cs.ID)
+	}
+	props, err := decryptSecrets(cs.Props)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateReservedProps(cs.Typ, props); err != nil {
+		return nil, err
+	}
+	if err := m.checkConnectionQuota(cs.ID, cs.Typ); err != nil {
+		return nil, err
+	}
+	return props, nil
+}
+
+// snapshotRules returns the distinct rules of the attachers
+func snapshotRules(attachers []Attacher) []string {
+	var rules []string
+	seen := make(map[string]struct{})
+	for _, a := range attachers {
+		if a.RuleID == "" {
+			continue
+		}
+		if _, ok := seen[a.RuleID]; !ok {
+			seen[a.RuleID] = struct{}{}
+			rules = append(rules, a.RuleID)
+		}
+	}
+	return rules
+}
@@ -0,0 +1,130 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/topo/context"
+	mockContext "github.com/lf-edge/ekuiper/v2/pkg/mock/context"
+)
+
+func TestSnapshotRestorePool(t *testing.T) {
+	require.NoError(t, InitConnectionManager4Test())
+	defer ForgetWarmUp("handoverRule")
+	ctx := context.Background()
+	_, err := CreateNamedConnection(ctx, "handoverNamed", "mock", map[string]any{"a": 1})
+	require.NoError(t, err)
+	ruleCtx := mockContext.NewMockContext("handoverRule", "op1")
+	_, err = FetchConnection(ruleCtx, "handoverConn", "mock", map[string]any{"b": 2}, nil)
+	require.NoError(t, err)
+
+	snap, err := SnapshotPool()
+	require.NoError(t, err)
+	require.Len(t, snap.Connections, 2)
+	anon := snap.Connections[0]
+	require.Equal(t, "handoverConn", anon.ID)
+	require.False(t, anon.Named)
+	require.Equal(t, 1, anon.RefCount)
+	require.Len(t, anon.Attachers, 1)
+	require.Equal(t, "handoverRule", anon.Attachers[0].RuleID)
+	require.Equal(t, "handoverNamed", snap.Connections[1].ID)
+	require.True(t, snap.Connections[1].Named)
+	require.NoError(t, DetachConnection(ruleCtx, "handoverConn"))
+	require.NoError(t, DropNameConnection(ctx, "handoverNamed"))
+
+	// the standby node creates the connections and holds the anonymous one for the rule
+	require.NoError(t, InitConnectionManager4Test())
+	result, err := RestorePool(ctx, snap, 0)
+	require.NoError(t, err)
+	require.Equal(t, []string{"handoverConn", "handoverNamed"}, result.Restored)
+	require.Empty(t, result.Skipped)
+	require.True(t, IsWarmedUp("handoverRule"))
+	require.Equal(t, 1, globalConnectionManager.getConnectionRef("handoverConn"))
+	meta, err := GetConnectionDetail(ctx, "handoverNamed")
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"a": 1}, meta.Props)
+
+	// restore again skips the existing connections
+	result, err = RestorePool(ctx, snap, 0)
+	require.NoError(t, err)
+	require.Empty(t, result.Restored)
+	require.Equal(t, []string{"handoverConn", "handoverNamed"}, result.Skipped)
+
+	// the rule takes over the restored connection
+	meta, err = GetConnectionDetail(ctx, "handoverConn")
+	require.NoError(t, err)
+	cw, err := FetchConnection(ruleCtx, "handoverConn", "mock", map[string]any{"b": 2}, nil)
+	require.NoError(t, err)
+	require.Same(t, meta.cw, cw)
+	ReleaseWarmUp("handoverRule")
+	require.Equal(t, 1, globalConnectionManager.getConnectionRef("handoverConn"))
+	require.NoError(t, DetachConnection(ruleCtx, "handoverConn"))
+
+	// the hold expires if the rule does not attach
+	mock := clock.NewMock()
+	SetClock(mock)
+	defer SetClock(nil)
+	require.NoError(t, InitConnectionManager4Test())
+	result, err = RestorePool(ctx, snap, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, result.Restored, 2)
+	mock.Add(time.Minute)
+	require.Eventually(t, func() bool {
+		_, err := GetConnectionDetail(ctx, "handoverConn")
+		return err != nil
+	}, time.Second, 10*time.Millisecond)
+	require.False(t, IsWarmedUp("handoverRule"))
+	_, err = GetConnectionDetail(ctx, "handoverNamed")
+	require.NoError(t, err)
+
+	_, err = RestorePool(ctx, nil, 0)
+	require.Error(t, err)
+}
+
+func TestSnapshotRestoreSecrets(t *testing.T) {
+	require.NoError(t, InitConnectionManager4Test())
+	ctx := context.Background()
+	_, err := CreateNamedConnection(ctx, "handoverSecret", "mock", map[string]any{"password": "pwd"})
+	require.NoError(t, err)
+	defer func() {
+		_ = DropNameConnectionPermanently(ctx, "handoverSecret")
+	}()
+	// the secrets are masked without the encryption
+	snap, err := SnapshotPool()
+	require.NoError(t, err)
+	require.Len(t, snap.Connections, 1)
+	require.Equal(t, map[string]any{"password": HiddenSecret}, snap.Connections[0].Props)
+
+	require.NoError(t, InitConnectionManager4Test())
+	result, err := RestorePool(ctx, snap, 0)
+	require.NoError(t, err)
+	require.Empty(t, result.Restored)
+	require.Equal(t, map[string]string{"handoverSecret": "secret prop password of connection handoverSecret is masked"}, result.Failed)
+
+	// the connections out of the namespace are not restored
+	snap.Connections[0].Props = map[string]any{"a": 1}
+	result, err = RestorePool(WithNamespace(ctx, "other"), snap, 0)
+	require.NoError(t, err)
+	require.Empty(t, result.Restored)
+	require.Contains(t, result.Failed, "handoverSecret")
+	result, err = RestorePool(ctx, snap, 0)
+	require.NoError(t, err)
+	require.Equal(t, []string{"handoverSecret"}, result.Restored)
+}
@@ -0,0 +1,245 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+
+	topoContext "github.com/lf-edge/ekuiper/v2/internal/topo/context"
+	"github.com/lf-edge/ekuiper/v2/pkg/modules"
+	"github.com/lf-edge/ekuiper/v2/pkg/syncx"
+)
+
+// The health check reconnects the named connections which are broken for good, that is, the creation gave up after
+// the retries or the stateless connection fails to ping. The stateful connections reconnect by themselves, so they
+// are only reconnected if the creation gave up. The connections implementing modules.Reconnectable reconnect in
+// place, otherwise the new connection is swapped in for the attached rules.
+
+type reconnectState struct {
+	meta    *Meta
+	b       backoff.BackOff
+	next    time.Time
+	running bool
+	// status is the failures persisted across restarts
+	status ReconnectStatus
+}
+
+var reconnects = struct {
+	syncx.Mutex
+	states map[string]*reconnectState
+}{states: make(map[string]*reconnectState)}
+
+// isBroken returns whether the connection needs to be recreated. The force closed connections stay closed.
+func isBroken(meta *Meta, status string) bool {
+	if meta.forceClosed.Load() || meta.paused.Load() || !meta.cw.IsInitialized() {
+		return false
+	}
+	conn, err := meta.cw.Wait(topoContext.Background())
+	if err != nil || conn == nil {
+		return true
+	}
+	// any physical connection of the pooled connection failed to create
+	for _, cw := range meta.members {
+		if cw.IsInitialized() {
+			if c, err := cw.Wait(topoContext.Background()); err != nil || c == nil {
+				return true
+			}
+		}
+	}
+	_, isStateful := conn.(modules.StatefulDialer)
+	return !isStateful && status == api.ConnectionDisconnected
+}
+
+// checkHealth starts to reconnect the broken named connection once its backoff expires. It runs in the patrol job.
+func checkHealth(meta *Meta, status string) {
+	if IsStandby() {
+		return
+	}
+	reconnects.Lock()
+	st, ok := reconnectStateOf(meta)
+	if ok && st.running {
+		reconnects.Unlock()
+		return
+	}
+	if !isBroken(meta, status) {
+		drop := ok && forgetReconnect(st)
+		reconnects.Unlock()
+		if drop {
+			dropReconnectStatus(meta.ID)
+		}
+		return
+	}
+	if !ok {
+		st = newReconnectState(meta)
+	}
+	if st.status.GaveUp || getClock().Now().Before(st.next) {
+		reconnects.Unlock()
+		return
+	}
+	st.running = true
+	meta.NotifyStatus(api.ConnectionConnecting, "")
+	reconnects.Unlock()
+	go reconnect(meta, st)
+}
+
+// reconnectStateOf returns the reconnect state of the connection. The state left by the dropped or replaced
+// connection of the same id is reset unless its reconnection is still running. It must be called with the reconnects
+// lock.
+func reconnectStateOf(meta *Meta) (*reconnectState, bool) {
+	st, ok := reconnects.states[meta.ID]
+	if ok && st.meta != meta && !st.running {
+		delete(reconnects.states, meta.ID)
+		return nil, false
+	}
+	return st, ok
+}
+
+func newReconnectState(meta *Meta) *reconnectState {
+	c, _ := connRetryConf(meta.Typ, meta.Props)
+	// keep retrying at the max interval regardless of the retry limits
+	st := &reconnectState{meta: meta, b: buildRetryPolicy(c).NewBackOff()}
+	st.status = loadReconnectStatus(meta.ID)
+	reconnects.states[meta.ID] = st
+	return st
+}
+
+// recoverOnAttach recreates the named connection whose creation gave up when a rule attaches it, so that the rule
+// starts without waiting for the health check. It is a single attempt bounded by the operation timeout. It is skipped
+// if a reconnection is running or the last attempt failed within the backoff, so the rules attaching a down
+// endpoint are not blocked one by one. It must be called without the pool lock.
+func recoverOnAttach(meta *Meta) {
+	if !meta.Named || IsStandby() || meta.forceClosed.Load() || meta.paused.Load() || !meta.cw.IsInitialized() {
+		return
+	}
+	if _, err := meta.cw.Wait(topoContext.Background()); err == nil {
+		return
+	}
+	reconnects.Lock()
+	st, ok := reconnectStateOf(meta)
+	if ok && (st.running || getClock().Now().Before(st.next)) {
+		reconnects.Unlock()
+		return
+	}
+	if !ok {
+		st = newReconnectState(meta)
+	}
+	st.running = true
+	reconnects.Unlock()
+	connLogger(meta.ID).Infof("recover failed connection %s on attach", meta.ID)
+	meta.NotifyStatus(api.ConnectionConnecting, "")
+	reconnect(meta, st)
+}
+
+func reconnect(meta *Meta, st *reconnectState) {
+	ctx := topoContext.Background()
+	var err error
+	if !reconnectInPlace(ctx, meta) {
+		err = recreate(ctx, meta)
+	}
+	reconnects.Lock()
+	st.running = false
+	if err != nil {
+		meta.NotifyStatus(api.ConnectionDisconnected, err.Error())
+		s := st.failed(err)
+		reconnects.Unlock()
+		saveReconnectStatus(st, s)
+		return
+	}
+	drop := forgetReconnect(st)
+	reconnects.Unlock()
+	if drop {
+		dropReconnectStatus(meta.ID)
+	}
+	recordEvent(meta.ID, EventReconnected, "")
+	connLogger(meta.ID).Infof("broken connection %s is reconnected", meta.ID)
+}
+
+// reconnectInPlace reconnects the connections of the meta by modules.Reconnectable instead of recreating them. It
+// returns false if any of them is not created or not reconnectable, or fails to reconnect or to warm up.
+func reconnectInPlace(ctx api.StreamContext, meta *Meta) bool {
+	meta.opLock.Lock()
+	defer meta.opLock.Unlock()
+	var conns []modules.Connection
+	for _, cw := range meta.wrappers() {
+		if !cw.IsInitialized() {
+			return false
+		}
+		conn, err := cw.Wait(ctx)
+		if err != nil || conn == nil {
+			return false
+		}
+		if _, ok := conn.(modules.Reconnectable); !ok {
+			return false
+		}
+		conns = append(conns, conn)
+	}
+	for _, conn := range conns {
+		_, warmable := conn.(modules.Warmable)
+		meta.warming.Store(warmable)
+		opCtx, cancel := withTimeout(ctx)
+		err := safeCall(meta.ID, "reconnect", func() error {
+			return conn.(modules.Reconnectable).Reconnect(opCtx)
+		})
+		cancel()
+		if err != nil {
+			meta.warming.Store(false)
+			connLogger(meta.ID).Warnf("reconnect connection %s in place failed, recreate it: %v", meta.ID, err)
+			return false
+		}
+		_, err = warmUpConn(ctx, meta, conn)
+		meta.warming.Store(false)
+		if err != nil {
+			connLogger(meta.ID).Warnf("warm up connection %s after reconnect failed, recreate it: %v", meta.ID, err)
+			return false
+		}
+	}
+	meta.NotifyStatus(api.ConnectionConnected, "")
+	return true
+}
+
+// recreate creates the connections of the meta again and swaps them in
+func recreate(ctx api.StreamContext, meta *Meta) error {
+	staged, err := establish(ctx, meta, meta.Props)
+	if err != nil {
+		return err
+	}
+	meta.opLock.Lock()
+	defer meta.opLock.Unlock()
+	if meta.manager().holds(meta) && !IsStandby() && !meta.paused.Load() {
+		staged.swapInto(ctx, meta)
+	} else {
+		// dropped or replaced in the meantime
+		staged.release()
+	}
+	return nil
+}
+
+// pruneReconnects forgets the connections removed from their pool
+func pruneReconnects() {
+	reconnects.Lock()
+	var dropped []string
+	for id, st := range reconnects.states {
+		if cur, ok := st.meta.manager().load()[id]; (!ok || cur != st.meta) && !st.running && forgetReconnect(st) {
+			dropped = append(dropped, id)
+		}
+	}
+	reconnects.Unlock()
+	for _, id := range dropped {
+		dropReconnectStatus(id)
+	}
+}
@@ -0,0 +1,218 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/context"
+	"github.com/lf-edge/ekuiper/v2/pkg/modules"
+)
+
+var flakyDialFail atomic.Bool
+
+type flakyConnection struct {
+	mockConnection
+}
+
+func (f *flakyConnection) Dial(ctx api.StreamContext) error {
+	if flakyDialFail.Load() {
+		return errors.New("dial failed")
+	}
+	return nil
+}
+
+func TestHealthCheckReconnect(t *testing.T) {
+	// reset the state left by the other tests
+	conf.InitConf()
+	SetRetryTimer(nil)
+	reconnects.Lock()
+	reconnects.states = make(map[string]*reconnectState)
+	reconnects.Unlock()
+	require.NoError(t, InitConnectionManager4Test())
+	mock := clock.NewMock()
+	SetClock(mock)
+	defer SetClock(nil)
+	modules.RegisterConnection("flakyconn", func(ctx api.StreamContext) modules.Connection {
+		return &flakyConnection{}
+	})
+	ctx := context.Background()
+	flakyDialFail.Store(true)
+	defer flakyDialFail.Store(false)
+	cw, err := CreateNamedConnection(ctx, "flaky1", "flakyconn", nil)
+	require.NoError(t, err)
+	defer func() {
+		_ = DropNameConnectionPermanently(ctx, "flaky1")
+	}()
+	// the failed connection is returned with the error
+	_, err = cw.Wait(ctx)
+	require.Error(t, err)
+	meta, err := GetConnectionDetail(ctx, "flaky1")
+	require.NoError(t, err)
+
+	// the reconnection fails and waits for the backoff
+	checkHealth(meta, api.ConnectionDisconnected)
+	require.Eventually(t, func() bool {
+		reconnects.Lock()
+		defer reconnects.Unlock()
+		st, ok := reconnects.states["flaky1"]
+		return ok && !st.running && !st.next.IsZero()
+	}, time.Second, 10*time.Millisecond)
+	s, _ := meta.GetStatus()
+	require.Equal(t, api.ConnectionDisconnected, s)
+	flakyDialFail.Store(false)
+	checkHealth(meta, api.ConnectionDisconnected)
+	_, err = cw.Wait(ctx)
+	require.Error(t, err)
+
+	// reconnected after the backoff and swapped in
+	mock.Add(time.Minute)
+	checkHealth(meta, api.ConnectionDisconnected)
+	require.Eventually(t, func() bool {
+		c, err := cw.Wait(ctx)
+		return err == nil && c != nil
+	}, time.Second, 10*time.Millisecond)
+	s, _ = meta.GetStatus()
+	require.Equal(t, api.ConnectionConnected, s)
+	require.Eventually(t, func() bool {
+		reconnects.Lock()
+		defer reconnects.Unlock()
+		_, ok := reconnects.states["flaky1"]
+		return !ok
+	}, time.Second, 10*time.Millisecond)
+	checkHealth(meta, api.ConnectionConnected)
+	require.False(t, isBroken(meta, api.ConnectionConnected))
+}
+
+func TestRecoverOnAttach(t *testing.T) {
+	require.NoError(t, InitConnectionManager4Test())
+	mock := clock.NewMock()
+	SetClock(mock)
+	defer SetClock(nil)
+	modules.RegisterConnection("flakyconn", func(ctx api.StreamContext) modules.Connection {
+		return &flakyConnection{}
+	})
+	ctx := context.Background()
+	flakyDialFail.Store(true)
+	defer flakyDialFail.Store(false)
+	cw, err := CreateNamedConnection(ctx, "flaky2", "flakyconn", nil)
+	require.NoError(t, err)
+	defer func() {
+		_ = DropNameConnectionPermanently(ctx, "flaky2")
+	}()
+	_, err = cw.Wait(ctx)
+	require.Error(t, err)
+	props := map[string]any{"connectionSelector": "flaky2"}
+
+	// the recovery fails and the next attach within the backoff does not retry
+	_, err = FetchConnection(ctx, extractRefId(ctx), "flakyconn", props, nil)
+	require.NoError(t, err)
+	_, err = cw.Wait(ctx)
+	require.Error(t, err)
+	require.NoError(t, DetachConnection(ctx, "flaky2"))
+	flakyDialFail.Store(false)
+	_, err = FetchConnection(ctx, extractRefId(ctx), "flakyconn", props, nil)
+	require.NoError(t, err)
+	_, err = cw.Wait(ctx)
+	require.Error(t, err)
+	require.NoError(t, DetachConnection(ctx, "flaky2"))
+
+	// recovered by the attach after the backoff
+	mock.Add(time.Minute)
+	got, err := FetchConnection(ctx, extractRefId(ctx), "flakyconn", props, nil)
+	require.NoError(t, err)
+	c, err := got.Wait(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, c)
+	meta, err := GetConnectionDetail(ctx, "flaky2")
+	require.NoError(t, err)
+	s, _ := meta.GetStatus()
+	require.Equal(t, api.ConnectionConnected, s)
+	require.NoError(t, DetachConnection(ctx, "flaky2"))
+}
+
+type reconnectableConnection struct {
+	mockConnection
+	down       atomic.Bool
+	reconnects atomic.Int32
+}
+
+func (r *reconnectableConnection) Ping(ctx api.StreamContext) error {
+	if r.down.Load() {
+		return errors.New("ping failed")
+	}
+	return nil
+}
+
+func (r *reconnectableConnection) Reconnect(ctx api.StreamContext) error {
+	r.reconnects.Add(1)
+	r.down.Store(false)
+	return nil
+}
+
+func (r *reconnectableConnection) Status(ctx api.StreamContext) modules.ConnectionStatus {
+	return modules.ConnectionStatus{Status: api.ConnectionConnected, Details: map[string]any{"reconnects": r.reconnects.Load()}}
+}
+
+func TestHealthCheckReconnectInPlace(t *testing.T) {
+	require.NoError(t, InitConnectionManager4Test())
+	modules.RegisterConnection("reconnectable", func(ctx api.StreamContext) modules.Connection {
+		return &reconnectableConnection{}
+	})
+	ctx := context.Background()
+	cw, err := CreateNamedConnection(ctx, "inplace1", "reconnectable", nil)
+	require.NoError(t, err)
+	defer func() {
+		_ = DropNameConnectionPermanently(ctx, "inplace1")
+	}()
+	c, err := cw.Wait(ctx)
+	require.NoError(t, err)
+	conn := c.(*reconnectableConnection)
+	meta, err := GetConnectionDetail(ctx, "inplace1")
+	require.NoError(t, err)
+	// the status reporter is not treated as stateful
+	require.False(t, isBroken(meta, api.ConnectionConnected))
+	ds, ok := meta.DriverStatus()
+	require.True(t, ok)
+	require.Equal(t, map[string]any{"reconnects": int32(0)}, ds.Details)
+
+	conn.down.Store(true)
+	s, _ := meta.GetStatus()
+	require.Equal(t, api.ConnectionDisconnected, s)
+	checkHealth(meta, s)
+	require.Eventually(t, func() bool {
+		return conn.reconnects.Load() == 1
+	}, time.Second, 10*time.Millisecond)
+	require.Eventually(t, func() bool {
+		reconnects.Lock()
+		defer reconnects.Unlock()
+		_, ok := reconnects.states["inplace1"]
+		return !ok
+	}, time.Second, 10*time.Millisecond)
+	// the same connection is kept
+	c, err = cw.Wait(ctx)
+	require.NoError(t, err)
+	require.Same(t, conn, c)
+	s, _ = meta.GetStatus()
+	require.Equal(t, api.ConnectionConnected, s)
+}
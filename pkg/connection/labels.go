@@ -0,0 +1,190 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+)
+
+// LabelsPropKey is the reserved prop of the labels to organize the connections, such as {"site": "plant1"}. The
+// labels are persisted with the props but not passed to the connection.
+const LabelsPropKey = "$labels"
+
+// parseLabels returns the labels of the props. It returns nil if there is no label.
+func parseLabels(props map[string]any) (map[string]string, error) {
+	v, ok := props[LabelsPropKey]
+	if !ok || v == nil {
+		return nil, nil
+	}
+	switch lv := v.(type) {
+	case map[string]string:
+		return lv, nil
+	case map[string]any:
+		labels := make(map[string]string, len(lv))
+		for k, val := range lv {
+			s, ok := val.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid %s: the value of label %s must be a string", LabelsPropKey, k)
+			}
+			labels[k] = s
+		}
+		return labels, nil
+	default:
+		return nil, fmt.Errorf("invalid %s: must be a map of strings", LabelsPropKey)
+	}
+}
+
+// validateLabels checks the label keys which can't be empty or contain the selector operators
+func validateLabels(labels map[string]string) error {
+	for k := range labels {
+		if k == "" || strings.ContainsAny(k, ",=! ") {
+			return fmt.Errorf("invalid label key %q", k)
+		}
+	}
+	return nil
+}
+
+// Labels returns the labels of the connection
+func (meta *Meta) Labels() map[string]string {
+	labels, _ := parseLabels(meta.Props)
+	return labels
+}
+
+// SetLabels replaces the labels of the named connection. The connection is not reconnected.
+func (m *ConnectionManager) SetLabels(ctx api.StreamContext, id string, labels map[string]string) error {
+	if id == "" {
+		return errorx.NewWithCode(errorx.ConnectionInvalidErr, "connection id should be defined")
+	}
+	if err := CheckNamespace(ctx, id); err != nil {
+		return err
+	}
+	if err := validateLabels(labels); err != nil {
+		return err
+	}
+	m.RLock()
+	meta, ok := m.connectionPool[id]
+	m.RUnlock()
+	if !ok {
+		return errorx.NewWithCode(errorx.NOT_FOUND, fmt.Sprintf("connection %s not existed", id))
+	}
+	if !meta.Named {
+		return errorx.NewWithCode(errorx.ConnectionReadOnlyErr, fmt.Sprintf("internal connection %v can't be edit", id))
+	}
+	meta.opLock.Lock()
+	defer meta.opLock.Unlock()
+	props := make(map[string]any, len(meta.Props)+1)
+	for k, v := range meta.Props {
+		props[k] = v
+	}
+	if len(labels) > 0 {
+		lv := make(map[string]any, len(labels))
+		for k, v := range labels {
+			lv[k] = v
+		}
+		props[LabelsPropKey] = lv
+	} else {
+		delete(props, LabelsPropKey)
+	}
+	m.Lock()
+	defer m.Unlock()
+	if cur, ok := m.connectionPool[id]; !ok || cur != meta {
+		return errorx.NewWithCode(errorx.ConnectionConflictErr, fmt.Sprintf("connection %s is changed during the update", id))
+	}
+	if err := storeConnectionMeta(meta.Typ, id, props); err != nil {
+		return fmt.Errorf("update connection %s labels failed, err:%w", id, err)
+	}
+	meta.Props = props
+	emitReplica(putEvent(meta))
+	recordEvent(id, EventUpdated, "")
+	return nil
+}
+
+type labelRequirement struct {
+	key    string
+	value  string
+	op     string
+	exists bool
+}
+
+// LabelSelector selects the connections by labels. All the requirements must be matched.
+type LabelSelector []labelRequirement
+
+// ParseLabelSelector parses the comma separated requirements. A requirement is in the form of key=value, key!=value,
+// key which means the label exists or !key which means the label does not exist.
+func ParseLabelSelector(s string) (LabelSelector, error) {
+	var sel LabelSelector
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		var r labelRequirement
+		if k, v, ok := strings.Cut(part, "!="); ok {
+			r = labelRequirement{key: strings.TrimSpace(k), value: strings.TrimSpace(v), op: "!="}
+		} else if k, v, ok := strings.Cut(part, "="); ok {
+			r = labelRequirement{key: strings.TrimSpace(k), value: strings.TrimSpace(v), op: "="}
+		} else if k, ok := strings.CutPrefix(part, "!"); ok {
+			r = labelRequirement{key: strings.TrimSpace(k), exists: false}
+		} else {
+			r = labelRequirement{key: part, exists: true}
+		}
+		if r.key == "" || strings.ContainsAny(r.key, "!= ") {
+			return nil, fmt.Errorf("invalid label selector %q", part)
+		}
+		sel = append(sel, r)
+	}
+	return sel, nil
+}
+
+// Matches checks whether the labels match all the requirements
+func (sel LabelSelector) Matches(labels map[string]string) bool {
+	for _, r := range sel {
+		v, ok := labels[r.key]
+		switch r.op {
+		case "=":
+			if !ok || v != r.value {
+				return false
+			}
+		case "!=":
+			if ok && v == r.value {
+				return false
+			}
+		default:
+			if ok != r.exists {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// FilterConnectionsMeta returns the connections whose labels match the selector
+func FilterConnectionsMeta(metas []*Meta, sel LabelSelector) []*Meta {
+	if len(sel) == 0 {
+		return metas
+	}
+	result := make([]*Meta, 0, len(metas))
+	for _, meta := range metas {
+		if sel.Matches(meta.Labels()) {
+			result = append(result, meta)
+		}
+	}
+	return result
+}
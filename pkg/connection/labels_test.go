@@ -0,0 +1,75 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/context"
+)
+
+func TestParseLabelSelector(t *testing.T) {
+	sel, err := ParseLabelSelector("site=plant1, env!=dev,tier,!legacy")
+	require.NoError(t, err)
+	require.Len(t, sel, 4)
+	require.True(t, sel.Matches(map[string]string{"site": "plant1", "env": "prod", "tier": "edge"}))
+	require.False(t, sel.Matches(map[string]string{"site": "plant1", "env": "dev", "tier": "edge"}))
+	require.False(t, sel.Matches(map[string]string{"site": "plant1", "tier": "edge", "legacy": "true"}))
+	require.False(t, sel.Matches(map[string]string{"site": "plant1"}))
+	sel, err = ParseLabelSelector("")
+	require.NoError(t, err)
+	require.True(t, sel.Matches(nil))
+	_, err = ParseLabelSelector("=plant1")
+	require.Error(t, err)
+}
+
+func TestConnectionLabels(t *testing.T) {
+	require.NoError(t, InitConnectionManager4Test())
+	ctx := context.Background()
+	_, err := CreateNamedConnection(ctx, "label1", "mock", map[string]any{LabelsPropKey: map[string]any{"site": "plant1", "env": "prod"}})
+	require.NoError(t, err)
+	_, err = CreateNamedConnection(ctx, "label2", "mock", map[string]any{LabelsPropKey: map[string]any{"site": "plant2"}})
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, DropNameConnectionPermanently(ctx, "label1"))
+		require.NoError(t, DropNameConnectionPermanently(ctx, "label2"))
+	}()
+	_, err = CreateNamedConnection(ctx, "label3", "mock", map[string]any{LabelsPropKey: map[string]any{"site": 1}})
+	require.Error(t, err)
+	require.Nil(t, withoutReservedProps(map[string]any{LabelsPropKey: map[string]any{}})[LabelsPropKey])
+
+	sel, err := ParseLabelSelector("site=plant1")
+	require.NoError(t, err)
+	metas := FilterConnectionsMeta(GetAllConnectionsMeta(false), sel)
+	require.Len(t, metas, 1)
+	require.Equal(t, "label1", metas[0].ID)
+
+	require.NoError(t, SetConnectionLabels(ctx, "label2", map[string]string{"site": "plant1"}))
+	require.Len(t, FilterConnectionsMeta(GetAllConnectionsMeta(false), sel), 2)
+	require.Error(t, SetConnectionLabels(ctx, "label2", map[string]string{"a=b": "c"}))
+	require.Error(t, SetConnectionLabels(ctx, "nonexist", map[string]string{"site": "plant1"}))
+
+	// persisted with the props
+	cfgs, err := conf.GetCfgFromKVStorage("connections", "mock", "label2")
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"site": "plant1"}, cfgs["connections.mock.label2"][LabelsPropKey])
+	require.NoError(t, SetConnectionLabels(ctx, "label2", nil))
+	meta, err := GetConnectionDetail(ctx, "label2")
+	require.NoError(t, err)
+	require.Nil(t, meta.Labels())
+}
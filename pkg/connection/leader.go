@@ -0,0 +1,194 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	topoContext "github.com/lf-edge/ekuiper/v2/internal/topo/context"
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+)
+
+// Leader election for the nodes sharing the config store. All the nodes load the named connections, but only the
+// leader holding the lease connects them. The standby nodes keep the metadata so that they can connect immediately
+// once they take over.
+
+const leaderLeaseName = "connection_leader"
+
+var (
+	errStandby = errorx.NewWithCode(errorx.ConnectionErr, "connection is not connected on the standby node")
+	// standby is true if the leader election is enabled and this node is not the leader
+	standby atomic.Bool
+	// leaderNodeID is the lease owner of this node, empty if the leader election is not running
+	leaderNodeID atomic.Value
+)
+
+// IsStandby returns whether this node is a standby node which does not connect the named connections
+func IsStandby() bool {
+	return standby.Load()
+}
+
+// startLeaderElection acquires the lease before the named connections are loaded so that a standby node never
+// connects them. Then the lease is renewed in the background.
+func startLeaderElection(ctx context.Context) {
+	c := conf.Config.Connection.LeaderElection
+	ttl := time.Duration(c.LeaseTTL)
+	leader, err := tryLead(c.NodeID, ttl)
+	if errors.Is(err, conf.ErrLeaseUnsupported) {
+		conf.Log.Warnf("connection leader election is disabled: %v", err)
+		return
+	}
+	if err != nil {
+		conf.Log.Errorf("acquire connection leader lease failed: %v", err)
+	}
+	leaderNodeID.Store(c.NodeID)
+	standby.Store(!leader)
+	conf.Log.Infof("connection leader election started as node %s, leader: %v", c.NodeID, leader)
+	go supervise(ctx, "leader election", func(ctx context.Context) {
+		runLeaderElection(ctx, c.NodeID, ttl)
+	})
+}
+
+func tryLead(nodeID string, ttl time.Duration) (bool, error) {
+	holder, err := conf.AcquireLease(leaderLeaseName, nodeID, ttl)
+	return err == nil && holder == nodeID, err
+}
+
+func runLeaderElection(ctx context.Context, nodeID string, ttl time.Duration) {
+	c := getClock()
+	ticker := c.Ticker(ttl / 3)
+	defer ticker.Stop()
+	lastRenew := c.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			resignLeader()
+			return
+		case <-ticker.C:
+			if id, _ := leaderNodeID.Load().(string); id == "" {
+				// resigned on shutdown
+				return
+			}
+			leader, err := tryLead(nodeID, ttl)
+			if err != nil {
+				conf.Log.Warnf("renew connection leader lease failed: %v", err)
+				// the store may be back soon, keep leading until the lease surely expires
+				if IsStandby() || c.Since(lastRenew) < ttl {
+					continue
+				}
+			}
+			if leader {
+				lastRenew = c.Now()
+			}
+			setLeader(leader)
+		}
+	}
+}
+
+// setLeader connects the named connections when promoted and closes them when demoted
+func setLeader(leader bool) {
+	globalConnectionManager.setLeader(leader)
+}
+
+func (m *ConnectionManager) setLeader(leader bool) {
+	if IsStandby() != leader {
+		return
+	}
+	m.Lock()
+	if m.closed {
+		m.Unlock()
+		return
+	}
+	standby.Store(!leader)
+	var named []*Meta
+	for _, meta := range m.connectionPool {
+		if meta.Named {
+			named = append(named, meta)
+		}
+	}
+	m.Unlock()
+	// the wrappers are kept for the attached consumers, only the connections in them are replaced
+	ctx := topoContext.Background()
+	for _, meta := range named {
+		switch {
+		case leader && meta.paused.Load():
+			meta.opLock.Lock()
+			parkWrappers(ctx, meta, errPaused)
+			meta.opLock.Unlock()
+			meta.NotifyStatus(ConnectionPaused, "")
+		case leader:
+			meta.status.Store(api.ConnectionConnecting)
+			meta.goroutines.Add(1)
+			go func(meta *Meta) {
+				defer meta.goroutines.Add(-1)
+				// reconnected by the health check if failed
+				if err := recreate(ctx, meta); err != nil {
+					meta.NotifyStatus(api.ConnectionDisconnected, err.Error())
+				}
+			}(meta)
+		default:
+			meta.opLock.Lock()
+			parkWrappers(ctx, meta, errStandby)
+			meta.opLock.Unlock()
+			meta.NotifyStatus(api.ConnectionDisconnected, errStandby.Error())
+		}
+	}
+	if leader {
+		conf.Log.Infof("this node becomes the connection leader, connect %d connections", len(named))
+	} else {
+		conf.Log.Warnf("this node loses the connection leader lease, close the named connections")
+	}
+}
+
+// resignLeader releases the lease so that a standby node can take over without waiting for the expiration
+func resignLeader() {
+	nodeID, _ := leaderNodeID.Load().(string)
+	leaderNodeID.Store("")
+	if nodeID == "" || IsStandby() {
+		return
+	}
+	if err := conf.ReleaseLease(leaderLeaseName, nodeID); err != nil {
+		conf.Log.Warnf("release connection leader lease failed: %v", err)
+	}
+}
+
+// newNamedConnWrapper connects the named connection on the leader. On a standby node, it only keeps the metadata.
+func newNamedConnWrapper(ctx api.StreamContext, meta *Meta) *ConnWrapper {
+	if IsStandby() {
+		return newStandbyConnWrapper(meta)
+	}
+	return newConnWrapper(ctx, meta)
+}
+
+func newStandbyConnWrapper(meta *Meta) *ConnWrapper {
+	meta.stats.onCreate()
+	meta.NotifyStatus(api.ConnectionDisconnected, errStandby.Error())
+	cw := &ConnWrapper{
+		ID:          meta.ID,
+		initialized: true,
+		err:         errStandby,
+		readCh:      make(chan struct{}),
+		detachCh:    make(chan struct{}),
+	}
+	cw.setThrottle(meta.Props)
+	close(cw.readCh)
+	return cw
+}
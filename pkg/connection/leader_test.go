@@ -0,0 +1,72 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/context"
+)
+
+func TestLeaderElection(t *testing.T) {
+	require.NoError(t, InitConnectionManager4Test())
+	defer standby.Store(false)
+	ctx := context.Background()
+	// another node holds the lease
+	holder, err := conf.AcquireLease(leaderLeaseName, "other", time.Second)
+	require.NoError(t, err)
+	require.Equal(t, "other", holder)
+	leader, err := tryLead("me", time.Second)
+	require.NoError(t, err)
+	require.False(t, leader)
+	standby.Store(true)
+
+	cw, err := CreateNamedConnection(ctx, "standby1", "mock", nil)
+	require.NoError(t, err)
+	_, err = cw.Wait(ctx)
+	require.ErrorIs(t, err, errStandby)
+	meta := GetAllConnectionsMeta(false)[0]
+	s, e := meta.GetStatus()
+	require.Equal(t, api.ConnectionDisconnected, s)
+	require.Equal(t, errStandby.Error(), e)
+
+	// promoted after the lease is released by the other node
+	require.NoError(t, conf.ReleaseLease(leaderLeaseName, "other"))
+	leader, err = tryLead("me", time.Second)
+	require.NoError(t, err)
+	require.True(t, leader)
+	setLeader(leader)
+	require.False(t, IsStandby())
+	// the wrapper attached by the consumers is kept
+	require.Same(t, cw, meta.cw)
+	require.Eventually(t, func() bool {
+		conn, err := meta.cw.Conn(ctx)
+		return err == nil && conn != nil
+	}, time.Second, 10*time.Millisecond)
+
+	// demoted
+	setLeader(false)
+	require.True(t, IsStandby())
+	require.Same(t, cw, meta.cw)
+	_, err = meta.cw.Wait(ctx)
+	require.ErrorIs(t, err, errStandby)
+	require.NoError(t, conf.ReleaseLease(leaderLeaseName, "me"))
+	require.NoError(t, DropNameConnection(ctx, "standby1"))
+}
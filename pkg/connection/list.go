@@ -0,0 +1,158 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+)
+
+// The sort keys of ListConnections. Prefix the key with "-" to sort in descending order.
+const (
+	SortByID        = "id"
+	SortByType      = "type"
+	SortByStatus    = "status"
+	SortByRefCount  = "refCount"
+	SortByCreatedAt = "createdAt"
+)
+
+// ConnectionFilter selects the connections to list. The empty fields match all the connections.
+type ConnectionFilter struct {
+	Namespace string
+	Typ       string
+	// Status is the last known status, the connections are not pinged
+	Status   string
+	Selector LabelSelector
+	// ForceAll includes the anonymous connections of the rules
+	ForceAll bool
+}
+
+// ConnectionSummary is the brief of a connection to list many connections without the props
+type ConnectionSummary struct {
+	ID       string            `json:"id"`
+	Typ      string            `json:"typ"`
+	Named    bool              `json:"named"`
+	Status   string            `json:"status"`
+	RefCount int               `json:"refCount"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	// CreatedAt is the unix milliseconds when the connection is created in the pool
+	CreatedAt int64 `json:"createdAt"`
+}
+
+// ConnectionPage is a page of the listed connections
+type ConnectionPage struct {
+	// Total is the count of all the matched connections regardless of the page
+	Total       int                 `json:"total"`
+	Connections []ConnectionSummary `json:"connections"`
+}
+
+// ListConnections lists the summaries of the connections matching the filter by page. The connections are sorted by
+// sortBy and then by id, so the pages are stable as long as the connections are not changed. The limit of 0 means
+// no limit.
+func ListConnections(filter ConnectionFilter, sortBy string, limit, offset int) (*ConnectionPage, error) {
+	if limit < 0 || offset < 0 {
+		return nil, errorx.NewWithCode(errorx.ConnectionInvalidErr, "limit and offset must not be negative")
+	}
+	less, err := connectionOrder(sortBy)
+	if err != nil {
+		return nil, err
+	}
+	metas := FilterConnectionsMeta(GetConnectionsMetaInNamespace(filter.Namespace, filter.ForceAll), filter.Selector)
+	items := make([]ConnectionSummary, 0, len(metas))
+	for _, meta := range metas {
+		if filter.Typ != "" && meta.Typ != filter.Typ {
+			continue
+		}
+		s := meta.summary()
+		if filter.Status != "" && s.Status != filter.Status {
+			continue
+		}
+		items = append(items, s)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return less(&items[i], &items[j])
+	})
+	page := &ConnectionPage{Total: len(items)}
+	if offset >= len(items) {
+		page.Connections = []ConnectionSummary{}
+		return page, nil
+	}
+	items = items[offset:]
+	if limit > 0 && limit < len(items) {
+		items = items[:limit]
+	}
+	page.Connections = items
+	return page, nil
+}
+
+func (meta *Meta) summary() ConnectionSummary {
+	s := ConnectionSummary{
+		ID:        meta.ID,
+		Typ:       meta.Typ,
+		Named:     meta.Named,
+		Status:    api.ConnectionConnecting,
+		RefCount:  meta.GetRefCount(),
+		Labels:    meta.Labels(),
+		CreatedAt: meta.stats.createdAt.Load(),
+	}
+	if st, ok := meta.status.Load().(string); ok {
+		s.Status = st
+	}
+	return s
+}
+
+// connectionOrder returns the comparison of the sort key with the id as the tie breaker
+func connectionOrder(sortBy string) (func(a, b *ConnectionSummary) bool, error) {
+	key, desc := strings.CutPrefix(sortBy, "-")
+	var cmp func(a, b *ConnectionSummary) int
+	switch key {
+	case "", SortByID:
+		cmp = func(a, b *ConnectionSummary) int { return 0 }
+	case SortByType:
+		cmp = func(a, b *ConnectionSummary) int { return strings.Compare(a.Typ, b.Typ) }
+	case SortByStatus:
+		cmp = func(a, b *ConnectionSummary) int { return strings.Compare(a.Status, b.Status) }
+	case SortByRefCount:
+		cmp = func(a, b *ConnectionSummary) int { return a.RefCount - b.RefCount }
+	case SortByCreatedAt:
+		cmp = func(a, b *ConnectionSummary) int {
+			switch {
+			case a.CreatedAt < b.CreatedAt:
+				return -1
+			case a.CreatedAt > b.CreatedAt:
+				return 1
+			default:
+				return 0
+			}
+		}
+	default:
+		return nil, errorx.NewWithCode(errorx.ConnectionInvalidErr, fmt.Sprintf("unknown sort key %s", key))
+	}
+	return func(a, b *ConnectionSummary) bool {
+		c := cmp(a, b)
+		if c == 0 {
+			c = strings.Compare(a.ID, b.ID)
+		}
+		if desc {
+			return c > 0
+		}
+		return c < 0
+	}, nil
+}
@@ -0,0 +1,90 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/topo/context"
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+)
+
+func summaryIDs(page *ConnectionPage) []string {
+	ids := make([]string, 0, len(page.Connections))
+	for _, c := range page.Connections {
+		ids = append(ids, c.ID)
+	}
+	return ids
+}
+
+func TestListConnections(t *testing.T) {
+	require.NoError(t, InitConnectionManager4Test())
+	ctx := context.Background()
+	for _, id := range []string{"list3", "list1", "list2"} {
+		_, err := CreateNamedConnection(ctx, id, "mock", map[string]any{LabelsPropKey: map[string]any{"group": id}})
+		require.NoError(t, err)
+	}
+	defer func() {
+		for _, id := range []string{"list1", "list2", "list3"} {
+			require.NoError(t, DropNameConnectionPermanently(ctx, id))
+		}
+	}()
+
+	page, err := ListConnections(ConnectionFilter{}, "", 0, 0)
+	require.NoError(t, err)
+	require.Equal(t, 3, page.Total)
+	require.Equal(t, []string{"list1", "list2", "list3"}, summaryIDs(page))
+	require.Equal(t, "mock", page.Connections[0].Typ)
+	require.True(t, page.Connections[0].Named)
+	require.Equal(t, map[string]string{"group": "list1"}, page.Connections[0].Labels)
+
+	page, err = ListConnections(ConnectionFilter{}, "-id", 2, 0)
+	require.NoError(t, err)
+	require.Equal(t, 3, page.Total)
+	require.Equal(t, []string{"list3", "list2"}, summaryIDs(page))
+	page, err = ListConnections(ConnectionFilter{}, "-id", 2, 2)
+	require.NoError(t, err)
+	require.Equal(t, []string{"list1"}, summaryIDs(page))
+	page, err = ListConnections(ConnectionFilter{}, "id", 2, 5)
+	require.NoError(t, err)
+	require.Equal(t, 3, page.Total)
+	require.Empty(t, page.Connections)
+
+	// the ties are ordered by id
+	page, err = ListConnections(ConnectionFilter{}, SortByType, 0, 0)
+	require.NoError(t, err)
+	require.Equal(t, []string{"list1", "list2", "list3"}, summaryIDs(page))
+	page, err = ListConnections(ConnectionFilter{}, "-"+SortByRefCount, 0, 0)
+	require.NoError(t, err)
+	require.Equal(t, []string{"list3", "list2", "list1"}, summaryIDs(page))
+
+	sel, err := ParseLabelSelector("group=list2")
+	require.NoError(t, err)
+	page, err = ListConnections(ConnectionFilter{Selector: sel}, "", 0, 0)
+	require.NoError(t, err)
+	require.Equal(t, []string{"list2"}, summaryIDs(page))
+	page, err = ListConnections(ConnectionFilter{Typ: "other"}, "", 0, 0)
+	require.NoError(t, err)
+	require.Equal(t, 0, page.Total)
+	require.NotNil(t, page.Connections)
+
+	_, err = ListConnections(ConnectionFilter{}, "props", 0, 0)
+	require.Error(t, err)
+	require.Equal(t, errorx.KindPermanent, errorx.KindOf(err))
+	_, err = ListConnections(ConnectionFilter{}, "", -1, 0)
+	require.Error(t, err)
+}
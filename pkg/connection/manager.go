@@ -0,0 +1,150 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"time"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+)
+
+// The package functions operate on the default manager of the engine.
+
+// DefaultConnectionManager returns the manager of the engine
+func DefaultConnectionManager() *ConnectionManager {
+	return globalConnectionManager
+}
+
+// FetchConnection is called by source/sink to get or create an anonymous connection instance in the pool
+func FetchConnection(ctx api.StreamContext, refId, typ string, props map[string]interface{}, sc api.StatusChangeHandler) (*ConnWrapper, error) {
+	return globalConnectionManager.Fetch(ctx, refId, typ, props, sc)
+}
+
+// AttachWait attaches the existing connection and waits until it is connected or the timeout expires. Detach it by
+// DetachConnection with the same ctx.
+func AttachWait(ctx api.StreamContext, id string, timeout time.Duration) (*ConnWrapper, error) {
+	return globalConnectionManager.AttachWait(ctx, id, timeout, nil)
+}
+
+func DetachConnection(ctx api.StreamContext, conId string) error {
+	return globalConnectionManager.Detach(ctx, conId)
+}
+
+// ReloadNamedConnection is called when server starts. It initializes all stored named connections.
+func ReloadNamedConnection() error {
+	return globalConnectionManager.Reload()
+}
+
+func CreateNamedConnection(ctx api.StreamContext, id, typ string, props map[string]any) (*ConnWrapper, error) {
+	return globalConnectionManager.Create(ctx, id, typ, props)
+}
+
+func GetAllConnectionsMeta(forceAll bool) []*Meta {
+	return globalConnectionManager.List(forceAll)
+}
+
+func GetConnectionDetail(ctx api.StreamContext, id string) (*Meta, error) {
+	return globalConnectionManager.Get(ctx, id)
+}
+
+func DropNameConnection(ctx api.StreamContext, selId string) error {
+	return globalConnectionManager.Drop(ctx, selId)
+}
+
+// DropNameConnectionPermanently drops the named connection without retaining it in the trash
+func DropNameConnectionPermanently(ctx api.StreamContext, selId string) error {
+	return globalConnectionManager.DropPermanently(ctx, selId)
+}
+
+func UpdateConnection(ctx api.StreamContext, id, typ string, props map[string]any) (*ConnWrapper, error) {
+	return globalConnectionManager.Update(ctx, id, typ, props)
+}
+
+// UpdateNamedConnection changes the props of the named connection without detaching the rules
+func UpdateNamedConnection(ctx api.StreamContext, id string, props map[string]any) (*ConnWrapper, error) {
+	return globalConnectionManager.UpdateNamed(ctx, id, props)
+}
+
+// Health returns the connection counts by status
+func Health() *PoolHealth {
+	return globalConnectionManager.Health()
+}
+
+// ShutdownConnectionManager drains the references and then closes all the connections
+func ShutdownConnectionManager(ctx api.StreamContext, timeout time.Duration) *ShutdownReport {
+	return globalConnectionManager.Shutdown(ctx, timeout)
+}
+
+// WarmUp creates the anonymous connections used by the last run of the rule ahead of its start. It returns the count
+// of the warmed connections. It does nothing if the rule is warmed already or has never run.
+func WarmUp(ruleId string) int {
+	return globalConnectionManager.WarmUp(ruleId)
+}
+
+// ReleaseWarmUp removes the warm-up reference of the rule. The warmed connections are closed if the rule has not
+// attached them.
+func ReleaseWarmUp(ruleId string) {
+	globalConnectionManager.ReleaseWarmUp(ruleId)
+}
+
+// IsWarmedUp returns whether the connections of the rule are held by the warm-up
+func IsWarmedUp(ruleId string) bool {
+	return globalConnectionManager.IsWarmedUp(ruleId)
+}
+
+// ForgetWarmUp releases the warm-up of the deleted rule and forgets its connections
+func ForgetWarmUp(ruleId string) {
+	globalConnectionManager.ForgetWarmUp(ruleId)
+}
+
+// SnapshotPool dumps all the connections in the pool sorted by id
+func SnapshotPool() (*PoolSnapshot, error) {
+	return globalConnectionManager.SnapshotPool()
+}
+
+// RestorePool creates the connections of the snapshot which are not in the pool
+func RestorePool(ctx api.StreamContext, snap *PoolSnapshot, hold time.Duration) (*RestoreResult, error) {
+	return globalConnectionManager.RestorePool(ctx, snap, hold)
+}
+
+// Shutdown closes all the connections in the pool and rejects new connections
+func Shutdown(ctx api.StreamContext) *ShutdownReport {
+	return globalConnectionManager.Close(ctx)
+}
+
+// PauseConnection closes the transport of the named connection and keeps it closed until it is resumed
+func PauseConnection(ctx api.StreamContext, id string) error {
+	return globalConnectionManager.Pause(ctx, id)
+}
+
+// ResumeConnection connects the paused named connection again
+func ResumeConnection(ctx api.StreamContext, id string) error {
+	return globalConnectionManager.Resume(ctx, id)
+}
+
+// RotateConnectionCerts reloads the certificates of the named connection and reconnects it
+func RotateConnectionCerts(ctx api.StreamContext, id string) error {
+	return globalConnectionManager.RotateCerts(ctx, id)
+}
+
+// SetConnectionLabels replaces the labels of the named connection. The connection is not reconnected.
+func SetConnectionLabels(ctx api.StreamContext, id string, labels map[string]string) error {
+	return globalConnectionManager.SetLabels(ctx, id, labels)
+}
+
+// RestoreConnection recreates the dropped named connection from the trash with its original props
+func RestoreConnection(ctx api.StreamContext, id string) (*ConnWrapper, error) {
+	return globalConnectionManager.Restore(ctx, id)
+}
@@ -0,0 +1,104 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	gocontext "context"
+	"testing"
+	"time"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/context"
+	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+	"github.com/lf-edge/ekuiper/v2/pkg/model"
+	"github.com/lf-edge/ekuiper/v2/pkg/modules"
+)
+
+func TestIsolatedManagers(t *testing.T) {
+	require.NoError(t, InitConnectionManager4Test())
+	ctx := context.Background()
+	m1, m2 := NewConnectionManager(), NewConnectionManager()
+	_, err := m1.Create(ctx, "iso1", "mock", nil)
+	require.NoError(t, err)
+	defer func() {
+		_ = m1.DropPermanently(ctx, "iso1")
+	}()
+	_, err = m2.Get(ctx, "iso1")
+	require.Error(t, err)
+	_, err = GetConnectionDetail(ctx, "iso1")
+	require.Error(t, err)
+
+	// the anonymous connections of the same ref are separated
+	refId := extractRefId(ctx)
+	cw1, err := m1.Fetch(ctx, refId, "mock", nil, nil)
+	require.NoError(t, err)
+	cw2, err := m2.Fetch(ctx, refId, "mock", nil, nil)
+	require.NoError(t, err)
+	require.NotSame(t, cw1, cw2)
+	require.Len(t, m1.List(true), 2)
+	require.Len(t, m2.List(true), 1)
+
+	require.NoError(t, m2.Detach(ctx, refId))
+	require.Empty(t, m2.List(true))
+	require.Equal(t, 1, m1.getConnectionRef(refId))
+
+	report := m2.Close(ctx)
+	require.Empty(t, report.Failed)
+	_, err = m2.Fetch(ctx, refId, "mock", nil, nil)
+	require.Error(t, err)
+	require.NoError(t, m1.Detach(ctx, refId))
+	require.Equal(t, 1, m1.Health().Total)
+}
+
+func TestOwnershipContext(t *testing.T) {
+	require.NoError(t, InitConnectionManager4Test())
+	conf.Config.Connection.TypeRetry = map[string]model.RetryConf{"faildial": {Policy: RetryConstant, Interval: cast.DurationConf(time.Hour)}}
+	defer func() {
+		conf.Config.Connection.TypeRetry = nil
+	}()
+	require.NoError(t, modules.RegisterConnectionType("faildial", func(_ api.StreamContext) modules.Connection {
+		return &failDialConnection{}
+	}))
+	defer modules.UnregisterConnection("faildial")
+	ctx := context.Background()
+	defer func() {
+		_ = dropConnectionStore("faildial", "own1")
+		_ = dropConnectionStore("faildial", "own2")
+	}()
+
+	// the ownership is released when dropped
+	m := NewConnectionManager()
+	_, err := m.Create(ctx, "own1", "faildial", nil)
+	require.NoError(t, err)
+	meta, err := m.Get(ctx, "own1")
+	require.NoError(t, err)
+	owner := meta.ownerContext(ctx)
+	require.NoError(t, owner.Err())
+	require.NoError(t, m.DropPermanently(ctx, "own1"))
+	require.ErrorIs(t, owner.Err(), gocontext.Canceled)
+
+	// the stuck creation is aborted when the manager is closed
+	cw, err := m.Create(ctx, "own2", "faildial", nil)
+	require.NoError(t, err)
+	meta, err = m.Get(ctx, "own2")
+	require.NoError(t, err)
+	m.Close(ctx)
+	_, err = cw.Wait(ctx)
+	require.ErrorIs(t, err, gocontext.Canceled)
+	require.ErrorIs(t, meta.ownerContext(ctx).Err(), gocontext.Canceled)
+}
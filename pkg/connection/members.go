@@ -0,0 +1,142 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"fmt"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+
+	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+)
+
+// PoolSizePropKey is the reserved prop to back a named connection by several physical connections, such as
+// {"$poolSize": 4}. The attaches are handed out to the least loaded one, while the status, the updates and the drop
+// apply to all of them. It helps the high-throughput sinks for which a single connection is the bottleneck.
+const PoolSizePropKey = "$poolSize"
+
+const maxPoolSize = 64
+
+// parsePoolSize returns the count of the physical connections of the props. Default to 1.
+func parsePoolSize(props map[string]any) (int, error) {
+	v, ok := props[PoolSizePropKey]
+	if !ok || v == nil {
+		return 1, nil
+	}
+	n, err := cast.ToInt(v, cast.CONVERT_SAMEKIND)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %v", PoolSizePropKey, err)
+	}
+	if n < 1 || n > maxPoolSize {
+		return 0, fmt.Errorf("invalid %s: %d, it must be between 1 and %d", PoolSizePropKey, n, maxPoolSize)
+	}
+	return n, nil
+}
+
+// poolSize returns the count of the physical connections. Only the named connections are pooled.
+func (meta *Meta) poolSize() int {
+	if !meta.Named {
+		return 1
+	}
+	n, err := parsePoolSize(meta.Props)
+	if err != nil {
+		return 1
+	}
+	return n
+}
+
+// newWrappers creates the wrappers of all the physical connections by the constructor. It must be called before the
+// meta is published or with the pool lock.
+func (meta *Meta) newWrappers(create func() *ConnWrapper) {
+	meta.cw = create()
+	meta.members = nil
+	for i := 1; i < meta.poolSize(); i++ {
+		meta.members = append(meta.members, create())
+	}
+}
+
+// wrappers returns the wrappers of all the physical connections, the first is the primary one
+func (meta *Meta) wrappers() []*ConnWrapper {
+	if len(meta.members) == 0 {
+		return []*ConnWrapper{meta.cw}
+	}
+	return append([]*ConnWrapper{meta.cw}, meta.members...)
+}
+
+// pick hands out the least loaded physical connection to the reference. The first one wins the ties, so the
+// references are spread round-robin when they come and go evenly.
+func (meta *Meta) pick(refId string) *ConnWrapper {
+	if len(meta.members) == 0 {
+		return meta.cw
+	}
+	loads := meta.loads()
+	cw := meta.cw
+	for _, m := range meta.members {
+		if loads[m] < loads[cw] {
+			cw = m
+		}
+	}
+	meta.memberRefs.Store(refId, cw)
+	return cw
+}
+
+// loads counts the references handed out to each physical connection
+func (meta *Meta) loads() map[*ConnWrapper]int {
+	result := make(map[*ConnWrapper]int, len(meta.members)+1)
+	meta.memberRefs.Range(func(_, v any) bool {
+		result[v.(*ConnWrapper)]++
+		return true
+	})
+	return result
+}
+
+// poolRefs returns the reference count of each physical connection of the pooled connection
+func (meta *Meta) poolRefs() []int {
+	if len(meta.members) == 0 {
+		return nil
+	}
+	all := meta.wrappers()
+	loads := meta.loads()
+	result := make([]int, len(all))
+	for i, cw := range all {
+		result[i] = loads[cw]
+	}
+	return result
+}
+
+func (meta *Meta) setThrottle(props map[string]any) {
+	for _, cw := range meta.wrappers() {
+		cw.setThrottle(props)
+	}
+}
+
+// pingMembers pings the other physical connections. The pooled connection is disconnected if any of them is down.
+func (meta *Meta) pingMembers(ctx api.StreamContext) error {
+	for i, cw := range meta.members {
+		if !cw.IsInitialized() {
+			continue
+		}
+		conn, err := cw.Wait(ctx)
+		if err == nil && conn != nil {
+			err = safeCall(meta.ID, "ping", func() error {
+				return conn.Ping(ctx)
+			})
+		}
+		if err != nil {
+			return fmt.Errorf("pooled connection %d: %v", i+1, err)
+		}
+	}
+	return nil
+}
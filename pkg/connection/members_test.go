@@ -0,0 +1,106 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/topo/context"
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+	mockContext "github.com/lf-edge/ekuiper/v2/pkg/mock/context"
+)
+
+func TestPooledConnection(t *testing.T) {
+	require.NoError(t, InitConnectionManager4Test())
+	ctx := context.Background()
+	_, err := CreateNamedConnection(ctx, "pooled0", "mock", map[string]any{PoolSizePropKey: 0})
+	require.Error(t, err)
+	_, err = CreateNamedConnection(ctx, "pooled1", "mock", map[string]any{PoolSizePropKey: 3})
+	require.NoError(t, err)
+	defer func() {
+		_ = DropNameConnectionPermanently(ctx, "pooled1")
+	}()
+	meta, err := GetConnectionDetail(ctx, "pooled1")
+	require.NoError(t, err)
+	require.Len(t, meta.wrappers(), 3)
+	require.Eventually(t, func() bool {
+		s, _ := meta.GetStatus()
+		return s == api.ConnectionConnected
+	}, time.Second, 10*time.Millisecond)
+
+	props := map[string]any{"connectionSelector": "pooled1"}
+	var (
+		ctxs []api.StreamContext
+		cws  []*ConnWrapper
+	)
+	for _, rule := range []string{"p1", "p2", "p3", "p4"} {
+		rctx := mockContext.NewMockContext(rule, "op")
+		cw, err := FetchConnection(rctx, extractRefId(rctx), "mock", props, nil)
+		require.NoError(t, err)
+		ctxs = append(ctxs, rctx)
+		cws = append(cws, cw)
+	}
+	// spread to the least loaded
+	require.Same(t, meta.cw, cws[0])
+	require.Same(t, meta.members[0], cws[1])
+	require.Same(t, meta.members[1], cws[2])
+	require.Same(t, meta.cw, cws[3])
+	require.Equal(t, []int{2, 1, 1}, meta.Stats().PoolRefs)
+	conns := make(map[any]struct{})
+	for _, cw := range meta.wrappers() {
+		conn, err := cw.Wait(ctx)
+		require.NoError(t, err)
+		conns[conn] = struct{}{}
+	}
+	require.Len(t, conns, 3)
+	require.NoError(t, DetachConnection(ctxs[1], "pooled1"))
+	rctx := mockContext.NewMockContext("p5", "op")
+	cw, err := FetchConnection(rctx, extractRefId(rctx), "mock", props, nil)
+	require.NoError(t, err)
+	require.Same(t, meta.members[0], cw)
+	ctxs[1] = rctx
+
+	// all the physical connections are swapped on update
+	_, err = UpdateNamedConnection(ctx, "pooled1", map[string]any{PoolSizePropKey: 3, "a": 1})
+	require.NoError(t, err)
+	for _, cw := range meta.wrappers() {
+		conn, err := cw.Wait(ctx)
+		require.NoError(t, err)
+		_, ok := conns[conn]
+		require.False(t, ok)
+	}
+	_, err = UpdateNamedConnection(ctx, "pooled1", map[string]any{PoolSizePropKey: 2})
+	code, _ := errorx.GetErrorCode(err)
+	require.Equal(t, errorx.ConnectionPropsErr, code)
+
+	require.NoError(t, PauseConnection(ctx, "pooled1"))
+	for _, cw := range meta.wrappers() {
+		_, err := cw.Wait(ctx)
+		require.Equal(t, errPaused, err)
+	}
+	require.NoError(t, ResumeConnection(ctx, "pooled1"))
+	for _, cw := range meta.wrappers() {
+		_, err := cw.Wait(ctx)
+		require.NoError(t, err)
+	}
+	for _, c := range ctxs {
+		require.NoError(t, DetachConnection(c, "pooled1"))
+	}
+	require.Equal(t, []int{0, 0, 0}, meta.Stats().PoolRefs)
+}
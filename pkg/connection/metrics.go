@@ -14,12 +14,25 @@
 
 package connection
 
-import "github.com/prometheus/client_golang/prometheus"
+import (
+	"context"
+	"sync"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/pkg/tracer"
+)
 
 const (
 	LblName = "name"
 )
 
+var registerOtelOnce sync.Once
+
 var ConnStatusGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
 	Namespace: "kuiper",
 	Subsystem: "conn_status",
@@ -27,6 +40,124 @@ var ConnStatusGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
 	Help:      "gauge of connection status",
 }, []string{LblName})
 
+// The labels of the pool metrics
+const (
+	LblID     = "id"
+	LblType   = "typ"
+	LblStatus = "status"
+	LblReason = "reason"
+)
+
+// The reasons of the connection evictions
+const (
+	// EvictReleased means the anonymous connection is released by its last rule
+	EvictReleased = "released"
+	// EvictDropped means the named connection is dropped
+	EvictDropped = "dropped"
+	// EvictForceClosed means the connection is force closed for exceeding the resource limits
+	EvictForceClosed = "forceClosed"
+)
+
+var (
+	// ConnCreateDuration is the time from the start of the creation to connected, including the retries
+	ConnCreateDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "kuiper",
+		Subsystem: "connection",
+		Name:      "create_duration_seconds",
+		Help:      "Histogram of the connection creation duration including the retries",
+		Buckets:   []float64{0.01, 0.05, 0.1, 0.5, 1, 5, 10, 30, 60},
+	}, []string{LblType})
+	// ConnEvictions counts the connections removed from the pool or force closed. The connection ids are not labeled
+	// to bound the series of the anonymous connections.
+	ConnEvictions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "kuiper",
+		Subsystem: "connection",
+		Name:      "evictions_total",
+		Help:      "Count of the connections removed from the pool",
+	}, []string{LblType, LblReason})
+)
+
 func init() {
-	prometheus.MustRegister(ConnStatusGauge)
+	prometheus.MustRegister(ConnStatusGauge, ConnCreateDuration, ConnEvictions, poolCollector{})
+}
+
+var (
+	poolConnectionsDesc = prometheus.NewDesc("kuiper_connection_pool_connections",
+		"Count of the connections in the pool by status", []string{LblStatus}, nil)
+	connUpDesc = prometheus.NewDesc("kuiper_connection_up",
+		"Whether the connection is connected", []string{LblID, LblType}, nil)
+	connRefsDesc = prometheus.NewDesc("kuiper_connection_refs",
+		"Count of the references of the connection", []string{LblID, LblType}, nil)
+	connReconnectsDesc = prometheus.NewDesc("kuiper_connection_reconnects_total",
+		"Count of the reconnections of the connection", []string{LblID, LblType}, nil)
+	connRetriesDesc = prometheus.NewDesc("kuiper_connection_retries_total",
+		"Count of the dial retries of the connection by the backoff", []string{LblID, LblType}, nil)
+	connErrorsDesc = prometheus.NewDesc("kuiper_connection_errors_total",
+		"Count of the dial and ping failures of the connection", []string{LblID, LblType}, nil)
+)
+
+// poolCollector exports the metrics of the connections in the pool when scraped, so that the removed connections
+// don't leave stale series. The status is the latest known one without pinging.
+type poolCollector struct{}
+
+func (poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- poolConnectionsDesc
+	ch <- connUpDesc
+	ch <- connRefsDesc
+	ch <- connReconnectsDesc
+	ch <- connRetriesDesc
+	ch <- connErrorsDesc
+}
+
+func (poolCollector) Collect(ch chan<- prometheus.Metric) {
+	if globalConnectionManager == nil {
+		return
+	}
+	counts := map[string]int{
+		api.ConnectionConnected:    0,
+		api.ConnectionConnecting:   0,
+		api.ConnectionDisconnected: 0,
+		ConnectionWarming:          0,
+		ConnectionPaused:           0,
+	}
+	for _, meta := range globalConnectionManager.load() {
+		status := api.ConnectionConnecting
+		if s, ok := meta.status.Load().(string); ok {
+			status = s
+		}
+		if meta.paused.Load() {
+			status = ConnectionPaused
+		}
+		counts[status]++
+		up := 0.0
+		if status == api.ConnectionConnected {
+			up = 1
+		}
+		ch <- prometheus.MustNewConstMetric(connUpDesc, prometheus.GaugeValue, up, meta.ID, meta.Typ)
+		ch <- prometheus.MustNewConstMetric(connRefsDesc, prometheus.GaugeValue, float64(meta.GetRefCount()), meta.ID, meta.Typ)
+		ch <- prometheus.MustNewConstMetric(connReconnectsDesc, prometheus.CounterValue, float64(meta.stats.reconnects.Load()), meta.ID, meta.Typ)
+		ch <- prometheus.MustNewConstMetric(connRetriesDesc, prometheus.CounterValue, float64(meta.stats.retries.Load()), meta.ID, meta.Typ)
+		ch <- prometheus.MustNewConstMetric(connErrorsDesc, prometheus.CounterValue, float64(meta.stats.errors.Load()), meta.ID, meta.Typ)
+	}
+	for status, count := range counts {
+		ch <- prometheus.MustNewConstMetric(poolConnectionsDesc, prometheus.GaugeValue, float64(count), status)
+	}
+}
+
+// registerOtelGauges exports the connection counts by status to the OTel metrics pipeline
+func registerOtelGauges() {
+	registerOtelOnce.Do(func() {
+		_, err := tracer.Meter().Int64ObservableGauge("kuiper.connection.count",
+			metric.WithDescription("The count of the connections in the pool by status"),
+			metric.WithInt64Callback(func(_ context.Context, o metric.Int64Observer) error {
+				h := Health()
+				for status, count := range h.States {
+					o.Observe(int64(count), metric.WithAttributes(attribute.String("status", status)))
+				}
+				return nil
+			}))
+		if err != nil {
+			conf.Log.Warnf("register connection otel gauge error: %v", err)
+		}
+	})
 }
@@ -0,0 +1,101 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+
+	mockContext "github.com/lf-edge/ekuiper/v2/pkg/mock/context"
+)
+
+func TestPoolCollector(t *testing.T) {
+	require.NoError(t, InitConnectionManager4Test())
+	ctx := mockContext.NewMockContext("metricRule", "op1")
+	cw, err := FetchConnection(ctx, "metricConn", "mock", map[string]any{}, nil)
+	require.NoError(t, err)
+	_, err = cw.Wait(ctx)
+	require.NoError(t, err)
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(poolCollector{})
+	mfs, err := reg.Gather()
+	require.NoError(t, err)
+	values := make(map[string]float64)
+	for _, mf := range mfs {
+		for _, m := range mf.GetMetric() {
+			key := mf.GetName()
+			for _, l := range m.GetLabel() {
+				key += "," + l.GetName() + "=" + l.GetValue()
+			}
+			if m.GetGauge() != nil {
+				values[key] = m.GetGauge().GetValue()
+			} else {
+				values[key] = m.GetCounter().GetValue()
+			}
+		}
+	}
+	require.Equal(t, 1.0, values["kuiper_connection_up,id=metricConn,typ=mock"])
+	require.Equal(t, 1.0, values["kuiper_connection_refs,id=metricConn,typ=mock"])
+	require.Equal(t, 0.0, values["kuiper_connection_retries_total,id=metricConn,typ=mock"])
+	require.Equal(t, 1.0, values["kuiper_connection_pool_connections,status=connected"])
+	require.Equal(t, 0.0, values["kuiper_connection_pool_connections,status=paused"])
+
+	// the released connection is counted as evicted and its series are gone
+	before := evictions(t, "mock", EvictReleased)
+	require.NoError(t, DetachConnection(ctx, "metricConn"))
+	require.Equal(t, before+1, evictions(t, "mock", EvictReleased))
+	mfs, err = reg.Gather()
+	require.NoError(t, err)
+	for _, mf := range mfs {
+		require.NotEqual(t, "kuiper_connection_up", mf.GetName())
+	}
+}
+
+func TestEvictionsOfDrop(t *testing.T) {
+	require.NoError(t, InitConnectionManager4Test())
+	ctx := mockContext.NewMockContext("", "")
+	_, err := CreateNamedConnection(ctx, "evictConn", "mock", map[string]any{"a": 1})
+	require.NoError(t, err)
+	before := evictions(t, "mock", EvictDropped)
+	// the replaced connection is not evicted
+	_, err = UpdateConnection(ctx, "evictConn", "mock", map[string]any{"a": 2})
+	require.NoError(t, err)
+	require.Equal(t, before, evictions(t, "mock", EvictDropped))
+	require.NoError(t, DropNameConnectionPermanently(ctx, "evictConn"))
+	require.Equal(t, before+1, evictions(t, "mock", EvictDropped))
+}
+
+func evictions(t *testing.T, typ, reason string) float64 {
+	mfs, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+	for _, mf := range mfs {
+		if mf.GetName() != "kuiper_connection_evictions_total" {
+			continue
+		}
+		for _, m := range mf.GetMetric() {
+			labels := make(map[string]string)
+			for _, l := range m.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+			if labels[LblType] == typ && labels[LblReason] == reason {
+				return m.GetCounter().GetValue()
+			}
+		}
+	}
+	return 0
+}
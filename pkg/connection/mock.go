@@ -65,6 +65,10 @@ func (m *mockConnection) Ref(ctx api.StreamContext) int {
 	return m.ref
 }
 
+func (m *mockConnection) CanThrottle() bool {
+	return true
+}
+
 func CreateMockConnection(ctx api.StreamContext) modules.Connection {
 	return &mockConnection{ref: 0}
 }
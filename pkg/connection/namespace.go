@@ -0,0 +1,114 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"fmt"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+
+	topoContext "github.com/lf-edge/ekuiper/v2/internal/topo/context"
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+)
+
+// The namespace isolates the named connections of the tenants. A connection is in the namespace of the tenant whose
+// prefix matches its id. The caller is in the namespace set explicitly in the context, or else in the namespace of
+// the tenant whose prefix matches its rule id. The management callers set in the default namespace and the internal
+// callers without a rule are not restricted. A rule in the default namespace can only access the connections of the
+// default namespace, so the connections are shared as before if no tenant is configured.
+
+// DefaultNamespace is the namespace of the connections and the callers not belonging to any tenant
+const DefaultNamespace = ""
+
+type namespaceKey struct{}
+
+// WithNamespace sets the namespace of the caller explicitly, which takes precedence over the rule id
+func WithNamespace(ctx api.StreamContext, ns string) api.StreamContext {
+	var parent *topoContext.DefaultContext
+	switch c := ctx.(type) {
+	case *topoContext.DefaultContext:
+		// derive a new context since WithValue changes the parent in place
+		parent = c.WithRuleId(c.GetRuleId()).(*topoContext.DefaultContext)
+	case nil:
+		parent = topoContext.Background()
+	default:
+		parent = topoContext.WithContext(c)
+	}
+	return topoContext.WithValue(parent, namespaceKey{}, ns)
+}
+
+// NamespaceOf returns the namespace of the caller
+func NamespaceOf(ctx api.StreamContext) string {
+	ns, _ := namespaceOf(ctx)
+	return ns
+}
+
+// namespaceOf returns the namespace of the caller and whether the caller is restricted to it
+func namespaceOf(ctx api.StreamContext) (string, bool) {
+	if ctx == nil {
+		return DefaultNamespace, false
+	}
+	if ns, ok := ctx.Value(namespaceKey{}).(string); ok {
+		return ns, ns != DefaultNamespace
+	}
+	ruleId := ctx.GetRuleId()
+	if t := tenantOf(ruleId); t != nil {
+		return t.name, true
+	}
+	return DefaultNamespace, ruleId != ""
+}
+
+// ConnectionNamespace returns the namespace of the connection id
+func ConnectionNamespace(id string) string {
+	if t := tenantOf(id); t != nil {
+		return t.name
+	}
+	return DefaultNamespace
+}
+
+// CheckNamespace checks whether the caller can access the named connection. The connection out of the namespace is
+// reported as not existed so that its existence is not leaked to the other tenants.
+func CheckNamespace(ctx api.StreamContext, id string) error {
+	ns, restricted := namespaceOf(ctx)
+	if !restricted || ConnectionNamespace(id) == ns {
+		return nil
+	}
+	return errorx.NewWithCode(errorx.NOT_FOUND, fmt.Sprintf("connection %s not existed", id))
+}
+
+// checkCreateNamespace checks whether the caller can create the connection in its namespace
+func checkCreateNamespace(ctx api.StreamContext, id string) error {
+	ns, restricted := namespaceOf(ctx)
+	if !restricted || ConnectionNamespace(id) == ns {
+		return nil
+	}
+	return errorx.NewWithCode(errorx.ConnectionNamespaceErr, fmt.Sprintf("connection %s is out of the namespace %s", id, ns))
+}
+
+// GetConnectionsMetaInNamespace lists the connections in the namespace. The default namespace lists all the
+// connections like GetAllConnectionsMeta.
+func GetConnectionsMetaInNamespace(ns string, forceAll bool) []*Meta {
+	metaList := GetAllConnectionsMeta(forceAll)
+	if ns == DefaultNamespace {
+		return metaList
+	}
+	result := make([]*Meta, 0, len(metaList))
+	for _, meta := range metaList {
+		if ConnectionNamespace(meta.ID) == ns {
+			result = append(result, meta)
+		}
+	}
+	return result
+}
@@ -0,0 +1,96 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/context"
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+	mockContext "github.com/lf-edge/ekuiper/v2/pkg/mock/context"
+	"github.com/lf-edge/ekuiper/v2/pkg/model"
+)
+
+func TestNamespaceIsolation(t *testing.T) {
+	conf.InitConf()
+	origin := conf.Config.Connection.Tenants
+	defer func() {
+		conf.Config.Connection.Tenants = origin
+		require.NoError(t, InitConnectionManager4Test())
+	}()
+	conf.Config.Connection.Tenants = map[string]model.TenantConf{
+		"a": {},
+		"b": {},
+	}
+	require.NoError(t, InitConnectionManager4Test())
+	ctx := context.Background()
+	_, err := CreateNamedConnection(ctx, "a_ns1", "mock", map[string]any{})
+	require.NoError(t, err)
+	_, err = CreateNamedConnection(ctx, "b_ns1", "mock", map[string]any{})
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, DropNameConnectionPermanently(ctx, "a_ns1"))
+		require.NoError(t, DropNameConnectionPermanently(ctx, "b_ns1"))
+	}()
+
+	// derived from the rule id
+	ruleCtx := mockContext.NewMockContext("a_rule1", "op1")
+	require.Equal(t, "a", NamespaceOf(ruleCtx))
+	_, err = FetchConnection(ruleCtx, extractRefId(ruleCtx), "mock", map[string]any{"connectionSelector": "b_ns1"}, nil)
+	code, _ := errorx.GetErrorCode(err)
+	require.Equal(t, errorx.NOT_FOUND, code)
+	_, err = FetchConnection(ruleCtx, extractRefId(ruleCtx), "mock", map[string]any{"connectionSelector": "a_ns1"}, nil)
+	require.NoError(t, err)
+	require.NoError(t, DetachConnection(ruleCtx, "a_ns1"))
+
+	// set explicitly
+	nsCtx := WithNamespace(ctx, "a")
+	require.Equal(t, DefaultNamespace, NamespaceOf(ctx))
+	_, err = GetConnectionDetail(nsCtx, "b_ns1")
+	code, _ = errorx.GetErrorCode(err)
+	require.Equal(t, errorx.NOT_FOUND, code)
+	_, err = GetConnectionDetail(nsCtx, "a_ns1")
+	require.NoError(t, err)
+	_, err = CreateNamedConnection(nsCtx, "b_ns2", "mock", map[string]any{})
+	code, _ = errorx.GetErrorCode(err)
+	require.Equal(t, errorx.ConnectionNamespaceErr, code)
+	err = DropNameConnection(nsCtx, "b_ns1")
+	code, _ = errorx.GetErrorCode(err)
+	require.Equal(t, errorx.NOT_FOUND, code)
+	_, err = UpdateNamedConnection(nsCtx, "b_ns1", map[string]any{"a": 1})
+	code, _ = errorx.GetErrorCode(err)
+	require.Equal(t, errorx.NOT_FOUND, code)
+
+	metas := GetConnectionsMetaInNamespace("a", false)
+	require.Len(t, metas, 1)
+	require.Equal(t, "a_ns1", metas[0].ID)
+	require.GreaterOrEqual(t, len(GetConnectionsMetaInNamespace(DefaultNamespace, false)), 2)
+
+	// the rules in the default namespace cannot access the tenants
+	defaultRuleCtx := mockContext.NewMockContext("rule1", "op1")
+	_, err = FetchConnection(defaultRuleCtx, extractRefId(defaultRuleCtx), "mock", map[string]any{"connectionSelector": "b_ns1"}, nil)
+	code, _ = errorx.GetErrorCode(err)
+	require.Equal(t, errorx.NOT_FOUND, code)
+
+	// the management and internal callers in the default namespace are not restricted
+	_, err = GetConnectionDetail(WithNamespace(ctx, DefaultNamespace), "b_ns1")
+	require.NoError(t, err)
+	_, err = FetchConnection(ctx, extractRefId(ctx), "mock", map[string]any{"connectionSelector": "b_ns1"}, nil)
+	require.NoError(t, err)
+	require.NoError(t, DetachConnection(ctx, "b_ns1"))
+}
@@ -0,0 +1,132 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+	"github.com/lf-edge/ekuiper/v2/pkg/modules"
+)
+
+// ConnectionPaused is the status of the paused named connection. The transport is closed while the meta, the
+// references and the stored props are kept.
+const ConnectionPaused = "paused"
+
+var errPaused = errors.New("connection is paused")
+
+// park takes the connection out of the wrapper so that the attached consumers get the err when they wait for it. It
+// returns the previous connection and its cancel func to release.
+func (cw *ConnWrapper) park(err error) (modules.Connection, func()) {
+	cw.l.Lock()
+	defer cw.l.Unlock()
+	old, oldCancel := cw.conn, cw.cancel
+	if cw.err != nil {
+		old = nil
+	}
+	cw.initialized = true
+	cw.conn, cw.err, cw.cancel = nil, err, nil
+	return old, oldCancel
+}
+
+// parkWrappers takes the connections out of all the wrappers of the meta and closes them together with the swapped
+// out ones. The attached consumers keep their wrappers, which get the connection again once swapped in.
+func parkWrappers(ctx api.StreamContext, meta *Meta, err error) {
+	for _, cw := range meta.wrappers() {
+		old, oldCancel := cw.park(err)
+		closeStale(ctx, meta.ID, append(cw.drainStale(), staleConn{conn: old, cancel: oldCancel}))
+	}
+}
+
+// IsPaused returns whether the connection is paused
+func (meta *Meta) IsPaused() bool {
+	return meta.paused.Load()
+}
+
+func (m *ConnectionManager) pausableConnection(ctx api.StreamContext, id string) (*Meta, error) {
+	if id == "" {
+		return nil, errorx.NewWithCode(errorx.ConnectionInvalidErr, "connection id should be defined")
+	}
+	if err := CheckNamespace(ctx, id); err != nil {
+		return nil, err
+	}
+	m.RLock()
+	meta, ok := m.connectionPool[id]
+	m.RUnlock()
+	if !ok {
+		return nil, errorx.NewWithCode(errorx.NOT_FOUND, fmt.Sprintf("connection %s not existed", id))
+	}
+	if !meta.Named {
+		return nil, errorx.NewWithCode(errorx.ConnectionReadOnlyErr, fmt.Sprintf("internal connection %v can't be paused", id))
+	}
+	return meta, nil
+}
+
+// Pause closes the transport of the named connection for maintenance. The attached rules keep their
+// references and are notified of the paused status. It is not reconnected by the health check until resumed.
+// The paused state is not persisted, so the connection connects again after restart.
+func (m *ConnectionManager) Pause(ctx api.StreamContext, id string) error {
+	meta, err := m.pausableConnection(ctx, id)
+	if err != nil {
+		return err
+	}
+	meta.opLock.Lock()
+	defer meta.opLock.Unlock()
+	if !meta.paused.CompareAndSwap(false, true) {
+		return nil
+	}
+	parkWrappers(ctx, meta, errPaused)
+	meta.NotifyStatus(ConnectionPaused, "")
+	recordEvent(id, EventPaused, "")
+	connLogger(id).Infof("connection %s is paused with %d references", id, meta.GetRefCount())
+	return nil
+}
+
+// Resume connects the paused connection again and swaps it in for the attached rules. If it fails to
+// connect within the operation timeout, the connection is still resumed and reconnected by the health check.
+func (m *ConnectionManager) Resume(ctx api.StreamContext, id string) error {
+	meta, err := m.pausableConnection(ctx, id)
+	if err != nil {
+		return err
+	}
+	meta.opLock.Lock()
+	defer meta.opLock.Unlock()
+	if !meta.paused.Load() {
+		return nil
+	}
+	recordEvent(id, EventResumed, "")
+	// the standby node does not connect, the leader connects it once promoted
+	if IsStandby() {
+		meta.paused.Store(false)
+		meta.NotifyStatus(api.ConnectionDisconnected, errStandby.Error())
+		return nil
+	}
+	staged, err := establish(ctx, meta, meta.Props)
+	meta.paused.Store(false)
+	if err != nil {
+		meta.NotifyStatus(api.ConnectionDisconnected, err.Error())
+		return fmt.Errorf("connection %s is resumed but fails to connect, it will be reconnected in background: %w", id, err)
+	}
+	if !m.holds(meta) {
+		staged.release()
+		return errorx.NewWithCode(errorx.ConnectionConflictErr, fmt.Sprintf("connection %s is changed during the resume", id))
+	}
+	staged.swapInto(ctx, meta)
+	connLogger(id).Infof("connection %s is resumed", id)
+	return nil
+}
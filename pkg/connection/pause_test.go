@@ -0,0 +1,91 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/topo/context"
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+	"github.com/lf-edge/ekuiper/v2/pkg/syncx"
+)
+
+func TestPauseResume(t *testing.T) {
+	require.NoError(t, InitConnectionManager4Test())
+	ctx := context.Background()
+	_, err := CreateNamedConnection(ctx, "pause1", "mock", nil)
+	require.NoError(t, err)
+	defer func() {
+		_ = DropNameConnectionPermanently(ctx, "pause1")
+	}()
+	meta, err := GetConnectionDetail(ctx, "pause1")
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		s, _ := meta.GetStatus()
+		return s == api.ConnectionConnected
+	}, time.Second, 10*time.Millisecond)
+
+	var (
+		mu       syncx.Mutex
+		statuses []string
+	)
+	refId := extractRefId(ctx)
+	cw, err := FetchConnection(ctx, refId, "mock", map[string]any{"connectionSelector": "pause1"}, func(status, _ string) {
+		mu.Lock()
+		defer mu.Unlock()
+		statuses = append(statuses, status)
+	})
+	require.NoError(t, err)
+	defer func() {
+		_ = DetachConnection(ctx, "pause1")
+	}()
+
+	require.NoError(t, PauseConnection(ctx, "pause1"))
+	require.NoError(t, PauseConnection(ctx, "pause1"))
+	require.True(t, meta.IsPaused())
+	s, _ := meta.GetStatus()
+	require.Equal(t, ConnectionPaused, s)
+	conn, err := cw.Wait(ctx)
+	require.Nil(t, conn)
+	require.Equal(t, errPaused, err)
+	require.Equal(t, 1, meta.GetRefCount())
+	// not reconnected by the health check
+	require.False(t, isBroken(meta, ConnectionPaused))
+	// the props are changed without connecting
+	_, err = UpdateNamedConnection(ctx, "pause1", map[string]any{"a": 1})
+	require.NoError(t, err)
+	_, err = cw.Wait(ctx)
+	require.Equal(t, errPaused, err)
+
+	require.NoError(t, ResumeConnection(ctx, "pause1"))
+	require.NoError(t, ResumeConnection(ctx, "pause1"))
+	require.False(t, meta.IsPaused())
+	conn, err = cw.Wait(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, conn)
+	s, _ = meta.GetStatus()
+	require.Equal(t, api.ConnectionConnected, s)
+	mu.Lock()
+	require.Equal(t, []string{api.ConnectionConnected, ConnectionPaused, api.ConnectionConnected}, statuses)
+	mu.Unlock()
+
+	err = PauseConnection(ctx, "nosuchconn")
+	code, _ := errorx.GetErrorCode(err)
+	require.Equal(t, errorx.NOT_FOUND, code)
+}
@@ -18,18 +18,25 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"reflect"
+	"sort"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/cenkalti/backoff/v4"
 	"github.com/lf-edge/ekuiper/contract/v2/api"
 	"github.com/pingcap/failpoint"
+	"github.com/sirupsen/logrus"
 
 	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/discovery"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/fault"
 	topoContext "github.com/lf-edge/ekuiper/v2/internal/topo/context"
 	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
 	"github.com/lf-edge/ekuiper/v2/pkg/modules"
 	"github.com/lf-edge/ekuiper/v2/pkg/syncx"
+	"github.com/lf-edge/ekuiper/v2/pkg/tracer"
 )
 
 // Connection pool manages all connections in the system. There are two kinds of connections:
@@ -37,22 +44,148 @@ import (
 // The connection will run through all the eKuiper server lifecycle. When restarting, it will be loaded and run as server init.
 // 2. Anonymous connection: It is a subsidiary of rules. The rule source/sink defines connection and the connection will
 // be fetched when rules start. If no rule has accessed it, it will be closed and dropped.
+//
+// The pool lock only guards the map. The slow operations of a connection, like closing and swapping, are done out of
+// the pool lock with the lock of the connection, so a connection which is slow to close does not stall the others.
 
-type Manager struct {
+// ConnectionManager is a connection pool. The engine uses the default manager through the package functions, while
+// the embedders and the tests can create isolated managers by NewConnectionManager. The connection types, the store,
+// the tuning, the tenants and the aliases are shared by all the managers of the process.
+type ConnectionManager struct {
 	syncx.RWMutex
-	// key is selId(explicitly specified or anonymous)
+	// key is selId(explicitly specified or anonymous). It is only accessed with the lock.
 	connectionPool map[string]*Meta
+	// retired are the connections removed or replaced with the lock. They are closed once the lock is released.
+	retired []retiredConn
+	// snapshot is the read-only copy of the pool published on each change, so that the status queries do not
+	// contend with the attach and detach of the rules
+	snapshot atomic.Pointer[map[string]*Meta]
+	// closed is set on engine shutdown to reject new connections
+	closed bool
+	// draining is set on graceful shutdown to reject new attaches while the existing references drain
+	draining bool
+	// ctx owns the connections of the manager, which is canceled when the manager is closed
+	ctx    api.StreamContext
+	cancel context.CancelFunc
+	// released are the connections removed with the lock. Their ownership is released after they are closed.
+	released []*Meta
+	// dedupRefs is the shared anonymous connection attached by each ref id
+	dedupLock syncx.Mutex
+	dedupRefs map[string]string
+	// footprints are the anonymous connections fetched by the rules, keyed by rule id and then connection id. They
+	// are recorded with the pool lock held, so the lock must not acquire other locks.
+	footprints     map[string]map[string]footprint
+	footprintsLock syncx.Mutex
+	// warmUps are the connection ids held by the warm-up reference of the rules. The lock is acquired before the
+	// pool lock.
+	warmUps struct {
+		syncx.Mutex
+		warmed map[string][]string
+	}
+	// opConns are the connections held by each rule op to resolve the connections of the spans. The lock may be
+	// acquired with the pool lock so it must not acquire other locks.
+	opConns     map[opKey]map[*Meta]int
+	opConnsLock syncx.Mutex
+}
+
+// NewConnectionManager creates an empty pool. The named connections created by it are persisted in the shared store,
+// so the managers in the same process must not use the same connection ids.
+func NewConnectionManager() *ConnectionManager {
+	m := &ConnectionManager{connectionPool: make(map[string]*Meta), dedupRefs: make(map[string]string), footprints: make(map[string]map[string]footprint), opConns: make(map[opKey]map[*Meta]int)}
+	m.warmUps.warmed = make(map[string][]string)
+	m.ctx, m.cancel = topoContext.Background().WithCancel()
+	m.publish()
+	return m
+}
+
+// put and remove must be called with the lock
+func (m *ConnectionManager) put(id string, meta *Meta) {
+	meta.m = m
+	m.connectionPool[id] = meta
+	m.publish()
+}
+
+// holds returns whether the meta is still in the pool, which is not dropped or replaced in the meantime
+func (m *ConnectionManager) holds(meta *Meta) bool {
+	m.RLock()
+	defer m.RUnlock()
+	cur, ok := m.connectionPool[meta.ID]
+	return ok && cur == meta && !m.closed
+}
+
+// remove does not count the eviction since the connection may be replaced by the new one, use evict instead to
+// drop or release it
+func (m *ConnectionManager) remove(id string) {
+	if meta, ok := m.connectionPool[id]; ok {
+		prev, _ := meta.status.Load().(string)
+		emitStatus(meta, prev, ConnectionDropped, "")
+		m.released = append(m.released, meta)
+	}
+	delete(m.connectionPool, id)
+	m.publish()
+}
+
+// evict removes the dropped or released connection and counts the eviction. It must be called with the lock.
+func (m *ConnectionManager) evict(id, reason string) {
+	if meta, ok := m.connectionPool[id]; ok {
+		ConnEvictions.WithLabelValues(meta.Typ, reason).Inc()
+	}
+	m.remove(id)
+}
+
+type retiredConn struct {
+	ctx  api.StreamContext
+	meta *Meta
+	cws  []*ConnWrapper
+}
+
+// retire schedules the current connection of the meta to close after the lock is released. It must be called with
+// the lock.
+func (m *ConnectionManager) retire(ctx api.StreamContext, meta *Meta) {
+	m.retired = append(m.retired, retiredConn{ctx: ctx, meta: meta, cws: meta.wrappers()})
+}
+
+// Unlock releases the lock and then closes the retired connections, so that the caller still returns after the close
+// while the other connections are not blocked by it
+func (m *ConnectionManager) Unlock() {
+	retired, released := m.retired, m.released
+	m.retired, m.released = nil, nil
+	m.RWMutex.Unlock()
+	for _, r := range retired {
+		for _, cw := range r.cws {
+			closeWrapper(r.ctx, r.meta, cw)
+		}
+	}
+	for _, meta := range released {
+		m.unindexMeta(meta)
+		meta.release()
+		if meta.Named {
+			resetReconnect(meta)
+		}
+	}
+}
+
+// publish copies the pool to the snapshot. The copy is cheap compared to the connection creation.
+func (m *ConnectionManager) publish() {
+	s := make(map[string]*Meta, len(m.connectionPool))
+	for id, meta := range m.connectionPool {
+		s[id] = meta
+	}
+	m.snapshot.Store(&s)
+}
+
+// load returns the latest snapshot of the pool which must not be modified
+func (m *ConnectionManager) load() map[string]*Meta {
+	return *m.snapshot.Load()
 }
 
 var (
-	globalConnectionManager *Manager
+	globalConnectionManager *ConnectionManager
 	mockErr                 = true
 )
 
 func init() {
-	globalConnectionManager = &Manager{
-		connectionPool: make(map[string]*Meta),
-	}
+	globalConnectionManager = NewConnectionManager()
 }
 
 func InitConnectionManager4Test() error {
@@ -62,13 +195,29 @@ func InitConnectionManager4Test() error {
 }
 
 func InitConnectionManager(ctx context.Context) {
-	globalConnectionManager = &Manager{
-		connectionPool: make(map[string]*Meta),
-	}
+	globalConnectionManager = NewConnectionManager()
+	initRetryGuard()
+	initTenants()
+	initTuning()
+	initSecretCipher()
+	initAliases()
+	standby.Store(false)
+	tracer.SetConnectionResolver(connectionsOfOp)
 	if conf.IsTesting {
 		return
 	}
-	go PatrolConnectionStatusJob(ctx)
+	if conf.Config != nil && conf.Config.OpenTelemetry.Metrics.Enable {
+		registerOtelGauges()
+	}
+	if conf.Config != nil && conf.Config.Connection.LeaderElection.Enable {
+		startLeaderElection(ctx)
+	}
+	go supervise(ctx, "patrol", PatrolConnectionStatusJob)
+	go supervise(ctx, "config watch", watchConnectionConfigs)
+	go supervise(ctx, "endpoint discovery", rediscoverEndpoints)
+	go supervise(ctx, "cert rotation", rotateOnCertReload)
+	go supervise(ctx, "reconnect", scheduleReconnects)
+	go supervise(ctx, "status tracing", traceConnectionStatus)
 }
 
 const (
@@ -77,22 +226,25 @@ const (
 )
 
 func PatrolConnectionStatusJob(ctx context.Context) {
-	ticker := time.NewTicker(15 * time.Second)
+	ticker := getClock().Ticker(time.Duration(GetTuning().PatrolInterval))
 	defer ticker.Stop()
 	for {
 		select {
 		case <-ctx.Done():
 			return
+		case <-patrolReset:
+			ticker.Reset(time.Duration(GetTuning().PatrolInterval))
 		case <-ticker.C:
-			patrolConnectionStatus()
+			globalConnectionManager.patrolConnectionStatus()
+			globalConnectionManager.enforceResourceLimits()
+			purgeExpiredTrash()
 		}
 	}
 }
 
-func patrolConnectionStatus() {
-	globalConnectionManager.RLock()
-	defer globalConnectionManager.RUnlock()
-	for connName, conn := range globalConnectionManager.connectionPool {
+func (m *ConnectionManager) patrolConnectionStatus() {
+	pool := m.load()
+	for connName, conn := range pool {
 		// For now, we only patrol named connection
 		if !conn.Named {
 			continue
@@ -103,36 +255,125 @@ func patrolConnectionStatus() {
 			ConnStatusGauge.WithLabelValues(connName).Set(1)
 		case api.ConnectionDisconnected:
 			ConnStatusGauge.WithLabelValues(connName).Set(-1)
-		case api.ConnectionConnecting:
+		case api.ConnectionConnecting, ConnectionWarming:
 			ConnStatusGauge.WithLabelValues(connName).Set(0)
+		case ConnectionPaused:
+			ConnStatusGauge.WithLabelValues(connName).Set(-2)
+		}
+		checkHealth(conn, status)
+		if status == api.ConnectionConnected {
+			failBack(conn)
 		}
 	}
+	pruneReconnects()
+	flushErrorLogs()
+}
+
+// PoolHealth counts the connections in the pool by their status
+type PoolHealth struct {
+	Total  int            `json:"total"`
+	Named  int            `json:"named"`
+	States map[string]int `json:"states"`
+	// Tenants is the usage of each tenant if any tenant is configured
+	Tenants map[string]*TenantUsage `json:"tenants,omitempty"`
+}
+
+// Health returns the connection counts by status. The status of each connection is evaluated
+// out of the pool lock because the stateless connections need to ping.
+func (m *ConnectionManager) Health() *PoolHealth {
+	pool := m.load()
+	metas := make([]*Meta, 0, len(pool))
+	for _, meta := range pool {
+		metas = append(metas, meta)
+	}
+	h := &PoolHealth{
+		Total: len(metas),
+		States: map[string]int{
+			api.ConnectionConnected:    0,
+			api.ConnectionConnecting:   0,
+			ConnectionWarming:          0,
+			api.ConnectionDisconnected: 0,
+		},
+	}
+	for _, meta := range metas {
+		if meta.Named {
+			h.Named++
+		}
+		status, _ := meta.GetStatus()
+		h.States[status]++
+	}
+	h.Tenants = tenantUsage(metas)
+	return h
 }
 
 func NewExponentialBackOff() *backoff.ExponentialBackOff {
+	t := GetTuning()
 	return backoff.NewExponentialBackOff(
-		backoff.WithInitialInterval(DefaultInitialInterval),
-		backoff.WithMaxInterval(DefaultMaxInterval),
-		backoff.WithMaxElapsedTime(0),
+		backoff.WithInitialInterval(time.Duration(t.InitialInterval)),
+		backoff.WithMaxInterval(time.Duration(t.MaxInterval)),
+		backoff.WithMaxElapsedTime(time.Duration(t.MaxElapsedTime)),
+		backoff.WithClockProvider(getClock()),
 	)
 }
 
-// FetchConnection is called by source/sink to get or create an anonymous connection instance in the pool
-func FetchConnection(ctx api.StreamContext, refId, typ string, props map[string]interface{}, sc api.StatusChangeHandler) (*ConnWrapper, error) {
+// Fetch is called by source/sink to get or create an anonymous connection instance in the pool
+func (m *ConnectionManager) Fetch(ctx api.StreamContext, refId, typ string, props map[string]interface{}, sc api.StatusChangeHandler) (*ConnWrapper, error) {
 	failpoint.Inject("FetchConnectionErr", func() {
 		failpoint.Return(nil, fmt.Errorf("FetchConnectionErr"))
 	})
 	if refId == "" {
-		return nil, fmt.Errorf("connection ref id should be defined")
+		return nil, errorx.NewWithCode(errorx.ConnectionInvalidErr, "connection ref id should be defined")
 	}
 	conId := extractSelID(props, refId)
-	globalConnectionManager.Lock()
-	defer globalConnectionManager.Unlock()
-	if _, ok := globalConnectionManager.connectionPool[conId]; ok {
-		conf.Log.Infof("FetchConnection return existed conn %s", conId)
+	if err := fault.InjectFor(fault.FetchConnection, conId); err != nil {
+		return nil, err
+	}
+	dedup := false
+	if conId != refId {
+		if err := CheckNamespace(ctx, conId); err != nil {
+			return nil, err
+		}
+		if target, ok := resolveAlias(conId); ok {
+			return m.fetchByAlias(ctx, refId, conId, target, typ, props, sc)
+		}
+		if meta, ok := m.load()[conId]; ok {
+			recoverOnAttach(meta)
+		}
+	} else if id, ok := dedupId(typ, props); ok {
+		conId, dedup = id, true
+	}
+	if cw, ok := m.fastAttach(conId, refId, sc); ok {
+		cw.hold(extractRefId(ctx))
+		if dedup {
+			m.recordDedupAttach(refId, conId)
+		}
+		// the connection can't be removed while referenced
+		if meta, ok := m.load()[conId]; ok {
+			m.recordFootprint(ctx.GetRuleId(), meta)
+			meta.addAttacher(ctx)
+			meta.auditRef(RefAttach, attacherOf(ctx))
+		}
+		return cw, nil
+	}
+	m.Lock()
+	defer m.Unlock()
+	if m.closed {
+		return nil, errPoolClosed
+	}
+	if m.draining {
+		return nil, errPoolDraining
+	}
+	if err := m.checkRefQuota(conId); err != nil {
+		return nil, err
+	}
+	if _, ok := m.connectionPool[conId]; ok {
+		connLogger(conId).Infof("FetchConnection return existed conn %s", conId)
 	} else {
-		if conId != refId {
-			return nil, fmt.Errorf("connection %s not existed", conId)
+		if conId != refId && !dedup {
+			return nil, errorx.NewWithCode(errorx.NOT_FOUND, fmt.Sprintf("connection %s not existed", conId))
+		}
+		if err := m.checkConnectionQuota(conId, typ); err != nil {
+			return nil, err
 		}
 		meta := &Meta{
 			ID:    conId,
@@ -140,57 +381,302 @@ func FetchConnection(ctx api.StreamContext, refId, typ string, props map[string]
 			Props: props,
 			Named: false,
 		}
-		meta.cw = newConnWrapper(ctx, meta)
-		globalConnectionManager.connectionPool[meta.ID] = meta
-		conf.Log.Infof("FetchConnection return new conn %s", conId)
+		meta.cw = newConnWrapper(m.ctx, meta)
+		// the anonymous connection is also owned by the rule creating it unless shared by the rules
+		if !dedup {
+			context.AfterFunc(ctx, meta.release)
+		}
+		m.put(meta.ID, meta)
+		connLogger(conId).Infof("FetchConnection return new conn %s", conId)
 	}
-	return attachConnection(conId, refId, sc)
+	meta := m.connectionPool[conId]
+	m.recordFootprint(ctx.GetRuleId(), meta)
+	meta.addAttacher(ctx)
+	cw, err := m.attachConnection(conId, refId, sc)
+	if err == nil {
+		cw.hold(extractRefId(ctx))
+		meta.auditRef(RefAttach, attacherOf(ctx))
+		if dedup {
+			m.recordDedupAttach(refId, conId)
+		}
+	}
+	return cw, err
 }
 
-// ReloadNamedConnection is called when server starts. It initializes all stored named connections
-func ReloadNamedConnection() error {
-	globalConnectionManager.Lock()
-	defer globalConnectionManager.Unlock()
-	cfgs, err := conf.GetCfgFromKVStorage("connections", "", "")
+// Reload is called when server starts. It initializes all stored named connections concurrently in
+// the order of the priorities and the dependencies.
+func (m *ConnectionManager) Reload() error {
+	m.Lock()
+	defer m.Unlock()
+	s, err := getConnectionStore()
+	if err != nil {
+		return err
+	}
+	stored, err := s.List()
 	if err != nil {
 		return err
 	}
-	for key, props := range cfgs {
-		names := strings.Split(key, ".")
-		if len(names) != 3 {
+	loaded := make([]*Meta, 0, len(stored))
+	for _, c := range stored {
+		typ := c.Typ
+		id := c.ID
+		if _, ok := m.connectionPool[id]; ok {
 			continue
 		}
-		typ := names[1]
-		id := names[2]
-		if _, ok := globalConnectionManager.connectionPool[id]; ok {
+		props, err := decryptSecrets(c.Props)
+		if err != nil {
+			conf.Log.Errorf("load connection %s failed: %v", id, err)
 			continue
 		}
-		meta := &Meta{
+		loaded = append(loaded, &Meta{
 			ID:    id,
 			Typ:   typ,
 			Props: props,
 			Named: true,
-		}
-		meta.cw = newConnWrapper(topoContext.WithContext(context.Background()), meta)
-		globalConnectionManager.connectionPool[id] = meta
+		})
+	}
+	startConnections(m.ctx, loaded)
+	for _, meta := range loaded {
+		m.put(meta.ID, meta)
+		emitReplica(putEvent(meta))
 	}
 	return nil
 }
 
+// watchConnectionConfigs applies the named connection changes made by other nodes or tools in the config store
+func watchConnectionConfigs(ctx context.Context) {
+	interval := time.Duration(conf.Config.Store.ConfigWatchInterval)
+	s, err := getConnectionStore()
+	if err != nil {
+		conf.Log.Errorf("watch connection configs failed: %v", err)
+		return
+	}
+	var ch <-chan conf.ConfigEvent
+	if isDefaultStore(s) {
+		ch, err = conf.WatchCfg(ctx, connCfgType+".", interval)
+	} else {
+		ch, err = watchConnectionStore(ctx, s, interval)
+	}
+	if err != nil {
+		conf.Log.Errorf("watch connection configs failed: %v", err)
+		return
+	}
+	for ev := range ch {
+		globalConnectionManager.applyConnectionEvent(ev)
+	}
+}
+
+func (m *ConnectionManager) applyConnectionEvent(ev conf.ConfigEvent) {
+	names := strings.Split(ev.Key, ".")
+	if len(names) != 3 {
+		return
+	}
+	typ := names[1]
+	id := names[2]
+	if ev.Type != conf.ConfigEventDelete {
+		props, err := decryptSecrets(ev.Props)
+		if err != nil {
+			connLogger(id).Errorf("apply connection %s change failed: %v", id, err)
+			return
+		}
+		ev.Props = props
+		if meta, ok := m.swappableMeta(id, typ, ev.Props); ok {
+			swapStoredConnection(meta, ev.Props)
+			return
+		}
+	}
+	m.Lock()
+	defer m.Unlock()
+	if m.closed {
+		return
+	}
+	meta, ok := m.connectionPool[id]
+	if ok {
+		if !meta.Named {
+			return
+		}
+		// the changes made by this node are already applied
+		if ev.Type != conf.ConfigEventDelete && meta.Typ == typ && reflect.DeepEqual(meta.Props, ev.Props) {
+			return
+		}
+		// the referenced connection can only be reloaded in place if the type is not changed
+		if meta.GetRefCount() > 0 {
+			connLogger(id).Warnf("connection %s is changed in the config store but can't be applied due to rule references %v", id, meta.GetRefNames())
+			return
+		}
+		m.retire(topoContext.WithContext(context.Background()), meta)
+		if ev.Type == conf.ConfigEventDelete {
+			m.evict(id, EvictDropped)
+		} else {
+			m.remove(id)
+		}
+	}
+	if ev.Type == conf.ConfigEventDelete {
+		if ok {
+			connLogger(id).Infof("connection %s is dropped by config store change", id)
+			emitReplica(ReplicaEvent{Type: ReplicaDelete, ID: id})
+		}
+		return
+	}
+	meta = &Meta{
+		ID:    id,
+		Typ:   typ,
+		Props: ev.Props,
+		Named: true,
+	}
+	meta.newWrappers(func() *ConnWrapper {
+		return newNamedConnWrapper(m.ctx, meta)
+	})
+	m.put(id, meta)
+	emitReplica(putEvent(meta))
+	connLogger(id).Infof("connection %s is %s by config store change", id, ev.Type)
+}
+
+// swappableMeta returns the referenced named connection whose props are changed in the config store. It can be
+// reconnected with the new props in place for the attached rules if the type is not changed.
+func (m *ConnectionManager) swappableMeta(id, typ string, props map[string]any) (*Meta, bool) {
+	meta, ok := m.load()[id]
+	if !ok || !meta.Named || meta.Typ != typ || meta.GetRefCount() == 0 {
+		return nil, false
+	}
+	return meta, !reflect.DeepEqual(meta.Props, props)
+}
+
+// swapStoredConnection applies the props changed in the config store to the referenced connection without detaching
+// the rules, like UpdateNamedConnection without persisting. The old connection is kept if the new one fails to
+// connect, and the change is applied again on the next change or restart.
+func swapStoredConnection(meta *Meta, props map[string]any) {
+	ctx := topoContext.WithContext(context.Background())
+	meta.opLock.Lock()
+	defer meta.opLock.Unlock()
+	if reflect.DeepEqual(meta.Props, props) {
+		return
+	}
+	if n, _ := parsePoolSize(props); n != len(meta.wrappers()) {
+		connLogger(meta.ID).Errorf("connection %s is changed in the config store but %s can't be changed while the rules are attached, keep the old one", meta.ID, PoolSizePropKey)
+		return
+	}
+	var staged *stagedConn
+	if !IsStandby() && !meta.paused.Load() {
+		var err error
+		staged, err = establish(ctx, meta, props)
+		if err != nil {
+			connLogger(meta.ID).Errorf("connection %s is changed in the config store but can't connect with the new props, keep the old one: %v", meta.ID, err)
+			return
+		}
+	}
+	m := meta.manager()
+	m.Lock()
+	if cur, ok := m.connectionPool[meta.ID]; !ok || cur != meta || m.closed {
+		m.Unlock()
+		if staged != nil {
+			staged.release()
+		}
+		return
+	}
+	meta.Props = props
+	meta.setThrottle(props)
+	emitReplica(putEvent(meta))
+	m.Unlock()
+	if staged != nil {
+		staged.swapInto(ctx, meta)
+	}
+	recordEvent(meta.ID, EventUpdated, "config store change")
+	connLogger(meta.ID).Infof("connection %s is reloaded by config store change with %d references", meta.ID, meta.GetRefCount())
+}
+
 // Connection API handlers
 
-func CreateNamedConnection(ctx api.StreamContext, id, typ string, props map[string]any) (*ConnWrapper, error) {
+// Create creates a named connection and persists it
+func (m *ConnectionManager) Create(ctx api.StreamContext, id, typ string, props map[string]any) (*ConnWrapper, error) {
 	if id == "" || typ == "" {
-		return nil, fmt.Errorf("connection id and type should be defined")
+		return nil, errorx.NewWithCode(errorx.ConnectionInvalidErr, "connection id and type should be defined")
 	}
-	globalConnectionManager.Lock()
-	defer globalConnectionManager.Unlock()
-	return createNamedConnection(ctx, id, typ, props)
+	m.Lock()
+	defer m.Unlock()
+	cw, err := m.createNamedConnection(ctx, id, typ, props)
+	if err == nil {
+		recordEvent(id, EventCreated, "")
+		m.rollbackOnFailure(m.connectionPool[id])
+	}
+	return cw, err
 }
 
-func createNamedConnection(ctx api.StreamContext, id, typ string, props map[string]any) (*ConnWrapper, error) {
-	if _, ok := globalConnectionManager.connectionPool[id]; ok {
-		return nil, fmt.Errorf("connection %v already been created", id)
+// rollbackOnFailure drops the created named connection and its stored props if the creation fails permanently, for
+// example due to the invalid props or the credentials, so that the broken config is not loaded again after restart.
+// The connections failed by the other errors like the network failure are kept and reconnected by the health check.
+// The connection is kept too if it is attached or changed in the meantime.
+func (m *ConnectionManager) rollbackOnFailure(meta *Meta) {
+	if meta == nil || IsStandby() {
+		return
+	}
+	cw := meta.cw
+	go func() {
+		if _, err := cw.Wait(m.ctx); err == nil || !isPermanentFailure(err) {
+			return
+		}
+		m.Lock()
+		cur, ok := m.connectionPool[meta.ID]
+		if !ok || cur != meta || m.closed || meta.GetRefCount() > 0 || meta.paused.Load() || len(aliasesOf(meta.ID)) > 0 {
+			m.Unlock()
+			return
+		}
+		// the connection may be reconnected by the update in the meantime
+		_, err := cw.Wait(m.ctx)
+		if err == nil || !isPermanentFailure(err) {
+			m.Unlock()
+			return
+		}
+		// the stored props are deleted with the lock, so that the connection created again with the same id is kept.
+		// The props changed in the config store by the other nodes are kept too.
+		if stored, serr := storedProps(meta.Typ, meta.ID); serr != nil || !sameProps(stored, meta.Props) {
+			m.Unlock()
+			return
+		}
+		if derr := dropConnectionStore(meta.Typ, meta.ID); derr != nil {
+			m.Unlock()
+			connLogger(meta.ID).Errorf("roll back the stored connection %s failed: %v", meta.ID, derr)
+			return
+		}
+		m.retire(m.ctx, meta)
+		m.evict(meta.ID, EvictDropped)
+		emitReplica(ReplicaEvent{Type: ReplicaDelete, ID: meta.ID})
+		m.Unlock()
+		recordEvent(meta.ID, EventDropped, err.Error())
+		connLogger(meta.ID).Warnf("connection %s is rolled back since the creation failed: %v", meta.ID, err)
+	}()
+}
+
+func isPermanentFailure(err error) bool {
+	switch errorx.KindOf(err) {
+	case errorx.KindPermanent, errorx.KindAuth:
+		return true
+	default:
+		return false
+	}
+}
+
+func (m *ConnectionManager) createNamedConnection(ctx api.StreamContext, id, typ string, props map[string]any) (*ConnWrapper, error) {
+	if m.closed {
+		return nil, errPoolClosed
+	}
+	if _, ok := m.connectionPool[id]; ok {
+		return nil, errorx.NewWithCode(errorx.ConnectionExistErr, fmt.Sprintf("connection %v already been created", id))
+	}
+	if _, ok := resolveAlias(id); ok {
+		return nil, errorx.NewWithCode(errorx.ConnectionExistErr, fmt.Sprintf("connection %v already been created as an alias", id))
+	}
+	if err := checkCreateNamespace(ctx, id); err != nil {
+		return nil, err
+	}
+	if err := m.checkConnectionQuota(id, typ); err != nil {
+		return nil, err
+	}
+	if err := validateReservedProps(typ, props); err != nil {
+		return nil, err
+	}
+	if err := validateProps(ctx, typ, props); err != nil {
+		return nil, err
 	}
 	meta := &Meta{
 		ID:    id,
@@ -198,19 +684,21 @@ func createNamedConnection(ctx api.StreamContext, id, typ string, props map[stri
 		Props: props,
 		Named: true,
 	}
-	meta.cw = newConnWrapper(ctx, meta)
+	meta.newWrappers(func() *ConnWrapper {
+		return newNamedConnWrapper(m.ctx, meta)
+	})
 	if err := storeConnectionMeta(typ, id, props); err != nil {
 		return nil, err
 	}
-	globalConnectionManager.connectionPool[id] = meta
+	m.put(id, meta)
+	emitReplica(putEvent(meta))
 	return meta.cw, nil
 }
 
-func GetAllConnectionsMeta(forceAll bool) []*Meta {
-	globalConnectionManager.RLock()
-	defer globalConnectionManager.RUnlock()
+// List returns the named connections, or all the connections if forceAll is set
+func (m *ConnectionManager) List(forceAll bool) []*Meta {
 	metaList := make([]*Meta, 0)
-	for _, meta := range globalConnectionManager.connectionPool {
+	for _, meta := range m.load() {
 		if !meta.Named && !forceAll {
 			continue
 		}
@@ -219,97 +707,281 @@ func GetAllConnectionsMeta(forceAll bool) []*Meta {
 	return metaList
 }
 
-func GetConnectionDetail(_ api.StreamContext, id string) (*Meta, error) {
+// Get returns the connection in the pool by the id or the alias
+func (m *ConnectionManager) Get(ctx api.StreamContext, id string) (*Meta, error) {
 	if id == "" {
-		return nil, fmt.Errorf("connection id should be defined")
+		return nil, errorx.NewWithCode(errorx.ConnectionInvalidErr, "connection id should be defined")
+	}
+	if err := CheckNamespace(ctx, id); err != nil {
+		return nil, err
 	}
-	globalConnectionManager.RLock()
-	defer globalConnectionManager.RUnlock()
-	meta, ok := globalConnectionManager.connectionPool[id]
+	meta, ok := m.load()[id]
 	if !ok {
-		return nil, fmt.Errorf("connection %s not existed", id)
+		if target, isAlias := resolveAlias(id); isAlias {
+			meta, ok = m.load()[target]
+		}
+	}
+	if !ok {
+		return nil, errorx.NewWithCode(errorx.NOT_FOUND, fmt.Sprintf("connection %s not existed", id))
 	}
 	return meta, nil
 }
 
-func DropNameConnection(ctx api.StreamContext, selId string) error {
+// Drop drops the named connection and retains it in the trash if enabled
+func (m *ConnectionManager) Drop(ctx api.StreamContext, selId string) error {
+	if selId == "" {
+		return errorx.NewWithCode(errorx.ConnectionInvalidErr, "connection id should be defined")
+	}
+	if err := CheckNamespace(ctx, selId); err != nil {
+		return err
+	}
+	m.Lock()
+	defer m.Unlock()
+	return m.dropAndRecord(ctx, selId, trashTTL())
+}
+
+// DropPermanently drops the named connection without retaining it in the trash
+func (m *ConnectionManager) DropPermanently(ctx api.StreamContext, selId string) error {
 	if selId == "" {
-		return fmt.Errorf("connection id should be defined")
+		return errorx.NewWithCode(errorx.ConnectionInvalidErr, "connection id should be defined")
+	}
+	if err := CheckNamespace(ctx, selId); err != nil {
+		return err
+	}
+	m.Lock()
+	defer m.Unlock()
+	return m.dropAndRecord(ctx, selId, 0)
+}
+
+// CheckConnectionCapability validates the named connection supports the capability required by the rule.
+// The connection types which do not declare capabilities are always valid.
+func CheckConnectionCapability(ctx api.StreamContext, id string, c modules.Capability) error {
+	meta, err := GetConnectionDetail(ctx, id)
+	if err != nil {
+		return err
+	}
+	caps, declared := modules.GetConnectionCapabilities(ctx, strings.ToLower(meta.Typ))
+	if !declared {
+		return nil
 	}
+	for _, cc := range caps {
+		if cc == c {
+			return nil
+		}
+	}
+	return errorx.NewWithCode(errorx.ConnectionInvalidErr, fmt.Sprintf("connection %s of type %s doesn't support %s", id, meta.Typ, c))
+}
+
+// UnregisterConnectionType removes a connection type registered at runtime. It fails if any connection of the type
+// is still in the pool, which must be dropped first.
+func UnregisterConnectionType(typ string) error {
 	globalConnectionManager.Lock()
 	defer globalConnectionManager.Unlock()
-	return dropNameConnection(ctx, selId)
+	var ids []string
+	for id, meta := range globalConnectionManager.connectionPool {
+		if strings.EqualFold(meta.Typ, typ) {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) > 0 {
+		sort.Strings(ids)
+		return errorx.NewWithCode(errorx.ConnectionInUseErr, fmt.Sprintf("connection type %s can't be unregistered due to live connections %v", typ, ids))
+	}
+	modules.UnregisterConnection(strings.ToLower(typ))
+	return nil
 }
 
-func dropNameConnection(ctx api.StreamContext, selId string) error {
-	meta, ok := globalConnectionManager.connectionPool[selId]
+// dropAndRecord removes the named connection and records the drop in its event history. It must be called with the
+// pool lock.
+func (m *ConnectionManager) dropAndRecord(ctx api.StreamContext, selId string, trashTTL time.Duration) error {
+	meta, existed := m.connectionPool[selId]
+	if err := m.removeNamedConnection(ctx, selId, trashTTL); err != nil {
+		return err
+	}
+	if existed {
+		ConnEvictions.WithLabelValues(meta.Typ, EvictDropped).Inc()
+		recordEvent(selId, EventDropped, "")
+	}
+	return nil
+}
+
+func (m *ConnectionManager) dropNameConnection(ctx api.StreamContext, selId string) error {
+	return m.removeNamedConnection(ctx, selId, 0)
+}
+
+// removeNamedConnection removes the connection from the pool. The stored connection is moved into the trash if the
+// trash ttl is positive, otherwise it is deleted.
+func (m *ConnectionManager) removeNamedConnection(ctx api.StreamContext, selId string, trashTTL time.Duration) error {
+	meta, ok := m.connectionPool[selId]
 	if !ok {
 		return nil
 	}
-	isInternal, err := isInternalConnection(selId)
+	isInternal, err := m.isInternalConnection(selId)
 	if err != nil {
 		return err
 	}
 	if isInternal {
-		return fmt.Errorf("internal connection %v can't be edit", selId)
+		return errorx.NewWithCode(errorx.ConnectionReadOnlyErr, fmt.Sprintf("internal connection %v can't be edit", selId))
 	}
 	if meta.GetRefCount() > 0 {
-		return fmt.Errorf("connection %s can't be dropped due to rule references %v", selId, meta.GetRefNames())
+		return errorx.NewWithCode(errorx.ConnectionInUseErr, fmt.Sprintf("connection %s can't be dropped due to rule references %v", selId, meta.attacherRules()))
 	}
-	err = dropConnectionStore(meta.Typ, selId)
-	if err != nil {
-		return fmt.Errorf("drop connection %s failed, err:%v", selId, err)
+	if as := aliasesOf(selId); len(as) > 0 {
+		return errorx.NewWithCode(errorx.ConnectionInUseErr, fmt.Sprintf("connection %s can't be dropped due to aliases %v", selId, as))
 	}
-	if meta.cw.IsInitialized() {
-		conn, err := meta.cw.Wait(ctx)
-		if conn != nil && err == nil {
-			conn.Close(ctx)
-		}
+	if trashTTL > 0 {
+		err = trashConnectionStore(meta, trashTTL)
+	} else {
+		err = dropConnectionStore(meta.Typ, selId)
+	}
+	if err != nil {
+		return fmt.Errorf("drop connection %s failed, err:%w", selId, err)
 	}
-	delete(globalConnectionManager.connectionPool, selId)
+	m.retire(ctx, meta)
+	m.remove(selId)
+	emitReplica(ReplicaEvent{Type: ReplicaDelete, ID: selId})
 	return nil
 }
 
-func UpdateConnection(ctx api.StreamContext, id, typ string, props map[string]any) (*ConnWrapper, error) {
+// Update replaces the named connection with the new type and props. The rules must be detached.
+func (m *ConnectionManager) Update(ctx api.StreamContext, id, typ string, props map[string]any) (*ConnWrapper, error) {
 	if id == "" || typ == "" {
-		return nil, fmt.Errorf("connection id and type should be defined")
+		return nil, errorx.NewWithCode(errorx.ConnectionInvalidErr, "connection id and type should be defined")
 	}
-	globalConnectionManager.Lock()
-	defer globalConnectionManager.Unlock()
-	isInternal, err := isInternalConnection(id)
+	if err := CheckNamespace(ctx, id); err != nil {
+		return nil, err
+	}
+	m.Lock()
+	defer m.Unlock()
+	isInternal, err := m.isInternalConnection(id)
 	if err != nil {
 		return nil, err
 	}
 	if isInternal {
-		return nil, fmt.Errorf("internal connection %v can't be edit", id)
+		return nil, errorx.NewWithCode(errorx.ConnectionReadOnlyErr, fmt.Sprintf("internal connection %v can't be edit", id))
 	}
-	if err := dropNameConnection(ctx, id); err != nil {
+	props = restoreSecrets(props, m.connectionPool[id].Props)
+	// check the quota before dropping so that the connection is not lost if the new one is not allowed
+	if err := m.checkConnectionQuota(id, typ); err != nil {
 		return nil, err
 	}
-	return createNamedConnection(ctx, id, typ, props)
+	if err := validateProps(ctx, typ, props); err != nil {
+		return nil, err
+	}
+	if err := m.dropNameConnection(ctx, id); err != nil {
+		return nil, err
+	}
+	cw, err := m.createNamedConnection(ctx, id, typ, props)
+	if err == nil {
+		recordEvent(id, EventUpdated, "")
+	}
+	return cw, err
 }
 
-func isInternalConnection(id string) (bool, error) {
-	meta, ok := globalConnectionManager.connectionPool[id]
+// UpdateNamed changes the props of the named connection without detaching the rules. The new connection is
+// established before the change, and it is swapped in for the attached consumers after the props are persisted.
+// The old connection is kept if the new one fails to connect within the operation timeout.
+func (m *ConnectionManager) UpdateNamed(ctx api.StreamContext, id string, props map[string]any) (*ConnWrapper, error) {
+	if id == "" {
+		return nil, errorx.NewWithCode(errorx.ConnectionInvalidErr, "connection id should be defined")
+	}
+	if err := CheckNamespace(ctx, id); err != nil {
+		return nil, err
+	}
+	m.RLock()
+	meta, ok := m.connectionPool[id]
+	m.RUnlock()
 	if !ok {
-		return false, fmt.Errorf("connection %s not existed", id)
+		return nil, errorx.NewWithCode(errorx.NOT_FOUND, fmt.Sprintf("connection %s not existed", id))
+	}
+	if !meta.Named {
+		return nil, errorx.NewWithCode(errorx.ConnectionReadOnlyErr, fmt.Sprintf("internal connection %v can't be edit", id))
+	}
+	// the updates of the same connection are serialized while the others are not blocked by the slow dial
+	meta.opLock.Lock()
+	defer meta.opLock.Unlock()
+	props = restoreSecrets(props, meta.Props)
+	if err := validateReservedProps(meta.Typ, props); err != nil {
+		return nil, err
+	}
+	if n, _ := parsePoolSize(props); n != len(meta.wrappers()) {
+		return nil, errorx.NewWithCode(errorx.ConnectionPropsErr, fmt.Sprintf("%s of connection %s can't be changed while the rules are attached", PoolSizePropKey, id))
+	}
+	if err := validateProps(ctx, meta.Typ, props); err != nil {
+		return nil, err
+	}
+	var staged *stagedConn
+	// the standby node and the paused connection do not connect, only the props are changed
+	if !IsStandby() && !meta.paused.Load() {
+		var err error
+		staged, err = establish(ctx, meta, props)
+		if err != nil {
+			return nil, fmt.Errorf("connect with the new props failed, the connection %s is not changed: %w", id, err)
+		}
+	}
+	rollback := func() {
+		if staged != nil {
+			staged.release()
+		}
+	}
+	m.Lock()
+	if cur, ok := m.connectionPool[id]; !ok || cur != meta || m.closed {
+		m.Unlock()
+		rollback()
+		return nil, errorx.NewWithCode(errorx.ConnectionConflictErr, fmt.Sprintf("connection %s is changed during the update", id))
+	}
+	if err := storeConnectionMeta(meta.Typ, id, props); err != nil {
+		m.Unlock()
+		rollback()
+		return nil, fmt.Errorf("update connection %s failed, err:%w", id, err)
+	}
+	meta.Props = props
+	meta.setThrottle(props)
+	emitReplica(putEvent(meta))
+	m.Unlock()
+	// if the connection is dropped in the meantime, its close waits for the connection lock and closes the new one
+	if staged != nil {
+		staged.swapInto(ctx, meta)
+	}
+	resetReconnect(meta)
+	recordEvent(id, EventUpdated, "")
+	connLogger(id).Infof("connection %s is updated with %d references", id, meta.GetRefCount())
+	return meta.cw, nil
+}
+
+func (m *ConnectionManager) isInternalConnection(id string) (bool, error) {
+	meta, ok := m.connectionPool[id]
+	if !ok {
+		return false, errorx.NewWithCode(errorx.NOT_FOUND, fmt.Sprintf("connection %s not existed", id))
 	}
 	return !meta.Named, nil
 }
 
-func DetachConnection(ctx api.StreamContext, conId string) error {
+// Detach removes the reference of the rule from the connection
+func (m *ConnectionManager) Detach(ctx api.StreamContext, conId string) error {
 	if conId == "" {
-		return fmt.Errorf("connection id should be defined")
+		return errorx.NewWithCode(errorx.ConnectionInvalidErr, "connection id should be defined")
 	}
-	globalConnectionManager.Lock()
-	defer globalConnectionManager.Unlock()
-	return detachConnection(ctx, conId)
+	refId := extractRefId(ctx)
+	conId = m.resolveDedup(resolveDetach(conId, refId), refId)
+	if m.fastDetach(ctx, conId) {
+		return nil
+	}
+	m.Lock()
+	defer m.Unlock()
+	return m.detachConnection(ctx, conId)
+}
+
+// detachByRef is Detach by the ref id passed to Fetch, which may differ from the ref id of ctx
+func (m *ConnectionManager) detachByRef(ctx api.StreamContext, conId, refId string) {
+	conId = m.resolveDedup(resolveDetach(conId, refId), refId)
+	m.Lock()
+	defer m.Unlock()
+	m.detachRef(ctx, conId, refId)
 }
 
-func getConnectionRef(id string) int {
-	globalConnectionManager.RLock()
-	defer globalConnectionManager.RUnlock()
-	meta, ok := globalConnectionManager.connectionPool[id]
+func (m *ConnectionManager) getConnectionRef(id string) int {
+	meta, ok := m.load()[id]
 	if !ok {
 		return 0
 	}
@@ -317,7 +989,18 @@ func getConnectionRef(id string) int {
 }
 
 func storeConnectionMeta(plugin, id string, props map[string]interface{}) error {
-	err := conf.WriteCfgIntoKVStorage("connections", plugin, id, props)
+	if err := fault.InjectFor(fault.StoreConnection, id); err != nil {
+		return err
+	}
+	stored, err := encryptSecrets(props)
+	if err != nil {
+		return err
+	}
+	s, err := getConnectionStore()
+	if err != nil {
+		return err
+	}
+	err = s.Put(plugin, id, stored)
 	failpoint.Inject("storeConnectionErr", func() {
 		err = errors.New("storeConnectionErr")
 	})
@@ -325,91 +1008,305 @@ func storeConnectionMeta(plugin, id string, props map[string]interface{}) error
 }
 
 func dropConnectionStore(plugin, id string) error {
-	err := conf.DropCfgKeyFromStorage("connections", plugin, id)
+	if err := fault.InjectFor(fault.DropConnection, id); err != nil {
+		return err
+	}
+	s, err := getConnectionStore()
+	if err != nil {
+		return err
+	}
+	err = s.Delete(plugin, id)
 	failpoint.Inject("dropConnectionStoreErr", func() {
 		err = errors.New("dropConnectionStoreErr")
 	})
 	return err
 }
 
-func attachConnection(conId string, refId string, sc api.StatusChangeHandler) (*ConnWrapper, error) {
-	if conId == "" {
-		return nil, fmt.Errorf("connection id should be defined")
+// fastAttach attaches to an existing connection with the read lock, which only excludes the removal of the connection
+// because the references are atomic. It falls back to the slow path if the tenant reference quota needs to be checked.
+func (m *ConnectionManager) fastAttach(conId string, refId string, sc api.StatusChangeHandler) (*ConnWrapper, bool) {
+	if t := tenantOf(conId); t != nil && t.conf.MaxRefs > 0 {
+		return nil, false
+	}
+	m.RLock()
+	defer m.RUnlock()
+	if m.closed || m.draining {
+		return nil, false
 	}
-	meta, ok := globalConnectionManager.connectionPool[conId]
+	meta, ok := m.connectionPool[conId]
 	if !ok {
-		return nil, fmt.Errorf("connection %s not existed", conId)
+		return nil, false
 	}
 	meta.AddRef(refId, sc)
-	return meta.cw, nil
+	return meta.pick(refId), true
 }
 
-func detachConnection(ctx api.StreamContext, conId string) error {
-	meta, ok := globalConnectionManager.connectionPool[conId]
+// fastDetach detaches from the connection with the read lock. Only when the last reference of an anonymous connection
+// leaves, it takes the write lock to drop the connection unless it is attached again in the meantime.
+func (m *ConnectionManager) fastDetach(ctx api.StreamContext, conId string) bool {
+	m.RLock()
+	meta, ok := m.connectionPool[conId]
 	if !ok {
-		conf.Log.Infof("detachConnection not found:%v", conId)
-		return nil
+		m.RUnlock()
+		return false
 	}
 	refId := extractRefId(ctx)
+	a := meta.attacherOfRef(ctx, refId)
 	meta.DeRef(refId)
-	globalConnectionManager.connectionPool[conId] = meta
-	conf.Log.Infof("detachConnection remove conn:%v,ref:%v", conId, refId)
-	if !meta.Named && meta.GetRefCount() == 0 {
+	meta.auditRef(RefDetach, a)
+	m.RUnlock()
+	connLogger(conId).Infof("detachConnection remove conn:%v,ref:%v", conId, refId)
+	if meta.Named || meta.GetRefCount() > 0 {
+		return true
+	}
+	m.Lock()
+	defer m.Unlock()
+	if cur, ok := m.connectionPool[conId]; ok && cur == meta && meta.GetRefCount() == 0 {
 		close(meta.cw.detachCh)
-		conn, err := meta.cw.Wait(ctx)
-		if conn != nil && err == nil {
-			conn.Close(ctx)
-		}
-		delete(globalConnectionManager.connectionPool, conId)
-		return nil
+		m.retire(ctx, meta)
+		m.evict(conId, EvictReleased)
+	}
+	return true
+}
+
+func (m *ConnectionManager) attachConnection(conId string, refId string, sc api.StatusChangeHandler) (*ConnWrapper, error) {
+	if conId == "" {
+		return nil, errorx.NewWithCode(errorx.ConnectionInvalidErr, "connection id should be defined")
 	}
+	meta, ok := m.connectionPool[conId]
+	if !ok {
+		return nil, errorx.NewWithCode(errorx.NOT_FOUND, fmt.Sprintf("connection %s not existed", conId))
+	}
+	meta.AddRef(refId, sc)
+	return meta.pick(refId), nil
+}
+
+func (m *ConnectionManager) detachConnection(ctx api.StreamContext, conId string) error {
+	m.detachRef(ctx, conId, extractRefId(ctx))
 	return nil
 }
 
+// detachRef removes the reference from the connection and drops the anonymous connection without references
+func (m *ConnectionManager) detachRef(ctx api.StreamContext, conId string, refId string) {
+	meta, ok := m.connectionPool[conId]
+	if !ok {
+		connLogger(conId).Infof("detachConnection not found:%v", conId)
+		return
+	}
+	a := meta.attacherOfRef(ctx, refId)
+	meta.DeRef(refId)
+	meta.auditRef(RefDetach, a)
+	connLogger(conId).Infof("detachConnection remove conn:%v,ref:%v", conId, refId)
+	if !meta.Named && meta.GetRefCount() == 0 {
+		close(meta.cw.detachCh)
+		m.retire(ctx, meta)
+		m.evict(conId, EvictReleased)
+	}
+}
+
+// closeConnection closes the connection if connected, or aborts the creation if it is still retrying.
+// The operation is bounded by the operation timeout. It must not be called with the pool lock.
+func closeConnection(ctx api.StreamContext, meta *Meta) {
+	for _, cw := range meta.wrappers() {
+		closeWrapper(ctx, meta, cw)
+	}
+}
+
+// closeWrapper closes the connection of the wrapper with the lock of the connection, so that it does not interleave
+// with the swap of the connection
+func closeWrapper(ctx api.StreamContext, meta *Meta, cw *ConnWrapper) {
+	meta.opLock.Lock()
+	defer meta.opLock.Unlock()
+	if !cw.IsInitialized() {
+		cw.stop()
+	}
+	opCtx, cancel := withTimeout(ctx)
+	defer cancel()
+	conn, err := cw.Wait(opCtx)
+	if conn != nil && err == nil {
+		_ = safeCall(meta.ID, "close", func() error {
+			return conn.Close(opCtx)
+		})
+	}
+	cw.stop()
+	closeStale(ctx, meta.ID, cw.drainStale())
+}
+
 func createConnection(connCtx api.StreamContext, meta *Meta) (modules.Connection, error) {
+	start := getClock().Now()
 	var conn modules.Connection
 	var err error
 	connRegister, ok := modules.GetConnectionProvider(strings.ToLower(meta.Typ))
 	if !ok {
-		return nil, fmt.Errorf("unknown connection type")
+		return nil, errorx.NewWithCode(errorx.ConnectionInvalidErr, "unknown connection type")
 	}
-	conn = connRegister(connCtx)
-	sc, isStateful := conn.(modules.StatefulDialer)
-	err = conn.Provision(connCtx, meta.ID, meta.Props)
+	group, err := newFailoverGroup(meta.Props)
 	if err != nil {
 		return nil, err
 	}
-	if isStateful {
-		sc.SetStatusChangeHandler(connCtx, meta.NotifyStatus)
+	meta.failover.Store(group)
+	var (
+		resolver *discovery.Resolver
+		prop     string
+	)
+	// the failover endpoints are not discoverable
+	if group == nil {
+		resolver, prop, err = newEndpointResolver(meta.Typ, meta.Props)
+		if err != nil {
+			return nil, err
+		}
+	}
+	meta.endpoints.Store(resolver)
+	var (
+		sc         modules.StatefulDialer
+		isStateful bool
+		addr       string
+		active     int
+	)
+	// provision a new connection to the selected address if the endpoint is discoverable, or to the selected
+	// endpoint if it has failover endpoints
+	provision := func() error {
+		props := withoutReservedProps(meta.Props)
+		if resolver != nil {
+			addr = resolver.Select()
+			props = withEndpoint(props, prop, addr)
+			meta.endpoint.Store(addr)
+		}
+		if group != nil {
+			active = group.selectEndpoint()
+			props = group.props(active)
+			meta.activeEndpoint.Store(int32(active))
+		}
+		conn = connRegister(connCtx)
+		sc, isStateful = conn.(modules.StatefulDialer)
+		err := safeCall(meta.ID, "provision", func() error {
+			return conn.Provision(connCtx, meta.ID, props)
+		})
+		if err == nil && isStateful {
+			sc.SetStatusChangeHandler(connCtx, meta.notifyDriverStatus)
+		}
+		return err
+	}
+	if err = provision(); err != nil {
+		// the props are rejected without dialing
+		if errorx.KindOf(err) == errorx.KindUnknown {
+			err = errorx.NewPermanent(err)
+		}
+		return nil, err
 	}
-	err = backoff.Retry(func() error {
+	// only the connections in the retry loop take the retry budget, the first dial is free
+	guard := retryGuardOf(meta.ID)
+	attempted, retrying := false, false
+	attempt := 0
+	// closed means the connection failed to warm up is closed
+	closed := false
+	defer func() {
+		if retrying {
+			guard.release()
+		}
+	}()
+	err = backoff.RetryNotifyWithTimer(func() error {
 		select {
 		case <-connCtx.Done():
 			return nil
 		default:
 		}
+		if attempted && !retrying {
+			if !guard.acquire(connCtx) {
+				return nil
+			}
+			retrying = true
+		}
+		if attempted {
+			meta.stats.retries.Add(1)
+		}
+		attempted = true
+		attempt++
+		if !guard.wait(connCtx) {
+			return nil
+		}
+		// switch to a healthy address or endpoint if the current one failed
+		if switched := (resolver != nil && resolver.Select() != addr) || (group != nil && group.selectEndpoint() != active); switched || closed {
+			if !closed {
+				_ = safeCall(meta.ID, "close", func() error {
+					return conn.Close(connCtx)
+				})
+			}
+			closed = false
+			if err = provision(); err != nil {
+				return backoff.Permanent(err)
+			}
+			if switched && group != nil {
+				connCtx.GetLogger().Infof("connection %s fails over to endpoint %d", meta.ID, active)
+			} else if switched {
+				connCtx.GetLogger().Infof("connection %s switches to %s", meta.ID, addr)
+			}
+		}
 		meta.NotifyStatus(api.ConnectionConnecting, "")
 		connCtx.GetLogger().Debugf("connection retry: %s", meta.ID)
-		err = conn.Dial(connCtx)
+		_, warmable := conn.(modules.Warmable)
+		meta.warming.Store(warmable)
+		if err = fault.InjectFor(fault.DialConnection, meta.ID); err == nil {
+			// a panic is a failed dial to retry
+			err = safeCall(meta.ID, "dial", func() error {
+				return conn.Dial(connCtx)
+			})
+			var pe *PanicError
+			if errors.As(err, &pe) {
+				err = errorx.NewTransient(err)
+			}
+		}
 		failpoint.Inject("createConnectionErr", func() {
 			if mockErr {
 				err = errorx.NewIOErr("createConnectionErr")
 				mockErr = false
 			}
 		})
+		warmed := false
+		if err == nil {
+			warmed, err = warmUpConn(connCtx, meta, conn)
+			if err != nil {
+				// the dialed connection is closed and provisioned again for the next attempt
+				_ = safeCall(meta.ID, "close", func() error {
+					return conn.Close(connCtx)
+				})
+				closed = true
+			}
+		}
+		meta.warming.Store(false)
+		// the endpoint is healthy only if the connection is warmed up
+		if resolver != nil {
+			if err == nil {
+				resolver.MarkHealthy(addr)
+			} else {
+				resolver.MarkFailed(addr)
+			}
+		}
+		if group != nil {
+			if err == nil {
+				group.markHealthy(active)
+			} else {
+				group.markFailed(active)
+			}
+		}
 		if err == nil {
-			if !isStateful {
+			guard.onSuccess()
+			ConnCreateDuration.WithLabelValues(meta.Typ).Observe(getClock().Since(start).Seconds())
+			// the stateful connection reported connected before warming up
+			if !isStateful || warmed {
 				meta.NotifyStatus(api.ConnectionConnected, "")
 			}
 			return nil
 		}
-		connCtx.GetLogger().Debugf("connection failed: %s, %v", meta.ID, err)
+		logConnError(meta, logrus.DebugLevel, "dial", attempt, 0, err)
 		meta.NotifyStatus(api.ConnectionDisconnected, err.Error())
-		if errorx.IsIOError(err) {
+		onTenantDialFailure(meta.ID)
+		if errorx.IsRetryable(err) {
+			guard.onFailure()
 			return err
 		}
 		return backoff.Permanent(err)
-	}, NewExponentialBackOff())
+	}, backoff.WithContext(connRetryPolicy(meta.Typ, meta.Props).NewBackOff(), connCtx), nil, newRetryTimer())
 	return conn, err
 }
 
@@ -0,0 +1,167 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/context"
+	mockContext "github.com/lf-edge/ekuiper/v2/pkg/mock/context"
+)
+
+// The benchmarks simulate the high-churn workloads of the short-lived scheduled rules. Run them with
+// go test -run=^$ -bench=. -cpu=1,4,8 ./pkg/connection
+
+func setupBench(b *testing.B) {
+	require.NoError(b, InitConnectionManager4Test())
+	level := conf.Log.GetLevel()
+	conf.Log.SetLevel(logrus.WarnLevel)
+	b.Cleanup(func() {
+		conf.Log.SetLevel(level)
+	})
+}
+
+var benchRuleSeq atomic.Int64
+
+func benchRuleCtx() api.StreamContext {
+	return mockContext.NewMockContext(fmt.Sprintf("rule%d", benchRuleSeq.Add(1)), "op")
+}
+
+// BenchmarkAttachDetachNamed attaches and detaches the rules to a shared named connection
+func BenchmarkAttachDetachNamed(b *testing.B) {
+	setupBench(b)
+	ctx := context.Background()
+	_, err := CreateNamedConnection(ctx, "bench", "mock", map[string]any{})
+	require.NoError(b, err)
+	props := map[string]any{"connectionSelector": "bench"}
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		rctx := benchRuleCtx()
+		refId := extractRefId(rctx)
+		for pb.Next() {
+			if _, err := FetchConnection(rctx, refId, "mock", props, nil); err != nil {
+				b.Error(err)
+				return
+			}
+			if err := DetachConnection(rctx, "bench"); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
+
+// BenchmarkAnonymousChurn creates and drops the anonymous connections of the rules
+func BenchmarkAnonymousChurn(b *testing.B) {
+	setupBench(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		rctx := benchRuleCtx()
+		i := 0
+		for pb.Next() {
+			id := fmt.Sprintf("%s_%d", rctx.GetRuleId(), i)
+			i++
+			if _, err := FetchConnection(rctx, id, "mock", nil, nil); err != nil {
+				b.Error(err)
+				return
+			}
+			if err := DetachConnection(rctx, id); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
+
+// BenchmarkStatusUnderChurn queries the status of all the connections while the rules keep attaching and detaching
+func BenchmarkStatusUnderChurn(b *testing.B) {
+	setupBench(b)
+	ctx := context.Background()
+	for i := 0; i < 100; i++ {
+		_, err := CreateNamedConnection(ctx, fmt.Sprintf("bench%d", i), "mock", map[string]any{})
+		require.NoError(b, err)
+	}
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			rctx := benchRuleCtx()
+			id := fmt.Sprintf("bench%d", i)
+			props := map[string]any{"connectionSelector": id}
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				_, _ = FetchConnection(rctx, extractRefId(rctx), "mock", props, nil)
+				_ = DetachConnection(rctx, id)
+			}
+		}(i)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			for _, meta := range GetAllConnectionsMeta(true) {
+				meta.GetStatus()
+			}
+		}
+	})
+	b.StopTimer()
+	close(stop)
+	wg.Wait()
+}
+
+func TestConcurrentAttachDetach(t *testing.T) {
+	require.NoError(t, InitConnectionManager4Test())
+	ctx := context.Background()
+	_, err := CreateNamedConnection(ctx, "shared", "mock", map[string]any{})
+	require.NoError(t, err)
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rctx := benchRuleCtx()
+			for j := 0; j < 50; j++ {
+				_, err := FetchConnection(rctx, extractRefId(rctx), "mock", map[string]any{"connectionSelector": "shared"}, nil)
+				require.NoError(t, err)
+				// the anonymous connection shared by the rules with the same id
+				_, err = FetchConnection(rctx, "anon", "mock", nil, nil)
+				require.NoError(t, err)
+				require.NoError(t, DetachConnection(rctx, "anon"))
+				require.NoError(t, DetachConnection(rctx, "shared"))
+			}
+		}()
+	}
+	wg.Wait()
+	require.Equal(t, 0, globalConnectionManager.getConnectionRef("shared"))
+	_, err = GetConnectionDetail(ctx, "anon")
+	require.Error(t, err)
+	require.Len(t, GetAllConnectionsMeta(true), 1)
+}
@@ -15,15 +15,22 @@
 package connection
 
 import (
+	gocontext "context"
+	"errors"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/lf-edge/ekuiper/contract/v2/api"
 	"github.com/pingcap/failpoint"
 	"github.com/stretchr/testify/require"
 
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
 	"github.com/lf-edge/ekuiper/v2/internal/topo/context"
+	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
 	mockContext "github.com/lf-edge/ekuiper/v2/pkg/mock/context"
+	"github.com/lf-edge/ekuiper/v2/pkg/model"
 	"github.com/lf-edge/ekuiper/v2/pkg/modules"
 )
 
@@ -36,28 +43,28 @@ func TestConnection(t *testing.T) {
 	require.NoError(t, err)
 	require.NotNil(t, conn)
 	require.NoError(t, conn.Ping(ctx))
-	require.Equal(t, 0, getConnectionRef("id1"))
+	require.Equal(t, 0, globalConnectionManager.getConnectionRef("id1"))
 	_, err = CreateNamedConnection(ctx, "id1", "mock", nil)
 	require.Error(t, err)
-	_, err = attachConnection("id1", "ref1", nil)
+	_, err = globalConnectionManager.attachConnection("id1", "ref1", nil)
 	require.NoError(t, err)
-	require.Equal(t, 1, getConnectionRef("id1"))
-	_, err = attachConnection("id1", "ref2", nil)
+	require.Equal(t, 1, globalConnectionManager.getConnectionRef("id1"))
+	_, err = globalConnectionManager.attachConnection("id1", "ref2", nil)
 	require.NoError(t, err)
-	require.Equal(t, 2, getConnectionRef("id1"))
-	err = detachConnection(ctx, "id1")
+	require.Equal(t, 2, globalConnectionManager.getConnectionRef("id1"))
+	err = globalConnectionManager.detachConnection(ctx, "id1")
 	require.NoError(t, err)
-	require.Equal(t, 1, getConnectionRef("id1"))
+	require.Equal(t, 1, globalConnectionManager.getConnectionRef("id1"))
 	err = DropNameConnection(ctx, "id1")
 	require.Error(t, err)
-	err = detachConnection(ctx, "id1")
+	err = globalConnectionManager.detachConnection(ctx, "id1")
 	require.NoError(t, err)
-	require.Equal(t, 0, getConnectionRef("id1"))
+	require.Equal(t, 0, globalConnectionManager.getConnectionRef("id1"))
 	err = DropNameConnection(ctx, "id1")
 	require.NoError(t, err)
 	err = DropNameConnection(ctx, "id1")
 	require.NoError(t, err)
-	conn3, err := attachConnection("id1", "ref3", nil)
+	conn3, err := globalConnectionManager.attachConnection("id1", "ref3", nil)
 	require.Error(t, err)
 	require.Nil(t, conn3)
 
@@ -69,7 +76,7 @@ func TestConnection(t *testing.T) {
 	require.NoError(t, err)
 	require.NotNil(t, cw)
 
-	require.Equal(t, 1, getConnectionRef("id2"))
+	require.Equal(t, 1, globalConnectionManager.getConnectionRef("id2"))
 }
 
 func TestConnectionErr(t *testing.T) {
@@ -85,7 +92,7 @@ func TestConnectionErr(t *testing.T) {
 	require.NoError(t, err)
 	_, err = cw.Wait(ctx)
 	require.Error(t, err)
-	_, err = attachConnection("", "ref1", nil)
+	_, err = globalConnectionManager.attachConnection("", "ref1", nil)
 	require.Error(t, err)
 	err = DetachConnection(ctx, "")
 	require.Error(t, err)
@@ -136,14 +143,14 @@ func TestNonStoredConnection(t *testing.T) {
 	ctx := mockContext.NewMockContext("id", "2")
 	_, err := FetchConnection(ctx, "id1", "mock", nil, nil)
 	require.NoError(t, err)
-	require.Equal(t, 1, getConnectionRef("id1"))
+	require.Equal(t, 1, globalConnectionManager.getConnectionRef("id1"))
 	_, err = FetchConnection(ctx, "id1", "mock", nil, nil)
 	require.NoError(t, err)
-	require.Equal(t, 2, getConnectionRef("id1"))
+	require.Equal(t, 2, globalConnectionManager.getConnectionRef("id1"))
 	require.NoError(t, DetachConnection(ctx, "id1"))
-	require.Equal(t, 1, getConnectionRef("id1"))
+	require.Equal(t, 1, globalConnectionManager.getConnectionRef("id1"))
 	require.NoError(t, DetachConnection(ctx, "id1"))
-	require.Equal(t, 0, getConnectionRef("id1"))
+	require.Equal(t, 0, globalConnectionManager.getConnectionRef("id1"))
 	_, ok := globalConnectionManager.connectionPool["id1"]
 	require.False(t, ok)
 }
@@ -173,13 +180,68 @@ func TestConnectionLock(t *testing.T) {
 	require.NoError(t, DropNameConnection(ctx, "ccc2"))
 }
 
+func TestCreateConnectionAsync(t *testing.T) {
+	require.NoError(t, InitConnectionManager4Test())
+	ctx := mockContext.NewMockContext("id", "2")
+	// the creation returns while dialing
+	cw, err := CreateNamedConnection(ctx, "async1", "blockconn", nil)
+	require.NoError(t, err)
+	meta, err := GetConnectionDetail(ctx, "async1")
+	require.NoError(t, err)
+	s, _ := meta.GetStatus()
+	require.Equal(t, api.ConnectionConnecting, s)
+	require.False(t, cw.IsInitialized())
+	blockCh <- struct{}{}
+	conn, err := cw.Wait(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, conn)
+	s, _ = meta.GetStatus()
+	require.Equal(t, api.ConnectionConnected, s)
+	require.NoError(t, DropNameConnection(ctx, "async1"))
+}
+
+func TestSlowCloseNotBlockPool(t *testing.T) {
+	require.NoError(t, InitConnectionManager4Test())
+	ctx := mockContext.NewMockContext("id", "2")
+	cw, err := CreateNamedConnection(ctx, "slow1", "slowclose", nil)
+	require.NoError(t, err)
+	_, err = cw.Wait(ctx)
+	require.NoError(t, err)
+	dropped := make(chan error, 1)
+	go func() {
+		dropped <- DropNameConnection(ctx, "slow1")
+	}()
+	<-closeStartCh
+	// the pool is not locked while closing slow1
+	require.False(t, checkConn("slow1"))
+	_, err = CreateNamedConnection(ctx, "fast1", "mock", nil)
+	require.NoError(t, err)
+	_, err = FetchConnection(ctx, "fast2", "mock", nil, nil)
+	require.NoError(t, err)
+	require.NoError(t, DetachConnection(ctx, "fast2"))
+	require.NoError(t, DropNameConnection(ctx, "fast1"))
+	select {
+	case <-dropped:
+		require.Fail(t, "drop should wait for the close")
+	default:
+	}
+	closeReleaseCh <- struct{}{}
+	require.NoError(t, <-dropped)
+}
+
 var blockCh chan any
 
+var (
+	closeStartCh   = make(chan struct{}, 1)
+	closeReleaseCh = make(chan struct{}, 1)
+)
+
 func init() {
 	blockCh = make(chan any, 10)
 	modules.RegisterConnection("blockconn", CreateBlockConnection)
 	modules.RegisterConnection("mock", CreateMockConnection)
 	modules.RegisterConnection("mockerr", CreateMockErrConnection)
+	modules.RegisterConnection("slowclose", CreateSlowCloseConnection)
 }
 
 type blockConnection struct {
@@ -213,6 +275,28 @@ func CreateBlockConnection(ctx api.StreamContext) modules.Connection {
 	return &blockConnection{}
 }
 
+// slowCloseConnection blocks in Close until it is released
+type slowCloseConnection struct {
+	blockConnection
+}
+
+func (s *slowCloseConnection) Dial(ctx api.StreamContext) error {
+	return nil
+}
+
+func (s *slowCloseConnection) Close(ctx api.StreamContext) error {
+	closeStartCh <- struct{}{}
+	select {
+	case <-closeReleaseCh:
+	case <-ctx.Done():
+	}
+	return nil
+}
+
+func CreateSlowCloseConnection(ctx api.StreamContext) modules.Connection {
+	return &slowCloseConnection{}
+}
+
 func checkConn(id string) bool {
 	globalConnectionManager.RLock()
 	defer globalConnectionManager.RUnlock()
@@ -225,3 +309,256 @@ func TestFetchConnectionNotExist(t *testing.T) {
 	_, err := FetchConnection(ctx, "2222", "mock", map[string]interface{}{"connectionSelector": "id2"}, nil)
 	require.Error(t, err)
 }
+
+func TestApplyConnectionEvent(t *testing.T) {
+	require.NoError(t, InitConnectionManager4Test())
+	ctx := context.Background()
+	// created by other nodes
+	globalConnectionManager.applyConnectionEvent(conf.ConfigEvent{Type: conf.ConfigEventAdd, Key: "connections.mock.w1", Props: map[string]any{"a": 1}})
+	meta, err := GetConnectionDetail(ctx, "w1")
+	require.NoError(t, err)
+	require.True(t, meta.Named)
+	require.Equal(t, map[string]any{"a": 1}, meta.Props)
+	// updated by other nodes
+	globalConnectionManager.applyConnectionEvent(conf.ConfigEvent{Type: conf.ConfigEventUpdate, Key: "connections.mock.w1", Props: map[string]any{"a": 2}})
+	meta, err = GetConnectionDetail(ctx, "w1")
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"a": 2}, meta.Props)
+	// referenced connection is reloaded in place
+	cw, err := globalConnectionManager.attachConnection("w1", "ref1", nil)
+	require.NoError(t, err)
+	globalConnectionManager.applyConnectionEvent(conf.ConfigEvent{Type: conf.ConfigEventUpdate, Key: "connections.mock.w1", Props: map[string]any{"a": 3}})
+	meta, err = GetConnectionDetail(ctx, "w1")
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"a": 3}, meta.Props)
+	require.Same(t, cw, meta.cw)
+	require.Equal(t, 1, meta.GetRefCount())
+	// referenced connection is not dropped or changed to another type
+	globalConnectionManager.applyConnectionEvent(conf.ConfigEvent{Type: conf.ConfigEventUpdate, Key: "connections.other.w1", Props: map[string]any{"a": 4}})
+	meta, err = GetConnectionDetail(ctx, "w1")
+	require.NoError(t, err)
+	require.Equal(t, "mock", meta.Typ)
+	globalConnectionManager.applyConnectionEvent(conf.ConfigEvent{Type: conf.ConfigEventDelete, Key: "connections.mock.w1"})
+	_, err = GetConnectionDetail(ctx, "w1")
+	require.NoError(t, err)
+	require.NoError(t, globalConnectionManager.detachConnection(ctx, "w1"))
+	globalConnectionManager.applyConnectionEvent(conf.ConfigEvent{Type: conf.ConfigEventDelete, Key: "connections.mock.w1"})
+	_, err = GetConnectionDetail(ctx, "w1")
+	require.Error(t, err)
+	// invalid key is ignored
+	globalConnectionManager.applyConnectionEvent(conf.ConfigEvent{Type: conf.ConfigEventAdd, Key: "connections.w2"})
+	require.Len(t, GetAllConnectionsMeta(true), 0)
+}
+
+func TestRegisterConnectionType(t *testing.T) {
+	require.NoError(t, InitConnectionManager4Test())
+	ctx := context.Background()
+	require.NoError(t, modules.RegisterConnectionType("dynconn", CreateMockConnection))
+	require.Error(t, modules.RegisterConnectionType("dynconn", CreateMockConnection))
+	require.Error(t, modules.RegisterConnectionType("nilconn", nil))
+	_, err := CreateNamedConnection(ctx, "dyn1", "dynconn", nil)
+	require.NoError(t, err)
+	err = UnregisterConnectionType("DynConn")
+	require.Error(t, err)
+	code, _ := errorx.GetErrorCode(err)
+	require.Equal(t, errorx.ConnectionInUseErr, code)
+	require.NoError(t, DropNameConnection(ctx, "dyn1"))
+	require.NoError(t, UnregisterConnectionType("dynconn"))
+	_, ok := modules.GetConnectionProvider("dynconn")
+	require.False(t, ok)
+	// can register again after unregistered
+	require.NoError(t, modules.RegisterConnectionType("dynconn", CreateMockConnection))
+	require.NoError(t, UnregisterConnectionType("dynconn"))
+}
+
+type subOnlyConnection struct {
+	mockConnection
+}
+
+func (s *subOnlyConnection) CanSubscribe() bool {
+	return true
+}
+
+func TestCheckConnectionCapability(t *testing.T) {
+	require.NoError(t, InitConnectionManager4Test())
+	ctx := context.Background()
+	require.NoError(t, modules.RegisterConnectionType("subonly", func(_ api.StreamContext) modules.Connection {
+		return &subOnlyConnection{}
+	}))
+	defer modules.UnregisterConnection("subonly")
+	caps, declared := modules.GetConnectionCapabilities(ctx, "subonly")
+	require.True(t, declared)
+	require.Equal(t, []modules.Capability{modules.CapSubscribe}, caps)
+	_, declared = modules.GetConnectionCapabilities(ctx, "mock")
+	require.False(t, declared)
+	require.Equal(t, []modules.Capability{modules.CapSubscribe}, modules.ConnectionCapabilities(ctx)["subonly"])
+	modules.RegisterConnectionDescriptor(modules.ConnectionDescriptor{Type: "subonly", DisplayName: "Sub Only", Props: []modules.ConnectionPropDescriptor{{Name: "topic", Type: "string"}}})
+	for _, info := range modules.ListConnectionTypes(ctx) {
+		switch info.Type {
+		case "subonly":
+			require.Equal(t, modules.ConnectionTypeInfo{
+				Type: "subonly", DisplayName: "Sub Only", Ping: true,
+				Capabilities: []modules.Capability{modules.CapSubscribe},
+				Props:        []modules.ConnectionPropDescriptor{{Name: "topic", Type: "string"}},
+			}, info)
+		case "mock":
+			require.Equal(t, modules.ConnectionTypeInfo{Type: "mock", DisplayName: "mock", Ping: true}, info)
+		}
+	}
+
+	_, err := CreateNamedConnection(ctx, "sub1", "subonly", nil)
+	require.NoError(t, err)
+	_, err = CreateNamedConnection(ctx, "mock1", "mock", nil)
+	require.NoError(t, err)
+	require.NoError(t, CheckConnectionCapability(ctx, "sub1", modules.CapSubscribe))
+	require.EqualError(t, CheckConnectionCapability(ctx, "sub1", modules.CapPublish), "connection sub1 of type subonly doesn't support publish")
+	// not declared, always valid
+	require.NoError(t, CheckConnectionCapability(ctx, "mock1", modules.CapQuery))
+	require.Error(t, CheckConnectionCapability(ctx, "notexist", modules.CapQuery))
+	require.NoError(t, DropNameConnection(ctx, "sub1"))
+	require.NoError(t, DropNameConnection(ctx, "mock1"))
+}
+
+type failDialConnection struct {
+	mockConnection
+}
+
+func (f *failDialConnection) Dial(ctx api.StreamContext) error {
+	return errorx.NewIOErr("network down")
+}
+
+func TestAbortRetryWhenStopped(t *testing.T) {
+	require.NoError(t, InitConnectionManager4Test())
+	conf.InitConf()
+	conf.Config.Connection.TypeRetry = map[string]model.RetryConf{"faildial": {Policy: RetryConstant, Interval: cast.DurationConf(time.Hour)}}
+	defer func() {
+		conf.Config.Connection.TypeRetry = nil
+	}()
+	require.NoError(t, modules.RegisterConnectionType("faildial", func(_ api.StreamContext) modules.Connection {
+		return &failDialConnection{}
+	}))
+	defer modules.UnregisterConnection("faildial")
+
+	// anonymous connection is aborted when the rule stops
+	ruleCtx, cancel := context.Background().WithCancel()
+	cw, err := FetchConnection(ruleCtx, "r1", "faildial", nil, nil)
+	require.NoError(t, err)
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	waitCtx, waitCancel := context.Background().WithTimeout(time.Second)
+	defer waitCancel()
+	start := time.Now()
+	_, err = cw.Wait(waitCtx)
+	require.Error(t, err)
+	require.Less(t, time.Since(start), time.Second)
+
+	// named connection is aborted when dropped
+	cw, err = CreateNamedConnection(context.Background(), "fail1", "faildial", nil)
+	require.NoError(t, err)
+	time.Sleep(50 * time.Millisecond)
+	require.NoError(t, DropNameConnection(context.Background(), "fail1"))
+	require.True(t, cw.IsInitialized())
+	_, err = cw.Wait(context.Background())
+	require.ErrorIs(t, err, gocontext.Canceled)
+}
+
+func TestProbeNamedConnections(t *testing.T) {
+	conf.InitConf()
+	origin := conf.Config.Connection.StoreType
+	defer func() {
+		conf.Config.Connection.StoreType = origin
+	}()
+	// only probe the connections stored by the test
+	RegisterConnectionStore("probetest", func() (ConnectionStore, error) {
+		return &memConnectionStore{conns: map[string]StoredConnection{}}, nil
+	})
+	conf.Config.Connection.StoreType = "probetest"
+	require.NoError(t, InitConnectionManager4Test())
+	require.NoError(t, modules.RegisterConnectionType("faildial", func(_ api.StreamContext) modules.Connection {
+		return &failDialConnection{}
+	}))
+	defer modules.UnregisterConnection("faildial")
+	ctx := context.Background()
+	require.NoError(t, storeConnectionMeta("mock", "probe1", map[string]any{}))
+	require.NoError(t, storeConnectionMeta("faildial", "probe2", map[string]any{}))
+	defer func() {
+		_ = dropConnectionStore("mock", "probe1")
+		_ = dropConnectionStore("faildial", "probe2")
+	}()
+	results, err := ProbeNamedConnections(ctx)
+	require.NoError(t, err)
+	got := make(map[string]error)
+	for _, r := range results {
+		got[r.ID] = r.Err
+	}
+	require.NoError(t, got["probe1"])
+	require.EqualError(t, got["probe2"], "network down")
+	// probed connections are not in the pool
+	require.Empty(t, GetAllConnectionsMeta(true))
+}
+
+func TestConnectionErrorsIs(t *testing.T) {
+	require.NoError(t, InitConnectionManager4Test())
+	ctx := context.Background()
+	_, err := CreateNamedConnection(ctx, "errs1", "mock", nil)
+	require.NoError(t, err)
+	defer func() {
+		_ = DropNameConnectionPermanently(ctx, "errs1")
+	}()
+
+	_, err = CreateNamedConnection(ctx, "errs1", "mock", nil)
+	require.ErrorIs(t, err, errorx.ErrConnectionExist)
+	_, err = CreateNamedConnection(ctx, "", "mock", nil)
+	require.ErrorIs(t, err, errorx.ErrConnectionInvalid)
+	_, err = ValidateConnection(ctx, "unknownType", nil)
+	require.ErrorIs(t, err, errorx.ErrConnectionInvalid)
+	_, err = GetConnectionDetail(ctx, "errs2")
+	require.ErrorIs(t, err, errorx.ErrConnectionNotFound)
+
+	ruleCtx := mockContext.NewMockContext("errsRule", "op1")
+	_, err = FetchConnection(ruleCtx, "errs1", "mock", map[string]any{"connectionSelector": "errs1"}, nil)
+	require.NoError(t, err)
+	err = DropNameConnection(ctx, "errs1")
+	require.ErrorIs(t, err, errorx.ErrConnectionInUse)
+	require.False(t, errors.Is(err, errorx.ErrConnectionNotFound))
+	require.NoError(t, DetachConnection(ruleCtx, "errs1"))
+}
+
+func TestCreateRollback(t *testing.T) {
+	require.NoError(t, InitConnectionManager4Test())
+	modules.RegisterConnection("propsconn", func(ctx api.StreamContext) modules.Connection {
+		return &propsConnection{}
+	})
+	ctx := context.Background()
+
+	// the props are rejected by the connection, so the created connection and its stored props are rolled back
+	cw, err := CreateNamedConnection(ctx, "rollback1", "propsconn", map[string]any{"fail": true})
+	require.NoError(t, err)
+	_, err = cw.Wait(ctx)
+	require.Error(t, err)
+	require.Eventually(t, func() bool {
+		_, err := GetConnectionDetail(ctx, "rollback1")
+		return err != nil
+	}, time.Second, 10*time.Millisecond)
+	require.Eventually(t, func() bool {
+		stored, _ := conf.GetCfgFromKVStorage("connections", "propsconn", "rollback1")
+		_, ok := stored["connections.propsconn.rollback1"]
+		return !ok
+	}, time.Second, 10*time.Millisecond)
+
+	// the connection failed by the network is kept
+	modules.RegisterConnection("flakyconn", func(ctx api.StreamContext) modules.Connection {
+		return &flakyConnection{}
+	})
+	flakyDialFail.Store(true)
+	defer flakyDialFail.Store(false)
+	cw, err = CreateNamedConnection(ctx, "rollback2", "flakyconn", nil)
+	require.NoError(t, err)
+	defer func() {
+		_ = DropNameConnectionPermanently(ctx, "rollback2")
+	}()
+	_, err = cw.Wait(ctx)
+	require.Error(t, err)
+	_, err = GetConnectionDetail(ctx, "rollback2")
+	require.NoError(t, err)
+}
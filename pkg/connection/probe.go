@@ -0,0 +1,81 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+	"github.com/lf-edge/ekuiper/v2/pkg/modules"
+)
+
+// ProbeResult is the result of probing a stored named connection
+type ProbeResult struct {
+	ID  string
+	Typ string
+	Err error
+}
+
+// ProbeNamedConnections dials and pings each stored named connection once without retry. The probed connections
+// are closed right after and not added to the pool.
+func ProbeNamedConnections(ctx api.StreamContext) ([]ProbeResult, error) {
+	s, err := getConnectionStore()
+	if err != nil {
+		return nil, err
+	}
+	stored, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	results := make([]ProbeResult, 0, len(stored))
+	for _, c := range stored {
+		typ, id := c.Typ, c.ID
+		props, err := decryptSecrets(c.Props)
+		if err == nil {
+			err = probeConnection(ctx, id, typ, props)
+		}
+		results = append(results, ProbeResult{ID: id, Typ: typ, Err: err})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].ID < results[j].ID
+	})
+	return results, nil
+}
+
+func probeConnection(ctx api.StreamContext, id, typ string, props map[string]any) error {
+	provider, ok := modules.GetConnectionProvider(strings.ToLower(typ))
+	if !ok {
+		return errorx.NewWithCode(errorx.ConnectionInvalidErr, fmt.Sprintf("unknown connection type %s", typ))
+	}
+	opCtx, cancel := withTimeout(ctx)
+	defer cancel()
+	props, err := resolveEndpointProps(typ, withoutReservedProps(props))
+	if err != nil {
+		return err
+	}
+	conn := provider(opCtx)
+	if err := conn.Provision(opCtx, id, props); err != nil {
+		return err
+	}
+	if err := conn.Dial(opCtx); err != nil {
+		return err
+	}
+	defer conn.Close(opCtx)
+	return conn.Ping(opCtx)
+}
@@ -0,0 +1,60 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+)
+
+// checkPoolQuota checks whether the pool can hold one more connection of the type by the global max connections and
+// the quota of the type. The connection of the same id is not counted because it is being replaced. It must be
+// called with the pool lock.
+func (m *ConnectionManager) checkPoolQuota(id, typ string) error {
+	if conf.Config == nil {
+		return nil
+	}
+	c := conf.Config.Connection
+	typeQuota := 0
+	for t, q := range c.TypeQuotas {
+		if strings.EqualFold(t, typ) {
+			typeQuota = q
+			break
+		}
+	}
+	if c.MaxConnections <= 0 && typeQuota <= 0 {
+		return nil
+	}
+	total, ofType := 0, 0
+	for cid, meta := range m.connectionPool {
+		if cid == id {
+			continue
+		}
+		total++
+		if strings.EqualFold(meta.Typ, typ) {
+			ofType++
+		}
+	}
+	if c.MaxConnections > 0 && total >= c.MaxConnections {
+		return errorx.NewWithCode(errorx.ConnectionQuotaErr, fmt.Sprintf("connection pool exceeds the quota of %d connections", c.MaxConnections))
+	}
+	if typeQuota > 0 && ofType >= typeQuota {
+		return errorx.NewWithCode(errorx.ConnectionQuotaErr, fmt.Sprintf("connection type %s exceeds the quota of %d connections", typ, typeQuota))
+	}
+	return nil
+}
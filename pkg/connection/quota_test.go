@@ -0,0 +1,70 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/context"
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+	"github.com/lf-edge/ekuiper/v2/pkg/modules"
+)
+
+func init() {
+	modules.RegisterConnection("quotaconn", CreateMockConnection)
+}
+
+func TestPoolQuota(t *testing.T) {
+	conf.InitConf()
+	originMax, originTypes := conf.Config.Connection.MaxConnections, conf.Config.Connection.TypeQuotas
+	defer func() {
+		conf.Config.Connection.MaxConnections = originMax
+		conf.Config.Connection.TypeQuotas = originTypes
+		require.NoError(t, InitConnectionManager4Test())
+	}()
+	conf.Config.Connection.MaxConnections = 3
+	conf.Config.Connection.TypeQuotas = map[string]int{"QuotaConn": 1}
+	require.NoError(t, InitConnectionManager4Test())
+	ctx := context.Background()
+
+	_, err := CreateNamedConnection(ctx, "q1", "quotaconn", map[string]any{})
+	require.NoError(t, err)
+	_, err = CreateNamedConnection(ctx, "q2", "quotaconn", map[string]any{})
+	code, _ := errorx.GetErrorCode(err)
+	require.Equal(t, errorx.ConnectionQuotaErr, code)
+	require.Equal(t, errorx.KindQuota, errorx.KindOf(err))
+	// update in place does not count itself
+	_, err = UpdateConnection(ctx, "q1", "quotaconn", map[string]any{"a": 1})
+	require.NoError(t, err)
+
+	_, err = CreateNamedConnection(ctx, "q2", "mock", map[string]any{})
+	require.NoError(t, err)
+	_, err = FetchConnection(ctx, "q3", "mock", nil, nil)
+	require.NoError(t, err)
+	// the anonymous connections are counted
+	_, err = FetchConnection(ctx, "q4", "mock", nil, nil)
+	code, _ = errorx.GetErrorCode(err)
+	require.Equal(t, errorx.ConnectionQuotaErr, code)
+	// attach to the existing connection is not limited
+	_, err = FetchConnection(ctx, "q5", "mock", map[string]any{"connectionSelector": "q2"}, nil)
+	require.NoError(t, err)
+
+	conf.Config.Connection.MaxConnections = 0
+	_, err = FetchConnection(ctx, "q4", "mock", nil, nil)
+	require.NoError(t, err)
+}
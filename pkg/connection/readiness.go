@@ -0,0 +1,145 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+)
+
+// The readiness policies decide which connections being down fail the readiness of the server
+const (
+	// ReadinessAll fails the readiness if any named connection is down
+	ReadinessAll = "all"
+	// ReadinessCritical fails the readiness only if the named connections labeled critical are down
+	ReadinessCritical = "critical"
+	// ReadinessNone never fails the readiness by the connections
+	ReadinessNone = "none"
+)
+
+const defaultCriticalLabel = "critical"
+
+// TypeHealth counts the connections of a type by their state
+type TypeHealth struct {
+	Total        int `json:"total"`
+	Running      int `json:"running"`
+	Failed       int `json:"failed"`
+	Reconnecting int `json:"reconnecting"`
+	Paused       int `json:"paused"`
+}
+
+// PoolReadiness is the aggregated health of the pool to back the readiness probe
+type PoolReadiness struct {
+	Ready        bool   `json:"ready"`
+	Policy       string `json:"policy"`
+	Total        int    `json:"total"`
+	Running      int    `json:"running"`
+	Failed       int    `json:"failed"`
+	Reconnecting int    `json:"reconnecting"`
+	Paused       int    `json:"paused"`
+	// Types are the counts by the connection type
+	Types map[string]*TypeHealth `json:"types"`
+	// MaxPingLatency is the worst latency in milliseconds of the last pings of the stateless connections, and
+	// SlowestConnection is the connection of it
+	MaxPingLatency    int64  `json:"maxPingLatency"`
+	SlowestConnection string `json:"slowestConnection,omitempty"`
+	// Down are the connections failing the readiness by the policy
+	Down []string `json:"down,omitempty"`
+}
+
+func (h *TypeHealth) count(status string) {
+	h.Total++
+	switch status {
+	case api.ConnectionConnected:
+		h.Running++
+	case api.ConnectionConnecting, ConnectionWarming:
+		h.Reconnecting++
+	case api.ConnectionDisconnected:
+		h.Failed++
+	case ConnectionPaused:
+		h.Paused++
+	}
+}
+
+func readinessConf() (policy, label string) {
+	policy, label = ReadinessAll, defaultCriticalLabel
+	if conf.Config == nil {
+		return
+	}
+	r := conf.Config.Connection.Readiness
+	if r.Policy != "" {
+		policy = strings.ToLower(r.Policy)
+	}
+	if r.CriticalLabel != "" {
+		label = r.CriticalLabel
+	}
+	return
+}
+
+// GetPoolHealth returns the aggregated health of the connections in the default pool
+func GetPoolHealth(ctx api.StreamContext) *PoolReadiness {
+	return globalConnectionManager.PoolHealth(ctx)
+}
+
+// PoolHealth aggregates the connections by state and type, and decides the readiness by the configured policy. A
+// named connection is down if it is disconnected, still connecting or warming up, while the paused connections are never down.
+// The status of each connection is evaluated out of the pool lock because the stateless connections need to ping.
+func (m *ConnectionManager) PoolHealth(_ api.StreamContext) *PoolReadiness {
+	policy, label := readinessConf()
+	h := &PoolReadiness{Ready: true, Policy: policy, Types: make(map[string]*TypeHealth)}
+	for id, meta := range m.load() {
+		status, _ := meta.GetStatus()
+		typ := strings.ToLower(meta.Typ)
+		th, ok := h.Types[typ]
+		if !ok {
+			th = &TypeHealth{}
+			h.Types[typ] = th
+		}
+		th.count(status)
+		if l := meta.stats.pingLatency.Load(); l > h.MaxPingLatency {
+			h.MaxPingLatency = l
+			h.SlowestConnection = id
+		}
+		if !meta.Named || (status != api.ConnectionDisconnected && status != api.ConnectionConnecting && status != ConnectionWarming) {
+			continue
+		}
+		switch policy {
+		case ReadinessAll:
+		case ReadinessCritical:
+			if meta.Labels()[label] != "true" {
+				continue
+			}
+		default:
+			continue
+		}
+		h.Down = append(h.Down, id)
+	}
+	for _, th := range h.Types {
+		h.Total += th.Total
+		h.Running += th.Running
+		h.Failed += th.Failed
+		h.Reconnecting += th.Reconnecting
+		h.Paused += th.Paused
+	}
+	if len(h.Down) > 0 {
+		sort.Strings(h.Down)
+		h.Ready = false
+	}
+	return h
+}
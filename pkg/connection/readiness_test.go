@@ -0,0 +1,86 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/context"
+)
+
+func TestPoolHealth(t *testing.T) {
+	require.NoError(t, InitConnectionManager4Test())
+	old := conf.Config.Connection.Readiness
+	defer func() {
+		conf.Config.Connection.Readiness = old
+	}()
+	ctx := context.Background()
+	m := NewConnectionManager()
+	_, err := m.Create(ctx, "ready1", "mock", nil)
+	require.NoError(t, err)
+	defer func() {
+		_ = m.DropPermanently(ctx, "ready1")
+	}()
+	require.Eventually(t, func() bool {
+		return m.PoolHealth(ctx).Running == 1
+	}, time.Second, 10*time.Millisecond)
+
+	add := func(id, typ, status string, critical bool) *Meta {
+		meta := &Meta{ID: id, Typ: typ, Named: true, Props: map[string]any{}}
+		if critical {
+			meta.Props[LabelsPropKey] = map[string]any{"critical": "true"}
+		}
+		meta.status.Store(status)
+		m.Lock()
+		m.put(id, meta)
+		m.Unlock()
+		return meta
+	}
+	add("failed1", "mqtt", api.ConnectionDisconnected, false)
+	add("connecting1", "MQTT", api.ConnectionConnecting, true)
+	paused := add("paused1", "sql", api.ConnectionDisconnected, true)
+	paused.paused.Store(true)
+	paused.stats.pingLatency.Store(30)
+
+	conf.Config.Connection.Readiness.Policy = ReadinessAll
+	h := m.PoolHealth(ctx)
+	require.False(t, h.Ready)
+	require.Equal(t, []string{"connecting1", "failed1"}, h.Down)
+	require.Equal(t, 4, h.Total)
+	require.Equal(t, 1, h.Running)
+	require.Equal(t, 1, h.Failed)
+	require.Equal(t, 1, h.Reconnecting)
+	require.Equal(t, 1, h.Paused)
+	require.Equal(t, &TypeHealth{Total: 2, Failed: 1, Reconnecting: 1}, h.Types["mqtt"])
+	require.Equal(t, int64(30), h.MaxPingLatency)
+	require.Equal(t, "paused1", h.SlowestConnection)
+
+	conf.Config.Connection.Readiness.Policy = ReadinessCritical
+	h = m.PoolHealth(ctx)
+	require.False(t, h.Ready)
+	require.Equal(t, []string{"connecting1"}, h.Down)
+
+	conf.Config.Connection.Readiness.CriticalLabel = "tier1"
+	require.True(t, m.PoolHealth(ctx).Ready)
+
+	conf.Config.Connection.Readiness = old
+	conf.Config.Connection.Readiness.Policy = ReadinessNone
+	require.True(t, m.PoolHealth(ctx).Ready)
+}
@@ -0,0 +1,184 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	gocontext "context"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+
+	"github.com/lf-edge/ekuiper/v2/pkg/modules"
+	"github.com/lf-edge/ekuiper/v2/pkg/syncx"
+)
+
+// leases tracks which connection each reference of the wrapper holds. The consumers cache the connection they wait
+// for at connect, so a connection swapped out of the wrapper is retired instead of closed until no reference holds
+// it. A reference moves to the new connection once it gets it by Conn or its rebind func is called.
+type leases struct {
+	mu syncx.Mutex
+	// holders is the generation of the connection held by each reference, keyed by the ref id
+	holders map[string]uint64
+	// rebinders are called with the new connection once swapped, keyed by the ref id
+	rebinders map[string]func(modules.Connection)
+	// retired are the swapped out connections by the generation
+	retired map[uint64]staleConn
+}
+
+type staleConn struct {
+	conn   modules.Connection
+	cancel gocontext.CancelFunc
+}
+
+// Conn returns the current connection of the wrapper without waiting. The consumers caching the connection should
+// get it by Conn before each use, so that they move to the new connection once the connection is updated or
+// reconnected by the pool.
+func (cw *ConnWrapper) Conn(ctx api.StreamContext) (modules.Connection, error) {
+	cw.l.RLock()
+	conn, err, gen := cw.conn, cw.err, cw.gen
+	cw.l.RUnlock()
+	if err == nil && conn != nil {
+		cw.moveHolder(ctx, extractRefId(ctx), gen)
+	}
+	return conn, err
+}
+
+// OnRebind registers the func to call with the new connection once the connection of the wrapper is swapped, so that
+// the consumer holding a subscription can move it to the new connection. The previous connection is closed after the
+// func returns. The func is removed once the reference detaches.
+func (cw *ConnWrapper) OnRebind(ctx api.StreamContext, rebind func(conn modules.Connection)) {
+	refId := extractRefId(ctx)
+	cw.leases.mu.Lock()
+	defer cw.leases.mu.Unlock()
+	if cw.leases.rebinders == nil {
+		cw.leases.rebinders = make(map[string]func(modules.Connection))
+	}
+	cw.leases.rebinders[refId] = rebind
+}
+
+// hold records that the reference holds the current connection once it attaches
+func (cw *ConnWrapper) hold(refId string) {
+	cw.l.RLock()
+	gen := cw.gen
+	cw.l.RUnlock()
+	cw.leases.mu.Lock()
+	defer cw.leases.mu.Unlock()
+	if cw.leases.holders == nil {
+		cw.leases.holders = make(map[string]uint64)
+	}
+	if _, ok := cw.leases.holders[refId]; !ok {
+		cw.leases.holders[refId] = gen
+	}
+}
+
+// release removes the reference once it detaches and returns the retired connections no longer held
+func (cw *ConnWrapper) release(refId string) []staleConn {
+	cw.leases.mu.Lock()
+	defer cw.leases.mu.Unlock()
+	delete(cw.leases.rebinders, refId)
+	if _, ok := cw.leases.holders[refId]; !ok {
+		return nil
+	}
+	delete(cw.leases.holders, refId)
+	return cw.unheldLocked()
+}
+
+func (cw *ConnWrapper) moveHolder(ctx api.StreamContext, refId string, gen uint64) {
+	cw.leases.mu.Lock()
+	held, ok := cw.leases.holders[refId]
+	if !ok || held == gen {
+		cw.leases.mu.Unlock()
+		return
+	}
+	cw.leases.holders[refId] = gen
+	unheld := cw.unheldLocked()
+	cw.leases.mu.Unlock()
+	closeStale(ctx, cw.ID, unheld)
+}
+
+// retire keeps the swapped out connection of the generation until the references holding it move on. It returns the
+// rebind funcs to call with the new connection.
+func (cw *ConnWrapper) retire(gen uint64, old modules.Connection, oldCancel gocontext.CancelFunc) map[string]func(modules.Connection) {
+	cw.leases.mu.Lock()
+	defer cw.leases.mu.Unlock()
+	if cw.leases.retired == nil {
+		cw.leases.retired = make(map[uint64]staleConn)
+	}
+	cw.leases.retired[gen] = staleConn{conn: old, cancel: oldCancel}
+	rebinders := make(map[string]func(modules.Connection), len(cw.leases.rebinders))
+	for refId, f := range cw.leases.rebinders {
+		rebinders[refId] = f
+	}
+	return rebinders
+}
+
+// rebound moves the references to the generation after their rebind funcs return, and returns the retired connections
+// no longer held
+func (cw *ConnWrapper) rebound(refIds []string, gen uint64) []staleConn {
+	cw.leases.mu.Lock()
+	defer cw.leases.mu.Unlock()
+	for _, refId := range refIds {
+		if _, ok := cw.leases.holders[refId]; ok {
+			cw.leases.holders[refId] = gen
+		}
+	}
+	return cw.unheldLocked()
+}
+
+// unheldLocked removes and returns the retired connections not held by any reference. It must be called with the lock.
+func (cw *ConnWrapper) unheldLocked() []staleConn {
+	var result []staleConn
+	for gen, r := range cw.leases.retired {
+		held := false
+		for _, g := range cw.leases.holders {
+			if g == gen {
+				held = true
+				break
+			}
+		}
+		if !held {
+			result = append(result, r)
+			delete(cw.leases.retired, gen)
+		}
+	}
+	return result
+}
+
+// drainStale removes and returns all the retired connections once the wrapper is closed
+func (cw *ConnWrapper) drainStale() []staleConn {
+	cw.leases.mu.Lock()
+	defer cw.leases.mu.Unlock()
+	result := make([]staleConn, 0, len(cw.leases.retired))
+	for gen, r := range cw.leases.retired {
+		result = append(result, r)
+		delete(cw.leases.retired, gen)
+	}
+	return result
+}
+
+// closeStale closes the retired connections within the operation timeout
+func closeStale(ctx api.StreamContext, id string, conns []staleConn) {
+	for _, r := range conns {
+		if r.conn != nil {
+			opCtx, opCancel := withTimeout(ctx)
+			_ = safeCall(id, "close", func() error {
+				return r.conn.Close(opCtx)
+			})
+			opCancel()
+		}
+		if r.cancel != nil {
+			r.cancel()
+		}
+	}
+}
@@ -0,0 +1,177 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+)
+
+// The kinds of the inconsistencies between the store and the pool, which are left by the operations failed half-way
+const (
+	// OrphanStored is a stored connection which is not in the pool
+	OrphanStored = "stored"
+	// OrphanUnstored is a named connection in the pool which is not stored, so it is lost after restart
+	OrphanUnstored = "unstored"
+	// OrphanDuplicate is a stored connection whose id is in the pool as another type
+	OrphanDuplicate = "duplicate"
+	// OrphanTrashed is a trashed connection whose id is in the pool, such as a restore failed to clean the trash
+	OrphanTrashed = "trashed"
+)
+
+// The repair actions
+const (
+	RepairLoaded  = "loaded"
+	RepairStored  = "stored"
+	RepairRemoved = "removed"
+)
+
+type Orphan struct {
+	ID     string `json:"id"`
+	Typ    string `json:"typ"`
+	Kind   string `json:"kind"`
+	Reason string `json:"reason"`
+	// Repair is the action taken in the repair mode. It is empty if not repaired.
+	Repair string `json:"repair,omitempty"`
+	// Err is the error of the repair, or the reason why it can't be repaired
+	Err string `json:"error,omitempty"`
+}
+
+type OrphanReport struct {
+	Stored   int      `json:"stored"`
+	Live     int      `json:"live"`
+	Repaired bool     `json:"repaired"`
+	Orphans  []Orphan `json:"orphans"`
+}
+
+// ReconcileConnections compares the stored named connections with the pool and reports the orphans. In the repair
+// mode, the loadable stored connections are loaded, the live connections are stored again since they are in use,
+// and the stale duplicate and trashed entries are removed. The connections which can't be decrypted are only reported
+// because the secrets key may be wrong.
+func ReconcileConnections(repair bool) (*OrphanReport, error) {
+	s, err := getConnectionStore()
+	if err != nil {
+		return nil, err
+	}
+	globalConnectionManager.Lock()
+	defer globalConnectionManager.Unlock()
+	if globalConnectionManager.closed {
+		return nil, errPoolClosed
+	}
+	stored, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	trashed, err := loadTrash()
+	if err != nil {
+		return nil, err
+	}
+	r := &OrphanReport{Stored: len(stored), Repaired: repair, Orphans: []Orphan{}}
+	storedKeys := make(map[string]bool, len(stored))
+	for _, c := range stored {
+		storedKeys[connKey(c.Typ, c.ID)] = true
+		meta, ok := globalConnectionManager.connectionPool[c.ID]
+		switch {
+		case ok && meta.Typ == c.Typ:
+			continue
+		case ok:
+			o := Orphan{ID: c.ID, Typ: c.Typ, Kind: OrphanDuplicate, Reason: fmt.Sprintf("connection %s is live as type %s", c.ID, meta.Typ)}
+			if repair {
+				o.repaired(RepairRemoved, s.Delete(c.Typ, c.ID))
+			}
+			r.Orphans = append(r.Orphans, o)
+		default:
+			o := Orphan{ID: c.ID, Typ: c.Typ, Kind: OrphanStored, Reason: "not loaded in the pool"}
+			props, err := decryptSecrets(c.Props)
+			if err != nil {
+				o.Err = err.Error()
+			} else if repair {
+				loadStoredConnection(c.ID, c.Typ, props)
+				o.repaired(RepairLoaded, nil)
+			}
+			r.Orphans = append(r.Orphans, o)
+		}
+	}
+	for id, meta := range globalConnectionManager.connectionPool {
+		if !meta.Named {
+			continue
+		}
+		r.Live++
+		if storedKeys[connKey(meta.Typ, id)] {
+			continue
+		}
+		o := Orphan{ID: id, Typ: meta.Typ, Kind: OrphanUnstored, Reason: "not saved in the store and lost after restart"}
+		if repair {
+			o.repaired(RepairStored, storeConnectionMeta(meta.Typ, id, meta.Props))
+		}
+		r.Orphans = append(r.Orphans, o)
+	}
+	for _, t := range trashed {
+		if _, ok := globalConnectionManager.connectionPool[t.ID]; !ok {
+			continue
+		}
+		o := Orphan{ID: t.ID, Typ: t.Typ, Kind: OrphanTrashed, Reason: "the connection is live"}
+		if repair {
+			o.repaired(RepairRemoved, conf.DropCfgKeyFromStorage(trashCfgType, t.Typ, t.ID))
+		}
+		r.Orphans = append(r.Orphans, o)
+	}
+	sort.Slice(r.Orphans, func(i, j int) bool {
+		if r.Orphans[i].ID != r.Orphans[j].ID {
+			return r.Orphans[i].ID < r.Orphans[j].ID
+		}
+		return r.Orphans[i].Kind < r.Orphans[j].Kind
+	})
+	for _, o := range r.Orphans {
+		o.log()
+	}
+	return r, nil
+}
+
+func (o *Orphan) repaired(action string, err error) {
+	if err != nil {
+		o.Err = err.Error()
+		return
+	}
+	o.Repair = action
+}
+
+func (o *Orphan) log() {
+	switch {
+	case o.Repair != "":
+		conf.Log.Infof("orphan connection %s of type %s (%s: %s) is %s", o.ID, o.Typ, o.Kind, o.Reason, o.Repair)
+	case o.Err != "":
+		conf.Log.Warnf("orphan connection %s of type %s (%s: %s) can't be repaired: %s", o.ID, o.Typ, o.Kind, o.Reason, o.Err)
+	default:
+		conf.Log.Warnf("found orphan connection %s of type %s (%s: %s)", o.ID, o.Typ, o.Kind, o.Reason)
+	}
+}
+
+// loadStoredConnection adds the stored connection into the pool like the reload. It must be called with the lock.
+func loadStoredConnection(id, typ string, props map[string]any) {
+	meta := &Meta{
+		ID:    id,
+		Typ:   typ,
+		Props: props,
+		Named: true,
+	}
+	meta.newWrappers(func() *ConnWrapper {
+		return newNamedConnWrapper(globalConnectionManager.ctx, meta)
+	})
+	globalConnectionManager.put(id, meta)
+	emitReplica(putEvent(meta))
+}
@@ -0,0 +1,75 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/context"
+)
+
+func TestReconcileConnections(t *testing.T) {
+	require.NoError(t, InitConnectionManager4Test())
+	require.NoError(t, conf.ClearKVStorage())
+	ctx := context.Background()
+	_, err := CreateNamedConnection(ctx, "live1", "mock", map[string]any{"a": 1})
+	require.NoError(t, err)
+	_, err = CreateNamedConnection(ctx, "unstored1", "mock", nil)
+	require.NoError(t, err)
+	defer func() {
+		for _, id := range []string{"live1", "unstored1", "stored1"} {
+			_ = DropNameConnectionPermanently(ctx, id)
+		}
+	}()
+	require.NoError(t, dropConnectionStore("mock", "unstored1"))
+	require.NoError(t, storeConnectionMeta("mock", "stored1", map[string]any{"b": 1}))
+	require.NoError(t, storeConnectionMeta("other", "live1", map[string]any{}))
+	require.NoError(t, conf.WriteCfgIntoKVStorage(trashCfgType, "mock", "live1", map[string]any{"props": map[string]any{}}))
+
+	r, err := ReconcileConnections(false)
+	require.NoError(t, err)
+	require.Equal(t, 3, r.Stored)
+	require.Equal(t, 2, r.Live)
+	require.Equal(t, []Orphan{
+		{ID: "live1", Typ: "other", Kind: OrphanDuplicate, Reason: "connection live1 is live as type mock"},
+		{ID: "live1", Typ: "mock", Kind: OrphanTrashed, Reason: "the connection is live"},
+		{ID: "stored1", Typ: "mock", Kind: OrphanStored, Reason: "not loaded in the pool"},
+		{ID: "unstored1", Typ: "mock", Kind: OrphanUnstored, Reason: "not saved in the store and lost after restart"},
+	}, r.Orphans)
+	_, err = GetConnectionDetail(ctx, "stored1")
+	require.Error(t, err)
+
+	r, err = ReconcileConnections(true)
+	require.NoError(t, err)
+	require.True(t, r.Repaired)
+	repairs := make([]string, 0, len(r.Orphans))
+	for _, o := range r.Orphans {
+		require.Empty(t, o.Err)
+		repairs = append(repairs, o.Repair)
+	}
+	require.Equal(t, []string{RepairRemoved, RepairRemoved, RepairLoaded, RepairStored}, repairs)
+	meta, err := GetConnectionDetail(ctx, "stored1")
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"b": 1}, meta.Props)
+
+	r, err = ReconcileConnections(false)
+	require.NoError(t, err)
+	require.Empty(t, r.Orphans)
+	require.Equal(t, 3, r.Stored)
+	require.Equal(t, 3, r.Live)
+}
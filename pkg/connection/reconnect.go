@@ -0,0 +1,215 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+	"github.com/sirupsen/logrus"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+	"github.com/lf-edge/ekuiper/v2/pkg/syncx"
+)
+
+// The reconnect scheduler retries the broken named connections found by the health check when their backoff expires,
+// so the delay of each connection is honored regardless of the patrol interval. The attempts and the last failure are
+// persisted, so a connection which is misconfigured for good gives up after connection.reconnectMaxAttempts even
+// across restarts. The gave-up connection is retried again once it is updated or connected by other means.
+
+// reconnectCfgType is the config type of the reconnect status. It must not have the prefix "connections".
+const reconnectCfgType = "connReconnects"
+
+// maxReconnectWait bounds the sleep of the scheduler without due reconnections
+const maxReconnectWait = time.Minute
+
+// ReconnectStatus is the background reconnection of a broken named connection. The times are unix milliseconds.
+type ReconnectStatus struct {
+	Attempts    int    `json:"attempts"`
+	LastError   string `json:"lastError,omitempty"`
+	LastAttempt int64  `json:"lastAttempt,omitempty"`
+	NextAttempt int64  `json:"nextAttempt,omitempty"`
+	GaveUp      bool   `json:"gaveUp,omitempty"`
+}
+
+// reconnectStore serializes the writes of the persisted reconnect status, so that the status of a forgotten state is
+// not saved after it is dropped. It is acquired before the reconnects lock, and the store is not accessed with the
+// reconnects lock.
+var reconnectStore syncx.Mutex
+
+// reconnectWake notifies the scheduler that a reconnection is rescheduled
+var reconnectWake = make(chan struct{}, 1)
+
+func reconnectMaxAttempts() int {
+	if conf.Config == nil {
+		return 0
+	}
+	return conf.Config.Connection.ReconnectMaxAttempts
+}
+
+func loadReconnectStatus(id string) ReconnectStatus {
+	s := ReconnectStatus{}
+	cfgs, err := conf.GetCfgFromKVStorage(reconnectCfgType, id, "")
+	if err != nil {
+		connLogger(id).Warnf("load the reconnect status of connection %s error: %v", id, err)
+		return s
+	}
+	if v, ok := cfgs[fmt.Sprintf("%s.%s", reconnectCfgType, id)]; ok {
+		if err := cast.MapToStruct(v, &s); err != nil {
+			connLogger(id).Warnf("load the reconnect status of connection %s error: %v", id, err)
+		}
+	}
+	return s
+}
+
+// failed records the failed attempt and schedules the next one. It must be called with the reconnects lock, and the
+// returned status is persisted by saveReconnectStatus after the lock is released.
+func (st *reconnectState) failed(err error) ReconnectStatus {
+	id := st.meta.ID
+	now := getClock().Now()
+	st.status.Attempts++
+	st.status.LastError = err.Error()
+	st.status.LastAttempt = now.UnixMilli()
+	if limit := reconnectMaxAttempts(); limit > 0 && st.status.Attempts >= limit {
+		st.status.GaveUp = true
+		st.status.NextAttempt = 0
+		recordEvent(id, EventReconnectGaveUp, err.Error())
+		connLogger(id).Errorf("reconnect broken connection %s gave up after %d attempts: %v", id, st.status.Attempts, err)
+	} else {
+		d := st.b.NextBackOff()
+		st.next = now.Add(d)
+		st.status.NextAttempt = st.next.UnixMilli()
+		logConnError(st.meta, logrus.WarnLevel, "reconnect", st.status.Attempts, d, err)
+	}
+	select {
+	case reconnectWake <- struct{}{}:
+	default:
+	}
+	return st.status
+}
+
+// saveReconnectStatus persists the status of the reconnect state unless the state is forgotten in the meantime
+func saveReconnectStatus(st *reconnectState, s ReconnectStatus) {
+	id := st.meta.ID
+	reconnectStore.Lock()
+	defer reconnectStore.Unlock()
+	reconnects.Lock()
+	cur, ok := reconnects.states[id]
+	reconnects.Unlock()
+	if !ok || cur != st {
+		return
+	}
+	m := map[string]any{"attempts": s.Attempts, "lastError": s.LastError, "lastAttempt": s.LastAttempt}
+	if s.NextAttempt > 0 {
+		m["nextAttempt"] = s.NextAttempt
+	}
+	if s.GaveUp {
+		m["gaveUp"] = true
+	}
+	if err := conf.WriteCfgIntoKVStorage(reconnectCfgType, id, "", m); err != nil {
+		connLogger(id).Warnf("save the reconnect status of connection %s error: %v", id, err)
+	}
+}
+
+// dropReconnectStatus deletes the persisted status of the connection
+func dropReconnectStatus(id string) {
+	reconnectStore.Lock()
+	defer reconnectStore.Unlock()
+	if err := conf.DropCfgKeyFromStorage(reconnectCfgType, id, ""); err != nil {
+		connLogger(id).Warnf("drop the reconnect status of connection %s error: %v", id, err)
+	}
+}
+
+// forgetReconnect drops the reconnect state if it is still the state of its connection. It returns whether the
+// persisted status is to be dropped by dropReconnectStatus after the lock is released. It must be called with the
+// reconnects lock.
+func forgetReconnect(st *reconnectState) bool {
+	if cur, ok := reconnects.states[st.meta.ID]; !ok || cur != st {
+		return false
+	}
+	delete(reconnects.states, st.meta.ID)
+	return st.status.Attempts > 0
+}
+
+// resetReconnect forgets the reconnect state and the persisted status of the dropped or updated connection, so that
+// the connection created again with the same id does not inherit the attempts
+func resetReconnect(meta *Meta) {
+	reconnects.Lock()
+	if st, ok := reconnects.states[meta.ID]; ok && st.meta != meta {
+		// the state belongs to the connection replacing it
+		reconnects.Unlock()
+		return
+	}
+	delete(reconnects.states, meta.ID)
+	reconnects.Unlock()
+	dropReconnectStatus(meta.ID)
+}
+
+// GetReconnectStatus returns the background reconnection of the connection, or nil if it is not broken
+func GetReconnectStatus(id string) *ReconnectStatus {
+	reconnects.Lock()
+	defer reconnects.Unlock()
+	st, ok := reconnects.states[id]
+	if !ok || st.status.Attempts == 0 {
+		return nil
+	}
+	s := st.status
+	return &s
+}
+
+// scheduleReconnects starts the due reconnections and sleeps until the next one
+func scheduleReconnects(ctx context.Context) {
+	for {
+		d := startDueReconnects()
+		select {
+		case <-ctx.Done():
+			return
+		case <-reconnectWake:
+		case <-getClock().After(d):
+		}
+	}
+}
+
+// startDueReconnects starts the reconnections whose backoff expires and returns the wait until the next one
+func startDueReconnects() time.Duration {
+	wait := maxReconnectWait
+	if IsStandby() {
+		return wait
+	}
+	reconnects.Lock()
+	defer reconnects.Unlock()
+	now := getClock().Now()
+	for id, st := range reconnects.states {
+		if st.running || st.status.GaveUp {
+			continue
+		}
+		if cur, ok := st.meta.manager().load()[id]; !ok || cur != st.meta {
+			continue
+		}
+		if d := st.next.Sub(now); d > 0 {
+			if d < wait {
+				wait = d
+			}
+			continue
+		}
+		st.running = true
+		st.meta.NotifyStatus(api.ConnectionConnecting, "")
+		go reconnect(st.meta, st)
+	}
+	return wait
+}
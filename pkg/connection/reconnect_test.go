@@ -0,0 +1,156 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/context"
+	"github.com/lf-edge/ekuiper/v2/pkg/modules"
+)
+
+func TestReconnectGiveUp(t *testing.T) {
+	conf.InitConf()
+	require.NoError(t, InitConnectionManager4Test())
+	mock := clock.NewMock()
+	SetClock(mock)
+	defer SetClock(nil)
+	conf.Config.Connection.ReconnectMaxAttempts = 2
+	defer func() {
+		conf.Config.Connection.ReconnectMaxAttempts = 0
+	}()
+	modules.RegisterConnection("flakyconn", func(ctx api.StreamContext) modules.Connection {
+		return &flakyConnection{}
+	})
+	ctx := context.Background()
+	flakyDialFail.Store(true)
+	defer flakyDialFail.Store(false)
+	cw, err := CreateNamedConnection(ctx, "giveup1", "flakyconn", nil)
+	require.NoError(t, err)
+	defer func() {
+		_ = DropNameConnectionPermanently(ctx, "giveup1")
+	}()
+	_, err = cw.Wait(ctx)
+	require.Error(t, err)
+	meta, err := GetConnectionDetail(ctx, "giveup1")
+	require.NoError(t, err)
+	require.Nil(t, GetReconnectStatus("giveup1"))
+
+	idle := func() bool {
+		reconnects.Lock()
+		defer reconnects.Unlock()
+		st, ok := reconnects.states["giveup1"]
+		return ok && !st.running
+	}
+	checkHealth(meta, api.ConnectionDisconnected)
+	require.Eventually(t, idle, time.Second, 10*time.Millisecond)
+	s := GetReconnectStatus("giveup1")
+	require.NotNil(t, s)
+	require.Equal(t, 1, s.Attempts)
+	require.Equal(t, "dial failed", s.LastError)
+	require.False(t, s.GaveUp)
+	require.Greater(t, s.NextAttempt, s.LastAttempt)
+	// not due yet
+	require.Greater(t, startDueReconnects(), time.Duration(0))
+
+	// scheduled by the backoff and gave up
+	mock.Add(time.Minute)
+	startDueReconnects()
+	require.Eventually(t, func() bool {
+		s := GetReconnectStatus("giveup1")
+		return idle() && s != nil && s.Attempts == 2
+	}, time.Second, 10*time.Millisecond)
+	s = GetReconnectStatus("giveup1")
+	require.True(t, s.GaveUp)
+	require.Zero(t, s.NextAttempt)
+	mock.Add(time.Minute)
+	require.Equal(t, maxReconnectWait, startDueReconnects())
+	checkHealth(meta, api.ConnectionDisconnected)
+	require.True(t, idle())
+	require.Equal(t, 2, GetReconnectStatus("giveup1").Attempts)
+	// persisted
+	require.Eventually(t, func() bool {
+		return *s == loadReconnectStatus("giveup1")
+	}, time.Second, 10*time.Millisecond)
+
+	// retried once updated
+	flakyDialFail.Store(false)
+	_, err = UpdateNamedConnection(ctx, "giveup1", map[string]any{"a": 1})
+	require.NoError(t, err)
+	checkHealth(meta, api.ConnectionConnected)
+	require.Nil(t, GetReconnectStatus("giveup1"))
+	require.Equal(t, ReconnectStatus{}, loadReconnectStatus("giveup1"))
+}
+
+func TestReconnectResetOnDrop(t *testing.T) {
+	conf.InitConf()
+	require.NoError(t, InitConnectionManager4Test())
+	mock := clock.NewMock()
+	SetClock(mock)
+	defer SetClock(nil)
+	conf.Config.Connection.ReconnectMaxAttempts = 1
+	defer func() {
+		conf.Config.Connection.ReconnectMaxAttempts = 0
+	}()
+	modules.RegisterConnection("flakyconn", func(ctx api.StreamContext) modules.Connection {
+		return &flakyConnection{}
+	})
+	ctx := context.Background()
+	flakyDialFail.Store(true)
+	defer flakyDialFail.Store(false)
+	cw, err := CreateNamedConnection(ctx, "giveup2", "flakyconn", nil)
+	require.NoError(t, err)
+	// the health check only reconnects the connection failed to create
+	_, err = cw.Wait(ctx)
+	require.Error(t, err)
+	meta, err := GetConnectionDetail(ctx, "giveup2")
+	require.NoError(t, err)
+	checkHealth(meta, api.ConnectionDisconnected)
+	require.Eventually(t, func() bool {
+		return loadReconnectStatus("giveup2").GaveUp
+	}, time.Second, 10*time.Millisecond)
+
+	// the connection created again with the same id starts over
+	require.NoError(t, DropNameConnectionPermanently(ctx, "giveup2"))
+	require.Nil(t, GetReconnectStatus("giveup2"))
+	require.Equal(t, ReconnectStatus{}, loadReconnectStatus("giveup2"))
+	cw, err = CreateNamedConnection(ctx, "giveup2", "flakyconn", nil)
+	require.NoError(t, err)
+	defer func() {
+		_ = DropNameConnectionPermanently(ctx, "giveup2")
+	}()
+	_, err = cw.Wait(ctx)
+	require.Error(t, err)
+	renewed, err := GetConnectionDetail(ctx, "giveup2")
+	require.NoError(t, err)
+	// the state left by the dropped connection is not inherited
+	reconnects.Lock()
+	reconnects.states["giveup2"] = &reconnectState{meta: meta, status: ReconnectStatus{Attempts: 1, GaveUp: true}}
+	reconnects.Unlock()
+	checkHealth(renewed, api.ConnectionDisconnected)
+	require.Eventually(t, func() bool {
+		s := GetReconnectStatus("giveup2")
+		return s != nil && s.Attempts == 1 && s.GaveUp
+	}, time.Second, 10*time.Millisecond)
+	reconnects.Lock()
+	require.Same(t, renewed, reconnects.states["giveup2"].meta)
+	reconnects.Unlock()
+}
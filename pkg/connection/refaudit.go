@@ -0,0 +1,150 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+	"github.com/lf-edge/ekuiper/v2/pkg/syncx"
+)
+
+const (
+	RefAttach = "attach"
+	RefDetach = "detach"
+
+	// maxRefAuditRecords is the count of the latest records kept for each connection
+	maxRefAuditRecords = 100
+)
+
+// RefAuditRecord is an attach or detach of the connection
+type RefAuditRecord struct {
+	Action     string `json:"action"`
+	RefID      string `json:"refId"`
+	RuleID     string `json:"ruleId"`
+	OpID       string `json:"opId"`
+	InstanceID int    `json:"instanceId"`
+	// RefCount is the reference count after the action
+	RefCount int       `json:"refCount"`
+	At       time.Time `json:"at"`
+}
+
+// RefAudit is the reference audit trail of the connection
+type RefAudit struct {
+	ID       string `json:"id"`
+	Enabled  bool   `json:"enabled"`
+	RefCount int    `json:"refCount"`
+	// Records are the latest attaches and detaches in order
+	Records []RefAuditRecord `json:"records"`
+	// Anomalies are the negative reference counts and the references left by the stopped rules
+	Anomalies []string `json:"anomalies"`
+}
+
+// refAuditLog keeps the latest attaches and detaches of a connection in the audit mode
+type refAuditLog struct {
+	mu        syncx.Mutex
+	records   []RefAuditRecord
+	anomalies []string
+}
+
+func refAuditEnabled() bool {
+	return conf.Config != nil && conf.Config.Connection.RefAudit
+}
+
+// auditRef records the attach or detach of the rule component in the audit mode
+func (meta *Meta) auditRef(action string, a Attacher) {
+	if !refAuditEnabled() {
+		return
+	}
+	r := RefAuditRecord{
+		Action:     action,
+		RefID:      a.RefID,
+		RuleID:     a.RuleID,
+		OpID:       a.OpID,
+		InstanceID: a.InstanceID,
+		RefCount:   meta.GetRefCount(),
+		At:         getClock().Now(),
+	}
+	l := &meta.refAudit
+	l.mu.Lock()
+	if len(l.records) >= maxRefAuditRecords {
+		l.records = append(l.records[:0], l.records[1:]...)
+	}
+	l.records = append(l.records, r)
+	l.mu.Unlock()
+}
+
+// refAnomaly logs the anomaly of the reference count and records it in the audit mode
+func (meta *Meta) refAnomaly(msg string) {
+	connLogger(meta.ID).Warnf("connection %s reference anomaly: %s", meta.ID, msg)
+	if !refAuditEnabled() {
+		return
+	}
+	l := &meta.refAudit
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.anomalies) >= maxRefAuditRecords {
+		l.anomalies = append(l.anomalies[:0], l.anomalies[1:]...)
+	}
+	l.anomalies = append(l.anomalies, fmt.Sprintf("%s: %s", getClock().Now().Format(time.RFC3339), msg))
+}
+
+// GetConnectionRefAudit returns the reference audit trail of the connection
+func GetConnectionRefAudit(id string) (*RefAudit, error) {
+	meta, ok := globalConnectionManager.load()[id]
+	if !ok {
+		return nil, errorx.NewWithCode(errorx.NOT_FOUND, fmt.Sprintf("connection %s not existed", id))
+	}
+	l := &meta.refAudit
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return &RefAudit{
+		ID:        id,
+		Enabled:   refAuditEnabled(),
+		RefCount:  meta.GetRefCount(),
+		Records:   append(make([]RefAuditRecord, 0, len(l.records)), l.records...),
+		Anomalies: append(make([]string, 0, len(l.anomalies)), l.anomalies...),
+	}, nil
+}
+
+// CheckRuleReferences checks the references of the rule after it stops in the audit mode. The references attached
+// before the stop and still held after the operation timeout are reported as leaks.
+func CheckRuleReferences(ruleId string) {
+	if !refAuditEnabled() {
+		return
+	}
+	stoppedAt := getClock().Now()
+	getClock().AfterFunc(time.Duration(GetTuning().OperationTimeout), func() {
+		checkRuleReferences(ruleId, stoppedAt)
+	})
+}
+
+// checkRuleReferences returns the connections still referenced by the rule since before the time
+func checkRuleReferences(ruleId string, before time.Time) []string {
+	var leaked []string
+	for id, meta := range globalConnectionManager.load() {
+		for _, a := range meta.Attachers() {
+			if a.RuleID == ruleId && !a.AttachedAt.After(before) {
+				meta.refAnomaly(fmt.Sprintf("reference %s is not released after rule %s stops", a.RefID, ruleId))
+				leaked = append(leaked, id)
+			}
+		}
+	}
+	sort.Strings(leaked)
+	return leaked
+}
@@ -0,0 +1,84 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	mockContext "github.com/lf-edge/ekuiper/v2/pkg/mock/context"
+)
+
+func TestConnectionRefAudit(t *testing.T) {
+	require.NoError(t, InitConnectionManager4Test())
+	conf.InitConf()
+	conf.Config.Connection.RefAudit = true
+	defer func() {
+		conf.Config.Connection.RefAudit = false
+	}()
+	ctx := mockContext.NewMockContext("audit", "create")
+	_, err := CreateNamedConnection(ctx, "audit1", "mock", nil)
+	require.NoError(t, err)
+	defer func() {
+		_ = DropNameConnectionPermanently(ctx, "audit1")
+	}()
+	_, err = GetConnectionRefAudit("nonexist")
+	require.Error(t, err)
+
+	props := map[string]any{"connectionSelector": "audit1"}
+	ctx1 := mockContext.NewMockContext("rule1", "op1")
+	_, err = FetchConnection(ctx1, "ref1", "mock", props, nil)
+	require.NoError(t, err)
+	require.NoError(t, DetachConnection(ctx1, "audit1"))
+	// detach twice
+	require.NoError(t, DetachConnection(ctx1, "audit1"))
+
+	a, err := GetConnectionRefAudit("audit1")
+	require.NoError(t, err)
+	require.True(t, a.Enabled)
+	require.Equal(t, -1, a.RefCount)
+	require.Len(t, a.Records, 3)
+	require.Equal(t, RefAttach, a.Records[0].Action)
+	require.Equal(t, "rule1", a.Records[0].RuleID)
+	require.Equal(t, "op1", a.Records[0].OpID)
+	require.Equal(t, 1, a.Records[0].RefCount)
+	require.Equal(t, RefDetach, a.Records[1].Action)
+	require.Equal(t, "rule1", a.Records[1].RuleID)
+	require.Equal(t, 0, a.Records[1].RefCount)
+	require.Equal(t, -1, a.Records[2].RefCount)
+	require.Len(t, a.Anomalies, 1)
+	require.Contains(t, a.Anomalies[0], "ref count is -1")
+
+	// the reference left by the stopped rule
+	ctx2 := mockContext.NewMockContext("rule2", "op1")
+	_, err = FetchConnection(ctx2, "ref2", "mock", props, nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"audit1"}, checkRuleReferences("rule2", getClock().Now()))
+	require.Empty(t, checkRuleReferences("rule1", getClock().Now()))
+	a, err = GetConnectionRefAudit("audit1")
+	require.NoError(t, err)
+	require.Len(t, a.Anomalies, 2)
+	require.Contains(t, a.Anomalies[1], "is not released after rule rule2 stops")
+
+	// not recorded if disabled
+	conf.Config.Connection.RefAudit = false
+	require.NoError(t, DetachConnection(ctx2, "audit1"))
+	a, err = GetConnectionRefAudit("audit1")
+	require.NoError(t, err)
+	require.False(t, a.Enabled)
+	require.Len(t, a.Records, 4)
+}
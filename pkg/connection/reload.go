@@ -0,0 +1,90 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/pkg/model"
+)
+
+func init() {
+	conf.RegisterReloader("connection", reloadConfig)
+}
+
+// reloadConfig applies the changed connection settings of the reloaded config file to the running pool. The new
+// settings apply to the next dial and health check. The leader election and replication require restart.
+func reloadConfig(old, c *model.KuiperConf) (applied, restart []string, err error) {
+	var resetGuards, resetTuning bool
+	for _, f := range conf.ChangedFields("connection", old.Connection, c.Connection) {
+		switch f {
+		case "connection.backoffMaxElapsedDuration":
+			old.Connection.BackoffMaxElapsedDuration = c.Connection.BackoffMaxElapsedDuration
+			resetTuning = true
+		case "connection.operationTimeout":
+			old.Connection.OperationTimeout = c.Connection.OperationTimeout
+			resetTuning = true
+		case "connection.retry":
+			old.Connection.Retry = c.Connection.Retry
+		case "connection.typeRetry":
+			old.Connection.TypeRetry = c.Connection.TypeRetry
+		case "connection.reconnectMaxAttempts":
+			old.Connection.ReconnectMaxAttempts = c.Connection.ReconnectMaxAttempts
+		case "connection.retryBudget":
+			old.Connection.RetryBudget = c.Connection.RetryBudget
+			resetGuards = true
+		case "connection.circuitBreaker":
+			old.Connection.CircuitBreaker = c.Connection.CircuitBreaker
+			resetGuards = true
+		case "connection.tenants":
+			old.Connection.Tenants = c.Connection.Tenants
+			resetGuards = true
+		case "connection.resourceLimits":
+			old.Connection.ResourceLimits = c.Connection.ResourceLimits
+		case "connection.maxConnections":
+			old.Connection.MaxConnections = c.Connection.MaxConnections
+		case "connection.typeQuotas":
+			old.Connection.TypeQuotas = c.Connection.TypeQuotas
+		case "connection.trashTTL":
+			old.Connection.TrashTTL = c.Connection.TrashTTL
+		case "connection.errorLogInterval":
+			old.Connection.ErrorLogInterval = c.Connection.ErrorLogInterval
+		case "connection.dedupAnonymous":
+			old.Connection.DedupAnonymous = c.Connection.DedupAnonymous
+		case "connection.refAudit":
+			old.Connection.RefAudit = c.Connection.RefAudit
+		case "connection.eventHistorySize":
+			old.Connection.EventHistorySize = c.Connection.EventHistorySize
+		case "connection.readiness":
+			old.Connection.Readiness = c.Connection.Readiness
+		default:
+			restart = append(restart, f)
+			continue
+		}
+		applied = append(applied, f)
+	}
+	if resetGuards {
+		initRetryGuard()
+		initTenants()
+	}
+	if resetTuning {
+		// the persisted tuning still takes precedence like the startup
+		initTuning()
+		select {
+		case patrolReset <- struct{}{}:
+		default:
+		}
+	}
+	return applied, restart, nil
+}
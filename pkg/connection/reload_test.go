@@ -0,0 +1,63 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+	"github.com/lf-edge/ekuiper/v2/pkg/model"
+)
+
+func TestReloadConfig(t *testing.T) {
+	conf.InitConf()
+	origin := conf.Config.Connection
+	defer func() {
+		conf.Config.Connection = origin
+		initRetryGuard()
+		initTenants()
+	}()
+	c := *conf.Config
+	c.Connection.RetryBudget = 2
+	c.Connection.Tenants = map[string]model.TenantConf{"a": {MaxConnections: 1}}
+	c.Connection.LeaderElection.Enable = !origin.LeaderElection.Enable
+	applied, restart, err := reloadConfig(conf.Config, &c)
+	require.NoError(t, err)
+	require.Equal(t, []string{"connection.retryBudget", "connection.tenants"}, applied)
+	require.Equal(t, []string{"connection.leaderElection"}, restart)
+	require.Equal(t, 2, conf.Config.Connection.RetryBudget)
+	require.Equal(t, origin.LeaderElection.Enable, conf.Config.Connection.LeaderElection.Enable)
+	require.Equal(t, 2, cap(globalRetryGuard.Load().sem))
+	require.Equal(t, "a", tenantOf("a_1").name)
+}
+
+func TestReloadBackoffMaxElapsed(t *testing.T) {
+	conf.InitConf()
+	origin := conf.Config.Connection
+	defer func() {
+		conf.Config.Connection = origin
+		initTuning()
+	}()
+	c := *conf.Config
+	c.Connection.BackoffMaxElapsedDuration = cast.DurationConf(time.Hour)
+	applied, _, err := reloadConfig(conf.Config, &c)
+	require.NoError(t, err)
+	require.Equal(t, []string{"connection.backoffMaxElapsedDuration"}, applied)
+	require.Equal(t, cast.DurationConf(time.Hour), GetTuning().MaxElapsedTime)
+}
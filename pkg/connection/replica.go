@@ -0,0 +1,271 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	topoContext "github.com/lf-edge/ekuiper/v2/internal/topo/context"
+	"github.com/lf-edge/ekuiper/v2/pkg/syncx"
+)
+
+// The replica events are the named connection changes streamed from the active node to the standby nodes, so that
+// the standby keeps the same connections and status to take over without reloading. The secret props are never
+// streamed in plain text. They are encrypted if the encryption is enabled, otherwise hidden and restored by the
+// standby from the config store shared with the active node.
+
+const (
+	ReplicaPut    = "put"
+	ReplicaDelete = "delete"
+	ReplicaStatus = "status"
+)
+
+type ReplicaEvent struct {
+	Type      string         `json:"type"`
+	ID        string         `json:"id"`
+	Typ       string         `json:"typ,omitempty"`
+	Props     map[string]any `json:"props,omitempty"`
+	Status    string         `json:"status,omitempty"`
+	LastError string         `json:"lastError,omitempty"`
+}
+
+type replicaHub struct {
+	mu   syncx.Mutex
+	next int
+	subs map[int]chan ReplicaEvent
+}
+
+var replicas = &replicaHub{subs: make(map[int]chan ReplicaEvent)}
+
+// SubscribeReplica returns the snapshot of the named connections and the channel of the following changes. The
+// channel is closed if the subscriber is too slow to consume, then it should subscribe again to get a new snapshot.
+func SubscribeReplica(buffer int) ([]ReplicaEvent, <-chan ReplicaEvent, func()) {
+	return globalConnectionManager.SubscribeReplica(buffer)
+}
+
+// SubscribeReplica returns the snapshot of the named connections of the pool and the channel of the following changes
+func (m *ConnectionManager) SubscribeReplica(buffer int) ([]ReplicaEvent, <-chan ReplicaEvent, func()) {
+	// hold the pool lock so that no change is missed between the snapshot and the subscription
+	m.RLock()
+	defer m.RUnlock()
+	snapshot := make([]ReplicaEvent, 0, len(m.connectionPool))
+	for _, meta := range m.connectionPool {
+		if meta.Named {
+			snapshot = append(snapshot, putEvent(meta))
+		}
+	}
+	ch := make(chan ReplicaEvent, buffer)
+	replicas.mu.Lock()
+	id := replicas.next
+	replicas.next++
+	replicas.subs[id] = ch
+	replicas.mu.Unlock()
+	return snapshot, ch, func() {
+		replicas.mu.Lock()
+		defer replicas.mu.Unlock()
+		if c, ok := replicas.subs[id]; ok {
+			delete(replicas.subs, id)
+			close(c)
+		}
+	}
+}
+
+func emitReplica(ev ReplicaEvent) {
+	replicas.mu.Lock()
+	defer replicas.mu.Unlock()
+	for id, ch := range replicas.subs {
+		select {
+		case ch <- ev:
+		default:
+			conf.Log.Warnf("connection replica subscriber %d is too slow, drop it", id)
+			delete(replicas.subs, id)
+			close(ch)
+		}
+	}
+}
+
+func putEvent(meta *Meta) ReplicaEvent {
+	s, e := meta.status.Load(), meta.lastError.Load()
+	ev := ReplicaEvent{Type: ReplicaPut, ID: meta.ID, Typ: meta.Typ, Props: replicaProps(meta.ID, meta.Props)}
+	ev.Status, _ = s.(string)
+	ev.LastError, _ = e.(string)
+	return ev
+}
+
+// replicaProps protects the secret props to stream
+func replicaProps(id string, props map[string]any) map[string]any {
+	if !secretsEncrypted() {
+		return MaskSecrets(props)
+	}
+	sealed, err := encryptSecrets(props)
+	if err != nil {
+		connLogger(id).Warnf("encrypt the replicated connection %s failed, hide its secrets: %v", id, err)
+		return MaskSecrets(props)
+	}
+	return sealed
+}
+
+// fromReplicaProps recovers the secret props of the replica event. The hidden ones are restored from the config store
+// written by the active node, or the current connection if it is not stored yet.
+func fromReplicaProps(ev ReplicaEvent, cur *Meta) (map[string]any, error) {
+	props, err := decryptSecrets(ev.Props)
+	if err != nil {
+		return nil, err
+	}
+	current, err := storedProps(ev.Typ, ev.ID)
+	if err != nil {
+		return nil, err
+	}
+	if current == nil && cur != nil && cur.Typ == ev.Typ {
+		current = cur.Props
+	}
+	return restoreSecrets(props, current), nil
+}
+
+// storedProps returns the props of the named connection in the config store
+func storedProps(typ, id string) (map[string]any, error) {
+	s, err := getConnectionStore()
+	if err != nil {
+		return nil, err
+	}
+	stored, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, sc := range stored {
+		if sc.ID == id && sc.Typ == typ {
+			return decryptSecrets(sc.Props)
+		}
+	}
+	return nil, nil
+}
+
+// sameProps compares the props by their JSON form, since the numbers of the props received in JSON are all float64
+func sameProps(a, b map[string]any) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return len(a) == len(b)
+	}
+	ja, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	jb, err := json.Marshal(b)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(ja, jb)
+}
+
+// ApplyReplicaSnapshot replaces the named connections with the snapshot from the active node. The connections absent
+// in the snapshot are dropped unless they are referenced by rules.
+func ApplyReplicaSnapshot(events []ReplicaEvent) {
+	globalConnectionManager.ApplyReplicaSnapshot(events)
+}
+
+// ApplyReplicaSnapshot replaces the named connections of the pool with the snapshot from the active node
+func (m *ConnectionManager) ApplyReplicaSnapshot(events []ReplicaEvent) {
+	m.Lock()
+	defer m.Unlock()
+	ids := make(map[string]struct{}, len(events))
+	for _, ev := range events {
+		ids[ev.ID] = struct{}{}
+		m.applyReplica(ev)
+	}
+	for id, meta := range m.connectionPool {
+		if _, ok := ids[id]; !ok && meta.Named {
+			m.applyReplica(ReplicaEvent{Type: ReplicaDelete, ID: id})
+		}
+	}
+}
+
+// ApplyReplica applies a named connection change from the active node
+func ApplyReplica(ev ReplicaEvent) {
+	globalConnectionManager.ApplyReplica(ev)
+}
+
+// ApplyReplica applies a named connection change from the active node to the pool
+func (m *ConnectionManager) ApplyReplica(ev ReplicaEvent) {
+	m.Lock()
+	defer m.Unlock()
+	m.applyReplica(ev)
+}
+
+func (m *ConnectionManager) applyReplica(ev ReplicaEvent) {
+	if m.closed {
+		return
+	}
+	meta, ok := m.connectionPool[ev.ID]
+	if ok && !meta.Named {
+		return
+	}
+	switch ev.Type {
+	case ReplicaStatus:
+		if ok {
+			applyReplicaStatus(meta, ev)
+		}
+		return
+	case ReplicaPut:
+		props, err := fromReplicaProps(ev, meta)
+		if err != nil {
+			connLogger(ev.ID).Warnf("connection %s is changed by the active node but its secrets can't be recovered: %v", ev.ID, err)
+			return
+		}
+		ev.Props = props
+		if ok && meta.Typ == ev.Typ && sameProps(meta.Props, ev.Props) {
+			applyReplicaStatus(meta, ev)
+			return
+		}
+	case ReplicaDelete:
+	default:
+		return
+	}
+	if ok {
+		if meta.GetRefCount() > 0 {
+			connLogger(ev.ID).Warnf("connection %s is changed by the active node but can't be applied due to rule references %v", ev.ID, meta.GetRefNames())
+			return
+		}
+		m.retire(topoContext.Background(), meta)
+		if ev.Type == ReplicaDelete {
+			m.evict(ev.ID, EvictDropped)
+		} else {
+			m.remove(ev.ID)
+		}
+	}
+	if ev.Type == ReplicaDelete {
+		return
+	}
+	meta = &Meta{
+		ID:    ev.ID,
+		Typ:   ev.Typ,
+		Props: ev.Props,
+		Named: true,
+	}
+	meta.newWrappers(func() *ConnWrapper {
+		return newNamedConnWrapper(m.ctx, meta)
+	})
+	m.put(ev.ID, meta)
+	applyReplicaStatus(meta, ev)
+}
+
+// applyReplicaStatus shows the status of the active node on the standby node
+func applyReplicaStatus(meta *Meta, ev ReplicaEvent) {
+	if !IsStandby() || ev.Status == "" {
+		return
+	}
+	meta.status.Store(ev.Status)
+	meta.lastError.Store(ev.LastError)
+}
@@ -0,0 +1,129 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/topo/context"
+)
+
+func TestSubscribeReplica(t *testing.T) {
+	require.NoError(t, InitConnectionManager4Test())
+	ctx := context.Background()
+	_, err := CreateNamedConnection(ctx, "rep1", "mock", map[string]any{"a": 1})
+	require.NoError(t, err)
+	snapshot, ch, cancel := SubscribeReplica(16)
+	defer cancel()
+	require.Len(t, snapshot, 1)
+	require.Equal(t, "rep1", snapshot[0].ID)
+	require.Equal(t, ReplicaPut, snapshot[0].Type)
+
+	_, err = CreateNamedConnection(ctx, "rep2", "mock", nil)
+	require.NoError(t, err)
+	ev := nextReplica(t, ch, ReplicaPut)
+	require.Equal(t, "rep2", ev.ID)
+	require.NoError(t, DropNameConnection(ctx, "rep2"))
+	ev = nextReplica(t, ch, ReplicaDelete)
+	require.Equal(t, "rep2", ev.ID)
+	require.NoError(t, DropNameConnection(ctx, "rep1"))
+}
+
+func TestApplyReplica(t *testing.T) {
+	require.NoError(t, InitConnectionManager4Test())
+	standby.Store(true)
+	defer standby.Store(false)
+	ctx := context.Background()
+	ApplyReplica(ReplicaEvent{Type: ReplicaPut, ID: "stale", Typ: "mock"})
+	ApplyReplicaSnapshot([]ReplicaEvent{
+		{Type: ReplicaPut, ID: "r1", Typ: "mock", Props: map[string]any{"a": 1}, Status: api.ConnectionConnected},
+		{Type: ReplicaPut, ID: "r2", Typ: "mock"},
+	})
+	metas := GetAllConnectionsMeta(false)
+	require.Len(t, metas, 2)
+	m, err := GetConnectionDetail(ctx, "r1")
+	require.NoError(t, err)
+	s, _ := m.GetStatus()
+	require.Equal(t, api.ConnectionConnected, s)
+	// standby node does not connect
+	_, err = m.cw.Wait(ctx)
+	require.ErrorIs(t, err, errStandby)
+
+	ApplyReplica(ReplicaEvent{Type: ReplicaStatus, ID: "r1", Status: api.ConnectionDisconnected, LastError: "broken"})
+	s, e := m.GetStatus()
+	require.Equal(t, api.ConnectionDisconnected, s)
+	require.Equal(t, "broken", e)
+	ApplyReplica(ReplicaEvent{Type: ReplicaDelete, ID: "r2"})
+	_, err = GetConnectionDetail(ctx, "r2")
+	require.Error(t, err)
+
+	// takeover connects the replicated connections without reloading
+	setLeader(true)
+	require.Eventually(t, func() bool {
+		conn, err := m.cw.Conn(ctx)
+		return err == nil && conn != nil
+	}, time.Second, 10*time.Millisecond)
+	ApplyReplica(ReplicaEvent{Type: ReplicaDelete, ID: "r1"})
+}
+
+func nextReplica(t *testing.T, ch <-chan ReplicaEvent, typ string) ReplicaEvent {
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case ev := <-ch:
+			if ev.Type == typ {
+				return ev
+			}
+		case <-timeout:
+			require.Fail(t, "no replica event of "+typ)
+			return ReplicaEvent{}
+		}
+	}
+}
+
+func TestApplyReplicaSecrets(t *testing.T) {
+	require.NoError(t, InitConnectionManager4Test())
+	standby.Store(true)
+	defer standby.Store(false)
+	ctx := context.Background()
+	_, err := CreateNamedConnection(ctx, "rs1", "mock", map[string]any{"port": 1883, "password": "secret"})
+	require.NoError(t, err)
+	defer func() {
+		_ = DropNameConnection(ctx, "rs1")
+	}()
+	snapshot, _, cancel := SubscribeReplica(16)
+	cancel()
+	require.Len(t, snapshot, 1)
+	require.Equal(t, HiddenSecret, snapshot[0].Props["password"])
+	m, err := GetConnectionDetail(ctx, "rs1")
+	require.NoError(t, err)
+
+	// the same props received in JSON do not recreate the connection
+	ApplyReplica(ReplicaEvent{Type: ReplicaPut, ID: "rs1", Typ: "mock", Props: map[string]any{"port": 1883.0, "password": HiddenSecret}})
+	cur, err := GetConnectionDetail(ctx, "rs1")
+	require.NoError(t, err)
+	require.Same(t, m, cur)
+
+	// the hidden secret is restored when changed
+	ApplyReplica(ReplicaEvent{Type: ReplicaPut, ID: "rs1", Typ: "mock", Props: map[string]any{"port": 1884.0, "password": HiddenSecret}})
+	cur, err = GetConnectionDetail(ctx, "rs1")
+	require.NoError(t, err)
+	require.NotSame(t, m, cur)
+	require.Equal(t, "secret", cur.Props["password"])
+}
@@ -0,0 +1,88 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"fmt"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/context"
+	"github.com/lf-edge/ekuiper/v2/pkg/modules"
+)
+
+// Resources returns the resources attributable to the connection. The goroutines started by the pool are counted
+// by the meta, and the others are reported by each physical connection if it implements the modules.ResourceReporter.
+func (meta *Meta) Resources() modules.ResourceUsage {
+	usage := modules.ResourceUsage{Goroutines: meta.goroutines.Load()}
+	m := meta.manager()
+	m.RLock()
+	var cws []*ConnWrapper
+	if meta.cw != nil {
+		cws = meta.wrappers()
+	}
+	m.RUnlock()
+	for _, cw := range cws {
+		cw.l.RLock()
+		conn, err := cw.conn, cw.err
+		cw.l.RUnlock()
+		if err != nil || conn == nil {
+			continue
+		}
+		if r, ok := conn.(modules.ResourceReporter); ok {
+			ru := r.ResourceUsage()
+			usage.Goroutines += ru.Goroutines
+			usage.BufferedBytes += ru.BufferedBytes
+			usage.OpenFiles += ru.OpenFiles
+		}
+	}
+	return usage
+}
+
+// exceedResourceLimits returns the description of the exceeded limit, or empty if within the limits
+func exceedResourceLimits(usage modules.ResourceUsage) string {
+	if conf.Config == nil {
+		return ""
+	}
+	l := conf.Config.Connection.ResourceLimits
+	switch {
+	case l.MaxGoroutines > 0 && usage.Goroutines > l.MaxGoroutines:
+		return fmt.Sprintf("goroutines %d exceeds the limit %d", usage.Goroutines, l.MaxGoroutines)
+	case l.MaxBufferedBytes > 0 && usage.BufferedBytes > l.MaxBufferedBytes:
+		return fmt.Sprintf("buffered bytes %d exceeds the limit %d", usage.BufferedBytes, l.MaxBufferedBytes)
+	case l.MaxOpenFiles > 0 && usage.OpenFiles > l.MaxOpenFiles:
+		return fmt.Sprintf("open files %d exceeds the limit %d", usage.OpenFiles, l.MaxOpenFiles)
+	}
+	return ""
+}
+
+// enforceResourceLimits force closes the runaway connections which exceed the resource limits. The closed
+// connection stays in the pool as disconnected until it is updated or its rules restart.
+func (m *ConnectionManager) enforceResourceLimits() {
+	for _, meta := range m.load() {
+		if meta.forceClosed.Load() {
+			continue
+		}
+		reason := exceedResourceLimits(meta.Resources())
+		if reason == "" || !meta.forceClosed.CompareAndSwap(false, true) {
+			continue
+		}
+		connLogger(meta.ID).Warnf("force close connection %s: %s", meta.ID, reason)
+		ConnEvictions.WithLabelValues(meta.Typ, EvictForceClosed).Inc()
+		closeConnection(context.Background(), meta)
+		meta.NotifyStatus(api.ConnectionDisconnected, "force closed: "+reason)
+	}
+}
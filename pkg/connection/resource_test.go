@@ -0,0 +1,79 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"sync/atomic"
+	"testing"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/context"
+	"github.com/lf-edge/ekuiper/v2/pkg/modules"
+)
+
+type resourceConnection struct {
+	mockConnection
+	buffered atomic.Int64
+	closed   atomic.Bool
+}
+
+func (r *resourceConnection) Close(_ api.StreamContext) error {
+	r.closed.Store(true)
+	return nil
+}
+
+func (r *resourceConnection) ResourceUsage() modules.ResourceUsage {
+	return modules.ResourceUsage{Goroutines: 2, BufferedBytes: r.buffered.Load(), OpenFiles: 1}
+}
+
+func TestResourceLimits(t *testing.T) {
+	conf.InitConf()
+	origin := conf.Config.Connection.ResourceLimits
+	defer func() {
+		conf.Config.Connection.ResourceLimits = origin
+	}()
+	rc := &resourceConnection{}
+	modules.RegisterConnection("resconn", func(ctx api.StreamContext) modules.Connection {
+		return rc
+	})
+	defer modules.UnregisterConnection("resconn")
+	require.NoError(t, InitConnectionManager4Test())
+	ctx := context.Background()
+	cw, err := CreateNamedConnection(ctx, "res1", "resconn", map[string]any{})
+	require.NoError(t, err)
+	_, err = cw.Wait(ctx)
+	require.NoError(t, err)
+	defer DropNameConnection(ctx, "res1")
+
+	meta, err := GetConnectionDetail(ctx, "res1")
+	require.NoError(t, err)
+	rc.buffered.Store(100)
+	require.Equal(t, modules.ResourceUsage{Goroutines: 2, BufferedBytes: 100, OpenFiles: 1}, meta.Resources())
+
+	// within the limits
+	conf.Config.Connection.ResourceLimits.MaxBufferedBytes = 1000
+	globalConnectionManager.enforceResourceLimits()
+	require.False(t, rc.closed.Load())
+
+	rc.buffered.Store(2000)
+	globalConnectionManager.enforceResourceLimits()
+	require.True(t, rc.closed.Load())
+	s, e := meta.GetStatus()
+	require.Equal(t, api.ConnectionDisconnected, s)
+	require.Equal(t, "force closed: buffered bytes 2000 exceeds the limit 1000", e)
+}
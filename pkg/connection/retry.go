@@ -0,0 +1,315 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+	"github.com/lf-edge/ekuiper/v2/pkg/model"
+)
+
+const (
+	RetryExponential        = "exponential"
+	RetryConstant           = "constant"
+	RetryFibonacci          = "fibonacci"
+	RetryDecorrelatedJitter = "decorrelatedJitter"
+
+	// RetryPropKey is the reserved prop of a connection to override the retry config of its type, for example
+	// {"$retry": {"policy": "constant", "interval": "1s", "maxRetries": 10}}. It is not passed to the connection.
+	RetryPropKey = "$retry"
+)
+
+// RetryPolicy creates the backoff to retry the connection dial. A new backoff is created for each dial.
+type RetryPolicy interface {
+	NewBackOff() backoff.BackOff
+}
+
+// RetryPolicyBuilder builds the retry policy from the config. Zero intervals mean default.
+type RetryPolicyBuilder func(c model.RetryConf) RetryPolicy
+
+var (
+	retryPolicies = map[string]RetryPolicyBuilder{
+		RetryExponential: func(c model.RetryConf) RetryPolicy {
+			t := GetTuning()
+			p := &exponentialPolicy{
+				initial:    intervalOr(c.Interval, t.InitialInterval),
+				max:        intervalOr(c.MaxInterval, t.MaxInterval),
+				multiplier: backoff.DefaultMultiplier,
+				jitter:     backoff.DefaultRandomizationFactor,
+			}
+			if c.Multiplier >= 1 {
+				p.multiplier = c.Multiplier
+			}
+			if c.Jitter != nil && *c.Jitter >= 0 && *c.Jitter <= 1 {
+				p.jitter = *c.Jitter
+			}
+			return p
+		},
+		RetryConstant: func(c model.RetryConf) RetryPolicy {
+			return &constantPolicy{interval: intervalOr(c.Interval, GetTuning().InitialInterval)}
+		},
+		RetryFibonacci: func(c model.RetryConf) RetryPolicy {
+			t := GetTuning()
+			return &fibonacciPolicy{initial: intervalOr(c.Interval, t.InitialInterval), max: intervalOr(c.MaxInterval, t.MaxInterval)}
+		},
+		RetryDecorrelatedJitter: func(c model.RetryConf) RetryPolicy {
+			t := GetTuning()
+			return &jitterPolicy{base: intervalOr(c.Interval, t.InitialInterval), max: intervalOr(c.MaxInterval, t.MaxInterval)}
+		},
+	}
+	retryPoliciesLock sync.RWMutex
+)
+
+// RegisterRetryPolicy registers a custom retry policy which can be selected by name in the config
+func RegisterRetryPolicy(name string, builder RetryPolicyBuilder) {
+	retryPoliciesLock.Lock()
+	defer retryPoliciesLock.Unlock()
+	retryPolicies[name] = builder
+}
+
+// retryTimer creates the timer to wait between the dial retries. Nil means the real timer.
+var retryTimer atomic.Pointer[func() backoff.Timer]
+
+// SetRetryTimer replaces the timer to wait between the dial retries, for example by a manual clock in the tests to
+// retry deterministically. Nil restores the real timer.
+func SetRetryTimer(newTimer func() backoff.Timer) {
+	if newTimer == nil {
+		retryTimer.Store(nil)
+		return
+	}
+	retryTimer.Store(&newTimer)
+}
+
+func newRetryTimer() backoff.Timer {
+	if f := retryTimer.Load(); f != nil {
+		return (*f)()
+	}
+	if c := poolClock.Load(); c != nil {
+		return &clockTimer{c: *c}
+	}
+	return nil
+}
+
+// GetRetryPolicy returns the retry policy of the connection type. The type specific config overrides the default one.
+// Unknown policy falls back to exponential backoff.
+func GetRetryPolicy(typ string) RetryPolicy {
+	return NewRetryPolicy(typeRetryConf(typ))
+}
+
+func typeRetryConf(typ string) model.RetryConf {
+	var c model.RetryConf
+	if conf.Config != nil {
+		c = conf.Config.Connection.Retry
+		if tc, ok := conf.Config.Connection.TypeRetry[strings.ToLower(typ)]; ok {
+			c = tc
+		}
+	}
+	return c
+}
+
+// connRetryConf returns the retry config of the connection, which is the config of its type overridden by the
+// reserved retry prop. The config of its type is returned along with the error if the retry prop is invalid.
+func connRetryConf(typ string, props map[string]any) (model.RetryConf, error) {
+	tc := typeRetryConf(typ)
+	v, ok := props[RetryPropKey]
+	if !ok {
+		return tc, nil
+	}
+	c := tc
+	// do not change the shared config by the pointer
+	if c.Jitter != nil {
+		j := *c.Jitter
+		c.Jitter = &j
+	}
+	var err error
+	if err = cast.MapToStruct(v, &c); err != nil {
+		return tc, fmt.Errorf("invalid %s: %v", RetryPropKey, err)
+	}
+	switch {
+	case c.Interval < 0 || c.MaxInterval < 0 || c.MaxElapsed < 0:
+		err = fmt.Errorf("invalid %s: durations must not be negative", RetryPropKey)
+	case c.Multiplier != 0 && c.Multiplier < 1:
+		err = fmt.Errorf("invalid %s: multiplier must not be less than 1", RetryPropKey)
+	case c.Jitter != nil && (*c.Jitter < 0 || *c.Jitter > 1):
+		err = fmt.Errorf("invalid %s: jitter must be between 0 and 1", RetryPropKey)
+	case c.MaxRetries < 0:
+		err = fmt.Errorf("invalid %s: maxRetries must not be negative", RetryPropKey)
+	}
+	if err != nil {
+		return tc, err
+	}
+	return c, nil
+}
+
+// connRetryPolicy returns the retry policy of the connection. The invalid retry prop, which is rejected when the
+// named connection is created, falls back to the config of its type.
+func connRetryPolicy(typ string, props map[string]any) RetryPolicy {
+	c, err := connRetryConf(typ, props)
+	if err != nil {
+		conf.Log.Warnf("%v, use the retry config of type %s instead", err, typ)
+	}
+	return NewRetryPolicy(c)
+}
+
+// NewRetryPolicy builds the retry policy from the config. The retries stop after the max elapsed time or the max
+// count of retries if set.
+func NewRetryPolicy(c model.RetryConf) RetryPolicy {
+	return &limitedPolicy{
+		RetryPolicy: buildRetryPolicy(c),
+		maxElapsed:  intervalOr(c.MaxElapsed, GetTuning().MaxElapsedTime),
+		maxRetries:  c.MaxRetries,
+	}
+}
+
+func buildRetryPolicy(c model.RetryConf) RetryPolicy {
+	name := c.Policy
+	if name == "" {
+		name = RetryExponential
+	}
+	retryPoliciesLock.RLock()
+	builder, ok := retryPolicies[name]
+	retryPoliciesLock.RUnlock()
+	if !ok {
+		conf.Log.Warnf("unknown retry policy %s, use exponential instead", name)
+		builder = retryPolicies[RetryExponential]
+	}
+	return builder(c)
+}
+
+func intervalOr(d cast.DurationConf, def cast.DurationConf) time.Duration {
+	if d <= 0 {
+		return time.Duration(def)
+	}
+	return time.Duration(d)
+}
+
+// limitedPolicy stops the backoff of the policy after the max elapsed time or the max count of retries
+type limitedPolicy struct {
+	RetryPolicy
+	maxElapsed time.Duration
+	maxRetries int
+}
+
+func (p *limitedPolicy) NewBackOff() backoff.BackOff {
+	b := p.RetryPolicy.NewBackOff()
+	if p.maxRetries > 0 {
+		b = backoff.WithMaxRetries(b, uint64(p.maxRetries))
+	}
+	return withMaxElapsed(b, p.maxElapsed)
+}
+
+type exponentialPolicy struct {
+	initial    time.Duration
+	max        time.Duration
+	multiplier float64
+	jitter     float64
+}
+
+func (p *exponentialPolicy) NewBackOff() backoff.BackOff {
+	return backoff.NewExponentialBackOff(
+		backoff.WithInitialInterval(p.initial),
+		backoff.WithMaxInterval(p.max),
+		backoff.WithMultiplier(p.multiplier),
+		backoff.WithRandomizationFactor(p.jitter),
+		backoff.WithMaxElapsedTime(0),
+		backoff.WithClockProvider(getClock()),
+	)
+}
+
+type constantPolicy struct {
+	interval time.Duration
+}
+
+func (p *constantPolicy) NewBackOff() backoff.BackOff {
+	return backoff.NewConstantBackOff(p.interval)
+}
+
+type fibonacciPolicy struct {
+	initial time.Duration
+	max     time.Duration
+}
+
+func (p *fibonacciPolicy) NewBackOff() backoff.BackOff {
+	b := &fibonacciBackOff{initial: p.initial, max: p.max}
+	b.Reset()
+	return b
+}
+
+// fibonacciBackOff grows the interval as initial * fibonacci sequence 1, 1, 2, 3, 5... which is gentler than exponential
+type fibonacciBackOff struct {
+	initial time.Duration
+	max     time.Duration
+	prev    time.Duration
+	cur     time.Duration
+}
+
+func (b *fibonacciBackOff) Reset() {
+	b.prev = 0
+	b.cur = b.initial
+}
+
+func (b *fibonacciBackOff) NextBackOff() time.Duration {
+	next := b.cur
+	if next >= b.max {
+		return b.max
+	}
+	b.prev, b.cur = b.cur, b.prev+b.cur
+	return next
+}
+
+type jitterPolicy struct {
+	base time.Duration
+	max  time.Duration
+}
+
+func (p *jitterPolicy) NewBackOff() backoff.BackOff {
+	b := &decorrelatedJitterBackOff{base: p.base, max: p.max}
+	b.Reset()
+	return b
+}
+
+// decorrelatedJitterBackOff picks the interval randomly between base and 3 times of the previous interval to avoid
+// the clients retrying at the same time
+type decorrelatedJitterBackOff struct {
+	base time.Duration
+	max  time.Duration
+	prev time.Duration
+}
+
+func (b *decorrelatedJitterBackOff) Reset() {
+	b.prev = b.base
+}
+
+func (b *decorrelatedJitterBackOff) NextBackOff() time.Duration {
+	upper := b.prev * 3
+	next := b.base
+	if upper > b.base {
+		next += time.Duration(rand.Int63n(int64(upper - b.base)))
+	}
+	if next > b.max {
+		next = b.max
+	}
+	b.prev = next
+	return next
+}
@@ -0,0 +1,120 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+)
+
+// retryGuard protects the system from retry storms during outages. The budget limits how many connections can be in
+// the retry loop at the same time and the circuit breaker pauses all the retries when the dials keep failing.
+type retryGuard struct {
+	// nil means unlimited
+	sem chan struct{}
+
+	sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openUntil time.Time
+}
+
+// globalRetryGuard is replaced when the config is reloaded. The dials in progress keep using the old one.
+var globalRetryGuard atomic.Pointer[retryGuard]
+
+func init() {
+	globalRetryGuard.Store(newRetryGuard(0, 0, 0))
+}
+
+func newRetryGuard(budget int, threshold int, cooldown time.Duration) *retryGuard {
+	g := &retryGuard{threshold: threshold, cooldown: cooldown}
+	if budget > 0 {
+		g.sem = make(chan struct{}, budget)
+	}
+	return g
+}
+
+func initRetryGuard() {
+	if conf.Config == nil {
+		return
+	}
+	c := conf.Config.Connection
+	globalRetryGuard.Store(newRetryGuard(c.RetryBudget, c.CircuitBreaker.Threshold, time.Duration(c.CircuitBreaker.Cooldown)))
+}
+
+// acquire takes a slot of the retry budget. It blocks until a slot is free and returns false if the context is done.
+func (g *retryGuard) acquire(ctx context.Context) bool {
+	if g.sem == nil {
+		return true
+	}
+	select {
+	case g.sem <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (g *retryGuard) release() {
+	if g.sem == nil {
+		return
+	}
+	<-g.sem
+}
+
+// wait blocks while the circuit is open. It returns false if the context is done.
+func (g *retryGuard) wait(ctx context.Context) bool {
+	g.Lock()
+	c := getClock()
+	d := c.Until(g.openUntil)
+	g.Unlock()
+	if d <= 0 {
+		return true
+	}
+	t := c.Timer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (g *retryGuard) onSuccess() {
+	g.Lock()
+	defer g.Unlock()
+	g.failures = 0
+	g.openUntil = time.Time{}
+}
+
+func (g *retryGuard) onFailure() {
+	if g.threshold <= 0 {
+		return
+	}
+	g.Lock()
+	defer g.Unlock()
+	g.failures++
+	now := getClock().Now()
+	if g.failures >= g.threshold && now.After(g.openUntil) {
+		g.openUntil = now.Add(g.cooldown)
+		conf.Log.Warnf("%d consecutive connection dial failures, pause the retries for %v", g.failures, g.cooldown)
+	}
+}
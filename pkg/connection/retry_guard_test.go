@@ -0,0 +1,63 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryBudget(t *testing.T) {
+	g := newRetryGuard(1, 0, 0)
+	require.True(t, g.acquire(context.Background()))
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	// no slot left
+	require.False(t, g.acquire(ctx))
+	g.release()
+	require.True(t, g.acquire(context.Background()))
+	g.release()
+	// unlimited
+	g = newRetryGuard(0, 0, 0)
+	for i := 0; i < 10; i++ {
+		require.True(t, g.acquire(context.Background()))
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	g := newRetryGuard(0, 2, time.Hour)
+	g.onFailure()
+	require.True(t, g.wait(context.Background()))
+	g.onFailure()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	// open
+	require.False(t, g.wait(ctx))
+	g.onSuccess()
+	require.True(t, g.wait(context.Background()))
+
+	g = newRetryGuard(0, 1, 20*time.Millisecond)
+	g.onFailure()
+	now := time.Now()
+	require.True(t, g.wait(context.Background()))
+	require.GreaterOrEqual(t, time.Since(now), 10*time.Millisecond)
+	// disabled
+	g = newRetryGuard(0, 0, time.Hour)
+	g.onFailure()
+	require.True(t, g.wait(context.Background()))
+}
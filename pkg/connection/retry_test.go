@@ -0,0 +1,183 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/context"
+	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+	"github.com/lf-edge/ekuiper/v2/pkg/model"
+	"github.com/lf-edge/ekuiper/v2/pkg/modules"
+)
+
+func TestFibonacciBackOff(t *testing.T) {
+	b := NewRetryPolicy(model.RetryConf{Policy: RetryFibonacci, Interval: cast.DurationConf(time.Second), MaxInterval: cast.DurationConf(6 * time.Second)}).NewBackOff()
+	var got []time.Duration
+	for i := 0; i < 7; i++ {
+		got = append(got, b.NextBackOff())
+	}
+	require.Equal(t, []time.Duration{time.Second, time.Second, 2 * time.Second, 3 * time.Second, 5 * time.Second, 6 * time.Second, 6 * time.Second}, got)
+	b.Reset()
+	require.Equal(t, time.Second, b.NextBackOff())
+}
+
+func TestConstantBackOff(t *testing.T) {
+	b := NewRetryPolicy(model.RetryConf{Policy: RetryConstant, Interval: cast.DurationConf(time.Second)}).NewBackOff()
+	for i := 0; i < 3; i++ {
+		require.Equal(t, time.Second, b.NextBackOff())
+	}
+}
+
+func TestDecorrelatedJitterBackOff(t *testing.T) {
+	b := NewRetryPolicy(model.RetryConf{Policy: RetryDecorrelatedJitter, Interval: cast.DurationConf(time.Second), MaxInterval: cast.DurationConf(10 * time.Second)}).NewBackOff()
+	prev := time.Second
+	for i := 0; i < 20; i++ {
+		next := b.NextBackOff()
+		require.GreaterOrEqual(t, next, time.Second)
+		require.LessOrEqual(t, next, 10*time.Second)
+		require.LessOrEqual(t, next, prev*3)
+		prev = next
+	}
+}
+
+func TestGetRetryPolicy(t *testing.T) {
+	conf.InitConf()
+	defer func() {
+		conf.Config.Connection.TypeRetry = nil
+	}()
+	conf.Config.Connection.TypeRetry = map[string]model.RetryConf{
+		"mqtt": {Policy: RetryConstant},
+		"bad":  {Policy: "unknown"},
+	}
+	// the policy of the type is wrapped by the limits
+	basePolicy := func(typ string) RetryPolicy {
+		return GetRetryPolicy(typ).(*limitedPolicy).RetryPolicy
+	}
+	_, ok := basePolicy("mock").NewBackOff().(*backoff.ExponentialBackOff)
+	require.True(t, ok)
+	_, ok = basePolicy("MQTT").NewBackOff().(*backoff.ConstantBackOff)
+	require.True(t, ok)
+	_, ok = basePolicy("bad").NewBackOff().(*backoff.ExponentialBackOff)
+	require.True(t, ok)
+
+	RegisterRetryPolicy("noRetry", func(_ model.RetryConf) RetryPolicy {
+		return noRetryPolicy{}
+	})
+	conf.Config.Connection.TypeRetry["mqtt"] = model.RetryConf{Policy: "noRetry"}
+	require.Equal(t, backoff.Stop, GetRetryPolicy("mqtt").NewBackOff().NextBackOff())
+}
+
+type noRetryPolicy struct{}
+
+func (noRetryPolicy) NewBackOff() backoff.BackOff {
+	return &backoff.StopBackOff{}
+}
+
+func TestExponentialBackOffConf(t *testing.T) {
+	jitter := 0.0
+	b := NewRetryPolicy(model.RetryConf{
+		Interval:    cast.DurationConf(time.Second),
+		MaxInterval: cast.DurationConf(5 * time.Second),
+		Multiplier:  2,
+		Jitter:      &jitter,
+		MaxRetries:  4,
+	}).NewBackOff()
+	var got []time.Duration
+	for i := 0; i < 5; i++ {
+		got = append(got, b.NextBackOff())
+	}
+	require.Equal(t, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, backoff.Stop}, got)
+}
+
+func TestConnRetryConf(t *testing.T) {
+	conf.InitConf()
+	defer func() {
+		conf.Config.Connection.TypeRetry = nil
+	}()
+	jitter := 0.2
+	conf.Config.Connection.TypeRetry = map[string]model.RetryConf{
+		"mqtt": {Policy: RetryConstant, Interval: cast.DurationConf(time.Second), Jitter: &jitter},
+	}
+	c, err := connRetryConf("mqtt", map[string]any{"server": "tcp://127.0.0.1:1883"})
+	require.NoError(t, err)
+	require.Equal(t, conf.Config.Connection.TypeRetry["mqtt"], c)
+
+	c, err = connRetryConf("mqtt", map[string]any{RetryPropKey: map[string]any{"interval": "2s", "jitter": 0.5, "maxRetries": 3}})
+	require.NoError(t, err)
+	require.Equal(t, RetryConstant, c.Policy)
+	require.Equal(t, cast.DurationConf(2*time.Second), c.Interval)
+	require.Equal(t, 0.5, *c.Jitter)
+	require.Equal(t, 3, c.MaxRetries)
+	// the type config is not changed
+	require.Equal(t, 0.2, jitter)
+
+	for _, invalid := range []any{
+		map[string]any{"multiplier": 0.5},
+		map[string]any{"jitter": 2},
+		map[string]any{"maxRetries": -1},
+		"constant",
+	} {
+		c, err = connRetryConf("mqtt", map[string]any{RetryPropKey: invalid})
+		require.Error(t, err)
+		require.Equal(t, conf.Config.Connection.TypeRetry["mqtt"], c)
+	}
+
+	props := map[string]any{"server": "tcp://127.0.0.1:1883", RetryPropKey: map[string]any{"maxRetries": 3}}
+	require.Equal(t, map[string]any{"server": "tcp://127.0.0.1:1883"}, withoutReservedProps(props))
+	require.Len(t, props, 2)
+
+	require.NoError(t, InitConnectionManager4Test())
+	_, err = CreateNamedConnection(context.Background(), "badretry", "mock", map[string]any{RetryPropKey: map[string]any{"multiplier": 0.5}})
+	require.Error(t, err)
+}
+
+type countDialConnection struct {
+	mockConnection
+	dials *atomic.Int32
+}
+
+func (c *countDialConnection) Dial(_ api.StreamContext) error {
+	c.dials.Add(1)
+	return errorx.NewIOErr("dial failed")
+}
+
+func TestRetryStrategyByProps(t *testing.T) {
+	require.NoError(t, InitConnectionManager4Test())
+	dials := &atomic.Int32{}
+	modules.RegisterConnection("countdial", func(_ api.StreamContext) modules.Connection {
+		return &countDialConnection{dials: dials}
+	})
+	ctx := context.Background()
+	cw, err := CreateNamedConnection(ctx, "capped", "countdial", map[string]any{
+		RetryPropKey: map[string]any{"policy": RetryConstant, "interval": "1ms", "maxRetries": 2},
+	})
+	require.NoError(t, err)
+	defer func() {
+		_ = DropNameConnectionPermanently(ctx, "capped")
+	}()
+	_, err = cw.Wait(ctx)
+	require.Error(t, err)
+	// the first dial and 2 retries
+	require.Equal(t, int32(3), dials.Load())
+}
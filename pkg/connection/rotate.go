@@ -0,0 +1,140 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	topoContext "github.com/lf-edge/ekuiper/v2/internal/topo/context"
+	"github.com/lf-edge/ekuiper/v2/pkg/cert"
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+)
+
+// The cert manager reloads the TLS materials when the files change, but they are only used by the new handshakes.
+// The long-lived connections are rotated by connecting again with the same props and swapping the new transport in,
+// so the pool entry and the references are kept and the rules don't restart on every cert renewal.
+
+var (
+	certReloads     = make(chan []string, 16)
+	certReloadsOnce sync.Once
+)
+
+// rotateOnCertReload rotates the named connections using the cert files reloaded by the cert watcher
+func rotateOnCertReload(ctx context.Context) {
+	certReloadsOnce.Do(func() {
+		cert.OnReload(func(files []string) {
+			select {
+			case certReloads <- files:
+			default:
+				conf.Log.Warnf("too many cert reloads pending, the connections using %v are not rotated", files)
+			}
+		})
+	})
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case files := <-certReloads:
+			sctx := topoContext.WithContext(ctx)
+			for _, meta := range globalConnectionManager.certConnections(sctx, files) {
+				if err := rotateCerts(sctx, meta, "cert files reloaded"); err != nil {
+					connLogger(meta.ID).Errorf("rotate certs of connection %s failed, keep the old transport: %v", meta.ID, err)
+				}
+			}
+		}
+	}
+}
+
+// certConnections returns the named connections referencing any of the files
+func (m *ConnectionManager) certConnections(ctx api.StreamContext, files []string) []*Meta {
+	m.RLock()
+	defer m.RUnlock()
+	var result []*Meta
+	for _, meta := range m.connectionPool {
+		if !meta.Named {
+			continue
+		}
+		used, err := cert.Files(ctx.GetRootPath(), meta.Props)
+		if err != nil {
+			continue
+		}
+	loop:
+		for _, u := range used {
+			for _, f := range files {
+				if u == f {
+					result = append(result, meta)
+					break loop
+				}
+			}
+		}
+	}
+	return result
+}
+
+// RotateCerts reloads the cert files of the named connection and connects it again with the new materials.
+// The new transport is swapped in for the attached rules and the old one is closed. The old transport is kept if the
+// new one fails to connect.
+func (m *ConnectionManager) RotateCerts(ctx api.StreamContext, id string) error {
+	if id == "" {
+		return errorx.NewWithCode(errorx.ConnectionInvalidErr, "connection id should be defined")
+	}
+	if err := CheckNamespace(ctx, id); err != nil {
+		return err
+	}
+	m.RLock()
+	meta, ok := m.connectionPool[id]
+	m.RUnlock()
+	if !ok {
+		return errorx.NewWithCode(errorx.NOT_FOUND, fmt.Sprintf("connection %s not existed", id))
+	}
+	if !meta.Named {
+		return errorx.NewWithCode(errorx.ConnectionReadOnlyErr, fmt.Sprintf("internal connection %v can't rotate certs", id))
+	}
+	files, err := cert.ReloadFiles(ctx.GetRootPath(), meta.Props)
+	if err != nil {
+		return fmt.Errorf("reload cert files of connection %s failed: %w", id, err)
+	}
+	if len(files) == 0 {
+		return errorx.NewWithCode(errorx.ConnectionPropsErr, fmt.Sprintf("connection %s has no cert files", id))
+	}
+	return rotateCerts(ctx, meta, "")
+}
+
+// rotateCerts connects the connection again with the current props and swaps it in. The paused connection and the
+// connection of the standby node are skipped, they pick up the new materials when they connect.
+func rotateCerts(ctx api.StreamContext, meta *Meta, reason string) error {
+	meta.opLock.Lock()
+	defer meta.opLock.Unlock()
+	if IsStandby() || meta.paused.Load() {
+		return nil
+	}
+	staged, err := establish(ctx, meta, meta.Props)
+	if err != nil {
+		return err
+	}
+	if !meta.manager().holds(meta) {
+		staged.release()
+		return errorx.NewWithCode(errorx.ConnectionConflictErr, fmt.Sprintf("connection %s is changed during the cert rotation", meta.ID))
+	}
+	staged.swapInto(ctx, meta)
+	recordEvent(meta.ID, EventCertRotated, reason)
+	connLogger(meta.ID).Infof("connection %s rotates certs with %d references", meta.ID, meta.GetRefCount())
+	return nil
+}
@@ -0,0 +1,72 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/topo/context"
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+)
+
+func TestRotateConnectionCerts(t *testing.T) {
+	require.NoError(t, InitConnectionManager4Test())
+	ctx := context.Background()
+	dir := t.TempDir()
+	props := map[string]any{
+		"certificationPath": dir + "/client.crt",
+		"privateKeyPath":    dir + "/client.key",
+	}
+	_, err := CreateNamedConnection(ctx, "rotate1", "mock", props)
+	require.NoError(t, err)
+	defer func() {
+		_ = DropNameConnectionPermanently(ctx, "rotate1")
+	}()
+	cw, err := FetchConnection(ctx, extractRefId(ctx), "mock", map[string]any{"connectionSelector": "rotate1"}, nil)
+	require.NoError(t, err)
+	defer func() {
+		_ = DetachConnection(ctx, "rotate1")
+	}()
+	conn, err := cw.Wait(ctx)
+	require.NoError(t, err)
+
+	require.NoError(t, RotateConnectionCerts(ctx, "rotate1"))
+	rotated, err := cw.Wait(ctx)
+	require.NoError(t, err)
+	require.True(t, conn != rotated)
+	meta, err := GetConnectionDetail(ctx, "rotate1")
+	require.NoError(t, err)
+	require.Equal(t, 1, meta.GetRefCount())
+
+	// matched by the reloaded files
+	metas := globalConnectionManager.certConnections(ctx, []string{dir + "/client.key"})
+	require.Len(t, metas, 1)
+	require.Equal(t, "rotate1", metas[0].ID)
+	require.Empty(t, globalConnectionManager.certConnections(ctx, []string{dir + "/ca.crt"}))
+
+	_, err = CreateNamedConnection(ctx, "rotate2", "mock", nil)
+	require.NoError(t, err)
+	defer func() {
+		_ = DropNameConnectionPermanently(ctx, "rotate2")
+	}()
+	err = RotateConnectionCerts(ctx, "rotate2")
+	code, _ := errorx.GetErrorCode(err)
+	require.Equal(t, errorx.ConnectionPropsErr, code)
+	err = RotateConnectionCerts(ctx, "rotate3")
+	code, _ = errorx.GetErrorCode(err)
+	require.Equal(t, errorx.NOT_FOUND, code)
+}
@@ -0,0 +1,296 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+	"sync/atomic"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/pkg/replace"
+)
+
+// The secret props of the connections, like the passwords and tokens, are masked in the API output and can be
+// encrypted in the config store. The secret props are the common password keys and the configured fields.
+
+// HiddenSecret replaces the secret props in the API output. Updating a connection with the hidden value keeps the
+// current secret.
+const HiddenSecret = "******"
+
+// encryptedSecretPrefix marks the encrypted values in the config store. The values without it are plain text, such as
+// the ones written before the encryption is enabled.
+const encryptedSecretPrefix = "$encrypted:"
+
+// SecretCipher encrypts the secret props of the named connections in the config store
+type SecretCipher interface {
+	Encrypt(plain string) (string, error)
+	Decrypt(sealed string) (string, error)
+}
+
+type cipherHolder struct {
+	c   SecretCipher
+	err error
+}
+
+var (
+	// registeredCipher replaces the default AES cipher, such as a KMS client
+	registeredCipher SecretCipher
+	secretCipher     atomic.Pointer[cipherHolder]
+)
+
+// RegisterSecretCipher replaces the default AES cipher of the secret props. It must be called before the connection
+// manager is initialized.
+func RegisterSecretCipher(c SecretCipher) {
+	registeredCipher = c
+}
+
+// initSecretCipher creates the cipher if the encryption is enabled. If the key is unavailable, storing the secrets
+// fails instead of writing them in plain text.
+func initSecretCipher() {
+	h := &cipherHolder{}
+	if conf.Config != nil && conf.Config.Connection.Secrets.Encrypt {
+		if registeredCipher != nil {
+			h.c = registeredCipher
+		} else {
+			key, err := conf.GetEncryptionKey(conf.Config.Connection.Secrets.KeyProvider)
+			if err == nil {
+				h.c, err = newAESSecretCipher(key)
+			}
+			if err != nil {
+				h.err = fmt.Errorf("connection secret encryption is not available: %v", err)
+				conf.Log.Error(h.err)
+			}
+		}
+	}
+	secretCipher.Store(h)
+}
+
+// IsSecretProp tells whether the prop key holds a secret, which is a password key or one of the configured secret
+// fields
+func IsSecretProp(k string) bool {
+	return isSecretField(k)
+}
+
+func isSecretField(k string) bool {
+	if replace.IsPasswordKey(k) {
+		return true
+	}
+	if conf.Config != nil {
+		for _, f := range conf.Config.Connection.Secrets.Fields {
+			if strings.EqualFold(f, k) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// MaskSecrets returns a copy of the props with the secret values hidden
+func MaskSecrets(props map[string]any) map[string]any {
+	if props == nil {
+		return nil
+	}
+	result := make(map[string]any, len(props))
+	for k, v := range props {
+		if isSecretField(k) {
+			if s, ok := v.(string); !ok || s != "" {
+				v = HiddenSecret
+			}
+		} else if vm, ok := v.(map[string]any); ok {
+			v = MaskSecrets(vm)
+		} else if vl, ok := v.([]any); ok {
+			v, _ = mapsInList(vl, func(_ int, m map[string]any) (map[string]any, error) {
+				return MaskSecrets(m), nil
+			})
+		}
+		result[k] = v
+	}
+	return result
+}
+
+// restoreSecrets replaces the hidden secrets of the new props with the current ones, so that the props got from the
+// API can be updated without knowing the secrets
+func restoreSecrets(props, current map[string]any) map[string]any {
+	if props == nil {
+		return nil
+	}
+	result := make(map[string]any, len(props))
+	for k, v := range props {
+		if isSecretField(k) && v == HiddenSecret {
+			if cv, ok := current[k]; ok {
+				v = cv
+			}
+		} else if vm, ok := v.(map[string]any); ok {
+			cm, _ := current[k].(map[string]any)
+			v = restoreSecrets(vm, cm)
+		} else if vl, ok := v.([]any); ok {
+			cl, _ := current[k].([]any)
+			v, _ = mapsInList(vl, func(i int, m map[string]any) (map[string]any, error) {
+				var cm map[string]any
+				if i < len(cl) {
+					cm, _ = cl[i].(map[string]any)
+				}
+				return restoreSecrets(m, cm), nil
+			})
+		}
+		result[k] = v
+	}
+	return result
+}
+
+// secretsEncrypted tells whether the secret props are encrypted in the config store
+func secretsEncrypted() bool {
+	h := secretCipher.Load()
+	return h != nil && h.c != nil
+}
+
+// encryptSecrets returns a copy of the props with the secret values encrypted to store. It returns the props as is if
+// the encryption is disabled.
+func encryptSecrets(props map[string]any) (map[string]any, error) {
+	h := secretCipher.Load()
+	if h == nil || (h.c == nil && h.err == nil) || props == nil {
+		return props, nil
+	}
+	if h.err != nil {
+		return nil, h.err
+	}
+	result := make(map[string]any, len(props))
+	for k, v := range props {
+		if s, ok := v.(string); ok && isSecretField(k) && s != "" && !strings.HasPrefix(s, encryptedSecretPrefix) {
+			sealed, err := h.c.Encrypt(s)
+			if err != nil {
+				return nil, fmt.Errorf("encrypt connection prop %s failed: %v", k, err)
+			}
+			v = encryptedSecretPrefix + sealed
+		} else if vm, ok := v.(map[string]any); ok {
+			ev, err := encryptSecrets(vm)
+			if err != nil {
+				return nil, err
+			}
+			v = ev
+		} else if vl, ok := v.([]any); ok {
+			ev, err := mapsInList(vl, func(_ int, m map[string]any) (map[string]any, error) {
+				return encryptSecrets(m)
+			})
+			if err != nil {
+				return nil, err
+			}
+			v = ev
+		}
+		result[k] = v
+	}
+	return result, nil
+}
+
+// decryptSecrets returns a copy of the stored props with the encrypted values decrypted. All the encrypted values are
+// decrypted even if the secret fields are changed since they are stored.
+func decryptSecrets(props map[string]any) (map[string]any, error) {
+	if props == nil {
+		return nil, nil
+	}
+	result := make(map[string]any, len(props))
+	for k, v := range props {
+		if s, ok := v.(string); ok && strings.HasPrefix(s, encryptedSecretPrefix) {
+			h := secretCipher.Load()
+			if h == nil || h.c == nil {
+				return nil, fmt.Errorf("connection prop %s is encrypted but the secret encryption is not enabled", k)
+			}
+			plain, err := h.c.Decrypt(strings.TrimPrefix(s, encryptedSecretPrefix))
+			if err != nil {
+				return nil, fmt.Errorf("decrypt connection prop %s failed: %v", k, err)
+			}
+			v = plain
+		} else if vm, ok := v.(map[string]any); ok {
+			dv, err := decryptSecrets(vm)
+			if err != nil {
+				return nil, err
+			}
+			v = dv
+		} else if vl, ok := v.([]any); ok {
+			dv, err := mapsInList(vl, func(_ int, m map[string]any) (map[string]any, error) {
+				return decryptSecrets(m)
+			})
+			if err != nil {
+				return nil, err
+			}
+			v = dv
+		}
+		result[k] = v
+	}
+	return result, nil
+}
+
+// mapsInList returns a copy of the list with the props in it, like the failover endpoints, converted by f
+func mapsInList(list []any, f func(i int, m map[string]any) (map[string]any, error)) ([]any, error) {
+	result := make([]any, len(list))
+	for i, e := range list {
+		if m, ok := e.(map[string]any); ok {
+			nm, err := f(i, m)
+			if err != nil {
+				return nil, err
+			}
+			e = nm
+		}
+		result[i] = e
+	}
+	return result, nil
+}
+
+// aesSecretCipher encrypts with AES-GCM and a random nonce prepended to the sealed value
+type aesSecretCipher struct {
+	gcm cipher.AEAD
+}
+
+func newAESSecretCipher(key []byte) (*aesSecretCipher, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &aesSecretCipher{gcm: gcm}, nil
+}
+
+func (a *aesSecretCipher) Encrypt(plain string) (string, error) {
+	nonce := make([]byte, a.gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(a.gcm.Seal(nonce, nonce, []byte(plain), nil)), nil
+}
+
+func (a *aesSecretCipher) Decrypt(sealed string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return "", err
+	}
+	nonceSize := a.gcm.NonceSize()
+	if len(data) < nonceSize {
+		return "", fmt.Errorf("encrypted value too short")
+	}
+	plain, err := a.gcm.Open(nil, data[:nonceSize], data[nonceSize:], nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
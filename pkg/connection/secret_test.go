@@ -0,0 +1,122 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/context"
+)
+
+func TestMaskSecrets(t *testing.T) {
+	conf.InitConf()
+	origin := conf.Config.Connection.Secrets.Fields
+	defer func() {
+		conf.Config.Connection.Secrets.Fields = origin
+	}()
+	conf.Config.Connection.Secrets.Fields = []string{"PrivateKeyRaw"}
+	props := map[string]any{
+		"server":        "tcp://127.0.0.1:1883",
+		"password":      "p1",
+		"token":         "",
+		"privateKeyRaw": "a2V5",
+		"tls":           map[string]any{"password": 123},
+	}
+	masked := MaskSecrets(props)
+	require.Equal(t, map[string]any{
+		"server":        "tcp://127.0.0.1:1883",
+		"password":      HiddenSecret,
+		"token":         "",
+		"privateKeyRaw": HiddenSecret,
+		"tls":           map[string]any{"password": HiddenSecret},
+	}, masked)
+	// the origin is not changed
+	require.Equal(t, "p1", props["password"])
+	require.Nil(t, MaskSecrets(nil))
+
+	masked["server"] = "tcp://127.0.0.1:1884"
+	require.Equal(t, map[string]any{
+		"server":        "tcp://127.0.0.1:1884",
+		"password":      "p1",
+		"token":         "",
+		"privateKeyRaw": "a2V5",
+		"tls":           map[string]any{"password": 123},
+	}, restoreSecrets(masked, props))
+	// the hidden value of a new secret is kept as is
+	require.Equal(t, map[string]any{"password": HiddenSecret}, restoreSecrets(map[string]any{"password": HiddenSecret}, nil))
+}
+
+func TestSecretEncryption(t *testing.T) {
+	conf.InitConf()
+	origin := conf.Config.Connection.Secrets
+	defer func() {
+		conf.Config.Connection.Secrets = origin
+		require.NoError(t, InitConnectionManager4Test())
+	}()
+	key := base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef"))
+	conf.RegisterKeyProvider("secretTest", func() ([]byte, error) {
+		return base64.StdEncoding.DecodeString(key)
+	})
+	conf.Config.Connection.Secrets.Encrypt = true
+	conf.Config.Connection.Secrets.KeyProvider = "secretTest"
+	require.NoError(t, InitConnectionManager4Test())
+	ctx := context.Background()
+
+	_, err := CreateNamedConnection(ctx, "sec1", "mock", map[string]any{"user": "u1", "password": "p1"})
+	require.NoError(t, err)
+	stored, err := conf.GetCfgFromKVStorage("connections", "mock", "sec1")
+	require.NoError(t, err)
+	props := stored["connections.mock.sec1"]
+	require.Equal(t, "u1", props["user"])
+	require.True(t, strings.HasPrefix(props["password"].(string), encryptedSecretPrefix))
+	meta, err := GetConnectionDetail(ctx, "sec1")
+	require.NoError(t, err)
+	require.Equal(t, "p1", meta.Props["password"])
+
+	// reload decrypts the secrets
+	require.NoError(t, InitConnectionManager4Test())
+	require.NoError(t, ReloadNamedConnection())
+	meta, err = GetConnectionDetail(ctx, "sec1")
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"user": "u1", "password": "p1"}, meta.Props)
+
+	// update with the masked props keeps the secret
+	_, err = UpdateNamedConnection(ctx, "sec1", map[string]any{"user": "u2", "password": HiddenSecret})
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"user": "u2", "password": "p1"}, meta.Props)
+	_, err = UpdateConnection(ctx, "sec1", "mock", MaskSecrets(meta.Props))
+	require.NoError(t, err)
+	meta, err = GetConnectionDetail(ctx, "sec1")
+	require.NoError(t, err)
+	require.Equal(t, "p1", meta.Props["password"])
+
+	// the storing fails without the key
+	conf.Config.Connection.Secrets.KeyProvider = "nonexist"
+	require.NoError(t, InitConnectionManager4Test())
+	_, err = CreateNamedConnection(ctx, "sec2", "mock", map[string]any{"password": "p2"})
+	require.Error(t, err)
+	_, err = GetConnectionDetail(ctx, "sec2")
+	require.Error(t, err)
+	// the encrypted connections can't be loaded
+	require.NoError(t, ReloadNamedConnection())
+	_, err = GetConnectionDetail(ctx, "sec1")
+	require.Error(t, err)
+	require.NoError(t, dropConnectionStore("mock", "sec1"))
+}
@@ -0,0 +1,225 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+	"github.com/lf-edge/ekuiper/v2/pkg/modules"
+)
+
+var (
+	errPoolClosed   = errorx.NewWithCode(errorx.ConnectionErr, "connection pool is shut down")
+	errPoolDraining = errorx.NewWithCode(errorx.ConnectionErr, "connection pool is shutting down")
+)
+
+// drainPollInterval is the interval to check whether the references of the connections are drained
+const drainPollInterval = 50 * time.Millisecond
+
+// The shutdown phases in order. The pending dials are cancelled first. Then the connections only for sinks are
+// closed so that the pending writes are flushed, then the connections for both sinks and sources, and the ones
+// only for sources at last.
+const (
+	phasePending = iota
+	phaseSink
+	phaseShared
+	phaseSource
+	phaseCount
+)
+
+// ShutdownReport is the result of closing the connections on engine shutdown
+type ShutdownReport struct {
+	Closed []string `json:"closed"`
+	// Failed is the connections failed to close cleanly with the reason
+	Failed map[string]string `json:"failed"`
+	// Undrained is the connections still referenced by the rules when the drain timeout elapses
+	Undrained []string `json:"undrained,omitempty"`
+}
+
+// Shutdown tears down the pool in order. It rejects new attaches, waits for the references of the
+// rules to drain until the timeout elapses, and then closes all the connections like Close.
+func (m *ConnectionManager) Shutdown(ctx api.StreamContext, timeout time.Duration) *ShutdownReport {
+	m.Lock()
+	m.draining = true
+	m.Unlock()
+	undrained := m.drainReferences(ctx, timeout)
+	report := m.Close(ctx)
+	report.Undrained = undrained
+	for _, id := range undrained {
+		connLogger(id).Warnf("connection %s is closed with references after the drain timeout", id)
+	}
+	return report
+}
+
+// drainReferences waits until no connection is referenced or the timeout elapses. It returns the connections still
+// referenced.
+func (m *ConnectionManager) drainReferences(ctx api.StreamContext, timeout time.Duration) []string {
+	c := getClock()
+	deadline := c.Now().Add(timeout)
+	ticker := c.Ticker(drainPollInterval)
+	defer ticker.Stop()
+	for {
+		var referenced []string
+		for id, meta := range m.load() {
+			if meta.GetRefCount() > 0 {
+				referenced = append(referenced, id)
+			}
+		}
+		if len(referenced) == 0 {
+			return nil
+		}
+		if !c.Now().Before(deadline) {
+			sort.Strings(referenced)
+			return referenced
+		}
+		select {
+		case <-ctx.Done():
+			sort.Strings(referenced)
+			return referenced
+		case <-ticker.C:
+		}
+	}
+}
+
+// Close closes all the connections in the pool and rejects new connections. It is called on engine shutdown
+// after the rules have drained. The connections in the same phase are closed concurrently, each bounded by the
+// operation timeout.
+func (m *ConnectionManager) Close(ctx api.StreamContext) *ShutdownReport {
+	m.Lock()
+	m.closed = true
+	metas := make([]*Meta, 0, len(m.connectionPool))
+	for _, meta := range m.connectionPool {
+		metas = append(metas, meta)
+		prev, _ := meta.status.Load().(string)
+		emitStatus(meta, prev, ConnectionDropped, "")
+	}
+	m.connectionPool = make(map[string]*Meta)
+	m.publish()
+	m.Unlock()
+	// the leadership is held for the default manager only
+	if m == globalConnectionManager {
+		resignLeader()
+	}
+
+	phases := make([][]*Meta, phaseCount)
+	for _, meta := range metas {
+		p := shutdownPhase(ctx, meta)
+		phases[p] = append(phases[p], meta)
+	}
+	report := &ShutdownReport{
+		Closed: make([]string, 0, len(metas)),
+		Failed: make(map[string]string),
+	}
+	var mu sync.Mutex
+	for _, phase := range phases {
+		var wg sync.WaitGroup
+		for _, meta := range phase {
+			wg.Add(1)
+			go func(meta *Meta) {
+				defer wg.Done()
+				err := shutdownConnection(ctx, meta)
+				mu.Lock()
+				defer mu.Unlock()
+				if err != nil {
+					report.Failed[meta.ID] = err.Error()
+				} else {
+					report.Closed = append(report.Closed, meta.ID)
+				}
+			}(meta)
+		}
+		wg.Wait()
+	}
+	// abort the connections still creating or swapped in during the close
+	m.cancel()
+	sort.Strings(report.Closed)
+	conf.Log.Infof("connection pool shutdown, %d closed, %d failed", len(report.Closed), len(report.Failed))
+	for id, reason := range report.Failed {
+		connLogger(id).Warnf("connection %s failed to close cleanly: %s", id, reason)
+	}
+	return report
+}
+
+func shutdownPhase(ctx api.StreamContext, meta *Meta) int {
+	if !meta.cw.IsInitialized() {
+		return phasePending
+	}
+	conn, err := meta.cw.Wait(ctx)
+	if err != nil || conn == nil {
+		return phasePending
+	}
+	var pub, sub bool
+	for _, c := range modules.GetCapabilities(conn) {
+		switch c {
+		case modules.CapPublish:
+			pub = true
+		case modules.CapSubscribe, modules.CapQuery:
+			sub = true
+		}
+	}
+	switch {
+	case pub && !sub:
+		return phaseSink
+	case sub && !pub:
+		return phaseSource
+	default:
+		return phaseShared
+	}
+}
+
+// shutdownConnection closes the connection within the operation timeout even if the connection ignores the context
+func shutdownConnection(ctx api.StreamContext, meta *Meta) error {
+	meta.opLock.Lock()
+	defer meta.opLock.Unlock()
+	var result error
+	for _, cw := range meta.wrappers() {
+		if err := shutdownWrapper(ctx, meta, cw); err != nil && result == nil {
+			result = err
+		}
+	}
+	return result
+}
+
+func shutdownWrapper(ctx api.StreamContext, meta *Meta, cw *ConnWrapper) error {
+	defer cw.stop()
+	if !cw.IsInitialized() {
+		return nil
+	}
+	opCtx, cancel := withTimeout(ctx)
+	defer cancel()
+	conn, err := cw.Wait(opCtx)
+	if err != nil || conn == nil {
+		// never connected, nothing to close
+		return nil
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- safeCall(meta.ID, "close", func() error {
+			return conn.Close(opCtx)
+		})
+	}()
+	select {
+	case err = <-done:
+		return err
+	case <-opCtx.Done():
+		return fmt.Errorf("close timeout: %v", opCtx.Err())
+	}
+}
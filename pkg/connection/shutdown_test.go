@@ -0,0 +1,168 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/topo/context"
+	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+	mockContext "github.com/lf-edge/ekuiper/v2/pkg/mock/context"
+	"github.com/lf-edge/ekuiper/v2/pkg/modules"
+	"github.com/lf-edge/ekuiper/v2/pkg/syncx"
+)
+
+var (
+	closeOrder []string
+	closeMu    syncx.Mutex
+)
+
+type orderedConnection struct {
+	mockConnection
+	pub, sub bool
+	hang     bool
+}
+
+func (o *orderedConnection) CanPublish() bool {
+	return o.pub
+}
+
+func (o *orderedConnection) CanSubscribe() bool {
+	return o.sub
+}
+
+func (o *orderedConnection) Close(ctx api.StreamContext) error {
+	if o.hang {
+		time.Sleep(time.Second)
+	}
+	closeMu.Lock()
+	defer closeMu.Unlock()
+	closeOrder = append(closeOrder, o.id)
+	return nil
+}
+
+func registerOrdered(t *testing.T, typ string, pub, sub, hang bool) {
+	require.NoError(t, modules.RegisterConnectionType(typ, func(_ api.StreamContext) modules.Connection {
+		return &orderedConnection{pub: pub, sub: sub, hang: hang}
+	}))
+}
+
+func TestShutdown(t *testing.T) {
+	require.NoError(t, InitConnectionManager4Test())
+	defer initTuning()
+	tu := GetTuning()
+	tu.OperationTimeout = cast.DurationConf(100 * time.Millisecond)
+	require.NoError(t, SetTuning(tu))
+	closeOrder = nil
+	registerOrdered(t, "srconly", false, true, false)
+	registerOrdered(t, "sinkonly", true, false, false)
+	registerOrdered(t, "both", true, true, false)
+	registerOrdered(t, "hang", true, false, true)
+	defer func() {
+		for _, typ := range []string{"srconly", "sinkonly", "both", "hang"} {
+			modules.UnregisterConnection(typ)
+		}
+	}()
+	ctx := context.Background()
+	stored := map[string]string{"src": "srconly", "sink": "sinkonly", "both": "both", "hang": "hang"}
+	// the shutdown keeps the stored connections
+	defer func() {
+		for id, typ := range stored {
+			_ = dropConnectionStore(typ, id)
+		}
+	}()
+	for id, typ := range stored {
+		cw, err := CreateNamedConnection(ctx, id, typ, nil)
+		require.NoError(t, err)
+		_, err = cw.Wait(ctx)
+		require.NoError(t, err)
+	}
+
+	report := Shutdown(ctx)
+	require.Equal(t, []string{"both", "sink", "src"}, report.Closed)
+	require.Equal(t, []string{"hang"}, keys(report.Failed))
+	closeMu.Lock()
+	require.Equal(t, []string{"sink", "both", "src"}, closeOrder)
+	closeMu.Unlock()
+	require.Empty(t, GetAllConnectionsMeta(true))
+	// no more connections after shutdown
+	_, err := CreateNamedConnection(ctx, "new", "mock", nil)
+	require.Error(t, err)
+	_, err = FetchConnection(ctx, "r1", "mock", nil, nil)
+	require.Error(t, err)
+}
+
+func TestShutdownConnectionManager(t *testing.T) {
+	require.NoError(t, InitConnectionManager4Test())
+	ctx := context.Background()
+	// the shutdown keeps the stored connections
+	defer func() {
+		for _, id := range []string{"drain1", "drain2", "drain3"} {
+			_ = dropConnectionStore("mock", id)
+		}
+	}()
+	_, err := CreateNamedConnection(ctx, "drain1", "mock", nil)
+	require.NoError(t, err)
+	_, err = CreateNamedConnection(ctx, "drain2", "mock", nil)
+	require.NoError(t, err)
+	props := map[string]any{"connectionSelector": "drain1"}
+	ctx1 := mockContext.NewMockContext("rule1", "op1")
+	_, err = FetchConnection(ctx1, "ref1", "mock", props, nil)
+	require.NoError(t, err)
+
+	done := make(chan *ShutdownReport, 1)
+	go func() {
+		done <- ShutdownConnectionManager(ctx, 5*time.Second)
+	}()
+	require.Eventually(t, func() bool {
+		globalConnectionManager.RLock()
+		defer globalConnectionManager.RUnlock()
+		return globalConnectionManager.draining
+	}, time.Second, 10*time.Millisecond)
+	// no new attaches while draining
+	_, err = FetchConnection(mockContext.NewMockContext("rule2", "op1"), "ref2", "mock", map[string]any{"connectionSelector": "drain2"}, nil)
+	require.Error(t, err)
+	select {
+	case <-done:
+		require.Fail(t, "shutdown before the references drain")
+	case <-time.After(100 * time.Millisecond):
+	}
+	require.NoError(t, DetachConnection(ctx1, "drain1"))
+	report := <-done
+	require.Equal(t, []string{"drain1", "drain2"}, report.Closed)
+	require.Empty(t, report.Undrained)
+
+	// close the referenced connections after the timeout
+	require.NoError(t, InitConnectionManager4Test())
+	_, err = CreateNamedConnection(ctx, "drain3", "mock", nil)
+	require.NoError(t, err)
+	_, err = FetchConnection(ctx1, "ref1", "mock", map[string]any{"connectionSelector": "drain3"}, nil)
+	require.NoError(t, err)
+	report = ShutdownConnectionManager(ctx, 100*time.Millisecond)
+	require.Equal(t, []string{"drain3"}, report.Undrained)
+	require.Equal(t, []string{"drain3"}, report.Closed)
+}
+
+func keys(m map[string]string) []string {
+	result := make([]string, 0, len(m))
+	for k := range m {
+		result = append(result, k)
+	}
+	return result
+}
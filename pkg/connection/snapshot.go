@@ -0,0 +1,60 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+)
+
+// StatusEntry is the status of a connection in the snapshot. The strings are shared with the pool, so no copy is made.
+type StatusEntry struct {
+	ID        string
+	Typ       string
+	Named     bool
+	Status    string
+	LastError string
+	RefCount  int
+}
+
+// StatusSnapshot appends the status of all the connections to dst[:0] sorted by id and returns the slice. It is
+// designed for the monitoring agents which poll frequently: reuse the returned slice in the next call so that no
+// allocation happens once its capacity is enough. Unlike Meta.GetStatus, it reports the latest known status without
+// pinging the stateless connections.
+func StatusSnapshot(dst []StatusEntry) []StatusEntry {
+	dst = dst[:0]
+	for _, meta := range globalConnectionManager.load() {
+		e := StatusEntry{
+			ID:       meta.ID,
+			Typ:      meta.Typ,
+			Named:    meta.Named,
+			Status:   api.ConnectionConnecting,
+			RefCount: meta.GetRefCount(),
+		}
+		if s, ok := meta.status.Load().(string); ok {
+			e.Status = s
+		}
+		if s, ok := meta.lastError.Load().(string); ok && e.Status != api.ConnectionConnected {
+			e.LastError = s
+		}
+		dst = append(dst, e)
+	}
+	slices.SortFunc(dst, func(a, b StatusEntry) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+	return dst
+}
@@ -0,0 +1,70 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/topo/context"
+)
+
+func TestStatusSnapshot(t *testing.T) {
+	require.NoError(t, InitConnectionManager4Test())
+	ctx := context.Background()
+	for _, id := range []string{"s2", "s1", "s3"} {
+		cw, err := CreateNamedConnection(ctx, id, "mock", map[string]any{})
+		require.NoError(t, err)
+		defer func() {
+			_ = DropNameConnectionPermanently(ctx, id)
+		}()
+		_, err = cw.Wait(ctx)
+		require.NoError(t, err)
+	}
+	meta, err := GetConnectionDetail(ctx, "s3")
+	require.NoError(t, err)
+	meta.NotifyStatus(api.ConnectionDisconnected, "lost")
+
+	entries := StatusSnapshot(nil)
+	require.Equal(t, []StatusEntry{
+		{ID: "s1", Typ: "mock", Named: true, Status: api.ConnectionConnected},
+		{ID: "s2", Typ: "mock", Named: true, Status: api.ConnectionConnected},
+		{ID: "s3", Typ: "mock", Named: true, Status: api.ConnectionDisconnected, LastError: "lost"},
+	}, entries)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		entries = StatusSnapshot(entries)
+	})
+	require.Equal(t, 0.0, allocs)
+	require.Len(t, entries, 3)
+}
+
+func BenchmarkStatusSnapshot(b *testing.B) {
+	require.NoError(b, InitConnectionManager4Test())
+	ctx := context.Background()
+	for i := 0; i < 100; i++ {
+		_, err := CreateNamedConnection(ctx, fmt.Sprintf("snap%d", i), "mock", map[string]any{})
+		require.NoError(b, err)
+	}
+	var entries []StatusEntry
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		entries = StatusSnapshot(entries)
+	}
+}
@@ -0,0 +1,203 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+)
+
+// The reserved props to order the creation of the named connections when they are loaded at startup. The
+// connections of a higher priority are created first and a connection is created after the connections it depends
+// on, such as the broker connection of a bridge. The connections of the same level are created concurrently.
+const (
+	PriorityPropKey  = "$priority"
+	DependsOnPropKey = "$dependsOn"
+)
+
+func parsePriority(props map[string]any) (int, error) {
+	v, ok := props[PriorityPropKey]
+	if !ok || v == nil {
+		return 0, nil
+	}
+	switch p := v.(type) {
+	case int:
+		return p, nil
+	case int64:
+		return int(p), nil
+	case float64:
+		if p == float64(int(p)) {
+			return int(p), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid %s: must be an integer", PriorityPropKey)
+}
+
+func parseDependsOn(props map[string]any) ([]string, error) {
+	v, ok := props[DependsOnPropKey]
+	if !ok || v == nil {
+		return nil, nil
+	}
+	switch dv := v.(type) {
+	case []string:
+		return dv, nil
+	case []any:
+		deps := make([]string, 0, len(dv))
+		for _, d := range dv {
+			s, ok := d.(string)
+			if !ok || s == "" {
+				return nil, fmt.Errorf("invalid %s: must be a list of connection ids", DependsOnPropKey)
+			}
+			deps = append(deps, s)
+		}
+		return deps, nil
+	default:
+		return nil, fmt.Errorf("invalid %s: must be a list of connection ids", DependsOnPropKey)
+	}
+}
+
+func validateStartupProps(props map[string]any) error {
+	if _, err := parsePriority(props); err != nil {
+		return err
+	}
+	_, err := parseDependsOn(props)
+	return err
+}
+
+// Priority returns the startup priority of the connection. The default is 0.
+func (meta *Meta) Priority() int {
+	p, _ := parsePriority(meta.Props)
+	return p
+}
+
+// DependsOn returns the ids of the connections which must be created before this one at startup
+func (meta *Meta) DependsOn() []string {
+	deps, _ := parseDependsOn(meta.Props)
+	return deps
+}
+
+// startupLevels groups the connections into the levels to create in order. The level of a connection is after the
+// levels of the higher priorities and the levels of its dependencies. The dependencies out of the group are ignored
+// since they are created already, and the dependency cycles are broken with a warning.
+func startupLevels(metas []*Meta) [][]*Meta {
+	byId := make(map[string]*Meta, len(metas))
+	priorities := make([]int, 0, len(metas))
+	for _, meta := range metas {
+		byId[meta.ID] = meta
+		priorities = append(priorities, meta.Priority())
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(priorities)))
+	// the base level of each priority, the same priority shares the level
+	base := make(map[int]int, len(priorities))
+	for _, p := range priorities {
+		if _, ok := base[p]; !ok {
+			base[p] = len(base)
+		}
+	}
+	levels := make(map[string]int, len(metas))
+	visiting := make(map[string]bool)
+	var levelOf func(meta *Meta) int
+	levelOf = func(meta *Meta) int {
+		if l, ok := levels[meta.ID]; ok {
+			return l
+		}
+		visiting[meta.ID] = true
+		l := base[meta.Priority()]
+		for _, dep := range meta.DependsOn() {
+			d, ok := byId[dep]
+			if !ok || dep == meta.ID {
+				continue
+			}
+			if visiting[dep] {
+				conf.Log.Warnf("connection %s and %s depend on each other, the dependency is ignored at startup", meta.ID, dep)
+				continue
+			}
+			if dl := levelOf(d) + 1; dl > l {
+				l = dl
+			}
+		}
+		delete(visiting, meta.ID)
+		levels[meta.ID] = l
+		return l
+	}
+	sorted := make([]int, 0, len(metas))
+	for _, meta := range metas {
+		sorted = append(sorted, levelOf(meta))
+	}
+	// compact the levels so that each level waits for the previous one only
+	sort.Ints(sorted)
+	index := make(map[int]int)
+	for _, l := range sorted {
+		if _, ok := index[l]; !ok {
+			index[l] = len(index)
+		}
+	}
+	result := make([][]*Meta, len(index))
+	for _, meta := range metas {
+		i := index[levels[meta.ID]]
+		result[i] = append(result[i], meta)
+	}
+	for _, level := range result {
+		sort.Slice(level, func(i, j int) bool {
+			return level[i].ID < level[j].ID
+		})
+	}
+	return result
+}
+
+// startConnections creates the wrappers of the loaded connections level by level. The connections of a level are
+// created after the initial creation of the previous level finishes or fails, or after the operation timeout so that
+// a slow connection never blocks the others forever.
+func startConnections(ctx api.StreamContext, metas []*Meta) {
+	var prev []*ConnWrapper
+	for _, level := range startupLevels(metas) {
+		var gate chan struct{}
+		if len(prev) > 0 {
+			gate = make(chan struct{})
+			go waitStartup(prev, gate)
+		}
+		current := make([]*ConnWrapper, 0, len(level))
+		for _, meta := range level {
+			if IsStandby() {
+				meta.newWrappers(func() *ConnWrapper {
+					return newStandbyConnWrapper(meta)
+				})
+			} else {
+				meta.newWrappers(func() *ConnWrapper {
+					return newGatedConnWrapper(ctx, meta, gate)
+				})
+			}
+			current = append(current, meta.wrappers()...)
+		}
+		prev = current
+	}
+}
+
+func waitStartup(prev []*ConnWrapper, gate chan struct{}) {
+	defer close(gate)
+	timeout := getClock().After(time.Duration(GetTuning().OperationTimeout))
+	for _, cw := range prev {
+		select {
+		case <-cw.readCh:
+		case <-timeout:
+			return
+		}
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/topo/context"
+	"github.com/lf-edge/ekuiper/v2/pkg/modules"
+	"github.com/lf-edge/ekuiper/v2/pkg/syncx"
+)
+
+func levelIds(levels [][]*Meta) [][]string {
+	result := make([][]string, 0, len(levels))
+	for _, level := range levels {
+		ids := make([]string, 0, len(level))
+		for _, meta := range level {
+			ids = append(ids, meta.ID)
+		}
+		result = append(result, ids)
+	}
+	return result
+}
+
+func TestStartupLevels(t *testing.T) {
+	metas := []*Meta{
+		{ID: "bridge", Props: map[string]any{DependsOnPropKey: []any{"broker", "missing"}}},
+		{ID: "broker", Props: map[string]any{}},
+		{ID: "db", Props: map[string]any{PriorityPropKey: 10.0}},
+		{ID: "cache", Props: map[string]any{PriorityPropKey: 10.0}},
+		{ID: "a", Props: map[string]any{DependsOnPropKey: []string{"b"}}},
+		{ID: "b", Props: map[string]any{DependsOnPropKey: []string{"a"}}},
+	}
+	require.Equal(t, [][]string{
+		{"cache", "db"},
+		{"b", "broker"},
+		{"a", "bridge"},
+	}, levelIds(startupLevels(metas)))
+	require.Empty(t, startupLevels(nil))
+}
+
+func TestValidateStartupProps(t *testing.T) {
+	require.NoError(t, validateStartupProps(map[string]any{PriorityPropKey: 2.0, DependsOnPropKey: []any{"a"}}))
+	require.EqualError(t, validateStartupProps(map[string]any{PriorityPropKey: 1.5}), "invalid $priority: must be an integer")
+	require.EqualError(t, validateStartupProps(map[string]any{DependsOnPropKey: "a"}), "invalid $dependsOn: must be a list of connection ids")
+}
+
+type startupConnection struct {
+	mockConnection
+	mu    *syncx.Mutex
+	order *[]string
+	delay time.Duration
+}
+
+func (o *startupConnection) Dial(_ api.StreamContext) error {
+	time.Sleep(o.delay)
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	*o.order = append(*o.order, o.id)
+	return nil
+}
+
+func TestReloadInOrder(t *testing.T) {
+	require.NoError(t, InitConnectionManager4Test())
+	var (
+		mu    syncx.Mutex
+		order []string
+	)
+	modules.RegisterConnection("ordered", func(_ api.StreamContext) modules.Connection {
+		return &startupConnection{mu: &mu, order: &order}
+	})
+	defer modules.UnregisterConnection("ordered")
+	modules.RegisterConnection("slowordered", func(_ api.StreamContext) modules.Connection {
+		return &startupConnection{mu: &mu, order: &order, delay: 100 * time.Millisecond}
+	})
+	defer modules.UnregisterConnection("slowordered")
+	require.NoError(t, storeConnectionMeta("ordered", "bridge", map[string]any{DependsOnPropKey: []any{"broker"}}))
+	require.NoError(t, storeConnectionMeta("slowordered", "broker", map[string]any{}))
+	require.NoError(t, storeConnectionMeta("ordered", "first", map[string]any{PriorityPropKey: 1}))
+	defer func() {
+		for _, id := range []string{"bridge", "broker", "first"} {
+			_ = DropNameConnectionPermanently(context.Background(), id)
+		}
+	}()
+	require.NoError(t, ReloadNamedConnection())
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(order) == 3
+	}, 2*time.Second, 10*time.Millisecond)
+	require.Equal(t, []string{"first", "broker", "bridge"}, order)
+}
@@ -0,0 +1,106 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+)
+
+// ConnStats are the runtime metrics of a connection to diagnose the flapping connections. The times are unix
+// milliseconds and the durations are milliseconds.
+type ConnStats struct {
+	CreatedAt int64 `json:"createdAt"`
+	// ConnectedAt is the time of the last transition to connected, and Uptime is the time since then if connected
+	ConnectedAt int64 `json:"connectedAt,omitempty"`
+	Uptime      int64 `json:"uptime"`
+	// LastPingAt is the time of the last successful ping of the stateless connection, and PingLatency is its latency
+	LastPingAt  int64 `json:"lastPingAt,omitempty"`
+	PingLatency int64 `json:"pingLatency"`
+	// Reconnects counts the transitions to connected after the first connection
+	Reconnects int64 `json:"reconnects"`
+	// Errors counts the dial and ping failures
+	Errors int64 `json:"errors"`
+	// Retries counts the dial retries by the backoff
+	Retries int64 `json:"retries"`
+	// Throttle are the metrics of the throttle if configured
+	Throttle *ThrottleStats `json:"throttle,omitempty"`
+	// PoolRefs are the references of each physical connection if pooled
+	PoolRefs []int `json:"poolRefs,omitempty"`
+	// Reconnect is the background reconnection if the connection is broken
+	Reconnect *ReconnectStatus `json:"reconnect,omitempty"`
+}
+
+// connStats tracks the runtime metrics of a connection
+type connStats struct {
+	createdAt   atomic.Int64
+	connectedAt atomic.Int64
+	lastPingAt  atomic.Int64
+	pingLatency atomic.Int64
+	reconnects  atomic.Int64
+	errors      atomic.Int64
+	retries     atomic.Int64
+}
+
+func (c *connStats) onCreate() {
+	c.createdAt.CompareAndSwap(0, getClock().Now().UnixMilli())
+}
+
+func (c *connStats) onStatus(prev, status string) {
+	switch status {
+	case api.ConnectionConnected:
+		if prev == api.ConnectionConnected {
+			return
+		}
+		if c.connectedAt.Swap(getClock().Now().UnixMilli()) != 0 {
+			c.reconnects.Add(1)
+		}
+	case api.ConnectionDisconnected:
+		c.errors.Add(1)
+	}
+}
+
+func (c *connStats) onPing(latency time.Duration, err error) {
+	if err != nil {
+		c.errors.Add(1)
+		return
+	}
+	c.lastPingAt.Store(getClock().Now().UnixMilli())
+	c.pingLatency.Store(latency.Milliseconds())
+}
+
+// Stats returns the runtime metrics of the connection
+func (meta *Meta) Stats() ConnStats {
+	s := ConnStats{
+		CreatedAt:   meta.stats.createdAt.Load(),
+		ConnectedAt: meta.stats.connectedAt.Load(),
+		LastPingAt:  meta.stats.lastPingAt.Load(),
+		PingLatency: meta.stats.pingLatency.Load(),
+		Reconnects:  meta.stats.reconnects.Load(),
+		Errors:      meta.stats.errors.Load(),
+		Retries:     meta.stats.retries.Load(),
+	}
+	if meta.cw != nil {
+		s.Throttle = meta.cw.ThrottleStats()
+		s.PoolRefs = meta.poolRefs()
+	}
+	s.Reconnect = GetReconnectStatus(meta.ID)
+	if status, _ := meta.status.Load().(string); status == api.ConnectionConnected && s.ConnectedAt > 0 {
+		s.Uptime = getClock().Now().UnixMilli() - s.ConnectedAt
+	}
+	return s
+}
@@ -0,0 +1,64 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnStats(t *testing.T) {
+	mock := clock.NewMock()
+	SetClock(mock)
+	defer SetClock(nil)
+	meta := &Meta{ID: "stats1"}
+	meta.stats.onCreate()
+	created := mock.Now().UnixMilli()
+
+	mock.Add(time.Second)
+	meta.NotifyStatus(api.ConnectionConnecting, "")
+	meta.NotifyStatus(api.ConnectionConnected, "")
+	meta.NotifyStatus(api.ConnectionConnected, "")
+	connected := mock.Now().UnixMilli()
+	mock.Add(time.Minute)
+	require.Equal(t, ConnStats{
+		CreatedAt:   created,
+		ConnectedAt: connected,
+		Uptime:      time.Minute.Milliseconds(),
+	}, meta.Stats())
+
+	// flapping
+	meta.NotifyStatus(api.ConnectionDisconnected, "lost")
+	s := meta.Stats()
+	require.Equal(t, int64(1), s.Errors)
+	require.Equal(t, int64(0), s.Uptime)
+	mock.Add(time.Second)
+	meta.NotifyStatus(api.ConnectionConnected, "")
+	s = meta.Stats()
+	require.Equal(t, int64(1), s.Reconnects)
+	require.Equal(t, mock.Now().UnixMilli(), s.ConnectedAt)
+
+	meta.stats.onPing(20*time.Millisecond, nil)
+	meta.stats.onPing(time.Second, errors.New("ping failed"))
+	s = meta.Stats()
+	require.Equal(t, mock.Now().UnixMilli(), s.LastPingAt)
+	require.Equal(t, int64(20), s.PingLatency)
+	require.Equal(t, int64(2), s.Errors)
+}
@@ -0,0 +1,131 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	gocontext "context"
+	"sync"
+	"time"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+
+	"github.com/lf-edge/ekuiper/v2/internal/topo/context"
+	"github.com/lf-edge/ekuiper/v2/pkg/modules"
+)
+
+// statusConcurrency is the max count of the concurrent pings to get the status of the connections
+const statusConcurrency = 32
+
+// StatusResult is the status of a connection. Cached means the ping did not finish in time and the last known status
+// is returned.
+type StatusResult struct {
+	Status string `json:"status"`
+	Err    string `json:"err,omitempty"`
+	Cached bool   `json:"cached,omitempty"`
+}
+
+// DriverStatus returns the status reported by the connection if it implements the modules.StatusReporter. The
+// status of the pooled connection is the one of its primary connection.
+func (meta *Meta) DriverStatus() (modules.ConnectionStatus, bool) {
+	if meta.cw == nil || !meta.cw.IsInitialized() {
+		return modules.ConnectionStatus{}, false
+	}
+	ctx := context.Background()
+	conn, err := meta.cw.Wait(ctx)
+	if err != nil || conn == nil {
+		return modules.ConnectionStatus{}, false
+	}
+	r, ok := conn.(modules.StatusReporter)
+	if !ok {
+		return modules.ConnectionStatus{}, false
+	}
+	var st modules.ConnectionStatus
+	if err := safeCall(meta.ID, "status", func() error {
+		st = r.Status(ctx)
+		return nil
+	}); err != nil {
+		return modules.ConnectionStatus{}, false
+	}
+	return st, true
+}
+
+// GetConnectionsStatus gets the status of the connections concurrently. The stateless connections are pinged with the
+// per-ping timeout, and the whole call is bounded by the deadline of ctx, or the operation timeout if ctx has no
+// deadline. The connections whose ping is not finished by the deadline or still running from the previous call report
+// their last known status, so the call is bounded even if the pings hang. The results are in the order of metas.
+func GetConnectionsStatus(ctx gocontext.Context, metas []*Meta) []StatusResult {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel gocontext.CancelFunc
+		ctx, cancel = gocontext.WithTimeout(ctx, time.Duration(GetTuning().OperationTimeout))
+		defer cancel()
+	}
+	results := make([]StatusResult, len(metas))
+	sem := make(chan struct{}, statusConcurrency)
+	var wg sync.WaitGroup
+	for i, meta := range metas {
+		wg.Add(1)
+		go func(i int, meta *Meta) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				results[i] = meta.cachedStatus()
+				return
+			}
+			results[i] = meta.statusWithin(ctx)
+		}(i, meta)
+	}
+	wg.Wait()
+	return results
+}
+
+// statusWithin gets the status and waits for it until ctx is done. Only one ping of the connection runs at a time.
+func (meta *Meta) statusWithin(ctx gocontext.Context) StatusResult {
+	if !meta.pinging.CompareAndSwap(false, true) {
+		return meta.cachedStatus()
+	}
+	ch := make(chan StatusResult, 1)
+	go func() {
+		defer meta.pinging.Store(false)
+		s, e := meta.GetStatus()
+		ch <- StatusResult{Status: s, Err: e}
+	}()
+	select {
+	case r := <-ch:
+		return r
+	case <-ctx.Done():
+		return meta.cachedStatus()
+	}
+}
+
+// cachedStatus returns the last known status without pinging. The last ping result is used if the connection is
+// still connected, otherwise the status changed after the ping.
+func (meta *Meta) cachedStatus() StatusResult {
+	r := StatusResult{Status: api.ConnectionConnecting, Cached: true}
+	if s, ok := meta.status.Load().(string); ok {
+		r.Status = s
+	}
+	if r.Status == api.ConnectionConnected {
+		if p := meta.lastPing.Load(); p != nil {
+			r.Status, r.Err = p.Status, p.Err
+		}
+		return r
+	}
+	if e, ok := meta.lastError.Load().(string); ok {
+		r.Err = e
+	}
+	return r
+}
@@ -0,0 +1,97 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	gocontext "context"
+	"testing"
+	"time"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/topo/context"
+	"github.com/lf-edge/ekuiper/v2/pkg/modules"
+)
+
+var hangPingCh = make(chan struct{})
+
+// hangPingConnection hangs in ping regardless of the context until released
+type hangPingConnection struct {
+	mockConnection
+}
+
+func (h *hangPingConnection) Ping(_ api.StreamContext) error {
+	<-hangPingCh
+	return nil
+}
+
+func TestGetConnectionsStatusBounded(t *testing.T) {
+	require.NoError(t, InitConnectionManager4Test())
+	modules.RegisterConnection("hangping", func(ctx api.StreamContext) modules.Connection {
+		return &hangPingConnection{}
+	})
+	ctx := context.Background()
+	ids := []string{"hang1", "hang2", "hang3", "status1"}
+	for _, id := range ids {
+		typ := "hangping"
+		if id == "status1" {
+			typ = "mock"
+		}
+		_, err := CreateNamedConnection(ctx, id, typ, map[string]any{})
+		require.NoError(t, err)
+	}
+	defer func() {
+		for _, id := range ids {
+			require.NoError(t, DropNameConnectionPermanently(ctx, id))
+		}
+	}()
+	metas := make([]*Meta, 0, len(ids))
+	for _, id := range ids {
+		meta, err := GetConnectionDetail(ctx, id)
+		require.NoError(t, err)
+		require.Eventually(t, func() bool {
+			s, _ := meta.status.Load().(string)
+			return s == api.ConnectionConnected
+		}, time.Second, 10*time.Millisecond)
+		metas = append(metas, meta)
+	}
+
+	dctx, cancel := gocontext.WithTimeout(gocontext.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	results := GetConnectionsStatus(dctx, metas)
+	require.Less(t, time.Since(start), time.Second)
+	require.Len(t, results, 4)
+	for i := 0; i < 3; i++ {
+		require.Equal(t, StatusResult{Status: api.ConnectionConnected, Cached: true}, results[i])
+	}
+	require.Equal(t, StatusResult{Status: api.ConnectionConnected}, results[3])
+
+	// the hanging pings are not started again
+	dctx2, cancel2 := gocontext.WithTimeout(gocontext.Background(), time.Second)
+	defer cancel2()
+	start = time.Now()
+	results = GetConnectionsStatus(dctx2, metas[:3])
+	require.Less(t, time.Since(start), 500*time.Millisecond)
+	require.True(t, results[0].Cached)
+
+	close(hangPingCh)
+	require.Eventually(t, func() bool {
+		return !metas[0].pinging.Load() && !metas[1].pinging.Load() && !metas[2].pinging.Load()
+	}, time.Second, 10*time.Millisecond)
+	results = GetConnectionsStatus(gocontext.Background(), metas[:1])
+	require.Equal(t, StatusResult{Status: api.ConnectionConnected}, results[0])
+}
@@ -0,0 +1,221 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/pkg/syncx"
+)
+
+// connCfgType is the config type of the named connections
+const connCfgType = "connections"
+
+// ConnectionStore persists the named connection definitions. The secret props are already encrypted when put.
+type ConnectionStore interface {
+	Put(typ, id string, props map[string]any) error
+	Delete(typ, id string) error
+	List() ([]StoredConnection, error)
+}
+
+type StoredConnection struct {
+	ID    string
+	Typ   string
+	Props map[string]any
+}
+
+type ConnectionStoreBuilder func() (ConnectionStore, error)
+
+var (
+	storeMu            syncx.Mutex
+	storeBuilders      = map[string]ConnectionStoreBuilder{}
+	connStore          ConnectionStore
+	connStoreType      string
+	connStoreAvailable bool
+)
+
+// RegisterConnectionStore registers the builder of a connection store backend. The config store backends like
+// redis and etcd are available without registration.
+func RegisterConnectionStore(typ string, builder ConnectionStoreBuilder) {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+	storeBuilders[typ] = builder
+}
+
+// getConnectionStore returns the store of the configured type. It is opened once and reopened if the type changes.
+func getConnectionStore() (ConnectionStore, error) {
+	typ := ""
+	if conf.Config != nil {
+		typ = conf.Config.Connection.StoreType
+	}
+	storeMu.Lock()
+	defer storeMu.Unlock()
+	if connStoreAvailable && connStoreType == typ {
+		return connStore, nil
+	}
+	s, err := newConnectionStore(typ)
+	if err != nil {
+		return nil, err
+	}
+	connStore, connStoreType, connStoreAvailable = s, typ, true
+	return s, nil
+}
+
+func newConnectionStore(typ string) (ConnectionStore, error) {
+	if typ == "" {
+		return kvConnectionStore{}, nil
+	}
+	if builder, ok := storeBuilders[typ]; ok {
+		return builder()
+	}
+	cs, err := conf.OpenConfigStore(typ)
+	if err != nil {
+		return nil, fmt.Errorf("open connection store %s failed: %v", typ, err)
+	}
+	return &configConnectionStore{cs: cs}, nil
+}
+
+// isDefaultStore tells whether the connections are saved in the config store of the node, which is shared with
+// the trash and watched by the config watcher
+func isDefaultStore(s ConnectionStore) bool {
+	_, ok := s.(kvConnectionStore)
+	return ok
+}
+
+// kvConnectionStore saves the connections in the config store of the node
+type kvConnectionStore struct{}
+
+func (kvConnectionStore) Put(typ, id string, props map[string]any) error {
+	return conf.WriteCfgIntoKVStorage(connCfgType, typ, id, props)
+}
+
+func (kvConnectionStore) Delete(typ, id string) error {
+	return conf.DropCfgKeyFromStorage(connCfgType, typ, id)
+}
+
+func (kvConnectionStore) List() ([]StoredConnection, error) {
+	cfgs, err := conf.GetCfgFromKVStorage(connCfgType, "", "")
+	if err != nil {
+		return nil, err
+	}
+	return toStoredConnections(cfgs), nil
+}
+
+// configConnectionStore saves the connections in a dedicated config store with the same keys as the node store
+type configConnectionStore struct {
+	cs conf.ConfigStore
+}
+
+func (s *configConnectionStore) Put(typ, id string, props map[string]any) error {
+	return s.cs.Set(connKey(typ, id), props)
+}
+
+func (s *configConnectionStore) Delete(typ, id string) error {
+	return s.cs.Delete(connKey(typ, id))
+}
+
+func (s *configConnectionStore) List() ([]StoredConnection, error) {
+	cfgs, err := s.cs.GetByPrefix(connCfgType + ".")
+	if err != nil {
+		return nil, err
+	}
+	return toStoredConnections(cfgs), nil
+}
+
+func connKey(typ, id string) string {
+	return connCfgType + "." + typ + "." + id
+}
+
+func toStoredConnections(cfgs map[string]map[string]any) []StoredConnection {
+	result := make([]StoredConnection, 0, len(cfgs))
+	for key, props := range cfgs {
+		names := strings.Split(key, ".")
+		if len(names) != 3 || names[0] != connCfgType {
+			continue
+		}
+		result = append(result, StoredConnection{ID: names[2], Typ: names[1], Props: props})
+	}
+	return result
+}
+
+// watchConnectionStore polls the custom store by the interval and emits the changes like the config watcher
+func watchConnectionStore(ctx context.Context, s ConnectionStore, interval time.Duration) (<-chan conf.ConfigEvent, error) {
+	snapshot, err := storeSnapshot(s)
+	if err != nil {
+		return nil, err
+	}
+	ch := make(chan conf.ConfigEvent)
+	go func() {
+		defer close(ch)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			current, err := storeSnapshot(s)
+			if err != nil {
+				conf.Log.Warnf("watch connection store error: %v", err)
+				continue
+			}
+			for _, ev := range diffSnapshot(snapshot, current) {
+				select {
+				case ch <- ev:
+				case <-ctx.Done():
+					return
+				}
+			}
+			snapshot = current
+		}
+	}()
+	return ch, nil
+}
+
+func storeSnapshot(s ConnectionStore) (map[string]map[string]any, error) {
+	conns, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]map[string]any, len(conns))
+	for _, c := range conns {
+		result[connKey(c.Typ, c.ID)] = c.Props
+	}
+	return result, nil
+}
+
+func diffSnapshot(old, current map[string]map[string]any) []conf.ConfigEvent {
+	var events []conf.ConfigEvent
+	for key, props := range current {
+		oldProps, ok := old[key]
+		if !ok {
+			events = append(events, conf.ConfigEvent{Type: conf.ConfigEventAdd, Key: key, Props: props})
+		} else if !reflect.DeepEqual(oldProps, props) {
+			events = append(events, conf.ConfigEvent{Type: conf.ConfigEventUpdate, Key: key, Props: props})
+		}
+	}
+	for key := range old {
+		if _, ok := current[key]; !ok {
+			events = append(events, conf.ConfigEvent{Type: conf.ConfigEventDelete, Key: key})
+		}
+	}
+	return events
+}
@@ -0,0 +1,124 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/context"
+	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+	"github.com/lf-edge/ekuiper/v2/pkg/syncx"
+)
+
+type memConnectionStore struct {
+	syncx.Mutex
+	conns map[string]StoredConnection
+}
+
+func (m *memConnectionStore) Put(typ, id string, props map[string]any) error {
+	m.Lock()
+	defer m.Unlock()
+	m.conns[id] = StoredConnection{ID: id, Typ: typ, Props: props}
+	return nil
+}
+
+func (m *memConnectionStore) Delete(_, id string) error {
+	m.Lock()
+	defer m.Unlock()
+	delete(m.conns, id)
+	return nil
+}
+
+func (m *memConnectionStore) List() ([]StoredConnection, error) {
+	m.Lock()
+	defer m.Unlock()
+	result := make([]StoredConnection, 0, len(m.conns))
+	for _, c := range m.conns {
+		result = append(result, c)
+	}
+	return result, nil
+}
+
+func (m *memConnectionStore) has(id string) bool {
+	m.Lock()
+	defer m.Unlock()
+	_, ok := m.conns[id]
+	return ok
+}
+
+func TestCustomConnectionStore(t *testing.T) {
+	conf.InitConf()
+	origin, originTTL := conf.Config.Connection.StoreType, conf.Config.Connection.TrashTTL
+	defer func() {
+		conf.Config.Connection.StoreType = origin
+		conf.Config.Connection.TrashTTL = originTTL
+	}()
+	conf.Config.Connection.TrashTTL = cast.DurationConf(time.Hour)
+	store := &memConnectionStore{conns: map[string]StoredConnection{}}
+	RegisterConnectionStore("memtest", func() (ConnectionStore, error) {
+		return store, nil
+	})
+	conf.Config.Connection.StoreType = "memtest"
+	require.NoError(t, InitConnectionManager4Test())
+	ctx := context.Background()
+
+	_, err := CreateNamedConnection(ctx, "store1", "mock", map[string]any{"a": 1})
+	require.NoError(t, err)
+	require.True(t, store.has("store1"))
+	// not saved in the config store of the node
+	cfgs, err := conf.GetCfgFromKVStorage(connCfgType, "mock", "store1")
+	require.NoError(t, err)
+	require.Empty(t, cfgs)
+
+	require.NoError(t, DropNameConnection(ctx, "store1"))
+	require.False(t, store.has("store1"))
+	_, err = RestoreConnection(ctx, "store1")
+	require.NoError(t, err)
+	require.True(t, store.has("store1"))
+	require.NoError(t, DropNameConnectionPermanently(ctx, "store1"))
+
+	// shared by another node
+	require.NoError(t, store.Put("mock", "store2", map[string]any{"a": 2}))
+	require.NoError(t, ReloadNamedConnection())
+	meta, err := GetConnectionDetail(ctx, "store2")
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"a": 2}, meta.Props)
+	require.NoError(t, DropNameConnectionPermanently(ctx, "store2"))
+}
+
+func TestDiffSnapshot(t *testing.T) {
+	old := map[string]map[string]any{
+		"connections.mock.a": {"v": 1},
+		"connections.mock.b": {"v": 1},
+	}
+	current := map[string]map[string]any{
+		"connections.mock.a": {"v": 2},
+		"connections.mock.c": {"v": 1},
+	}
+	events := diffSnapshot(old, current)
+	byKey := make(map[string]conf.ConfigEventType, len(events))
+	for _, ev := range events {
+		byKey[ev.Key] = ev.Type
+	}
+	require.Equal(t, map[string]conf.ConfigEventType{
+		"connections.mock.a": conf.ConfigEventUpdate,
+		"connections.mock.b": conf.ConfigEventDelete,
+		"connections.mock.c": conf.ConfigEventAdd,
+	}, byKey)
+}
@@ -0,0 +1,106 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"time"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/pkg/syncx"
+)
+
+// ConnectionDropped is the status of the connection event when the connection is removed from the pool
+const ConnectionDropped = "dropped"
+
+const statusSubBuffer = 64
+
+// StatusEvent is a status transition of a connection. The status is one of connected, connecting, disconnected and
+// dropped.
+type StatusEvent struct {
+	ID        string    `json:"id"`
+	Status    string    `json:"status"`
+	Prev      string    `json:"prev,omitempty"`
+	LastError string    `json:"lastError,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+	// meta is the connection of the event
+	meta *Meta
+}
+
+type statusSub struct {
+	// connId is empty to subscribe all the connections
+	connId string
+	ch     chan StatusEvent
+}
+
+type statusHub struct {
+	mu   syncx.Mutex
+	next int
+	subs map[int]*statusSub
+}
+
+var statusSubs = &statusHub{subs: make(map[int]*statusSub)}
+
+// SubscribeConnectionStatus returns the channel of the status transitions of the connection and the func to
+// unsubscribe. The connection does not need to exist, and the subscription outlives its drop and recreation. The
+// channel is closed if the subscriber is too slow to consume.
+func SubscribeConnectionStatus(id string) (<-chan StatusEvent, func()) {
+	return subscribeStatus(id)
+}
+
+// SubscribeAll returns the channel of the status transitions of all the connections and the func to unsubscribe
+func SubscribeAll() (<-chan StatusEvent, func()) {
+	return subscribeStatus("")
+}
+
+func subscribeStatus(connId string) (<-chan StatusEvent, func()) {
+	ch := make(chan StatusEvent, statusSubBuffer)
+	statusSubs.mu.Lock()
+	id := statusSubs.next
+	statusSubs.next++
+	statusSubs.subs[id] = &statusSub{connId: connId, ch: ch}
+	statusSubs.mu.Unlock()
+	return ch, func() {
+		statusSubs.mu.Lock()
+		defer statusSubs.mu.Unlock()
+		if s, ok := statusSubs.subs[id]; ok {
+			delete(statusSubs.subs, id)
+			close(s.ch)
+		}
+	}
+}
+
+// emitStatus delivers the transition to the subscribers without blocking. It only takes the leaf lock of the hub, so
+// it can be called with the pool lock.
+func emitStatus(meta *Meta, prev, status, lastError string) {
+	if prev == status {
+		return
+	}
+	connId := meta.ID
+	ev := StatusEvent{ID: connId, Status: status, Prev: prev, LastError: lastError, Timestamp: getClock().Now(), meta: meta}
+	statusSubs.mu.Lock()
+	defer statusSubs.mu.Unlock()
+	for id, s := range statusSubs.subs {
+		if s.connId != "" && s.connId != connId {
+			continue
+		}
+		select {
+		case s.ch <- ev:
+		default:
+			conf.Log.Warnf("connection status subscriber %d is too slow, drop it", id)
+			delete(statusSubs.subs, id)
+			close(s.ch)
+		}
+	}
+}
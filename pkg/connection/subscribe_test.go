@@ -0,0 +1,91 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+	"github.com/stretchr/testify/require"
+
+	mockContext "github.com/lf-edge/ekuiper/v2/pkg/mock/context"
+)
+
+// recvStatus receives the next event of the connection, skipping the connections left by the other tests
+func recvStatus(t *testing.T, ch <-chan StatusEvent, id string) StatusEvent {
+	for {
+		select {
+		case ev, ok := <-ch:
+			require.True(t, ok)
+			if ev.ID == id {
+				return ev
+			}
+		case <-time.After(5 * time.Second):
+			require.Fail(t, "no status event received")
+			return StatusEvent{}
+		}
+	}
+}
+
+func TestSubscribeConnectionStatus(t *testing.T) {
+	require.NoError(t, InitConnectionManager4Test())
+	ctx := mockContext.NewMockContext("id", "2")
+	all, unsubAll := SubscribeAll()
+	defer unsubAll()
+	one, unsubOne := SubscribeConnectionStatus("sub1")
+	other, unsubOther := SubscribeConnectionStatus("sub2")
+
+	cw, err := CreateNamedConnection(ctx, "sub1", "mock", nil)
+	require.NoError(t, err)
+	_, err = cw.Wait(ctx)
+	require.NoError(t, err)
+	require.NoError(t, DropNameConnection(ctx, "sub1"))
+
+	for _, ch := range []<-chan StatusEvent{all, one} {
+		ev := recvStatus(t, ch, "sub1")
+		require.Equal(t, "sub1", ev.ID)
+		require.Equal(t, api.ConnectionConnecting, ev.Status)
+		require.Equal(t, "", ev.Prev)
+		ev = recvStatus(t, ch, "sub1")
+		require.Equal(t, api.ConnectionConnected, ev.Status)
+		require.Equal(t, api.ConnectionConnecting, ev.Prev)
+		ev = recvStatus(t, ch, "sub1")
+		require.Equal(t, ConnectionDropped, ev.Status)
+		require.Equal(t, api.ConnectionConnected, ev.Prev)
+	}
+	require.Len(t, other, 0)
+	unsubOther()
+	_, ok := <-other
+	require.False(t, ok)
+	// unsubscribe twice is harmless
+	unsubOther()
+
+	// the same status is not a transition
+	emitStatus(&Meta{ID: "sub1"}, api.ConnectionConnected, api.ConnectionConnected, "")
+	require.Len(t, one, 0)
+
+	// the slow subscriber is dropped
+	for i := 0; i <= statusSubBuffer; i++ {
+		emitStatus(&Meta{ID: "sub1"}, api.ConnectionConnecting, api.ConnectionDisconnected, fmt.Sprintf("err%d", i))
+	}
+	for range statusSubBuffer {
+		<-one
+	}
+	_, ok = <-one
+	require.False(t, ok)
+	unsubOne()
+}
@@ -0,0 +1,87 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/pkg/tracer"
+)
+
+// superviseRestartDelay is the delay to restart a supervised loop after panic, so that a panic on every run does
+// not spin
+var superviseRestartDelay = time.Second
+
+// PanicError is the recovered panic of a goroutine owned by the connection pool
+type PanicError struct {
+	Op    string
+	Value any
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic in connection %s: %v", e.Op, e.Value)
+}
+
+// safeCall runs the driver function and converts its panic to an error. The panic is logged with the stack and
+// recorded as an error span. The caller decides how the connection status changes.
+func safeCall(id, op string, fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{Op: op, Value: r}
+			recordPanic(id, op, err, debug.Stack())
+		}
+	}()
+	return fn()
+}
+
+func recordPanic(id, op string, err error, stack []byte) {
+	if id == "" {
+		conf.Log.Errorf("%v, stack: %s", err, stack)
+	} else {
+		connLogger(id).Errorf("%v, stack: %s", err, stack)
+	}
+	_, span := tracer.GetTracer().Start(context.Background(), "connection_panic")
+	span.SetAttributes(attribute.String("connection", id), attribute.String("op", op))
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+	span.End()
+}
+
+// supervise runs the loop until it returns normally or the context is done. It is restarted after panic so that
+// a driver bug can't stop the loop for all connections.
+func supervise(ctx context.Context, name string, loop func(ctx context.Context)) {
+	for {
+		err := safeCall("", name, func() error {
+			loop(ctx)
+			return nil
+		})
+		if err == nil {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-getClock().After(superviseRestartDelay):
+			conf.Log.Warnf("restart connection %s loop after panic", name)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	gocontext "context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/topo/context"
+	"github.com/lf-edge/ekuiper/v2/pkg/modules"
+)
+
+// panicConnection panics on the first dial and on every ping
+type panicConnection struct {
+	mockConnection
+	dials atomic.Int32
+}
+
+func (p *panicConnection) Dial(_ api.StreamContext) error {
+	if p.dials.Add(1) == 1 {
+		panic("dial bug")
+	}
+	return nil
+}
+
+func (p *panicConnection) Ping(_ api.StreamContext) error {
+	panic("ping bug")
+}
+
+func TestSafeCall(t *testing.T) {
+	err := safeCall("c1", "dial", func() error {
+		panic("bug")
+	})
+	var pe *PanicError
+	require.True(t, errors.As(err, &pe))
+	require.Equal(t, "dial", pe.Op)
+	require.Equal(t, "panic in connection dial: bug", err.Error())
+	e := errors.New("normal")
+	require.Equal(t, e, safeCall("c1", "dial", func() error {
+		return e
+	}))
+}
+
+func TestSupervise(t *testing.T) {
+	origin := superviseRestartDelay
+	superviseRestartDelay = time.Millisecond
+	defer func() {
+		superviseRestartDelay = origin
+	}()
+	var runs atomic.Int32
+	supervise(gocontext.Background(), "test", func(_ gocontext.Context) {
+		if runs.Add(1) < 3 {
+			panic("loop bug")
+		}
+	})
+	require.Equal(t, int32(3), runs.Load())
+
+	// not restarted after the context is done
+	ctx, cancel := gocontext.WithCancel(gocontext.Background())
+	runs.Store(0)
+	supervise(ctx, "test", func(_ gocontext.Context) {
+		runs.Add(1)
+		cancel()
+		panic("loop bug")
+	})
+	require.Equal(t, int32(1), runs.Load())
+}
+
+func TestConnectionPanic(t *testing.T) {
+	require.NoError(t, InitConnectionManager4Test())
+	modules.RegisterConnection("panicconn", func(_ api.StreamContext) modules.Connection {
+		return &panicConnection{}
+	})
+	ctx := context.Background()
+	var handled atomic.Int32
+	meta := &Meta{ID: "p1"}
+	meta.ref.Store("bad", api.StatusChangeHandler(func(string, string) {
+		panic("handler bug")
+	}))
+	meta.ref.Store("good", api.StatusChangeHandler(func(string, string) {
+		handled.Add(1)
+	}))
+	meta.NotifyStatus(api.ConnectionConnected, "")
+	require.Equal(t, int32(1), handled.Load())
+
+	// the panic dial is retried
+	_, err := CreateNamedConnection(ctx, "p1", "panicconn", map[string]any{})
+	require.NoError(t, err)
+	m, err := GetConnectionDetail(ctx, "p1")
+	require.NoError(t, err)
+	conn, err := m.cw.Wait(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int32(2), conn.(*panicConnection).dials.Load())
+	// the panic ping reports the connection failed
+	status, e := m.GetStatus()
+	require.Equal(t, api.ConnectionDisconnected, status)
+	require.Equal(t, "panic in connection ping: ping bug", e)
+	require.NoError(t, DropNameConnection(ctx, "p1"))
+}
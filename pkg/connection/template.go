@@ -0,0 +1,338 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+	"github.com/lf-edge/ekuiper/v2/pkg/modules"
+	"github.com/lf-edge/ekuiper/v2/pkg/syncx"
+)
+
+// The templates remove the duplication of the connections which differ only by a few props. A template has the base
+// props with the placeholders like ${clientId}, and the named connections are instantiated from it by the values of
+// the placeholders. The derived connections record the template and the values in the reserved prop $template, so
+// that the changes of the template can be propagated to them.
+
+// templateCfgType is the config type of the connection templates. It must not have the prefix "connections"
+// so that the templates are not loaded as connections.
+const templateCfgType = "connTemplate"
+
+// TemplatePropKey is the reserved prop of the derived connection, which is {"id": templateId, "params": values}
+const TemplatePropKey = "$template"
+
+var placeholderRegex = regexp.MustCompile(`\$\{([A-Za-z0-9_]+)}`)
+
+// ConnectionTemplate is the type and the base props with placeholders to instantiate the named connections
+type ConnectionTemplate struct {
+	ID    string         `json:"id"`
+	Typ   string         `json:"typ"`
+	Props map[string]any `json:"props"`
+	// Params are the placeholders in the props
+	Params []string `json:"params,omitempty"`
+}
+
+// TemplatePropagation is the result of propagating the template changes to the derived connections
+type TemplatePropagation struct {
+	Updated []string          `json:"updated"`
+	Failed  map[string]string `json:"failed,omitempty"`
+}
+
+var templateLock syncx.Mutex
+
+func loadTemplates() ([]*ConnectionTemplate, error) {
+	cfgs, err := conf.GetCfgFromKVStorage(templateCfgType, "", "")
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*ConnectionTemplate, 0, len(cfgs))
+	for key, v := range cfgs {
+		names := strings.Split(key, ".")
+		if len(names) != 3 {
+			continue
+		}
+		props, err := decryptSecrets(v)
+		if err != nil {
+			conf.Log.Warnf("invalid connection template %s: %v", key, err)
+			continue
+		}
+		result = append(result, &ConnectionTemplate{ID: names[2], Typ: names[1], Props: props, Params: templateParams(props)})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID < result[j].ID
+	})
+	return result, nil
+}
+
+func findTemplate(id string) (*ConnectionTemplate, error) {
+	all, err := loadTemplates()
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range all {
+		if t.ID == id {
+			return t, nil
+		}
+	}
+	return nil, errorx.NewWithCode(errorx.NOT_FOUND, fmt.Sprintf("connection template %s not existed", id))
+}
+
+func storeTemplate(t *ConnectionTemplate) error {
+	props, err := encryptSecrets(t.Props)
+	if err != nil {
+		return err
+	}
+	return conf.WriteCfgIntoKVStorage(templateCfgType, t.Typ, t.ID, props)
+}
+
+// ListConnectionTemplates returns all the connection templates ordered by id
+func ListConnectionTemplates() ([]*ConnectionTemplate, error) {
+	return loadTemplates()
+}
+
+// GetConnectionTemplate returns the connection template
+func GetConnectionTemplate(id string) (*ConnectionTemplate, error) {
+	return findTemplate(id)
+}
+
+// CreateConnectionTemplate stores a new connection template
+func CreateConnectionTemplate(t *ConnectionTemplate) error {
+	if t.ID == "" || t.Typ == "" {
+		return fmt.Errorf("connection template id and type should be defined")
+	}
+	if _, ok := modules.GetConnectionProvider(strings.ToLower(t.Typ)); !ok {
+		return errorx.NewWithCode(errorx.ConnectionInvalidErr, fmt.Sprintf("unknown connection type %s", t.Typ))
+	}
+	templateLock.Lock()
+	defer templateLock.Unlock()
+	if _, err := findTemplate(t.ID); err == nil {
+		return errorx.NewWithCode(errorx.ConnectionExistErr, fmt.Sprintf("connection template %s already been created", t.ID))
+	}
+	t.Params = templateParams(t.Props)
+	return storeTemplate(t)
+}
+
+// UpdateConnectionTemplate replaces the props of the template. If propagate is true, the derived connections are
+// updated with the new props and their own values. The failures of the derived connections do not fail the update
+// and are reported in the result.
+func UpdateConnectionTemplate(ctx api.StreamContext, id string, props map[string]any, propagate bool) (*TemplatePropagation, error) {
+	templateLock.Lock()
+	defer templateLock.Unlock()
+	t, err := findTemplate(id)
+	if err != nil {
+		return nil, err
+	}
+	t.Props = restoreSecrets(props, t.Props)
+	t.Params = templateParams(t.Props)
+	if err := storeTemplate(t); err != nil {
+		return nil, err
+	}
+	result := &TemplatePropagation{Updated: []string{}}
+	if !propagate {
+		return result, nil
+	}
+	for _, meta := range derivedConnections(id) {
+		if err := propagateTemplate(ctx, t, meta); err != nil {
+			if result.Failed == nil {
+				result.Failed = make(map[string]string)
+			}
+			result.Failed[meta.ID] = err.Error()
+			connLogger(meta.ID).Warnf("propagate connection template %s to %s failed: %v", id, meta.ID, err)
+			continue
+		}
+		result.Updated = append(result.Updated, meta.ID)
+	}
+	return result, nil
+}
+
+func propagateTemplate(ctx api.StreamContext, t *ConnectionTemplate, meta *Meta) error {
+	_, params := templateOf(meta.Props)
+	props, err := renderTemplate(t, params)
+	if err != nil {
+		return err
+	}
+	// the labels are managed per connection
+	if labels, ok := meta.Props[LabelsPropKey]; ok {
+		props[LabelsPropKey] = labels
+	}
+	if !strings.EqualFold(meta.Typ, t.Typ) {
+		_, err = UpdateConnection(ctx, meta.ID, t.Typ, props)
+		return err
+	}
+	_, err = UpdateNamedConnection(ctx, meta.ID, props)
+	return err
+}
+
+// DropConnectionTemplate deletes the template. It fails if any connection is derived from it.
+func DropConnectionTemplate(id string) error {
+	templateLock.Lock()
+	defer templateLock.Unlock()
+	t, err := findTemplate(id)
+	if err != nil {
+		return err
+	}
+	if derived := derivedConnections(id); len(derived) > 0 {
+		ids := make([]string, 0, len(derived))
+		for _, meta := range derived {
+			ids = append(ids, meta.ID)
+		}
+		return errorx.NewWithCode(errorx.ConnectionInUseErr, fmt.Sprintf("connection template %s can't be dropped due to derived connections %v", id, ids))
+	}
+	return conf.DropCfgKeyFromStorage(templateCfgType, t.Typ, t.ID)
+}
+
+// InstantiateConnectionTemplate creates the named connection from the template with the values of the placeholders
+func InstantiateConnectionTemplate(ctx api.StreamContext, templateId, id string, params map[string]any) (*ConnWrapper, error) {
+	t, err := findTemplate(templateId)
+	if err != nil {
+		return nil, err
+	}
+	props, err := renderTemplate(t, params)
+	if err != nil {
+		return nil, err
+	}
+	return CreateNamedConnection(ctx, id, t.Typ, props)
+}
+
+// renderTemplate replaces the placeholders of the template props and records the template in the props
+func renderTemplate(t *ConnectionTemplate, params map[string]any) (map[string]any, error) {
+	var missing []string
+	for _, p := range templateParams(t.Props) {
+		if _, ok := params[p]; !ok {
+			missing = append(missing, p)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("missing the values of the template params %v", missing)
+	}
+	props := renderValue(t.Props, params).(map[string]any)
+	record := map[string]any{"id": t.ID}
+	if len(params) > 0 {
+		record["params"] = params
+	}
+	props[TemplatePropKey] = record
+	return props, nil
+}
+
+// renderValue replaces the placeholders recursively. The value which is a placeholder only is replaced by the param
+// value in its own type, so that the numbers and the booleans can be parameterized too.
+func renderValue(v any, params map[string]any) any {
+	switch vv := v.(type) {
+	case string:
+		if m := placeholderRegex.FindStringSubmatch(vv); m != nil && m[0] == vv {
+			return params[m[1]]
+		}
+		return placeholderRegex.ReplaceAllStringFunc(vv, func(s string) string {
+			return fmt.Sprint(params[s[2:len(s)-1]])
+		})
+	case map[string]any:
+		result := make(map[string]any, len(vv))
+		for k, e := range vv {
+			result[k] = renderValue(e, params)
+		}
+		return result
+	case []any:
+		result := make([]any, len(vv))
+		for i, e := range vv {
+			result[i] = renderValue(e, params)
+		}
+		return result
+	default:
+		return v
+	}
+}
+
+// templateParams returns the sorted placeholder names in the props
+func templateParams(props map[string]any) []string {
+	set := make(map[string]struct{})
+	var walk func(v any)
+	walk = func(v any) {
+		switch vv := v.(type) {
+		case string:
+			for _, m := range placeholderRegex.FindAllStringSubmatch(vv, -1) {
+				set[m[1]] = struct{}{}
+			}
+		case map[string]any:
+			for _, e := range vv {
+				walk(e)
+			}
+		case []any:
+			for _, e := range vv {
+				walk(e)
+			}
+		}
+	}
+	walk(props)
+	result := make([]string, 0, len(set))
+	for p := range set {
+		result = append(result, p)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// templateOf returns the template id and the values of the derived connection props
+func templateOf(props map[string]any) (string, map[string]any) {
+	record, ok := props[TemplatePropKey].(map[string]any)
+	if !ok {
+		return "", nil
+	}
+	id, _ := record["id"].(string)
+	params, _ := record["params"].(map[string]any)
+	return id, params
+}
+
+// derivedConnections returns the named connections derived from the template ordered by id
+func derivedConnections(templateId string) []*Meta {
+	var result []*Meta
+	for _, meta := range GetAllConnectionsMeta(false) {
+		if id, _ := templateOf(meta.Props); id == templateId {
+			result = append(result, meta)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID < result[j].ID
+	})
+	return result
+}
+
+// validateTemplateRecord checks the reserved prop of the derived connection
+func validateTemplateRecord(props map[string]any) error {
+	v, ok := props[TemplatePropKey]
+	if !ok || v == nil {
+		return nil
+	}
+	record, ok := v.(map[string]any)
+	if !ok {
+		return fmt.Errorf("invalid %s: must be props", TemplatePropKey)
+	}
+	if id, ok := record["id"].(string); !ok || id == "" {
+		return fmt.Errorf("invalid %s: the template id should be defined", TemplatePropKey)
+	}
+	if p, ok := record["params"]; ok && p != nil {
+		if _, err := cast.ToStringMap(p); err != nil {
+			return fmt.Errorf("invalid %s: the params must be props", TemplatePropKey)
+		}
+	}
+	return nil
+}
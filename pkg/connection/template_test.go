@@ -0,0 +1,102 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/topo/context"
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	tpl := &ConnectionTemplate{ID: "tpl", Typ: "mock", Props: map[string]any{
+		"server":   "tcp://${host}:1883",
+		"clientId": "${site}_${line}",
+		"qos":      "${qos}",
+		"topics":   []any{"${site}/data"},
+		"nested":   map[string]any{"a": "${site}"},
+	}}
+	require.Equal(t, []string{"host", "line", "qos", "site"}, templateParams(tpl.Props))
+	_, err := renderTemplate(tpl, map[string]any{"host": "h"})
+	require.EqualError(t, err, "missing the values of the template params [line qos site]")
+	props, err := renderTemplate(tpl, map[string]any{"host": "h", "site": "p1", "line": 2, "qos": 1})
+	require.NoError(t, err)
+	require.Equal(t, "tcp://h:1883", props["server"])
+	require.Equal(t, "p1_2", props["clientId"])
+	require.Equal(t, 1, props["qos"])
+	require.Equal(t, []any{"p1/data"}, props["topics"])
+	require.Equal(t, map[string]any{"a": "p1"}, props["nested"])
+	id, params := templateOf(props)
+	require.Equal(t, "tpl", id)
+	require.Equal(t, 2, params["line"])
+	// the template props are not changed
+	require.Equal(t, "${site}_${line}", tpl.Props["clientId"])
+}
+
+func TestConnectionTemplate(t *testing.T) {
+	require.NoError(t, InitConnectionManager4Test())
+	ctx := context.Background()
+	require.NoError(t, CreateConnectionTemplate(&ConnectionTemplate{ID: "tpl1", Typ: "mock", Props: map[string]any{"clientId": "${site}_client"}}))
+	defer func() {
+		require.NoError(t, DropConnectionTemplate("tpl1"))
+	}()
+	err := CreateConnectionTemplate(&ConnectionTemplate{ID: "tpl1", Typ: "mock"})
+	code, _ := errorx.GetErrorCode(err)
+	require.Equal(t, errorx.ConnectionExistErr, code)
+	require.Error(t, CreateConnectionTemplate(&ConnectionTemplate{ID: "tpl2", Typ: "nonexist"}))
+	tpl, err := GetConnectionTemplate("tpl1")
+	require.NoError(t, err)
+	require.Equal(t, []string{"site"}, tpl.Params)
+
+	_, err = InstantiateConnectionTemplate(ctx, "tpl1", "tplconn1", map[string]any{"site": "p1"})
+	require.NoError(t, err)
+	_, err = InstantiateConnectionTemplate(ctx, "tpl1", "tplconn2", map[string]any{"site": "p2"})
+	require.NoError(t, err)
+	_, err = InstantiateConnectionTemplate(ctx, "tpl1", "tplconn3", nil)
+	require.Error(t, err)
+	require.NoError(t, SetConnectionLabels(ctx, "tplconn2", map[string]string{"site": "p2"}))
+	meta, err := GetConnectionDetail(ctx, "tplconn1")
+	require.NoError(t, err)
+	require.Equal(t, "p1_client", meta.Props["clientId"])
+
+	err = DropConnectionTemplate("tpl1")
+	code, _ = errorx.GetErrorCode(err)
+	require.Equal(t, errorx.ConnectionInUseErr, code)
+
+	// not propagated
+	result, err := UpdateConnectionTemplate(ctx, "tpl1", map[string]any{"clientId": "${site}_c"}, false)
+	require.NoError(t, err)
+	require.Empty(t, result.Updated)
+	meta, err = GetConnectionDetail(ctx, "tplconn1")
+	require.NoError(t, err)
+	require.Equal(t, "p1_client", meta.Props["clientId"])
+	// propagated with the values of each connection
+	result, err = UpdateConnectionTemplate(ctx, "tpl1", map[string]any{"clientId": "${site}_c2"}, true)
+	require.NoError(t, err)
+	require.Equal(t, []string{"tplconn1", "tplconn2"}, result.Updated)
+	meta, err = GetConnectionDetail(ctx, "tplconn1")
+	require.NoError(t, err)
+	require.Equal(t, "p1_c2", meta.Props["clientId"])
+	meta, err = GetConnectionDetail(ctx, "tplconn2")
+	require.NoError(t, err)
+	require.Equal(t, "p2_c2", meta.Props["clientId"])
+	require.Equal(t, map[string]string{"site": "p2"}, meta.Labels())
+
+	require.NoError(t, DropNameConnectionPermanently(ctx, "tplconn1"))
+	require.NoError(t, DropNameConnectionPermanently(ctx, "tplconn2"))
+}
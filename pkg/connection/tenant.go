@@ -0,0 +1,181 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+	"github.com/lf-edge/ekuiper/v2/pkg/model"
+)
+
+// The tenants share the gateway by the connection id prefix. Each tenant has its quota of the connections and
+// references, and its own retry guard so that the dial failures of a tenant do not pause the retries of the others.
+
+type tenant struct {
+	name     string
+	conf     model.TenantConf
+	guard    *retryGuard
+	failures atomic.Int64
+}
+
+// TenantUsage is the resource usage of a tenant in the pool
+type TenantUsage struct {
+	Connections  int   `json:"connections"`
+	Refs         int   `json:"refs"`
+	DialFailures int64 `json:"dialFailures"`
+}
+
+var tenants atomic.Pointer[[]*tenant]
+
+var ConnTenantFailureCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "kuiper",
+	Subsystem: "conn_tenant",
+	Name:      "dial_failures_total",
+	Help:      "count of the connection dial failures by tenant",
+}, []string{"tenant"})
+
+func init() {
+	prometheus.MustRegister(ConnTenantFailureCounter)
+}
+
+func initTenants() {
+	var ts []*tenant
+	if conf.Config != nil {
+		c := conf.Config.Connection
+		for name, tc := range c.Tenants {
+			if tc.Prefix == "" {
+				tc.Prefix = name + "_"
+			}
+			ts = append(ts, &tenant{
+				name:  name,
+				conf:  tc,
+				guard: newRetryGuard(c.RetryBudget, c.CircuitBreaker.Threshold, time.Duration(c.CircuitBreaker.Cooldown)),
+			})
+		}
+	}
+	// match the longest prefix first
+	sort.Slice(ts, func(i, j int) bool {
+		return len(ts[i].conf.Prefix) > len(ts[j].conf.Prefix)
+	})
+	tenants.Store(&ts)
+}
+
+// tenantOf returns the tenant of the connection id, or nil if it does not belong to any tenant
+func tenantOf(id string) *tenant {
+	ts := tenants.Load()
+	if ts == nil {
+		return nil
+	}
+	for _, t := range *ts {
+		if strings.HasPrefix(id, t.conf.Prefix) {
+			return t
+		}
+	}
+	return nil
+}
+
+func retryGuardOf(id string) *retryGuard {
+	if t := tenantOf(id); t != nil {
+		return t.guard
+	}
+	return globalRetryGuard.Load()
+}
+
+func onTenantDialFailure(id string) {
+	if t := tenantOf(id); t != nil {
+		t.failures.Add(1)
+		ConnTenantFailureCounter.WithLabelValues(t.name).Inc()
+	}
+}
+
+// checkConnectionQuota checks whether the connection can be added to the pool. The connection with the same id is
+// not counted, so it can be called to replace it. It must be called with the pool lock.
+func (m *ConnectionManager) checkConnectionQuota(id, typ string) error {
+	if err := m.checkPoolQuota(id, typ); err != nil {
+		return err
+	}
+	t := tenantOf(id)
+	if t == nil {
+		return nil
+	}
+	if len(t.conf.AllowedTypes) > 0 {
+		allowed := false
+		for _, at := range t.conf.AllowedTypes {
+			if strings.EqualFold(at, typ) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return errorx.NewWithCode(errorx.ConnectionTypeNotAllowedErr, fmt.Sprintf("connection type %s is not allowed for tenant %s", typ, t.name))
+		}
+	}
+	if t.conf.MaxConnections > 0 {
+		count := 0
+		for cid := range m.connectionPool {
+			if cid != id && tenantOf(cid) == t {
+				count++
+			}
+		}
+		if count >= t.conf.MaxConnections {
+			return errorx.NewWithCode(errorx.ConnectionQuotaErr, fmt.Sprintf("tenant %s exceeds the quota of %d connections", t.name, t.conf.MaxConnections))
+		}
+	}
+	return nil
+}
+
+// checkRefQuota checks whether the connection can be referred once more. It must be called with the pool lock.
+func (m *ConnectionManager) checkRefQuota(id string) error {
+	t := tenantOf(id)
+	if t == nil || t.conf.MaxRefs <= 0 {
+		return nil
+	}
+	refs := 0
+	for cid, meta := range m.connectionPool {
+		if tenantOf(cid) == t {
+			refs += meta.GetRefCount()
+		}
+	}
+	if refs >= t.conf.MaxRefs {
+		return errorx.NewWithCode(errorx.ConnectionQuotaErr, fmt.Sprintf("tenant %s exceeds the quota of %d references", t.name, t.conf.MaxRefs))
+	}
+	return nil
+}
+
+func tenantUsage(metas []*Meta) map[string]*TenantUsage {
+	ts := tenants.Load()
+	if ts == nil || len(*ts) == 0 {
+		return nil
+	}
+	usage := make(map[string]*TenantUsage, len(*ts))
+	for _, t := range *ts {
+		usage[t.name] = &TenantUsage{DialFailures: t.failures.Load()}
+	}
+	for _, meta := range metas {
+		if t := tenantOf(meta.ID); t != nil {
+			usage[t.name].Connections++
+			usage[t.name].Refs += meta.GetRefCount()
+		}
+	}
+	return usage
+}
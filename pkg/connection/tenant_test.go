@@ -0,0 +1,88 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/context"
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+	"github.com/lf-edge/ekuiper/v2/pkg/model"
+)
+
+func TestTenantQuota(t *testing.T) {
+	conf.InitConf()
+	origin := conf.Config.Connection.Tenants
+	defer func() {
+		conf.Config.Connection.Tenants = origin
+		require.NoError(t, InitConnectionManager4Test())
+		for _, id := range []string{"a_1", "a_2", "a_b_1"} {
+			_ = dropConnectionStore("mock", id)
+		}
+	}()
+	conf.Config.Connection.Tenants = map[string]model.TenantConf{
+		"a":  {MaxConnections: 2, MaxRefs: 2, AllowedTypes: []string{"mock"}},
+		"ab": {Prefix: "a_b_"},
+	}
+	require.NoError(t, InitConnectionManager4Test())
+	ctx := context.Background()
+
+	require.Equal(t, "a", tenantOf("a_1").name)
+	require.Equal(t, "ab", tenantOf("a_b_1").name)
+	require.Nil(t, tenantOf("b_1"))
+
+	_, err := CreateNamedConnection(ctx, "a_1", "mqtt", map[string]any{})
+	code, _ := errorx.GetErrorCode(err)
+	require.Equal(t, errorx.ConnectionTypeNotAllowedErr, code)
+	_, err = CreateNamedConnection(ctx, "a_1", "mock", map[string]any{})
+	require.NoError(t, err)
+	_, err = CreateNamedConnection(ctx, "a_2", "mock", map[string]any{})
+	require.NoError(t, err)
+	_, err = CreateNamedConnection(ctx, "a_3", "mock", map[string]any{})
+	code, _ = errorx.GetErrorCode(err)
+	require.Equal(t, errorx.ConnectionQuotaErr, code)
+	require.Equal(t, errorx.KindQuota, errorx.KindOf(err))
+	// other tenants are not affected
+	_, err = CreateNamedConnection(ctx, "a_b_1", "mock", map[string]any{})
+	require.NoError(t, err)
+	// update in place does not count itself
+	_, err = UpdateConnection(ctx, "a_2", "mock", map[string]any{"a": 1})
+	require.NoError(t, err)
+	_, err = UpdateConnection(ctx, "a_2", "mqtt", map[string]any{})
+	require.Error(t, err)
+	_, err = GetConnectionDetail(ctx, "a_2")
+	require.NoError(t, err)
+
+	props := map[string]any{"connectionSelector": "a_1"}
+	_, err = FetchConnection(ctx, "rule1", "mock", props, nil)
+	require.NoError(t, err)
+	_, err = FetchConnection(ctx, "rule2", "mock", props, nil)
+	require.NoError(t, err)
+	_, err = FetchConnection(ctx, "rule3", "mock", props, nil)
+	code, _ = errorx.GetErrorCode(err)
+	require.Equal(t, errorx.ConnectionQuotaErr, code)
+
+	h := Health()
+	require.Equal(t, &TenantUsage{Connections: 2, Refs: 2}, h.Tenants["a"])
+	require.Equal(t, &TenantUsage{Connections: 1}, h.Tenants["ab"])
+
+	onTenantDialFailure("a_1")
+	require.Equal(t, int64(1), Health().Tenants["a"].DialFailures)
+	require.NotSame(t, retryGuardOf("a_1"), retryGuardOf("a_b_1"))
+	require.Same(t, globalRetryGuard.Load(), retryGuardOf("b_1"))
+}
@@ -0,0 +1,103 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testkit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/context"
+	"github.com/lf-edge/ekuiper/v2/pkg/connection"
+)
+
+const pollInterval = 10 * time.Millisecond
+
+// Setup loads the config and resets the connection pool with the in-memory storage for the test
+func Setup(t testing.TB) {
+	t.Helper()
+	conf.IsTesting = true
+	conf.InitConf()
+	if err := connection.InitConnectionManager4Test(); err != nil {
+		t.Fatalf("init connection manager: %v", err)
+	}
+}
+
+// RequireStatus waits until the connection is in the status or fails the test after the timeout
+func RequireStatus(t testing.TB, id, status string, timeout time.Duration) {
+	t.Helper()
+	var last, lastErr string
+	ok := poll(timeout, func() bool {
+		meta, err := connection.GetConnectionDetail(context.Background(), id)
+		if err != nil {
+			last, lastErr = "", err.Error()
+			return false
+		}
+		last, lastErr = meta.GetStatus()
+		return last == status
+	})
+	if !ok {
+		t.Fatalf("connection %s is %q (%s), want %q", id, last, lastErr, status)
+	}
+}
+
+// RequireRefCount checks the reference count of the connection
+func RequireRefCount(t testing.TB, id string, refs int) {
+	t.Helper()
+	meta, err := connection.GetConnectionDetail(context.Background(), id)
+	if err != nil {
+		t.Fatalf("get connection %s: %v", id, err)
+	}
+	if got := meta.GetRefCount(); got != refs {
+		t.Fatalf("connection %s has %d refs, want %d", id, got, refs)
+	}
+}
+
+// RequireInPool checks the connection is in the pool
+func RequireInPool(t testing.TB, id string) {
+	t.Helper()
+	if _, err := connection.GetConnectionDetail(context.Background(), id); err != nil {
+		t.Fatalf("connection %s is not in the pool: %v", id, err)
+	}
+}
+
+// RequireNotInPool checks the connection is not in the pool
+func RequireNotInPool(t testing.TB, id string) {
+	t.Helper()
+	if _, err := connection.GetConnectionDetail(context.Background(), id); err == nil {
+		t.Fatalf("connection %s is still in the pool", id)
+	}
+}
+
+// RequirePoolSize checks the count of all the connections in the pool including the anonymous ones
+func RequirePoolSize(t testing.TB, size int) {
+	t.Helper()
+	if got := len(connection.GetAllConnectionsMeta(true)); got != size {
+		t.Fatalf("pool has %d connections, want %d", got, size)
+	}
+}
+
+func poll(timeout time.Duration, cond func() bool) bool {
+	deadline := time.Now().Add(timeout)
+	for {
+		if cond() {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(pollInterval)
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testkit
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/cenkalti/backoff/v4"
+
+	"github.com/lf-edge/ekuiper/v2/pkg/connection"
+	"github.com/lf-edge/ekuiper/v2/pkg/tracer"
+)
+
+// ManualClock drives the dial retries of the pool. The retries wait until the test fires the clock instead of
+// sleeping, so the tests do not depend on the timing.
+type ManualClock struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	pending map[*manualTimer]struct{}
+	waits   []time.Duration
+}
+
+// InstallManualClock replaces the retry timer of the pool with a manual clock until the test ends
+func InstallManualClock(t testing.TB) *ManualClock {
+	c := &ManualClock{pending: make(map[*manualTimer]struct{})}
+	c.cond = sync.NewCond(&c.mu)
+	connection.SetRetryTimer(func() backoff.Timer {
+		return &manualTimer{clock: c, ch: make(chan time.Time, 1)}
+	})
+	t.Cleanup(func() {
+		connection.SetRetryTimer(nil)
+	})
+	return c
+}
+
+// InstallMockClock replaces the clock of the pool and the tracer with a mock clock until the test ends. The time only
+// advances by Add or Set of the mock, which drives the retries, the circuit breaker, the operation timeouts, the
+// periodic jobs and the span timestamps.
+func InstallMockClock(t testing.TB) *clock.Mock {
+	mock := clock.NewMock()
+	connection.SetClock(mock)
+	tracer.SetClock(mock)
+	t.Cleanup(func() {
+		connection.SetClock(nil)
+		tracer.SetClock(nil)
+	})
+	return mock
+}
+
+// Pending returns the count of the retries waiting for the clock
+func (c *ManualClock) Pending() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.pending)
+}
+
+// WaitPending blocks until n retries are waiting or the timeout. It returns whether they are waiting.
+func (c *ManualClock) WaitPending(n int, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	timer := time.AfterFunc(timeout, func() {
+		c.mu.Lock()
+		c.cond.Broadcast()
+		c.mu.Unlock()
+	})
+	defer timer.Stop()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for len(c.pending) < n {
+		if !time.Now().Before(deadline) {
+			return false
+		}
+		c.cond.Wait()
+	}
+	return true
+}
+
+// Fire wakes up all the waiting retries and returns the count
+func (c *ManualClock) Fire() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := len(c.pending)
+	for t := range c.pending {
+		t.ch <- time.Now()
+		delete(c.pending, t)
+	}
+	return n
+}
+
+// Waits returns the backoff intervals requested by the retries in order, which can be used to assert the policy
+func (c *ManualClock) Waits() []time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]time.Duration(nil), c.waits...)
+}
+
+type manualTimer struct {
+	clock *ManualClock
+	ch    chan time.Time
+}
+
+func (t *manualTimer) Start(d time.Duration) {
+	c := t.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.waits = append(c.waits, d)
+	c.pending[t] = struct{}{}
+	c.cond.Broadcast()
+}
+
+func (t *manualTimer) Stop() {
+	c := t.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.pending, t)
+}
+
+func (t *manualTimer) C() <-chan time.Time {
+	return t.ch
+}
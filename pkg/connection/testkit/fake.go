@@ -0,0 +1,184 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package testkit helps the plugin authors test against the connection pool. It provides a scriptable fake
+// connection type, a manual clock to drive the dial retries deterministically and the assertions of the pool state.
+package testkit
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+
+	"github.com/lf-edge/ekuiper/v2/pkg/modules"
+)
+
+// Script scripts the behaviors of the fake connections
+type Script struct {
+	// DialErrs are returned by the dials in order. The dials after them succeed.
+	DialErrs []error
+	// ProvisionErr is returned by the provision
+	ProvisionErr error
+	PingErr      error
+	CloseErr     error
+}
+
+// Fake is a registered fake connection type. All the connections created by the pool share the script.
+type Fake struct {
+	Type string
+
+	mu     sync.Mutex
+	script Script
+	dials  int
+	conns  []*Connection
+}
+
+// Register registers a fake connection type which is unregistered when the test ends. The pool looks up the type in
+// lower case, so it is registered in lower case.
+func Register(t testing.TB, typ string, script Script) *Fake {
+	t.Helper()
+	f := &Fake{Type: typ, script: script}
+	name := strings.ToLower(typ)
+	if err := modules.RegisterConnectionType(name, f.provide); err != nil {
+		t.Fatalf("register fake connection type %s: %v", typ, err)
+	}
+	t.Cleanup(func() {
+		modules.UnregisterConnection(name)
+	})
+	return f
+}
+
+func (f *Fake) provide(_ api.StreamContext) modules.Connection {
+	c := &Connection{fake: f}
+	f.mu.Lock()
+	f.conns = append(f.conns, c)
+	f.mu.Unlock()
+	return c
+}
+
+// SetPingErr changes the ping result of all the connections, for example to simulate an outage
+func (f *Fake) SetPingErr(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.script.PingErr = err
+}
+
+// AppendDialErrs appends the errors to be returned by the following dials
+func (f *Fake) AppendDialErrs(errs ...error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.script.DialErrs = append(f.script.DialErrs, errs...)
+}
+
+// Dials returns the count of the dials of all the connections
+func (f *Fake) Dials() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.dials
+}
+
+// Connections returns the connections created by the pool in order
+func (f *Fake) Connections() []*Connection {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]*Connection(nil), f.conns...)
+}
+
+// Last returns the latest created connection or nil
+func (f *Fake) Last() *Connection {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.conns) == 0 {
+		return nil
+	}
+	return f.conns[len(f.conns)-1]
+}
+
+func (f *Fake) nextDialErr() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.dials++
+	if len(f.script.DialErrs) == 0 {
+		return nil
+	}
+	err := f.script.DialErrs[0]
+	f.script.DialErrs = f.script.DialErrs[1:]
+	return err
+}
+
+// Connection is the fake connection which records the calls
+type Connection struct {
+	fake  *Fake
+	id    string
+	props map[string]any
+
+	dials  atomic.Int32
+	pings  atomic.Int32
+	closed atomic.Bool
+}
+
+func (c *Connection) Provision(_ api.StreamContext, conId string, props map[string]any) error {
+	c.id, c.props = conId, props
+	c.fake.mu.Lock()
+	defer c.fake.mu.Unlock()
+	return c.fake.script.ProvisionErr
+}
+
+func (c *Connection) Dial(_ api.StreamContext) error {
+	c.dials.Add(1)
+	return c.fake.nextDialErr()
+}
+
+func (c *Connection) GetId(_ api.StreamContext) string {
+	return c.id
+}
+
+func (c *Connection) Ping(_ api.StreamContext) error {
+	c.pings.Add(1)
+	c.fake.mu.Lock()
+	defer c.fake.mu.Unlock()
+	return c.fake.script.PingErr
+}
+
+func (c *Connection) Close(_ api.StreamContext) error {
+	c.closed.Store(true)
+	c.fake.mu.Lock()
+	defer c.fake.mu.Unlock()
+	return c.fake.script.CloseErr
+}
+
+// ID returns the connection id provisioned by the pool
+func (c *Connection) ID() string {
+	return c.id
+}
+
+// Props returns the props provisioned by the pool
+func (c *Connection) Props() map[string]any {
+	return c.props
+}
+
+func (c *Connection) Dials() int {
+	return int(c.dials.Load())
+}
+
+func (c *Connection) Pings() int {
+	return int(c.pings.Load())
+}
+
+func (c *Connection) Closed() bool {
+	return c.closed.Load()
+}
@@ -0,0 +1,74 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testkit
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/topo/context"
+	"github.com/lf-edge/ekuiper/v2/pkg/connection"
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+)
+
+func TestFakeConnectionRetry(t *testing.T) {
+	Setup(t)
+	clock := InstallManualClock(t)
+	fake := Register(t, "fakeRetry", Script{DialErrs: []error{errorx.NewIOErr("down"), errorx.NewIOErr("down")}})
+	ctx := context.Background()
+	_, err := connection.CreateNamedConnection(ctx, "fake1", "fakeRetry", map[string]any{"a": 1})
+	require.NoError(t, err)
+
+	// each failed dial waits for the clock
+	for i := 0; i < 2; i++ {
+		require.True(t, clock.WaitPending(1, 5*time.Second))
+		RequireStatus(t, "fake1", api.ConnectionDisconnected, time.Second)
+		require.Equal(t, 1, clock.Fire())
+	}
+	RequireStatus(t, "fake1", api.ConnectionConnected, 5*time.Second)
+	require.Equal(t, 3, fake.Dials())
+	require.Len(t, clock.Waits(), 2)
+	c := fake.Last()
+	require.Equal(t, "fake1", c.ID())
+	require.Equal(t, map[string]any{"a": 1}, c.Props())
+	RequireInPool(t, "fake1")
+	RequirePoolSize(t, 1)
+
+	// stateless connection pings for the status
+	fake.SetPingErr(errors.New("lost"))
+	RequireStatus(t, "fake1", api.ConnectionDisconnected, time.Second)
+	require.Greater(t, c.Pings(), 0)
+
+	require.NoError(t, connection.DropNameConnection(ctx, "fake1"))
+	RequireNotInPool(t, "fake1")
+	require.True(t, c.Closed())
+}
+
+func TestFakeConnectionRef(t *testing.T) {
+	Setup(t)
+	Register(t, "fakeRef", Script{})
+	ctx := context.Background()
+	_, err := connection.CreateNamedConnection(ctx, "fake2", "fakeRef", map[string]any{})
+	require.NoError(t, err)
+	_, err = connection.FetchConnection(ctx, "rule1", "fakeRef", map[string]any{"connectionSelector": "fake2"}, nil)
+	require.NoError(t, err)
+	RequireRefCount(t, "fake2", 1)
+	require.NoError(t, connection.DetachConnection(ctx, "fake2"))
+	require.NoError(t, connection.DropNameConnection(ctx, "fake2"))
+}
@@ -0,0 +1,190 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+	"golang.org/x/time/rate"
+
+	topoContext "github.com/lf-edge/ekuiper/v2/internal/topo/context"
+	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+	"github.com/lf-edge/ekuiper/v2/pkg/modules"
+)
+
+// ThrottlePropKey is the reserved prop to protect the remote endpoint of a shared connection from the rules, such as
+// {"$throttle": {"rate": 100, "burst": 10, "maxInFlight": 4}}. The consumers call Acquire of the connection wrapper
+// before each operation, which waits for the rate limit and the in-flight slot. It is only accepted by the connection
+// types implementing modules.Throttleable.
+const ThrottlePropKey = "$throttle"
+
+// ThrottleConf limits the operations of a connection. 0 means unlimited.
+type ThrottleConf struct {
+	// Rate is the operations per second
+	Rate float64 `json:"rate"`
+	// Burst is the operations allowed at once over the rate. Default to the rate rounded up.
+	Burst int `json:"burst"`
+	// MaxInFlight is the operations running at the same time
+	MaxInFlight int `json:"maxInFlight"`
+}
+
+// ThrottleStats are the metrics of the throttled connection. Waited is the total milliseconds waited.
+type ThrottleStats struct {
+	ThrottleConf
+	InFlight  int64 `json:"inFlight"`
+	Acquired  int64 `json:"acquired"`
+	Throttled int64 `json:"throttled"`
+	Waited    int64 `json:"waited"`
+	Rejected  int64 `json:"rejected"`
+}
+
+type throttle struct {
+	conf    ThrottleConf
+	limiter *rate.Limiter
+	slots   chan struct{}
+}
+
+// throttleMetrics are kept across the throttle changes of the connection
+type throttleMetrics struct {
+	inFlight  atomic.Int64
+	acquired  atomic.Int64
+	throttled atomic.Int64
+	waited    atomic.Int64
+	rejected  atomic.Int64
+}
+
+// parseThrottle parses the throttle of the props. It returns nil if there is no limit.
+func parseThrottle(props map[string]any) (*throttle, error) {
+	v, ok := props[ThrottlePropKey]
+	if !ok || v == nil {
+		return nil, nil
+	}
+	c := ThrottleConf{}
+	if err := cast.MapToStruct(v, &c); err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", ThrottlePropKey, err)
+	}
+	if c.Rate < 0 || c.Burst < 0 || c.MaxInFlight < 0 {
+		return nil, fmt.Errorf("invalid %s: the limits must not be negative", ThrottlePropKey)
+	}
+	if c.Rate == 0 && c.MaxInFlight == 0 {
+		return nil, nil
+	}
+	t := &throttle{conf: c}
+	if c.Rate > 0 {
+		if t.conf.Burst == 0 {
+			t.conf.Burst = int(math.Ceil(c.Rate))
+		}
+		t.limiter = rate.NewLimiter(rate.Limit(c.Rate), t.conf.Burst)
+	}
+	if c.MaxInFlight > 0 {
+		t.slots = make(chan struct{}, c.MaxInFlight)
+	}
+	return t, nil
+}
+
+// validateThrottle checks the throttle of the props is valid and enforced by the connection type
+func validateThrottle(typ string, props map[string]any) error {
+	t, err := parseThrottle(props)
+	if err != nil || t == nil {
+		return err
+	}
+	cp, ok := modules.GetConnectionProvider(strings.ToLower(typ))
+	if !ok {
+		return nil
+	}
+	if th, ok := cp(topoContext.Background()).(modules.Throttleable); !ok || !th.CanThrottle() {
+		return fmt.Errorf("invalid %s: connection type %s doesn't support the throttle", ThrottlePropKey, typ)
+	}
+	return nil
+}
+
+// setThrottle applies the throttle of the props. The operations in flight are released to the previous throttle.
+func (cw *ConnWrapper) setThrottle(props map[string]any) {
+	t, err := parseThrottle(props)
+	if err != nil {
+		connLogger(cw.ID).Warnf("connection %s is not throttled: %v", cw.ID, err)
+	}
+	cw.throttle.Store(t)
+}
+
+// Acquire waits until the operation of the connection is allowed by the throttle. The returned function must be
+// called when the operation ends. It fails if the context is done while waiting.
+func (cw *ConnWrapper) Acquire(ctx api.StreamContext) (func(), error) {
+	t := cw.throttle.Load()
+	if t == nil {
+		return func() {}, nil
+	}
+	m := &cw.throttleMetrics
+	start := time.Now()
+	if t.slots != nil {
+		select {
+		case t.slots <- struct{}{}:
+		default:
+			select {
+			case t.slots <- struct{}{}:
+			case <-ctx.Done():
+				m.rejected.Add(1)
+				return nil, ctx.Err()
+			}
+		}
+	}
+	if t.limiter != nil {
+		if err := t.limiter.Wait(ctx); err != nil {
+			if t.slots != nil {
+				<-t.slots
+			}
+			m.rejected.Add(1)
+			return nil, err
+		}
+	}
+	if d := time.Since(start); d >= time.Millisecond {
+		m.throttled.Add(1)
+		m.waited.Add(d.Milliseconds())
+	}
+	m.acquired.Add(1)
+	m.inFlight.Add(1)
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			m.inFlight.Add(-1)
+			if t.slots != nil {
+				<-t.slots
+			}
+		})
+	}, nil
+}
+
+// ThrottleStats returns the throttle metrics, or nil if the connection is not throttled
+func (cw *ConnWrapper) ThrottleStats() *ThrottleStats {
+	t := cw.throttle.Load()
+	if t == nil {
+		return nil
+	}
+	m := &cw.throttleMetrics
+	return &ThrottleStats{
+		ThrottleConf: t.conf,
+		InFlight:     m.inFlight.Load(),
+		Acquired:     m.acquired.Load(),
+		Throttled:    m.throttled.Load(),
+		Waited:       m.waited.Load(),
+		Rejected:     m.rejected.Load(),
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/topo/context"
+)
+
+func TestParseThrottle(t *testing.T) {
+	th, err := parseThrottle(map[string]any{})
+	require.NoError(t, err)
+	require.Nil(t, th)
+	th, err = parseThrottle(map[string]any{ThrottlePropKey: map[string]any{"rate": 2.5}})
+	require.NoError(t, err)
+	require.Equal(t, ThrottleConf{Rate: 2.5, Burst: 3}, th.conf)
+	require.Nil(t, th.slots)
+	_, err = parseThrottle(map[string]any{ThrottlePropKey: map[string]any{"maxInFlight": -1}})
+	require.Error(t, err)
+	_, err = parseThrottle(map[string]any{ThrottlePropKey: "fast"})
+	require.Error(t, err)
+}
+
+func TestThrottleConnection(t *testing.T) {
+	require.NoError(t, InitConnectionManager4Test())
+	ctx := context.Background()
+	_, err := CreateNamedConnection(ctx, "throttle1", "mock", map[string]any{ThrottlePropKey: "fast"})
+	require.Error(t, err)
+	// the throttle is not enforced by the type
+	_, err = CreateNamedConnection(ctx, "throttle1", "blockconn", map[string]any{ThrottlePropKey: map[string]any{"maxInFlight": 1}})
+	require.EqualError(t, err, "invalid $throttle: connection type blockconn doesn't support the throttle")
+	cw, err := CreateNamedConnection(ctx, "throttle1", "mock", map[string]any{ThrottlePropKey: map[string]any{"maxInFlight": 1}})
+	require.NoError(t, err)
+	defer func() {
+		_ = DropNameConnectionPermanently(ctx, "throttle1")
+	}()
+
+	release, err := cw.Acquire(ctx)
+	require.NoError(t, err)
+	waitCtx, cancel := ctx.WithTimeout(20 * time.Millisecond)
+	_, err = cw.Acquire(waitCtx)
+	cancel()
+	require.Error(t, err)
+	release()
+	release()
+	release, err = cw.Acquire(ctx)
+	require.NoError(t, err)
+	release()
+
+	meta, err := GetConnectionDetail(ctx, "throttle1")
+	require.NoError(t, err)
+	stats := meta.Stats().Throttle
+	require.NotNil(t, stats)
+	require.Equal(t, 1, stats.MaxInFlight)
+	require.Equal(t, int64(0), stats.InFlight)
+	require.Equal(t, int64(2), stats.Acquired)
+	require.Equal(t, int64(1), stats.Rejected)
+
+	// the throttle is removed by the update while the metrics are kept
+	_, err = UpdateNamedConnection(ctx, "throttle1", map[string]any{})
+	require.NoError(t, err)
+	require.Nil(t, meta.Stats().Throttle)
+	release, err = cw.Acquire(ctx)
+	require.NoError(t, err)
+	release()
+}
@@ -0,0 +1,108 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/lf-edge/ekuiper/v2/pkg/tracer"
+)
+
+// traceConnectionStatus adds the status transitions of the connections as the events of the running spans of the
+// rule components holding them, so that the trace of a failed sink shows that its connection was reconnecting. The
+// subscription is renewed if it is dropped as too slow.
+func traceConnectionStatus(ctx context.Context) {
+	for {
+		ch, cancel := SubscribeAll()
+		if !consumeStatus(ctx, ch, traceStatusEvent) {
+			cancel()
+			return
+		}
+		cancel()
+	}
+}
+
+// consumeStatus handles the events until the context is done or the channel is closed. It returns false once the
+// context is done.
+func consumeStatus(ctx context.Context, ch <-chan StatusEvent, handle func(StatusEvent)) bool {
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case ev, ok := <-ch:
+			if !ok {
+				return true
+			}
+			handle(ev)
+		}
+	}
+}
+
+// traceStatusEvent annotates the spans of the attachers of the connection. The dropped connection is no longer in
+// the pool so it is skipped.
+func traceStatusEvent(ev StatusEvent) {
+	meta := ev.meta
+	if meta == nil {
+		return
+	}
+	if cur, ok := meta.manager().load()[ev.ID]; !ok || cur != meta {
+		return
+	}
+	name := statusEventName(ev)
+	attrs := []attribute.KeyValue{
+		attribute.String(tracer.ConnectionIDKey, ev.ID),
+		attribute.String(tracer.ConnectionStatusKey, ev.Status),
+	}
+	if ev.Prev != "" {
+		attrs = append(attrs, attribute.String(tracer.ConnectionPrevStatusKey, ev.Prev))
+	}
+	if ev.LastError != "" {
+		attrs = append(attrs, attribute.String(tracer.ConnectionErrorKey, ev.LastError))
+	}
+	seen := make(map[[2]string]struct{})
+	for _, a := range meta.Attachers() {
+		key := [2]string{a.RuleID, a.OpID}
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		tracer.AnnotateActiveSpans(a.RuleID, a.OpID, name, attrs...)
+	}
+}
+
+// statusEventName is the span event name of the transition such as "connection mqtt1 reconnecting"
+func statusEventName(ev StatusEvent) string {
+	status := ev.Status
+	if status == api.ConnectionConnecting && ev.Prev != "" {
+		status = "reconnecting"
+	}
+	return fmt.Sprintf("connection %s %s", ev.ID, status)
+}
+
+// connectionsOfOp returns the sorted ids of the connections held by the op of the rule. It is the connection resolver
+// of the tracer to record the connection ids of the spans.
+func connectionsOfOp(ruleID, opID string) []string {
+	var result []string
+	for _, meta := range globalConnectionManager.opConnections(ruleID, opID) {
+		result = append(result, meta.ID)
+	}
+	sort.Strings(result)
+	return result
+}
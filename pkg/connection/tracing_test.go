@@ -0,0 +1,81 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+	"github.com/stretchr/testify/require"
+
+	mockContext "github.com/lf-edge/ekuiper/v2/pkg/mock/context"
+)
+
+func TestConnectionsOfOp(t *testing.T) {
+	require.NoError(t, InitConnectionManager4Test())
+	ctx := mockContext.NewMockContext("trc", "create")
+	for _, id := range []string{"trc2", "trc1"} {
+		_, err := CreateNamedConnection(ctx, id, "mock", nil)
+		require.NoError(t, err)
+		defer func() {
+			require.NoError(t, DropNameConnectionPermanently(ctx, id))
+		}()
+	}
+	ctx1 := mockContext.NewMockContext("rule1", "op1")
+	ctx2 := mockContext.NewMockContext("rule1", "op2")
+	for _, id := range []string{"trc2", "trc1"} {
+		_, err := FetchConnection(ctx1, id, "mock", map[string]any{"connectionSelector": id}, nil)
+		require.NoError(t, err)
+	}
+	_, err := FetchConnection(ctx2, "trc2", "mock", map[string]any{"connectionSelector": "trc2"}, nil)
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"trc1", "trc2"}, connectionsOfOp("rule1", "op1"))
+	require.Equal(t, []string{"trc2"}, connectionsOfOp("rule1", "op2"))
+	require.Empty(t, connectionsOfOp("rule2", "op1"))
+
+	require.NoError(t, DetachConnection(ctx1, "trc1"))
+	require.Equal(t, []string{"trc2"}, connectionsOfOp("rule1", "op1"))
+	require.NoError(t, DetachConnection(ctx1, "trc2"))
+	require.NoError(t, DetachConnection(ctx2, "trc2"))
+	require.Empty(t, connectionsOfOp("rule1", "op2"))
+	require.Empty(t, globalConnectionManager.opConns)
+}
+
+func TestStatusEventName(t *testing.T) {
+	require.Equal(t, "connection c1 connecting", statusEventName(StatusEvent{ID: "c1", Status: api.ConnectionConnecting}))
+	require.Equal(t, "connection c1 reconnecting", statusEventName(StatusEvent{ID: "c1", Status: api.ConnectionConnecting, Prev: api.ConnectionDisconnected}))
+	require.Equal(t, "connection c1 disconnected", statusEventName(StatusEvent{ID: "c1", Status: api.ConnectionDisconnected, Prev: api.ConnectionConnected}))
+	require.Equal(t, "connection c1 dropped", statusEventName(StatusEvent{ID: "c1", Status: ConnectionDropped, Prev: api.ConnectionConnected}))
+}
+
+func TestConsumeStatus(t *testing.T) {
+	ch := make(chan StatusEvent, 2)
+	var got []string
+	handle := func(ev StatusEvent) { got = append(got, ev.Status) }
+	ch <- StatusEvent{ID: "c1", Status: api.ConnectionDisconnected}
+	ch <- StatusEvent{ID: "c1", Status: api.ConnectionConnecting}
+	close(ch)
+	// the closed subscription is renewed
+	require.True(t, consumeStatus(context.Background(), ch, handle))
+	require.Equal(t, []string{api.ConnectionDisconnected, api.ConnectionConnecting}, got)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.False(t, consumeStatus(ctx, make(chan StatusEvent), handle))
+	// the loop ends once the context is done
+	traceConnectionStatus(ctx)
+}
@@ -0,0 +1,131 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+	"gopkg.in/yaml.v3"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/pkg/modules"
+)
+
+// The named connections are exported and imported in the same layout as the declaration file, so that an exported
+// document can be imported to other nodes or used as their connections.yaml.
+
+const (
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+)
+
+// ExportConnections returns the named connections as a document in json or yaml. The secret props are masked if
+// mask is set, and the masked connections can only be imported to the nodes which have the secrets.
+func ExportConnections(format string, mask bool) ([]byte, error) {
+	d := &Declaration{Connections: make(map[string]map[string]map[string]any)}
+	for _, meta := range GetAllConnectionsMeta(false) {
+		props := meta.Props
+		if mask {
+			props = MaskSecrets(props)
+		}
+		if props == nil {
+			props = map[string]any{}
+		}
+		if _, ok := d.Connections[meta.Typ]; !ok {
+			d.Connections[meta.Typ] = make(map[string]map[string]any)
+		}
+		d.Connections[meta.Typ][meta.ID] = props
+	}
+	switch strings.ToLower(format) {
+	case FormatJSON, "":
+		return json.Marshal(d)
+	case FormatYAML:
+		return yaml.Marshal(d)
+	default:
+		return nil, fmt.Errorf("unsupported export format %s", format)
+	}
+}
+
+// ImportConnections validates, stores and creates the named connections of the document in json or yaml. The existing
+// connections are recreated with the new props only if overwrite is set, and those referenced by rules are never
+// changed. The masked secrets are filled with the ones of the existing connections.
+func ImportConnections(ctx api.StreamContext, data []byte, overwrite bool) (*ReconcileReport, error) {
+	d := &Declaration{}
+	// json is also valid yaml
+	if err := yaml.Unmarshal(data, d); err != nil {
+		return nil, fmt.Errorf("invalid connections document: %v", err)
+	}
+	report := &ReconcileReport{Failed: make(map[string]string)}
+	globalConnectionManager.Lock()
+	defer globalConnectionManager.Unlock()
+	imported := make(map[string]struct{})
+	for typ, conns := range d.Connections {
+		for id, props := range conns {
+			if _, ok := imported[id]; ok {
+				report.Failed[id] = fmt.Sprintf("connection %s is declared more than once", id)
+				continue
+			}
+			imported[id] = struct{}{}
+			if _, ok := modules.GetConnectionProvider(strings.ToLower(typ)); !ok {
+				report.Failed[id] = fmt.Sprintf("unknown connection type %s", typ)
+				continue
+			}
+			meta, exists := globalConnectionManager.connectionPool[id]
+			if exists && !overwrite {
+				report.Skipped = append(report.Skipped, id)
+				continue
+			}
+			if exists {
+				props = restoreSecrets(props, meta.Props)
+			}
+			if k, masked := maskedSecret(props); masked {
+				report.Failed[id] = fmt.Sprintf("secret prop %s of connection %s is masked", k, id)
+				continue
+			}
+			applyDeclared(ctx, id, typ, props, report)
+		}
+	}
+	report.sort()
+	conf.Log.Infof("connections imported, %d created, %d updated, %d unchanged, %d skipped, %d failed",
+		len(report.Created), len(report.Updated), len(report.Unchanged), len(report.Skipped), len(report.Failed))
+	return report, nil
+}
+
+// maskedSecret returns the first secret prop which is still masked
+func maskedSecret(props map[string]any) (string, bool) {
+	for k, v := range props {
+		if isSecretField(k) && v == HiddenSecret {
+			return k, true
+		}
+		if vm, ok := v.(map[string]any); ok {
+			if sk, masked := maskedSecret(vm); masked {
+				return k + "." + sk, true
+			}
+		}
+		if vl, ok := v.([]any); ok {
+			for i, e := range vl {
+				if em, ok := e.(map[string]any); ok {
+					if sk, masked := maskedSecret(em); masked {
+						return fmt.Sprintf("%s.%d.%s", k, i, sk), true
+					}
+				}
+			}
+		}
+	}
+	return "", false
+}
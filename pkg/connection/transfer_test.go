@@ -0,0 +1,104 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"github.com/lf-edge/ekuiper/v2/internal/topo/context"
+)
+
+func TestExportImportConnections(t *testing.T) {
+	require.NoError(t, InitConnectionManager4Test())
+	ctx := context.Background()
+	defer func() {
+		for _, id := range []string{"exp1", "exp2", "exp3"} {
+			_ = DropNameConnectionPermanently(ctx, id)
+		}
+	}()
+	_, err := CreateNamedConnection(ctx, "exp1", "mock", map[string]any{"server": "s1", "password": "p1"})
+	require.NoError(t, err)
+	_, err = CreateNamedConnection(ctx, "exp2", "mock", map[string]any{"server": "s2"})
+	require.NoError(t, err)
+	_, err = FetchConnection(ctx, "anonymous", "mock", nil, nil)
+	require.NoError(t, err)
+
+	data, err := ExportConnections(FormatJSON, true)
+	require.NoError(t, err)
+	d := &Declaration{}
+	require.NoError(t, json.Unmarshal(data, d))
+	// the anonymous connections are not exported
+	require.Equal(t, map[string]map[string]map[string]any{
+		"mock": {
+			"exp1": {"server": "s1", "password": HiddenSecret},
+			"exp2": {"server": "s2"},
+		},
+	}, d.Connections)
+	data, err = ExportConnections(FormatYAML, false)
+	require.NoError(t, err)
+	d = &Declaration{}
+	require.NoError(t, yaml.Unmarshal(data, d))
+	require.Equal(t, "p1", d.Connections["mock"]["exp1"]["password"])
+	_, err = ExportConnections("xml", false)
+	require.Error(t, err)
+
+	doc := []byte(`
+connections:
+  mock:
+    exp1:
+      server: s3
+      password: "******"
+    exp3:
+      server: s3
+    exp4:
+      password: "******"
+  unknown:
+    exp5: {}
+`)
+	report, err := ImportConnections(ctx, doc, false)
+	require.NoError(t, err)
+	require.Equal(t, []string{"exp3"}, report.Created)
+	require.Equal(t, []string{"exp1"}, report.Skipped)
+	require.Len(t, report.Failed, 2)
+	require.Contains(t, report.Failed["exp4"], "masked")
+	require.Contains(t, report.Failed["exp5"], "unknown connection type")
+
+	// overwrite keeps the masked secret
+	report, err = ImportConnections(ctx, doc, true)
+	require.NoError(t, err)
+	require.Equal(t, []string{"exp1"}, report.Updated)
+	require.Equal(t, []string{"exp3"}, report.Unchanged)
+	meta, err := GetConnectionDetail(ctx, "exp1")
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"server": "s3", "password": "p1"}, meta.Props)
+
+	// import the exported document in json
+	data, err = ExportConnections(FormatJSON, false)
+	require.NoError(t, err)
+	require.NoError(t, InitConnectionManager4Test())
+	report, err = ImportConnections(ctx, data, false)
+	require.NoError(t, err)
+	require.Equal(t, []string{"exp1", "exp2", "exp3"}, report.Created)
+	meta, err = GetConnectionDetail(ctx, "exp1")
+	require.NoError(t, err)
+	require.Equal(t, "p1", meta.Props["password"])
+
+	_, err = ImportConnections(ctx, []byte("connections: ["), false)
+	require.Error(t, err)
+}
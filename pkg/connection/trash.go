@@ -0,0 +1,202 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+)
+
+// trashCfgType is the config type of the dropped named connections. It must not have the prefix "connections"
+// so that the trashed connections are not loaded.
+const trashCfgType = "connTrash"
+
+// TrashedConnection is a dropped named connection retained in the storage until it expires
+type TrashedConnection struct {
+	ID    string         `json:"id"`
+	Typ   string         `json:"typ"`
+	Props map[string]any `json:"props"`
+	// DroppedAt and ExpireAt are unix milliseconds
+	DroppedAt int64 `json:"droppedAt"`
+	ExpireAt  int64 `json:"expireAt"`
+}
+
+func (t *TrashedConnection) expired(now time.Time) bool {
+	return now.UnixMilli() >= t.ExpireAt
+}
+
+// trashTTL is how long the dropped named connections are retained. 0 means they are dropped permanently.
+func trashTTL() time.Duration {
+	if conf.Config == nil {
+		return 0
+	}
+	return time.Duration(conf.Config.Connection.TrashTTL)
+}
+
+// trashConnectionStore moves the stored connection into the trash atomically
+func trashConnectionStore(meta *Meta, ttl time.Duration) error {
+	props, err := encryptSecrets(meta.Props)
+	if err != nil {
+		return err
+	}
+	now := getClock().Now()
+	entry := map[string]any{
+		"props":     props,
+		"droppedAt": now.UnixMilli(),
+		"expireAt":  now.Add(ttl).UnixMilli(),
+	}
+	s, err := getConnectionStore()
+	if err != nil {
+		return err
+	}
+	if isDefaultStore(s) {
+		return conf.BatchCfgInKVStorage([]conf.ConfigOp{
+			conf.NewCfgSetOp(trashCfgType, meta.Typ, meta.ID, entry),
+			conf.NewCfgDeleteOp(connCfgType, meta.Typ, meta.ID),
+		})
+	}
+	// the trash is local so the entry is written first to never lose the connection
+	if err := conf.WriteCfgIntoKVStorage(trashCfgType, meta.Typ, meta.ID, entry); err != nil {
+		return err
+	}
+	if err := s.Delete(meta.Typ, meta.ID); err != nil {
+		_ = conf.DropCfgKeyFromStorage(trashCfgType, meta.Typ, meta.ID)
+		return err
+	}
+	return nil
+}
+
+func loadTrash() ([]*TrashedConnection, error) {
+	cfgs, err := conf.GetCfgFromKVStorage(trashCfgType, "", "")
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*TrashedConnection, 0, len(cfgs))
+	for key, v := range cfgs {
+		names := strings.Split(key, ".")
+		if len(names) != 3 {
+			continue
+		}
+		t := &TrashedConnection{Typ: names[1], ID: names[2]}
+		if err := cast.MapToStruct(v, t); err != nil {
+			conf.Log.Warnf("invalid trashed connection %s: %v", key, err)
+			continue
+		}
+		t.Typ, t.ID = names[1], names[2]
+		result = append(result, t)
+	}
+	return result, nil
+}
+
+func findTrash(id string) (*TrashedConnection, error) {
+	all, err := loadTrash()
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range all {
+		if t.ID == id && !t.expired(getClock().Now()) {
+			return t, nil
+		}
+	}
+	return nil, errorx.NewWithCode(errorx.NOT_FOUND, fmt.Sprintf("connection %s is not in the trash", id))
+}
+
+// ListTrashedConnections returns the dropped named connections which can be restored, ordered by the drop time
+func ListTrashedConnections() ([]*TrashedConnection, error) {
+	all, err := loadTrash()
+	if err != nil {
+		return nil, err
+	}
+	now := getClock().Now()
+	result := make([]*TrashedConnection, 0, len(all))
+	for _, t := range all {
+		if !t.expired(now) {
+			result = append(result, t)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].DroppedAt > result[j].DroppedAt
+	})
+	return result, nil
+}
+
+// Restore recreates the dropped named connection from the trash with its original props
+func (m *ConnectionManager) Restore(ctx api.StreamContext, id string) (*ConnWrapper, error) {
+	if id == "" {
+		return nil, errorx.NewWithCode(errorx.ConnectionInvalidErr, "connection id should be defined")
+	}
+	m.Lock()
+	defer m.Unlock()
+	t, err := findTrash(id)
+	if err != nil {
+		return nil, err
+	}
+	props, err := decryptSecrets(t.Props)
+	if err != nil {
+		return nil, err
+	}
+	cw, err := m.createNamedConnection(ctx, t.ID, t.Typ, props)
+	if err != nil {
+		return nil, err
+	}
+	if err := conf.DropCfgKeyFromStorage(trashCfgType, t.Typ, t.ID); err != nil {
+		conf.Log.Warnf("remove restored connection %s from the trash error: %v", id, err)
+	}
+	recordEvent(id, EventCreated, "")
+	conf.Log.Infof("restore connection %s from the trash", id)
+	return cw, nil
+}
+
+// PurgeTrashedConnection deletes the dropped named connection from the trash permanently
+func PurgeTrashedConnection(id string) error {
+	all, err := loadTrash()
+	if err != nil {
+		return err
+	}
+	for _, t := range all {
+		if t.ID == id {
+			return conf.DropCfgKeyFromStorage(trashCfgType, t.Typ, t.ID)
+		}
+	}
+	return errorx.NewWithCode(errorx.NOT_FOUND, fmt.Sprintf("connection %s is not in the trash", id))
+}
+
+// purgeExpiredTrash deletes the expired connections in the trash. It runs in the patrol job.
+func purgeExpiredTrash() {
+	all, err := loadTrash()
+	if err != nil {
+		conf.Log.Warnf("load connection trash error: %v", err)
+		return
+	}
+	now := getClock().Now()
+	for _, t := range all {
+		if !t.expired(now) {
+			continue
+		}
+		if err := conf.DropCfgKeyFromStorage(trashCfgType, t.Typ, t.ID); err != nil {
+			conf.Log.Warnf("purge expired connection %s from the trash error: %v", t.ID, err)
+			continue
+		}
+		conf.Log.Infof("purge expired connection %s from the trash", t.ID)
+	}
+}
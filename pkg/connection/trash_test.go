@@ -0,0 +1,97 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/context"
+	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+)
+
+func TestConnectionTrash(t *testing.T) {
+	require.NoError(t, InitConnectionManager4Test())
+	conf.InitConf()
+	old := conf.Config.Connection.TrashTTL
+	conf.Config.Connection.TrashTTL = cast.DurationConf(time.Hour)
+	defer func() {
+		conf.Config.Connection.TrashTTL = old
+	}()
+	mock := clock.NewMock()
+	SetClock(mock)
+	defer SetClock(nil)
+	ctx := context.Background()
+	props := map[string]any{"a": "b"}
+
+	_, err := CreateNamedConnection(ctx, "trash1", "mock", props)
+	require.NoError(t, err)
+	require.NoError(t, DropNameConnection(ctx, "trash1"))
+	_, err = GetConnectionDetail(ctx, "trash1")
+	require.Error(t, err)
+	list, err := ListTrashedConnections()
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	require.Equal(t, "trash1", list[0].ID)
+	require.Equal(t, "mock", list[0].Typ)
+	require.Equal(t, props, list[0].Props)
+	require.Equal(t, mock.Now().Add(time.Hour).UnixMilli(), list[0].ExpireAt)
+
+	// restore with the original props
+	_, err = RestoreConnection(ctx, "trash1")
+	require.NoError(t, err)
+	meta, err := GetConnectionDetail(ctx, "trash1")
+	require.NoError(t, err)
+	require.Equal(t, props, meta.Props)
+	list, err = ListTrashedConnections()
+	require.NoError(t, err)
+	require.Len(t, list, 0)
+	_, err = RestoreConnection(ctx, "trash1")
+	require.Error(t, err)
+
+	// permanent drop skips the trash
+	require.NoError(t, DropNameConnectionPermanently(ctx, "trash1"))
+	list, err = ListTrashedConnections()
+	require.NoError(t, err)
+	require.Len(t, list, 0)
+
+	// purge
+	_, err = CreateNamedConnection(ctx, "trash2", "mock", props)
+	require.NoError(t, err)
+	require.NoError(t, DropNameConnection(ctx, "trash2"))
+	require.NoError(t, PurgeTrashedConnection("trash2"))
+	require.Error(t, PurgeTrashedConnection("trash2"))
+	_, err = RestoreConnection(ctx, "trash2")
+	require.Error(t, err)
+
+	// expired
+	_, err = CreateNamedConnection(ctx, "trash3", "mock", props)
+	require.NoError(t, err)
+	require.NoError(t, DropNameConnection(ctx, "trash3"))
+	mock.Add(time.Hour)
+	list, err = ListTrashedConnections()
+	require.NoError(t, err)
+	require.Len(t, list, 0)
+	_, err = RestoreConnection(ctx, "trash3")
+	require.Error(t, err)
+	purgeExpiredTrash()
+	all, err := loadTrash()
+	require.NoError(t, err)
+	require.Len(t, all, 0)
+}
@@ -0,0 +1,185 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+	"github.com/lf-edge/ekuiper/v2/pkg/syncx"
+)
+
+const (
+	TuningCfgKey = "$$connection_tuning"
+
+	// DefaultBackoffMaxElapsedDuration 0 means retry until the connection is dropped. It is overridden by the
+	// backoffMaxElapsedDuration of the config
+	DefaultBackoffMaxElapsedDuration = time.Duration(0)
+	DefaultPatrolInterval            = 15 * time.Second
+)
+
+// Tuning is the connection defaults which can be changed at runtime without restart.
+// The retry intervals apply to the retry policies without explicit intervals.
+type Tuning struct {
+	InitialInterval cast.DurationConf `json:"initialInterval"`
+	MaxInterval     cast.DurationConf `json:"maxInterval"`
+	// MaxElapsedTime stops retrying the dial after the elapsed time. 0 means retry forever
+	MaxElapsedTime cast.DurationConf `json:"maxElapsedTime"`
+	// PatrolInterval is the interval of the connection health check
+	PatrolInterval cast.DurationConf `json:"patrolInterval"`
+	// OperationTimeout bounds the health check ping and the other pool operations
+	OperationTimeout cast.DurationConf `json:"operationTimeout"`
+}
+
+var (
+	tuning      *Tuning
+	tuningLock  syncx.RWMutex
+	patrolReset = make(chan struct{}, 1)
+)
+
+func defaultTuning() *Tuning {
+	t := &Tuning{
+		InitialInterval:  cast.DurationConf(DefaultInitialInterval),
+		MaxInterval:      cast.DurationConf(DefaultMaxInterval),
+		MaxElapsedTime:   cast.DurationConf(DefaultBackoffMaxElapsedDuration),
+		PatrolInterval:   cast.DurationConf(DefaultPatrolInterval),
+		OperationTimeout: cast.DurationConf(10 * time.Second),
+	}
+	if conf.Config != nil && conf.Config.Connection.OperationTimeout > 0 {
+		t.OperationTimeout = conf.Config.Connection.OperationTimeout
+	}
+	if conf.Config != nil && conf.Config.Connection.BackoffMaxElapsedDuration > 0 {
+		t.MaxElapsedTime = conf.Config.Connection.BackoffMaxElapsedDuration
+	}
+	return t
+}
+
+// initTuning resets the tuning to the defaults and applies the persisted changes
+func initTuning() {
+	t := defaultTuning()
+	if !conf.IsTesting {
+		lt, err := loadTuning()
+		if err != nil {
+			conf.Log.Warnf("load connection tuning error, use the defaults: %v", err)
+		} else {
+			t = lt
+		}
+	}
+	tuningLock.Lock()
+	tuning = t
+	tuningLock.Unlock()
+}
+
+func loadTuning() (*Tuning, error) {
+	t := defaultTuning()
+	props, err := conf.LoadCfgKeyKV(TuningCfgKey)
+	if err != nil {
+		return nil, err
+	}
+	if props != nil {
+		if err := cast.MapToStruct(props, t); err != nil {
+			return nil, err
+		}
+	}
+	if err := t.Validate(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// GetTuning returns a copy of the current tuning
+func GetTuning() Tuning {
+	tuningLock.RLock()
+	defer tuningLock.RUnlock()
+	if tuning == nil {
+		return *defaultTuning()
+	}
+	return *tuning
+}
+
+// SetTuning validates and persists the tuning, then applies it to the new dials and the health check
+func SetTuning(t Tuning) error {
+	if err := t.Validate(); err != nil {
+		return err
+	}
+	err := conf.SaveCfgKeyToKV(TuningCfgKey, map[string]any{
+		"initialInterval":  time.Duration(t.InitialInterval).String(),
+		"maxInterval":      time.Duration(t.MaxInterval).String(),
+		"maxElapsedTime":   time.Duration(t.MaxElapsedTime).String(),
+		"patrolInterval":   time.Duration(t.PatrolInterval).String(),
+		"operationTimeout": time.Duration(t.OperationTimeout).String(),
+	})
+	if err != nil {
+		return err
+	}
+	tuningLock.Lock()
+	tuning = &t
+	tuningLock.Unlock()
+	select {
+	case patrolReset <- struct{}{}:
+	default:
+	}
+	conf.Log.Infof("set connection tuning: %+v", t)
+	return nil
+}
+
+func (t *Tuning) Validate() error {
+	if t.InitialInterval <= 0 {
+		return fmt.Errorf("initialInterval must be positive")
+	}
+	if t.MaxInterval < t.InitialInterval {
+		return fmt.Errorf("maxInterval must not be less than initialInterval")
+	}
+	if t.MaxElapsedTime < 0 {
+		return fmt.Errorf("maxElapsedTime must not be negative")
+	}
+	if t.PatrolInterval < cast.DurationConf(time.Second) {
+		return fmt.Errorf("patrolInterval must be at least 1s")
+	}
+	if t.OperationTimeout <= 0 {
+		return fmt.Errorf("operationTimeout must be positive")
+	}
+	return nil
+}
+
+// withMaxElapsed stops the backoff after the max elapsed time since it is created
+func withMaxElapsed(b backoff.BackOff, max time.Duration) backoff.BackOff {
+	if max <= 0 {
+		return b
+	}
+	return &elapsedBackOff{BackOff: b, max: max, start: getClock().Now()}
+}
+
+type elapsedBackOff struct {
+	backoff.BackOff
+	max   time.Duration
+	start time.Time
+}
+
+func (b *elapsedBackOff) NextBackOff() time.Duration {
+	if getClock().Since(b.start) > b.max {
+		return backoff.Stop
+	}
+	return b.BackOff.NextBackOff()
+}
+
+func (b *elapsedBackOff) Reset() {
+	b.start = getClock().Now()
+	b.BackOff.Reset()
+}
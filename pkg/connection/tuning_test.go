@@ -0,0 +1,63 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+	"github.com/lf-edge/ekuiper/v2/pkg/model"
+)
+
+func TestTuning(t *testing.T) {
+	require.NoError(t, InitConnectionManager4Test())
+	defer initTuning()
+	tu := GetTuning()
+	require.Equal(t, cast.DurationConf(DefaultInitialInterval), tu.InitialInterval)
+	require.Equal(t, cast.DurationConf(DefaultPatrolInterval), tu.PatrolInterval)
+
+	invalid := tu
+	invalid.MaxInterval = invalid.InitialInterval - 1
+	require.EqualError(t, SetTuning(invalid), "maxInterval must not be less than initialInterval")
+
+	tu.InitialInterval = cast.DurationConf(time.Second)
+	tu.MaxInterval = cast.DurationConf(time.Minute)
+	tu.MaxElapsedTime = cast.DurationConf(time.Hour)
+	require.NoError(t, SetTuning(tu))
+	require.Equal(t, tu, GetTuning())
+	// new retry policies use the tuned intervals
+	b := NewRetryPolicy(model.RetryConf{Policy: RetryConstant}).NewBackOff()
+	require.Equal(t, time.Second, b.NextBackOff())
+	// persisted
+	loaded, err := loadTuning()
+	require.NoError(t, err)
+	require.Equal(t, tu, *loaded)
+}
+
+func TestWithMaxElapsed(t *testing.T) {
+	b := withMaxElapsed(backoff.NewConstantBackOff(time.Millisecond), 20*time.Millisecond)
+	require.Equal(t, time.Millisecond, b.NextBackOff())
+	time.Sleep(30 * time.Millisecond)
+	require.Equal(t, backoff.Stop, b.NextBackOff())
+	b.Reset()
+	require.Equal(t, time.Millisecond, b.NextBackOff())
+	// 0 means no limit
+	c := backoff.NewConstantBackOff(time.Millisecond)
+	require.Equal(t, backoff.BackOff(c), withMaxElapsed(c, 0))
+}
@@ -0,0 +1,125 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+
+	"github.com/lf-edge/ekuiper/v2/internal/topo/context"
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+	"github.com/lf-edge/ekuiper/v2/pkg/modules"
+)
+
+// ConnectionTypeError is returned when the pooled connection does not implement the type expected by the caller,
+// such as a connectionSelector referring to the connection of another type. It is permanent since retry won't
+// change the type.
+type ConnectionTypeError struct {
+	ID       string
+	Expected string
+	Actual   string
+}
+
+func (e *ConnectionTypeError) Error() string {
+	return fmt.Sprintf("connection %s is %s, not %s", e.ID, e.Actual, e.Expected)
+}
+
+func (e *ConnectionTypeError) Kind() errorx.ErrorKind {
+	return errorx.KindPermanent
+}
+
+// AsConnection converts the connection got from the pool to the expected client type or interface
+func AsConnection[T modules.Connection](id string, conn modules.Connection) (T, error) {
+	if c, ok := conn.(T); ok {
+		return c, nil
+	}
+	var zero T
+	return zero, &ConnectionTypeError{ID: id, Expected: reflect.TypeOf((*T)(nil)).Elem().String(), Actual: fmt.Sprintf("%T", conn)}
+}
+
+// CurrentConnection gets the current connection of the wrapper by Conn and converts it to the expected type. The
+// consumers caching the typed client call it before each use to move to the new connection once it is swapped.
+func CurrentConnection[T modules.Connection](ctx api.StreamContext, cw *ConnWrapper) (T, error) {
+	var zero T
+	conn, err := cw.Conn(ctx)
+	if err != nil {
+		return zero, err
+	}
+	if conn == nil {
+		return zero, fmt.Errorf("connection %s is not ready", cw.ID)
+	}
+	return AsConnection[T](cw.ID, conn)
+}
+
+// FetchTypedConnection fetches the connection like FetchConnection, waits until it is created and converts it to
+// the expected type. The reference of refId is released if it fails or the type does not match.
+func FetchTypedConnection[T modules.Connection](ctx api.StreamContext, refId, typ string, props map[string]any) (T, error) {
+	var zero T
+	cw, err := FetchConnection(ctx, refId, typ, props, nil)
+	if err != nil {
+		return zero, err
+	}
+	conn, err := cw.Wait(ctx)
+	if err == nil && conn == nil {
+		err = fmt.Errorf("connection %s is not ready", cw.ID)
+	}
+	var c T
+	if err == nil {
+		c, err = AsConnection[T](cw.ID, conn)
+	}
+	if err != nil {
+		globalConnectionManager.detachByRef(ctx, cw.ID, refId)
+		return zero, err
+	}
+	return c, nil
+}
+
+// ConnectionOfType checks whether the created connection of the id or the alias can be assigned to the target, which
+// must be a non-nil pointer to the client type or an interface, and assigns it if so. It does not wait for the
+// connection being created.
+func ConnectionOfType(id string, target any) error {
+	v := reflect.ValueOf(target)
+	if !v.IsValid() || v.Kind() != reflect.Pointer || v.IsNil() {
+		return errorx.NewWithCode(errorx.ConnectionInvalidErr, "the target must be a non-nil pointer")
+	}
+	meta, ok := globalConnectionManager.load()[id]
+	if !ok {
+		if t, isAlias := resolveAlias(id); isAlias {
+			meta, ok = globalConnectionManager.load()[t]
+		}
+	}
+	if !ok {
+		return errorx.NewWithCode(errorx.NOT_FOUND, fmt.Sprintf("connection %s not existed", id))
+	}
+	if meta.cw == nil || !meta.cw.IsInitialized() {
+		return errorx.NewTransient(fmt.Errorf("connection %s is not created yet", id))
+	}
+	conn, err := meta.cw.Wait(context.Background())
+	if err != nil {
+		return err
+	}
+	if conn == nil {
+		return errorx.NewTransient(fmt.Errorf("connection %s is not created yet", id))
+	}
+	expected := v.Elem().Type()
+	cv := reflect.ValueOf(conn)
+	if !cv.Type().AssignableTo(expected) {
+		return &ConnectionTypeError{ID: id, Expected: expected.String(), Actual: cv.Type().String()}
+	}
+	v.Elem().Set(cv)
+	return nil
+}
@@ -0,0 +1,108 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/topo/context"
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+	"github.com/lf-edge/ekuiper/v2/pkg/modules"
+)
+
+type refConnection interface {
+	modules.Connection
+	Ref(ctx api.StreamContext) int
+}
+
+func TestFetchTypedConnection(t *testing.T) {
+	require.NoError(t, InitConnectionManager4Test())
+	ctx := context.Background()
+	_, err := CreateNamedConnection(ctx, "typed1", "mock", nil)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, DropNameConnectionPermanently(ctx, "typed1"))
+	}()
+	refId := extractRefId(ctx)
+	props := map[string]any{"connectionSelector": "typed1"}
+
+	c, err := FetchTypedConnection[*mockConnection](ctx, refId, "mock", props)
+	require.NoError(t, err)
+	require.NotNil(t, c)
+	require.Equal(t, 1, globalConnectionManager.getConnectionRef("typed1"))
+	require.NoError(t, DetachConnection(ctx, "typed1"))
+
+	rc, err := FetchTypedConnection[refConnection](ctx, refId, "mock", props)
+	require.NoError(t, err)
+	require.NotNil(t, rc)
+	require.NoError(t, DetachConnection(ctx, "typed1"))
+
+	// the reference is released if the type does not match
+	_, err = FetchTypedConnection[*blockConnection](ctx, refId, "mock", props)
+	require.Error(t, err)
+	var te *ConnectionTypeError
+	require.True(t, errors.As(err, &te))
+	require.Equal(t, "typed1", te.ID)
+	require.Equal(t, "*connection.blockConnection", te.Expected)
+	require.Equal(t, "*connection.mockConnection", te.Actual)
+	require.Equal(t, errorx.KindPermanent, errorx.KindOf(err))
+	require.Equal(t, 0, globalConnectionManager.getConnectionRef("typed1"))
+
+	// the ref other than the one of ctx is released and the ref of ctx is kept
+	_, err = FetchConnection(ctx, refId, "mock", props, nil)
+	require.NoError(t, err)
+	_, err = FetchTypedConnection[*blockConnection](ctx, "typedRef", "mock", props)
+	require.Error(t, err)
+	meta, err := GetConnectionDetail(ctx, "typed1")
+	require.NoError(t, err)
+	require.Equal(t, []string{refId}, meta.GetRefNames())
+	require.NoError(t, DetachConnection(ctx, "typed1"))
+}
+
+func TestConnectionOfType(t *testing.T) {
+	require.NoError(t, InitConnectionManager4Test())
+	ctx := context.Background()
+	cw, err := CreateNamedConnection(ctx, "typed2", "mock", nil)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, DropNameConnectionPermanently(ctx, "typed2"))
+	}()
+	_, err = cw.Wait(ctx)
+	require.NoError(t, err)
+
+	var c *mockConnection
+	require.NoError(t, ConnectionOfType("typed2", &c))
+	require.NotNil(t, c)
+	var rc refConnection
+	require.NoError(t, ConnectionOfType("typed2", &rc))
+	require.NotNil(t, rc)
+
+	var b *blockConnection
+	err = ConnectionOfType("typed2", &b)
+	var te *ConnectionTypeError
+	require.True(t, errors.As(err, &te))
+	require.Nil(t, b)
+
+	require.Error(t, ConnectionOfType("typed2", c))
+	require.Error(t, ConnectionOfType("typed2", nil))
+	err = ConnectionOfType("notexist", &c)
+	code, ok := errorx.GetErrorCode(err)
+	require.True(t, ok)
+	require.Equal(t, errorx.NOT_FOUND, code)
+}
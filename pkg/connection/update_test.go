@@ -0,0 +1,150 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/context"
+	mockContext "github.com/lf-edge/ekuiper/v2/pkg/mock/context"
+	"github.com/lf-edge/ekuiper/v2/pkg/modules"
+)
+
+type propsConnection struct {
+	mockConnection
+	props  map[string]any
+	closed atomic.Bool
+}
+
+func (p *propsConnection) Provision(ctx api.StreamContext, conId string, props map[string]any) error {
+	if fail, _ := props["fail"].(bool); fail {
+		return backoff.Permanent(errors.New("provision failed"))
+	}
+	p.id, p.props = conId, props
+	return nil
+}
+
+func (p *propsConnection) Close(ctx api.StreamContext) error {
+	p.closed.Store(true)
+	return nil
+}
+
+func TestUpdateNamedConnection(t *testing.T) {
+	require.NoError(t, InitConnectionManager4Test())
+	modules.RegisterConnection("propsconn", func(ctx api.StreamContext) modules.Connection {
+		return &propsConnection{}
+	})
+	ctx := context.Background()
+	_, err := UpdateNamedConnection(ctx, "upd1", map[string]any{"v": 1})
+	require.Error(t, err)
+
+	_, err = CreateNamedConnection(ctx, "upd1", "propsconn", map[string]any{"v": 1})
+	require.NoError(t, err)
+	defer func() {
+		_ = DetachConnection(ctx, "upd1")
+		_ = DropNameConnectionPermanently(ctx, "upd1")
+	}()
+	cw, err := FetchConnection(ctx, "ref1", "propsconn", map[string]any{"connectionSelector": "upd1"}, nil)
+	require.NoError(t, err)
+	c, err := cw.Wait(ctx)
+	require.NoError(t, err)
+	old := c.(*propsConnection)
+
+	// swapped for the attached consumer and persisted
+	ncw, err := UpdateNamedConnection(ctx, "upd1", map[string]any{"v": 2})
+	require.NoError(t, err)
+	require.Same(t, cw, ncw)
+	c, err = cw.Wait(ctx)
+	require.NoError(t, err)
+	cur := c.(*propsConnection)
+	require.NotSame(t, old, cur)
+	require.Equal(t, map[string]any{"v": 2}, cur.props)
+	// the consumer still holds the old connection until it gets the new one
+	require.False(t, old.closed.Load())
+	c, err = cw.Conn(ctx)
+	require.NoError(t, err)
+	require.Same(t, cur, c)
+	require.True(t, old.closed.Load())
+	require.Equal(t, 1, globalConnectionManager.getConnectionRef("upd1"))
+	meta, err := GetConnectionDetail(ctx, "upd1")
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"v": 2}, meta.Props)
+	stored, err := conf.GetCfgFromKVStorage("connections", "propsconn", "upd1")
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"v": 2}, stored["connections.propsconn.upd1"])
+
+	// rollback if the new connection fails
+	_, err = UpdateNamedConnection(ctx, "upd1", map[string]any{"fail": true})
+	require.Error(t, err)
+	c, err = cw.Wait(ctx)
+	require.NoError(t, err)
+	require.Same(t, cur, c)
+	require.False(t, cur.closed.Load())
+	require.Equal(t, map[string]any{"v": 2}, meta.Props)
+	stored, err = conf.GetCfgFromKVStorage("connections", "propsconn", "upd1")
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"v": 2}, stored["connections.propsconn.upd1"])
+}
+
+func TestUpdateRebindConnection(t *testing.T) {
+	require.NoError(t, InitConnectionManager4Test())
+	modules.RegisterConnection("propsconn", func(ctx api.StreamContext) modules.Connection {
+		return &propsConnection{}
+	})
+	ctx := context.Background()
+	_, err := CreateNamedConnection(ctx, "upd2", "propsconn", map[string]any{"v": 1})
+	require.NoError(t, err)
+	defer func() {
+		_ = DropNameConnectionPermanently(ctx, "upd2")
+	}()
+	ctx1 := mockContext.NewMockContext("rebind1", "op1")
+	ctx2 := mockContext.NewMockContext("rebind2", "op1")
+	cw, err := FetchConnection(ctx1, extractRefId(ctx1), "propsconn", map[string]any{"connectionSelector": "upd2"}, nil)
+	require.NoError(t, err)
+	_, err = FetchConnection(ctx2, extractRefId(ctx2), "propsconn", map[string]any{"connectionSelector": "upd2"}, nil)
+	require.NoError(t, err)
+	c, err := cw.Wait(ctx)
+	require.NoError(t, err)
+	old := c.(*propsConnection)
+	var rebound modules.Connection
+	cw.OnRebind(ctx1, func(conn modules.Connection) {
+		require.False(t, old.closed.Load())
+		rebound = conn
+	})
+
+	// the consumer of rule1 is rebound while the consumer of rule2 still holds the old connection
+	_, err = UpdateNamedConnection(ctx, "upd2", map[string]any{"v": 2})
+	require.NoError(t, err)
+	cur, err := cw.Wait(ctx)
+	require.NoError(t, err)
+	require.Same(t, cur, rebound)
+	require.False(t, old.closed.Load())
+
+	// closed once the last holder detaches
+	require.NoError(t, DetachConnection(ctx2, "upd2"))
+	require.Eventually(t, func() bool {
+		return old.closed.Load()
+	}, time.Second, 10*time.Millisecond)
+	require.False(t, cur.(*propsConnection).closed.Load())
+	require.NoError(t, DetachConnection(ctx1, "upd2"))
+}
@@ -0,0 +1,150 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+	"github.com/lf-edge/ekuiper/v2/pkg/modules"
+)
+
+// validationConnId is the id to provision the connection under validation, which is never added to the pool
+const validationConnId = "$$validate$$"
+
+// ValidationResult is the result of validating the props of a connection without creating it
+type ValidationResult struct {
+	Valid bool `json:"valid"`
+	// SchemaErrors are the errors of the props themselves, such as the missing required props and the provision errors
+	SchemaErrors []string `json:"schemaErrors,omitempty"`
+	// ReachabilityError is the error to dial or ping the connection. It is only checked if the props are valid.
+	ReachabilityError string `json:"reachabilityError,omitempty"`
+}
+
+// ValidateConnection validates the props of the connection type and then dials and pings it once, so that the props
+// can be tested before creating a named connection. The connection is closed right after and is never added to the
+// pool or the store. It only returns an error if the type is unknown.
+func ValidateConnection(ctx api.StreamContext, typ string, props map[string]any) (*ValidationResult, error) {
+	provider, ok := modules.GetConnectionProvider(strings.ToLower(typ))
+	if !ok {
+		return nil, errorx.NewWithCode(errorx.ConnectionInvalidErr, fmt.Sprintf("unknown connection type %s", typ))
+	}
+	if props == nil {
+		props = map[string]any{}
+	}
+	opCtx, cancel := withTimeout(ctx)
+	defer cancel()
+	conn := provider(opCtx)
+	r := &ValidationResult{SchemaErrors: validateSchema(opCtx, conn, typ, props)}
+	if len(r.SchemaErrors) > 0 {
+		return r, nil
+	}
+	resolved, err := resolveEndpointProps(typ, withoutReservedProps(props))
+	if err != nil {
+		r.ReachabilityError = err.Error()
+		return r, nil
+	}
+	if err := conn.Provision(opCtx, validationConnId, resolved); err != nil {
+		r.SchemaErrors = append(r.SchemaErrors, err.Error())
+		return r, nil
+	}
+	if err := conn.Dial(opCtx); err != nil {
+		r.ReachabilityError = err.Error()
+		return r, nil
+	}
+	defer conn.Close(opCtx)
+	if err := conn.Ping(opCtx); err != nil {
+		r.ReachabilityError = err.Error()
+		return r, nil
+	}
+	r.Valid = true
+	return r, nil
+}
+
+// validateSchema checks the props by the descriptor and the Validate hook of the connection type if any
+func validateSchema(ctx api.StreamContext, conn modules.Connection, typ string, props map[string]any) []string {
+	var errs []string
+	for _, f := range checkDescriptor(typ, props) {
+		errs = append(errs, f.String())
+	}
+	if err := validateReservedProps(typ, props); err != nil {
+		errs = append(errs, err.Error())
+	}
+	if k, masked := maskedSecret(props); masked {
+		errs = append(errs, fmt.Sprintf("secret prop %s is masked", k))
+	}
+	if v, ok := conn.(modules.Validator); ok {
+		if err := v.Validate(ctx, props); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	return errs
+}
+
+// validateProps checks the props by the schema of the connection type before creating the connection, so that the
+// invalid props are reported by the fields instead of the provision failure deep inside the driver
+func validateProps(ctx api.StreamContext, typ string, props map[string]any) error {
+	fields := checkDescriptor(typ, props)
+	if provider, ok := modules.GetConnectionProvider(strings.ToLower(typ)); ok {
+		if v, ok := provider(ctx).(modules.Validator); ok {
+			if err := v.Validate(ctx, props); err != nil {
+				fields = append(fields, errorx.FieldError{Message: err.Error()})
+			}
+		}
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	return errorx.NewValidationError(errorx.ConnectionPropsErr, fmt.Sprintf("invalid props of connection type %s", typ), fields)
+}
+
+// checkDescriptor checks the required props, the enums and the types by the descriptor of the connection type
+func checkDescriptor(typ string, props map[string]any) []errorx.FieldError {
+	desc, ok := modules.GetConnectionDescriptor(strings.ToLower(typ))
+	if !ok {
+		return nil
+	}
+	var fields []errorx.FieldError
+	for _, p := range desc.Props {
+		v, ok := props[p.Name]
+		if !ok || v == nil || v == "" {
+			if p.Required {
+				fields = append(fields, errorx.FieldError{Field: p.Name, Message: "is required"})
+			}
+			continue
+		}
+		if !modules.CheckPropType(p.Type, v) {
+			fields = append(fields, errorx.FieldError{Field: p.Name, Message: fmt.Sprintf("must be %s", p.Type)})
+			continue
+		}
+		if len(p.Values) > 0 && !containsValue(p.Values, v) {
+			fields = append(fields, errorx.FieldError{Field: p.Name, Message: fmt.Sprintf("must be one of %v", p.Values)})
+		}
+	}
+	return fields
+}
+
+func containsValue(values []any, v any) bool {
+	s := fmt.Sprint(v)
+	for _, value := range values {
+		if fmt.Sprint(value) == s {
+			return true
+		}
+	}
+	return false
+}
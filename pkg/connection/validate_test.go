@@ -0,0 +1,152 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/topo/context"
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+	"github.com/lf-edge/ekuiper/v2/pkg/modules"
+)
+
+type validatedConnection struct {
+	mockConnection
+	props  map[string]any
+	closed bool
+}
+
+func (v *validatedConnection) Validate(_ api.StreamContext, props map[string]any) error {
+	if props["port"] == 0.0 {
+		return errors.New("port must be positive")
+	}
+	return nil
+}
+
+func (v *validatedConnection) Provision(ctx api.StreamContext, conId string, props map[string]any) error {
+	v.props = props
+	return v.mockConnection.Provision(ctx, conId, props)
+}
+
+func (v *validatedConnection) Ping(_ api.StreamContext) error {
+	if v.props["server"] == "unreachable" {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+func (v *validatedConnection) Close(_ api.StreamContext) error {
+	v.closed = true
+	return nil
+}
+
+func TestValidateConnection(t *testing.T) {
+	require.NoError(t, InitConnectionManager4Test())
+	var last *validatedConnection
+	modules.RegisterConnection("validconn", func(_ api.StreamContext) modules.Connection {
+		last = &validatedConnection{}
+		return last
+	})
+	modules.RegisterConnectionDescriptor(modules.ConnectionDescriptor{
+		Type: "validconn",
+		Props: []modules.ConnectionPropDescriptor{
+			{Name: "server", Type: "string", Required: true},
+			{Name: "protocol", Type: "string", Values: []any{"tcp", "ssl"}},
+		},
+	})
+	ctx := context.Background()
+
+	_, err := ValidateConnection(ctx, "nosuchconn", nil)
+	require.Error(t, err)
+
+	r, err := ValidateConnection(ctx, "validconn", map[string]any{"protocol": "udp", "port": 0.0, "password": HiddenSecret})
+	require.NoError(t, err)
+	require.False(t, r.Valid)
+	require.Equal(t, []string{
+		"prop server is required",
+		"prop protocol must be one of [tcp ssl]",
+		"secret prop password is masked",
+		"port must be positive",
+	}, r.SchemaErrors)
+	require.Empty(t, r.ReachabilityError)
+
+	r, err = ValidateConnection(ctx, "validconn", map[string]any{"server": "unreachable"})
+	require.NoError(t, err)
+	require.False(t, r.Valid)
+	require.Empty(t, r.SchemaErrors)
+	require.Equal(t, "connection refused", r.ReachabilityError)
+	require.True(t, last.closed)
+
+	r, err = ValidateConnection(ctx, "ValidConn", map[string]any{"server": "tcp://127.0.0.1:1883", "protocol": "tcp"})
+	require.NoError(t, err)
+	require.True(t, r.Valid)
+
+	// nothing is added to the pool or the store
+	_, err = GetConnectionDetail(ctx, validationConnId)
+	require.Error(t, err)
+}
+
+type schemaConnConf struct {
+	Server   string        `json:"server" required:"true"`
+	Protocol string        `json:"protocol" enum:"tcp,ssl"`
+	Port     int           `json:"port"`
+	Timeout  time.Duration `json:"timeout"`
+}
+
+func TestCreateValidatesProps(t *testing.T) {
+	require.NoError(t, InitConnectionManager4Test())
+	modules.RegisterConnection("schemaconn", func(_ api.StreamContext) modules.Connection {
+		return &validatedConnection{}
+	})
+	defer modules.UnregisterConnection("schemaconn")
+	props := modules.PropsFromStruct(&schemaConnConf{})
+	require.Equal(t, []modules.ConnectionPropDescriptor{
+		{Name: "server", Type: modules.PropTypeString, Required: true},
+		{Name: "protocol", Type: modules.PropTypeString, Values: []any{"tcp", "ssl"}},
+		{Name: "port", Type: modules.PropTypeInt},
+		{Name: "timeout", Type: modules.PropTypeDuration},
+	}, props)
+	modules.RegisterConnectionDescriptor(modules.ConnectionDescriptor{Type: "schemaconn", Props: props})
+	ctx := context.Background()
+
+	_, err := CreateNamedConnection(ctx, "schema1", "schemaconn", map[string]any{"protocol": "udp", "port": 1.5, "timeout": "10s"})
+	require.Error(t, err)
+	code, _ := errorx.GetErrorCode(err)
+	require.Equal(t, errorx.ConnectionPropsErr, code)
+	require.Equal(t, []errorx.FieldError{
+		{Field: "server", Message: "is required"},
+		{Field: "protocol", Message: "must be one of [tcp ssl]"},
+		{Field: "port", Message: "must be int"},
+	}, errorx.GetFieldErrors(err))
+	_, err = GetConnectionDetail(ctx, "schema1")
+	require.Error(t, err)
+
+	_, err = CreateNamedConnection(ctx, "schema1", "schemaconn", map[string]any{"server": "tcp://127.0.0.1:1883", "port": 0.0})
+	require.EqualError(t, err, "invalid props of connection type schemaconn: port must be positive")
+
+	_, err = CreateNamedConnection(ctx, "schema1", "schemaconn", map[string]any{"server": "tcp://127.0.0.1:1883", "port": 1883.0})
+	require.NoError(t, err)
+	_, err = UpdateNamedConnection(ctx, "schema1", map[string]any{"server": "tcp://127.0.0.1:1883", "timeout": "soon"})
+	require.Equal(t, []errorx.FieldError{{Field: "timeout", Message: "must be duration"}}, errorx.GetFieldErrors(err))
+	meta, err := GetConnectionDetail(ctx, "schema1")
+	require.NoError(t, err)
+	require.Equal(t, 1883.0, meta.Props["port"])
+	require.NoError(t, DropNameConnectionPermanently(ctx, "schema1"))
+}
@@ -0,0 +1,53 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"fmt"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+	"github.com/lf-edge/ekuiper/v2/pkg/modules"
+)
+
+// ConnectionWarming is the status of the connection which is dialed and running its warm-up hook. The status goes
+// from connecting to warming and then connected, so that the rules don't use the connection before it is warmed up.
+const ConnectionWarming = "warming"
+
+// warmUpConn runs the warm-up hook of the connection if it implements modules.Warmable, and returns whether the hook
+// is run. The hook is bounded by the operation timeout. A failed warm-up is retried like a failed dial unless it is
+// classified as not retryable.
+func warmUpConn(ctx api.StreamContext, meta *Meta, conn modules.Connection) (bool, error) {
+	w, ok := conn.(modules.Warmable)
+	if !ok {
+		return false, nil
+	}
+	meta.NotifyStatus(ConnectionWarming, "")
+	start := getClock().Now()
+	opCtx, cancel := withTimeout(ctx)
+	defer cancel()
+	err := safeCall(meta.ID, "warmup", func() error {
+		return w.Warmup(opCtx)
+	})
+	if err != nil {
+		if errorx.KindOf(err) == errorx.KindUnknown {
+			err = errorx.WithKind(err, errorx.KindTransient)
+		}
+		return true, fmt.Errorf("warm up connection %s failed: %w", meta.ID, err)
+	}
+	connLogger(meta.ID).Infof("connection %s is warmed up in %v", meta.ID, getClock().Since(start))
+	return true, nil
+}
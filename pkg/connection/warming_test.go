@@ -0,0 +1,128 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/topo/context"
+	"github.com/lf-edge/ekuiper/v2/pkg/modules"
+)
+
+var (
+	warmupGate  chan struct{}
+	warmupFails atomic.Int32
+	warmups     atomic.Int32
+	warmCloses  atomic.Int32
+)
+
+type warmableConnection struct {
+	mockConnection
+}
+
+func (w *warmableConnection) Warmup(ctx api.StreamContext) error {
+	warmups.Add(1)
+	if warmupFails.Add(-1) >= 0 {
+		return errors.New("cache not ready")
+	}
+	select {
+	case <-warmupGate:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (w *warmableConnection) Close(ctx api.StreamContext) error {
+	warmCloses.Add(1)
+	return nil
+}
+
+func TestWarmup(t *testing.T) {
+	require.NoError(t, InitConnectionManager4Test())
+	modules.RegisterConnection("warmconn", func(ctx api.StreamContext) modules.Connection {
+		return &warmableConnection{}
+	})
+	ctx := context.Background()
+	warmupGate = make(chan struct{})
+	warmupFails.Store(0)
+	warmups.Store(0)
+	cw, err := CreateNamedConnection(ctx, "warm1", "warmconn", nil)
+	require.NoError(t, err)
+	defer func() {
+		_ = DropNameConnectionPermanently(ctx, "warm1")
+	}()
+	meta, err := GetConnectionDetail(ctx, "warm1")
+	require.NoError(t, err)
+	// not used until warmed up
+	require.Eventually(t, func() bool {
+		s, _ := meta.GetStatus()
+		return s == ConnectionWarming
+	}, time.Second, 10*time.Millisecond)
+	require.False(t, GetPoolHealth(ctx).Ready)
+	close(warmupGate)
+	c, err := cw.Wait(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, c)
+	s, _ := meta.GetStatus()
+	require.Equal(t, api.ConnectionConnected, s)
+	require.Equal(t, int32(1), warmups.Load())
+}
+
+func TestWarmupRetry(t *testing.T) {
+	require.NoError(t, InitConnectionManager4Test())
+	mock := clock.NewMock()
+	SetClock(mock)
+	defer SetClock(nil)
+	modules.RegisterConnection("warmconn", func(ctx api.StreamContext) modules.Connection {
+		return &warmableConnection{}
+	})
+	ctx := context.Background()
+	warmupGate = make(chan struct{})
+	close(warmupGate)
+	warmupFails.Store(1)
+	warmups.Store(0)
+	warmCloses.Store(0)
+	cw, err := CreateNamedConnection(ctx, "warm2", "warmconn", nil)
+	require.NoError(t, err)
+	defer func() {
+		_ = DropNameConnectionPermanently(ctx, "warm2")
+	}()
+	meta, err := GetConnectionDetail(ctx, "warm2")
+	require.NoError(t, err)
+	// the failed warm-up is retried like a failed dial
+	require.Eventually(t, func() bool {
+		s, e := meta.GetStatus()
+		return s == api.ConnectionDisconnected && e == "warm up connection warm2 failed: cache not ready"
+	}, time.Second, 10*time.Millisecond)
+	require.Eventually(t, func() bool {
+		mock.Add(time.Minute)
+		s, _ := meta.GetStatus()
+		return s == api.ConnectionConnected
+	}, time.Second, 10*time.Millisecond)
+	c, err := cw.Wait(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, c)
+	require.Equal(t, int32(2), warmups.Load())
+	// the connection failed to warm up is closed before the retry
+	require.Equal(t, int32(1), warmCloses.Load())
+}
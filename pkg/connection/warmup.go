@@ -0,0 +1,151 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	topoContext "github.com/lf-edge/ekuiper/v2/internal/topo/context"
+)
+
+// The warm-up pre-establishes the anonymous connections of a scheduled rule before its window opens. The pool
+// records the anonymous connections fetched by each rule, so the connections of a rule are known after its first run.
+// A warmed connection is held by the warm-up reference of the rule until the rule attaches it or the warm-up is
+// released.
+
+type footprint struct {
+	typ   string
+	props map[string]any
+}
+
+func warmUpRef(ruleId string) string {
+	return ruleId + "_warmup"
+}
+
+// recordFootprint remembers the anonymous connection fetched by the rule to warm it up later
+func (m *ConnectionManager) recordFootprint(ruleId string, meta *Meta) {
+	if ruleId == "" || meta == nil || meta.Named {
+		return
+	}
+	m.footprintsLock.Lock()
+	defer m.footprintsLock.Unlock()
+	fp, ok := m.footprints[ruleId]
+	if !ok {
+		fp = make(map[string]footprint)
+		m.footprints[ruleId] = fp
+	}
+	fp[meta.ID] = footprint{typ: meta.Typ, props: meta.Props}
+}
+
+func (m *ConnectionManager) getFootprints(ruleId string) map[string]footprint {
+	m.footprintsLock.Lock()
+	defer m.footprintsLock.Unlock()
+	result := make(map[string]footprint, len(m.footprints[ruleId]))
+	for id, fp := range m.footprints[ruleId] {
+		result[id] = fp
+	}
+	return result
+}
+
+// WarmUp creates the anonymous connections used by the last run of the rule ahead of its start. It returns the count
+// of the warmed connections. It does nothing if the rule is warmed already or has never run.
+func (m *ConnectionManager) WarmUp(ruleId string) int {
+	m.warmUps.Lock()
+	defer m.warmUps.Unlock()
+	if _, ok := m.warmUps.warmed[ruleId]; ok {
+		return 0
+	}
+	fps := m.getFootprints(ruleId)
+	if len(fps) == 0 {
+		return 0
+	}
+	m.Lock()
+	defer m.Unlock()
+	if m.closed {
+		return 0
+	}
+	ref := warmUpRef(ruleId)
+	ids := make([]string, 0, len(fps))
+	for id, fp := range fps {
+		meta, ok := m.connectionPool[id]
+		if !ok {
+			if err := m.checkConnectionQuota(id, fp.typ); err != nil {
+				conf.Log.Warnf("warm up connection %s for rule %s failed: %v", id, ruleId, err)
+				continue
+			}
+			meta = &Meta{
+				ID:    id,
+				Typ:   fp.typ,
+				Props: fp.props,
+			}
+			meta.cw = newConnWrapper(m.ctx, meta)
+			m.put(id, meta)
+		}
+		meta.AddRef(ref, nil)
+		a := Attacher{RefID: ref, RuleID: ruleId, OpID: "warmup", AttachedAt: getClock().Now()}
+		meta.storeAttacher(a)
+		meta.auditRef(RefAttach, a)
+		ids = append(ids, id)
+	}
+	m.warmUps.warmed[ruleId] = ids
+	conf.Log.Infof("warm up connections %v for rule %s", ids, ruleId)
+	return len(ids)
+}
+
+// ReleaseWarmUp removes the warm-up reference of the rule. The warmed connections are closed if the rule has not
+// attached them.
+func (m *ConnectionManager) ReleaseWarmUp(ruleId string) {
+	m.warmUps.Lock()
+	defer m.warmUps.Unlock()
+	m.releaseWarmUp(ruleId)
+}
+
+func (m *ConnectionManager) releaseWarmUp(ruleId string) {
+	ids, ok := m.warmUps.warmed[ruleId]
+	if !ok {
+		return
+	}
+	delete(m.warmUps.warmed, ruleId)
+	m.Lock()
+	defer m.Unlock()
+	ctx := topoContext.Background()
+	ref := warmUpRef(ruleId)
+	for _, id := range ids {
+		// the connection may be force closed and recreated in the meantime
+		if meta, ok := m.connectionPool[id]; ok {
+			if _, held := meta.ref.Load(ref); held {
+				m.detachRef(ctx, id, ref)
+			}
+		}
+	}
+	conf.Log.Infof("release warm-up connections %v for rule %s", ids, ruleId)
+}
+
+// IsWarmedUp returns whether the connections of the rule are held by the warm-up
+func (m *ConnectionManager) IsWarmedUp(ruleId string) bool {
+	m.warmUps.Lock()
+	defer m.warmUps.Unlock()
+	_, ok := m.warmUps.warmed[ruleId]
+	return ok
+}
+
+// ForgetWarmUp releases the warm-up of the deleted rule and forgets its connections
+func (m *ConnectionManager) ForgetWarmUp(ruleId string) {
+	m.warmUps.Lock()
+	defer m.warmUps.Unlock()
+	m.releaseWarmUp(ruleId)
+	m.footprintsLock.Lock()
+	delete(m.footprints, ruleId)
+	m.footprintsLock.Unlock()
+}
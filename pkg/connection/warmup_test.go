@@ -0,0 +1,68 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	mockContext "github.com/lf-edge/ekuiper/v2/pkg/mock/context"
+)
+
+func TestWarmUp(t *testing.T) {
+	require.NoError(t, InitConnectionManager4Test())
+	ctx := mockContext.NewMockContext("warmRule", "op1")
+	defer ForgetWarmUp("warmRule")
+
+	// never run, nothing to warm up
+	require.Equal(t, 0, WarmUp("warmRule"))
+	require.False(t, IsWarmedUp("warmRule"))
+
+	// the first run records the anonymous connection
+	_, err := FetchConnection(ctx, "warmConn", "mock", map[string]any{"a": 1}, nil)
+	require.NoError(t, err)
+	require.NoError(t, DetachConnection(ctx, "warmConn"))
+	_, err = GetConnectionDetail(ctx, "warmConn")
+	require.Error(t, err)
+
+	// warm up before the next run and the rule attaches the warmed connection
+	require.Equal(t, 1, WarmUp("warmRule"))
+	require.True(t, IsWarmedUp("warmRule"))
+	require.Equal(t, 0, WarmUp("warmRule"))
+	meta, err := GetConnectionDetail(ctx, "warmConn")
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"a": 1}, meta.Props)
+	require.Equal(t, 1, globalConnectionManager.getConnectionRef("warmConn"))
+	cw, err := FetchConnection(ctx, "warmConn", "mock", map[string]any{"a": 1}, nil)
+	require.NoError(t, err)
+	require.Same(t, meta.cw, cw)
+	ReleaseWarmUp("warmRule")
+	require.False(t, IsWarmedUp("warmRule"))
+	require.Equal(t, 1, globalConnectionManager.getConnectionRef("warmConn"))
+	require.NoError(t, DetachConnection(ctx, "warmConn"))
+	_, err = GetConnectionDetail(ctx, "warmConn")
+	require.Error(t, err)
+
+	// the warmed connection is closed if the rule does not run
+	require.Equal(t, 1, WarmUp("warmRule"))
+	ReleaseWarmUp("warmRule")
+	_, err = GetConnectionDetail(ctx, "warmConn")
+	require.Error(t, err)
+
+	// the deleted rule is forgotten
+	ForgetWarmUp("warmRule")
+	require.Equal(t, 0, WarmUp("warmRule"))
+}
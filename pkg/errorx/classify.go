@@ -0,0 +1,103 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errorx
+
+import (
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"syscall"
+)
+
+// Classifier classifies the errors of a specific driver. It returns false if the error is not recognized so that the
+// next classifier is tried.
+type Classifier func(err error) (ErrorKind, bool)
+
+type namedClassifier struct {
+	name string
+	fn   Classifier
+}
+
+var classifiers struct {
+	sync.RWMutex
+	list []namedClassifier
+}
+
+// RegisterClassifier registers the classifier of the driver errors, usually in the init of the connection module.
+// The classifiers are tried in the registration order for the errors without explicit kind or error code. Register
+// with the same name again replaces the classifier.
+func RegisterClassifier(name string, c Classifier) {
+	classifiers.Lock()
+	defer classifiers.Unlock()
+	for i, nc := range classifiers.list {
+		if nc.name == name {
+			classifiers.list[i].fn = c
+			return
+		}
+	}
+	classifiers.list = append(classifiers.list, namedClassifier{name: name, fn: c})
+}
+
+// UnregisterClassifier removes the classifier of the name if it exists
+func UnregisterClassifier(name string) {
+	classifiers.Lock()
+	defer classifiers.Unlock()
+	for i, nc := range classifiers.list {
+		if nc.name == name {
+			classifiers.list = append(classifiers.list[:i], classifiers.list[i+1:]...)
+			return
+		}
+	}
+}
+
+func classifyRegistered(err error) (ErrorKind, bool) {
+	classifiers.RLock()
+	defer classifiers.RUnlock()
+	for _, nc := range classifiers.list {
+		if k, ok := nc.fn(err); ok && k != KindUnknown {
+			return k, true
+		}
+	}
+	return KindUnknown, false
+}
+
+// classifyNetwork classifies the common network errors of the standard library which are returned by most drivers
+func classifyNetwork(err error) (ErrorKind, bool) {
+	var ne net.Error
+	if errors.As(err, &ne) && ne.Timeout() {
+		return KindTimeout, true
+	}
+	// the host not found is transient too, the host name may be registered later such as the containers starting up
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return KindTransient, true
+	}
+	var addrErr *net.AddrError
+	if errors.As(err, &addrErr) {
+		return KindPermanent, true
+	}
+	switch {
+	case errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.ECONNABORTED),
+		errors.Is(err, syscall.EPIPE), errors.Is(err, syscall.EHOSTUNREACH), errors.Is(err, syscall.ENETUNREACH),
+		errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, net.ErrClosed):
+		return KindTransient, true
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return KindTransient, true
+	}
+	return KindUnknown, false
+}
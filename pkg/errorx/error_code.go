@@ -35,10 +35,104 @@ const (
 	StreamTableError ErrorCode = 3000
 	RuleErr          ErrorCode = 4000
 	ConfKeyError     ErrorCode = 5000
+
+	// error code for connection
+
+	ConnectionErr         ErrorCode = 6000
+	ConnectionExistErr    ErrorCode = 6001
+	ConnectionInUseErr    ErrorCode = 6002
+	ConnectionReadOnlyErr ErrorCode = 6003
+	// ConnectionQuotaErr means the quota of the connections or references is exceeded
+	ConnectionQuotaErr ErrorCode = 6004
+	// ConnectionTypeNotAllowedErr means the connection type is not allowed for the tenant
+	ConnectionTypeNotAllowedErr ErrorCode = 6005
+	// ConnectionNamespaceErr means the connection is out of the namespace of the caller
+	ConnectionNamespaceErr ErrorCode = 6006
+	// ConnectionPropsErr means the props don't match the schema of the connection type
+	ConnectionPropsErr ErrorCode = 6007
+	// ConnectionInvalidErr means the request of the connection is invalid, like the missing id or the unknown type
+	ConnectionInvalidErr ErrorCode = 6008
+	// ConnectionConflictErr means the connection is changed concurrently during the operation
+	ConnectionConflictErr ErrorCode = 6009
+
+	// error code for tracer
+
+	TracerErr         ErrorCode = 7000
+	TracerDisabledErr ErrorCode = 7001
 )
 
+var codeNames = map[ErrorCode]string{
+	Undefined_Err:               "UNDEFINED",
+	GENERAL_ERR:                 "GENERAL",
+	NOT_FOUND:                   "NOT_FOUND",
+	IOErr:                       "IO",
+	CovnerterErr:                "CONVERTER",
+	EOF:                         "EOF",
+	ParserError:                 "PARSER",
+	PlanError:                   "PLAN",
+	ExecutorError:               "EXECUTOR",
+	StreamTableError:            "STREAM_TABLE",
+	RuleErr:                     "RULE",
+	ConfKeyError:                "CONF_KEY",
+	ConnectionErr:               "CONNECTION",
+	ConnectionExistErr:          "CONNECTION_EXIST",
+	ConnectionInUseErr:          "CONNECTION_IN_USE",
+	ConnectionReadOnlyErr:       "CONNECTION_READONLY",
+	ConnectionQuotaErr:          "CONNECTION_QUOTA",
+	ConnectionTypeNotAllowedErr: "CONNECTION_TYPE_NOT_ALLOWED",
+	ConnectionNamespaceErr:      "CONNECTION_NAMESPACE",
+	ConnectionPropsErr:          "CONNECTION_PROPS",
+	ConnectionInvalidErr:        "CONNECTION_INVALID",
+	ConnectionConflictErr:       "CONNECTION_CONFLICT",
+	TracerErr:                   "TRACER",
+	TracerDisabledErr:           "TRACER_DISABLED",
+}
+
+// codeKinds is the default kind of the error codes which are not retryable by nature
+var codeKinds = map[ErrorCode]ErrorKind{
+	NOT_FOUND:                   KindPermanent,
+	IOErr:                       KindTransient,
+	ParserError:                 KindPermanent,
+	PlanError:                   KindPermanent,
+	ConnectionExistErr:          KindPermanent,
+	ConnectionInUseErr:          KindPermanent,
+	ConnectionReadOnlyErr:       KindPermanent,
+	ConnectionQuotaErr:          KindQuota,
+	ConnectionTypeNotAllowedErr: KindPermanent,
+	ConnectionNamespaceErr:      KindPermanent,
+	ConnectionPropsErr:          KindPermanent,
+	ConnectionInvalidErr:        KindPermanent,
+	ConnectionConflictErr:       KindTransient,
+	TracerDisabledErr:           KindPermanent,
+}
+
+// String returns the stable name of the code which can be used by the clients instead of parsing the message
+func (c ErrorCode) String() string {
+	if n, ok := codeNames[c]; ok {
+		return n
+	}
+	return "UNDEFINED"
+}
+
 var NotFoundErr = NewWithCode(NOT_FOUND, "not found")
 
+// The sentinel errors of the connection subsystem. The errors of the connection pool carry the error codes, and they
+// match the sentinel of the same code by errors.Is even if wrapped, for example
+// errors.Is(err, errorx.ErrConnectionInUse).
+var (
+	ErrConnectionNotFound       = NewWithCode(NOT_FOUND, "connection not found")
+	ErrConnectionExist          = NewWithCode(ConnectionExistErr, "connection already exists")
+	ErrConnectionInUse          = NewWithCode(ConnectionInUseErr, "connection is referenced")
+	ErrConnectionReadOnly       = NewWithCode(ConnectionReadOnlyErr, "connection is read-only")
+	ErrConnectionQuota          = NewWithCode(ConnectionQuotaErr, "connection quota exceeded")
+	ErrConnectionTypeNotAllowed = NewWithCode(ConnectionTypeNotAllowedErr, "connection type is not allowed")
+	ErrConnectionNamespace      = NewWithCode(ConnectionNamespaceErr, "connection is out of the namespace")
+	ErrConnectionProps          = NewWithCode(ConnectionPropsErr, "invalid connection props")
+	ErrConnectionInvalid        = NewWithCode(ConnectionInvalidErr, "invalid connection request")
+	ErrConnectionConflict       = NewWithCode(ConnectionConflictErr, "connection is changed concurrently")
+	ErrIO                       = NewWithCode(IOErr, "io error")
+)
+
 func NewIOErr(msg string) error {
 	return &Error{
 		code: IOErr,
@@ -54,7 +148,8 @@ func NewEOF(msg string) error {
 }
 
 func IsIOError(err error) bool {
-	if withCode, ok := err.(ErrorWithCode); ok {
+	var withCode ErrorWithCode
+	if errors.As(err, &withCode) {
 		return withCode.Code() == IOErr
 	}
 	return false
@@ -80,7 +175,8 @@ func NewParserError(msg string) error {
 }
 
 func GetErrorCode(err error) (ErrorCode, bool) {
-	if code, ok := err.(ErrorWithCode); ok {
+	var code ErrorWithCode
+	if errors.As(err, &code) {
 		return code.Code(), true
 	}
 	return 0, false
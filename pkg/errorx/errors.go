@@ -15,6 +15,7 @@
 package errorx
 
 import (
+	"errors"
 	"io"
 	"net/url"
 	"strings"
@@ -41,6 +42,64 @@ func (e *Error) Code() ErrorCode {
 	return e.code
 }
 
+// Is matches the errors of the same code, so that the errors match the sentinel errors of their code by errors.Is.
+// The general and undefined errors only match themselves.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	if !ok || e.code == GENERAL_ERR || e.code == Undefined_Err {
+		return false
+	}
+	return t.code == e.code
+}
+
+// FieldError is the validation error of a field. The field is empty if the error is not about a single field.
+type FieldError struct {
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+func (e FieldError) String() string {
+	if e.Field == "" {
+		return e.Message
+	}
+	return "prop " + e.Field + " " + e.Message
+}
+
+// ValidationError is a coded error with the errors of each invalid field
+type ValidationError struct {
+	err    *Error
+	Fields []FieldError
+}
+
+func NewValidationError(code ErrorCode, message string, fields []FieldError) *ValidationError {
+	msgs := make([]string, 0, len(fields))
+	for _, f := range fields {
+		msgs = append(msgs, f.String())
+	}
+	return &ValidationError{err: NewWithCode(code, message+": "+strings.Join(msgs, "; ")), Fields: fields}
+}
+
+func (e *ValidationError) Error() string {
+	return e.err.Error()
+}
+
+func (e *ValidationError) Code() ErrorCode {
+	return e.err.Code()
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.err
+}
+
+// GetFieldErrors returns the field errors if the error is a validation error
+func GetFieldErrors(err error) []FieldError {
+	var ve *ValidationError
+	if errors.As(err, &ve) {
+		return ve.Fields
+	}
+	return nil
+}
+
 type ErrorWithCode interface {
 	Error() string
 	Code() ErrorCode
@@ -15,6 +15,8 @@
 package errorx
 
 import (
+	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -38,3 +40,29 @@ func TestErrorResult(t *testing.T) {
 	assert.Equal(t, "not found", err.Error())
 	assert.Equal(t, NOT_FOUND, err.Code())
 }
+
+func TestValidationError(t *testing.T) {
+	err := NewValidationError(ConnectionPropsErr, "invalid props", []FieldError{
+		{Field: "server", Message: "is required"},
+		{Message: "port must be positive"},
+	})
+	assert.Equal(t, "invalid props: prop server is required; port must be positive", err.Error())
+	code, ok := GetErrorCode(fmt.Errorf("wrapped: %w", err))
+	assert.True(t, ok)
+	assert.Equal(t, ConnectionPropsErr, code)
+	assert.Len(t, GetFieldErrors(fmt.Errorf("wrapped: %w", err)), 2)
+	assert.Nil(t, GetFieldErrors(New("general error")))
+}
+
+func TestSentinelErrors(t *testing.T) {
+	err := fmt.Errorf("drop connection failed: %w", NewWithCode(ConnectionInUseErr, "connection conn1 is referenced by rule1"))
+	assert.True(t, errors.Is(err, ErrConnectionInUse))
+	assert.False(t, errors.Is(err, ErrConnectionNotFound))
+	assert.True(t, errors.Is(NewWithCode(NOT_FOUND, "connection conn1 not existed"), ErrConnectionNotFound))
+	assert.True(t, errors.Is(NewIOErr("dial failed"), ErrIO))
+	assert.True(t, errors.Is(NewValidationError(ConnectionPropsErr, "invalid props", nil), ErrConnectionProps))
+	// the general errors only match themselves
+	general := New("general error")
+	assert.True(t, errors.Is(general, general))
+	assert.False(t, errors.Is(general, New("general error")))
+}
@@ -0,0 +1,163 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errorx
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrorKind classifies the errors by how the caller should react, mainly whether to retry
+type ErrorKind int
+
+const (
+	// KindUnknown means the error is not classified. It is not retried.
+	KindUnknown ErrorKind = iota
+	// KindTransient errors like network failure are likely to succeed after retry
+	KindTransient
+	// KindPermanent errors like invalid configuration won't succeed by retry
+	KindPermanent
+	// KindAuth errors need the credentials to be fixed
+	KindAuth
+	// KindQuota errors like rate limit may succeed after waiting
+	KindQuota
+	// KindTimeout errors may succeed by retry
+	KindTimeout
+)
+
+func (k ErrorKind) String() string {
+	switch k {
+	case KindTransient:
+		return "transient"
+	case KindPermanent:
+		return "permanent"
+	case KindAuth:
+		return "auth"
+	case KindQuota:
+		return "quota"
+	case KindTimeout:
+		return "timeout"
+	default:
+		return "unknown"
+	}
+}
+
+// ErrorWithKind is implemented by the classified errors
+type ErrorWithKind interface {
+	error
+	Kind() ErrorKind
+}
+
+type kindError struct {
+	err        error
+	kind       ErrorKind
+	retryAfter time.Duration
+}
+
+func (e *kindError) Error() string {
+	return e.err.Error()
+}
+
+func (e *kindError) Unwrap() error {
+	return e.err
+}
+
+func (e *kindError) Kind() ErrorKind {
+	return e.kind
+}
+
+// WithKind classifies the error. It returns nil if err is nil.
+func WithKind(err error, kind ErrorKind) error {
+	if err == nil {
+		return nil
+	}
+	return &kindError{err: err, kind: kind}
+}
+
+func NewTransient(err error) error {
+	return WithKind(err, KindTransient)
+}
+
+func NewPermanent(err error) error {
+	return WithKind(err, KindPermanent)
+}
+
+func NewAuth(err error) error {
+	return WithKind(err, KindAuth)
+}
+
+func NewQuota(err error) error {
+	return WithKind(err, KindQuota)
+}
+
+// NewQuotaWithRetryAfter creates a quota error with a hint of how long to wait before retry
+func NewQuotaWithRetryAfter(err error, retryAfter time.Duration) error {
+	if err == nil {
+		return nil
+	}
+	return &kindError{err: err, kind: KindQuota, retryAfter: retryAfter}
+}
+
+func NewTimeout(err error) error {
+	return WithKind(err, KindTimeout)
+}
+
+// KindOf returns the kind of the error. The errors without explicit kind are classified by their code, then by the
+// registered classifiers and at last by their type: deadline exceeded and network timeout are timeout, the other
+// network failures like connection refused are transient.
+func KindOf(err error) ErrorKind {
+	if err == nil {
+		return KindUnknown
+	}
+	var ke ErrorWithKind
+	if errors.As(err, &ke) {
+		return ke.Kind()
+	}
+	if code, ok := GetErrorCode(err); ok {
+		if k, ok := codeKinds[code]; ok {
+			return k
+		}
+	}
+	if k, ok := classifyRegistered(err); ok {
+		return k
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return KindTimeout
+	}
+	if k, ok := classifyNetwork(err); ok {
+		return k
+	}
+	return KindUnknown
+}
+
+// IsRetryable returns whether the error deserves a retry with backoff
+func IsRetryable(err error) bool {
+	switch KindOf(err) {
+	case KindTransient, KindTimeout, KindQuota:
+		return true
+	default:
+		return false
+	}
+}
+
+// RetryAfter returns the retry hint of the error if any
+func RetryAfter(err error) (time.Duration, bool) {
+	var ke *kindError
+	if errors.As(err, &ke) && ke.retryAfter > 0 {
+		return ke.retryAfter, true
+	}
+	return 0, false
+}
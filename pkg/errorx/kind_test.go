@@ -0,0 +1,129 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errorx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type timeoutErr struct{}
+
+func (timeoutErr) Error() string   { return "i/o timeout" }
+func (timeoutErr) Timeout() bool   { return true }
+func (timeoutErr) Temporary() bool { return true }
+
+var _ net.Error = timeoutErr{}
+
+func TestKindOf(t *testing.T) {
+	base := errors.New("base")
+	tests := []struct {
+		err       error
+		kind      ErrorKind
+		retryable bool
+	}{
+		{nil, KindUnknown, false},
+		{base, KindUnknown, false},
+		{NewTransient(base), KindTransient, true},
+		{NewPermanent(base), KindPermanent, false},
+		{NewAuth(base), KindAuth, false},
+		{NewQuota(base), KindQuota, true},
+		{NewTimeout(base), KindTimeout, true},
+		{NewIOErr("io"), KindTransient, true},
+		{fmt.Errorf("wrap: %w", NewAuth(base)), KindAuth, false},
+		{context.DeadlineExceeded, KindTimeout, true},
+		{timeoutErr{}, KindTimeout, true},
+		// explicit kind wins
+		{NewPermanent(NewIOErr("io")), KindPermanent, false},
+		{fmt.Errorf("wrap: %w", NewIOErr("io")), KindTransient, true},
+		{&net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}, KindTransient, true},
+		{fmt.Errorf("ping: %w", &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}), KindTransient, true},
+		{&net.DNSError{Err: "no such host", Name: "db", IsNotFound: true}, KindTransient, true},
+		{&net.DNSError{Err: "server misbehaving", Name: "db", IsTemporary: true}, KindTransient, true},
+		{&net.AddrError{Err: "missing port in address", Addr: "db"}, KindPermanent, false},
+		{fmt.Errorf("read: %w", io.ErrUnexpectedEOF), KindTransient, true},
+	}
+	for i, tt := range tests {
+		assert.Equal(t, tt.kind, KindOf(tt.err), i)
+		assert.Equal(t, tt.retryable, IsRetryable(tt.err), i)
+	}
+	assert.Nil(t, WithKind(nil, KindAuth))
+	wrapped := NewAuth(base)
+	assert.True(t, errors.Is(wrapped, base))
+	assert.Equal(t, "base", wrapped.Error())
+	assert.Equal(t, "auth", KindAuth.String())
+}
+
+func TestRetryAfter(t *testing.T) {
+	err := fmt.Errorf("wrap: %w", NewQuotaWithRetryAfter(errors.New("rate limited"), 3*time.Second))
+	assert.Equal(t, KindQuota, KindOf(err))
+	d, ok := RetryAfter(err)
+	assert.True(t, ok)
+	assert.Equal(t, 3*time.Second, d)
+	_, ok = RetryAfter(NewQuota(errors.New("rate limited")))
+	assert.False(t, ok)
+	_, ok = RetryAfter(errors.New("plain"))
+	assert.False(t, ok)
+}
+
+func TestCodeKind(t *testing.T) {
+	assert.Equal(t, "NOT_FOUND", NOT_FOUND.String())
+	assert.Equal(t, "CONNECTION_IN_USE", ConnectionInUseErr.String())
+	assert.Equal(t, "UNDEFINED", ErrorCode(42).String())
+	assert.Equal(t, KindPermanent, KindOf(NotFoundErr))
+	assert.Equal(t, KindPermanent, KindOf(fmt.Errorf("wrap: %w", NewWithCode(ConnectionExistErr, "exist"))))
+	assert.Equal(t, KindUnknown, KindOf(New("general")))
+	code, ok := GetErrorCode(fmt.Errorf("wrap: %w", NewWithCode(TracerDisabledErr, "disabled")))
+	assert.True(t, ok)
+	assert.Equal(t, TracerDisabledErr, code)
+}
+
+var errDriverAuth = errors.New("access denied")
+
+func TestRegisterClassifier(t *testing.T) {
+	defer UnregisterClassifier("test")
+	err := fmt.Errorf("dial: %w", errDriverAuth)
+	assert.Equal(t, KindUnknown, KindOf(err))
+	RegisterClassifier("test", func(err error) (ErrorKind, bool) {
+		if errors.Is(err, errDriverAuth) {
+			return KindAuth, true
+		}
+		return KindUnknown, false
+	})
+	assert.Equal(t, KindAuth, KindOf(err))
+	assert.False(t, IsRetryable(err))
+	// the explicit kind and the code are not overridden
+	assert.Equal(t, KindTransient, KindOf(NewTransient(err)))
+	assert.Equal(t, KindUnknown, KindOf(errors.New("other")))
+
+	// register again replaces the classifier
+	RegisterClassifier("test", func(err error) (ErrorKind, bool) {
+		if errors.Is(err, errDriverAuth) {
+			return KindQuota, true
+		}
+		return KindUnknown, false
+	})
+	assert.Equal(t, KindQuota, KindOf(err))
+	UnregisterClassifier("test")
+	assert.Equal(t, KindUnknown, KindOf(err))
+}
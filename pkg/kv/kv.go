@@ -30,3 +30,16 @@ type KeyValue interface {
 	Drop() error
 	GetByPrefix(prefix string) (map[string][]byte, error)
 }
+
+// Op is a write operation in a batch. The key is deleted if Delete is true, otherwise the value is set.
+type Op struct {
+	Key    string
+	Value  interface{}
+	Delete bool
+}
+
+// Batcher is implemented by the KeyValue which can apply multiple writes atomically
+type Batcher interface {
+	// Batch applies all the operations in one transaction. Deleting a missing key is not an error.
+	Batch(ops []Op) error
+}
@@ -17,6 +17,7 @@ package model
 import (
 	"errors"
 	"fmt"
+	"regexp"
 	"time"
 
 	"github.com/lf-edge/ekuiper/contract/v2/api"
@@ -42,6 +43,7 @@ type KuiperConf struct {
 		ConsoleLog              bool                  `yaml:"consoleLog"`
 		FileLog                 bool                  `yaml:"fileLog"`
 		LogDisableTimestamp     bool                  `yaml:"logDisableTimestamp"`
+		LogFormat               string                `yaml:"logFormat"`
 		Syslog                  *SyslogConf           `yaml:"syslog"`
 		RotateTime              int                   `yaml:"rotateTime"`
 		MaxAge                  int                   `yaml:"maxAge"`
@@ -71,6 +73,10 @@ type KuiperConf struct {
 		EnableRestAuditLog      bool                  `yaml:"enableRestAuditLog"`
 		EnablePrivateNet        bool                  `yaml:"enablePrivateNet"`
 		AllowExternalFileAccess bool                  `yaml:"allowExternalFileAccess"`
+		// ManagementGrpcAddr is the listening address of the gRPC management API. Empty means disabled.
+		ManagementGrpcAddr string `yaml:"managementGrpcAddr"`
+		// WatchConfigFile reloads the config file when it is changed
+		WatchConfigFile bool `yaml:"watchConfigFile"`
 	}
 	Rule   def.RuleOption
 	Sink   *SinkConf
@@ -78,7 +84,11 @@ type KuiperConf struct {
 	Store  struct {
 		Type         string `yaml:"type"`
 		ExtStateType string `yaml:"extStateType"`
-		Redis        struct {
+		// ConfigStoreType is the backend of the sources, sinks and connections configurations
+		ConfigStoreType string `yaml:"configStoreType"`
+		// ConfigWatchInterval is the interval to check the config store for the changes made by others
+		ConfigWatchInterval cast.DurationConf `yaml:"configWatchInterval"`
+		Redis               struct {
 			Host               string            `yaml:"host"`
 			Port               int               `yaml:"port"`
 			Password           string            `yaml:"password"`
@@ -95,6 +105,22 @@ type KuiperConf struct {
 			Path string `yaml:"path"`
 			Name string `yaml:"name"`
 		}
+		Etcd struct {
+			Endpoints []string          `yaml:"endpoints"`
+			Username  string            `yaml:"username"`
+			Password  string            `yaml:"password"`
+			Timeout   cast.DurationConf `yaml:"timeout"`
+			// Prefix is prepended to all the keys so that multiple clusters can share the etcd
+			Prefix string `yaml:"prefix"`
+		}
+		// Encryption encrypts the values of the config store at rest
+		Encryption struct {
+			Enable bool `yaml:"enable"`
+			// KeyProvider is where to get the base64 encoded AES key: env, file or a registered KMS provider
+			KeyProvider string `yaml:"keyProvider"`
+			KeyEnv      string `yaml:"keyEnv"`
+			KeyFile     string `yaml:"keyFile"`
+		}
 	}
 	Portable struct {
 		PythonBin   string            `yaml:"pythonBin"`
@@ -104,10 +130,167 @@ type KuiperConf struct {
 	}
 	Connection struct {
 		BackoffMaxElapsedDuration cast.DurationConf `yaml:"backoffMaxElapsedDuration"`
+		// OperationTimeout bounds the pool operations like ping and close
+		OperationTimeout cast.DurationConf `yaml:"operationTimeout"`
+		// Retry is the default retry policy to dial the connections
+		Retry RetryConf `yaml:"retry"`
+		// TypeRetry overrides the retry policy by connection type such as mqtt
+		TypeRetry map[string]RetryConf `yaml:"typeRetry"`
+		// ReconnectMaxAttempts stops the background reconnection of a broken named connection after the failed attempts.
+		// 0 means retrying forever.
+		ReconnectMaxAttempts int `yaml:"reconnectMaxAttempts"`
+		// RetryBudget limits how many connections can be in the retry loop at the same time. 0 means unlimited.
+		RetryBudget    int `yaml:"retryBudget"`
+		CircuitBreaker struct {
+			// Threshold is the count of consecutive dial failures of all connections to pause the retries. 0 means disabled.
+			Threshold int               `yaml:"threshold"`
+			Cooldown  cast.DurationConf `yaml:"cooldown"`
+		} `yaml:"circuitBreaker"`
+		// TrashTTL retains the dropped named connections in the trash to restore. 0 means dropping permanently.
+		TrashTTL cast.DurationConf `yaml:"trashTTL"`
+		// ErrorLogInterval aggregates the identical errors of a connection logged within the interval into a summary
+		// line. 0 means the default 1 minute.
+		ErrorLogInterval cast.DurationConf `yaml:"errorLogInterval"`
+		// LeaderElection makes only one of the nodes sharing the config store connect the named connections
+		LeaderElection struct {
+			Enable bool `yaml:"enable"`
+			// NodeID identifies this node in the lease. Default to hostname-pid.
+			NodeID   string            `yaml:"nodeId"`
+			LeaseTTL cast.DurationConf `yaml:"leaseTTL"`
+		} `yaml:"leaderElection"`
+		// Replication streams the named connections and tracer config from the active node to the standby nodes
+		Replication struct {
+			// ListenAddr is the address of the gRPC server for the standby nodes to follow. Empty means disabled.
+			ListenAddr string `yaml:"listenAddr"`
+			// Peer is the replication address of the other node to follow while this node is standby
+			Peer string `yaml:"peer"`
+			// Tls is the certificate of this node and the root CA to verify the other node. It is required, and the
+			// nodes verify each other if the root CA is set.
+			Tls *TlsConfigurationOptions `yaml:"tls"`
+			// Token is the shared secret for the standby nodes to authenticate. It is required.
+			Token string `yaml:"token"`
+		} `yaml:"replication"`
+		// ResourceLimits force closes the connections holding too many resources. 0 means unlimited.
+		ResourceLimits struct {
+			MaxGoroutines    int64 `yaml:"maxGoroutines"`
+			MaxBufferedBytes int64 `yaml:"maxBufferedBytes"`
+			MaxOpenFiles     int64 `yaml:"maxOpenFiles"`
+		} `yaml:"resourceLimits"`
+		// Secrets masks the secret props of the connections in the API output and encrypts them in the config store
+		Secrets struct {
+			// Fields are the secret props besides the common password and token keys
+			Fields []string `yaml:"fields"`
+			// Encrypt encrypts the secret props with the AES key of the key provider
+			Encrypt     bool   `yaml:"encrypt"`
+			KeyProvider string `yaml:"keyProvider"`
+		} `yaml:"secrets"`
+		// MaxConnections limits the connections in the pool. 0 means unlimited.
+		MaxConnections int `yaml:"maxConnections"`
+		// TypeQuotas limits the connections of each type in the pool. The key is the connection type.
+		TypeQuotas map[string]int `yaml:"typeQuotas"`
+		// Tenants limits the connections of each tenant sharing the gateway. The key is the tenant name.
+		Tenants map[string]TenantConf `yaml:"tenants"`
+		// DedupAnonymous shares one physical connection among the anonymous connections with the identical type and props
+		DedupAnonymous bool `yaml:"dedupAnonymous"`
+		// RefAudit records the attaches and detaches of the connections to find the reference leaks
+		RefAudit bool `yaml:"refAudit"`
+		// EventHistorySize is the count of the latest lifecycle events kept for each named connection. 0 means disabled.
+		EventHistorySize int `yaml:"eventHistorySize"`
+		// StoreType is the backend of the named connection definitions. Empty means the config store of the node.
+		StoreType string `yaml:"storeType"`
+		// Readiness decides which connections being down fail the readiness probe
+		Readiness struct {
+			// Policy is all, critical or none. Default to all.
+			Policy string `yaml:"policy"`
+			// CriticalLabel is the label marking the critical connections with the value "true". Default to critical.
+			CriticalLabel string `yaml:"criticalLabel"`
+		} `yaml:"readiness"`
 	}
 	OpenTelemetry OpenTelemetry `yaml:"openTelemetry"`
 	AesKey        []byte
 	Security      *SecurityConf
+	// Proxy is the proxy of the OTLP exporter and the http based connections
+	Proxy ProxyConf `yaml:"proxy"`
+	// Compliance restricts the cryptography of all the connections and the trace exporter
+	Compliance ComplianceConf `yaml:"compliance"`
+	// Discovery configures the endpoints specified as DNS SRV names or address lists
+	Discovery DiscoveryConf `yaml:"discovery"`
+	// ConfigSync pulls the connection and tracer definitions from a central place periodically
+	ConfigSync ConfigSyncConf `yaml:"configSync"`
+	// ManagementAuth protects the connection and tracer management APIs by api keys and rate limit
+	ManagementAuth ManagementAuthConf `yaml:"managementAuth"`
+	// AuditLog records the configuration changes of the connections, tracer and conf keys
+	AuditLog AuditLogConf `yaml:"auditLog"`
+}
+
+// AuditLogConf defines the append-only event log of the configuration changes
+type AuditLogConf struct {
+	Enable bool `yaml:"enable"`
+	// MaxRecords is the count of the latest events to keep. 0 means unlimited.
+	MaxRecords int `yaml:"maxRecords"`
+}
+
+// ManagementAuthConf defines the api keys and the rate limit of the management APIs
+type ManagementAuthConf struct {
+	// APIKeys are the allowed keys. Empty means no api key is required.
+	APIKeys []APIKeyConf `yaml:"apiKeys"`
+	// RateLimit is the requests per second allowed for each key, or each client ip if no key is required. 0 means unlimited.
+	RateLimit float64 `yaml:"rateLimit"`
+	Burst     int     `yaml:"burst"`
+}
+
+type APIKeyConf struct {
+	Name string `yaml:"name"`
+	Key  string `yaml:"key"`
+	// Scope is read for the read-only access or admin for all
+	Scope string `yaml:"scope"`
+	// Namespace binds the key to the connection namespace of a tenant. Empty means all the namespaces.
+	Namespace string `yaml:"namespace"`
+}
+
+// ConfigSyncConf defines where to pull the definitions. The document has the same format as connections.yaml with an
+// optional tracer section.
+type ConfigSyncConf struct {
+	Enable bool `yaml:"enable"`
+	// Source is git or http
+	Source string `yaml:"source"`
+	URL    string `yaml:"url"`
+	// Branch and Path locate the document in the git repo
+	Branch string `yaml:"branch"`
+	Path   string `yaml:"path"`
+	// Headers are sent with the http request, such as the Authorization header
+	Headers  map[string]string `yaml:"headers"`
+	Interval cast.DurationConf `yaml:"interval"`
+}
+
+// TenantConf is the quota of a tenant. The connections belong to the tenant whose prefix matches their ids.
+type TenantConf struct {
+	// Prefix is the connection id prefix of the tenant. Default to the tenant name with an underscore.
+	Prefix string `yaml:"prefix"`
+	// MaxConnections limits the connections in the pool. 0 means unlimited.
+	MaxConnections int `yaml:"maxConnections"`
+	// MaxRefs limits the total references of the connections. 0 means unlimited.
+	MaxRefs int `yaml:"maxRefs"`
+	// AllowedTypes are the allowed connection types. Empty means all.
+	AllowedTypes []string `yaml:"allowedTypes"`
+}
+
+// RetryConf defines how to retry the connection dial
+type RetryConf struct {
+	// Policy is the name of the retry policy: exponential, constant, fibonacci or decorrelatedJitter
+	Policy string `json:"policy" yaml:"policy"`
+	// Interval is the initial interval, or the fixed interval for constant policy
+	Interval cast.DurationConf `json:"interval" yaml:"interval"`
+	// MaxInterval caps the interval between retries
+	MaxInterval cast.DurationConf `json:"maxInterval" yaml:"maxInterval"`
+	// Multiplier is the growth factor of the exponential interval. 0 means 1.5
+	Multiplier float64 `json:"multiplier" yaml:"multiplier"`
+	// Jitter randomizes the exponential interval by the factor between 0 and 1. Unset means 0.5
+	Jitter *float64 `json:"jitter" yaml:"jitter"`
+	// MaxElapsed stops retrying after the elapsed time. 0 means the default backoffMaxElapsedDuration
+	MaxElapsed cast.DurationConf `json:"maxElapsed" yaml:"maxElapsed"`
+	// MaxRetries stops retrying after the count of retries. 0 means unlimited
+	MaxRetries int `json:"maxRetries" yaml:"maxRetries"`
 }
 
 type TlsConf struct {
@@ -241,4 +424,190 @@ type OpenTelemetry struct {
 	RemoteEndpoint        string `yaml:"remoteEndpoint"`
 	LocalTraceCapacity    int    `yaml:"localTraceCapacity"`
 	EnableLocalStorage    bool   `yaml:"enableLocalStorage"`
+	// LocalStorage is the backend of the local spans: memory, sqlite or file. Empty means sqlite if EnableLocalStorage
+	// is set, otherwise memory.
+	LocalStorage string `yaml:"localStorage"`
+	// LocalTraceFile is the path of the append-only span file of the file backend. Default to trace/spans.log in the
+	// data directory.
+	LocalTraceFile string `yaml:"localTraceFile"`
+	// LocalTraceRetention is how long the spans are kept in the local storage
+	LocalTraceRetention cast.DurationConf `yaml:"localTraceRetention"`
+	// LocalTraceCleanupInterval is the interval to run the retention cleanup job
+	LocalTraceCleanupInterval cast.DurationConf `yaml:"localTraceCleanupInterval"`
+	// LocalTraceMaxSpans and LocalTraceMaxBytes are the quota of the local storage checked by the cleanup job. The
+	// oldest traces are deleted once exceeded. 0 means no limit.
+	LocalTraceMaxSpans int64 `yaml:"localTraceMaxSpans"`
+	LocalTraceMaxBytes int64 `yaml:"localTraceMaxBytes"`
+	// RecordMode decides which spans are recorded. "all" records all spans, "error" only records the traces with error
+	RecordMode string `yaml:"recordMode"`
+	// IndexedAttributes are the span attribute keys indexed in the local storage for fast lookup
+	IndexedAttributes []string `yaml:"indexedAttributes"`
+	// PreserveAttributeTypes records the types of the integer and the array span attributes along with the stored spans
+	// so that they are decoded as the same types instead of float64 and the generic arrays
+	PreserveAttributeTypes bool `yaml:"preserveAttributeTypes"`
+	// SpanNameTemplates maps the operator type to its span name template. The "default" key applies to all operators
+	SpanNameTemplates map[string]string `yaml:"spanNameTemplates"`
+	// OtlpReceiverAddress is the listening address of the OTLP/gRPC span receiver. Empty means disabled
+	OtlpReceiverAddress string `yaml:"otlpReceiverAddress"`
+	// OtlpReceiverHttpAddress is the listening address of the OTLP/HTTP span receiver. Empty means disabled
+	OtlpReceiverHttpAddress string `yaml:"otlpReceiverHttpAddress"`
+	// Metrics configures the OTel metrics pipeline which shares the resource of the tracer
+	Metrics OtelMetricsConf `yaml:"metrics"`
+	// RemoteTls is the TLS config of the remote collector and the metrics endpoint. Nil means insecure
+	RemoteTls *TlsConfigurationOptions `yaml:"remoteTls"`
+	// RemoteProtocol is the protocol of the remote collector: otlphttp, otlpgrpc, jaeger or zipkin. Default to otlphttp.
+	RemoteProtocol string `yaml:"remoteProtocol"`
+	// RemoteExport is the batching and the retry of the spans exported to the remote collector
+	RemoteExport RemoteExportConf `yaml:"remoteExport"`
+	// SpanPipeline is the bounded queue of the ended spans processed in the background
+	SpanPipeline SpanPipelineConf `yaml:"spanPipeline"`
+	// SpanCompression compresses the spans saved in the local storage and the batches exported to the remote collector
+	SpanCompression SpanCompressionConf `yaml:"spanCompression"`
+	// Sampling decides which traces of the traced rules are sampled
+	Sampling SamplingConf `yaml:"sampling"`
+	// ResourceAttributes are the extra static resource attributes of the node to tell the traces of the nodes apart,
+	// such as the site or the deployment environment. They override the default host.name and service.instance.id.
+	ResourceAttributes map[string]string `yaml:"resourceAttributes"`
+	// Redaction removes or masks the sensitive span attributes before they are stored or exported. The "default" key
+	// applies to all rules and the rule id key adds to it for the rule.
+	Redaction map[string]*RedactionConf `yaml:"redaction"`
+}
+
+// RedactionConf decides which span attributes are kept and how their values are masked
+type RedactionConf struct {
+	// Allow keeps only the listed attribute keys if set
+	Allow []string `json:"allow,omitempty" yaml:"allow"`
+	// Deny removes the listed attribute keys
+	Deny []string `json:"deny,omitempty" yaml:"deny"`
+	// Masks are the regular expressions of which the matched parts of the string values are replaced by ******
+	Masks []string `json:"masks,omitempty" yaml:"masks"`
+}
+
+// Validate removes the invalid masks.
+func (r *RedactionConf) Validate(logger api.Logger) error {
+	var errs error
+	masks := r.Masks[:0]
+	for _, m := range r.Masks {
+		if _, err := regexp.Compile(m); err != nil {
+			logger.Warnf("redaction mask %s is invalid: %v", m, err)
+			errs = errors.Join(errs, fmt.Errorf("masks:invalid mask %s: %v", m, err))
+			continue
+		}
+		masks = append(masks, m)
+	}
+	r.Masks = masks
+	return errs
+}
+
+// Sampling overrides of the rules
+const (
+	SampleAlways = "always"
+	SampleNever  = "never"
+)
+
+// SamplingConf decides whether a new trace is sampled. The child spans follow the decision of their parents.
+type SamplingConf struct {
+	// Ratio is the probability in [0, 1] to sample a trace. Nil means 1.
+	Ratio *float64 `json:"ratio,omitempty" yaml:"ratio"`
+	// RateLimit is the max count of the sampled traces per second. 0 means no limit.
+	RateLimit float64 `json:"rateLimit" yaml:"rateLimit"`
+	// Rules overrides the ratio and the rate limit by rule id, the value is always or never
+	Rules map[string]string `json:"rules,omitempty" yaml:"rules"`
+}
+
+// Validate the configuration and reset the invalid values to the default.
+func (s *SamplingConf) Validate(logger api.Logger) error {
+	var errs error
+	if s.Ratio != nil && (*s.Ratio < 0 || *s.Ratio > 1) {
+		logger.Warnf("sampling ratio %v is not in [0, 1], set to 1", *s.Ratio)
+		errs = errors.Join(errs, errors.New("ratio:ratio must be in [0, 1]"))
+		s.Ratio = nil
+	}
+	if s.RateLimit < 0 {
+		logger.Warnf("sampling rateLimit %v is negative, set to 0", s.RateLimit)
+		errs = errors.Join(errs, errors.New("rateLimit:rateLimit must not be negative"))
+		s.RateLimit = 0
+	}
+	for rule, v := range s.Rules {
+		switch v {
+		case SampleAlways, SampleNever:
+		default:
+			logger.Warnf("sampling of rule %s is %s, must be always or never", rule, v)
+			errs = errors.Join(errs, fmt.Errorf("rules:sampling of rule %s must be always or never", rule))
+			delete(s.Rules, rule)
+		}
+	}
+	return errs
+}
+
+// RemoteExportConf is the bounded queue of the spans to the remote collector. The batches failed by the transport
+// errors are retried with backoff until MaxElapsed, and the spans are dropped once the queue is full.
+type RemoteExportConf struct {
+	QueueSize       int               `yaml:"queueSize"`
+	BatchSize       int               `yaml:"batchSize"`
+	FlushInterval   cast.DurationConf `yaml:"flushInterval"`
+	InitialInterval cast.DurationConf `yaml:"initialInterval"`
+	MaxInterval     cast.DurationConf `yaml:"maxInterval"`
+	MaxElapsed      cast.DurationConf `yaml:"maxElapsed"`
+}
+
+// SpanPipelineConf is the bounded queue of the ended spans. The spans are converted, stored and sent to the
+// subscribers and the remote queue in batches in the background, and dropped once the queue is full, so that the
+// rules are never blocked by the tracing.
+type SpanPipelineConf struct {
+	QueueSize     int               `yaml:"queueSize"`
+	BatchSize     int               `yaml:"batchSize"`
+	FlushInterval cast.DurationConf `yaml:"flushInterval"`
+	// DropWarnRatio logs a warning once the dropped spans exceed the ratio of the received spans in a minute
+	DropWarnRatio float64 `yaml:"dropWarnRatio"`
+}
+
+// SpanCompressionConf is the codecs of the serialized spans. The stored spans are decompressed on read whatever codec
+// they are saved by, so that the codec can be changed at any time.
+type SpanCompressionConf struct {
+	// Storage compresses each span saved in the sqlite or the file storage
+	Storage CompressionConf `yaml:"storage"`
+	// Export compresses each batch of spans exported to the remote collector. Only gzip is accepted by the collectors.
+	Export CompressionConf `yaml:"export"`
+}
+
+// CompressionConf is a compression codec and its level
+type CompressionConf struct {
+	// Codec is none, gzip or zstd. Default to none.
+	Codec string `json:"codec" yaml:"codec"`
+	// Level is the compression level of the codec: -2 to 9 for gzip and 1 to 22 for zstd. 0 means the default level.
+	Level int `json:"level,omitempty" yaml:"level"`
+}
+
+// ProxyConf is the proxy to reach the cloud. The empty fields fall back to the HTTP_PROXY, HTTPS_PROXY and NO_PROXY
+// environment variables.
+type ProxyConf struct {
+	HttpProxy  string `yaml:"httpProxy"`
+	HttpsProxy string `yaml:"httpsProxy"`
+	// NoProxy is the comma separated hosts, domains or CIDRs to connect directly
+	NoProxy string `yaml:"noProxy"`
+}
+
+// ComplianceConf is the compliance mode required by the regulated deployments. Once enabled, only TLS 1.2+ with the
+// approved cipher suites are allowed and the plaintext endpoints are rejected.
+type ComplianceConf struct {
+	Enable bool `yaml:"enable"`
+	// AllowPlaintext is the hosts or host:port endpoints which are allowed to connect without TLS
+	AllowPlaintext []string `yaml:"allowPlaintext"`
+}
+
+// DiscoveryConf configures the re-discovery and the health-aware selection of the discoverable endpoints
+type DiscoveryConf struct {
+	// RefreshInterval is the interval to re-resolve the SRV names
+	RefreshInterval cast.DurationConf `yaml:"refreshInterval"`
+	// FailureCooldown is how long a failed address is skipped in the selection
+	FailureCooldown cast.DurationConf `yaml:"failureCooldown"`
+}
+
+type OtelMetricsConf struct {
+	Enable bool `yaml:"enable"`
+	// Endpoint is the OTLP/HTTP endpoint of the metrics. Empty means the same endpoint as the tracer
+	Endpoint string `yaml:"endpoint"`
+	// Interval is the interval to export the metrics
+	Interval cast.DurationConf `yaml:"interval"`
 }
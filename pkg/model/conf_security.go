@@ -14,11 +14,17 @@
 
 package model
 
-import "encoding/base64"
+import (
+	"encoding/base64"
+
+	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+)
 
 type SecurityConf struct {
 	Encryption *EncryptionConf          `yaml:"encryption,omitempty"`
 	Tls        *TlsConfigurationOptions `yaml:"tls,omitempty"`
+	// CertExpiryWarning is how long before the certificates expire to warn. Default to 7 days.
+	CertExpiryWarning cast.DurationConf `yaml:"certExpiryWarning,omitempty"`
 }
 
 type EncryptionConf struct {
@@ -38,6 +44,9 @@ type TlsConfigurationOptions struct {
 	TLSMinVersion        string          `json:"tlsMinVersion" yaml:"tlsMinVersion"`
 	RenegotiationSupport string          `json:"renegotiationSupport" yaml:"renegotiationSupport"`
 	Decrypt              *EncryptionConf `json:"decrypt" yaml:"decrypt,omitempty"`
+	// SpiffeID authorizes the peer by the SPIFFE ID in its certificate instead of the host name. It can be a trust
+	// domain like spiffe://example.org to accept all the workloads in it.
+	SpiffeID string `json:"spiffeId" yaml:"spiffeId,omitempty"`
 	// whether use default tls setting
 	Tls string `json:"tls"`
 }
@@ -15,6 +15,9 @@
 package modules
 
 import (
+	"fmt"
+	"sort"
+
 	"github.com/lf-edge/ekuiper/contract/v2/api"
 
 	"github.com/lf-edge/ekuiper/v2/pkg/syncx"
@@ -23,6 +26,8 @@ import (
 type ConnectionStatus struct {
 	Status string `json:"status"`
 	ErrMsg string `json:"errMsg,omitempty"`
+	// Details are the driver level status like the session or the server version, if reported by the connection
+	Details map[string]any `json:"details,omitempty"`
 }
 
 type Connection interface {
@@ -35,18 +40,170 @@ type Connection interface {
 
 type StatefulDialer interface {
 	SetStatusChangeHandler(ctx api.StreamContext, handler api.StatusChangeHandler)
+	StatusReporter
+}
+
+// StatusReporter is implemented by the connections which can report their driver level status. The stateless
+// connections may implement it to show the details without being treated as stateful.
+type StatusReporter interface {
 	Status(ctx api.StreamContext) ConnectionStatus
 }
 
+// Reconnectable is implemented by the connections which can reconnect in place, for example to keep the client and
+// its subscriptions. The pool prefers it to recreating the broken connection, and recreates it if Reconnect fails.
+type Reconnectable interface {
+	Reconnect(ctx api.StreamContext) error
+}
+
+// Warmable is implemented by the connections which need the warm-up work before the first use, such as to fill the
+// SQL pool or to restore the MQTT subscriptions. The pool calls Warmup after the connection is created or reconnected
+// and reports it as connected only once it returns. A failed warm-up is a failed dial to retry.
+type Warmable interface {
+	Warmup(ctx api.StreamContext) error
+}
+
+// Throttleable is implemented by the connections whose consumers acquire the throttle of the pool before each
+// operation. The reserved throttle prop is only accepted by them, otherwise it would not be enforced.
+type Throttleable interface {
+	CanThrottle() bool
+}
+
+// StatefulReconnect is implemented by the connections which reconnect by themselves and report the status changes
+type StatefulReconnect = StatefulDialer
+
+// Subscribable is implemented by the connections which can be used by sources to receive data
+type Subscribable interface {
+	CanSubscribe() bool
+}
+
+// Publishable is implemented by the connections which can be used by sinks to send data
+type Publishable interface {
+	CanPublish() bool
+}
+
+// Queryable is implemented by the connections which can be used by lookup sources to query data
+type Queryable interface {
+	CanQuery() bool
+}
+
+// ResourceUsage is the resources attributable to a connection
+type ResourceUsage struct {
+	Goroutines    int64 `json:"goroutines"`
+	BufferedBytes int64 `json:"bufferedBytes"`
+	OpenFiles     int64 `json:"openFiles"`
+}
+
+// ResourceReporter is implemented by the connections which can report the resources they hold, such as the
+// goroutines of the client, the buffered messages and the sockets
+type ResourceReporter interface {
+	ResourceUsage() ResourceUsage
+}
+
+// Validator is implemented by the connections which can validate the props without dialing, for example to check
+// the format of the addresses and the combination of the props. It is used to test the props before saving them.
+type Validator interface {
+	Validate(ctx api.StreamContext, props map[string]any) error
+}
+
+type Capability string
+
+const (
+	CapSubscribe         Capability = "subscribe"
+	CapPublish           Capability = "publish"
+	CapQuery             Capability = "query"
+	CapStatefulReconnect Capability = "statefulReconnect"
+)
+
+// GetCapabilities returns the capabilities the connection declares by the capability interfaces
+func GetCapabilities(conn Connection) []Capability {
+	caps := make([]Capability, 0, 4)
+	if s, ok := conn.(Subscribable); ok && s.CanSubscribe() {
+		caps = append(caps, CapSubscribe)
+	}
+	if p, ok := conn.(Publishable); ok && p.CanPublish() {
+		caps = append(caps, CapPublish)
+	}
+	if q, ok := conn.(Queryable); ok && q.CanQuery() {
+		caps = append(caps, CapQuery)
+	}
+	if _, ok := conn.(StatefulReconnect); ok {
+		caps = append(caps, CapStatefulReconnect)
+	}
+	return caps
+}
+
+// DeclaresCapabilities returns whether the connection implements any of the Subscribable, Publishable or Queryable.
+// The capabilities of the connections without declaration are unknown and should not be validated.
+func DeclaresCapabilities(conn Connection) bool {
+	switch conn.(type) {
+	case Subscribable, Publishable, Queryable:
+		return true
+	default:
+		return false
+	}
+}
+
 type ConnectionProvider func(ctx api.StreamContext) Connection
 
+// ConnectionDescriptor describes a connection type for the management console to render the create/update forms
+type ConnectionDescriptor struct {
+	Type        string                     `json:"type"`
+	DisplayName string                     `json:"displayName"`
+	Description string                     `json:"description,omitempty"`
+	Props       []ConnectionPropDescriptor `json:"props"`
+	Example     map[string]any             `json:"example,omitempty"`
+}
+
+// ConnectionPropDescriptor describes a property of the connection
+type ConnectionPropDescriptor struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Required    bool   `json:"required"`
+	Default     any    `json:"default,omitempty"`
+	Description string `json:"description,omitempty"`
+	// Values are the allowed values if it is an enum
+	Values []any `json:"values,omitempty"`
+}
+
 var (
-	connectionRegisterMu syncx.RWMutex
-	ConnectionRegister   map[string]ConnectionProvider
+	connectionRegisterMu  syncx.RWMutex
+	ConnectionRegister    map[string]ConnectionProvider
+	connectionDescriptors map[string]ConnectionDescriptor
 )
 
 func init() {
 	ConnectionRegister = map[string]ConnectionProvider{}
+	connectionDescriptors = map[string]ConnectionDescriptor{}
+}
+
+// RegisterConnectionDescriptor registers the descriptor of a connection type. The type is identified by desc.Type.
+func RegisterConnectionDescriptor(desc ConnectionDescriptor) {
+	connectionRegisterMu.Lock()
+	defer connectionRegisterMu.Unlock()
+	if desc.DisplayName == "" {
+		desc.DisplayName = desc.Type
+	}
+	connectionDescriptors[desc.Type] = desc
+}
+
+// GetConnectionDescriptor returns the registered descriptor of the connection type
+func GetConnectionDescriptor(name string) (ConnectionDescriptor, bool) {
+	connectionRegisterMu.RLock()
+	defer connectionRegisterMu.RUnlock()
+	desc, ok := connectionDescriptors[name]
+	return desc, ok
+}
+
+// ConnectionTypes returns the names of all the registered connection types in order
+func ConnectionTypes() []string {
+	connectionRegisterMu.RLock()
+	defer connectionRegisterMu.RUnlock()
+	names := make([]string, 0, len(ConnectionRegister))
+	for name := range ConnectionRegister {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
 }
 
 func RegisterConnection(name string, cp ConnectionProvider) {
@@ -55,6 +212,30 @@ func RegisterConnection(name string, cp ConnectionProvider) {
 	ConnectionRegister[name] = cp
 }
 
+// RegisterConnectionType registers a connection type at runtime, for example by a dynamically loaded plugin.
+// Unlike RegisterConnection, it fails if the type is already registered.
+func RegisterConnectionType(name string, cp ConnectionProvider) error {
+	if name == "" || cp == nil {
+		return fmt.Errorf("connection type name and provider are required")
+	}
+	connectionRegisterMu.Lock()
+	defer connectionRegisterMu.Unlock()
+	if _, ok := ConnectionRegister[name]; ok {
+		return fmt.Errorf("connection type %s is already registered", name)
+	}
+	ConnectionRegister[name] = cp
+	return nil
+}
+
+// UnregisterConnection removes the connection type. It does not check the live connections,
+// use connection.UnregisterConnectionType instead to protect the pooled connections.
+func UnregisterConnection(name string) {
+	connectionRegisterMu.Lock()
+	defer connectionRegisterMu.Unlock()
+	delete(ConnectionRegister, name)
+	delete(connectionDescriptors, name)
+}
+
 // GetConnectionProvider returns a connection provider by name in a thread-safe manner
 func GetConnectionProvider(name string) (ConnectionProvider, bool) {
 	connectionRegisterMu.RLock()
@@ -62,3 +243,68 @@ func GetConnectionProvider(name string) (ConnectionProvider, bool) {
 	cp, ok := ConnectionRegister[name]
 	return cp, ok
 }
+
+// GetConnectionCapabilities returns the capabilities of the connection type. The bool is false if the type is not found
+// or it does not declare the capabilities.
+func GetConnectionCapabilities(ctx api.StreamContext, name string) ([]Capability, bool) {
+	cp, ok := GetConnectionProvider(name)
+	if !ok {
+		return nil, false
+	}
+	conn := cp(ctx)
+	return GetCapabilities(conn), DeclaresCapabilities(conn)
+}
+
+// ConnectionCapabilities returns the declared capabilities of all the registered connection types
+func ConnectionCapabilities(ctx api.StreamContext) map[string][]Capability {
+	names := ConnectionTypes()
+	result := make(map[string][]Capability, len(names))
+	for _, name := range names {
+		if caps, ok := GetConnectionCapabilities(ctx, name); ok {
+			result[name] = caps
+		}
+	}
+	return result
+}
+
+// ConnectionTypeInfo is the metadata of a registered connection type, so that the management consoles can list the
+// supported types without a hardcoded list
+type ConnectionTypeInfo struct {
+	Type        string `json:"type"`
+	DisplayName string `json:"displayName"`
+	Description string `json:"description,omitempty"`
+	// Stateful means the connection reconnects by itself and reports the status changes. Otherwise, the pool checks
+	// its health by Ping.
+	Stateful bool `json:"stateful"`
+	Ping     bool `json:"ping"`
+	// Capabilities are empty if the type does not declare them
+	Capabilities []Capability `json:"capabilities,omitempty"`
+	// Props are the props of the registered descriptor if provided
+	Props []ConnectionPropDescriptor `json:"props,omitempty"`
+}
+
+// ListConnectionTypes returns the metadata of all the registered connection types in order
+func ListConnectionTypes(ctx api.StreamContext) []ConnectionTypeInfo {
+	names := ConnectionTypes()
+	result := make([]ConnectionTypeInfo, 0, len(names))
+	for _, name := range names {
+		cp, ok := GetConnectionProvider(name)
+		if !ok {
+			continue
+		}
+		info := ConnectionTypeInfo{Type: name, DisplayName: name}
+		if desc, ok := GetConnectionDescriptor(name); ok {
+			info.DisplayName = desc.DisplayName
+			info.Description = desc.Description
+			info.Props = desc.Props
+		}
+		conn := cp(ctx)
+		_, info.Stateful = conn.(StatefulDialer)
+		info.Ping = !info.Stateful
+		if DeclaresCapabilities(conn) {
+			info.Capabilities = GetCapabilities(conn)
+		}
+		result = append(result, info)
+	}
+	return result
+}
@@ -0,0 +1,154 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package modules
+
+import (
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+)
+
+// The prop types of the descriptor which are checked by the validation. The props of other types are not checked.
+const (
+	PropTypeString   = "string"
+	PropTypeInt      = "int"
+	PropTypeFloat    = "float"
+	PropTypeBool     = "bool"
+	PropTypeDuration = "duration"
+	PropTypeList     = "list"
+	PropTypeObject   = "object"
+)
+
+var durationTypes = map[reflect.Type]bool{
+	reflect.TypeOf(time.Duration(0)):     true,
+	reflect.TypeOf(cast.DurationConf(0)): true,
+}
+
+// PropsFromStruct describes the props by the fields of the config struct which the props are decoded into. The prop
+// name is the json tag. The tags required:"true", enum:"a,b" and description:"..." set the other attributes.
+// The embedded structs are flattened like the decoding.
+func PropsFromStruct(v any) []ConnectionPropDescriptor {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+	var props []ConnectionPropDescriptor
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if f.Anonymous && name == "" {
+			props = append(props, PropsFromStruct(reflect.New(f.Type).Interface())...)
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		p := ConnectionPropDescriptor{
+			Name:        name,
+			Type:        propType(f.Type),
+			Required:    f.Tag.Get("required") == "true",
+			Description: f.Tag.Get("description"),
+		}
+		if enum := f.Tag.Get("enum"); enum != "" {
+			for _, e := range strings.Split(enum, ",") {
+				p.Values = append(p.Values, strings.TrimSpace(e))
+			}
+		}
+		props = append(props, p)
+	}
+	return props
+}
+
+func propType(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if durationTypes[t] {
+		return PropTypeDuration
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return PropTypeString
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return PropTypeInt
+	case reflect.Float32, reflect.Float64:
+		return PropTypeFloat
+	case reflect.Bool:
+		return PropTypeBool
+	case reflect.Slice, reflect.Array:
+		return PropTypeList
+	case reflect.Map, reflect.Struct:
+		return PropTypeObject
+	default:
+		return ""
+	}
+}
+
+// CheckPropType checks whether the prop value matches the descriptor type. The numbers decoded from JSON are float64
+// so the integral floats are valid int.
+func CheckPropType(typ string, v any) bool {
+	switch typ {
+	case PropTypeString:
+		_, ok := v.(string)
+		return ok
+	case PropTypeInt:
+		switch n := v.(type) {
+		case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+			return true
+		case float64:
+			return n == float64(int64(n))
+		case float32:
+			return n == float32(int64(n))
+		}
+		return false
+	case PropTypeFloat:
+		switch v.(type) {
+		case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+			return true
+		}
+		return false
+	case PropTypeBool:
+		_, ok := v.(bool)
+		return ok
+	case PropTypeDuration:
+		switch d := v.(type) {
+		case string:
+			_, err := time.ParseDuration(d)
+			return err == nil
+		case int, int64, float64, time.Duration:
+			return true
+		}
+		return false
+	case PropTypeList:
+		k := reflect.ValueOf(v).Kind()
+		return k == reflect.Slice || k == reflect.Array
+	case PropTypeObject:
+		return reflect.ValueOf(v).Kind() == reflect.Map
+	default:
+		return true
+	}
+}
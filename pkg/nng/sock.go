@@ -35,8 +35,8 @@ import (
 )
 
 type SockConf struct {
-	Url      string `json:"url"`
-	Protocol string `json:"protocol"`
+	Url      string `json:"url" required:"true"`
+	Protocol string `json:"protocol" enum:"pair,push,req"`
 }
 
 type Sock struct {
@@ -185,6 +185,22 @@ func (s *Sock) Ping(_ api.StreamContext) error {
 	return nil
 }
 
+// ResourceUsage reports the socket once connected
+func (s *Sock) ResourceUsage() modules.ResourceUsage {
+	if !s.connected.Load() {
+		return modules.ResourceUsage{}
+	}
+	return modules.ResourceUsage{OpenFiles: 1}
+}
+
+func (s *Sock) CanSubscribe() bool {
+	return true
+}
+
+func (s *Sock) CanPublish() bool {
+	return true
+}
+
 func (s *Sock) Close(_ api.StreamContext) error {
 	return s.Socket.Close()
 }
@@ -184,6 +184,12 @@ var passwordDict = map[string]struct{}{
 	"refresh_token": {},
 }
 
+// IsPasswordKey returns whether the prop key is a password or token which should be hidden
+func IsPasswordKey(k string) bool {
+	_, ok := passwordDict[k]
+	return ok
+}
+
 func HidePassword(props map[string]any) map[string]any {
 	return hide(props)
 }
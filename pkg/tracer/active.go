@@ -0,0 +1,160 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build trace || !core
+
+package tracer
+
+import (
+	"strings"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/lf-edge/ekuiper/v2/pkg/syncx"
+)
+
+// maxActiveSpans is the max count of the running spans to annotate. The spans started beyond it are not annotated.
+const maxActiveSpans = 4096
+
+// ConnectionResolver returns the ids of the connections used by the op of the rule
+type ConnectionResolver func(ruleID, opID string) []string
+
+type activeKey struct {
+	rule string
+	op   string
+}
+
+// activeSpanSet keeps the sampled spans of the rules which are not ended yet, so that the events happened outside the
+// rule such as the connection status changes can be added to the affected spans.
+type activeSpanSet struct {
+	mu    syncx.Mutex
+	spans map[activeKey]map[trace.SpanID]sdktrace.ReadWriteSpan
+	count int
+}
+
+var (
+	activeSpans        = &activeSpanSet{spans: make(map[activeKey]map[trace.SpanID]sdktrace.ReadWriteSpan)}
+	connectionResolver atomic.Pointer[ConnectionResolver]
+)
+
+// SetConnectionResolver sets the func to find the connections of the op, which are recorded as the connection.id
+// attribute once the span of the op starts. Nil removes it.
+func SetConnectionResolver(r ConnectionResolver) {
+	if r == nil {
+		connectionResolver.Store(nil)
+		return
+	}
+	connectionResolver.Store(&r)
+}
+
+// AnnotateActiveSpans adds the event to the running spans of the op of the rule, or all the ops of the rule if the op
+// is empty. It returns the count of the annotated spans.
+func AnnotateActiveSpans(ruleID, opID, name string, attrs ...attribute.KeyValue) int {
+	spans := activeSpans.find(ruleID, opID)
+	for _, s := range spans {
+		s.AddEvent(name, trace.WithAttributes(attrs...))
+	}
+	return len(spans)
+}
+
+// trackSpan records the sampled span of the rule op and its connections once it starts
+func trackSpan(s sdktrace.ReadWriteSpan) {
+	if !s.SpanContext().IsSampled() {
+		return
+	}
+	key, ok := spanActiveKey(s.Attributes())
+	if !ok {
+		return
+	}
+	if r := connectionResolver.Load(); r != nil {
+		if ids := (*r)(key.rule, key.op); len(ids) > 0 {
+			s.SetAttributes(attribute.String(ConnectionIDKey, strings.Join(ids, ",")))
+		}
+	}
+	activeSpans.add(key, s)
+}
+
+// untrackSpan removes the span once it ends
+func untrackSpan(s sdktrace.ReadOnlySpan) {
+	if !s.SpanContext().IsSampled() {
+		return
+	}
+	if key, ok := spanActiveKey(s.Attributes()); ok {
+		activeSpans.remove(key, s.SpanContext().SpanID())
+	}
+}
+
+func spanActiveKey(attrs []attribute.KeyValue) (activeKey, bool) {
+	var key activeKey
+	for _, attr := range attrs {
+		switch attr.Key {
+		case ruleAttributeKey:
+			key.rule = attr.Value.AsString()
+		case OpKey:
+			key.op = attr.Value.AsString()
+		}
+	}
+	return key, key.rule != "" && key.op != ""
+}
+
+func (a *activeSpanSet) add(key activeKey, s sdktrace.ReadWriteSpan) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.count >= maxActiveSpans {
+		return
+	}
+	m, ok := a.spans[key]
+	if !ok {
+		m = make(map[trace.SpanID]sdktrace.ReadWriteSpan)
+		a.spans[key] = m
+	}
+	if _, ok := m[s.SpanContext().SpanID()]; !ok {
+		m[s.SpanContext().SpanID()] = s
+		a.count++
+	}
+}
+
+func (a *activeSpanSet) remove(key activeKey, id trace.SpanID) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	m, ok := a.spans[key]
+	if !ok {
+		return
+	}
+	if _, ok := m[id]; ok {
+		delete(m, id)
+		a.count--
+	}
+	if len(m) == 0 {
+		delete(a.spans, key)
+	}
+}
+
+func (a *activeSpanSet) find(ruleID, opID string) []sdktrace.ReadWriteSpan {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var result []sdktrace.ReadWriteSpan
+	for key, m := range a.spans {
+		if key.rule != ruleID || (opID != "" && key.op != opID) {
+			continue
+		}
+		for _, s := range m {
+			result = append(result, s)
+		}
+	}
+	return result
+}
@@ -0,0 +1,72 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build trace || !core
+
+package tracer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/lf-edge/ekuiper/v2/pkg/model"
+)
+
+func TestAnnotateActiveSpans(t *testing.T) {
+	SetConnectionResolver(func(ruleID, opID string) []string {
+		if ruleID == "r1" && opID == "sink" {
+			return []string{"c1", "c2"}
+		}
+		return nil
+	})
+	defer SetConnectionResolver(nil)
+	p := newSpanPipeline(nil, model.SpanPipelineConf{QueueSize: 10, BatchSize: 10})
+	recorder := tracetest.NewSpanRecorder()
+	tr := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(p), sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+	start := func(rule, op string) trace.Span {
+		attrs := []attribute.KeyValue{attribute.String(ruleAttributeKey, rule)}
+		if op != "" {
+			attrs = append(attrs, attribute.String(OpKey, op))
+		}
+		_, span := tr.Start(context.Background(), op, trace.WithAttributes(attrs...))
+		return span
+	}
+	spans := []trace.Span{start("r1", "sink"), start("r1", "source"), start("r2", "sink"), start("r1", "")}
+
+	require.Equal(t, 1, AnnotateActiveSpans("r1", "sink", "connection c1 reconnecting", attribute.String(ConnectionIDKey, "c1")))
+	require.Equal(t, 2, AnnotateActiveSpans("r1", "", "connection c2 connected"))
+	require.Equal(t, 0, AnnotateActiveSpans("r3", "", "connection c3 connected"))
+	for _, s := range spans {
+		s.End()
+	}
+	require.Equal(t, 0, AnnotateActiveSpans("r1", "", "connection c1 connected"))
+
+	ended := recorder.Ended()
+	require.Len(t, ended, 4)
+	sink := ended[0]
+	require.Contains(t, sink.Attributes(), attribute.String(ConnectionIDKey, "c1,c2"))
+	require.Len(t, sink.Events(), 2)
+	require.Equal(t, "connection c1 reconnecting", sink.Events()[0].Name)
+	require.Equal(t, []attribute.KeyValue{attribute.String(ConnectionIDKey, "c1")}, sink.Events()[0].Attributes)
+	require.Len(t, ended[1].Events(), 1)
+	require.NotContains(t, ended[1].Attributes(), attribute.String(ConnectionIDKey, "c1,c2"))
+	require.Empty(t, ended[2].Events())
+	require.Empty(t, ended[3].Events())
+}
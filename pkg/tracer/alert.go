@@ -0,0 +1,197 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build trace || !core
+
+package tracer
+
+import (
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/pkg/syncx"
+)
+
+const (
+	// alertTraceCapacity bounds the traces watched by the trace alerts. The oldest trace is evicted once exceeded.
+	alertTraceCapacity = 4096
+	// alertHistory is the count of the recent alert events kept for the query
+	alertHistory = 100
+)
+
+// alertTrace is the state of a trace watched by the trace alerts
+type alertTrace struct {
+	start time.Time
+	fired map[string]struct{}
+}
+
+// alertHub checks the finished spans against the alerts of their rules and fires the events to the handlers
+type alertHub struct {
+	mu       syncx.Mutex
+	next     int
+	handlers map[int]func(*SpanAlertEvent)
+	traces   map[string]*alertTrace
+	// order is the ring of the watched trace ids by the insertion order
+	order []string
+	head  int
+	// recent is the latest events by the fired order
+	recent []*SpanAlertEvent
+}
+
+var spanAlerts = newAlertHub()
+
+func newAlertHub() *alertHub {
+	return &alertHub{
+		handlers: make(map[int]func(*SpanAlertEvent)),
+		traces:   make(map[string]*alertTrace),
+	}
+}
+
+// OnSpanAlert registers the handler of the span alert events and returns the func to unregister. The handlers are
+// called by the background span pipeline one by one, so they must return quickly and never block.
+func OnSpanAlert(handler func(*SpanAlertEvent)) func() {
+	spanAlerts.mu.Lock()
+	defer spanAlerts.mu.Unlock()
+	id := spanAlerts.next
+	spanAlerts.next++
+	spanAlerts.handlers[id] = handler
+	return func() {
+		spanAlerts.mu.Lock()
+		defer spanAlerts.mu.Unlock()
+		delete(spanAlerts.handlers, id)
+	}
+}
+
+// GetSpanAlerts returns the recent span alert events of the rule, the latest first. Empty rule id returns all.
+func GetSpanAlerts(ruleID string) []*SpanAlertEvent {
+	spanAlerts.mu.Lock()
+	defer spanAlerts.mu.Unlock()
+	result := make([]*SpanAlertEvent, 0, len(spanAlerts.recent))
+	for i := len(spanAlerts.recent) - 1; i >= 0; i-- {
+		if e := spanAlerts.recent[i]; ruleID == "" || e.RuleID == ruleID {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+// watchSpan checks the finished span against the alerts of its rule
+func watchSpan(s sdktrace.ReadOnlySpan) {
+	rule := spanRule(s.Attributes())
+	if rule == "" {
+		return
+	}
+	st := runtimeTraces.rule(rule)
+	if st == nil || len(st.conf.Alerts) == 0 {
+		return
+	}
+	spanAlerts.check(rule, st.conf.Alerts, s)
+}
+
+func (h *alertHub) check(rule string, alerts []SpanAlert, s sdktrace.ReadOnlySpan) {
+	sc := s.SpanContext()
+	traceID := sc.TraceID().String()
+	isErr := s.Status().Code == codes.Error
+	elapsed := s.EndTime().Sub(s.StartTime())
+	var events []*SpanAlertEvent
+	h.mu.Lock()
+	for _, a := range alerts {
+		ev := &SpanAlertEvent{
+			Alert:    a.Name,
+			RuleID:   rule,
+			TraceID:  traceID,
+			SpanID:   sc.SpanID().String(),
+			SpanName: s.Name(),
+			Time:     s.EndTime().UnixMilli(),
+		}
+		threshold := time.Duration(a.Threshold)
+		if a.Trace {
+			t := h.trace(traceID, s.StartTime())
+			if _, ok := t.fired[a.Name]; ok {
+				continue
+			}
+			ev.Elapsed = s.EndTime().Sub(t.start).Milliseconds()
+			switch {
+			case a.OnError && isErr:
+				ev.Reason, ev.Error = AlertReasonError, s.Status().Description
+			case threshold > 0 && s.EndTime().Sub(t.start) > threshold:
+				ev.Reason, ev.Threshold = AlertReasonLatency, threshold.Milliseconds()
+			default:
+				continue
+			}
+			t.fired[a.Name] = struct{}{}
+		} else {
+			if a.Span != "" && a.Span != s.Name() {
+				continue
+			}
+			ev.Elapsed = elapsed.Milliseconds()
+			switch {
+			case a.OnError && isErr:
+				ev.Reason, ev.Error = AlertReasonError, s.Status().Description
+			case threshold > 0 && elapsed > threshold:
+				ev.Reason, ev.Threshold = AlertReasonLatency, threshold.Milliseconds()
+			default:
+				continue
+			}
+		}
+		events = append(events, ev)
+		h.record(ev)
+	}
+	var handlers []func(*SpanAlertEvent)
+	if len(events) > 0 {
+		handlers = make([]func(*SpanAlertEvent), 0, len(h.handlers))
+		for _, handler := range h.handlers {
+			handlers = append(handlers, handler)
+		}
+	}
+	h.mu.Unlock()
+	for _, ev := range events {
+		conf.Log.Warnf("span alert %s of rule %s fired by %s of span %s in trace %s", ev.Alert, ev.RuleID, ev.Reason, ev.SpanName, ev.TraceID)
+		for _, handler := range handlers {
+			handler(ev)
+		}
+	}
+}
+
+// trace returns the watched state of the trace with the earliest start. It must be called with the lock.
+func (h *alertHub) trace(traceID string, start time.Time) *alertTrace {
+	t, ok := h.traces[traceID]
+	if !ok {
+		if len(h.order) < alertTraceCapacity {
+			h.order = append(h.order, traceID)
+		} else {
+			delete(h.traces, h.order[h.head])
+			h.order[h.head] = traceID
+			h.head = (h.head + 1) % alertTraceCapacity
+		}
+		t = &alertTrace{start: start, fired: make(map[string]struct{})}
+		h.traces[traceID] = t
+	}
+	if start.Before(t.start) {
+		t.start = start
+	}
+	return t
+}
+
+// record keeps the event in the recent events. It must be called with the lock.
+func (h *alertHub) record(ev *SpanAlertEvent) {
+	if len(h.recent) == alertHistory {
+		copy(h.recent, h.recent[1:])
+		h.recent = h.recent[:alertHistory-1]
+	}
+	h.recent = append(h.recent, ev)
+}
@@ -0,0 +1,148 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build trace || !core
+
+package tracer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+)
+
+func alertSpan(traceID, spanID byte, name string, start time.Time, d time.Duration, isErr bool) sdktrace.ReadOnlySpan {
+	stub := tracetest.SpanStub{
+		Name: name,
+		SpanContext: trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID: trace.TraceID{traceID},
+			SpanID:  trace.SpanID{spanID},
+		}),
+		StartTime:  start,
+		EndTime:    start.Add(d),
+		Attributes: []attribute.KeyValue{attribute.String("rule", "r1")},
+	}
+	if isErr {
+		stub.Status = sdktrace.Status{Code: codes.Error, Description: "boom"}
+	}
+	return stub.Snapshot()
+}
+
+func TestSpanAlertValidate(t *testing.T) {
+	for _, tc := range []struct {
+		alerts []SpanAlert
+		err    string
+	}{
+		{alerts: []SpanAlert{{Threshold: cast.DurationConf(time.Second)}}, err: "alert name is required"},
+		{alerts: []SpanAlert{{Name: "a", OnError: true}, {Name: "a", OnError: true}}, err: "duplicate alert a"},
+		{alerts: []SpanAlert{{Name: "a"}}, err: "alert a requires threshold or onError"},
+		{alerts: []SpanAlert{{Name: "a", Threshold: -1}}, err: "alert a threshold must not be negative"},
+		{alerts: []SpanAlert{{Name: "a", Trace: true, Span: "sink", OnError: true}}, err: "alert a watches the trace, span must be empty"},
+		{alerts: []SpanAlert{{Name: "a", Span: "sink", Threshold: cast.DurationConf(time.Second)}}},
+	} {
+		err := (&RuleTraceConf{Alerts: tc.alerts}).Validate()
+		if tc.err == "" {
+			require.NoError(t, err)
+		} else {
+			require.EqualError(t, err, tc.err)
+		}
+	}
+}
+
+func TestSpanAlert(t *testing.T) {
+	conf.IsTesting = true
+	conf.InitConf()
+	resetRuntimeTraces()
+	defer resetRuntimeTraces()
+	old := spanAlerts
+	spanAlerts = newAlertHub()
+	defer func() { spanAlerts = old }()
+
+	require.NoError(t, SetRuleTrace("r1", &RuleTraceConf{Enabled: true, Alerts: []SpanAlert{
+		{Name: "slowSink", Span: "sink", Threshold: cast.DurationConf(2 * time.Second)},
+		{Name: "failed", OnError: true},
+		{Name: "slowTrace", Trace: true, Threshold: cast.DurationConf(3 * time.Second)},
+	}}))
+	// the alerts are persisted with the setting
+	resetRuntimeTraces()
+	c, err := GetRuleTrace("r1")
+	require.NoError(t, err)
+	require.Len(t, c.Alerts, 3)
+	require.Equal(t, cast.DurationConf(2*time.Second), c.Alerts[0].Threshold)
+
+	var fired []*SpanAlertEvent
+	unregister := OnSpanAlert(func(ev *SpanAlertEvent) {
+		fired = append(fired, ev)
+	})
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	watchSpan(alertSpan(1, 1, "source", start, time.Second, false))
+	require.Empty(t, fired)
+	watchSpan(alertSpan(1, 2, "sink", start.Add(time.Second), 3*time.Second, false))
+	require.Len(t, fired, 2)
+	require.Equal(t, "slowSink", fired[0].Alert)
+	require.Equal(t, AlertReasonLatency, fired[0].Reason)
+	require.Equal(t, int64(3000), fired[0].Elapsed)
+	require.Equal(t, int64(2000), fired[0].Threshold)
+	require.Equal(t, "slowTrace", fired[1].Alert)
+	require.Equal(t, int64(4000), fired[1].Elapsed)
+
+	// the trace alert fires once for a trace
+	watchSpan(alertSpan(1, 3, "op", start.Add(2*time.Second), 3*time.Second, true))
+	require.Len(t, fired, 3)
+	require.Equal(t, "failed", fired[2].Alert)
+	require.Equal(t, AlertReasonError, fired[2].Reason)
+	require.Equal(t, "boom", fired[2].Error)
+
+	// the spans of the other rules are not watched
+	other := tracetest.SpanStub{Name: "sink", StartTime: start, EndTime: start.Add(time.Minute)}
+	watchSpan(other.Snapshot())
+	require.Len(t, fired, 3)
+
+	unregister()
+	watchSpan(alertSpan(2, 1, "sink", start, time.Minute, false))
+	require.Len(t, fired, 3)
+	events := GetSpanAlerts("r1")
+	require.Len(t, events, 5)
+	require.Equal(t, trace.TraceID{2}.String(), events[0].TraceID)
+	require.Empty(t, GetSpanAlerts("r2"))
+}
+
+func TestSpanAlertBounded(t *testing.T) {
+	h := newAlertHub()
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	alerts := []SpanAlert{{Name: "slowTrace", Trace: true, Threshold: cast.DurationConf(time.Second)}}
+	for i := 0; i < alertTraceCapacity+10; i++ {
+		s := tracetest.SpanStub{
+			Name: "op",
+			SpanContext: trace.NewSpanContext(trace.SpanContextConfig{
+				TraceID: trace.TraceID{byte(i), byte(i >> 8)},
+				SpanID:  trace.SpanID{1},
+			}),
+			StartTime: start,
+			EndTime:   start.Add(2 * time.Second),
+		}
+		h.check("r1", alerts, s.Snapshot())
+	}
+	require.Len(t, h.traces, alertTraceCapacity)
+	require.Len(t, h.recent, alertHistory)
+}
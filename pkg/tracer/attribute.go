@@ -0,0 +1,266 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// The type tags of the attributes whose types are lost once the span is decoded from the json
+const (
+	AttributeTypeInt64        = "int64"
+	AttributeTypeInt64Slice   = "int64[]"
+	AttributeTypeFloat64Slice = "float64[]"
+	AttributeTypeBoolSlice    = "bool[]"
+	AttributeTypeStringSlice  = "string[]"
+)
+
+// attributeType returns the type tag of the attribute value, or empty if the json keeps its type
+func attributeType(v interface{}) string {
+	switch v.(type) {
+	case int64:
+		return AttributeTypeInt64
+	case []int64:
+		return AttributeTypeInt64Slice
+	case []float64:
+		return AttributeTypeFloat64Slice
+	case []bool:
+		return AttributeTypeBoolSlice
+	case []string:
+		return AttributeTypeStringSlice
+	default:
+		return ""
+	}
+}
+
+// UnmarshalJSON decodes the span and restores the attributes of the recorded types. The integers are decoded
+// from the raw json so that the values beyond the float64 precision are kept.
+func (span *LocalSpan) UnmarshalJSON(data []byte) error {
+	type plain LocalSpan
+	if err := json.Unmarshal(data, (*plain)(span)); err != nil {
+		return err
+	}
+	if len(span.AttributeTypes) == 0 || len(span.Attribute) == 0 {
+		return nil
+	}
+	var raw struct {
+		Attribute map[string]json.RawMessage `json:"attribute"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	for k, t := range span.AttributeTypes {
+		r, ok := raw.Attribute[k]
+		if !ok {
+			continue
+		}
+		// keep the generic value if the tag is unknown or does not match, such as the tag of a newer version
+		if v, err := decodeTypedAttribute(r, t); err == nil {
+			span.Attribute[k] = v
+		}
+	}
+	return nil
+}
+
+func decodeTypedAttribute(r json.RawMessage, t string) (interface{}, error) {
+	var (
+		v   interface{}
+		err error
+	)
+	switch t {
+	case AttributeTypeInt64:
+		var i int64
+		err = json.Unmarshal(r, &i)
+		v = i
+	case AttributeTypeInt64Slice:
+		var s []int64
+		err = json.Unmarshal(r, &s)
+		v = s
+	case AttributeTypeFloat64Slice:
+		var s []float64
+		err = json.Unmarshal(r, &s)
+		v = s
+	case AttributeTypeBoolSlice:
+		var s []bool
+		err = json.Unmarshal(r, &s)
+		v = s
+	case AttributeTypeStringSlice:
+		var s []string
+		err = json.Unmarshal(r, &s)
+		v = s
+	default:
+		return nil, fmt.Errorf("unknown attribute type %s", t)
+	}
+	return v, err
+}
+
+// restoreAttributeTypes converts the generic attribute values decoded from the binary form to the recorded types
+func restoreAttributeTypes(span *LocalSpan) {
+	for k, t := range span.AttributeTypes {
+		v, ok := span.Attribute[k]
+		if !ok {
+			continue
+		}
+		if tv, ok := convertAttribute(v, t); ok {
+			span.Attribute[k] = tv
+		}
+	}
+	for _, c := range span.ChildSpan {
+		restoreAttributeTypes(c)
+	}
+}
+
+func convertAttribute(v interface{}, t string) (interface{}, bool) {
+	switch t {
+	case AttributeTypeInt64:
+		return toInt64(v)
+	case AttributeTypeInt64Slice:
+		return convertSlice(v, toInt64)
+	case AttributeTypeFloat64Slice:
+		return convertSlice(v, toFloat64)
+	case AttributeTypeBoolSlice:
+		return convertSlice(v, func(e interface{}) (bool, bool) {
+			b, ok := e.(bool)
+			return b, ok
+		})
+	case AttributeTypeStringSlice:
+		return convertSlice(v, func(e interface{}) (string, bool) {
+			s, ok := e.(string)
+			return s, ok
+		})
+	default:
+		return nil, false
+	}
+}
+
+func convertSlice[T any](v interface{}, conv func(interface{}) (T, bool)) (interface{}, bool) {
+	if s, ok := v.([]T); ok {
+		return s, true
+	}
+	items, ok := v.([]interface{})
+	if !ok {
+		return nil, false
+	}
+	r := make([]T, len(items))
+	for i, item := range items {
+		if r[i], ok = conv(item); !ok {
+			return nil, false
+		}
+	}
+	return r, true
+}
+
+func toInt64(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return n, true
+	case int:
+		return int64(n), true
+	case uint64:
+		if n > math.MaxInt64 {
+			return 0, false
+		}
+		return int64(n), true
+	case float64:
+		// the spans without the type tags have the integers decoded as float64
+		if n != math.Trunc(n) || n < math.MinInt64 || n >= math.MaxInt64 {
+			return 0, false
+		}
+		return int64(n), true
+	case json.Number:
+		i, err := n.Int64()
+		return i, err == nil
+	default:
+		return 0, false
+	}
+}
+
+func toFloat64(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int64:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// IntAttribute returns the integer attribute. The whole float64 values of the spans stored without the type tags
+// are accepted too.
+func (span *LocalSpan) IntAttribute(key string) (int64, bool) {
+	v, ok := span.Attribute[key]
+	if !ok {
+		return 0, false
+	}
+	return toInt64(v)
+}
+
+// FloatAttribute returns the numeric attribute as float64
+func (span *LocalSpan) FloatAttribute(key string) (float64, bool) {
+	v, ok := span.Attribute[key]
+	if !ok {
+		return 0, false
+	}
+	return toFloat64(v)
+}
+
+func (span *LocalSpan) StringAttribute(key string) (string, bool) {
+	s, ok := span.Attribute[key].(string)
+	return s, ok
+}
+
+func (span *LocalSpan) BoolAttribute(key string) (bool, bool) {
+	b, ok := span.Attribute[key].(bool)
+	return b, ok
+}
+
+// StringsAttribute returns the string array attribute whether it is typed or decoded as a generic array
+func (span *LocalSpan) StringsAttribute(key string) ([]string, bool) {
+	v, ok := span.Attribute[key]
+	if !ok {
+		return nil, false
+	}
+	r, ok := convertAttribute(v, AttributeTypeStringSlice)
+	if !ok {
+		return nil, false
+	}
+	return r.([]string), true
+}
+
+// FormatAttribute formats the attribute value to compare with the string filters. The numbers are formatted the
+// same whether they are decoded as integers or float64, so that 1e+06 and 1000000 both match "1000000".
+func FormatAttribute(v interface{}) string {
+	switch n := v.(type) {
+	case string:
+		return n
+	case float64:
+		return strconv.FormatFloat(n, 'f', -1, 64)
+	case int64:
+		return strconv.FormatInt(n, 10)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func typedSpan() *LocalSpan {
+	return &LocalSpan{
+		Name:    "op",
+		TraceID: "t0",
+		SpanID:  "s0",
+		Attribute: map[string]interface{}{
+			"rule":  "r1",
+			"count": int64(9007199254740993),
+			"ids":   []int64{1, 2},
+			"rates": []float64{0.5, 1},
+			"flags": []bool{true, false},
+			"tags":  []string{"a", "b"},
+			"ratio": 0.5,
+		},
+		AttributeTypes: map[string]string{
+			"count": AttributeTypeInt64,
+			"ids":   AttributeTypeInt64Slice,
+			"rates": AttributeTypeFloat64Slice,
+			"flags": AttributeTypeBoolSlice,
+			"tags":  AttributeTypeStringSlice,
+		},
+	}
+}
+
+func TestTypedAttributeRoundTrip(t *testing.T) {
+	for _, format := range []SpanFormat{SpanFormatJSON, SpanFormatBinary} {
+		t.Run(string(format), func(t *testing.T) {
+			span := typedSpan()
+			data, err := MarshalSpans([]*LocalSpan{span}, format)
+			require.NoError(t, err)
+			decoded, err := UnmarshalSpans(data)
+			require.NoError(t, err)
+			require.Len(t, decoded, 1)
+			require.Equal(t, span.Attribute, decoded[0].Attribute)
+		})
+	}
+}
+
+func TestUntypedAttributeDecode(t *testing.T) {
+	decoded, err := DecodeLocalSpan([]byte(`{"name":"op","attribute":{"count":1000000,"tags":["a"],"ratio":0.5}}`))
+	require.NoError(t, err)
+	require.Equal(t, float64(1000000), decoded.Attribute["count"])
+	require.Equal(t, []interface{}{"a"}, decoded.Attribute["tags"])
+
+	i, ok := decoded.IntAttribute("count")
+	require.True(t, ok)
+	require.Equal(t, int64(1000000), i)
+	_, ok = decoded.IntAttribute("ratio")
+	require.False(t, ok)
+	tags, ok := decoded.StringsAttribute("tags")
+	require.True(t, ok)
+	require.Equal(t, []string{"a"}, tags)
+	require.Equal(t, "1000000", FormatAttribute(decoded.Attribute["count"]))
+}
+
+func TestTypedAttributeMismatch(t *testing.T) {
+	// the values which do not match the tags are kept as decoded
+	decoded, err := DecodeLocalSpan([]byte(`{"name":"op","attribute":{"count":"x","ids":[1.5]},"attributeTypes":{"count":"int64","ids":"int64[]","missing":"int64"}}`))
+	require.NoError(t, err)
+	require.Equal(t, "x", decoded.Attribute["count"])
+	require.Equal(t, []interface{}{1.5}, decoded.Attribute["ids"])
+	_, ok := decoded.Attribute["missing"]
+	require.False(t, ok)
+}
+
+func TestAttributeHelpers(t *testing.T) {
+	span := typedSpan()
+	i, ok := span.IntAttribute("count")
+	require.True(t, ok)
+	require.Equal(t, int64(9007199254740993), i)
+	f, ok := span.FloatAttribute("ratio")
+	require.True(t, ok)
+	require.Equal(t, 0.5, f)
+	s, ok := span.StringAttribute("rule")
+	require.True(t, ok)
+	require.Equal(t, "r1", s)
+	_, ok = span.StringAttribute("count")
+	require.False(t, ok)
+	_, ok = span.BoolAttribute("flags")
+	require.False(t, ok)
+	tags, ok := span.StringsAttribute("tags")
+	require.True(t, ok)
+	require.Equal(t, []string{"a", "b"}, tags)
+	_, ok = span.StringsAttribute("ids")
+	require.False(t, ok)
+
+	require.Equal(t, "9007199254740993", FormatAttribute(span.Attribute["count"]))
+	require.Equal(t, "0.5", FormatAttribute(0.5))
+	require.Equal(t, "[a b]", FormatAttribute(span.Attribute["tags"]))
+}
+
+func TestTypedAttributeReader(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewSpanWriter(&buf, SpanFormatJSONLines)
+	require.NoError(t, err)
+	require.NoError(t, w.Write(typedSpan()))
+	require.NoError(t, w.Close())
+	r, err := NewSpanReader(&buf)
+	require.NoError(t, err)
+	span, err := r.Read()
+	require.NoError(t, err)
+	require.Equal(t, int64(9007199254740993), span.Attribute["count"])
+}
@@ -0,0 +1,216 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build trace || !core
+
+package tracer
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+)
+
+const (
+	bundleManifest     = "manifest.json"
+	bundleTraceDir     = "traces/"
+	maxBundleEntrySize = 256 << 20
+)
+
+// ExportTraceBundle writes the traces, the trace settings of their rules, the resource of the node and the tracer
+// config into the writer as a gzipped tar archive. The flat spans of each trace are in traces/{traceID}.json and the
+// manifest.json describes the bundle. The traces not found are listed in the manifest, and it is an error if none
+// is found.
+func ExportTraceBundle(traceIDs []string, w io.Writer) error {
+	globalTracerManager.InitIfNot()
+	b := &TraceBundle{
+		Version:   TraceBundleVersion,
+		CreatedAt: getClock().Now().UnixMilli(),
+		Resource:  make(map[string]interface{}),
+		Rules:     make(map[string]*RuleTraceConf),
+		Traces:    make([]TraceBundleEntry, 0, len(traceIDs)),
+	}
+	var roots []*LocalSpan
+	seen := make(map[string]struct{}, len(traceIDs))
+	for _, id := range traceIDs {
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		root, err := GetTraceByID(id)
+		if err != nil {
+			return err
+		}
+		if root == nil {
+			b.Missing = append(b.Missing, id)
+			continue
+		}
+		roots = append(roots, root)
+	}
+	if len(roots) == 0 {
+		return errorx.NewWithCode(errorx.NOT_FOUND, fmt.Sprintf("traces %v are not found", traceIDs))
+	}
+	// the running tracer config which may differ from the persisted one before the reload
+	globalTracerManager.RLock()
+	tc := &TracerConfig{
+		EnableRemoteCollector: globalTracerManager.EnableRemoteEndpoint,
+		ServiceName:           globalTracerManager.ServiceName,
+		RemoteEndpoint:        globalTracerManager.RemoteEndpoint,
+	}
+	globalTracerManager.RUnlock()
+	b.Tracer = tc
+	serviceName := tc.ServiceName
+	if serviceName == "" {
+		// the tracer initialized without config
+		serviceName = "kuiperd-service"
+	}
+	for _, attr := range newResource(serviceName).Attributes() {
+		b.Resource[string(attr.Key)] = attr.Value.AsInterface()
+	}
+
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+	for _, root := range roots {
+		spans := root.Flatten()
+		entry := TraceBundleEntry{
+			TraceID: root.TraceID,
+			RuleID:  root.RuleID,
+			Spans:   len(spans),
+			File:    bundleTraceDir + root.TraceID + ".json",
+		}
+		data, err := json.Marshal(spans)
+		if err != nil {
+			return err
+		}
+		if err := writeBundleEntry(tw, entry.File, data, b.CreatedAt); err != nil {
+			return err
+		}
+		b.Traces = append(b.Traces, entry)
+		if _, ok := b.Rules[root.RuleID]; !ok && root.RuleID != "" {
+			if rc, err := GetRuleTrace(root.RuleID); err == nil && rc != nil {
+				b.Rules[root.RuleID] = rc
+			}
+		}
+	}
+	// the manifest is the last so that it records the written traces
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeBundleEntry(tw, bundleManifest, data, b.CreatedAt); err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gw.Close()
+}
+
+func writeBundleEntry(tw *tar.Writer, name string, data []byte, ts int64) error {
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    0o644,
+		Size:    int64(len(data)),
+		ModTime: time.UnixMilli(ts),
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
+// ImportTraceBundle reads the bundle written by ExportTraceBundle. The traces are linked into trees in the Roots of the
+// returned manifest, and the broken traces are linked as much as possible like BuildTraceTree.
+func ImportTraceBundle(r io.Reader) (*TraceBundle, error) {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trace bundle: %v", err)
+	}
+	defer gr.Close()
+	tr := tar.NewReader(gr)
+	var b *TraceBundle
+	files := make(map[string][]*LocalSpan)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid trace bundle: %v", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		if hdr.Size > maxBundleEntrySize {
+			return nil, fmt.Errorf("entry %s of the trace bundle exceeds %d bytes", hdr.Name, maxBundleEntrySize)
+		}
+		data, err := io.ReadAll(io.LimitReader(tr, maxBundleEntrySize))
+		if err != nil {
+			return nil, fmt.Errorf("read %s of the trace bundle error: %v", hdr.Name, err)
+		}
+		name := path.Clean(hdr.Name)
+		switch {
+		case name == bundleManifest:
+			b = &TraceBundle{}
+			if err := json.Unmarshal(data, b); err != nil {
+				return nil, fmt.Errorf("invalid manifest of the trace bundle: %v", err)
+			}
+			if b.Version > TraceBundleVersion {
+				return nil, fmt.Errorf("trace bundle version %d is newer than the supported %d", b.Version, TraceBundleVersion)
+			}
+		case strings.HasPrefix(name, bundleTraceDir):
+			var raws []json.RawMessage
+			if err := json.Unmarshal(data, &raws); err != nil {
+				return nil, fmt.Errorf("invalid %s of the trace bundle: %v", name, err)
+			}
+			spans := make([]*LocalSpan, 0, len(raws))
+			for _, raw := range raws {
+				span, err := DecodeLocalSpan(raw)
+				if err != nil {
+					return nil, fmt.Errorf("invalid %s of the trace bundle: %v", name, err)
+				}
+				spans = append(spans, span)
+			}
+			files[name] = spans
+		}
+	}
+	if b == nil {
+		return nil, errors.New("invalid trace bundle: manifest.json is missing")
+	}
+	b.Roots = make(map[string]*LocalSpan, len(b.Traces))
+	for _, entry := range b.Traces {
+		spans, ok := files[path.Clean(entry.File)]
+		if !ok {
+			return nil, fmt.Errorf("invalid trace bundle: %s of trace %s is missing", entry.File, entry.TraceID)
+		}
+		root, err := BuildTraceTree(spans)
+		var tErr *TraceTreeError
+		if err != nil && !errors.As(err, &tErr) {
+			return nil, fmt.Errorf("invalid %s of the trace bundle: %v", entry.File, err)
+		}
+		if root != nil {
+			b.Roots[entry.TraceID] = root
+		}
+	}
+	return b, nil
+}
@@ -0,0 +1,95 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build trace || !core
+
+package tracer
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+)
+
+func TestTraceBundle(t *testing.T) {
+	conf.IsTesting = true
+	conf.InitConf()
+	resetRuntimeTraces()
+	defer resetRuntimeTraces()
+	g := &GlobalTracerManager{}
+	require.NoError(t, g.SetTracer(false, "edge1", ""))
+	old := globalTracerManager
+	globalTracerManager = g
+	defer func() {
+		globalTracerManager = old
+	}()
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := g.SpanExporter.spanStorage.(*LocalSpanMemoryStorage)
+	require.NoError(t, s.saveSpan(&LocalSpan{TraceID: "t0", SpanID: "s0", Name: "source", RuleID: "r1", StartTime: start}))
+	require.NoError(t, s.saveSpan(&LocalSpan{TraceID: "t0", SpanID: "s1", ParentSpanID: "s0", Name: "sink", RuleID: "r1", StartTime: start.Add(time.Millisecond)}))
+	require.NoError(t, s.saveSpan(&LocalSpan{TraceID: "t1", SpanID: "s2", Name: "source", RuleID: "r2", StartTime: start}))
+	require.NoError(t, SetRuleTrace("r1", &RuleTraceConf{Enabled: true, MaxDepth: 3}))
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, ExportTraceBundle([]string{"t0", "t1", "t0", "notExist"}, buf))
+	b, err := ImportTraceBundle(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	require.Equal(t, TraceBundleVersion, b.Version)
+	require.Equal(t, "edge1", b.Resource["service.name"])
+	require.Equal(t, "edge1", b.Tracer.ServiceName)
+	require.Equal(t, map[string]*RuleTraceConf{"r1": {Enabled: true, MaxDepth: 3}}, b.Rules)
+	require.Equal(t, []string{"notExist"}, b.Missing)
+	require.Equal(t, []TraceBundleEntry{
+		{TraceID: "t0", RuleID: "r1", Spans: 2, File: "traces/t0.json"},
+		{TraceID: "t1", RuleID: "r2", Spans: 1, File: "traces/t1.json"},
+	}, b.Traces)
+	require.Len(t, b.Roots, 2)
+	root := b.Roots["t0"]
+	require.Equal(t, "s0", root.SpanID)
+	require.Len(t, root.ChildSpan, 1)
+	require.Equal(t, "sink", root.ChildSpan[0].Name)
+
+	require.Error(t, ExportTraceBundle([]string{"notExist"}, &bytes.Buffer{}))
+}
+
+func TestImportBrokenTraceBundle(t *testing.T) {
+	_, err := ImportTraceBundle(bytes.NewReader([]byte("not gzip")))
+	require.Error(t, err)
+
+	archive := func(files map[string]string) []byte {
+		buf := &bytes.Buffer{}
+		gw := gzip.NewWriter(buf)
+		tw := tar.NewWriter(gw)
+		for name, content := range files {
+			require.NoError(t, writeBundleEntry(tw, name, []byte(content), 0))
+		}
+		require.NoError(t, tw.Close())
+		require.NoError(t, gw.Close())
+		return buf.Bytes()
+	}
+	_, err = ImportTraceBundle(bytes.NewReader(archive(map[string]string{"traces/t0.json": "[]"})))
+	require.EqualError(t, err, "invalid trace bundle: manifest.json is missing")
+	_, err = ImportTraceBundle(bytes.NewReader(archive(map[string]string{"manifest.json": `{"version":99}`})))
+	require.EqualError(t, err, "trace bundle version 99 is newer than the supported 1")
+	_, err = ImportTraceBundle(bytes.NewReader(archive(map[string]string{
+		"manifest.json": `{"version":1,"traces":[{"traceID":"t0","file":"traces/t0.json"}]}`,
+	})))
+	require.EqualError(t, err, "invalid trace bundle: traces/t0.json of trace t0 is missing")
+}
@@ -0,0 +1,146 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build trace || !core
+
+package tracer
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/metrics"
+)
+
+// retentionCleaner is implemented by the span storages which support TTL based cleanup
+type retentionCleaner interface {
+	CleanupBefore(deadline time.Time) (*CleanupResult, error)
+}
+
+// quotaCleaner is implemented by the span storages which support size based cleanup
+type quotaCleaner interface {
+	// CleanupOverQuota deletes the oldest traces until the spans and the bytes are within the limits. 0 means no limit.
+	CleanupOverQuota(maxSpans, maxBytes int64) (*CleanupResult, error)
+}
+
+// cleanupJob deletes the spans older than the retention periodically, then the oldest traces beyond the quota
+type cleanupJob struct {
+	cleaner   retentionCleaner
+	retention time.Duration
+	interval  time.Duration
+	maxSpans  int64
+	maxBytes  int64
+	cancel    context.CancelFunc
+	last      atomic.Pointer[CleanupResult]
+}
+
+func newCleanupJob(cleaner retentionCleaner, retention, interval time.Duration) *cleanupJob {
+	return &cleanupJob{
+		cleaner:   cleaner,
+		retention: retention,
+		interval:  interval,
+	}
+}
+
+// withQuota sets the quota if the storage supports it
+func (j *cleanupJob) withQuota(maxSpans, maxBytes int64) *cleanupJob {
+	if _, ok := j.cleaner.(quotaCleaner); ok {
+		j.maxSpans, j.maxBytes = maxSpans, maxBytes
+	} else if maxSpans > 0 || maxBytes > 0 {
+		conf.Log.Warnf("the local span storage does not support the quota, ignore it")
+	}
+	return j
+}
+
+// lastResult returns the result of the latest successful run, nil if never run
+func (j *cleanupJob) lastResult() *CleanupResult {
+	if j == nil {
+		return nil
+	}
+	return j.last.Load()
+}
+
+func (j *cleanupJob) start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	j.cancel = cancel
+	go j.run(ctx)
+}
+
+func (j *cleanupJob) stop() {
+	if j.cancel != nil {
+		j.cancel()
+	}
+}
+
+func (j *cleanupJob) run(ctx context.Context) {
+	c := getClock()
+	ticker := c.Ticker(j.interval)
+	defer ticker.Stop()
+	last := c.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			now := c.Now()
+			if steppedForward(last, now) {
+				// the deadline is moved forward too, skip the round so that the recent spans are not deleted by a
+				// wrong clock. The next round uses the corrected clock if the step is real.
+				conf.Log.Warnf("wall clock stepped forward by %v, skip the trace cleanup", now.Round(0).Sub(last.Round(0))-now.Sub(last))
+				last = now
+				continue
+			}
+			last = now
+			_, _ = j.runOnce(now)
+		}
+	}
+}
+
+// maxClockStep is the tolerance of the difference between the wall clock and the monotonic clock
+const maxClockStep = time.Minute
+
+// steppedForward returns whether the wall clock jumps forward between the two times like an NTP correction. The
+// elapsed time is measured by the monotonic clock if both times have the monotonic reading.
+func steppedForward(last, now time.Time) bool {
+	wall := now.Round(0).Sub(last.Round(0))
+	return wall-now.Sub(last) > maxClockStep
+}
+
+func (j *cleanupJob) runOnce(now time.Time) (*CleanupResult, error) {
+	r, err := j.cleaner.CleanupBefore(now.Add(-j.retention))
+	if err == nil && (j.maxSpans > 0 || j.maxBytes > 0) {
+		var q *CleanupResult
+		q, err = j.cleaner.(quotaCleaner).CleanupOverQuota(j.maxSpans, j.maxBytes)
+		if err == nil {
+			q.DeletedSpans += r.DeletedSpans
+			q.DeletedBytes += r.DeletedBytes
+			r = q
+		}
+	}
+	if err != nil {
+		TraceStoreCounter.WithLabelValues(metrics.LblException).Inc()
+		conf.Log.Warnf("trace cleanup err:%v", err)
+		return nil, err
+	}
+	r.Time = now.UnixMilli()
+	j.last.Store(r)
+	TraceStoreCounter.WithLabelValues(LblDeletedSpans).Add(float64(r.DeletedSpans))
+	TraceStoreCounter.WithLabelValues(LblDeletedBytes).Add(float64(r.DeletedBytes))
+	TraceStoreGauge.WithLabelValues(LblSpans).Set(float64(r.RemainSpans))
+	TraceStoreGauge.WithLabelValues(LblBytes).Set(float64(r.RemainBytes))
+	conf.Log.Infof("trace cleanup deleted %d spans(%d bytes) older than %v or beyond the quota, remain %d spans(%d bytes)", r.DeletedSpans, r.DeletedBytes, j.retention, r.RemainSpans, r.RemainBytes)
+	return r, nil
+}
@@ -0,0 +1,43 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"sync/atomic"
+
+	"github.com/benbjohnson/clock"
+)
+
+var realClock = clock.New()
+
+var tracerClock atomic.Pointer[clock.Clock]
+
+// SetClock replaces the clock of the span timestamps, the export stats, the error-only buffer and the retention
+// cleanup, for example by clock.NewMock() to advance the time deterministically in the tests. Nil restores the real
+// clock.
+func SetClock(c clock.Clock) {
+	if c == nil {
+		tracerClock.Store(nil)
+		return
+	}
+	tracerClock.Store(&c)
+}
+
+func getClock() clock.Clock {
+	if c := tracerClock.Load(); c != nil {
+		return *c
+	}
+	return realClock
+}
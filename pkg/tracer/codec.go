@@ -0,0 +1,242 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+
+	"github.com/ugorji/go/codec"
+)
+
+// SpanFormat is the serialization of a batch of spans
+type SpanFormat string
+
+const (
+	// SpanFormatJSON is a json array of the spans
+	SpanFormatJSON SpanFormat = "json"
+	// SpanFormatJSONLines is a span json per line
+	SpanFormatJSONLines SpanFormat = "jsonl"
+	// SpanFormatBinary is the msgpack of the spans one after another behind a magic header
+	SpanFormatBinary SpanFormat = "binary"
+)
+
+// spanBinaryMagic starts the binary form so that it can be told apart from the json
+var spanBinaryMagic = []byte{0xc1, 'K', 'S', 1}
+
+// spanMsgpack is the msgpack handle of the spans. It reads the json tags so that the fields are named the same as
+// the json, and decodes the attribute values as the types closest to the json decoding.
+var spanMsgpack = func() *codec.MsgpackHandle {
+	h := &codec.MsgpackHandle{}
+	h.MapType = reflect.TypeOf(map[string]interface{}(nil))
+	h.RawToString = true
+	h.WriteExt = true
+	h.SignedInteger = true
+	return h
+}()
+
+// ParseSpanFormat returns the span format by name. Empty means json.
+func ParseSpanFormat(s string) (SpanFormat, error) {
+	switch f := SpanFormat(s); f {
+	case "":
+		return SpanFormatJSON, nil
+	case SpanFormatJSON, SpanFormatJSONLines, SpanFormatBinary:
+		return f, nil
+	default:
+		return "", fmt.Errorf("unknown span format %s, must be json, jsonl or binary", s)
+	}
+}
+
+// MarshalSpans serializes the spans as json lines or the binary form
+func MarshalSpans(spans []*LocalSpan, format SpanFormat) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := NewSpanWriter(&buf, format)
+	if err != nil {
+		return nil, err
+	}
+	for _, span := range spans {
+		if err := w.Write(span); err != nil {
+			return nil, err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalSpans decodes the spans serialized by MarshalSpans of any format. The spans of the older schema versions
+// are upgraded.
+func UnmarshalSpans(data []byte) ([]*LocalSpan, error) {
+	r, err := NewSpanReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	var spans []*LocalSpan
+	for {
+		span, err := r.Read()
+		if err == io.EOF {
+			return spans, nil
+		}
+		if err != nil {
+			return spans, err
+		}
+		spans = append(spans, span)
+	}
+}
+
+// SpanWriter streams the spans into the writer one by one without serializing each span into its own buffer
+type SpanWriter struct {
+	w      *bufio.Writer
+	format SpanFormat
+	json   *json.Encoder
+	mp     *codec.Encoder
+	count  int
+}
+
+// NewSpanWriter creates the writer of the format. The binary form writes the magic header at once.
+func NewSpanWriter(w io.Writer, format SpanFormat) (*SpanWriter, error) {
+	sw := &SpanWriter{w: bufio.NewWriter(w), format: format}
+	switch format {
+	case SpanFormatJSON, SpanFormatJSONLines:
+		sw.json = json.NewEncoder(sw.w)
+	case SpanFormatBinary:
+		if _, err := sw.w.Write(spanBinaryMagic); err != nil {
+			return nil, err
+		}
+		sw.mp = codec.NewEncoder(sw.w, spanMsgpack)
+	default:
+		return nil, fmt.Errorf("unknown span format %s", format)
+	}
+	return sw, nil
+}
+
+// Write encodes the span with the current schema version
+func (e *SpanWriter) Write(span *LocalSpan) error {
+	span.SchemaVersion = LocalSpanSchemaVersion
+	if e.format == SpanFormatJSON {
+		sep := byte(',')
+		if e.count == 0 {
+			sep = '['
+		}
+		if err := e.w.WriteByte(sep); err != nil {
+			return err
+		}
+	}
+	var err error
+	if e.mp != nil {
+		err = e.mp.Encode(span)
+	} else {
+		// the encoder ends each span with a new line
+		err = e.json.Encode(span)
+	}
+	if err != nil {
+		return err
+	}
+	e.count++
+	return nil
+}
+
+// Close ends the json array if needed and flushes the buffered spans. It does not close the underlying writer.
+func (e *SpanWriter) Close() error {
+	if e.format == SpanFormatJSON {
+		end := "]"
+		if e.count == 0 {
+			end = "[]"
+		}
+		if _, err := e.w.WriteString(end); err != nil {
+			return err
+		}
+	}
+	return e.w.Flush()
+}
+
+// Count returns the number of the written spans
+func (e *SpanWriter) Count() int {
+	return e.count
+}
+
+// SpanReader decodes the spans one by one from a json array, json lines or the binary form. The format is detected
+// by the leading bytes.
+type SpanReader struct {
+	json *json.Decoder
+	// array is true once the leading bracket of the json array is consumed
+	array bool
+	mp    *codec.Decoder
+	r     *bufio.Reader
+}
+
+func NewSpanReader(r io.Reader) (*SpanReader, error) {
+	br := bufio.NewReader(r)
+	sr := &SpanReader{r: br}
+	head, err := br.Peek(len(spanBinaryMagic))
+	if err == nil && bytes.Equal(head, spanBinaryMagic) {
+		if _, err := br.Discard(len(spanBinaryMagic)); err != nil {
+			return nil, err
+		}
+		sr.mp = codec.NewDecoder(br, spanMsgpack)
+		return sr, nil
+	}
+	sr.json = json.NewDecoder(br)
+	if c, err := firstNonSpace(br); err == nil && c == '[' {
+		if _, err := sr.json.Token(); err != nil {
+			return nil, err
+		}
+		sr.array = true
+	}
+	return sr, nil
+}
+
+// firstNonSpace peeks the first byte which is not a json white space
+func firstNonSpace(br *bufio.Reader) (byte, error) {
+	for n := 1; ; n++ {
+		b, err := br.Peek(n)
+		if len(b) < n {
+			return 0, err
+		}
+		switch c := b[n-1]; c {
+		case ' ', '\t', '\r', '\n':
+		default:
+			return c, nil
+		}
+	}
+}
+
+// Read returns the next span, or io.EOF once all the spans are read
+func (d *SpanReader) Read() (*LocalSpan, error) {
+	span := &LocalSpan{}
+	if d.mp != nil {
+		if _, err := d.r.Peek(1); err != nil {
+			return nil, err
+		}
+		if err := d.mp.Decode(span); err != nil {
+			return nil, err
+		}
+		restoreAttributeTypes(span)
+	} else {
+		if d.array && !d.json.More() {
+			return nil, io.EOF
+		}
+		if err := d.json.Decode(span); err != nil {
+			return nil, err
+		}
+	}
+	upgradeLocalSpan(span)
+	return span, nil
+}
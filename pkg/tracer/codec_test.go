@@ -0,0 +1,142 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func codecSpans() []*LocalSpan {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	return []*LocalSpan{
+		{
+			Name:      "source",
+			TraceID:   "t0",
+			SpanID:    "s0",
+			RuleID:    "r1",
+			StartTime: start,
+			EndTime:   start.Add(time.Millisecond),
+			Attribute: map[string]interface{}{"rule": "r1", "data": `{"a":1}`, "ratio": 0.5, "ok": true},
+			Events:    []LocalEvent{{Name: "ev", Timestamp: start, Attributes: map[string]interface{}{"k": "v"}}},
+			Links:     []LocalLink{{TraceID: "t9", SpanID: "s9"}},
+			Resource:  map[string]interface{}{"service.name": "kuiper"},
+			Scope:     "kuiperd-service",
+		},
+		{
+			Name:          "sink",
+			TraceID:       "t0",
+			SpanID:        "s1",
+			ParentSpanID:  "s0",
+			RuleID:        "r1",
+			StartTime:     start.Add(time.Millisecond),
+			EndTime:       start.Add(3 * time.Millisecond),
+			Status:        "Error",
+			StatusMessage: "failed",
+		},
+	}
+}
+
+func TestSpanCodecRoundTrip(t *testing.T) {
+	for _, format := range []SpanFormat{SpanFormatJSON, SpanFormatJSONLines, SpanFormatBinary} {
+		t.Run(string(format), func(t *testing.T) {
+			spans := codecSpans()
+			data, err := MarshalSpans(spans, format)
+			require.NoError(t, err)
+			got, err := UnmarshalSpans(data)
+			require.NoError(t, err)
+			require.Equal(t, codecSpansWithVersion(), got)
+		})
+	}
+	jl, err := MarshalSpans(codecSpans(), SpanFormatJSONLines)
+	require.NoError(t, err)
+	require.Equal(t, 2, bytes.Count(jl, []byte("\n")))
+	bin, err := MarshalSpans(codecSpans(), SpanFormatBinary)
+	require.NoError(t, err)
+	require.True(t, bytes.HasPrefix(bin, spanBinaryMagic))
+	require.Less(t, len(bin), len(jl))
+}
+
+func codecSpansWithVersion() []*LocalSpan {
+	spans := codecSpans()
+	for _, s := range spans {
+		s.SchemaVersion = LocalSpanSchemaVersion
+	}
+	return spans
+}
+
+func TestSpanCodecEmpty(t *testing.T) {
+	for _, format := range []SpanFormat{SpanFormatJSON, SpanFormatJSONLines, SpanFormatBinary} {
+		data, err := MarshalSpans(nil, format)
+		require.NoError(t, err)
+		got, err := UnmarshalSpans(data)
+		require.NoError(t, err)
+		require.Empty(t, got)
+	}
+	got, err := UnmarshalSpans(nil)
+	require.NoError(t, err)
+	require.Empty(t, got)
+}
+
+func TestSpanCodecBinaryTypes(t *testing.T) {
+	data, err := MarshalSpans([]*LocalSpan{{TraceID: "t0", SpanID: "s0", Attribute: map[string]interface{}{"count": int64(3), "tags": []interface{}{"a", "b"}}}}, SpanFormatBinary)
+	require.NoError(t, err)
+	got, err := UnmarshalSpans(data)
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	// the integers are kept, while the json decodes them as float64
+	require.Equal(t, int64(3), got[0].Attribute["count"])
+	require.Equal(t, []interface{}{"a", "b"}, got[0].Attribute["tags"])
+}
+
+func TestSpanReaderUpgrade(t *testing.T) {
+	// the span lines of the old version without the rule id
+	r, err := NewSpanReader(bytes.NewBufferString(" {\"traceID\":\"t0\",\"spanID\":\"s0\",\"attribute\":{\"rule\":\"r1\"}}\n{\"traceID\":\"t0\",\"spanID\":\"s1\"}\n"))
+	require.NoError(t, err)
+	span, err := r.Read()
+	require.NoError(t, err)
+	require.Equal(t, "r1", span.RuleID)
+	require.Equal(t, LocalSpanSchemaVersion, span.SchemaVersion)
+	span, err = r.Read()
+	require.NoError(t, err)
+	require.Equal(t, "s1", span.SpanID)
+	_, err = r.Read()
+	require.Equal(t, io.EOF, err)
+
+	// a json array with the spaces ahead
+	spans, err := UnmarshalSpans([]byte("\n [{\"traceID\":\"t0\",\"spanID\":\"s0\"}, {\"traceID\":\"t0\",\"spanID\":\"s1\"}]"))
+	require.NoError(t, err)
+	require.Len(t, spans, 2)
+
+	_, err = UnmarshalSpans([]byte("{invalid"))
+	require.Error(t, err)
+}
+
+func TestSpanWriterFormat(t *testing.T) {
+	f, err := ParseSpanFormat("")
+	require.NoError(t, err)
+	require.Equal(t, SpanFormatJSON, f)
+	f, err = ParseSpanFormat("binary")
+	require.NoError(t, err)
+	require.Equal(t, SpanFormatBinary, f)
+	_, err = ParseSpanFormat("xml")
+	require.EqualError(t, err, "unknown span format xml, must be json, jsonl or binary")
+	_, err = NewSpanWriter(io.Discard, "xml")
+	require.EqualError(t, err, "unknown span format xml")
+}
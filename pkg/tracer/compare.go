@@ -0,0 +1,196 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// The kinds of the span differences
+const (
+	// SpanMatched is the span in both traces
+	SpanMatched = "matched"
+	// SpanRemoved is the span only in the baseline trace
+	SpanRemoved = "removed"
+	// SpanAdded is the span only in the compared trace
+	SpanAdded = "added"
+)
+
+// SpanDiff is the difference of a span between the baseline and the compared trace
+type SpanDiff struct {
+	// Path is the span names from the root separated by /. The repeated names of the siblings are suffixed by their
+	// position such as sink#1.
+	Path string `json:"path"`
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+	// SpanA and SpanB are the span ids in the baseline and the compared trace
+	SpanA string `json:"spanA,omitempty"`
+	SpanB string `json:"spanB,omitempty"`
+	// DurationA and DurationB are the microseconds of the span
+	DurationA int64 `json:"durationA"`
+	DurationB int64 `json:"durationB"`
+	// Delta is the microseconds of DurationB - DurationA of the matched span
+	Delta int64 `json:"delta"`
+	// SelfDelta is the delta of the self time which excludes the time of the children, so that the regression is
+	// located at the span instead of all its ancestors
+	SelfDelta int64 `json:"selfDelta"`
+	// StatusA and StatusB are set if the status of the matched span changes
+	StatusA string `json:"statusA,omitempty"`
+	StatusB string `json:"statusB,omitempty"`
+}
+
+// TraceComparison is the result of comparing a trace against a baseline trace of the same rule
+type TraceComparison struct {
+	RuleID string `json:"ruleID"`
+	TraceA string `json:"traceA"`
+	TraceB string `json:"traceB"`
+	// Delta is the microseconds of the root span duration of B - A
+	Delta int64 `json:"delta"`
+	// Spans are the differences of the spans in pre-order of the trees
+	Spans []*SpanDiff `json:"spans"`
+	// Regressed is the path of the matched span of the largest self time increase, empty if none slows down
+	Regressed string `json:"regressed,omitempty"`
+	// Structural reports whether the trees have added or removed spans
+	Structural bool `json:"structural"`
+}
+
+// CompareTraces aligns the trace tree b against the baseline tree a of the same rule and reports the latency delta of
+// each span and the structural differences. The roots are matched with each other, then the children of the matched
+// spans are matched by the span name and their position among the siblings of the same name.
+func CompareTraces(a, b *LocalSpan) (*TraceComparison, error) {
+	if a == nil || b == nil {
+		return nil, errors.New("both traces are required")
+	}
+	if a.RuleID != b.RuleID {
+		return nil, fmt.Errorf("trace %s of rule %s cannot compare with trace %s of rule %s", b.TraceID, b.RuleID, a.TraceID, a.RuleID)
+	}
+	r := &TraceComparison{
+		RuleID: a.RuleID,
+		TraceA: a.TraceID,
+		TraceB: b.TraceID,
+		Delta:  spanDuration(b) - spanDuration(a),
+	}
+	compareSpans(r, "", a, b)
+	var maxSelf int64
+	for _, d := range r.Spans {
+		if d.Kind != SpanMatched {
+			r.Structural = true
+			continue
+		}
+		if d.SelfDelta > maxSelf {
+			maxSelf = d.SelfDelta
+			r.Regressed = d.Path
+		}
+	}
+	return r, nil
+}
+
+// compareSpans compares the span a and b at the path. Either of them is nil if not matched.
+func compareSpans(r *TraceComparison, path string, a, b *LocalSpan) {
+	d := &SpanDiff{Path: path}
+	switch {
+	case a != nil && b != nil:
+		d.Name, d.Kind = a.Name, SpanMatched
+		d.SpanA, d.SpanB = a.SpanID, b.SpanID
+		d.DurationA, d.DurationB = spanDuration(a), spanDuration(b)
+		d.Delta = d.DurationB - d.DurationA
+		d.SelfDelta = selfDuration(b) - selfDuration(a)
+		if a.Status != b.Status {
+			d.StatusA, d.StatusB = a.Status, b.Status
+		}
+	case a != nil:
+		d.Name, d.Kind, d.SpanA = a.Name, SpanRemoved, a.SpanID
+		d.DurationA = spanDuration(a)
+	default:
+		d.Name, d.Kind, d.SpanB = b.Name, SpanAdded, b.SpanID
+		d.DurationB = spanDuration(b)
+	}
+	if d.Path == "" {
+		d.Path = d.Name
+	}
+	r.Spans = append(r.Spans, d)
+
+	var ca, cb []*LocalSpan
+	if a != nil {
+		ca = a.ChildSpan
+	}
+	if b != nil {
+		cb = b.ChildSpan
+	}
+	keysA, keysB := childKeys(ca), childKeys(cb)
+	matched := make(map[string]*LocalSpan, len(cb))
+	for i, child := range cb {
+		matched[keysB[i]] = child
+	}
+	for i, child := range ca {
+		other := matched[keysA[i]]
+		delete(matched, keysA[i])
+		compareSpans(r, d.Path+"/"+keysA[i], child, other)
+	}
+	for i, child := range cb {
+		if _, ok := matched[keysB[i]]; ok {
+			compareSpans(r, d.Path+"/"+keysB[i], nil, child)
+		}
+	}
+}
+
+// childKeys returns the keys to match the sorted children: the name, suffixed by the position among the siblings of
+// the same name if not the first
+func childKeys(children []*LocalSpan) []string {
+	keys := make([]string, len(children))
+	seen := make(map[string]int, len(children))
+	for i, child := range children {
+		n := seen[child.Name]
+		seen[child.Name] = n + 1
+		keys[i] = child.Name
+		if n > 0 {
+			keys[i] = fmt.Sprintf("%s#%d", child.Name, n)
+		}
+	}
+	return keys
+}
+
+func spanDuration(span *LocalSpan) int64 {
+	if span.EndTime.Before(span.StartTime) {
+		return 0
+	}
+	return span.EndTime.Sub(span.StartTime).Microseconds()
+}
+
+// selfDuration is the time of the span not covered by its children
+func selfDuration(span *LocalSpan) int64 {
+	var covered time.Duration
+	var end time.Time
+	// the children are sorted by the start time, merge the overlapped ones
+	for _, child := range span.ChildSpan {
+		start, stop := child.StartTime, child.EndTime
+		if start.Before(span.StartTime) {
+			start = span.StartTime
+		}
+		if stop.After(span.EndTime) {
+			stop = span.EndTime
+		}
+		if start.Before(end) {
+			start = end
+		}
+		if stop.After(start) {
+			covered += stop.Sub(start)
+			end = stop
+		}
+	}
+	return spanDuration(span) - covered.Microseconds()
+}
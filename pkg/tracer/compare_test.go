@@ -0,0 +1,88 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func compareTree(t *testing.T, traceID string, spans ...*LocalSpan) *LocalSpan {
+	for _, s := range spans {
+		s.TraceID, s.RuleID = traceID, "r1"
+	}
+	root, err := BuildTraceTree(spans)
+	require.NoError(t, err)
+	return root
+}
+
+func TestCompareTraces(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	ms := func(n int) time.Time { return start.Add(time.Duration(n) * time.Millisecond) }
+	a := compareTree(t, "ta",
+		&LocalSpan{SpanID: "a0", Name: "source", StartTime: ms(0), EndTime: ms(10)},
+		&LocalSpan{SpanID: "a1", ParentSpanID: "a0", Name: "filter", StartTime: ms(1), EndTime: ms(3)},
+		&LocalSpan{SpanID: "a2", ParentSpanID: "a0", Name: "sink", StartTime: ms(3), EndTime: ms(9)},
+		&LocalSpan{SpanID: "a3", ParentSpanID: "a0", Name: "sink", StartTime: ms(9), EndTime: ms(10)},
+	)
+	b := compareTree(t, "tb",
+		&LocalSpan{SpanID: "b0", Name: "source", StartTime: ms(0), EndTime: ms(20)},
+		&LocalSpan{SpanID: "b1", ParentSpanID: "b0", Name: "filter", StartTime: ms(1), EndTime: ms(3), Status: "Error"},
+		&LocalSpan{SpanID: "b2", ParentSpanID: "b0", Name: "sink", StartTime: ms(3), EndTime: ms(18)},
+		&LocalSpan{SpanID: "b3", ParentSpanID: "b0", Name: "project", StartTime: ms(18), EndTime: ms(19)},
+	)
+	r, err := CompareTraces(a, b)
+	require.NoError(t, err)
+	require.Equal(t, "r1", r.RuleID)
+	require.Equal(t, int64(10000), r.Delta)
+	require.Equal(t, "source/sink", r.Regressed)
+	require.True(t, r.Structural)
+	require.Equal(t, []*SpanDiff{
+		{Path: "source", Name: "source", Kind: SpanMatched, SpanA: "a0", SpanB: "b0", DurationA: 10000, DurationB: 20000, Delta: 10000, SelfDelta: 1000},
+		{Path: "source/filter", Name: "filter", Kind: SpanMatched, SpanA: "a1", SpanB: "b1", DurationA: 2000, DurationB: 2000, StatusB: "Error"},
+		{Path: "source/sink", Name: "sink", Kind: SpanMatched, SpanA: "a2", SpanB: "b2", DurationA: 6000, DurationB: 15000, Delta: 9000, SelfDelta: 9000},
+		{Path: "source/sink#1", Name: "sink", Kind: SpanRemoved, SpanA: "a3", DurationA: 1000},
+		{Path: "source/project", Name: "project", Kind: SpanAdded, SpanB: "b3", DurationB: 1000},
+	}, r.Spans)
+
+	// the same trace has no difference
+	r, err = CompareTraces(a, a)
+	require.NoError(t, err)
+	require.False(t, r.Structural)
+	require.Empty(t, r.Regressed)
+
+	_, err = CompareTraces(a, nil)
+	require.EqualError(t, err, "both traces are required")
+	b.RuleID = "r2"
+	_, err = CompareTraces(a, b)
+	require.EqualError(t, err, "trace tb of rule r2 cannot compare with trace ta of rule r1")
+}
+
+func TestSelfDuration(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	ms := func(n int) time.Time { return start.Add(time.Duration(n) * time.Millisecond) }
+	span := &LocalSpan{StartTime: ms(0), EndTime: ms(10), ChildSpan: []*LocalSpan{
+		{StartTime: ms(1), EndTime: ms(4)},
+		// overlapped with the previous child
+		{StartTime: ms(2), EndTime: ms(6)},
+		// inside the previous child
+		{StartTime: ms(3), EndTime: ms(5)},
+		// ends after the parent
+		{StartTime: ms(8), EndTime: ms(12)},
+	}}
+	require.Equal(t, int64(3000), selfDuration(span))
+}
@@ -0,0 +1,189 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build trace || !core
+
+package tracer
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/pkg/model"
+)
+
+// The codecs of the serialized spans
+const (
+	CodecNone = "none"
+	CodecGzip = "gzip"
+	CodecZstd = "zstd"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	// zstdDecoder is safe for the concurrent DecodeAll
+	zstdDecoder, _ = zstd.NewReader(nil, zstd.WithDecoderConcurrency(0))
+)
+
+var storageCompressor = &compressorHolder{}
+
+// compressorHolder keeps the compressor of the spans saved in the local storage. The config is replaced at runtime by
+// the reload, and the spans saved by the former codec are still read transparently.
+type compressorHolder struct {
+	c atomic.Pointer[spanCompressor]
+}
+
+func (h *compressorHolder) update(c model.CompressionConf) {
+	sc, err := newSpanCompressor(c)
+	if err != nil {
+		conf.Log.Warnf("invalid span compression %s: %v, the spans are saved uncompressed", c.Codec, err)
+		sc = &spanCompressor{codec: CodecNone}
+	}
+	h.c.Store(sc)
+}
+
+func (h *compressorHolder) get() *spanCompressor {
+	sc := h.c.Load()
+	if sc == nil {
+		if conf.Config == nil {
+			return nil
+		}
+		h.update(conf.Config.OpenTelemetry.SpanCompression.Storage)
+		sc = h.c.Load()
+	}
+	return sc
+}
+
+// spanCompressor compresses the serialized spans by the codec. It is safe for concurrent use.
+type spanCompressor struct {
+	codec string
+	level int
+	gzips sync.Pool
+	zstd  *zstd.Encoder
+}
+
+func newSpanCompressor(c model.CompressionConf) (*spanCompressor, error) {
+	sc := &spanCompressor{codec: c.Codec, level: c.Level}
+	switch c.Codec {
+	case "", CodecNone:
+		sc.codec = CodecNone
+	case CodecGzip:
+		level := c.Level
+		if level == 0 {
+			level = gzip.DefaultCompression
+		}
+		// validate the level once so that the pooled writers never fail
+		if _, err := gzip.NewWriterLevel(io.Discard, level); err != nil {
+			return nil, err
+		}
+		sc.gzips.New = func() any {
+			w, _ := gzip.NewWriterLevel(io.Discard, level)
+			return w
+		}
+	case CodecZstd:
+		opts := []zstd.EOption{zstd.WithEncoderConcurrency(1)}
+		if c.Level != 0 {
+			opts = append(opts, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(c.Level)))
+		}
+		e, err := zstd.NewWriter(nil, opts...)
+		if err != nil {
+			return nil, err
+		}
+		sc.zstd = e
+	default:
+		return nil, fmt.Errorf("unknown codec %s", c.Codec)
+	}
+	return sc, nil
+}
+
+// compress returns the data compressed by the codec. The data is returned as is if not compressed.
+func (c *spanCompressor) compress(data []byte) ([]byte, error) {
+	if c == nil {
+		return data, nil
+	}
+	switch c.codec {
+	case CodecGzip:
+		w := c.gzips.Get().(*gzip.Writer)
+		defer c.gzips.Put(w)
+		buf := &bytes.Buffer{}
+		w.Reset(buf)
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case CodecZstd:
+		return c.zstd.EncodeAll(data, make([]byte, 0, len(data)/4)), nil
+	default:
+		return data, nil
+	}
+}
+
+// codecOf detects the codec of the serialized span by the magic number. The json of the uncompressed spans never
+// starts with them.
+func codecOf(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, gzipMagic):
+		return CodecGzip
+	case bytes.HasPrefix(data, zstdMagic):
+		return CodecZstd
+	default:
+		return CodecNone
+	}
+}
+
+// decompress returns the serialized span whatever codec it is saved by
+func decompress(data []byte) ([]byte, error) {
+	switch codecOf(data) {
+	case CodecGzip:
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return io.ReadAll(r)
+	case CodecZstd:
+		return zstdDecoder.DecodeAll(data, nil)
+	default:
+		return data, nil
+	}
+}
+
+// encodeStoredSpan serializes the span to be saved in the local storage by the storage codec
+func encodeStoredSpan(span *LocalSpan) ([]byte, error) {
+	bs, err := span.ToBytes()
+	if err != nil {
+		return nil, err
+	}
+	return storageCompressor.get().compress(bs)
+}
+
+// decodeStoredSpan decodes the span saved in the local storage, compressed or not
+func decodeStoredSpan(data []byte) (*LocalSpan, error) {
+	bs, err := decompress(data)
+	if err != nil {
+		return nil, fmt.Errorf("decompress span error: %v", err)
+	}
+	return DecodeLocalSpan(bs)
+}
@@ -0,0 +1,155 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build trace || !core
+
+package tracer
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/gzip"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/pkg/model"
+)
+
+func TestSpanCompressor(t *testing.T) {
+	span := &LocalSpan{TraceID: "t0", SpanID: "s0", RuleID: "r1", Name: strings.Repeat("op", 100)}
+	data, err := span.ToBytes()
+	require.NoError(t, err)
+	for _, c := range []model.CompressionConf{
+		{},
+		{Codec: CodecNone},
+		{Codec: CodecGzip},
+		{Codec: CodecGzip, Level: 9},
+		{Codec: CodecZstd},
+		{Codec: CodecZstd, Level: 19},
+	} {
+		sc, err := newSpanCompressor(c)
+		require.NoError(t, err)
+		bs, err := sc.compress(data)
+		require.NoError(t, err)
+		require.Equal(t, sc.codec, codecOf(bs))
+		if sc.codec != CodecNone {
+			require.Less(t, len(bs), len(data))
+		}
+		got, err := decodeStoredSpan(bs)
+		require.NoError(t, err)
+		require.Equal(t, span.Name, got.Name)
+	}
+	_, err = newSpanCompressor(model.CompressionConf{Codec: "lz4"})
+	require.Error(t, err)
+	_, err = newSpanCompressor(model.CompressionConf{Codec: CodecGzip, Level: 20})
+	require.Error(t, err)
+	_, err = decodeStoredSpan(append([]byte{0x1f, 0x8b}, "broken"...))
+	require.Error(t, err)
+}
+
+func TestFileSpanStorageCompression(t *testing.T) {
+	conf.InitConf()
+	defer storageCompressor.update(model.CompressionConf{})
+	path := filepath.Join(t.TempDir(), "trace", "spans.log")
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	s, err := newFileSpanStorage(path, 10)
+	require.NoError(t, err)
+	require.NoError(t, s.saveSpan(&LocalSpan{TraceID: "t0", SpanID: "s0", RuleID: "r1", StartTime: start}))
+	storageCompressor.update(model.CompressionConf{Codec: CodecZstd})
+	require.NoError(t, s.saveSpan(&LocalSpan{TraceID: "t0", SpanID: "s1", ParentSpanID: "s0", RuleID: "r1", StartTime: start.Add(time.Second)}))
+	require.NoError(t, s.Close())
+
+	// both the plain and the compressed records are replayed
+	s, err = newFileSpanStorage(path, 10)
+	require.NoError(t, err)
+	defer s.Close()
+	root, err := s.GetTraceById("t0")
+	require.NoError(t, err)
+	require.Len(t, root.ChildSpan, 1)
+
+	r, err := s.recompress(storageCompressor.get())
+	require.NoError(t, err)
+	require.Equal(t, CodecZstd, r.Codec)
+	require.Equal(t, int64(2), r.Spans)
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NotContains(t, string(content), `"span"`)
+	root, err = s.GetTraceById("t0")
+	require.NoError(t, err)
+	require.Len(t, root.ChildSpan, 1)
+}
+
+func TestSqlStorageRecompress(t *testing.T) {
+	conf.InitConf()
+	defer storageCompressor.update(model.CompressionConf{})
+	setupTraceDB(t)
+	s := newSqlspanStorage()
+	attrs := map[string]any{"payload": strings.Repeat("a", 1024)}
+	require.NoError(t, s.saveLocalSpan(&LocalSpan{TraceID: "t0", SpanID: "s0", RuleID: "r1", Attribute: attrs}))
+	storageCompressor.update(model.CompressionConf{Codec: CodecGzip})
+	require.NoError(t, s.saveLocalSpan(&LocalSpan{TraceID: "t1", SpanID: "s1", RuleID: "r1", Attribute: attrs}))
+	for _, id := range []string{"t0", "t1"} {
+		root, err := s.GetTraceById(id)
+		require.NoError(t, err)
+		require.Equal(t, attrs["payload"], root.Attribute["payload"])
+	}
+
+	// only the plain span is rewritten
+	r, err := s.recompress(storageCompressor.get())
+	require.NoError(t, err)
+	require.Equal(t, int64(2), r.Spans)
+	require.Equal(t, int64(1), r.Rewritten)
+	require.Less(t, r.AfterBytes, r.BeforeBytes)
+	root, err := s.GetTraceById("t0")
+	require.NoError(t, err)
+	require.Equal(t, attrs["payload"], root.Attribute["payload"])
+	r, err = s.recompress(storageCompressor.get())
+	require.NoError(t, err)
+	require.Equal(t, int64(0), r.Rewritten)
+}
+
+func TestZipkinExporterGzip(t *testing.T) {
+	var (
+		received []zipkinSpan
+		encoding string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encoding = r.Header.Get("Content-Encoding")
+		gr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if err := json.NewDecoder(gr).Decode(&received); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+	sc, err := newSpanCompressor(model.CompressionConf{Codec: CodecGzip, Level: 1})
+	require.NoError(t, err)
+	z := &zipkinExporter{url: zipkinURL(srv.URL, false), client: srv.Client(), compressor: sc}
+	require.NoError(t, z.ExportSpans(context.Background(), testSpans(3)))
+	require.Equal(t, CodecGzip, encoding)
+	require.Len(t, received, 3)
+}
@@ -16,9 +16,13 @@ package tracer
 
 import (
 	"encoding/json"
+	"fmt"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/pkg/cast"
 )
 
 type TracerConfig struct {
@@ -35,24 +39,346 @@ func TracerConfigFromConf() *TracerConfig {
 	}
 }
 
+var configListener atomic.Value
+
+// OnConfigChange sets the listener which is called after the tracer config is changed by SetTracer
+func OnConfigChange(fn func(config *TracerConfig)) {
+	configListener.Store(fn)
+}
+
+func notifyConfigChange(config *TracerConfig) {
+	if fn, ok := configListener.Load().(func(config *TracerConfig)); ok && fn != nil {
+		fn(config)
+	}
+}
+
+// LocalSpanSchemaVersion is the current version of the serialized LocalSpan. Bump it when
+// the struct changes and add the upgrade logic in upgradeLocalSpan.
+const LocalSpanSchemaVersion = 4
+
 type LocalSpan struct {
+	// SchemaVersion is the version of the serialized span. 0 means written by the old instances without version.
+	SchemaVersion int `json:"schemaVersion,omitempty"`
+
 	Name         string                 `json:"name"`
 	TraceID      string                 `json:"traceID"`
 	SpanID       string                 `json:"spanID"`
 	ParentSpanID string                 `json:"parentSpanID,omitempty"`
 	Attribute    map[string]interface{} `json:"attribute,omitempty"`
-	Links        []LocalLink            `json:"links,omitempty"`
-	StartTime    time.Time              `json:"startTime"`
-	EndTime      time.Time              `json:"endTime"`
-	RuleID       string                 `json:"ruleID"`
+	// AttributeTypes are the type tags of the attributes whose types are lost by the json, such as int64 and the
+	// arrays. It is only recorded if openTelemetry.preserveAttributeTypes is on.
+	AttributeTypes map[string]string `json:"attributeTypes,omitempty"`
+	Links          []LocalLink       `json:"links,omitempty"`
+	StartTime      time.Time         `json:"startTime"`
+	EndTime        time.Time         `json:"endTime"`
+	RuleID         string            `json:"ruleID"`
+
+	// Events are the timed events of the span such as the error details
+	Events []LocalEvent `json:"events,omitempty"`
+	// Status is the status code of the span: Ok or Error. Empty means unset.
+	Status        string `json:"status,omitempty"`
+	StatusMessage string `json:"statusMessage,omitempty"`
+
+	// Resource are the attributes of the node which produces the span, such as service.name and host.name
+	Resource map[string]interface{} `json:"resource,omitempty"`
+	// Scope and ScopeVersion are the instrumentation scope which creates the span
+	Scope        string `json:"scope,omitempty"`
+	ScopeVersion string `json:"scopeVersion,omitempty"`
 
 	ChildSpan []*LocalSpan
 }
 
+type LocalEvent struct {
+	Name       string                 `json:"name"`
+	Timestamp  time.Time              `json:"timestamp"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+}
+
+// LocalLink is the link of the span to a span of another trace, such as a window span linked by its input tuples
 type LocalLink struct {
-	TraceID string `yaml:"traceID"`
+	TraceID    string                 `json:"traceID"`
+	SpanID     string                 `json:"spanID,omitempty"`
+	TraceState string                 `json:"traceState,omitempty"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+}
+
+// SpanLink is a link recorded by a span
+type SpanLink struct {
+	// TraceID, SpanID and Name are the span recording the link
+	TraceID string    `json:"traceID"`
+	SpanID  string    `json:"spanID"`
+	Name    string    `json:"name"`
+	Link    LocalLink `json:"link"`
+}
+
+// TraceLinks are the relationships of a trace with the other traces
+type TraceLinks struct {
+	// Links are recorded by the spans of the trace to the other traces
+	Links []SpanLink `json:"links"`
+	// LinkedBy are recorded by the spans of the other traces to the trace, such as the traces of the input tuples of
+	// a window linking to the window trace
+	LinkedBy []SpanLink `json:"linkedBy"`
+}
+
+// RuleTraceConf is the runtime trace setting of a rule which is persisted and applied each time the rule starts
+type RuleTraceConf struct {
+	Enabled bool `json:"enabled"`
+	// Strategy is the trace strategy of the rule: always or head. Empty means always.
+	Strategy string `json:"strategy,omitempty"`
+	// Ratio overrides the sampling ratio of the new traces of the rule. Nil means the global sampling.
+	Ratio *float64 `json:"ratio,omitempty"`
+	// MaxDepth limits the depth of the spans in the traces of the rule, the root span is of depth 1. 0 means no limit.
+	MaxDepth int `json:"maxDepth,omitempty"`
+	// Alerts watch the finished spans of the rule and fire the alert events
+	Alerts []SpanAlert `json:"alerts,omitempty"`
+	// Payload records a truncated sample of the data on the source and sink spans of the rule. Nil means no sample.
+	Payload *PayloadSampleConf `json:"payload,omitempty"`
+}
+
+// PayloadSampleConf is the payload sample setting of a rule. The sample is masked by the redaction before truncated.
+type PayloadSampleConf struct {
+	// MaxBytes is the max bytes of the sample, the longer payload is truncated. 0 means the default 1024.
+	MaxBytes int `json:"maxBytes,omitempty"`
+	// Every samples one of every N messages of each source and sink. 0 or 1 means all the messages.
+	Every int `json:"every,omitempty"`
+}
+
+// The defaults and limits of the payload sample
+const (
+	DefaultPayloadSampleBytes = 1024
+	MaxPayloadSampleBytes     = 64 * 1024
+)
+
+// The span attributes of the payload sample
+const (
+	PayloadSampleKey    = "data.sample"
+	PayloadSizeKey      = "data.size"
+	PayloadTruncatedKey = "data.truncated"
+)
+
+// The span attributes and events to correlate the spans with the shared connections of the rule
+const (
+	// OpKey is the span attribute of the rule op which started the span
+	OpKey = "op"
+	// ConnectionIDKey is the span attribute of the connections used by the op, joined by comma if more than one
+	ConnectionIDKey         = "connection.id"
+	ConnectionStatusKey     = "connection.status"
+	ConnectionPrevStatusKey = "connection.prevStatus"
+	ConnectionErrorKey      = "connection.error"
+)
+
+// SpanAlert fires an alert once a finished span of the rule, or its whole trace, exceeds the latency threshold or ends
+// with error. An alert fires at most once for a span, and once for a trace if it watches the trace.
+type SpanAlert struct {
+	Name string `json:"name"`
+	// Span is the name of the spans to watch. Empty means all the spans of the rule.
+	Span string `json:"span,omitempty"`
+	// Trace watches the elapsed time of the whole trace from its first span start instead of each span
+	Trace bool `json:"trace,omitempty"`
+	// Threshold is the latency to alert, such as 2s. 0 means no latency alert.
+	Threshold cast.DurationConf `json:"threshold,omitempty"`
+	// OnError alerts once the span ends with the error status
+	OnError bool `json:"onError,omitempty"`
+}
+
+// The reasons of the span alert events
+const (
+	AlertReasonLatency = "latency"
+	AlertReasonError   = "error"
+)
+
+// SpanAlertEvent is fired by the span alert of a rule
+type SpanAlertEvent struct {
+	Alert    string `json:"alert"`
+	RuleID   string `json:"ruleID"`
+	TraceID  string `json:"traceID"`
+	SpanID   string `json:"spanID"`
+	SpanName string `json:"spanName"`
+	// Reason is latency or error
+	Reason string `json:"reason"`
+	// Elapsed is the milliseconds of the span, or of the trace till the span ends if the alert watches the trace
+	Elapsed int64 `json:"elapsed"`
+	// Threshold is the milliseconds of the latency threshold
+	Threshold int64 `json:"threshold,omitempty"`
+	// Error is the status message of the error span
+	Error string `json:"error,omitempty"`
+	// Time is the unix milliseconds when the span ends
+	Time int64 `json:"time"`
+}
+
+// Validate checks the values of the rule trace setting
+func (c *RuleTraceConf) Validate() error {
+	switch strings.ToLower(c.Strategy) {
+	case "", "always", "head":
+	default:
+		return fmt.Errorf("invalid strategy %s, must be always or head", c.Strategy)
+	}
+	if c.Ratio != nil && (*c.Ratio < 0 || *c.Ratio > 1) {
+		return fmt.Errorf("ratio must be in [0, 1]")
+	}
+	if c.MaxDepth < 0 {
+		return fmt.Errorf("maxDepth must not be negative")
+	}
+	names := make(map[string]struct{}, len(c.Alerts))
+	for _, a := range c.Alerts {
+		if a.Name == "" {
+			return fmt.Errorf("alert name is required")
+		}
+		if _, ok := names[a.Name]; ok {
+			return fmt.Errorf("duplicate alert %s", a.Name)
+		}
+		names[a.Name] = struct{}{}
+		if a.Threshold < 0 {
+			return fmt.Errorf("alert %s threshold must not be negative", a.Name)
+		}
+		if a.Threshold == 0 && !a.OnError {
+			return fmt.Errorf("alert %s requires threshold or onError", a.Name)
+		}
+		if a.Trace && a.Span != "" {
+			return fmt.Errorf("alert %s watches the trace, span must be empty", a.Name)
+		}
+	}
+	if p := c.Payload; p != nil {
+		if p.MaxBytes < 0 || p.MaxBytes > MaxPayloadSampleBytes {
+			return fmt.Errorf("payload maxBytes must be in [0, %d]", MaxPayloadSampleBytes)
+		}
+		if p.Every < 0 {
+			return fmt.Errorf("payload every must not be negative")
+		}
+	}
+	return nil
+}
+
+// TraceSwitch is the global switch of the span collection
+type TraceSwitch struct {
+	Enabled bool `json:"enabled"`
+}
+
+// The formats to export a trace for the standard tracing ui
+const (
+	TraceFormatJaeger = "jaeger"
+	TraceFormatZipkin = "zipkin"
+)
+
+// SpanFilter selects the spans to subscribe. The empty fields match all.
+type SpanFilter struct {
+	RuleID string
+	// Attributes are the attribute values which must all be in the span
+	Attributes map[string]string
+}
+
+// CleanupResult records what a retention cleanup run deleted and what is left in the store
+type CleanupResult struct {
+	DeletedSpans int64 `json:"deletedSpans"`
+	DeletedBytes int64 `json:"deletedBytes"`
+	RemainSpans  int64 `json:"remainSpans"`
+	RemainBytes  int64 `json:"remainBytes"`
+	// Time is the unix milliseconds of the cleanup run
+	Time int64 `json:"time,omitempty"`
+}
+
+// SpanMigration is the result of copying the spans to the sqlite span store
+type SpanMigration struct {
+	Spans    int      `json:"spans"`
+	Failed   int      `json:"failed"`
+	Traces   int      `json:"traces"`
+	Verified int      `json:"verified"`
+	Missing  []string `json:"missing,omitempty"`
+}
+
+// SpanRecompression is the result of rewriting the stored spans by the storage codec
+type SpanRecompression struct {
+	Codec string `json:"codec"`
+	Spans int64  `json:"spans"`
+	// Rewritten is the count of the spans saved by another codec before
+	Rewritten   int64 `json:"rewritten"`
+	BeforeBytes int64 `json:"beforeBytes"`
+	AfterBytes  int64 `json:"afterBytes"`
+}
+
+// TraceBundleVersion is the version of the trace bundle format
+const TraceBundleVersion = 1
+
+// TraceBundle is the manifest of a trace bundle, which packages the traces with the context of the node for the
+// offline analysis
+type TraceBundle struct {
+	Version int `json:"version"`
+	// CreatedAt is the unix milliseconds when the bundle is exported
+	CreatedAt int64 `json:"createdAt"`
+	// Resource are the attributes of the node such as host.name and service.name
+	Resource map[string]interface{} `json:"resource,omitempty"`
+	Tracer   *TracerConfig          `json:"tracer,omitempty"`
+	// Rules are the trace settings of the rules of the traces
+	Rules  map[string]*RuleTraceConf `json:"rules,omitempty"`
+	Traces []TraceBundleEntry        `json:"traces"`
+	// Missing are the requested traces which are not found
+	Missing []string `json:"missing,omitempty"`
+	// Roots are the root spans of the traces with the child spans linked, which are loaded by the import
+	Roots map[string]*LocalSpan `json:"-"`
+}
+
+// TraceBundleEntry is a trace in the bundle
+type TraceBundleEntry struct {
+	TraceID string `json:"traceID"`
+	RuleID  string `json:"ruleID"`
+	Spans   int    `json:"spans"`
+	// File is the path of the flat spans of the trace in the archive
+	File string `json:"file"`
+}
+
+// TraceSearchResult is a page of the traces found by SearchTraces
+type TraceSearchResult struct {
+	// Total is the count of all the matched traces regardless of the page
+	Total int `json:"total"`
+	// Traces are the root spans of the matched traces in the page, the latest first. The child spans are not loaded.
+	Traces []*LocalSpan `json:"traces"`
+}
+
+// SpanMetrics is the latency, error and throughput derived from the ended spans of a rule or an operator of the rule.
+// The latencies are in microseconds estimated by the histogram buckets.
+type SpanMetrics struct {
+	RuleID string `json:"ruleId"`
+	// Operator is the span name, empty for the aggregation of all the spans of the rule
+	Operator  string  `json:"operator,omitempty"`
+	Count     int64   `json:"count"`
+	Errors    int64   `json:"errors"`
+	ErrorRate float64 `json:"errorRate"`
+	// Throughput is the spans per second in the last minute
+	Throughput float64 `json:"throughput"`
+	P50Us      int64   `json:"p50Us"`
+	P95Us      int64   `json:"p95Us"`
+	P99Us      int64   `json:"p99Us"`
+	MaxUs      int64   `json:"maxUs"`
 }
 
 func (span *LocalSpan) ToBytes() ([]byte, error) {
+	span.SchemaVersion = LocalSpanSchemaVersion
 	return json.Marshal(span)
 }
+
+// DecodeLocalSpan decodes the serialized span of any version. Spans written by older versions are upgraded
+// to the current version. Spans written by newer versions are decoded in best effort, the unknown fields are ignored.
+func DecodeLocalSpan(data []byte) (*LocalSpan, error) {
+	span := &LocalSpan{}
+	if err := json.Unmarshal(data, span); err != nil {
+		return nil, err
+	}
+	upgradeLocalSpan(span)
+	return span, nil
+}
+
+func upgradeLocalSpan(span *LocalSpan) {
+	if span.SchemaVersion >= LocalSpanSchemaVersion {
+		return
+	}
+	// version 0: rule id was only recorded in the attributes
+	if span.SchemaVersion < 1 && span.RuleID == "" {
+		if rule, ok := span.Attribute["rule"].(string); ok {
+			span.RuleID = rule
+		}
+	}
+	// version 1: the events and the status were not recorded, leave them empty
+	// version 2: the resource and the scope were not recorded, leave them empty
+	// version 3: the links only had the trace id which was decoded case-insensitively, leave the rest empty
+	span.SchemaVersion = LocalSpanSchemaVersion
+}
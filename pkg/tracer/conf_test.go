@@ -0,0 +1,51 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeLocalSpan(t *testing.T) {
+	// span written without schema version
+	legacy := `{"name":"op","traceID":"t0","spanID":"s0","attribute":{"rule":"r1"},"links":[{"TraceID":"t1"}],"startTime":"2024-01-01T00:00:00Z","endTime":"2024-01-01T00:00:01Z","ruleID":""}`
+	span, err := DecodeLocalSpan([]byte(legacy))
+	require.NoError(t, err)
+	require.Equal(t, LocalSpanSchemaVersion, span.SchemaVersion)
+	require.Equal(t, "r1", span.RuleID)
+	// the links of the old versions were serialized without json tags
+	require.Equal(t, []LocalLink{{TraceID: "t1"}}, span.Links)
+	// span written by a newer version with unknown fields
+	newer := `{"schemaVersion":99,"name":"op","traceID":"t0","spanID":"s0","ruleID":"r2","unknown":{"a":1}}`
+	span, err = DecodeLocalSpan([]byte(newer))
+	require.NoError(t, err)
+	require.Equal(t, 99, span.SchemaVersion)
+	require.Equal(t, "r2", span.RuleID)
+	// round trip
+	origin := &LocalSpan{
+		Name: "op", TraceID: "t0", SpanID: "s0", RuleID: "r1", Status: "Error", StatusMessage: "boom",
+		Events: []LocalEvent{{Name: "exception", Timestamp: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), Attributes: map[string]interface{}{"exception.message": "boom"}}},
+	}
+	bs, err := origin.ToBytes()
+	require.NoError(t, err)
+	span, err = DecodeLocalSpan(bs)
+	require.NoError(t, err)
+	require.Equal(t, origin, span)
+	_, err = DecodeLocalSpan([]byte("{"))
+	require.Error(t, err)
+}
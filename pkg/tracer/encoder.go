@@ -0,0 +1,64 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// SpanEncoder writes spans into the writer as a json array one by one,
+// so that a large batch of spans is never materialized in memory.
+type SpanEncoder struct {
+	w     io.Writer
+	enc   *json.Encoder
+	count int
+}
+
+func NewSpanEncoder(w io.Writer) *SpanEncoder {
+	return &SpanEncoder{w: w, enc: json.NewEncoder(w)}
+}
+
+// Encode writes the span at once. The json encoder reuses its buffer for all the spans.
+func (e *SpanEncoder) Encode(span *LocalSpan) error {
+	sep := ","
+	if e.count == 0 {
+		sep = "["
+	}
+	if _, err := io.WriteString(e.w, sep); err != nil {
+		return err
+	}
+	span.SchemaVersion = LocalSpanSchemaVersion
+	if err := e.enc.Encode(span); err != nil {
+		return err
+	}
+	e.count++
+	return nil
+}
+
+// Close ends the json array. It must be called after all spans are encoded.
+func (e *SpanEncoder) Close() error {
+	end := "]"
+	if e.count == 0 {
+		end = "[]"
+	}
+	_, err := io.WriteString(e.w, end)
+	return err
+}
+
+// Count returns the number of encoded spans
+func (e *SpanEncoder) Count() int {
+	return e.count
+}
@@ -0,0 +1,56 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpanEncoder(t *testing.T) {
+	buf := &bytes.Buffer{}
+	enc := NewSpanEncoder(buf)
+	require.NoError(t, enc.Close())
+	require.Equal(t, "[]", buf.String())
+
+	buf.Reset()
+	enc = NewSpanEncoder(buf)
+	require.NoError(t, enc.Encode(&LocalSpan{TraceID: "t0", SpanID: "s0"}))
+	require.NoError(t, enc.Encode(&LocalSpan{TraceID: "t0", SpanID: "s1", ParentSpanID: "s0"}))
+	require.NoError(t, enc.Close())
+	require.Equal(t, 2, enc.Count())
+	var got []*LocalSpan
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+	require.Len(t, got, 2)
+	require.Equal(t, "s1", got[1].SpanID)
+	require.Equal(t, "s0", got[1].ParentSpanID)
+}
+
+type errWriter struct{}
+
+func (errWriter) Write(_ []byte) (int, error) {
+	return 0, errors.New("write err")
+}
+
+func TestSpanEncoderErr(t *testing.T) {
+	enc := NewSpanEncoder(errWriter{})
+	require.Error(t, enc.Encode(&LocalSpan{TraceID: "t0", SpanID: "s0"}))
+	require.Error(t, enc.Close())
+	require.Equal(t, 0, enc.Count())
+}
@@ -0,0 +1,118 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build trace || !core
+
+package tracer
+
+import (
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	"github.com/lf-edge/ekuiper/v2/pkg/syncx"
+)
+
+const (
+	RecordModeAll   = "all"
+	RecordModeError = "error"
+
+	// errorOnlyBufferTTL is how long the spans of a trace without error are buffered before dropped
+	errorOnlyBufferTTL = time.Minute
+)
+
+type pendingTrace struct {
+	spans   []sdktrace.ReadOnlySpan
+	created time.Time
+}
+
+// errorOnlyBuffer buffers the spans per trace(message) and only releases them if any span of the trace has error.
+// Once a trace has error, its later spans are released directly.
+type errorOnlyBuffer struct {
+	syncx.Mutex
+	capacity int
+	ttl      time.Duration
+	pending  map[string]*pendingTrace
+	// pending trace ids in arrival order, may contain the ids which are already released or dropped
+	order []string
+	// trace id -> last seen time of the traces with error
+	errored map[string]time.Time
+}
+
+func newErrorOnlyBuffer(capacity int, ttl time.Duration) *errorOnlyBuffer {
+	return &errorOnlyBuffer{
+		capacity: capacity,
+		ttl:      ttl,
+		pending:  make(map[string]*pendingTrace),
+		errored:  make(map[string]time.Time),
+	}
+}
+
+// Filter returns the spans to be recorded
+func (b *errorOnlyBuffer) Filter(spans []sdktrace.ReadOnlySpan, now time.Time) []sdktrace.ReadOnlySpan {
+	b.Lock()
+	defer b.Unlock()
+	var result []sdktrace.ReadOnlySpan
+	for _, span := range spans {
+		traceID := span.SpanContext().TraceID().String()
+		if _, ok := b.errored[traceID]; ok {
+			b.errored[traceID] = now
+			result = append(result, span)
+			continue
+		}
+		if span.Status().Code == codes.Error {
+			b.errored[traceID] = now
+			if p, ok := b.pending[traceID]; ok {
+				result = append(result, p.spans...)
+				delete(b.pending, traceID)
+			}
+			result = append(result, span)
+			continue
+		}
+		p, ok := b.pending[traceID]
+		if !ok {
+			p = &pendingTrace{created: now}
+			b.pending[traceID] = p
+			b.order = append(b.order, traceID)
+		}
+		p.spans = append(p.spans, span)
+	}
+	b.evict(now)
+	return result
+}
+
+func (b *errorOnlyBuffer) evict(now time.Time) {
+	for len(b.order) > 0 {
+		traceID := b.order[0]
+		p, ok := b.pending[traceID]
+		if ok && len(b.pending) <= b.capacity && now.Sub(p.created) < b.ttl {
+			break
+		}
+		delete(b.pending, traceID)
+		b.order = b.order[1:]
+	}
+	for traceID, lastSeen := range b.errored {
+		if now.Sub(lastSeen) >= b.ttl {
+			delete(b.errored, traceID)
+		}
+	}
+}
+
+// Len returns the number of the buffered traces
+func (b *errorOnlyBuffer) Len() int {
+	b.Lock()
+	defer b.Unlock()
+	return len(b.pending)
+}
@@ -0,0 +1,68 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build trace || !core
+
+package tracer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func mockSpan(traceID byte, spanID byte, isErr bool) sdktrace.ReadOnlySpan {
+	stub := tracetest.SpanStub{
+		SpanContext: trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID: trace.TraceID{traceID},
+			SpanID:  trace.SpanID{spanID},
+		}),
+	}
+	if isErr {
+		stub.Status = sdktrace.Status{Code: codes.Error, Description: "mock error"}
+	}
+	return stub.Snapshot()
+}
+
+func TestErrorOnlyBuffer(t *testing.T) {
+	b := newErrorOnlyBuffer(2, time.Minute)
+	now := time.Now()
+	// trace 1 has no error, buffered
+	got := b.Filter([]sdktrace.ReadOnlySpan{mockSpan(1, 1, false), mockSpan(1, 2, false)}, now)
+	require.Len(t, got, 0)
+	require.Equal(t, 1, b.Len())
+	// trace 2 has error, all its spans are released
+	got = b.Filter([]sdktrace.ReadOnlySpan{mockSpan(2, 1, false)}, now)
+	require.Len(t, got, 0)
+	got = b.Filter([]sdktrace.ReadOnlySpan{mockSpan(2, 2, true)}, now)
+	require.Len(t, got, 2)
+	// the later span of the errored trace is released directly
+	got = b.Filter([]sdktrace.ReadOnlySpan{mockSpan(2, 3, false)}, now)
+	require.Len(t, got, 1)
+	require.Equal(t, 1, b.Len())
+	// exceed capacity, the oldest trace 1 is dropped
+	b.Filter([]sdktrace.ReadOnlySpan{mockSpan(3, 1, false), mockSpan(4, 1, false)}, now)
+	require.Equal(t, 2, b.Len())
+	got = b.Filter([]sdktrace.ReadOnlySpan{mockSpan(1, 3, true)}, now)
+	require.Len(t, got, 1)
+	// expired
+	b.Filter(nil, now.Add(2*time.Minute))
+	require.Equal(t, 0, b.Len())
+	require.Len(t, b.errored, 0)
+}
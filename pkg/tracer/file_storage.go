@@ -0,0 +1,420 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build trace || !core
+
+package tracer
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/pkg/syncx"
+)
+
+// minCompactRecords is the least count of the records in the span file to compact
+const minCompactRecords = 1024
+
+var errSpanFileClosed = errors.New("span file is closed")
+
+// spanRecord is a line of the span file, either a saved span or a deleted trace. The span is saved as json, or as the
+// compressed data if the storage compression is set.
+type spanRecord struct {
+	Span    json.RawMessage `json:"span,omitempty"`
+	Data    []byte          `json:"data,omitempty"`
+	Deleted string          `json:"deleted,omitempty"`
+}
+
+func newSpanRecord(span *LocalSpan) (*spanRecord, error) {
+	bs, err := encodeStoredSpan(span)
+	if err != nil {
+		return nil, err
+	}
+	if codecOf(bs) != CodecNone {
+		return &spanRecord{Data: bs}, nil
+	}
+	return &spanRecord{Span: bs}, nil
+}
+
+// fileSpanStorage keeps the spans in an append-only file so that they survive restarts on the devices without sqlite.
+// The spans are indexed by a memory ring of the same capacity as the memory storage, which serves the queries. The
+// file is replayed into the ring on start, and compacted to the spans in the ring once it has twice as many records,
+// so the disk usage is bounded by the capacity.
+type fileSpanStorage struct {
+	mem *LocalSpanMemoryStorage
+	// lock guards the file and is acquired before the lock of the ring
+	lock      syncx.Mutex
+	path      string
+	file      *os.File
+	size      int64
+	records   int
+	compactAt int
+}
+
+func newFileSpanStorage(path string, capacity int, indexKeys ...string) (*fileSpanStorage, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	s := &fileSpanStorage{
+		mem:  newLocalSpanMemoryStorage(capacity, indexKeys...),
+		path: path,
+	}
+	partial, err := s.replay()
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	s.file = f
+	if partial {
+		// terminate the line cut by a crash so that the next record is not appended to it
+		n, err := f.Write([]byte{'\n'})
+		if err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+		s.size += int64(n)
+	}
+	s.compactAt = max(2*s.records, minCompactRecords)
+	conf.Log.Infof("load %d span records from %s", s.records, path)
+	return s, nil
+}
+
+// replay loads the records of the file into the ring. It returns whether the last line is cut by a crash.
+func (s *fileSpanStorage) replay() (bool, error) {
+	f, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	reader := bufio.NewReader(f)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			s.size += int64(len(line))
+			s.records++
+			if e := s.apply(line); e != nil {
+				conf.Log.Warnf("skip the broken span record %d of %s: %v", s.records, s.path, e)
+			}
+		}
+		if err == io.EOF {
+			return len(line) > 0 && line[len(line)-1] != '\n', nil
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+}
+
+func (s *fileSpanStorage) apply(line []byte) error {
+	r := &spanRecord{}
+	if err := json.Unmarshal(line, r); err != nil {
+		return err
+	}
+	if r.Deleted != "" {
+		s.mem.deleteTrace(r.Deleted)
+		return nil
+	}
+	data := []byte(r.Span)
+	if len(r.Data) > 0 {
+		data = r.Data
+	}
+	span, err := decodeStoredSpan(data)
+	if err != nil {
+		return err
+	}
+	return s.mem.saveSpan(span)
+}
+
+func (s *fileSpanStorage) SaveSpan(span sdktrace.ReadOnlySpan) error {
+	return s.saveSpan(FromReadonlySpan(span))
+}
+
+func (s *fileSpanStorage) saveSpan(span *LocalSpan) error {
+	r, err := newSpanRecord(span)
+	if err != nil {
+		return err
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if err := s.append(r); err != nil {
+		return err
+	}
+	s.mem.Lock()
+	err = s.mem.saveSpan(span)
+	s.mem.Unlock()
+	if err != nil {
+		return err
+	}
+	return s.compactIfNeeded()
+}
+
+func (s *fileSpanStorage) DeleteTrace(traceID string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.mem.RLock()
+	_, ok := s.mem.m[traceID]
+	s.mem.RUnlock()
+	if !ok {
+		return nil
+	}
+	if err := s.append(&spanRecord{Deleted: traceID}); err != nil {
+		return err
+	}
+	s.mem.Lock()
+	s.mem.deleteTrace(traceID)
+	s.mem.Unlock()
+	return s.compactIfNeeded()
+}
+
+// CleanupBefore deletes the traces whose spans all started before the deadline and compacts the file
+func (s *fileSpanStorage) CleanupBefore(deadline time.Time) (*CleanupResult, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.file == nil {
+		return nil, errSpanFileClosed
+	}
+	r := &CleanupResult{}
+	s.mem.Lock()
+	for traceID, spans := range s.mem.m {
+		expired := true
+		for _, span := range spans {
+			if !span.StartTime.Before(deadline) {
+				expired = false
+				break
+			}
+		}
+		if expired {
+			r.DeletedSpans += int64(len(spans))
+			s.mem.deleteTrace(traceID)
+		}
+	}
+	s.mem.Unlock()
+	before := s.size
+	if err := s.compact(); err != nil {
+		return nil, err
+	}
+	r.DeletedBytes = before - s.size
+	r.RemainSpans = int64(s.records)
+	r.RemainBytes = s.size
+	return r, nil
+}
+
+// CleanupOverQuota deletes the oldest traces in the ring until the spans and the size of the compacted file are within
+// the limits
+func (s *fileSpanStorage) CleanupOverQuota(maxSpans, maxBytes int64) (*CleanupResult, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.file == nil {
+		return nil, errSpanFileClosed
+	}
+	// the deleted records are dropped by the compaction so that the size is of the live spans
+	if err := s.compact(); err != nil {
+		return nil, err
+	}
+	r := &CleanupResult{RemainSpans: int64(s.records), RemainBytes: s.size}
+	if !overQuota(r, maxSpans, maxBytes) {
+		return r, nil
+	}
+	spans, size := r.RemainSpans, r.RemainBytes
+	s.mem.Lock()
+	seen := make(map[string]struct{})
+	for _, traceID := range append([]string(nil), s.mem.queue.items...) {
+		if !overQuota(r, maxSpans, maxBytes) {
+			break
+		}
+		if _, ok := seen[traceID]; ok {
+			continue
+		}
+		seen[traceID] = struct{}{}
+		for _, span := range s.mem.m[traceID] {
+			r.RemainSpans--
+			r.RemainBytes -= recordSize(span)
+		}
+		s.mem.deleteTrace(traceID)
+	}
+	s.mem.Unlock()
+	if err := s.compact(); err != nil {
+		return nil, err
+	}
+	r.DeletedSpans = spans - int64(s.records)
+	r.DeletedBytes = size - s.size
+	r.RemainSpans = int64(s.records)
+	r.RemainBytes = s.size
+	return r, nil
+}
+
+// recordSize is the estimated size of the span in the file
+func recordSize(span *LocalSpan) int64 {
+	r, err := newSpanRecord(span)
+	if err != nil {
+		return 0
+	}
+	bs, err := json.Marshal(r)
+	if err != nil {
+		return 0
+	}
+	return int64(len(bs)) + 1
+}
+
+// Usage returns the spans in the ring and the size of the file including the records to be compacted
+func (s *fileSpanStorage) Usage() (int64, int64, error) {
+	s.lock.Lock()
+	size := s.size
+	s.lock.Unlock()
+	s.mem.RLock()
+	defer s.mem.RUnlock()
+	return int64(s.mem.queue.Len()), size, nil
+}
+
+func (s *fileSpanStorage) GetTraceById(traceID string) (*LocalSpan, error) {
+	return s.mem.GetTraceById(traceID)
+}
+
+func (s *fileSpanStorage) GetTraceByRuleID(ruleID string, limit int64) ([]string, error) {
+	return s.mem.GetTraceByRuleID(ruleID, limit)
+}
+
+func (s *fileSpanStorage) GetTraceByAttribute(key, value string, start, end time.Time, limit int64) ([]string, error) {
+	return s.mem.GetTraceByAttribute(key, value, start, end, limit)
+}
+
+func (s *fileSpanStorage) RangeSpans(start, end time.Time, fn func(span *LocalSpan) error) error {
+	return s.mem.RangeSpans(start, end, fn)
+}
+
+func (s *fileSpanStorage) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+// append writes the record to the end of the file. It must be called with the file lock.
+func (s *fileSpanStorage) append(r *spanRecord) error {
+	if s.file == nil {
+		return errSpanFileClosed
+	}
+	bs, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	n, err := s.file.Write(append(bs, '\n'))
+	s.size += int64(n)
+	if err != nil {
+		return err
+	}
+	s.records++
+	return nil
+}
+
+func (s *fileSpanStorage) compactIfNeeded() error {
+	if s.records < s.compactAt {
+		return nil
+	}
+	return s.compact()
+}
+
+// compact rewrites the file with the spans in the ring by the order they are saved. The new file is written aside and
+// renamed so that a crash leaves either the old or the new file. It must be called with the file lock.
+func (s *fileSpanStorage) compact() error {
+	s.mem.RLock()
+	var spans []*LocalSpan
+	seen := make(map[string]struct{}, len(s.mem.m))
+	for _, traceID := range s.mem.queue.items {
+		if _, ok := seen[traceID]; ok {
+			continue
+		}
+		seen[traceID] = struct{}{}
+		trace := make([]*LocalSpan, 0, len(s.mem.m[traceID]))
+		for _, span := range s.mem.m[traceID] {
+			// the spans are saved flat without the children
+			cp := *span
+			cp.ChildSpan = nil
+			trace = append(trace, &cp)
+		}
+		sort.Slice(trace, func(i, j int) bool {
+			return trace[i].StartTime.Before(trace[j].StartTime)
+		})
+		spans = append(spans, trace...)
+	}
+	s.mem.RUnlock()
+
+	tmp := s.path + ".tmp"
+	size, err := writeSpanFile(tmp, spans)
+	if err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	_ = s.file.Close()
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		s.file = nil
+		return err
+	}
+	s.file, s.size, s.records = f, size, len(spans)
+	s.compactAt = max(2*s.records, minCompactRecords)
+	return nil
+}
+
+func writeSpanFile(path string, spans []*LocalSpan) (int64, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	var size int64
+	for _, span := range spans {
+		r, err := newSpanRecord(span)
+		if err != nil {
+			return 0, err
+		}
+		line, err := json.Marshal(r)
+		if err != nil {
+			return 0, err
+		}
+		n, err := w.Write(append(line, '\n'))
+		if err != nil {
+			return 0, err
+		}
+		size += int64(n)
+	}
+	if err := w.Flush(); err != nil {
+		return 0, err
+	}
+	return size, f.Sync()
+}
@@ -0,0 +1,164 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build trace || !core
+
+package tracer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+)
+
+func TestFileSpanStorage(t *testing.T) {
+	conf.InitConf()
+	path := filepath.Join(t.TempDir(), "trace", "spans.log")
+	s, err := newFileSpanStorage(path, 10, "deviceId")
+	require.NoError(t, err)
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, s.saveSpan(&LocalSpan{TraceID: "t0", SpanID: "s0", RuleID: "r1", StartTime: start, Attribute: map[string]any{"deviceId": "d1"}}))
+	require.NoError(t, s.saveSpan(&LocalSpan{TraceID: "t0", SpanID: "s1", ParentSpanID: "s0", RuleID: "r1", StartTime: start.Add(time.Second)}))
+	require.NoError(t, s.saveSpan(&LocalSpan{TraceID: "t1", SpanID: "s2", RuleID: "r1", StartTime: start.Add(time.Minute)}))
+	require.NoError(t, s.DeleteTrace("t1"))
+	require.NoError(t, s.DeleteTrace("notExist"))
+	require.NoError(t, s.Close())
+	require.ErrorIs(t, s.saveSpan(&LocalSpan{TraceID: "t2", SpanID: "s3"}), errSpanFileClosed)
+
+	// the spans survive the restart
+	s, err = newFileSpanStorage(path, 10, "deviceId")
+	require.NoError(t, err)
+	defer s.Close()
+	require.Equal(t, 4, s.records)
+	root, err := s.GetTraceById("t0")
+	require.NoError(t, err)
+	require.Equal(t, "s0", root.SpanID)
+	require.Len(t, root.ChildSpan, 1)
+	root, err = s.GetTraceById("t1")
+	require.NoError(t, err)
+	require.Nil(t, root)
+	ids, err := s.GetTraceByRuleID("r1", 0)
+	require.NoError(t, err)
+	require.Equal(t, []string{"t0"}, ids)
+	ids, err = s.GetTraceByAttribute("deviceId", "d1", time.Time{}, time.Time{}, 0)
+	require.NoError(t, err)
+	require.Equal(t, []string{"t0"}, ids)
+
+	// the expired traces are removed and the file is compacted
+	r, err := s.CleanupBefore(start.Add(time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, int64(2), r.DeletedSpans)
+	require.Equal(t, int64(0), r.RemainSpans)
+	require.Equal(t, int64(0), r.RemainBytes)
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, int64(0), info.Size())
+}
+
+func TestFileSpanStorageQuota(t *testing.T) {
+	conf.InitConf()
+	path := filepath.Join(t.TempDir(), "spans.log")
+	s, err := newFileSpanStorage(path, 10)
+	require.NoError(t, err)
+	defer s.Close()
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		traceID := fmt.Sprintf("t%d", i)
+		require.NoError(t, s.saveSpan(&LocalSpan{TraceID: traceID, SpanID: "s0", StartTime: start}))
+		require.NoError(t, s.saveSpan(&LocalSpan{TraceID: traceID, SpanID: "s1", ParentSpanID: "s0", StartTime: start}))
+	}
+	require.NoError(t, s.DeleteTrace("notExist"))
+	r, err := s.CleanupOverQuota(6, 0)
+	require.NoError(t, err)
+	require.Equal(t, int64(0), r.DeletedSpans)
+	require.Equal(t, int64(6), r.RemainSpans)
+	// the oldest traces are deleted first
+	r, err = s.CleanupOverQuota(3, 0)
+	require.NoError(t, err)
+	require.Equal(t, int64(4), r.DeletedSpans)
+	require.Equal(t, int64(2), r.RemainSpans)
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, r.RemainBytes, info.Size())
+	require.True(t, r.DeletedBytes > 0)
+	root, err := s.GetTraceById("t2")
+	require.NoError(t, err)
+	require.NotNil(t, root)
+	root, err = s.GetTraceById("t1")
+	require.NoError(t, err)
+	require.Nil(t, root)
+	// by bytes
+	r, err = s.CleanupOverQuota(0, r.RemainBytes-1)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), r.DeletedSpans)
+	require.Equal(t, int64(0), r.RemainBytes)
+}
+
+func TestFileSpanStorageCompact(t *testing.T) {
+	conf.InitConf()
+	path := filepath.Join(t.TempDir(), "spans.log")
+	s, err := newFileSpanStorage(path, 10)
+	require.NoError(t, err)
+	defer s.Close()
+	for i := 0; i < 3*minCompactRecords; i++ {
+		require.NoError(t, s.saveSpan(&LocalSpan{TraceID: fmt.Sprintf("t%d", i), SpanID: "s0"}))
+	}
+	// bounded by the capacity of the ring
+	require.Less(t, s.records, minCompactRecords)
+	count := 0
+	require.NoError(t, s.RangeSpans(time.Time{}, time.Time{}, func(_ *LocalSpan) error {
+		count++
+		return nil
+	}))
+	require.Equal(t, 10, count)
+	root, err := s.GetTraceById(fmt.Sprintf("t%d", 3*minCompactRecords-1))
+	require.NoError(t, err)
+	require.NotNil(t, root)
+	_, err = os.Stat(path + ".tmp")
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestFileSpanStorageBrokenTail(t *testing.T) {
+	conf.InitConf()
+	path := filepath.Join(t.TempDir(), "spans.log")
+	s, err := newFileSpanStorage(path, 10)
+	require.NoError(t, err)
+	require.NoError(t, s.saveSpan(&LocalSpan{TraceID: "t0", SpanID: "s0"}))
+	require.NoError(t, s.Close())
+	// a crash cuts the last record
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"span":{"traceID":"t1"`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	s, err = newFileSpanStorage(path, 10)
+	require.NoError(t, err)
+	require.NoError(t, s.saveSpan(&LocalSpan{TraceID: "t2", SpanID: "s2"}))
+	require.NoError(t, s.Close())
+	s, err = newFileSpanStorage(path, 10)
+	require.NoError(t, err)
+	defer s.Close()
+	for _, id := range []string{"t0", "t2"} {
+		root, err := s.GetTraceById(id)
+		require.NoError(t, err)
+		require.NotNil(t, root)
+	}
+}
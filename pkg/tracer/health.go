@@ -0,0 +1,63 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+// ExporterHealth is the snapshot of the span export pipeline used by the health check
+type ExporterHealth struct {
+	// Enabled is false when the tracer is not built in or not set up yet
+	Enabled         bool `json:"enabled"`
+	RemoteCollector bool `json:"remoteCollector"`
+	// QueueDepth is the count of the ended spans which are not handed to the exporter yet
+	QueueDepth int64 `json:"queueDepth"`
+	// RemoteQueueDepth is the count of the spans waiting to be exported to the remote collector
+	RemoteQueueDepth int64 `json:"remoteQueueDepth"`
+	// RemoteLastError is the error of the last batch to the remote collector if it is dropped
+	RemoteLastError string `json:"remoteLastError,omitempty"`
+	// LastExport and LastSuccess are unix milliseconds, 0 means never
+	LastExport  int64  `json:"lastExport"`
+	LastSuccess int64  `json:"lastSuccess"`
+	LastError   string `json:"lastError,omitempty"`
+	// LastCleanup is what the latest retention cleanup purged from the local storage
+	LastCleanup *CleanupResult `json:"lastCleanup,omitempty"`
+}
+
+// Healthy reports whether the latest export has succeeded
+func (h *ExporterHealth) Healthy() bool {
+	return !h.Enabled || h.LastExport == 0 || h.LastSuccess >= h.LastExport
+}
+
+// TracerStatistics is the statistics of the tracing subsystem itself. The counters are since the process starts.
+type TracerStatistics struct {
+	// Enabled is false when the tracer is not built in, not set up yet or turned off by the global switch
+	Enabled bool `json:"enabled"`
+	// Received is the count of the ended spans which are sampled
+	Received int64 `json:"received"`
+	// Exported is the count of the spans saved to the local storage
+	Exported int64 `json:"exported"`
+	// RemoteExported is the count of the spans exported to the remote collector
+	RemoteExported int64 `json:"remoteExported"`
+	// Dropped is the count of the spans dropped by the full queues, the storage errors or the failed remote export
+	Dropped int64 `json:"dropped"`
+	// QueueDepth and RemoteQueueDepth are the spans waiting in the span pipeline and the remote queue
+	QueueDepth       int64 `json:"queueDepth"`
+	RemoteQueueDepth int64 `json:"remoteQueueDepth"`
+	// StoredSpans and StorageBytes are the usage of the sqlite or the file storage
+	StoredSpans  int64 `json:"storedSpans"`
+	StorageBytes int64 `json:"storageBytes"`
+	// LastExportError is the error of the latest failed export to the local storage or the remote collector, and
+	// LastExportErrorTime is its unix milliseconds
+	LastExportError     string `json:"lastExportError,omitempty"`
+	LastExportErrorTime int64  `json:"lastExportErrorTime,omitempty"`
+}
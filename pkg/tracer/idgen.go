@@ -0,0 +1,105 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"context"
+	crand "crypto/rand"
+	"encoding/binary"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// IDGenerator generates the ids of the new traces and spans. It has the same methods as the IDGenerator of the
+// OpenTelemetry SDK.
+type IDGenerator interface {
+	// NewIDs returns the ids of a new trace and its root span
+	NewIDs(ctx context.Context) (trace.TraceID, trace.SpanID)
+	// NewSpanID returns the id of a new span in the trace
+	NewSpanID(ctx context.Context, traceID trace.TraceID) trace.SpanID
+}
+
+var idGenerator atomic.Pointer[IDGenerator]
+
+// SetIDGenerator replaces the generator of the trace and span ids, for example by a deterministic one to get the
+// reproducible ids in the tests and the simulations. It applies to the spans started later without resetting the
+// tracer. Nil restores the random generator.
+func SetIDGenerator(g IDGenerator) {
+	if g == nil {
+		idGenerator.Store(nil)
+		return
+	}
+	idGenerator.Store(&g)
+}
+
+// globalIDGenerator is installed to the tracer providers and delegates to the current generator
+var globalIDGenerator IDGenerator = delegateIDGenerator{}
+
+type delegateIDGenerator struct{}
+
+func (delegateIDGenerator) NewIDs(ctx context.Context) (trace.TraceID, trace.SpanID) {
+	if g := idGenerator.Load(); g != nil {
+		return (*g).NewIDs(ctx)
+	}
+	return randomIDs.NewIDs(ctx)
+}
+
+func (delegateIDGenerator) NewSpanID(ctx context.Context, traceID trace.TraceID) trace.SpanID {
+	if g := idGenerator.Load(); g != nil {
+		return (*g).NewSpanID(ctx, traceID)
+	}
+	return randomIDs.NewSpanID(ctx, traceID)
+}
+
+var randomIDs = newRandomIDGenerator()
+
+// randomIDGenerator is the default random generator like the one of the SDK
+type randomIDGenerator struct {
+	sync.Mutex
+	rand *rand.Rand
+}
+
+func newRandomIDGenerator() *randomIDGenerator {
+	var seed int64
+	_ = binary.Read(crand.Reader, binary.LittleEndian, &seed)
+	return &randomIDGenerator{rand: rand.New(rand.NewSource(seed))}
+}
+
+func (g *randomIDGenerator) NewIDs(_ context.Context) (trace.TraceID, trace.SpanID) {
+	g.Lock()
+	defer g.Unlock()
+	tid := trace.TraceID{}
+	for !tid.IsValid() {
+		_, _ = g.rand.Read(tid[:])
+	}
+	sid := trace.SpanID{}
+	for !sid.IsValid() {
+		_, _ = g.rand.Read(sid[:])
+	}
+	return tid, sid
+}
+
+func (g *randomIDGenerator) NewSpanID(_ context.Context, _ trace.TraceID) trace.SpanID {
+	g.Lock()
+	defer g.Unlock()
+	sid := trace.SpanID{}
+	for !sid.IsValid() {
+		_, _ = g.rand.Read(sid[:])
+	}
+	return sid
+}
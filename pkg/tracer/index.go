@@ -0,0 +1,85 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build trace || !core
+
+package tracer
+
+import (
+	"sort"
+	"time"
+)
+
+// ruleIndex keeps the traces of each rule with the latest start time of their spans, so that the recent traces of a
+// rule are found without scanning the spans. It is guarded by the lock of the storage.
+type ruleIndex struct {
+	seq   uint64
+	rules map[string]map[string]ruleIndexEntry
+}
+
+type ruleIndexEntry struct {
+	latest time.Time
+	// seq orders the traces of the same start time by the last save
+	seq uint64
+}
+
+func newRuleIndex() *ruleIndex {
+	return &ruleIndex{rules: make(map[string]map[string]ruleIndexEntry)}
+}
+
+func (r *ruleIndex) add(ruleID, traceID string, start time.Time) {
+	traces, ok := r.rules[ruleID]
+	if !ok {
+		traces = make(map[string]ruleIndexEntry)
+		r.rules[ruleID] = traces
+	}
+	r.seq++
+	e := traces[traceID]
+	if start.After(e.latest) {
+		e.latest = start
+	}
+	e.seq = r.seq
+	traces[traceID] = e
+}
+
+func (r *ruleIndex) remove(ruleID, traceID string) {
+	traces, ok := r.rules[ruleID]
+	if !ok {
+		return
+	}
+	delete(traces, traceID)
+	if len(traces) == 0 {
+		delete(r.rules, ruleID)
+	}
+}
+
+// latest returns the trace ids of the rule, the latest first. Limit less than 1 means no limit.
+func (r *ruleIndex) latest(ruleID string, limit int) []string {
+	traces := r.rules[ruleID]
+	ids := make([]string, 0, len(traces))
+	for id := range traces {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		a, b := traces[ids[i]], traces[ids[j]]
+		if a.latest.Equal(b.latest) {
+			return a.seq > b.seq
+		}
+		return a.latest.After(b.latest)
+	})
+	if limit > 0 && limit < len(ids) {
+		ids = ids[:limit]
+	}
+	return ids
+}
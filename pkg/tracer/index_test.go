@@ -0,0 +1,92 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build trace || !core
+
+package tracer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+)
+
+func TestRuleIndex(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	r := newRuleIndex()
+	r.add("r1", "t0", start.Add(time.Second))
+	r.add("r1", "t1", start)
+	r.add("r1", "t2", start)
+	// the later span of t1 makes it the latest
+	r.add("r1", "t1", start.Add(2*time.Second))
+	// the earlier span does not change the order
+	r.add("r1", "t0", start)
+	r.add("r2", "t3", start)
+	require.Equal(t, []string{"t1", "t0", "t2"}, r.latest("r1", 0))
+	require.Equal(t, []string{"t1", "t0"}, r.latest("r1", 2))
+	require.Equal(t, []string{"t3"}, r.latest("r2", 10))
+	r.remove("r2", "t3")
+	r.remove("r3", "t3")
+	require.Empty(t, r.latest("r2", 0))
+	require.NotContains(t, r.rules, "r2")
+}
+
+func TestMemoryStorageIndex(t *testing.T) {
+	conf.InitConf()
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := newLocalSpanMemoryStorage(2)
+	require.NoError(t, s.saveSpan(&LocalSpan{TraceID: "t0", SpanID: "s0", RuleID: "r1", StartTime: start}))
+	require.NoError(t, s.saveSpan(&LocalSpan{TraceID: "t0", SpanID: "s1", ParentSpanID: "s0", RuleID: "r1", StartTime: start.Add(time.Millisecond)}))
+	require.NoError(t, s.saveSpan(&LocalSpan{TraceID: "t1", SpanID: "s2", RuleID: "r1", StartTime: start.Add(time.Second)}))
+	// the ring counts the traces rather than the spans
+	require.Equal(t, 2, s.queue.Len())
+	root, err := s.GetTraceById("t0")
+	require.NoError(t, err)
+	require.Len(t, root.ChildSpan, 1)
+	ids, err := s.GetTraceByRuleID("r1", 0)
+	require.NoError(t, err)
+	require.Equal(t, []string{"t1", "t0"}, ids)
+
+	// the evicted trace is removed from the index
+	require.NoError(t, s.saveSpan(&LocalSpan{TraceID: "t2", SpanID: "s3", RuleID: "r1", StartTime: start.Add(2 * time.Second)}))
+	ids, err = s.GetTraceByRuleID("r1", 0)
+	require.NoError(t, err)
+	require.Equal(t, []string{"t2", "t1"}, ids)
+	ids, err = s.GetTraceByRuleID("r1", 1)
+	require.NoError(t, err)
+	require.Equal(t, []string{"t2"}, ids)
+}
+
+func TestSqlStorageRuleIndex(t *testing.T) {
+	setupTraceDB(t)
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := newSqlspanStorage()
+	require.NoError(t, s.saveLocalSpan(&LocalSpan{TraceID: "t0", SpanID: "s0", RuleID: "r1", StartTime: start.Add(time.Second)}))
+	require.NoError(t, s.saveLocalSpan(&LocalSpan{TraceID: "t1", SpanID: "s1", RuleID: "r1", StartTime: start}))
+	require.NoError(t, s.saveLocalSpan(&LocalSpan{TraceID: "t2", SpanID: "s2", RuleID: "r2", StartTime: start}))
+	ids, err := s.GetTraceByRuleID("r1", 0)
+	require.NoError(t, err)
+	require.Equal(t, []string{"t0", "t1"}, ids)
+	ids, err = s.GetTraceByRuleID("r1", 1)
+	require.NoError(t, err)
+	require.Equal(t, []string{"t0"}, ids)
+
+	require.NoError(t, s.DeleteTrace("t0"))
+	ids, err = s.GetTraceByRuleID("r1", 0)
+	require.NoError(t, err)
+	require.Equal(t, []string{"t1"}, ids)
+}
@@ -19,76 +19,257 @@ package tracer
 import (
 	"context"
 	"database/sql"
-	"encoding/json"
 	"errors"
+	"io"
+	"path/filepath"
+	"sync/atomic"
 	"time"
 
 	"github.com/pingcap/failpoint"
-	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
-	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 
 	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/discovery"
 	"github.com/lf-edge/ekuiper/v2/internal/pkg/store"
+	"github.com/lf-edge/ekuiper/v2/pkg/model"
 	"github.com/lf-edge/ekuiper/v2/pkg/syncx"
 )
 
 type SpanExporter struct {
-	remoteSpanExport *otlptrace.Exporter
+	remoteSpanExport sdktrace.SpanExporter
+	remoteQueue      *remoteQueue
 	spanStorage      LocalSpanStorage
+	cleanup          *cleanupJob
+	// only set in error record mode
+	errorOnly *errorOnlyBuffer
+	// pipeline queues the ended spans to be exported
+	pipeline *spanPipeline
+	// export pipeline stats for the health check
+	lastExport  atomic.Int64
+	lastSuccess atomic.Int64
+	lastError   atomic.Value
+	// endpoints resolves the remote endpoint specified as a DNS SRV name or an address list. The remote exporter is
+	// switched to another address if the one in use fails or is not discovered anymore.
+	endpoints   *discovery.Resolver
+	remoteAddr  string
+	remoteLock  syncx.RWMutex
+	rediscovery context.CancelFunc
+	switchCh    chan struct{}
 }
 
 func NewSpanExporter(remoteCollector bool, remoteEndpoint string) (*SpanExporter, error) {
 	s := &SpanExporter{}
 	if remoteCollector {
-		exporter, err := otlptracehttp.New(context.Background(),
-			otlptracehttp.WithEndpoint(remoteEndpoint),
-			otlptracehttp.WithInsecure(),
-		)
+		addr := remoteEndpoint
+		if discovery.IsDiscoverable(remoteEndpoint) {
+			r, err := discovery.NewResolver(remoteEndpoint, time.Duration(conf.Config.Discovery.FailureCooldown))
+			if err != nil {
+				return nil, err
+			}
+			s.endpoints = r
+			addr = r.Select()
+		}
+		exporter, err := newRemoteExporter(addr)
 		if err != nil {
 			return nil, err
 		}
-		s.remoteSpanExport = exporter
+		s.remoteSpanExport, s.remoteAddr = exporter, addr
+		if s.endpoints != nil {
+			s.startRediscovery(time.Duration(conf.Config.Discovery.RefreshInterval))
+		}
+	}
+	storage, err := newLocalSpanStorage(&conf.Config.OpenTelemetry)
+	if err != nil {
+		s.stopRediscovery()
+		return nil, err
+	}
+	s.spanStorage = storage
+	if s.remoteSpanExport != nil {
+		s.remoteQueue = newRemoteQueue(s, conf.Config.OpenTelemetry.RemoteExport)
+		s.remoteQueue.start()
+	}
+	if conf.Config.OpenTelemetry.RecordMode == RecordModeError {
+		s.errorOnly = newErrorOnlyBuffer(conf.Config.OpenTelemetry.LocalTraceCapacity, errorOnlyBufferTTL)
 	}
-	if !conf.Config.OpenTelemetry.EnableLocalStorage {
-		s.spanStorage = newLocalSpanMemoryStorage(conf.Config.OpenTelemetry.LocalTraceCapacity)
-	} else {
-		s.spanStorage = newSqlspanStorage()
+	if cleaner, ok := s.spanStorage.(retentionCleaner); ok {
+		s.cleanup = newCleanupJob(cleaner, time.Duration(conf.Config.OpenTelemetry.LocalTraceRetention), time.Duration(conf.Config.OpenTelemetry.LocalTraceCleanupInterval)).
+			withQuota(conf.Config.OpenTelemetry.LocalTraceMaxSpans, conf.Config.OpenTelemetry.LocalTraceMaxBytes)
+		s.cleanup.start()
 	}
 	return s, nil
 }
 
+func (l *SpanExporter) remote() (sdktrace.SpanExporter, string) {
+	l.remoteLock.RLock()
+	defer l.remoteLock.RUnlock()
+	return l.remoteSpanExport, l.remoteAddr
+}
+
+func (l *SpanExporter) startRediscovery(refresh time.Duration) {
+	ctx, cancel := context.WithCancel(context.Background())
+	l.rediscovery = cancel
+	l.switchCh = make(chan struct{}, 1)
+	go l.rediscover(ctx, refresh)
+}
+
+func (l *SpanExporter) stopRediscovery() {
+	if l.rediscovery != nil {
+		l.rediscovery()
+	}
+}
+
+// rediscover re-resolves the remote endpoint periodically or switches the address once the export fails
+func (l *SpanExporter) rediscover(ctx context.Context, refresh time.Duration) {
+	ticker := getClock().Ticker(refresh)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := l.endpoints.Refresh(); err != nil {
+				conf.Log.Warnf("rediscover remote trace collector error: %v", err)
+			}
+			l.switchRemote()
+		case <-l.switchCh:
+			l.switchRemote()
+		}
+	}
+}
+
+func (l *SpanExporter) switchRemote() {
+	addr := l.endpoints.Select()
+	_, cur := l.remote()
+	if addr == "" || addr == cur {
+		return
+	}
+	exporter, err := newRemoteExporter(addr)
+	if err != nil {
+		conf.Log.Warnf("switch remote trace collector to %s error: %v", addr, err)
+		return
+	}
+	l.remoteLock.Lock()
+	old := l.remoteSpanExport
+	l.remoteSpanExport, l.remoteAddr = exporter, addr
+	l.remoteLock.Unlock()
+	conf.Log.Infof("switch remote trace collector from %s to %s", cur, addr)
+	if old != nil {
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_ = old.Shutdown(ctx)
+		}()
+	}
+}
+
+// onRemoteFailure puts the failed address into cooldown and notifies to switch to another one
+func (l *SpanExporter) onRemoteFailure(addr string) {
+	if l.endpoints == nil {
+		return
+	}
+	l.endpoints.MarkFailed(addr)
+	select {
+	case l.switchCh <- struct{}{}:
+	default:
+	}
+}
+
 func (l *SpanExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
 	if l == nil {
 		return nil
 	}
-	if l.remoteSpanExport != nil {
-		err := l.remoteSpanExport.ExportSpans(ctx, spans)
-		if err != nil {
-			conf.Log.Warnf("export remote span err: %v", err)
+	if l.errorOnly != nil {
+		spans = l.errorOnly.Filter(spans, getClock().Now())
+		if len(spans) == 0 {
+			return nil
 		}
 	}
+	// the remote collector is exported in the background so that it does not delay the local storage
+	if l.remoteQueue != nil {
+		l.remoteQueue.offer(globalRedactor.redactSpans(spans))
+	}
+	var lastErr error
+	start := getClock().Now()
+	saved := 0
 	for _, span := range spans {
 		if err := l.spanStorage.SaveSpan(span); err != nil {
-			conf.Log.Errorf("save span err:%v", err)
+			sc := span.SpanContext()
+			conf.LogWithFields(conf.Log, conf.LogFieldTraceID, sc.TraceID().String(), conf.LogFieldSpanID, sc.SpanID().String()).Errorf("save span err:%v", err)
+			lastErr = err
+			TraceExportCounter.WithLabelValues(LblExportErrors).Inc()
+			globalStats.drop(1)
+			continue
 		}
+		saved++
 	}
+	TraceExportDurationHist.WithLabelValues(LblLocal).Observe(float64(getClock().Since(start).Microseconds()))
+	TraceExportCounter.WithLabelValues(LblExportedSpans).Add(float64(saved))
+	globalStats.exported.Add(int64(saved))
+	l.recordExport(getClock().Now(), lastErr)
 	return nil
 }
 
+func (l *SpanExporter) recordExport(now time.Time, err error) {
+	l.lastExport.Store(now.UnixMilli())
+	if err != nil {
+		l.lastError.Store(err.Error())
+		globalStats.exportError(now, err)
+		return
+	}
+	l.lastSuccess.Store(now.UnixMilli())
+}
+
+// Health returns the snapshot of the export pipeline
+func (l *SpanExporter) Health() *ExporterHealth {
+	remote, _ := l.remote()
+	h := &ExporterHealth{
+		Enabled:          true,
+		RemoteCollector:  remote != nil,
+		QueueDepth:       l.pipeline.depth(),
+		RemoteQueueDepth: l.remoteQueue.depth(),
+		LastExport:       l.lastExport.Load(),
+		LastSuccess:      l.lastSuccess.Load(),
+		LastCleanup:      l.cleanup.lastResult(),
+	}
+	if !h.Healthy() {
+		h.LastError, _ = l.lastError.Load().(string)
+	}
+	if l.remoteQueue != nil {
+		h.RemoteLastError, _ = l.remoteQueue.lastError.Load().(string)
+	}
+	return h
+}
+
 func (l *SpanExporter) Shutdown(ctx context.Context) error {
 	if l == nil {
 		return nil
 	}
-	if l.remoteSpanExport != nil {
-		err := l.remoteSpanExport.Shutdown(ctx)
+	if l.cleanup != nil {
+		l.cleanup.stop()
+	}
+	if l.remoteQueue != nil {
+		l.remoteQueue.stop(ctx)
+	}
+	l.stopRediscovery()
+	if remote, _ := l.remote(); remote != nil {
+		err := remote.Shutdown(ctx)
 		if err != nil {
 			conf.Log.Warnf("shutdown remote span exporter err: %v", err)
 		}
 	}
+	l.closeStorage()
 	return nil
 }
 
+// closeStorage releases the persistent span storage such as the span file
+func (l *SpanExporter) closeStorage() {
+	if c, ok := l.spanStorage.(io.Closer); ok {
+		if err := c.Close(); err != nil {
+			conf.Log.Warnf("close local span storage err: %v", err)
+		}
+	}
+}
+
 func (l *SpanExporter) GetTraceById(traceID string) (*LocalSpan, error) {
 	return l.spanStorage.GetTraceById(traceID)
 }
@@ -97,10 +278,139 @@ func (l *SpanExporter) GetTraceByRuleID(ruleID string, limit int64) ([]string, e
 	return l.spanStorage.GetTraceByRuleID(ruleID, limit)
 }
 
+func (l *SpanExporter) GetTraceByAttribute(key, value string, start, end time.Time, limit int64) ([]string, error) {
+	return l.spanStorage.GetTraceByAttribute(key, value, start, end, limit)
+}
+
+func (l *SpanExporter) RangeSpans(start, end time.Time, fn func(span *LocalSpan) error) error {
+	return l.spanStorage.RangeSpans(start, end, fn)
+}
+
+func (l *SpanExporter) SearchTraces(ruleID string, start, end time.Time, attrFilters map[string]string, limit, offset int) (*TraceSearchResult, error) {
+	return searchTraces(l.spanStorage, ruleID, start, end, attrFilters, limit, offset)
+}
+
+func (l *SpanExporter) TraceLinks(traceID string) (*TraceLinks, error) {
+	return traceLinks(l.spanStorage, traceID)
+}
+
+// PurgeRule deletes all the traces of the rule from the local storage. The bytes are the size of the serialized spans.
+func (l *SpanExporter) PurgeRule(ruleID string) (*CleanupResult, error) {
+	ids, err := l.spanStorage.GetTraceByRuleID(ruleID, 0)
+	if err != nil {
+		return nil, err
+	}
+	r := &CleanupResult{Time: getClock().Now().UnixMilli()}
+	seen := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		root, err := l.spanStorage.GetTraceById(id)
+		if err != nil {
+			return r, err
+		}
+		if root != nil {
+			for _, span := range root.Flatten() {
+				r.DeletedSpans++
+				if bs, err := span.ToBytes(); err == nil {
+					r.DeletedBytes += int64(len(bs))
+				}
+			}
+		}
+		if err := l.spanStorage.DeleteTrace(id); err != nil {
+			return r, err
+		}
+	}
+	TraceStoreCounter.WithLabelValues(LblDeletedSpans).Add(float64(r.DeletedSpans))
+	TraceStoreCounter.WithLabelValues(LblDeletedBytes).Add(float64(r.DeletedBytes))
+	return r, nil
+}
+
+func (l *SpanExporter) DeleteTrace(traceID string) error {
+	return l.spanStorage.DeleteTrace(traceID)
+}
+
 type LocalSpanStorage interface {
 	SaveSpan(span sdktrace.ReadOnlySpan) error
 	GetTraceById(traceID string) (*LocalSpan, error)
 	GetTraceByRuleID(ruleID string, limit int64) ([]string, error)
+	// RangeSpans calls fn for each span started in [start, end]. Zero time means no bound.
+	RangeSpans(start, end time.Time, fn func(span *LocalSpan) error) error
+	// GetTraceByAttribute returns the latest trace ids whose span started in [start, end] has the attribute value.
+	// Only the indexed attribute keys are supported.
+	GetTraceByAttribute(key, value string, start, end time.Time, limit int64) ([]string, error)
+	// DeleteTrace deletes all the spans of the trace. It is not an error if the trace does not exist.
+	DeleteTrace(traceID string) error
+}
+
+// The backends of the local spans
+const (
+	LocalStorageMemory = "memory"
+	LocalStorageSqlite = "sqlite"
+	LocalStorageFile   = "file"
+)
+
+// localStorageOf returns the configured backend of the local spans. The legacy enableLocalStorage selects sqlite.
+func localStorageOf(c *model.OpenTelemetry) string {
+	if c.LocalStorage != "" {
+		return c.LocalStorage
+	}
+	if c.EnableLocalStorage {
+		return LocalStorageSqlite
+	}
+	return LocalStorageMemory
+}
+
+func newLocalSpanStorage(c *model.OpenTelemetry) (LocalSpanStorage, error) {
+	switch localStorageOf(c) {
+	case LocalStorageSqlite:
+		return newSqlspanStorage(c.IndexedAttributes...), nil
+	case LocalStorageFile:
+		path := c.LocalTraceFile
+		if path == "" {
+			dataDir, err := conf.GetDataLoc()
+			if err != nil {
+				return nil, err
+			}
+			path = filepath.Join(dataDir, "trace", "spans.log")
+		}
+		return newFileSpanStorage(path, c.LocalTraceCapacity, c.IndexedAttributes...)
+	default:
+		return newLocalSpanMemoryStorage(c.LocalTraceCapacity, c.IndexedAttributes...), nil
+	}
+}
+
+// indexedValues returns the string values of the indexed attributes of the span
+func indexedValues(span *LocalSpan, keys []string) map[string]string {
+	var r map[string]string
+	for _, k := range keys {
+		v, ok := span.Attribute[k]
+		if !ok {
+			continue
+		}
+		if r == nil {
+			r = make(map[string]string, len(keys))
+		}
+		r[k] = FormatAttribute(v)
+	}
+	return r
+}
+
+type attrIndexEntry struct {
+	traceID   string
+	startTime time.Time
+}
+
+func inTimeRange(span *LocalSpan, start, end time.Time) bool {
+	if !start.IsZero() && span.StartTime.Before(start) {
+		return false
+	}
+	if !end.IsZero() && span.StartTime.After(end) {
+		return false
+	}
+	return true
 }
 
 type LocalSpanMemoryStorage struct {
@@ -108,15 +418,20 @@ type LocalSpanMemoryStorage struct {
 	queue *Queue
 	// traceid -> spanid -> span
 	m map[string]map[string]*LocalSpan
-	// rule -> traceID, traceIDs will have duplicates, need to dedup when return
-	ruleTraces map[string][]string
+	// rule -> the traces ordered by the latest start time
+	rules     *ruleIndex
+	indexKeys []string
+	// attribute key -> value -> traces
+	attrIndex map[string]map[string][]attrIndexEntry
 }
 
-func newLocalSpanMemoryStorage(capacity int) *LocalSpanMemoryStorage {
+func newLocalSpanMemoryStorage(capacity int, indexKeys ...string) *LocalSpanMemoryStorage {
 	return &LocalSpanMemoryStorage{
-		queue:      NewQueue(capacity),
-		ruleTraces: make(map[string][]string),
-		m:          map[string]map[string]*LocalSpan{},
+		queue:     NewQueue(capacity),
+		rules:     newRuleIndex(),
+		m:         map[string]map[string]*LocalSpan{},
+		indexKeys: indexKeys,
+		attrIndex: make(map[string]map[string][]attrIndexEntry),
 	}
 }
 
@@ -130,19 +445,24 @@ func (l *LocalSpanMemoryStorage) SaveSpan(span sdktrace.ReadOnlySpan) error {
 func (l *LocalSpanMemoryStorage) saveSpan(localSpan *LocalSpan) error {
 	droppedTraceID := l.queue.Enqueue(localSpan)
 	if droppedTraceID != "" {
+		l.dropIndex(droppedTraceID)
 		delete(l.m, droppedTraceID)
 	}
+	for k, v := range indexedValues(localSpan, l.indexKeys) {
+		values, ok := l.attrIndex[k]
+		if !ok {
+			values = make(map[string][]attrIndexEntry)
+			l.attrIndex[k] = values
+		}
+		values[v] = append(values[v], attrIndexEntry{traceID: localSpan.TraceID, startTime: localSpan.StartTime})
+	}
 	spanMap, ok := l.m[localSpan.TraceID]
 	if !ok {
 		spanMap = make(map[string]*LocalSpan)
 		l.m[localSpan.TraceID] = spanMap
 	}
 	if len(localSpan.RuleID) > 0 {
-		_, ok := l.ruleTraces[localSpan.RuleID]
-		if !ok {
-			l.ruleTraces[localSpan.RuleID] = make([]string, 0)
-		}
-		l.ruleTraces[localSpan.RuleID] = append(l.ruleTraces[localSpan.RuleID], localSpan.TraceID)
+		l.rules.add(localSpan.RuleID, localSpan.TraceID, localSpan.StartTime)
 	}
 
 	spanMap[localSpan.SpanID] = localSpan
@@ -153,82 +473,107 @@ func (l *LocalSpanMemoryStorage) GetTraceById(traceID string) (*LocalSpan, error
 	l.RLock()
 	defer l.RUnlock()
 	allSpans := l.m[traceID]
-	if len(allSpans) < 1 {
-		return nil, nil
-	}
-	rootSpan := findRootSpan(allSpans)
-	if rootSpan == nil {
-		return nil, nil
-	}
-	copySpan := make(map[string]*LocalSpan)
-	for k, s := range allSpans {
-		copySpan[k] = s
+	spans := make([]*LocalSpan, 0, len(allSpans))
+	for _, s := range allSpans {
+		spans = append(spans, s)
 	}
-	buildSpanLink(rootSpan, copySpan)
-	return rootSpan, nil
+	return assembleTrace(spans), nil
 }
 
 func (l *LocalSpanMemoryStorage) GetTraceByRuleID(ruleID string, limit int64) ([]string, error) {
 	l.RLock()
 	defer l.RUnlock()
-	allTraces := l.ruleTraces[ruleID]
-	r := make([]string, 0)
-	if limit < 1 {
-		limit = int64(len(allTraces))
+	return l.rules.latest(ruleID, int(limit)), nil
+}
+
+// dropIndex removes the trace from the rule and the attribute indexes
+func (l *LocalSpanMemoryStorage) dropIndex(traceID string) {
+	for _, span := range l.m[traceID] {
+		if span.RuleID != "" {
+			l.rules.remove(span.RuleID, traceID)
+		}
+		for k, v := range indexedValues(span, l.indexKeys) {
+			entries := l.attrIndex[k][v]
+			kept := entries[:0]
+			for _, e := range entries {
+				if e.traceID != traceID {
+					kept = append(kept, e)
+				}
+			}
+			if len(kept) == 0 {
+				delete(l.attrIndex[k], v)
+			} else {
+				l.attrIndex[k][v] = kept
+			}
+		}
 	}
-	count := int64(0)
+}
+
+func (l *LocalSpanMemoryStorage) GetTraceByAttribute(key, value string, start, end time.Time, limit int64) ([]string, error) {
+	l.RLock()
+	defer l.RUnlock()
+	entries := l.attrIndex[key][value]
+	r := make([]string, 0)
 	traceMap := make(map[string]struct{})
-	for i := len(allTraces) - 1; i >= 0; i-- {
-		traceID := allTraces[i]
-		if _, existed := traceMap[traceID]; existed {
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		if (!start.IsZero() && e.startTime.Before(start)) || (!end.IsZero() && e.startTime.After(end)) {
+			continue
+		}
+		if _, existed := traceMap[e.traceID]; existed {
 			continue
 		}
-		traceMap[traceID] = struct{}{}
-		r = append(r, traceID)
-		count++
-		if count >= limit {
+		traceMap[e.traceID] = struct{}{}
+		r = append(r, e.traceID)
+		if limit > 0 && int64(len(r)) >= limit {
 			break
 		}
 	}
 	return r, nil
 }
 
-func findRootSpan(allSpans map[string]*LocalSpan) *LocalSpan {
-	for id1, span1 := range allSpans {
-		if span1.ParentSpanID == "" {
-			return span1
-		}
-		isRoot := true
-		for id2, span2 := range allSpans {
-			if id1 == id2 {
-				continue
-			}
-			if span1.ParentSpanID == span2.SpanID {
-				isRoot = false
-				break
-			}
-		}
-		if isRoot {
-			return span1
-		}
-	}
+func (l *LocalSpanMemoryStorage) DeleteTrace(traceID string) error {
+	l.Lock()
+	defer l.Unlock()
+	l.deleteTrace(traceID)
 	return nil
 }
 
-func buildSpanLink(cur *LocalSpan, OtherSpans map[string]*LocalSpan) {
-	// should only build once?
-	if len(cur.ChildSpan) > 0 {
+func (l *LocalSpanMemoryStorage) deleteTrace(traceID string) {
+	if _, ok := l.m[traceID]; !ok {
 		return
 	}
-	for k, otherSpan := range OtherSpans {
-		if cur.SpanID == otherSpan.ParentSpanID {
-			cur.ChildSpan = append(cur.ChildSpan, otherSpan)
-			delete(OtherSpans, k)
+	l.dropIndex(traceID)
+	delete(l.m, traceID)
+	l.queue.Remove(traceID)
+}
+
+func (l *LocalSpanMemoryStorage) RangeSpans(start, end time.Time, fn func(span *LocalSpan) error) error {
+	l.RLock()
+	spans := make([]*LocalSpan, 0)
+	for _, spanMap := range l.m {
+		for _, span := range spanMap {
+			if inTimeRange(span, start, end) {
+				spans = append(spans, span)
+			}
 		}
 	}
-	for _, span := range cur.ChildSpan {
-		buildSpanLink(span, OtherSpans)
+	l.RUnlock()
+	for _, span := range spans {
+		if err := fn(span); err != nil {
+			return err
+		}
 	}
+	return nil
+}
+
+// assembleTrace builds the tree of the stored spans. The broken links are logged and the linked part is returned.
+func assembleTrace(spans []*LocalSpan) *LocalSpan {
+	root, err := BuildTraceTree(spans)
+	if err != nil {
+		conf.Log.Warnf("assemble trace: %v", err)
+	}
+	return root
 }
 
 // Queue is traceID FIFO queue with sized capacity
@@ -256,6 +601,7 @@ func (q *Queue) Enqueue(item *LocalSpan) string {
 		dropped = q.Dequeue()
 	}
 	q.items = append(q.items, item.TraceID)
+	q.m[item.TraceID] = struct{}{}
 	return dropped
 }
 
@@ -269,23 +615,28 @@ func (q *Queue) Dequeue() string {
 	return traceID
 }
 
+// Remove removes all the items of the trace
+func (q *Queue) Remove(traceID string) {
+	kept := q.items[:0]
+	for _, id := range q.items {
+		if id != traceID {
+			kept = append(kept, id)
+		}
+	}
+	q.items = kept
+	delete(q.m, traceID)
+}
+
 func (q *Queue) Len() int {
 	return len(q.items)
 }
 
-type sqlSpanStorage struct{}
+type sqlSpanStorage struct {
+	indexKeys []string
+}
 
-func newSqlspanStorage() *sqlSpanStorage {
-	go func() {
-		ticker := time.NewTicker(time.Hour)
-		defer ticker.Stop()
-		for range ticker.C {
-			if err := gcSqliteSpan(); err != nil {
-				conf.Log.Warnf("gc sqlite trace span err:%v", err.Error())
-			}
-		}
-	}()
-	return &sqlSpanStorage{}
+func newSqlspanStorage(indexKeys ...string) *sqlSpanStorage {
+	return &sqlSpanStorage{indexKeys: indexKeys}
 }
 
 func (s *sqlSpanStorage) SaveSpan(span sdktrace.ReadOnlySpan) error {
@@ -297,12 +648,94 @@ func (s *sqlSpanStorage) GetTraceById(traceID string) (*LocalSpan, error) {
 	return s.loadTraceByTraceID(traceID)
 }
 
+// GetTraceByRuleID returns the latest traces of the rule by the rule index
 func (s *sqlSpanStorage) GetTraceByRuleID(ruleID string, limit int64) ([]string, error) {
-	return s.loadTraceByRuleID(ruleID)
+	if limit < 1 {
+		limit = -1
+	}
+	traceIDList := make([]string, 0)
+	err := store.TraceStores.Apply(func(db *sql.DB) error {
+		rows, err := db.Query("select traceID from trace_rule where ruleID = ? order by startTime desc, rowid desc limit ?", ruleID, limit)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		var traceID string
+		for rows.Next() {
+			if err := rows.Scan(&traceID); err != nil {
+				return err
+			}
+			traceIDList = append(traceIDList, traceID)
+		}
+		return rows.Err()
+	})
+	return traceIDList, err
+}
+
+func (s *sqlSpanStorage) RangeSpans(start, end time.Time, fn func(span *LocalSpan) error) error {
+	return store.TraceStores.Apply(func(db *sql.DB) error {
+		rows, err := db.Query("select value from trace")
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		var value []byte
+		for rows.Next() {
+			if err := rows.Scan(&value); err != nil {
+				return err
+			}
+			l, err := decodeStoredSpan(value)
+			if err != nil {
+				return err
+			}
+			if !inTimeRange(l, start, end) {
+				continue
+			}
+			if err := fn(l); err != nil {
+				return err
+			}
+		}
+		return rows.Err()
+	})
+}
+
+func (s *sqlSpanStorage) GetTraceByAttribute(key, value string, start, end time.Time, limit int64) ([]string, error) {
+	query := "select traceID from trace_attr where attrKey = ? and attrValue = ?"
+	args := []any{key, value}
+	if !start.IsZero() {
+		query += " and startTime >= ?"
+		args = append(args, start.UnixNano())
+	}
+	if !end.IsZero() {
+		query += " and startTime <= ?"
+		args = append(args, end.UnixNano())
+	}
+	if limit < 1 {
+		limit = -1
+	}
+	query += " group by traceID order by max(startTime) desc limit ?"
+	args = append(args, limit)
+	traceIDList := make([]string, 0)
+	err := store.TraceStores.Apply(func(db *sql.DB) error {
+		rows, err := db.Query(query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		var traceID string
+		for rows.Next() {
+			if err := rows.Scan(&traceID); err != nil {
+				return err
+			}
+			traceIDList = append(traceIDList, traceID)
+		}
+		return rows.Err()
+	})
+	return traceIDList, err
 }
 
 func (s *sqlSpanStorage) saveLocalSpan(span *LocalSpan) error {
-	bs, err := span.ToBytes()
+	bs, err := encodeStoredSpan(span)
 	if err != nil {
 		return err
 	}
@@ -319,7 +752,20 @@ func (s *sqlSpanStorage) saveLocalSpan(span *LocalSpan) error {
 			failpoint.Inject("injectTraceErr_2", func() {
 				err = errors.New("injectTraceErr_2")
 			})
-			return err
+			if err != nil {
+				return err
+			}
+			if span.RuleID != "" {
+				if _, err := db.Exec("insert into trace_rule(ruleID, traceID, startTime) values (?,?,?) on conflict(ruleID, traceID) do update set startTime = max(startTime, excluded.startTime)", span.RuleID, span.TraceID, span.StartTime.UnixNano()); err != nil {
+					return err
+				}
+			}
+			for k, v := range indexedValues(span, s.indexKeys) {
+				if _, err := db.Exec("insert into trace_attr(traceID, attrKey, attrValue, startTime) values (?,?,?,?)", span.TraceID, k, v, span.StartTime.UnixNano()); err != nil {
+					return err
+				}
+			}
+			return nil
 		})
 	}
 	return nil
@@ -401,29 +847,136 @@ func (s *sqlSpanStorage) loadTraceByTraceID(traceID string) (*LocalSpan, error)
 	if err != nil {
 		return nil, err
 	}
-	spans := make(map[string]*LocalSpan)
+	spans := make([]*LocalSpan, 0, len(valueList))
 	for _, value := range valueList {
-		l := &LocalSpan{}
-		if err := json.Unmarshal(value, &l); err != nil {
+		l, err := decodeStoredSpan(value)
+		if err != nil {
 			return nil, err
 		}
-		spans[l.SpanID] = l
-	}
-	rootSpan := findRootSpan(spans)
-	if rootSpan == nil {
-		return nil, nil
+		spans = append(spans, l)
 	}
-	copySpan := make(map[string]*LocalSpan)
-	for k, s := range spans {
-		copySpan[k] = s
-	}
-	buildSpanLink(rootSpan, copySpan)
-	return rootSpan, nil
+	return assembleTrace(spans), nil
 }
 
-func gcSqliteSpan() error {
+func (s *sqlSpanStorage) DeleteTrace(traceID string) error {
 	return store.TraceStores.Apply(func(db *sql.DB) error {
-		_, err := db.Exec("DELETE FROM trace WHERE createdtimestamp < datetime('now', '-1 day')")
-		return err
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+		if _, err := tx.Exec("DELETE FROM trace WHERE traceID = ?", traceID); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("DELETE FROM trace_attr WHERE traceID = ?", traceID); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("DELETE FROM trace_rule WHERE traceID = ?", traceID); err != nil {
+			return err
+		}
+		return tx.Commit()
+	})
+}
+
+// CleanupBefore deletes the spans created before the deadline and reports the deleted and remaining size
+func (s *sqlSpanStorage) CleanupBefore(deadline time.Time) (*CleanupResult, error) {
+	r := &CleanupResult{}
+	ts := deadline.UTC().Format(time.DateTime)
+	err := store.TraceStores.Apply(func(db *sql.DB) error {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+		if err := tx.QueryRow("SELECT COUNT(*), COALESCE(SUM(LENGTH(value)), 0) FROM trace WHERE createdtimestamp < ?", ts).Scan(&r.DeletedSpans, &r.DeletedBytes); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("DELETE FROM trace WHERE createdtimestamp < ?", ts); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("DELETE FROM trace_attr WHERE createdtimestamp < ?", ts); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("DELETE FROM trace_rule WHERE traceID NOT IN (SELECT traceID FROM trace)"); err != nil {
+			return err
+		}
+		if err := tx.QueryRow("SELECT COUNT(*), COALESCE(SUM(LENGTH(value)), 0) FROM trace").Scan(&r.RemainSpans, &r.RemainBytes); err != nil {
+			return err
+		}
+		return tx.Commit()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Usage returns the count and the bytes of the saved spans
+func (s *sqlSpanStorage) Usage() (spans, bytes int64, err error) {
+	err = store.TraceStores.Apply(func(db *sql.DB) error {
+		return db.QueryRow("SELECT COUNT(*), COALESCE(SUM(LENGTH(value)), 0) FROM trace").Scan(&spans, &bytes)
 	})
+	return spans, bytes, err
+}
+
+// CleanupOverQuota deletes the oldest traces by the creation time until the spans and the bytes are within the limits
+func (s *sqlSpanStorage) CleanupOverQuota(maxSpans, maxBytes int64) (*CleanupResult, error) {
+	r := &CleanupResult{}
+	err := store.TraceStores.Apply(func(db *sql.DB) error {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+		if err := tx.QueryRow("SELECT COUNT(*), COALESCE(SUM(LENGTH(value)), 0) FROM trace").Scan(&r.RemainSpans, &r.RemainBytes); err != nil {
+			return err
+		}
+		if !overQuota(r, maxSpans, maxBytes) {
+			return nil
+		}
+		rows, err := tx.Query("SELECT traceID, COUNT(*), COALESCE(SUM(LENGTH(value)), 0) FROM trace GROUP BY traceID ORDER BY MIN(createdtimestamp), MIN(rowid)")
+		if err != nil {
+			return err
+		}
+		var traces []string
+		for overQuota(r, maxSpans, maxBytes) && rows.Next() {
+			var (
+				traceID      string
+				spans, bytes int64
+			)
+			if err := rows.Scan(&traceID, &spans, &bytes); err != nil {
+				rows.Close()
+				return err
+			}
+			traces = append(traces, traceID)
+			r.DeletedSpans += spans
+			r.DeletedBytes += bytes
+			r.RemainSpans -= spans
+			r.RemainBytes -= bytes
+		}
+		if err := rows.Close(); err != nil {
+			return err
+		}
+		for _, traceID := range traces {
+			if _, err := tx.Exec("DELETE FROM trace WHERE traceID = ?", traceID); err != nil {
+				return err
+			}
+			if _, err := tx.Exec("DELETE FROM trace_attr WHERE traceID = ?", traceID); err != nil {
+				return err
+			}
+			if _, err := tx.Exec("DELETE FROM trace_rule WHERE traceID = ?", traceID); err != nil {
+				return err
+			}
+		}
+		return tx.Commit()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// overQuota returns whether the remaining size of the result exceeds the limits. 0 means no limit.
+func overQuota(r *CleanupResult, maxSpans, maxBytes int64) bool {
+	return (maxSpans > 0 && r.RemainSpans > maxSpans) || (maxBytes > 0 && r.RemainBytes > maxBytes)
 }
@@ -17,16 +17,18 @@
 package tracer
 
 import (
+	"context"
 	"fmt"
 	"os"
-	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/pingcap/failpoint"
 	"github.com/stretchr/testify/require"
 
 	"github.com/lf-edge/ekuiper/v2/internal/conf"
 	"github.com/lf-edge/ekuiper/v2/internal/pkg/store"
+	"github.com/lf-edge/ekuiper/v2/pkg/model"
 )
 
 func TestLocalSpan(t *testing.T) {
@@ -76,11 +78,41 @@ func TestLocalTraceByRule(t *testing.T) {
 	require.Len(t, ids, 2)
 }
 
-func TestLocalStorageTraceManager(t *testing.T) {
+func TestLocalSpanDelete(t *testing.T) {
+	conf.InitConf()
+	s := newLocalSpanMemoryStorage(10, "deviceId")
+	require.NoError(t, s.saveSpan(&LocalSpan{TraceID: "t0", SpanID: "s0", RuleID: "r1", Attribute: map[string]any{"deviceId": "d1"}}))
+	require.NoError(t, s.saveSpan(&LocalSpan{TraceID: "t1", SpanID: "s1", RuleID: "r1"}))
+	require.NoError(t, s.DeleteTrace("t0"))
+	require.NoError(t, s.DeleteTrace("notExist"))
+	root, err := s.GetTraceById("t0")
+	require.NoError(t, err)
+	require.Nil(t, root)
+	ids, err := s.GetTraceByRuleID("r1", 0)
+	require.NoError(t, err)
+	require.Equal(t, []string{"t1"}, ids)
+	ids, err = s.GetTraceByAttribute("deviceId", "d1", time.Time{}, time.Time{}, 0)
+	require.NoError(t, err)
+	require.Empty(t, ids)
+	require.Equal(t, 1, s.queue.Len())
+}
+
+// setupTraceDB opens the trace db in the own data dir of the test, so that the db still opened by the previous test
+// is not reused
+func setupTraceDB(t *testing.T) {
+	testId := conf.TestId
+	conf.TestId = "trace_" + t.Name()
 	dataDir, err := conf.GetDataLoc()
 	require.NoError(t, err)
-	os.Remove(filepath.Join(dataDir, "trace.db"))
+	t.Cleanup(func() {
+		conf.TestId = testId
+		os.RemoveAll(dataDir)
+	})
 	require.NoError(t, store.SetupDefault(dataDir))
+}
+
+func TestLocalStorageTraceManager(t *testing.T) {
+	setupTraceDB(t)
 	spanStorage := newSqlspanStorage()
 	span0 := &LocalSpan{
 		TraceID: "t0",
@@ -103,10 +135,7 @@ func TestLocalStorageTraceManager(t *testing.T) {
 }
 
 func TestLocalStorageTraceManagerErr(t *testing.T) {
-	dataDir, err := conf.GetDataLoc()
-	require.NoError(t, err)
-	os.Remove(filepath.Join(dataDir, "trace.db"))
-	require.NoError(t, store.SetupDefault(dataDir))
+	setupTraceDB(t)
 	spanStorage := newSqlspanStorage()
 	span0 := &LocalSpan{
 		TraceID: "t0",
@@ -142,3 +171,173 @@ func TestLocalStorageTraceManagerErr(t *testing.T) {
 		failpoint.Disable(failpointPath)
 	}
 }
+
+func TestSqlSpanStorageCleanup(t *testing.T) {
+	setupTraceDB(t)
+	spanStorage := newSqlspanStorage()
+	require.NoError(t, spanStorage.saveLocalSpan(&LocalSpan{TraceID: "t0", SpanID: "s0", RuleID: "r1"}))
+	require.NoError(t, spanStorage.saveLocalSpan(&LocalSpan{TraceID: "t1", SpanID: "s1", RuleID: "r1"}))
+	job := newCleanupJob(spanStorage, time.Hour, time.Hour)
+	// nothing is expired
+	r, err := job.runOnce(time.Now())
+	require.NoError(t, err)
+	require.Equal(t, int64(0), r.DeletedSpans)
+	require.Equal(t, int64(2), r.RemainSpans)
+	require.True(t, r.RemainBytes > 0)
+	// all spans are expired
+	r, err = job.runOnce(time.Now().Add(2 * time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, int64(2), r.DeletedSpans)
+	require.True(t, r.DeletedBytes > 0)
+	require.Equal(t, int64(0), r.RemainSpans)
+	require.Equal(t, int64(0), r.RemainBytes)
+	got, err := spanStorage.loadTraceByRuleID("r1")
+	require.NoError(t, err)
+	require.Len(t, got, 0)
+}
+
+func TestSqlSpanStorageQuota(t *testing.T) {
+	setupTraceDB(t)
+	spanStorage := newSqlspanStorage()
+	for i := 0; i < 3; i++ {
+		require.NoError(t, spanStorage.saveLocalSpan(&LocalSpan{TraceID: fmt.Sprintf("t%d", i), SpanID: "s0", RuleID: "r1"}))
+	}
+	job := newCleanupJob(spanStorage, time.Hour, time.Hour).withQuota(2, 0)
+	require.Nil(t, job.lastResult())
+	r, err := job.runOnce(time.Now())
+	require.NoError(t, err)
+	require.Equal(t, int64(1), r.DeletedSpans)
+	require.True(t, r.DeletedBytes > 0)
+	require.Equal(t, int64(2), r.RemainSpans)
+	require.Equal(t, r, job.lastResult())
+	// the oldest trace is deleted
+	root, err := spanStorage.GetTraceById("t0")
+	require.NoError(t, err)
+	require.Nil(t, root)
+	// within the quota
+	r, err = spanStorage.CleanupOverQuota(2, r.RemainBytes)
+	require.NoError(t, err)
+	require.Equal(t, int64(0), r.DeletedSpans)
+	r, err = spanStorage.CleanupOverQuota(0, r.RemainBytes-1)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), r.DeletedSpans)
+	require.Equal(t, int64(1), r.RemainSpans)
+	got, err := spanStorage.loadTraceByRuleID("r1")
+	require.NoError(t, err)
+	require.Equal(t, []string{"t2"}, got)
+}
+
+func TestPurgeRule(t *testing.T) {
+	conf.InitConf()
+	s := newLocalSpanMemoryStorage(10)
+	require.NoError(t, s.saveSpan(&LocalSpan{TraceID: "t0", SpanID: "s0", RuleID: "r1"}))
+	require.NoError(t, s.saveSpan(&LocalSpan{TraceID: "t0", SpanID: "s1", ParentSpanID: "s0", RuleID: "r1"}))
+	require.NoError(t, s.saveSpan(&LocalSpan{TraceID: "t1", SpanID: "s2", RuleID: "r2"}))
+	e := &SpanExporter{spanStorage: s}
+	r, err := e.PurgeRule("r1")
+	require.NoError(t, err)
+	require.Equal(t, int64(2), r.DeletedSpans)
+	require.True(t, r.DeletedBytes > 0)
+	root, err := s.GetTraceById("t0")
+	require.NoError(t, err)
+	require.Nil(t, root)
+	root, err = s.GetTraceById("t1")
+	require.NoError(t, err)
+	require.NotNil(t, root)
+	r, err = e.PurgeRule("notExist")
+	require.NoError(t, err)
+	require.Equal(t, int64(0), r.DeletedSpans)
+}
+
+func TestLocalSpanRange(t *testing.T) {
+	conf.InitConf()
+	s := newLocalSpanMemoryStorage(10)
+	now := time.Now()
+	require.NoError(t, s.saveSpan(&LocalSpan{TraceID: "t0", SpanID: "s0", StartTime: now.Add(-time.Hour)}))
+	require.NoError(t, s.saveSpan(&LocalSpan{TraceID: "t1", SpanID: "s1", StartTime: now}))
+	var got []string
+	collect := func(span *LocalSpan) error {
+		got = append(got, span.SpanID)
+		return nil
+	}
+	require.NoError(t, s.RangeSpans(time.Time{}, time.Time{}, collect))
+	require.Len(t, got, 2)
+	got = nil
+	require.NoError(t, s.RangeSpans(now.Add(-time.Minute), time.Time{}, collect))
+	require.Equal(t, []string{"s1"}, got)
+	got = nil
+	require.NoError(t, s.RangeSpans(time.Time{}, now.Add(-time.Minute), collect))
+	require.Equal(t, []string{"s0"}, got)
+}
+
+func TestLocalTraceByAttribute(t *testing.T) {
+	conf.InitConf()
+	s := newLocalSpanMemoryStorage(2, "deviceId")
+	now := time.Now()
+	require.NoError(t, s.saveSpan(&LocalSpan{TraceID: "t0", SpanID: "s0", StartTime: now.Add(-time.Hour), Attribute: map[string]interface{}{"deviceId": "d1"}}))
+	require.NoError(t, s.saveSpan(&LocalSpan{TraceID: "t1", SpanID: "s1", StartTime: now, Attribute: map[string]interface{}{"deviceId": "d1", "topic": "a"}}))
+	ids, err := s.GetTraceByAttribute("deviceId", "d1", time.Time{}, time.Time{}, 0)
+	require.NoError(t, err)
+	require.Equal(t, []string{"t1", "t0"}, ids)
+	ids, err = s.GetTraceByAttribute("deviceId", "d1", now.Add(-time.Minute), now.Add(time.Minute), 0)
+	require.NoError(t, err)
+	require.Equal(t, []string{"t1"}, ids)
+	ids, err = s.GetTraceByAttribute("deviceId", "d1", time.Time{}, time.Time{}, 1)
+	require.NoError(t, err)
+	require.Equal(t, []string{"t1"}, ids)
+	// topic is not indexed
+	ids, err = s.GetTraceByAttribute("topic", "a", time.Time{}, time.Time{}, 0)
+	require.NoError(t, err)
+	require.Len(t, ids, 0)
+	// t0 is dropped from the queue, so is the index
+	require.NoError(t, s.saveSpan(&LocalSpan{TraceID: "t2", SpanID: "s2", StartTime: now}))
+	ids, err = s.GetTraceByAttribute("deviceId", "d1", time.Time{}, time.Time{}, 0)
+	require.NoError(t, err)
+	require.Equal(t, []string{"t1"}, ids)
+}
+
+func TestSqlTraceByAttribute(t *testing.T) {
+	setupTraceDB(t)
+	spanStorage := newSqlspanStorage("deviceId")
+	now := time.Now()
+	require.NoError(t, spanStorage.saveLocalSpan(&LocalSpan{TraceID: "t0", SpanID: "s0", RuleID: "r1", StartTime: now.Add(-time.Hour), Attribute: map[string]interface{}{"deviceId": 1}}))
+	require.NoError(t, spanStorage.saveLocalSpan(&LocalSpan{TraceID: "t1", SpanID: "s1", RuleID: "r1", StartTime: now, Attribute: map[string]interface{}{"deviceId": 1}}))
+	ids, err := spanStorage.GetTraceByAttribute("deviceId", "1", time.Time{}, time.Time{}, 0)
+	require.NoError(t, err)
+	require.Equal(t, []string{"t1", "t0"}, ids)
+	ids, err = spanStorage.GetTraceByAttribute("deviceId", "1", now.Add(-time.Minute), time.Time{}, 0)
+	require.NoError(t, err)
+	require.Equal(t, []string{"t1"}, ids)
+	ids, err = spanStorage.GetTraceByAttribute("deviceId", "2", time.Time{}, time.Time{}, 0)
+	require.NoError(t, err)
+	require.Len(t, ids, 0)
+}
+
+func TestExporterHealth(t *testing.T) {
+	conf.InitConf()
+	e, err := NewSpanExporter(false, "")
+	require.NoError(t, err)
+	h := e.Health()
+	require.True(t, h.Enabled)
+	require.True(t, h.Healthy())
+	require.Equal(t, int64(0), h.LastExport)
+
+	e.pipeline = newSpanPipeline(e, model.SpanPipelineConf{QueueSize: 10, BatchSize: 10})
+	for _, span := range sampledSpans(2) {
+		e.pipeline.OnEnd(span)
+	}
+	require.Equal(t, int64(2), e.Health().QueueDepth)
+
+	now := time.Now()
+	e.recordExport(now, fmt.Errorf("mock error"))
+	h = e.Health()
+	require.False(t, h.Healthy())
+	require.Equal(t, "mock error", h.LastError)
+	e.recordExport(now.Add(time.Second), nil)
+	h = e.Health()
+	require.True(t, h.Healthy())
+	require.Empty(t, h.LastError)
+
+	require.NoError(t, e.ExportSpans(context.Background(), nil))
+	require.Equal(t, int64(2), e.Health().QueueDepth)
+}
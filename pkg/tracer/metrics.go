@@ -0,0 +1,88 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build trace || !core
+
+package tracer
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/lf-edge/ekuiper/v2/metrics"
+)
+
+const (
+	LblDeletedSpans = "deleted_spans"
+	LblDeletedBytes = "deleted_bytes"
+	LblSpans        = "spans"
+	LblBytes        = "bytes"
+
+	LblStartedSpans  = "started_spans"
+	LblExportedSpans = "exported_spans"
+	LblDroppedSpans  = "dropped_spans"
+	LblExportErrors  = "export_errors"
+	LblQueueSpans    = "queue_spans"
+	LblRemote        = "remote"
+	LblLocal         = "local"
+
+	LblRemoteQueueSpans   = "remote_queue_spans"
+	LblRemoteRetries      = "remote_retries"
+	LblRemoteDroppedSpans = "remote_dropped_spans"
+)
+
+var (
+	TraceStoreCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "kuiper",
+		Subsystem: "trace_store",
+		Name:      "counter",
+		Help:      "counter of trace store cleanup",
+	}, []string{metrics.LblType})
+
+	TraceStoreGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "kuiper",
+		Subsystem: "trace_store",
+		Name:      "gauge",
+		Help:      "gauge of trace store size",
+	}, []string{metrics.LblType})
+
+	TraceExportCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "kuiper",
+		Subsystem: "trace_export",
+		Name:      "counter",
+		Help:      "counter of spans through the trace export pipeline",
+	}, []string{metrics.LblType})
+
+	TraceExportGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "kuiper",
+		Subsystem: "trace_export",
+		Name:      "gauge",
+		Help:      "gauge of spans waiting to be exported",
+	}, []string{metrics.LblType})
+
+	TraceExportDurationHist = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "kuiper",
+		Subsystem: "trace_export",
+		Name:      "duration_microseconds",
+		Help:      "Historgram duration of span export",
+		Buckets:   prometheus.ExponentialBuckets(10, 2, 22), // 10us ~ 20s
+	}, []string{metrics.LblType})
+)
+
+func init() {
+	prometheus.MustRegister(TraceStoreCounter)
+	prometheus.MustRegister(TraceStoreGauge)
+	prometheus.MustRegister(TraceExportCounter)
+	prometheus.MustRegister(TraceExportGauge)
+	prometheus.MustRegister(TraceExportDurationHist)
+}
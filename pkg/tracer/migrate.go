@@ -0,0 +1,191 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build trace || !core
+
+package tracer
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/store"
+)
+
+// MigrateSpans copies the spans in the memory span store to the sqlite span store and verifies that each trace can be
+// loaded back. The exporter keeps saving into the memory store, so enable openTelemetry.enableLocalStorage and
+// restart after the migration.
+func (l *SpanExporter) MigrateSpans() (*SpanMigration, error) {
+	if _, ok := l.spanStorage.(*sqlSpanStorage); ok {
+		return nil, errors.New("the spans are already saved in the sqlite span store")
+	}
+	return migrateSpans(l.spanStorage, newSqlspanStorage(conf.Config.OpenTelemetry.IndexedAttributes...))
+}
+
+func migrateSpans(src LocalSpanStorage, dst *sqlSpanStorage) (*SpanMigration, error) {
+	r := &SpanMigration{}
+	var (
+		traces   []string
+		seen     = make(map[string]struct{})
+		firstErr error
+	)
+	err := src.RangeSpans(time.Time{}, time.Time{}, func(span *LocalSpan) error {
+		r.Spans++
+		// the spans are saved flat without the children
+		s := *span
+		s.ChildSpan = nil
+		if err := dst.saveLocalSpan(&s); err != nil {
+			r.Failed++
+			if firstErr == nil {
+				firstErr = err
+			}
+			return nil
+		}
+		if _, ok := seen[s.TraceID]; !ok {
+			seen[s.TraceID] = struct{}{}
+			traces = append(traces, s.TraceID)
+		}
+		return nil
+	})
+	if err != nil {
+		return r, err
+	}
+	r.Traces = len(traces)
+	for _, id := range traces {
+		root, err := dst.loadTraceByTraceID(id)
+		if err != nil {
+			return r, err
+		}
+		if root == nil {
+			r.Missing = append(r.Missing, id)
+			continue
+		}
+		r.Verified++
+	}
+	switch {
+	case firstErr != nil:
+		return r, fmt.Errorf("%d spans failed to migrate, the first error: %v", r.Failed, firstErr)
+	case len(r.Missing) > 0:
+		return r, fmt.Errorf("%d traces are missing after migration", len(r.Missing))
+	}
+	return r, nil
+}
+
+// spanRecompressor is the local storage of the serialized spans which can be rewritten by another codec
+type spanRecompressor interface {
+	recompress(c *spanCompressor) (*SpanRecompression, error)
+}
+
+// RecompressSpans rewrites the spans in the local storage by the configured storage codec, so that the spans saved
+// before the compression is enabled or changed take less space. The spans are readable during and after the rewrite.
+func (l *SpanExporter) RecompressSpans() (*SpanRecompression, error) {
+	rc, ok := l.spanStorage.(spanRecompressor)
+	if !ok {
+		return nil, errors.New("the spans in the memory span store are not serialized")
+	}
+	return rc.recompress(storageCompressor.get())
+}
+
+// recompressBatch is the count of the rows rewritten in a transaction
+const recompressBatch = 500
+
+func (s *sqlSpanStorage) recompress(c *spanCompressor) (*SpanRecompression, error) {
+	r := &SpanRecompression{Codec: c.codec}
+	err := store.TraceStores.Apply(func(db *sql.DB) error {
+		var last int64
+		for {
+			n, err := s.recompressRows(db, c, r, &last)
+			if err != nil {
+				return err
+			}
+			if n < recompressBatch {
+				return nil
+			}
+		}
+	})
+	return r, err
+}
+
+// recompressRows rewrites a batch of rows after the last rowid and returns the count of the loaded rows
+func (s *sqlSpanStorage) recompressRows(db *sql.DB, c *spanCompressor, r *SpanRecompression, last *int64) (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	rows, err := tx.Query("SELECT rowid, value FROM trace WHERE rowid > ? ORDER BY rowid LIMIT ?", *last, recompressBatch)
+	if err != nil {
+		return 0, err
+	}
+	type row struct {
+		id    int64
+		value []byte
+	}
+	var batch []row
+	for rows.Next() {
+		var w row
+		if err := rows.Scan(&w.id, &w.value); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		batch = append(batch, w)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	for _, w := range batch {
+		*last = w.id
+		r.Spans++
+		r.BeforeBytes += int64(len(w.value))
+		if codecOf(w.value) == c.codec {
+			r.AfterBytes += int64(len(w.value))
+			continue
+		}
+		bs, err := decompress(w.value)
+		if err != nil {
+			return 0, fmt.Errorf("decompress span error: %v", err)
+		}
+		bs, err = c.compress(bs)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := tx.Exec("UPDATE trace SET value = ? WHERE rowid = ?", bs, w.id); err != nil {
+			return 0, err
+		}
+		r.Rewritten++
+		r.AfterBytes += int64(len(bs))
+	}
+	return len(batch), tx.Commit()
+}
+
+// recompress rewrites the span file by the compaction which saves the spans by the current codec
+func (s *fileSpanStorage) recompress(c *spanCompressor) (*SpanRecompression, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.file == nil {
+		return nil, errSpanFileClosed
+	}
+	r := &SpanRecompression{Codec: c.codec, BeforeBytes: s.size}
+	if err := s.compact(); err != nil {
+		return nil, err
+	}
+	r.Spans = int64(s.records)
+	r.Rewritten = r.Spans
+	r.AfterBytes = s.size
+	return r, nil
+}
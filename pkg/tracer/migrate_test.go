@@ -0,0 +1,51 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build trace || !core
+
+package tracer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrateSpans(t *testing.T) {
+	setupTraceDB(t)
+
+	src := newLocalSpanMemoryStorage(10)
+	require.NoError(t, src.saveSpan(&LocalSpan{TraceID: "m0", SpanID: "s0", RuleID: "r1"}))
+	require.NoError(t, src.saveSpan(&LocalSpan{TraceID: "m1", SpanID: "s1", RuleID: "r1"}))
+	// loading the trace links the children into the root
+	_, err := src.GetTraceById("m0")
+	require.NoError(t, err)
+
+	dst := newSqlspanStorage()
+	r, err := migrateSpans(src, dst)
+	require.NoError(t, err)
+	require.Equal(t, &SpanMigration{Spans: 2, Traces: 2, Verified: 2}, r)
+	ids, err := dst.GetTraceByRuleID("r1", 0)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"m0", "m1"}, ids)
+
+	// the traces exist in the target already
+	r, err = migrateSpans(src, dst)
+	require.Error(t, err)
+	require.Equal(t, 2, r.Failed)
+
+	e := &SpanExporter{spanStorage: dst}
+	_, err = e.MigrateSpans()
+	require.Error(t, err)
+}
@@ -17,17 +17,30 @@
 package tracer
 
 import (
-	"fmt"
+	"context"
+	"io"
+	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/trace"
+
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+	"github.com/lf-edge/ekuiper/v2/pkg/model"
 )
 
-var traceErr = fmt.Errorf("trace not enabled")
+var traceErr = errorx.NewWithCode(errorx.TracerDisabledErr, "trace not enabled")
 
 func GetTraceIDListByRuleID(ruleID string, limit int64) ([]string, error) {
 	return nil, traceErr
 }
 
+func GetLatestTraceIDsByRule(ruleID string, limit int64) ([]string, error) {
+	return nil, traceErr
+}
+
 func InitTracer() error {
 	return nil
 }
@@ -36,10 +49,160 @@ func SetTracer(config *TracerConfig) error {
 	return traceErr
 }
 
+func GetTracerConfig() (*TracerConfig, error) {
+	return nil, traceErr
+}
+
 func GetSpanByTraceID(traceID string) (root *LocalSpan, err error) {
 	return nil, traceErr
 }
 
+func GetTraceByID(traceID string) (root *LocalSpan, err error) {
+	return nil, traceErr
+}
+
 func GetTracer() trace.Tracer {
 	return nil
 }
+
+func StartRemoteSpan(ctx context.Context, carrier propagation.TextMapCarrier, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return ctx, trace.SpanFromContext(ctx)
+}
+
+func ExportSpans(w io.Writer, start, end time.Time) (int, error) {
+	return 0, traceErr
+}
+
+func ExportSpansAs(w io.Writer, format SpanFormat, start, end time.Time) (int, error) {
+	return 0, traceErr
+}
+
+func GetTraceIDListByAttribute(key, value string, start, end time.Time, limit int64) ([]string, error) {
+	return nil, traceErr
+}
+
+func SearchTraces(ruleID string, start, end time.Time, attrFilters map[string]string, limit, offset int) (*TraceSearchResult, error) {
+	return nil, traceErr
+}
+
+func GetTraceLinks(traceID string) (*TraceLinks, error) {
+	return nil, traceErr
+}
+
+func SetSampling(c model.SamplingConf) error {
+	return traceErr
+}
+
+func GetSampling() model.SamplingConf {
+	return model.SamplingConf{}
+}
+
+func PurgeRuleTraces(ruleID string) (*CleanupResult, error) {
+	return nil, traceErr
+}
+
+func SubscribeSpans(filter SpanFilter) (<-chan *LocalSpan, func(), error) {
+	return nil, nil, traceErr
+}
+
+type ConnectionResolver func(ruleID, opID string) []string
+
+func SetConnectionResolver(r ConnectionResolver) {}
+
+func AnnotateActiveSpans(ruleID, opID, name string, attrs ...attribute.KeyValue) int {
+	return 0
+}
+
+func GetSpanMetrics(ruleID string) []SpanMetrics {
+	return nil
+}
+
+func ResetSpanMetrics(ruleID string) {}
+
+func ExportTraceBundle(traceIDs []string, w io.Writer) error {
+	return traceErr
+}
+
+func ImportTraceBundle(r io.Reader) (*TraceBundle, error) {
+	return nil, traceErr
+}
+
+func OnSpanAlert(handler func(*SpanAlertEvent)) func() {
+	return func() {}
+}
+
+func GetSpanAlerts(ruleID string) []*SpanAlertEvent {
+	return nil
+}
+
+func SamplePayload(ruleID, opID string, payload func() string) []attribute.KeyValue {
+	return nil
+}
+
+func ExportTraceAsJaegerJSON(traceID string) ([]byte, error) {
+	return nil, traceErr
+}
+
+func ExportTraceAsZipkinJSON(traceID string) ([]byte, error) {
+	return nil, traceErr
+}
+
+func IsTracingEnabled() bool {
+	return true
+}
+
+func SetTracingEnabled(enabled bool) error {
+	return traceErr
+}
+
+func GetRuleTrace(ruleID string) (*RuleTraceConf, error) {
+	return nil, traceErr
+}
+
+func SetRuleTrace(ruleID string, c *RuleTraceConf) error {
+	return traceErr
+}
+
+func DeleteTrace(traceID string) error {
+	return traceErr
+}
+
+func MigrateSpans() (*SpanMigration, error) {
+	return nil, traceErr
+}
+
+func RecompressSpans() (*SpanRecompression, error) {
+	return nil, traceErr
+}
+
+func Health() *ExporterHealth {
+	return &ExporterHealth{}
+}
+
+func TracerStats() *TracerStatistics {
+	return &TracerStatistics{}
+}
+
+func InitMeter() error {
+	return nil
+}
+
+func StopMeter(_ context.Context) {}
+
+func Meter() metric.Meter {
+	return noop.NewMeterProvider().Meter("")
+}
+
+func CheckRemoteEndpoint(_ time.Duration) (string, error) {
+	return "", nil
+}
+
+func StartOtlpReceiver(addr string) error {
+	return traceErr
+}
+
+func StartOtlpHttpReceiver(addr string) error {
+	return traceErr
+}
+
+func StopOtlpReceiver() {}
@@ -17,7 +17,10 @@
 package tracer
 
 import (
+	"go.opentelemetry.io/otel/codes"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
 )
 
 func FromReadonlySpan(readonly sdktrace.ReadOnlySpan) *LocalSpan {
@@ -30,21 +33,70 @@ func FromReadonlySpan(readonly sdktrace.ReadOnlySpan) *LocalSpan {
 		StartTime:    readonly.StartTime(),
 		EndTime:      readonly.EndTime(),
 	}
-	if len(readonly.Attributes()) > 0 {
+	attrs := readonly.Attributes()
+	// the sensitive attributes never reach the storage and the subscribers
+	redaction := globalRedactor.policy(spanRule(attrs))
+	attrs = redaction.redact(attrs)
+	if len(attrs) > 0 {
 		span.Attribute = make(map[string]interface{})
-		for _, attr := range readonly.Attributes() {
+		preserve := conf.Config != nil && conf.Config.OpenTelemetry.PreserveAttributeTypes
+		for _, attr := range attrs {
 			if string(attr.Key) == "rule" {
 				span.RuleID = attr.Value.AsString()
 			}
-			span.Attribute[string(attr.Key)] = attr.Value.AsInterface()
+			v := attr.Value.AsInterface()
+			span.Attribute[string(attr.Key)] = v
+			if !preserve {
+				continue
+			}
+			if t := attributeType(v); t != "" {
+				if span.AttributeTypes == nil {
+					span.AttributeTypes = make(map[string]string)
+				}
+				span.AttributeTypes[string(attr.Key)] = t
+			}
+		}
+	}
+	for _, ev := range readonly.Events() {
+		e := LocalEvent{Name: ev.Name, Timestamp: ev.Time}
+		if evAttrs := redaction.redact(ev.Attributes); len(evAttrs) > 0 {
+			e.Attributes = make(map[string]interface{}, len(evAttrs))
+			for _, attr := range evAttrs {
+				e.Attributes[string(attr.Key)] = attr.Value.AsInterface()
+			}
 		}
+		span.Events = append(span.Events, e)
 	}
+	if st := readonly.Status(); st.Code != codes.Unset {
+		span.Status = st.Code.String()
+		span.StatusMessage = st.Description
+	}
+	if res := readonly.Resource(); res != nil && res.Len() > 0 {
+		span.Resource = make(map[string]interface{}, res.Len())
+		for _, attr := range res.Attributes() {
+			span.Resource[string(attr.Key)] = attr.Value.AsInterface()
+		}
+	}
+	scope := readonly.InstrumentationScope()
+	span.Scope = scope.Name
+	span.ScopeVersion = scope.Version
 	if len(readonly.Links()) > 0 {
 		span.Links = make([]LocalLink, 0)
 		for _, link := range readonly.Links() {
-			span.Links = append(span.Links, LocalLink{
-				TraceID: link.SpanContext.TraceID().String(),
-			})
+			l := LocalLink{
+				TraceID:    link.SpanContext.TraceID().String(),
+				TraceState: link.SpanContext.TraceState().String(),
+			}
+			if link.SpanContext.HasSpanID() {
+				l.SpanID = link.SpanContext.SpanID().String()
+			}
+			if linkAttrs := redaction.redact(link.Attributes); len(linkAttrs) > 0 {
+				l.Attributes = make(map[string]interface{}, len(linkAttrs))
+				for _, attr := range linkAttrs {
+					l.Attributes[string(attr.Key)] = attr.Value.AsInterface()
+				}
+			}
+			span.Links = append(span.Links, l)
 		}
 	}
 	return span
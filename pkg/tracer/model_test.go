@@ -0,0 +1,129 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build trace || !core
+
+package tracer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+)
+
+func TestFromReadonlySpan(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	ts, err := trace.ParseTraceState("vendor=v1")
+	require.NoError(t, err)
+	stub := tracetest.SpanStub{
+		Name: "op",
+		SpanContext: trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID: trace.TraceID{1},
+			SpanID:  trace.SpanID{2},
+		}),
+		StartTime:  start,
+		EndTime:    start.Add(time.Second),
+		Attributes: []attribute.KeyValue{attribute.String("rule", "r1")},
+		Events: []sdktrace.Event{
+			{Name: "exception", Time: start.Add(time.Millisecond), Attributes: []attribute.KeyValue{attribute.String("exception.message", "boom"), attribute.Int64("retry", 2)}},
+			{Name: "done", Time: start.Add(time.Second)},
+		},
+		Status: sdktrace.Status{Code: codes.Error, Description: "boom"},
+		Links: []sdktrace.Link{{
+			SpanContext: trace.NewSpanContext(trace.SpanContextConfig{TraceID: trace.TraceID{3}, SpanID: trace.SpanID{4}, TraceState: ts}),
+			Attributes:  []attribute.KeyValue{attribute.String("link.type", "window")},
+		}},
+	}
+	span := FromReadonlySpan(stub.Snapshot())
+	require.Equal(t, "r1", span.RuleID)
+	require.Equal(t, "Error", span.Status)
+	require.Equal(t, "boom", span.StatusMessage)
+	require.Equal(t, []LocalEvent{
+		{Name: "exception", Timestamp: start.Add(time.Millisecond), Attributes: map[string]interface{}{"exception.message": "boom", "retry": int64(2)}},
+		{Name: "done", Timestamp: start.Add(time.Second)},
+	}, span.Events)
+	links := []LocalLink{{
+		TraceID:    "03000000000000000000000000000000",
+		SpanID:     "0400000000000000",
+		TraceState: "vendor=v1",
+		Attributes: map[string]interface{}{"link.type": "window"},
+	}}
+	require.Equal(t, links, span.Links)
+
+	// the events, the status and the links are kept by the serialization
+	bs, err := span.ToBytes()
+	require.NoError(t, err)
+	decoded, err := DecodeLocalSpan(bs)
+	require.NoError(t, err)
+	require.Equal(t, span.Status, decoded.Status)
+	require.Equal(t, span.StatusMessage, decoded.StatusMessage)
+	require.Len(t, decoded.Events, 2)
+	require.Equal(t, "boom", decoded.Events[0].Attributes["exception.message"])
+	require.True(t, start.Add(time.Millisecond).Equal(decoded.Events[0].Timestamp))
+	require.Equal(t, links, decoded.Links)
+
+	require.Nil(t, decoded.Resource)
+
+	stub.Resource = resource.NewSchemaless(attribute.String("service.name", "edge1"), attribute.String("host.name", "h1"))
+	stub.InstrumentationScope = instrumentation.Scope{Name: "kuiperd-service", Version: "v1"}
+	span = FromReadonlySpan(stub.Snapshot())
+	require.Equal(t, map[string]interface{}{"service.name": "edge1", "host.name": "h1"}, span.Resource)
+	require.Equal(t, "kuiperd-service", span.Scope)
+	require.Equal(t, "v1", span.ScopeVersion)
+	bs, err = span.ToBytes()
+	require.NoError(t, err)
+	decoded, err = DecodeLocalSpan(bs)
+	require.NoError(t, err)
+	require.Equal(t, span.Resource, decoded.Resource)
+	require.Equal(t, "kuiperd-service", decoded.Scope)
+	require.Equal(t, "v1", decoded.ScopeVersion)
+
+	stub.Status = sdktrace.Status{}
+	stub.Events = nil
+	span = FromReadonlySpan(stub.Snapshot())
+	require.Empty(t, span.Status)
+	require.Empty(t, span.Events)
+}
+
+func TestFromReadonlySpanAttributeTypes(t *testing.T) {
+	conf.InitConf()
+	defer func() {
+		conf.Config.OpenTelemetry.PreserveAttributeTypes = false
+	}()
+	stub := tracetest.SpanStub{
+		Name:       "op",
+		Attributes: []attribute.KeyValue{attribute.String("rule", "r1"), attribute.Int64("count", 3), attribute.StringSlice("tags", []string{"a"})},
+	}
+	span := FromReadonlySpan(stub.Snapshot())
+	require.Nil(t, span.AttributeTypes)
+
+	conf.Config.OpenTelemetry.PreserveAttributeTypes = true
+	span = FromReadonlySpan(stub.Snapshot())
+	require.Equal(t, map[string]string{"count": AttributeTypeInt64, "tags": AttributeTypeStringSlice}, span.AttributeTypes)
+	bs, err := span.ToBytes()
+	require.NoError(t, err)
+	decoded, err := DecodeLocalSpan(bs)
+	require.NoError(t, err)
+	require.Equal(t, span.Attribute, decoded.Attribute)
+}
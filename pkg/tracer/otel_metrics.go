@@ -0,0 +1,119 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build trace || !core
+
+package tracer
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/discovery"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/httpx"
+	"github.com/lf-edge/ekuiper/v2/pkg/cert"
+	"github.com/lf-edge/ekuiper/v2/pkg/syncx"
+)
+
+const meterName = "kuiperd-service"
+
+var (
+	meterProvider *sdkmetric.MeterProvider
+	meterMu       syncx.Mutex
+)
+
+// InitMeter sets up the OTel metrics pipeline. It shares the resource and the OTLP endpoint of the tracer unless
+// the metrics endpoint is specified. The pipeline is set up once, the change of tracer config takes effect after restart.
+func InitMeter() error {
+	c := conf.Config.OpenTelemetry.Metrics
+	if !c.Enable {
+		return nil
+	}
+	meterMu.Lock()
+	defer meterMu.Unlock()
+	if meterProvider != nil {
+		return nil
+	}
+	tracerConfig, err := loadTracerConfig()
+	if err != nil {
+		return err
+	}
+	endpoint := c.Endpoint
+	if endpoint == "" {
+		endpoint = tracerConfig.RemoteEndpoint
+	}
+	if discovery.IsDiscoverable(endpoint) {
+		r, err := discovery.NewResolver(endpoint, 0)
+		if err != nil {
+			return err
+		}
+		endpoint = r.Select()
+	}
+	opts := []otlpmetrichttp.Option{otlpmetrichttp.WithEndpoint(endpoint)}
+	tc, err := cert.GenTLSConfigForService(conf.Config.OpenTelemetry.RemoteTls)
+	if err != nil {
+		return err
+	}
+	proxy, err := httpx.ProxyFunc("")
+	if err != nil {
+		return err
+	}
+	if proxy != nil {
+		opts = append(opts, otlpmetrichttp.WithProxy(proxy))
+	}
+	if tc != nil {
+		opts = append(opts, otlpmetrichttp.WithTLSClientConfig(tc))
+	} else {
+		if err := cert.CheckPlaintext(endpoint); err != nil {
+			return err
+		}
+		opts = append(opts, otlpmetrichttp.WithInsecure())
+	}
+	exporter, err := otlpmetrichttp.New(context.Background(), opts...)
+	if err != nil {
+		return err
+	}
+	meterProvider = sdkmetric.NewMeterProvider(
+		sdkmetric.WithResource(newResource(tracerConfig.ServiceName)),
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exporter, sdkmetric.WithInterval(time.Duration(c.Interval)))),
+	)
+	otel.SetMeterProvider(meterProvider)
+	conf.Log.Infof("set meter success, serviceName:%v, endpoint:%v", tracerConfig.ServiceName, endpoint)
+	return nil
+}
+
+// StopMeter flushes the pending metrics and shuts down the metrics pipeline
+func StopMeter(ctx context.Context) {
+	meterMu.Lock()
+	defer meterMu.Unlock()
+	if meterProvider == nil {
+		return
+	}
+	if err := meterProvider.Shutdown(ctx); err != nil {
+		conf.Log.Warnf("shutdown meter provider err: %v", err)
+	}
+	meterProvider = nil
+}
+
+// Meter returns the meter to create the instruments. The instruments created before InitMeter are bound to
+// the pipeline once it is set up, and are no-op if the metrics are disabled.
+func Meter() metric.Meter {
+	return otel.Meter(meterName)
+}
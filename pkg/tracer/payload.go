@@ -0,0 +1,75 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build trace || !core
+
+package tracer
+
+import (
+	"sync/atomic"
+	"unicode/utf8"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// SamplePayload returns the payload sample attributes to record on the source or sink span of the op in the rule, or
+// nil if the rule has no payload sample setting or the message is skipped by the every setting. The payload is only
+// built once sampled. The redaction of the rule applies before the truncation so that a masked value won't be cut into
+// an unmasked prefix.
+func SamplePayload(ruleID, opID string, payload func() string) []attribute.KeyValue {
+	st := runtimeTraces.rule(ruleID)
+	if st == nil || st.conf.Payload == nil {
+		return nil
+	}
+	c := st.conf.Payload
+	if c.Every > 1 {
+		v, _ := st.payloadSeq.LoadOrStore(opID, new(atomic.Uint64))
+		if (v.(*atomic.Uint64).Add(1)-1)%uint64(c.Every) != 0 {
+			return nil
+		}
+	}
+	p := globalRedactor.policy(ruleID)
+	if p != nil && !p.keep(PayloadSampleKey) {
+		return nil
+	}
+	s := payload()
+	size := len(s)
+	if p != nil {
+		s = p.maskString(s)
+	}
+	maxBytes := c.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = DefaultPayloadSampleBytes
+	}
+	truncated := len(s) > maxBytes
+	if truncated {
+		s = truncateUTF8(s, maxBytes)
+	}
+	return []attribute.KeyValue{
+		attribute.String(PayloadSampleKey, s),
+		attribute.Int(PayloadSizeKey, size),
+		attribute.Bool(PayloadTruncatedKey, truncated),
+	}
+}
+
+// truncateUTF8 cuts the string to at most n bytes without splitting a rune
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
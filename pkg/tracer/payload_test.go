@@ -0,0 +1,95 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build trace || !core
+
+package tracer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/pkg/model"
+)
+
+func TestSamplePayload(t *testing.T) {
+	conf.IsTesting = true
+	conf.InitConf()
+	resetRuntimeTraces()
+	defer resetRuntimeTraces()
+	defer globalRedactor.state.Store(nil)
+	globalRedactor.update(nil)
+	built := 0
+	payload := func() string {
+		built++
+		return `{"token":"abc","name":"数据"}`
+	}
+	// no payload setting
+	require.NoError(t, SetRuleTrace("r1", &RuleTraceConf{Enabled: true}))
+	require.Nil(t, SamplePayload("r1", "src", payload))
+	require.Equal(t, 0, built)
+
+	require.EqualError(t, SetRuleTrace("r1", &RuleTraceConf{Enabled: true, Payload: &PayloadSampleConf{MaxBytes: -1}}), "payload maxBytes must be in [0, 65536]")
+	require.EqualError(t, SetRuleTrace("r1", &RuleTraceConf{Enabled: true, Payload: &PayloadSampleConf{Every: -1}}), "payload every must not be negative")
+	require.NoError(t, SetRuleTrace("r1", &RuleTraceConf{Enabled: true, Payload: &PayloadSampleConf{MaxBytes: 26, Every: 2}}))
+	// reload from the kv as after restart
+	resetRuntimeTraces()
+	c, err := GetRuleTrace("r1")
+	require.NoError(t, err)
+	require.Equal(t, &PayloadSampleConf{MaxBytes: 26, Every: 2}, c.Payload)
+
+	// the first of every two messages of each op, the multi-byte rune is not split
+	expected := []attribute.KeyValue{
+		attribute.String(PayloadSampleKey, `{"token":"abc","name":"数`),
+		attribute.Int(PayloadSizeKey, 31),
+		attribute.Bool(PayloadTruncatedKey, true),
+	}
+	require.Equal(t, expected, SamplePayload("r1", "src", payload))
+	require.Equal(t, expected, SamplePayload("r1", "sink", payload))
+	require.Nil(t, SamplePayload("r1", "src", payload))
+	require.Nil(t, SamplePayload("r1", "sink", payload))
+	require.Equal(t, expected, SamplePayload("r1", "src", payload))
+	require.Equal(t, 3, built)
+	require.Nil(t, SamplePayload("r2", "src", payload))
+
+	// the redaction masks before truncating, and the denied sample is not built
+	globalRedactor.update(map[string]*model.RedactionConf{
+		"r1": {Masks: []string{`"token":"[^"]*"`}},
+		"r2": {Deny: []string{PayloadSampleKey}},
+	})
+	require.NoError(t, SetRuleTrace("r1", &RuleTraceConf{Enabled: true, Payload: &PayloadSampleConf{}}))
+	require.NoError(t, SetRuleTrace("r2", &RuleTraceConf{Enabled: true, Payload: &PayloadSampleConf{}}))
+	require.Equal(t, []attribute.KeyValue{
+		attribute.String(PayloadSampleKey, `{******,"name":"数据"}`),
+		attribute.Int(PayloadSizeKey, 31),
+		attribute.Bool(PayloadTruncatedKey, false),
+	}, SamplePayload("r1", "src", payload))
+	built = 0
+	require.Nil(t, SamplePayload("r2", "src", payload))
+	require.Equal(t, 0, built)
+	require.NoError(t, SetRuleTrace("r1", nil))
+	require.NoError(t, SetRuleTrace("r2", nil))
+}
+
+func TestTruncateUTF8(t *testing.T) {
+	require.Equal(t, "abc", truncateUTF8("abc", 5))
+	require.Equal(t, "ab", truncateUTF8("abc", 2))
+	require.Equal(t, "a", truncateUTF8("a数", 3))
+	require.Equal(t, "a数", truncateUTF8("a数b", 4))
+	require.Equal(t, 3, len(truncateUTF8(strings.Repeat("数", 3), 5)))
+}
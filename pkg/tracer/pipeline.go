@@ -0,0 +1,191 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build trace || !core
+
+package tracer
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	"github.com/lf-edge/ekuiper/v2/pkg/model"
+)
+
+// spanPipeline processes the ended spans in the background so that the rules are not delayed by the tracing. The
+// spans are buffered in a bounded queue and processed in batches: counted by the span metrics, sent to the
+// subscribers and exported to the storage and the remote queue. The spans are dropped and counted once the queue is
+// full. The exporter is nil if the spans are not stored.
+type spanPipeline struct {
+	e         *SpanExporter
+	ch        chan sdktrace.ReadOnlySpan
+	batchSize int
+	flush     time.Duration
+	pending   atomic.Int64
+	stopped   atomic.Bool
+	flushCh   chan chan struct{}
+	cancel    context.CancelFunc
+	done      chan struct{}
+	dropWarn  float64
+}
+
+func newSpanPipeline(e *SpanExporter, c model.SpanPipelineConf) *spanPipeline {
+	return &spanPipeline{
+		e:         e,
+		ch:        make(chan sdktrace.ReadOnlySpan, max(c.QueueSize, 1)),
+		batchSize: max(c.BatchSize, 1),
+		flush:     max(time.Duration(c.FlushInterval), time.Millisecond),
+		flushCh:   make(chan chan struct{}),
+		done:      make(chan struct{}),
+		dropWarn:  c.DropWarnRatio,
+	}
+}
+
+func (p *spanPipeline) start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+	go p.run(ctx)
+}
+
+func (p *spanPipeline) OnStart(_ context.Context, s sdktrace.ReadWriteSpan) {
+	TraceExportCounter.WithLabelValues(LblStartedSpans).Inc()
+	rememberTrace(s)
+	trackSpan(s)
+}
+
+// OnEnd queues the span without blocking, the span is dropped if the queue is full
+func (p *spanPipeline) OnEnd(s sdktrace.ReadOnlySpan) {
+	untrackSpan(s)
+	if !s.SpanContext().IsSampled() {
+		return
+	}
+	globalStats.receive()
+	if p.stopped.Load() {
+		globalStats.drop(1)
+		return
+	}
+	select {
+	case p.ch <- s:
+		TraceExportGauge.WithLabelValues(LblQueueSpans).Set(float64(p.pending.Add(1)))
+	default:
+		globalStats.drop(1)
+	}
+}
+
+// ForceFlush processes the queued spans and waits for it
+func (p *spanPipeline) ForceFlush(ctx context.Context) error {
+	if p.stopped.Load() {
+		return nil
+	}
+	ack := make(chan struct{})
+	select {
+	case p.flushCh <- ack:
+	case <-p.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-ack:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Shutdown processes the remaining spans and waits for it until the context is done
+func (p *spanPipeline) Shutdown(ctx context.Context) error {
+	if !p.stopped.CompareAndSwap(false, true) {
+		return nil
+	}
+	if p.cancel == nil {
+		return nil
+	}
+	p.cancel()
+	select {
+	case <-p.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *spanPipeline) depth() int64 {
+	if p == nil {
+		return 0
+	}
+	return p.pending.Load()
+}
+
+func (p *spanPipeline) run(ctx context.Context) {
+	defer close(p.done)
+	ticker := getClock().Ticker(p.flush)
+	defer ticker.Stop()
+	batch := make([]sdktrace.ReadOnlySpan, 0, p.batchSize)
+	monitor := newDropRateMonitor(p.dropWarn, getClock().Now())
+	// drain processes all the queued spans
+	drain := func() {
+		for {
+			select {
+			case s := <-p.ch:
+				batch = append(batch, s)
+				if len(batch) >= p.batchSize {
+					batch = p.process(batch)
+				}
+			default:
+				batch = p.process(batch)
+				return
+			}
+		}
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			drain()
+			return
+		case ack := <-p.flushCh:
+			drain()
+			close(ack)
+		case s := <-p.ch:
+			batch = append(batch, s)
+			if len(batch) >= p.batchSize {
+				batch = p.process(batch)
+			}
+		case <-ticker.C:
+			batch = p.process(batch)
+			monitor.check(getClock().Now())
+		}
+	}
+}
+
+// process handles the batch and returns the empty batch to reuse
+func (p *spanPipeline) process(batch []sdktrace.ReadOnlySpan) []sdktrace.ReadOnlySpan {
+	if len(batch) == 0 {
+		return batch
+	}
+	TraceExportGauge.WithLabelValues(LblQueueSpans).Set(float64(p.pending.Add(-int64(len(batch)))))
+	for _, s := range batch {
+		globalSpanMetrics.record(s)
+		watchSpan(s)
+		publishSpan(s)
+	}
+	if p.e != nil {
+		_ = p.e.ExportSpans(context.Background(), batch)
+	}
+	clear(batch)
+	return batch[:0]
+}
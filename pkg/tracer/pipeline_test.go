@@ -0,0 +1,118 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build trace || !core
+
+package tracer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+	"github.com/lf-edge/ekuiper/v2/pkg/model"
+)
+
+// sampledSpans are the sampled spans of rule r1, each in its own trace
+func sampledSpans(n int) []sdktrace.ReadOnlySpan {
+	spans := testSpans(n)
+	stubs := make(tracetest.SpanStubs, 0, n)
+	for i, s := range spans {
+		stub := tracetest.SpanStubFromReadOnlySpan(s)
+		stub.SpanContext = trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID:    trace.TraceID{byte(i + 1)},
+			SpanID:     trace.SpanID{byte(i + 1)},
+			TraceFlags: trace.FlagsSampled,
+		})
+		stub.Parent = trace.SpanContext{}
+		stubs = append(stubs, stub)
+	}
+	return stubs.Snapshots()
+}
+
+func TestSpanPipeline(t *testing.T) {
+	conf.InitConf()
+	mc := clock.NewMock()
+	SetClock(mc)
+	defer SetClock(nil)
+	defer ResetSpanMetrics("")
+	ResetSpanMetrics("")
+	storage := newLocalSpanMemoryStorage(10)
+	e := &SpanExporter{spanStorage: storage}
+	p := newSpanPipeline(e, model.SpanPipelineConf{QueueSize: 10, BatchSize: 3, FlushInterval: cast.DurationConf(time.Minute)})
+	e.pipeline = p
+	ch, cancel, err := SubscribeSpans(SpanFilter{})
+	require.NoError(t, err)
+	defer cancel()
+	p.start()
+
+	spans := sampledSpans(4)
+	// the full batch is processed at once
+	for _, s := range spans[:3] {
+		p.OnEnd(s)
+	}
+	for i := 0; i < 3; i++ {
+		require.Equal(t, "r1", (<-ch).RuleID)
+	}
+	// the rest is processed by the flush interval
+	p.OnEnd(spans[3])
+	require.Eventually(t, func() bool { return p.depth() == 1 }, time.Second, time.Millisecond)
+	mc.Add(time.Minute)
+	<-ch
+	require.Eventually(t, func() bool { return p.depth() == 0 }, time.Second, time.Millisecond)
+	for i := range spans {
+		root, err := storage.GetTraceById(spans[i].SpanContext().TraceID().String())
+		require.NoError(t, err)
+		require.NotNil(t, root)
+	}
+	require.Equal(t, int64(4), GetSpanMetrics("r1")[0].Count)
+
+	// the unsampled spans are ignored
+	p.OnEnd(testSpans(1)[0])
+	require.Equal(t, int64(0), p.depth())
+
+	require.NoError(t, p.Shutdown(context.Background()))
+	require.NoError(t, p.ForceFlush(context.Background()))
+	// the spans after shutdown are dropped
+	p.OnEnd(spans[0])
+	require.Equal(t, int64(0), p.depth())
+}
+
+func TestSpanPipelineFull(t *testing.T) {
+	storage := newLocalSpanMemoryStorage(10)
+	e := &SpanExporter{spanStorage: storage}
+	p := newSpanPipeline(e, model.SpanPipelineConf{QueueSize: 2, BatchSize: 10, FlushInterval: cast.DurationConf(time.Hour)})
+	spans := sampledSpans(3)
+	// not started so that the queue is full
+	for _, s := range spans {
+		p.OnEnd(s)
+	}
+	require.Equal(t, int64(2), p.depth())
+
+	p.start()
+	require.NoError(t, p.ForceFlush(context.Background()))
+	require.Equal(t, int64(0), p.depth())
+	ids, err := storage.GetTraceByRuleID("r1", 0)
+	require.NoError(t, err)
+	require.Len(t, ids, 2)
+	require.NoError(t, p.Shutdown(context.Background()))
+}
@@ -0,0 +1,113 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Span is the span handle for the source/sink/function plugins. It is safe to call all the methods
+// even if tracing is disabled, they will do nothing.
+type Span struct {
+	span trace.Span
+}
+
+// ContextFromData returns the tracing context carried by the data received by the plugin such as the tuple of
+// the sink Collect. It returns nil if the rule is not traced or the data is not traced.
+func ContextFromData(ctx api.StreamContext, data any) context.Context {
+	if !ctx.IsTraceEnabled() || !IsTracingEnabled() {
+		return nil
+	}
+	holder, ok := data.(interface{ GetTracerCtx() api.StreamContext })
+	if !ok {
+		return nil
+	}
+	traceCtx := holder.GetTracerCtx()
+	if traceCtx == nil || !trace.SpanContextFromContext(traceCtx).IsValid() {
+		return nil
+	}
+	return traceCtx
+}
+
+// StartSpan starts a child span of the parent tracing context and returns the context of the new span
+// which can be used as the parent of the nested spans. If parent is nil, a no-op span is returned.
+func StartSpan(ctx api.StreamContext, parent context.Context, name string) (context.Context, *Span) {
+	if parent == nil {
+		return nil, &Span{span: trace.SpanFromContext(context.Background())}
+	}
+	t := GetTracer()
+	if t == nil {
+		return parent, &Span{span: trace.SpanFromContext(context.Background())}
+	}
+	spanCtx, span := t.Start(parent, name)
+	span.SetAttributes(attribute.String("rule", ctx.GetRuleId()))
+	return spanCtx, &Span{span: span}
+}
+
+// SetAttributes sets the attributes of the span. The values are recorded as their types if supported, otherwise as string.
+func (s *Span) SetAttributes(attrs map[string]any) {
+	s.span.SetAttributes(toAttributes(attrs)...)
+}
+
+// AddEvent records an event happened in the span
+func (s *Span) AddEvent(name string, attrs map[string]any) {
+	s.span.AddEvent(name, trace.WithAttributes(toAttributes(attrs)...))
+}
+
+// SetError records the error and marks the span as failed
+func (s *Span) SetError(err error) {
+	if err == nil {
+		return
+	}
+	s.span.RecordError(err)
+	s.span.SetStatus(codes.Error, err.Error())
+}
+
+// SetOK marks the span as succeeded
+func (s *Span) SetOK() {
+	s.span.SetStatus(codes.Ok, "")
+}
+
+// End completes the span. It must be called once the work is done.
+func (s *Span) End() {
+	s.span.End()
+}
+
+func toAttributes(attrs map[string]any) []attribute.KeyValue {
+	result := make([]attribute.KeyValue, 0, len(attrs))
+	for k, v := range attrs {
+		switch vt := v.(type) {
+		case string:
+			result = append(result, attribute.String(k, vt))
+		case bool:
+			result = append(result, attribute.Bool(k, vt))
+		case int:
+			result = append(result, attribute.Int(k, vt))
+		case int64:
+			result = append(result, attribute.Int64(k, vt))
+		case float64:
+			result = append(result, attribute.Float64(k, vt))
+		default:
+			result = append(result, attribute.String(k, fmt.Sprintf("%v", vt)))
+		}
+	}
+	return result
+}
@@ -0,0 +1,76 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build trace || !core
+
+package tracer
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+
+	mockContext "github.com/lf-edge/ekuiper/v2/pkg/mock/context"
+)
+
+type mockTracedData struct {
+	ctx api.StreamContext
+}
+
+func (m *mockTracedData) GetTracerCtx() api.StreamContext {
+	return m.ctx
+}
+
+func TestPluginSpan(t *testing.T) {
+	ctx := mockContext.NewMockContext("rule1", "op1")
+	// trace not enabled
+	require.Nil(t, ContextFromData(ctx, &mockTracedData{ctx: ctx}))
+	spanCtx, span := StartSpan(ctx, nil, "noop")
+	require.Nil(t, spanCtx)
+	span.SetAttributes(map[string]any{"a": 1})
+	span.AddEvent("e", nil)
+	span.SetError(errors.New("err"))
+	span.End()
+
+	ctx.EnableTracer(true)
+	require.Nil(t, ContextFromData(ctx, "not traced"))
+	require.Nil(t, ContextFromData(ctx, &mockTracedData{ctx: ctx}))
+	parentCtx, parent := GetTracer().Start(context.Background(), "parent")
+	defer parent.End()
+	data := &mockTracedData{ctx: mockContext.NewMockContext("rule1", "op1")}
+	require.Nil(t, ContextFromData(ctx, data))
+	traced := ContextFromData(ctx, &mockTracedData{ctx: wrapStreamContext(ctx, parentCtx)})
+	require.NotNil(t, traced)
+	childCtx, child := StartSpan(ctx, traced, "child")
+	defer child.End()
+	require.Equal(t, trace.SpanContextFromContext(parentCtx).TraceID(), trace.SpanContextFromContext(childCtx).TraceID())
+}
+
+// wrapStreamContext makes a stream context carrying the span of the parent context
+type wrappedStreamContext struct {
+	api.StreamContext
+	parent context.Context
+}
+
+func (w *wrappedStreamContext) Value(key any) any {
+	return w.parent.Value(key)
+}
+
+func wrapStreamContext(ctx api.StreamContext, parent context.Context) api.StreamContext {
+	return &wrappedStreamContext{StreamContext: ctx, parent: parent}
+}
@@ -0,0 +1,112 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/otel/propagation"
+)
+
+const (
+	TraceParentKey = "traceparent"
+	TraceStateKey  = "tracestate"
+	// MetaTraceParent and MetaTraceState are the source metadata keys of the upstream trace context. The source node
+	// starts the span as the child of the upstream span if found.
+	MetaTraceParent = "traceId"
+	MetaTraceState  = "traceState"
+)
+
+var w3cPropagator = propagation.TraceContext{}
+
+// MetaCarrier adapts the message metadata whose values may be string or bytes, such as the kafka headers
+type MetaCarrier map[string]any
+
+func (c MetaCarrier) Get(key string) string {
+	switch v := c[key].(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	}
+	return ""
+}
+
+func (c MetaCarrier) Set(key string, value string) {
+	c[key] = value
+}
+
+func (c MetaCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// ExtractRemote returns the context with the w3c traceparent and tracestate in the carrier as the remote parent.
+// The ctx is returned as is if the carrier has no valid traceparent.
+func ExtractRemote(ctx context.Context, carrier propagation.TextMapCarrier) context.Context {
+	return w3cPropagator.Extract(ctx, carrier)
+}
+
+// ExtractHeader extracts the upstream trace context from the http headers
+func ExtractHeader(ctx context.Context, h http.Header) context.Context {
+	return ExtractRemote(ctx, propagation.HeaderCarrier(h))
+}
+
+// ExtractProps extracts the upstream trace context from the string properties such as the mqtt user properties
+func ExtractProps(ctx context.Context, props map[string]string) context.Context {
+	return ExtractRemote(ctx, propagation.MapCarrier(props))
+}
+
+// CopyToMeta copies the traceparent and tracestate in the carrier to the source metadata. The meta is created if nil
+// and there is a traceparent to copy.
+func CopyToMeta(carrier propagation.TextMapCarrier, meta map[string]any) map[string]any {
+	tp := carrier.Get(TraceParentKey)
+	if tp == "" {
+		return meta
+	}
+	if meta == nil {
+		meta = make(map[string]any, 2)
+	}
+	meta[MetaTraceParent] = tp
+	if ts := carrier.Get(TraceStateKey); ts != "" {
+		meta[MetaTraceState] = ts
+	}
+	return meta
+}
+
+// Inject writes the traceparent and tracestate of the span in ctx to the carrier. Nothing is written if ctx has no
+// valid span. The sampled flag follows the local sampling decision.
+func Inject(ctx context.Context, carrier propagation.TextMapCarrier) {
+	w3cPropagator.Inject(ctx, carrier)
+}
+
+// InjectHeader writes the trace context of the span in ctx to the http headers
+func InjectHeader(ctx context.Context, h http.Header) {
+	Inject(ctx, propagation.HeaderCarrier(h))
+}
+
+// InjectProps writes the trace context of the span in ctx to the string properties, such as the mqtt user
+// properties or the kafka headers. The props is created if nil and returned.
+func InjectProps(ctx context.Context, props map[string]string) map[string]string {
+	if props == nil {
+		props = make(map[string]string, 2)
+	}
+	Inject(ctx, propagation.MapCarrier(props))
+	return props
+}
@@ -0,0 +1,63 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	testParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	testState  = "congo=t61rcWkgMzE"
+)
+
+func TestExtractInject(t *testing.T) {
+	ctx := ExtractProps(context.Background(), map[string]string{TraceParentKey: testParent, TraceStateKey: testState})
+	sc := trace.SpanContextFromContext(ctx)
+	require.True(t, sc.IsValid())
+	require.True(t, sc.IsRemote())
+	require.True(t, sc.IsSampled())
+	require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID().String())
+	require.Equal(t, "00f067aa0ba902b7", sc.SpanID().String())
+	require.Equal(t, testState, sc.TraceState().String())
+
+	props := InjectProps(ctx, nil)
+	require.Equal(t, map[string]string{TraceParentKey: testParent, TraceStateKey: testState}, props)
+	h := http.Header{}
+	InjectHeader(ctx, h)
+	require.Equal(t, testParent, h.Get(TraceParentKey))
+	sc2 := trace.SpanContextFromContext(ExtractHeader(context.Background(), h))
+	require.True(t, sc.Equal(sc2))
+
+	// no valid trace context
+	bg := context.Background()
+	require.Equal(t, bg, ExtractProps(bg, map[string]string{TraceParentKey: "invalid"}))
+	require.Empty(t, InjectProps(bg, nil))
+}
+
+func TestCopyToMeta(t *testing.T) {
+	carrier := MetaCarrier{TraceParentKey: []byte(testParent), TraceStateKey: testState, "other": 1}
+	require.ElementsMatch(t, []string{TraceParentKey, TraceStateKey, "other"}, carrier.Keys())
+	meta := CopyToMeta(carrier, nil)
+	require.Equal(t, map[string]any{MetaTraceParent: testParent, MetaTraceState: testState}, meta)
+	meta = map[string]any{"topic": "a"}
+	require.Equal(t, map[string]any{"topic": "a", MetaTraceParent: testParent}, CopyToMeta(MetaCarrier{TraceParentKey: testParent}, meta))
+	require.Nil(t, CopyToMeta(MetaCarrier{"other": "a"}, nil))
+}
@@ -0,0 +1,411 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build trace || !core
+
+package tracer
+
+import (
+	"context"
+	"net"
+	"slices"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/grpc"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+	"github.com/lf-edge/ekuiper/v2/pkg/syncx"
+)
+
+// otlpReceiver accepts the spans from portable plugins and co-located agents by OTLP/gRPC.
+// The received spans go through the same exporter as the spans produced by kuiper.
+type otlpReceiver struct {
+	coltracepb.UnimplementedTraceServiceServer
+	server *grpc.Server
+}
+
+var (
+	receiverMu     syncx.Mutex
+	globalReceiver *otlpReceiver
+)
+
+// StartOtlpReceiver listens on the address for OTLP/gRPC trace export requests.
+func StartOtlpReceiver(addr string) error {
+	receiverMu.Lock()
+	defer receiverMu.Unlock()
+	if globalReceiver != nil {
+		return errorx.NewWithCode(errorx.TracerErr, "otlp receiver is already started")
+	}
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	r := &otlpReceiver{server: grpc.NewServer()}
+	coltracepb.RegisterTraceServiceServer(r.server, r)
+	globalReceiver = r
+	go func() {
+		if err := r.server.Serve(lis); err != nil {
+			conf.Log.Errorf("otlp receiver stopped with error: %v", err)
+		}
+	}()
+	conf.Log.Infof("otlp receiver listening on %s", lis.Addr().String())
+	return nil
+}
+
+// StopOtlpReceiver stops the gRPC and the HTTP receivers if started
+func StopOtlpReceiver() {
+	receiverMu.Lock()
+	defer receiverMu.Unlock()
+	if globalReceiver != nil {
+		globalReceiver.server.GracefulStop()
+		globalReceiver = nil
+	}
+	stopOtlpHttpReceiver()
+}
+
+func (r *otlpReceiver) Export(ctx context.Context, req *coltracepb.ExportTraceServiceRequest) (*coltracepb.ExportTraceServiceResponse, error) {
+	return receiveSpans(ctx, req)
+}
+
+// receiveSpans converts the spans of the OTLP request and feeds them into the span pipeline
+func receiveSpans(ctx context.Context, req *coltracepb.ExportTraceServiceRequest) (*coltracepb.ExportTraceServiceResponse, error) {
+	spans, rejected := fromOtlpSpans(req.GetResourceSpans())
+	if err := globalTracerManager.exportReceived(ctx, spans); err != nil {
+		return nil, err
+	}
+	resp := &coltracepb.ExportTraceServiceResponse{}
+	if rejected > 0 {
+		resp.PartialSuccess = &coltracepb.ExportTracePartialSuccess{
+			RejectedSpans: rejected,
+			ErrorMessage:  "invalid trace id or span id",
+		}
+	}
+	return resp, nil
+}
+
+// exportReceived merges the received spans into the rule traces and queues them into the span pipeline, so that
+// they are counted, watched by the alerts, sent to the subscribers, stored and exported like the spans of kuiper
+func (g *GlobalTracerManager) exportReceived(_ context.Context, spans []sdktrace.ReadOnlySpan) error {
+	g.RLock()
+	defer g.RUnlock()
+	if g.pipeline == nil || len(spans) == 0 {
+		return nil
+	}
+	rules := make(map[trace.TraceID]string)
+	for i, span := range spans {
+		if rule := spanRule(span.Attributes()); rule != "" {
+			// the later spans of the trace may be received before this one is stored
+			recentTraceRules.put(span.SpanContext().TraceID(), rule)
+			continue
+		}
+		tid := span.SpanContext().TraceID()
+		rule, ok := rules[tid]
+		if !ok {
+			// inherit the rule of the trace which the sidecar span joins. The spans of the trace may be still running
+			// or queued, so the recently started traces are looked up before the storage.
+			rule = recentTraceRules.get(tid)
+			if rule == "" && g.SpanExporter != nil {
+				if root, err := g.SpanExporter.GetTraceById(tid.String()); err == nil && root != nil {
+					rule = root.RuleID
+				}
+			}
+			rules[tid] = rule
+		}
+		if rule != "" {
+			attrs := append(slices.Clone(span.Attributes()), attribute.String(ruleAttributeKey, rule))
+			spans[i] = stitchedSpan{ReadOnlySpan: span, attrs: attrs}
+		}
+	}
+	for _, span := range spans {
+		g.pipeline.OnEnd(span)
+	}
+	return nil
+}
+
+// traceRuleCapacity bounds the recently started traces to stitch the received spans. The oldest trace is evicted
+// once exceeded.
+const traceRuleCapacity = 4096
+
+// traceRules remembers the rule of the recently started traces
+type traceRules struct {
+	mu    syncx.Mutex
+	rules map[trace.TraceID]string
+	// order is the ring of the trace ids by the insertion order
+	order []trace.TraceID
+	head  int
+}
+
+var recentTraceRules = newTraceRules(traceRuleCapacity)
+
+func newTraceRules(capacity int) *traceRules {
+	return &traceRules{rules: make(map[trace.TraceID]string), order: make([]trace.TraceID, 0, capacity)}
+}
+
+func (t *traceRules) put(tid trace.TraceID, rule string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.rules[tid]; ok {
+		return
+	}
+	if len(t.order) < cap(t.order) {
+		t.order = append(t.order, tid)
+	} else {
+		delete(t.rules, t.order[t.head])
+		t.order[t.head] = tid
+		t.head = (t.head + 1) % len(t.order)
+	}
+	t.rules[tid] = rule
+}
+
+func (t *traceRules) get(tid trace.TraceID) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.rules[tid]
+}
+
+// rememberTrace records the rule of the trace once its first local span starts
+func rememberTrace(s sdktrace.ReadWriteSpan) {
+	if p := s.Parent(); p.IsValid() && !p.IsRemote() {
+		return
+	}
+	if rule := spanRule(s.Attributes()); rule != "" {
+		recentTraceRules.put(s.SpanContext().TraceID(), rule)
+	}
+}
+
+// fromOtlpSpans converts the OTLP spans. Spans with invalid ids are rejected.
+func fromOtlpSpans(resourceSpans []*tracepb.ResourceSpans) ([]sdktrace.ReadOnlySpan, int64) {
+	var (
+		result   []sdktrace.ReadOnlySpan
+		rejected int64
+	)
+	for _, rs := range resourceSpans {
+		res := resource.NewSchemaless(toAttributeList(rs.GetResource().GetAttributes())...)
+		for _, ss := range rs.GetScopeSpans() {
+			scope := instrumentation.Scope{Name: ss.GetScope().GetName(), Version: ss.GetScope().GetVersion()}
+			for _, s := range ss.GetSpans() {
+				span, ok := fromOtlpSpan(s)
+				if !ok {
+					rejected++
+					continue
+				}
+				span.resource = res
+				span.scope = scope
+				result = append(result, span)
+			}
+		}
+	}
+	return result, rejected
+}
+
+// stitchedSpan is the received span with the rule attribute of the trace it joins
+type stitchedSpan struct {
+	sdktrace.ReadOnlySpan
+	attrs []attribute.KeyValue
+}
+
+func (s stitchedSpan) Attributes() []attribute.KeyValue {
+	return s.attrs
+}
+
+// receivedSpan is the span converted from OTLP. The embedded interface is always nil and only satisfies the
+// unexported method of the sdk, all the exported methods are implemented by the fields.
+type receivedSpan struct {
+	sdktrace.ReadOnlySpan
+	name     string
+	sc       trace.SpanContext
+	parent   trace.SpanContext
+	kind     trace.SpanKind
+	start    time.Time
+	end      time.Time
+	attrs    []attribute.KeyValue
+	links    []sdktrace.Link
+	events   []sdktrace.Event
+	status   sdktrace.Status
+	scope    instrumentation.Scope
+	resource *resource.Resource
+}
+
+func (s *receivedSpan) Name() string {
+	return s.name
+}
+
+func (s *receivedSpan) SpanContext() trace.SpanContext {
+	return s.sc
+}
+
+func (s *receivedSpan) Parent() trace.SpanContext {
+	return s.parent
+}
+
+func (s *receivedSpan) SpanKind() trace.SpanKind {
+	return s.kind
+}
+
+func (s *receivedSpan) StartTime() time.Time {
+	return s.start
+}
+
+func (s *receivedSpan) EndTime() time.Time {
+	return s.end
+}
+
+func (s *receivedSpan) Attributes() []attribute.KeyValue {
+	return s.attrs
+}
+
+func (s *receivedSpan) Links() []sdktrace.Link {
+	return s.links
+}
+
+func (s *receivedSpan) Events() []sdktrace.Event {
+	return s.events
+}
+
+func (s *receivedSpan) Status() sdktrace.Status {
+	return s.status
+}
+
+func (s *receivedSpan) InstrumentationScope() instrumentation.Scope {
+	return s.scope
+}
+
+//nolint:staticcheck
+func (s *receivedSpan) InstrumentationLibrary() instrumentation.Library {
+	return s.scope
+}
+
+func (s *receivedSpan) Resource() *resource.Resource {
+	return s.resource
+}
+
+func (s *receivedSpan) DroppedAttributes() int {
+	return 0
+}
+
+func (s *receivedSpan) DroppedLinks() int {
+	return 0
+}
+
+func (s *receivedSpan) DroppedEvents() int {
+	return 0
+}
+
+func (s *receivedSpan) ChildSpanCount() int {
+	return 0
+}
+
+func fromOtlpSpan(s *tracepb.Span) (*receivedSpan, bool) {
+	sc, ok := toSpanContext(s.GetTraceId(), s.GetSpanId(), s.GetTraceState())
+	if !ok {
+		return nil, false
+	}
+	span := &receivedSpan{
+		name:  s.GetName(),
+		sc:    sc,
+		kind:  trace.SpanKind(s.GetKind()),
+		start: time.Unix(0, int64(s.GetStartTimeUnixNano())),
+		end:   time.Unix(0, int64(s.GetEndTimeUnixNano())),
+		attrs: toAttributeList(s.GetAttributes()),
+	}
+	if parent, ok := toSpanContext(s.GetTraceId(), s.GetParentSpanId(), ""); ok {
+		span.parent = parent
+	}
+	for _, e := range s.GetEvents() {
+		span.events = append(span.events, sdktrace.Event{
+			Name:       e.GetName(),
+			Time:       time.Unix(0, int64(e.GetTimeUnixNano())),
+			Attributes: toAttributeList(e.GetAttributes()),
+		})
+	}
+	for _, l := range s.GetLinks() {
+		lsc, ok := toSpanContext(l.GetTraceId(), l.GetSpanId(), l.GetTraceState())
+		if !ok {
+			continue
+		}
+		span.links = append(span.links, sdktrace.Link{SpanContext: lsc, Attributes: toAttributeList(l.GetAttributes())})
+	}
+	switch s.GetStatus().GetCode() {
+	case tracepb.Status_STATUS_CODE_OK:
+		span.status = sdktrace.Status{Code: codes.Ok}
+	case tracepb.Status_STATUS_CODE_ERROR:
+		span.status = sdktrace.Status{Code: codes.Error, Description: s.GetStatus().GetMessage()}
+	}
+	return span, true
+}
+
+func toSpanContext(traceID, spanID []byte, traceState string) (trace.SpanContext, bool) {
+	var (
+		tid trace.TraceID
+		sid trace.SpanID
+	)
+	if len(traceID) != len(tid) || len(spanID) != len(sid) {
+		return trace.SpanContext{}, false
+	}
+	copy(tid[:], traceID)
+	copy(sid[:], spanID)
+	cfg := trace.SpanContextConfig{TraceID: tid, SpanID: sid, TraceFlags: trace.FlagsSampled, Remote: true}
+	if traceState != "" {
+		if ts, err := trace.ParseTraceState(traceState); err == nil {
+			cfg.TraceState = ts
+		}
+	}
+	sc := trace.NewSpanContext(cfg)
+	return sc, sc.IsValid()
+}
+
+func toAttributeList(kvs []*commonpb.KeyValue) []attribute.KeyValue {
+	if len(kvs) == 0 {
+		return nil
+	}
+	r := make([]attribute.KeyValue, 0, len(kvs))
+	for _, kv := range kvs {
+		r = append(r, toAttribute(kv.GetKey(), kv.GetValue()))
+	}
+	return r
+}
+
+func toAttribute(key string, v *commonpb.AnyValue) attribute.KeyValue {
+	switch val := v.GetValue().(type) {
+	case *commonpb.AnyValue_StringValue:
+		return attribute.String(key, val.StringValue)
+	case *commonpb.AnyValue_BoolValue:
+		return attribute.Bool(key, val.BoolValue)
+	case *commonpb.AnyValue_IntValue:
+		return attribute.Int64(key, val.IntValue)
+	case *commonpb.AnyValue_DoubleValue:
+		return attribute.Float64(key, val.DoubleValue)
+	case *commonpb.AnyValue_BytesValue:
+		return attribute.String(key, string(val.BytesValue))
+	case *commonpb.AnyValue_ArrayValue:
+		values := make([]string, 0, len(val.ArrayValue.GetValues()))
+		for _, e := range val.ArrayValue.GetValues() {
+			values = append(values, toAttribute(key, e).Value.Emit())
+		}
+		return attribute.StringSlice(key, values)
+	default:
+		// nested key-value lists are not supported by the attribute model, keep the text form
+		return attribute.String(key, v.String())
+	}
+}
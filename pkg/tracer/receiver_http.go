@@ -0,0 +1,208 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build trace || !core
+
+package tracer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"time"
+
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+)
+
+const (
+	otlpHttpPath = "/v1/traces"
+	// otlpHttpMaxBody bounds the decompressed body of an export request
+	otlpHttpMaxBody = 16 << 20
+
+	contentTypeProtobuf = "application/x-protobuf"
+	contentTypeJSON     = "application/json"
+)
+
+// otlpHttpReceiver accepts the spans from portable plugins and co-located agents by OTLP/HTTP with the protobuf or
+// the json encoding. The spans are handled the same as the OTLP/gRPC receiver.
+type otlpHttpReceiver struct {
+	server *http.Server
+}
+
+var globalHttpReceiver *otlpHttpReceiver
+
+// StartOtlpHttpReceiver listens on the address for OTLP/HTTP trace export requests to /v1/traces
+func StartOtlpHttpReceiver(addr string) error {
+	receiverMu.Lock()
+	defer receiverMu.Unlock()
+	if globalHttpReceiver != nil {
+		return errorx.NewWithCode(errorx.TracerErr, "otlp http receiver is already started")
+	}
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(otlpHttpPath, handleOtlpHttp)
+	r := &otlpHttpReceiver{server: &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}}
+	globalHttpReceiver = r
+	go func() {
+		if err := r.server.Serve(lis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			conf.Log.Errorf("otlp http receiver stopped with error: %v", err)
+		}
+	}()
+	conf.Log.Infof("otlp http receiver listening on %s", lis.Addr().String())
+	return nil
+}
+
+func stopOtlpHttpReceiver() {
+	if globalHttpReceiver == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := globalHttpReceiver.server.Shutdown(ctx); err != nil {
+		conf.Log.Warnf("stop otlp http receiver error: %v", err)
+	}
+	globalHttpReceiver = nil
+}
+
+func handleOtlpHttp(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	ct, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	var (
+		unmarshal func([]byte, proto.Message) error
+		marshal   func(proto.Message) ([]byte, error)
+	)
+	switch ct {
+	case contentTypeProtobuf:
+		unmarshal, marshal = proto.Unmarshal, proto.Marshal
+	case contentTypeJSON:
+		unmarshal, marshal = unmarshalOtlpJSON, protojson.Marshal
+	default:
+		http.Error(w, fmt.Sprintf("unsupported content type %s", ct), http.StatusUnsupportedMediaType)
+		return
+	}
+	body, err := readOtlpBody(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req := &coltracepb.ExportTraceServiceRequest{}
+	if err := unmarshal(body, req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid export request: %v", err), http.StatusBadRequest)
+		return
+	}
+	resp, err := receiveSpans(r.Context(), req)
+	if err != nil {
+		// the status is encoded the same as the body so that the otlp clients can parse it
+		b, _ := marshal(status.Convert(err).Proto())
+		w.Header().Set("Content-Type", ct)
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write(b)
+		return
+	}
+	b, err := marshal(resp)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", ct)
+	_, _ = w.Write(b)
+}
+
+// unmarshalOtlpJSON decodes the OTLP/JSON request. The trace and span ids are hex encoded by OTLP/JSON instead of
+// base64 of the protobuf json mapping, so they are converted first.
+func unmarshalOtlpJSON(b []byte, m proto.Message) error {
+	var v any
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	if err := d.Decode(&v); err != nil {
+		return err
+	}
+	hexToBase64IDs(v)
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return protojson.Unmarshal(b, m)
+}
+
+var otlpIDKeys = map[string]struct{}{
+	"traceId": {}, "trace_id": {}, "spanId": {}, "span_id": {}, "parentSpanId": {}, "parent_span_id": {},
+}
+
+func hexToBase64IDs(v any) {
+	switch val := v.(type) {
+	case map[string]any:
+		for k, e := range val {
+			if _, ok := otlpIDKeys[k]; ok {
+				if s, ok := e.(string); ok && (len(s) == 32 || len(s) == 16) {
+					if id, err := hex.DecodeString(s); err == nil {
+						val[k] = base64.StdEncoding.EncodeToString(id)
+					}
+				}
+				continue
+			}
+			hexToBase64IDs(e)
+		}
+	case []any:
+		for _, e := range val {
+			hexToBase64IDs(e)
+		}
+	}
+}
+
+func readOtlpBody(r *http.Request) ([]byte, error) {
+	var body io.Reader = r.Body
+	switch r.Header.Get("Content-Encoding") {
+	case "", "identity":
+	case "gzip":
+		gr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip body: %v", err)
+		}
+		defer gr.Close()
+		body = gr
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %s", r.Header.Get("Content-Encoding"))
+	}
+	b, err := io.ReadAll(io.LimitReader(body, otlpHttpMaxBody+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(b) > otlpHttpMaxBody {
+		return nil, fmt.Errorf("export request exceeds %d bytes", otlpHttpMaxBody)
+	}
+	return b, nil
+}
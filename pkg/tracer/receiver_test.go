@@ -0,0 +1,226 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build trace || !core
+
+package tracer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+)
+
+func TestReceiverExport(t *testing.T) {
+	conf.InitConf()
+	g := &GlobalTracerManager{}
+	require.NoError(t, g.SetTracer(false, "test", ""))
+	old := globalTracerManager
+	globalTracerManager = g
+	defer func() {
+		globalTracerManager = old
+	}()
+	traceID := []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+	// the root span produced by the rule
+	require.NoError(t, g.SpanExporter.spanStorage.(*LocalSpanMemoryStorage).saveSpan(&LocalSpan{
+		TraceID: "0102030405060708090a0b0c0d0e0f10",
+		SpanID:  "0000000000000001",
+		RuleID:  "rule1",
+	}))
+	req := &coltracepb.ExportTraceServiceRequest{
+		ResourceSpans: []*tracepb.ResourceSpans{{
+			Resource: &resourcepb.Resource{Attributes: []*commonpb.KeyValue{{Key: "service.name", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "sidecar"}}}}},
+			ScopeSpans: []*tracepb.ScopeSpans{{
+				Spans: []*tracepb.Span{
+					{
+						TraceId:           traceID,
+						SpanId:            []byte{0, 0, 0, 0, 0, 0, 0, 2},
+						ParentSpanId:      []byte{0, 0, 0, 0, 0, 0, 0, 1},
+						Name:              "sidecar_process",
+						StartTimeUnixNano: 1000,
+						EndTimeUnixNano:   2000,
+						Attributes:        []*commonpb.KeyValue{{Key: "count", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: 3}}}},
+						Status:            &tracepb.Status{Code: tracepb.Status_STATUS_CODE_ERROR, Message: "failed"},
+					},
+					{
+						TraceId: []byte{1, 2},
+						SpanId:  []byte{0, 0, 0, 0, 0, 0, 0, 3},
+						Name:    "invalid",
+					},
+				},
+			}},
+		}},
+	}
+	resp, err := (&otlpReceiver{}).Export(context.Background(), req)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), resp.GetPartialSuccess().GetRejectedSpans())
+	require.NoError(t, g.pipeline.ForceFlush(context.Background()))
+	root, err := g.GetTraceById("0102030405060708090a0b0c0d0e0f10")
+	require.NoError(t, err)
+	require.Len(t, root.ChildSpan, 1)
+	child := root.ChildSpan[0]
+	require.Equal(t, "sidecar_process", child.Name)
+	require.Equal(t, "rule1", child.RuleID)
+	require.Equal(t, int64(3), child.Attribute["count"])
+}
+
+func TestReceiverStitchRunningTrace(t *testing.T) {
+	conf.InitConf()
+	g := &GlobalTracerManager{}
+	require.NoError(t, g.SetTracer(false, "test", ""))
+	old := globalTracerManager
+	globalTracerManager = g
+	defer func() {
+		globalTracerManager = old
+	}()
+	// the rule span is still running so that it is not stored
+	tr := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(g.pipeline)).Tracer("test")
+	ctx, span := tr.Start(context.Background(), "rule_op", trace.WithAttributes(attribute.String(ruleAttributeKey, "rule2")))
+	sc := trace.SpanContextFromContext(ctx)
+	tid, sid := sc.TraceID(), sc.SpanID()
+	req := &coltracepb.ExportTraceServiceRequest{
+		ResourceSpans: []*tracepb.ResourceSpans{{
+			ScopeSpans: []*tracepb.ScopeSpans{{
+				Spans: []*tracepb.Span{{
+					TraceId:           tid[:],
+					SpanId:            []byte{0, 0, 0, 0, 0, 0, 0, 9},
+					ParentSpanId:      sid[:],
+					Name:              "plugin_func",
+					StartTimeUnixNano: 1000,
+					EndTimeUnixNano:   2000,
+				}},
+			}},
+		}},
+	}
+	_, err := receiveSpans(context.Background(), req)
+	require.NoError(t, err)
+	span.End()
+	require.NoError(t, g.pipeline.ForceFlush(context.Background()))
+	root, err := g.GetTraceById(tid.String())
+	require.NoError(t, err)
+	require.Equal(t, "rule2", root.RuleID)
+	require.Len(t, root.ChildSpan, 1)
+	require.Equal(t, "plugin_func", root.ChildSpan[0].Name)
+	require.Equal(t, "rule2", root.ChildSpan[0].RuleID)
+}
+
+func TestTraceRules(t *testing.T) {
+	r := newTraceRules(2)
+	r.put(trace.TraceID{1}, "r1")
+	r.put(trace.TraceID{2}, "r2")
+	r.put(trace.TraceID{1}, "r3")
+	require.Equal(t, "r1", r.get(trace.TraceID{1}))
+	// the oldest is evicted
+	r.put(trace.TraceID{3}, "r3")
+	require.Equal(t, "", r.get(trace.TraceID{1}))
+	require.Equal(t, "r2", r.get(trace.TraceID{2}))
+	require.Equal(t, "r3", r.get(trace.TraceID{3}))
+}
+
+func TestOtlpHttpReceiver(t *testing.T) {
+	conf.InitConf()
+	g := &GlobalTracerManager{}
+	require.NoError(t, g.SetTracer(false, "test", ""))
+	old := globalTracerManager
+	globalTracerManager = g
+	defer func() {
+		globalTracerManager = old
+	}()
+	req := &coltracepb.ExportTraceServiceRequest{
+		ResourceSpans: []*tracepb.ResourceSpans{{
+			ScopeSpans: []*tracepb.ScopeSpans{{
+				Spans: []*tracepb.Span{
+					{
+						TraceId:    []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 17},
+						SpanId:     []byte{0, 0, 0, 0, 0, 0, 0, 1},
+						Name:       "plugin_root",
+						Attributes: []*commonpb.KeyValue{{Key: "rule", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "rule3"}}}},
+					},
+					{TraceId: []byte{1}, SpanId: []byte{2}, Name: "invalid"},
+				},
+			}},
+		}},
+	}
+	srv := httptest.NewServer(http.HandlerFunc(handleOtlpHttp))
+	defer srv.Close()
+
+	// gzip compressed protobuf
+	pb, err := proto.Marshal(req)
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, err = zw.Write(pb)
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	hr, err := http.NewRequest(http.MethodPost, srv.URL+otlpHttpPath, &buf)
+	require.NoError(t, err)
+	hr.Header.Set("Content-Type", contentTypeProtobuf)
+	hr.Header.Set("Content-Encoding", "gzip")
+	resp, err := http.DefaultClient.Do(hr)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var body bytes.Buffer
+	_, err = body.ReadFrom(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	result := &coltracepb.ExportTraceServiceResponse{}
+	require.NoError(t, proto.Unmarshal(body.Bytes(), result))
+	require.Equal(t, int64(1), result.GetPartialSuccess().GetRejectedSpans())
+
+	// json with the hex ids
+	jb := []byte(`{"resourceSpans":[{"scopeSpans":[{"spans":[{"traceId":"0102030405060708090a0b0c0d0e0f11","spanId":"0000000000000002","parentSpanId":"0000000000000001","name":"plugin_child","startTimeUnixNano":"1000","endTimeUnixNano":"2000"}]}]}]}`)
+	resp, err = http.Post(srv.URL+otlpHttpPath, "application/json; charset=utf-8", bytes.NewReader(jb))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, contentTypeJSON, resp.Header.Get("Content-Type"))
+
+	require.NoError(t, g.pipeline.ForceFlush(context.Background()))
+	root, err := g.GetTraceById("0102030405060708090a0b0c0d0e0f11")
+	require.NoError(t, err)
+	require.Equal(t, "plugin_root", root.Name)
+	require.Equal(t, "rule3", root.RuleID)
+	require.Len(t, root.ChildSpan, 1)
+	require.Equal(t, "plugin_child", root.ChildSpan[0].Name)
+	require.Equal(t, "rule3", root.ChildSpan[0].RuleID)
+
+	// unsupported requests
+	resp, err = http.Post(srv.URL+otlpHttpPath, "text/plain", bytes.NewReader(jb))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusUnsupportedMediaType, resp.StatusCode)
+	resp, err = http.Post(srv.URL+otlpHttpPath, contentTypeProtobuf, bytes.NewReader([]byte("invalid")))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	resp, err = http.Get(srv.URL + otlpHttpPath)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
@@ -0,0 +1,241 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build trace || !core
+
+package tracer
+
+import (
+	"regexp"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/pkg/model"
+)
+
+const (
+	redactionDefault = "default"
+	redactedValue    = "******"
+)
+
+var globalRedactor = &redactor{}
+
+// redactor removes or masks the sensitive span attributes by the redaction config so that they never reach the local
+// storage, the subscribers or the remote collector. The config is replaced at runtime by the reload.
+type redactor struct {
+	state atomic.Pointer[redactState]
+}
+
+type redactState struct {
+	def   *redactPolicy
+	rules map[string]*redactPolicy
+}
+
+type redactPolicy struct {
+	allow map[string]struct{}
+	deny  map[string]struct{}
+	masks []*regexp.Regexp
+}
+
+func (r *redactor) update(c map[string]*model.RedactionConf) {
+	st := &redactState{rules: make(map[string]*redactPolicy, len(c))}
+	def := c[redactionDefault]
+	if def != nil {
+		st.def = newRedactPolicy(nil, def)
+	}
+	for k, v := range c {
+		if k != redactionDefault && v != nil {
+			st.rules[k] = newRedactPolicy(def, v)
+		}
+	}
+	r.state.Store(st)
+}
+
+// newRedactPolicy merges the rule config into the default. The denied keys and the masks of both apply, the allowed
+// keys of the rule replace the default ones.
+func newRedactPolicy(def, c *model.RedactionConf) *redactPolicy {
+	p := &redactPolicy{}
+	allow := c.Allow
+	var deny, masks []string
+	if def != nil {
+		if len(allow) == 0 {
+			allow = def.Allow
+		}
+		deny = append(deny, def.Deny...)
+		masks = append(masks, def.Masks...)
+	}
+	deny = append(deny, c.Deny...)
+	masks = append(masks, c.Masks...)
+	if len(allow) > 0 {
+		p.allow = make(map[string]struct{}, len(allow))
+		for _, k := range allow {
+			p.allow[k] = struct{}{}
+		}
+	}
+	if len(deny) > 0 {
+		p.deny = make(map[string]struct{}, len(deny))
+		for _, k := range deny {
+			p.deny[k] = struct{}{}
+		}
+	}
+	for _, m := range masks {
+		// the invalid masks are removed by the config validation
+		if re, err := regexp.Compile(m); err == nil {
+			p.masks = append(p.masks, re)
+		}
+	}
+	return p
+}
+
+// policy returns the redaction of the rule, or nil if nothing to redact
+func (r *redactor) policy(rule string) *redactPolicy {
+	st := r.state.Load()
+	if st == nil {
+		if conf.Config == nil {
+			return nil
+		}
+		r.update(conf.Config.OpenTelemetry.Redaction)
+		st = r.state.Load()
+	}
+	if p, ok := st.rules[rule]; ok {
+		return p
+	}
+	return st.def
+}
+
+func (p *redactPolicy) keep(key string) bool {
+	// the rule attribute identifies the span so that it is always kept
+	if key == ruleAttributeKey {
+		return true
+	}
+	if _, ok := p.deny[key]; ok {
+		return false
+	}
+	if p.allow != nil {
+		_, ok := p.allow[key]
+		return ok
+	}
+	return true
+}
+
+func (p *redactPolicy) mask(v attribute.Value) attribute.Value {
+	if len(p.masks) == 0 {
+		return v
+	}
+	switch v.Type() {
+	case attribute.STRING:
+		return attribute.StringValue(p.maskString(v.AsString()))
+	case attribute.STRINGSLICE:
+		ss := v.AsStringSlice()
+		for i, s := range ss {
+			ss[i] = p.maskString(s)
+		}
+		return attribute.StringSliceValue(ss)
+	default:
+		return v
+	}
+}
+
+func (p *redactPolicy) maskString(s string) string {
+	for _, re := range p.masks {
+		s = re.ReplaceAllLiteralString(s, redactedValue)
+	}
+	return s
+}
+
+// redact returns the kept attributes with the masked values. The input is not changed.
+func (p *redactPolicy) redact(attrs []attribute.KeyValue) []attribute.KeyValue {
+	if p == nil || len(attrs) == 0 {
+		return attrs
+	}
+	result := make([]attribute.KeyValue, 0, len(attrs))
+	for _, attr := range attrs {
+		if !p.keep(string(attr.Key)) {
+			continue
+		}
+		result = append(result, attribute.KeyValue{Key: attr.Key, Value: p.mask(attr.Value)})
+	}
+	return result
+}
+
+func spanRule(attrs []attribute.KeyValue) string {
+	for _, attr := range attrs {
+		if attr.Key == ruleAttributeKey {
+			return attr.Value.AsString()
+		}
+	}
+	return ""
+}
+
+// redactedSpan is the span with the redacted attributes, events and links for the remote exporters
+type redactedSpan struct {
+	sdktrace.ReadOnlySpan
+	attrs  []attribute.KeyValue
+	events []sdktrace.Event
+	links  []sdktrace.Link
+}
+
+func (s redactedSpan) Attributes() []attribute.KeyValue {
+	return s.attrs
+}
+
+func (s redactedSpan) Events() []sdktrace.Event {
+	return s.events
+}
+
+func (s redactedSpan) Links() []sdktrace.Link {
+	return s.links
+}
+
+// redactSpans returns the spans to be exported with the sensitive attributes redacted
+func (r *redactor) redactSpans(spans []sdktrace.ReadOnlySpan) []sdktrace.ReadOnlySpan {
+	var result []sdktrace.ReadOnlySpan
+	for i, s := range spans {
+		attrs := s.Attributes()
+		p := r.policy(spanRule(attrs))
+		if p == nil {
+			if result != nil {
+				result = append(result, s)
+			}
+			continue
+		}
+		if result == nil {
+			result = make([]sdktrace.ReadOnlySpan, i, len(spans))
+			copy(result, spans[:i])
+		}
+		rs := redactedSpan{ReadOnlySpan: s, attrs: p.redact(attrs)}
+		if evs := s.Events(); len(evs) > 0 {
+			rs.events = make([]sdktrace.Event, len(evs))
+			for j, ev := range evs {
+				ev.Attributes = p.redact(ev.Attributes)
+				rs.events[j] = ev
+			}
+		}
+		if links := s.Links(); len(links) > 0 {
+			rs.links = make([]sdktrace.Link, len(links))
+			for j, l := range links {
+				l.Attributes = p.redact(l.Attributes)
+				rs.links[j] = l
+			}
+		}
+		result = append(result, rs)
+	}
+	if result == nil {
+		return spans
+	}
+	return result
+}
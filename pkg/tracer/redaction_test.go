@@ -0,0 +1,92 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build trace || !core
+
+package tracer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/lf-edge/ekuiper/v2/pkg/model"
+)
+
+func redactionSpans() []sdktrace.ReadOnlySpan {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	stub := func(rule string, id byte) tracetest.SpanStub {
+		return tracetest.SpanStub{
+			Name:        "op",
+			SpanContext: trace.NewSpanContext(trace.SpanContextConfig{TraceID: trace.TraceID{1}, SpanID: trace.SpanID{id}}),
+			StartTime:   start,
+			EndTime:     start.Add(time.Millisecond),
+			Attributes: []attribute.KeyValue{
+				attribute.String("rule", rule),
+				attribute.String("password", "secret"),
+				attribute.String("data", "token=abc,id=1"),
+				attribute.StringSlice("tags", []string{"token=x", "a"}),
+				attribute.Int("count", 2),
+			},
+			Events: []sdktrace.Event{{Name: "ev", Time: start, Attributes: []attribute.KeyValue{attribute.String("password", "secret")}}},
+		}
+	}
+	return tracetest.SpanStubs{stub("r1", 1), stub("r2", 2)}.Snapshots()
+}
+
+func TestRedaction(t *testing.T) {
+	defer globalRedactor.state.Store(nil)
+	spans := redactionSpans()
+	// nothing to redact
+	globalRedactor.update(nil)
+	require.Equal(t, spans, globalRedactor.redactSpans(spans))
+	require.Equal(t, "secret", FromReadonlySpan(spans[0]).Attribute["password"])
+
+	globalRedactor.update(map[string]*model.RedactionConf{
+		"default": {Deny: []string{"password"}, Masks: []string{`token=[^,]+`}},
+		"r2":      {Allow: []string{"data", "count"}},
+	})
+	s := FromReadonlySpan(spans[0])
+	require.Equal(t, map[string]interface{}{
+		"rule":  "r1",
+		"data":  "******,id=1",
+		"tags":  []string{"******", "a"},
+		"count": int64(2),
+	}, s.Attribute)
+	require.Equal(t, "r1", s.RuleID)
+	require.Nil(t, s.Events[0].Attributes)
+	// the rule adds to the default
+	require.Equal(t, map[string]interface{}{
+		"rule":  "r2",
+		"data":  "******,id=1",
+		"count": int64(2),
+	}, FromReadonlySpan(spans[1]).Attribute)
+
+	redacted := globalRedactor.redactSpans(spans)
+	require.Len(t, redacted, 2)
+	require.Equal(t, []attribute.KeyValue{
+		attribute.String("rule", "r2"),
+		attribute.String("data", "******,id=1"),
+		attribute.Int("count", 2),
+	}, redacted[1].Attributes())
+	require.Empty(t, redacted[0].Events()[0].Attributes)
+	require.Equal(t, spans[0].SpanContext(), redacted[0].SpanContext())
+	// the original spans are not changed
+	require.Equal(t, attribute.String("password", "secret"), spans[0].Attributes()[1])
+}
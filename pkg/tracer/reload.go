@@ -0,0 +1,111 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build trace || !core
+
+package tracer
+
+import (
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/pkg/model"
+)
+
+func init() {
+	conf.RegisterReloader("openTelemetry", reloadConfig)
+}
+
+// reloadConfig applies the changed tracer and span exporter settings of the reloaded config file. The exporter is
+// rebuilt so the spans in the memory storage are dropped. The receiver and the metrics pipeline require restart.
+func reloadConfig(old, c *model.KuiperConf) (applied, restart []string, err error) {
+	var rebuild bool
+	o, n := &old.OpenTelemetry, &c.OpenTelemetry
+	for _, f := range conf.ChangedFields("openTelemetry", *o, *n) {
+		switch f {
+		case "openTelemetry.serviceName":
+			o.ServiceName = n.ServiceName
+		case "openTelemetry.enableRemoteCollector":
+			o.EnableRemoteCollector = n.EnableRemoteCollector
+		case "openTelemetry.remoteEndpoint":
+			o.RemoteEndpoint = n.RemoteEndpoint
+		case "openTelemetry.remoteTls":
+			o.RemoteTls = n.RemoteTls
+		case "openTelemetry.resourceAttributes":
+			o.ResourceAttributes = n.ResourceAttributes
+		case "openTelemetry.remoteProtocol":
+			o.RemoteProtocol = n.RemoteProtocol
+		case "openTelemetry.remoteExport":
+			o.RemoteExport = n.RemoteExport
+		case "openTelemetry.spanPipeline":
+			o.SpanPipeline = n.SpanPipeline
+		case "openTelemetry.spanCompression":
+			// the storage codec applies to the next saved span, the export codec to the rebuilt remote exporter
+			o.SpanCompression = n.SpanCompression
+			storageCompressor.update(n.SpanCompression.Storage)
+		case "openTelemetry.localTraceCapacity":
+			o.LocalTraceCapacity = n.LocalTraceCapacity
+		case "openTelemetry.enableLocalStorage":
+			o.EnableLocalStorage = n.EnableLocalStorage
+		case "openTelemetry.localStorage":
+			o.LocalStorage = n.LocalStorage
+		case "openTelemetry.localTraceFile":
+			o.LocalTraceFile = n.LocalTraceFile
+		case "openTelemetry.localTraceRetention":
+			o.LocalTraceRetention = n.LocalTraceRetention
+		case "openTelemetry.localTraceCleanupInterval":
+			o.LocalTraceCleanupInterval = n.LocalTraceCleanupInterval
+		case "openTelemetry.localTraceMaxSpans":
+			o.LocalTraceMaxSpans = n.LocalTraceMaxSpans
+		case "openTelemetry.localTraceMaxBytes":
+			o.LocalTraceMaxBytes = n.LocalTraceMaxBytes
+		case "openTelemetry.recordMode":
+			o.RecordMode = n.RecordMode
+		case "openTelemetry.indexedAttributes":
+			o.IndexedAttributes = n.IndexedAttributes
+		case "openTelemetry.sampling":
+			// the sampler is shared by the tracer providers
+			o.Sampling = n.Sampling
+			globalSampler.update(n.Sampling)
+			applied = append(applied, f)
+			continue
+		case "openTelemetry.redaction":
+			// read by the span conversion and the remote export of each span
+			o.Redaction = n.Redaction
+			globalRedactor.update(n.Redaction)
+			applied = append(applied, f)
+			continue
+		case "openTelemetry.preserveAttributeTypes":
+			// read by the span conversion of each span
+			o.PreserveAttributeTypes = n.PreserveAttributeTypes
+			applied = append(applied, f)
+			continue
+		case "openTelemetry.spanNameTemplates":
+			// read by the trace nodes for each span
+			o.SpanNameTemplates = n.SpanNameTemplates
+			applied = append(applied, f)
+			continue
+		default:
+			restart = append(restart, f)
+			continue
+		}
+		rebuild = true
+		applied = append(applied, f)
+	}
+	if rebuild {
+		// the persisted tracer config still takes precedence like the startup
+		if err := InitTracer(); err != nil {
+			return nil, restart, err
+		}
+	}
+	return applied, restart, nil
+}
@@ -0,0 +1,322 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build trace || !core
+
+package tracer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/grpc"
+	grpccodes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/status"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/httpx"
+	"github.com/lf-edge/ekuiper/v2/pkg/cert"
+)
+
+// The protocols of the remote collector
+const (
+	RemoteProtocolOtlpHttp = "otlphttp"
+	RemoteProtocolOtlpGrpc = "otlpgrpc"
+	// RemoteProtocolJaeger sends OTLP/HTTP which is accepted by Jaeger natively. The Jaeger thrift protocol is
+	// deprecated by OpenTelemetry.
+	RemoteProtocolJaeger = "jaeger"
+	RemoteProtocolZipkin = "zipkin"
+)
+
+// remoteExportTimeout bounds each attempt to export a batch
+const remoteExportTimeout = 10 * time.Second
+
+// newRemoteExporter creates the exporter of the configured protocol to the endpoint. The exporters do not retry by
+// themselves, the failed batches are retried by the remote queue.
+func newRemoteExporter(endpoint string) (sdktrace.SpanExporter, error) {
+	tc, err := cert.GenTLSConfigForService(conf.Config.OpenTelemetry.RemoteTls)
+	if err != nil {
+		return nil, err
+	}
+	if tc == nil {
+		if err := cert.CheckPlaintext(endpoint); err != nil {
+			return nil, err
+		}
+	}
+	proxy, err := httpx.ProxyFunc("")
+	if err != nil {
+		return nil, err
+	}
+	// each batch is compressed as a whole. The OTLP exporters compress by the default gzip level.
+	compression := conf.Config.OpenTelemetry.SpanCompression.Export
+	gzipped := compression.Codec == CodecGzip
+	switch conf.Config.OpenTelemetry.RemoteProtocol {
+	case RemoteProtocolOtlpGrpc:
+		creds := insecure.NewCredentials()
+		if tc != nil {
+			creds = credentials.NewTLS(tc)
+		}
+		return otlptrace.New(context.Background(), &grpcTraceClient{addr: endpoint, creds: creds, gzip: gzipped})
+	case RemoteProtocolZipkin:
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.Proxy = proxy
+		transport.TLSClientConfig = tc
+		z := &zipkinExporter{
+			url:    zipkinURL(endpoint, tc != nil),
+			client: &http.Client{Transport: transport, Timeout: remoteExportTimeout},
+		}
+		if gzipped {
+			if z.compressor, err = newSpanCompressor(compression); err != nil {
+				return nil, err
+			}
+		}
+		return z, nil
+	default:
+		opts := []otlptracehttp.Option{
+			otlptracehttp.WithEndpoint(endpoint),
+			otlptracehttp.WithRetry(otlptracehttp.RetryConfig{Enabled: false}),
+		}
+		if gzipped {
+			opts = append(opts, otlptracehttp.WithCompression(otlptracehttp.GzipCompression))
+		}
+		if proxy != nil {
+			opts = append(opts, otlptracehttp.WithProxy(proxy))
+		}
+		if tc != nil {
+			opts = append(opts, otlptracehttp.WithTLSClientConfig(tc))
+		} else {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		return otlptracehttp.New(context.Background(), opts...)
+	}
+}
+
+// retryableExportError returns whether the batch failed by a transport error deserves a retry. The errors which are
+// not classified, such as the network errors, are retried.
+func retryableExportError(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	var se *httpStatusError
+	if errors.As(err, &se) {
+		return se.code == http.StatusTooManyRequests || se.code >= http.StatusInternalServerError
+	}
+	if s, ok := status.FromError(err); ok {
+		switch s.Code() {
+		case grpccodes.Unavailable, grpccodes.DeadlineExceeded, grpccodes.ResourceExhausted, grpccodes.Aborted:
+			return true
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// grpcTraceClient uploads the spans transformed by otlptrace to the collector by OTLP/gRPC
+type grpcTraceClient struct {
+	addr   string
+	creds  credentials.TransportCredentials
+	gzip   bool
+	conn   *grpc.ClientConn
+	client coltracepb.TraceServiceClient
+}
+
+func (c *grpcTraceClient) Start(_ context.Context) error {
+	conn, err := grpc.NewClient(c.addr, grpc.WithTransportCredentials(c.creds))
+	if err != nil {
+		return err
+	}
+	c.conn, c.client = conn, coltracepb.NewTraceServiceClient(conn)
+	return nil
+}
+
+func (c *grpcTraceClient) Stop(_ context.Context) error {
+	if c.conn == nil {
+		return nil
+	}
+	return c.conn.Close()
+}
+
+func (c *grpcTraceClient) UploadTraces(ctx context.Context, protoSpans []*tracepb.ResourceSpans) error {
+	var opts []grpc.CallOption
+	if c.gzip {
+		opts = append(opts, grpc.UseCompressor(gzip.Name))
+	}
+	resp, err := c.client.Export(ctx, &coltracepb.ExportTraceServiceRequest{ResourceSpans: protoSpans}, opts...)
+	if err != nil {
+		return err
+	}
+	// the rejected spans are invalid and never accepted by retrying
+	if ps := resp.GetPartialSuccess(); ps != nil && ps.GetRejectedSpans() > 0 {
+		conf.Log.Warnf("remote collector %s rejected %d spans: %s", c.addr, ps.GetRejectedSpans(), ps.GetErrorMessage())
+	}
+	return nil
+}
+
+type httpStatusError struct {
+	code int
+	body string
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("remote collector responded %d: %s", e.code, e.body)
+}
+
+// zipkinURL returns the span API of the zipkin endpoint which is an address or a url
+func zipkinURL(endpoint string, tls bool) string {
+	if !strings.Contains(endpoint, "://") {
+		scheme := "http"
+		if tls {
+			scheme = "https"
+		}
+		endpoint = scheme + "://" + endpoint
+	}
+	if i := strings.Index(endpoint, "://"); !strings.Contains(endpoint[i+3:], "/") {
+		endpoint += "/api/v2/spans"
+	}
+	return endpoint
+}
+
+// zipkinExporter posts the spans by the zipkin v2 JSON API
+type zipkinExporter struct {
+	url    string
+	client *http.Client
+	// compressor gzips the body of each batch if set
+	compressor *spanCompressor
+}
+
+type zipkinEndpoint struct {
+	ServiceName string `json:"serviceName,omitempty"`
+}
+
+type zipkinAnnotation struct {
+	Timestamp int64  `json:"timestamp"`
+	Value     string `json:"value"`
+}
+
+type zipkinSpan struct {
+	TraceID       string             `json:"traceId"`
+	ID            string             `json:"id"`
+	ParentID      string             `json:"parentId,omitempty"`
+	Name          string             `json:"name"`
+	Kind          string             `json:"kind,omitempty"`
+	Timestamp     int64              `json:"timestamp"`
+	Duration      int64              `json:"duration,omitempty"`
+	LocalEndpoint *zipkinEndpoint    `json:"localEndpoint,omitempty"`
+	Tags          map[string]string  `json:"tags,omitempty"`
+	Annotations   []zipkinAnnotation `json:"annotations,omitempty"`
+}
+
+var zipkinKinds = map[trace.SpanKind]string{
+	trace.SpanKindClient:   "CLIENT",
+	trace.SpanKindServer:   "SERVER",
+	trace.SpanKindProducer: "PRODUCER",
+	trace.SpanKindConsumer: "CONSUMER",
+}
+
+func toZipkinSpan(span sdktrace.ReadOnlySpan) zipkinSpan {
+	sc := span.SpanContext()
+	z := zipkinSpan{
+		TraceID:   sc.TraceID().String(),
+		ID:        sc.SpanID().String(),
+		Name:      span.Name(),
+		Kind:      zipkinKinds[span.SpanKind()],
+		Timestamp: span.StartTime().UnixMicro(),
+		Duration:  span.EndTime().Sub(span.StartTime()).Microseconds(),
+	}
+	if span.Parent().HasSpanID() {
+		z.ParentID = span.Parent().SpanID().String()
+	}
+	if res := span.Resource(); res != nil {
+		if v, ok := res.Set().Value("service.name"); ok {
+			z.LocalEndpoint = &zipkinEndpoint{ServiceName: v.Emit()}
+		}
+	}
+	if attrs := span.Attributes(); len(attrs) > 0 {
+		z.Tags = make(map[string]string, len(attrs)+1)
+		for _, attr := range attrs {
+			z.Tags[string(attr.Key)] = attr.Value.Emit()
+		}
+	}
+	if span.Status().Code == codes.Error {
+		if z.Tags == nil {
+			z.Tags = make(map[string]string, 1)
+		}
+		z.Tags["error"] = span.Status().Description
+	}
+	for _, ev := range span.Events() {
+		z.Annotations = append(z.Annotations, zipkinAnnotation{Timestamp: ev.Time.UnixMicro(), Value: ev.Name})
+	}
+	return z
+}
+
+func (z *zipkinExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	if len(spans) == 0 {
+		return nil
+	}
+	payload := make([]zipkinSpan, 0, len(spans))
+	for _, span := range spans {
+		payload = append(payload, toZipkinSpan(span))
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	if z.compressor != nil {
+		if body, err = z.compressor.compress(body); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, z.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if z.compressor != nil {
+		req.Header.Set("Content-Encoding", z.compressor.codec)
+	}
+	resp, err := z.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
+		return &httpStatusError{code: resp.StatusCode, body: string(msg)}
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+func (z *zipkinExporter) Shutdown(_ context.Context) error {
+	z.client.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,216 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build trace || !core
+
+package tracer
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/pkg/model"
+)
+
+// remoteQueue exports the spans to the remote collector in the background so that a slow or unreachable collector
+// does not delay the local storage. The spans are buffered in a bounded queue and exported in batches. A batch failed
+// by a transport error is retried with backoff until the max elapsed time. The spans are dropped and counted if the
+// queue is full or the retries are exhausted.
+type remoteQueue struct {
+	e         *SpanExporter
+	ch        chan sdktrace.ReadOnlySpan
+	batchSize int
+	flush     time.Duration
+	backoff   model.RemoteExportConf
+	pending   atomic.Int64
+	stopped   atomic.Bool
+	lastError atomic.Value
+	// flushCtx bounds the last export of the remaining spans once stopped. It is set before cancel.
+	flushCtx context.Context
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+func newRemoteQueue(e *SpanExporter, c model.RemoteExportConf) *remoteQueue {
+	return &remoteQueue{
+		e:         e,
+		ch:        make(chan sdktrace.ReadOnlySpan, max(c.QueueSize, 1)),
+		batchSize: max(c.BatchSize, 1),
+		flush:     max(time.Duration(c.FlushInterval), time.Millisecond),
+		backoff:   c,
+		done:      make(chan struct{}),
+	}
+}
+
+func (q *remoteQueue) start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	q.cancel = cancel
+	go q.run(ctx)
+}
+
+// stop aborts the retry in progress, exports the remaining spans once bounded by the context and waits for it
+func (q *remoteQueue) stop(ctx context.Context) {
+	if !q.stopped.CompareAndSwap(false, true) {
+		return
+	}
+	q.flushCtx = ctx
+	q.cancel()
+	select {
+	case <-q.done:
+	case <-ctx.Done():
+	}
+}
+
+// offer queues the spans without blocking, the spans beyond the capacity are dropped
+func (q *remoteQueue) offer(spans []sdktrace.ReadOnlySpan) {
+	dropped := 0
+	for _, span := range spans {
+		if q.stopped.Load() {
+			dropped++
+			continue
+		}
+		select {
+		case q.ch <- span:
+			q.pending.Add(1)
+		default:
+			dropped++
+		}
+	}
+	if dropped > 0 {
+		q.drop(dropped)
+	}
+	TraceExportGauge.WithLabelValues(LblRemoteQueueSpans).Set(float64(q.pending.Load()))
+}
+
+func (q *remoteQueue) depth() int64 {
+	if q == nil {
+		return 0
+	}
+	return q.pending.Load()
+}
+
+func (q *remoteQueue) drop(n int) {
+	globalStats.drop(n)
+	TraceExportCounter.WithLabelValues(LblRemoteDroppedSpans).Add(float64(n))
+}
+
+func (q *remoteQueue) run(ctx context.Context) {
+	defer close(q.done)
+	ticker := getClock().Ticker(q.flush)
+	defer ticker.Stop()
+	batch := make([]sdktrace.ReadOnlySpan, 0, q.batchSize)
+	for {
+		select {
+		case <-ctx.Done():
+			// export the remaining spans once without retry
+		drain:
+			for {
+				select {
+				case span := <-q.ch:
+					batch = append(batch, span)
+					if len(batch) >= q.batchSize {
+						q.exportOnce(q.flushCtx, batch)
+						batch = batch[:0]
+					}
+				default:
+					break drain
+				}
+			}
+			if len(batch) > 0 {
+				q.exportOnce(q.flushCtx, batch)
+			}
+			return
+		case span := <-q.ch:
+			batch = append(batch, span)
+			if len(batch) >= q.batchSize {
+				q.export(ctx, batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				q.export(ctx, batch)
+				batch = batch[:0]
+			}
+		}
+	}
+}
+
+// export exports the batch with retry
+func (q *remoteQueue) export(ctx context.Context, batch []sdktrace.ReadOnlySpan) {
+	b := backoff.NewExponentialBackOff(
+		backoff.WithInitialInterval(time.Duration(q.backoff.InitialInterval)),
+		backoff.WithMaxInterval(time.Duration(q.backoff.MaxInterval)),
+		backoff.WithMaxElapsedTime(time.Duration(q.backoff.MaxElapsed)),
+	)
+	attempt := 0
+	err := backoff.RetryNotify(func() error {
+		attempt++
+		if attempt > 1 {
+			TraceExportCounter.WithLabelValues(LblRemoteRetries).Inc()
+		}
+		err := q.send(ctx, batch)
+		if err != nil && !retryableExportError(err) {
+			return backoff.Permanent(err)
+		}
+		return err
+	}, backoff.WithContext(b, ctx), func(err error, d time.Duration) {
+		conf.Log.Warnf("export %d spans to the remote collector err: %v, retry in %v", len(batch), err, d)
+	})
+	q.finish(batch, err)
+}
+
+// exportOnce exports the batch without retry
+func (q *remoteQueue) exportOnce(ctx context.Context, batch []sdktrace.ReadOnlySpan) {
+	q.finish(batch, q.send(ctx, batch))
+}
+
+func (q *remoteQueue) send(ctx context.Context, batch []sdktrace.ReadOnlySpan) error {
+	remote, addr := q.e.remote()
+	if remote == nil {
+		return nil
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := context.WithTimeout(ctx, remoteExportTimeout)
+	defer cancel()
+	start := getClock().Now()
+	err := remote.ExportSpans(ctx, batch)
+	TraceExportDurationHist.WithLabelValues(LblRemote).Observe(float64(getClock().Since(start).Microseconds()))
+	if err != nil {
+		TraceExportCounter.WithLabelValues(LblExportErrors).Inc()
+		// switch to another discovered address for the retry
+		q.e.onRemoteFailure(addr)
+	}
+	return err
+}
+
+func (q *remoteQueue) finish(batch []sdktrace.ReadOnlySpan, err error) {
+	q.pending.Add(-int64(len(batch)))
+	TraceExportGauge.WithLabelValues(LblRemoteQueueSpans).Set(float64(q.pending.Load()))
+	q.e.recordExport(getClock().Now(), err)
+	if err == nil {
+		q.lastError.Store("")
+		globalStats.remoteExported.Add(int64(len(batch)))
+	} else {
+		q.lastError.Store(err.Error())
+		conf.Log.Warnf("drop %d spans to the remote collector: %v", len(batch), err)
+		q.drop(len(batch))
+	}
+}
@@ -0,0 +1,227 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build trace || !core
+
+package tracer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	grpccodes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+	"github.com/lf-edge/ekuiper/v2/pkg/model"
+)
+
+func TestZipkinURL(t *testing.T) {
+	require.Equal(t, "http://localhost:9411/api/v2/spans", zipkinURL("localhost:9411", false))
+	require.Equal(t, "https://localhost:9411/api/v2/spans", zipkinURL("localhost:9411", true))
+	require.Equal(t, "http://zipkin:9411/api/v2/spans", zipkinURL("http://zipkin:9411", true))
+	require.Equal(t, "http://zipkin:9411/custom", zipkinURL("http://zipkin:9411/custom", false))
+}
+
+func TestRetryableExportError(t *testing.T) {
+	tests := []struct {
+		err       error
+		retryable bool
+	}{
+		{err: errors.New("connection refused"), retryable: true},
+		{err: context.Canceled, retryable: false},
+		{err: &httpStatusError{code: http.StatusServiceUnavailable}, retryable: true},
+		{err: &httpStatusError{code: http.StatusTooManyRequests}, retryable: true},
+		{err: &httpStatusError{code: http.StatusBadRequest}, retryable: false},
+		{err: fmt.Errorf("wrapped: %w", &httpStatusError{code: http.StatusBadGateway}), retryable: true},
+		{err: status.Error(grpccodes.Unavailable, "unavailable"), retryable: true},
+		{err: status.Error(grpccodes.InvalidArgument, "invalid"), retryable: false},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.retryable, retryableExportError(tt.err), tt.err.Error())
+	}
+}
+
+func testSpans(n int) []sdktrace.ReadOnlySpan {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	stubs := make(tracetest.SpanStubs, 0, n)
+	for i := 0; i < n; i++ {
+		stubs = append(stubs, tracetest.SpanStub{
+			Name: fmt.Sprintf("op%d", i),
+			SpanContext: trace.NewSpanContext(trace.SpanContextConfig{
+				TraceID: trace.TraceID{1},
+				SpanID:  trace.SpanID{byte(i + 1)},
+			}),
+			Parent: trace.NewSpanContext(trace.SpanContextConfig{
+				TraceID: trace.TraceID{1},
+				SpanID:  trace.SpanID{0xff},
+			}),
+			SpanKind:   trace.SpanKindServer,
+			StartTime:  start,
+			EndTime:    start.Add(time.Millisecond),
+			Attributes: []attribute.KeyValue{attribute.String("rule", "r1")},
+			Status:     sdktrace.Status{Code: codes.Error, Description: "boom"},
+			Events:     []sdktrace.Event{{Name: "ev", Time: start}},
+		})
+	}
+	return stubs.Snapshots()
+}
+
+func TestZipkinExporter(t *testing.T) {
+	var received []zipkinSpan
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v2/spans" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+	z := &zipkinExporter{url: zipkinURL(srv.URL, false), client: srv.Client()}
+	require.NoError(t, z.ExportSpans(context.Background(), testSpans(2)))
+	require.Len(t, received, 2)
+	s := received[0]
+	require.Equal(t, trace.TraceID{1}.String(), s.TraceID)
+	require.Equal(t, trace.SpanID{1}.String(), s.ID)
+	require.Equal(t, trace.SpanID{0xff}.String(), s.ParentID)
+	require.Equal(t, "op0", s.Name)
+	require.Equal(t, "SERVER", s.Kind)
+	require.Equal(t, int64(1000), s.Duration)
+	require.Equal(t, map[string]string{"rule": "r1", "error": "boom"}, s.Tags)
+	require.Len(t, s.Annotations, 1)
+
+	z.url = srv.URL + "/notFound"
+	err := z.ExportSpans(context.Background(), testSpans(1))
+	var se *httpStatusError
+	require.ErrorAs(t, err, &se)
+	require.Equal(t, http.StatusNotFound, se.code)
+	require.False(t, retryableExportError(err))
+}
+
+type stubRemoteExporter struct {
+	sync.Mutex
+	failures int
+	calls    int
+	spans    int
+}
+
+func (s *stubRemoteExporter) ExportSpans(_ context.Context, spans []sdktrace.ReadOnlySpan) error {
+	s.Lock()
+	defer s.Unlock()
+	s.calls++
+	if s.failures > 0 {
+		s.failures--
+		return &httpStatusError{code: http.StatusServiceUnavailable}
+	}
+	s.spans += len(spans)
+	return nil
+}
+
+func (s *stubRemoteExporter) Shutdown(_ context.Context) error {
+	return nil
+}
+
+func (s *stubRemoteExporter) stats() (int, int) {
+	s.Lock()
+	defer s.Unlock()
+	return s.calls, s.spans
+}
+
+func testRemoteExportConf(queueSize, batchSize int) model.RemoteExportConf {
+	return model.RemoteExportConf{
+		QueueSize:       queueSize,
+		BatchSize:       batchSize,
+		FlushInterval:   cast.DurationConf(time.Hour),
+		InitialInterval: cast.DurationConf(time.Millisecond),
+		MaxInterval:     cast.DurationConf(5 * time.Millisecond),
+		MaxElapsed:      cast.DurationConf(time.Second),
+	}
+}
+
+func TestRemoteQueueRetry(t *testing.T) {
+	conf.InitConf()
+	stub := &stubRemoteExporter{failures: 2}
+	e := &SpanExporter{remoteSpanExport: stub}
+	q := newRemoteQueue(e, testRemoteExportConf(10, 2))
+	q.start()
+	q.offer(testSpans(2))
+	require.Eventually(t, func() bool {
+		_, spans := stub.stats()
+		return spans == 2
+	}, time.Second, 5*time.Millisecond)
+	calls, _ := stub.stats()
+	require.Equal(t, 3, calls)
+	require.Equal(t, int64(0), q.depth())
+	require.Equal(t, "", q.lastError.Load())
+	q.stop(context.Background())
+}
+
+func TestRemoteQueueDrop(t *testing.T) {
+	conf.InitConf()
+	stub := &stubRemoteExporter{}
+	e := &SpanExporter{remoteSpanExport: stub}
+	q := newRemoteQueue(e, testRemoteExportConf(3, 10))
+	// the queue is full before running
+	q.offer(testSpans(5))
+	require.Equal(t, int64(3), q.depth())
+	q.start()
+	// the remaining spans are flushed once stopped
+	q.stop(context.Background())
+	calls, spans := stub.stats()
+	require.Equal(t, 1, calls)
+	require.Equal(t, 3, spans)
+	require.Equal(t, int64(0), q.depth())
+	// the spans are dropped after stopped
+	q.offer(testSpans(1))
+	require.Equal(t, int64(0), q.depth())
+	require.Equal(t, int64(0), (*remoteQueue)(nil).depth())
+}
+
+func TestRemoteQueueUnreachable(t *testing.T) {
+	conf.InitConf()
+	stub := &stubRemoteExporter{failures: 1000}
+	e := &SpanExporter{remoteSpanExport: stub}
+	c := testRemoteExportConf(10, 1)
+	c.MaxElapsed = cast.DurationConf(20 * time.Millisecond)
+	q := newRemoteQueue(e, c)
+	q.start()
+	defer q.stop(context.Background())
+	q.offer(testSpans(1))
+	require.Eventually(t, func() bool {
+		s, _ := q.lastError.Load().(string)
+		return s != "" && q.depth() == 0
+	}, time.Second, 5*time.Millisecond)
+	calls, spans := stub.stats()
+	require.Greater(t, calls, 1)
+	require.Equal(t, 0, spans)
+	require.NotEmpty(t, e.lastError.Load())
+}
@@ -0,0 +1,236 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build trace || !core
+
+package tracer
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+	"github.com/lf-edge/ekuiper/v2/pkg/syncx"
+)
+
+const (
+	TraceRulesKey  = "$$tracer_rules"
+	TraceSwitchKey = "$$tracer_switch"
+)
+
+type ruleTraceState struct {
+	conf  RuleTraceConf
+	ratio sdktrace.Sampler
+	// payloadSeq counts the messages of each op to sample the payload, keyed by the op id
+	payloadSeq sync.Map
+}
+
+// runtimeTrace keeps the persisted rule trace settings and the global switch. They are read lock free by the sampler
+// and the trace nodes, and replaced as a whole once changed.
+type runtimeTrace struct {
+	mu     syncx.Mutex
+	loaded bool
+	rules  atomic.Pointer[map[string]*ruleTraceState]
+	// disabled is the global switch to turn off all the span collection
+	disabled atomic.Bool
+}
+
+var runtimeTraces = &runtimeTrace{}
+
+// load reads the persisted settings at the first time
+func (r *runtimeTrace) load() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.loadLocked()
+}
+
+func (r *runtimeTrace) loadLocked() error {
+	if r.loaded {
+		return nil
+	}
+	props, err := conf.LoadCfgKeyKV(TraceRulesKey)
+	if err != nil {
+		return err
+	}
+	// nothing is saved on a fresh store
+	var confs map[string]RuleTraceConf
+	if len(props) > 0 {
+		if err := cast.MapToStruct(props, &confs); err != nil {
+			return err
+		}
+	}
+	rules := make(map[string]*ruleTraceState, len(confs))
+	for id, c := range confs {
+		rules[id] = newRuleTraceState(c)
+	}
+	sw, err := conf.LoadCfgKeyKV(TraceSwitchKey)
+	if err != nil {
+		return err
+	}
+	if enabled, ok := sw["enabled"].(bool); ok {
+		r.disabled.Store(!enabled)
+	}
+	r.rules.Store(&rules)
+	r.loaded = true
+	return nil
+}
+
+func newRuleTraceState(c RuleTraceConf) *ruleTraceState {
+	st := &ruleTraceState{conf: c}
+	if c.Ratio != nil {
+		st.ratio = sdktrace.TraceIDRatioBased(*c.Ratio)
+	}
+	return st
+}
+
+func (r *runtimeTrace) rule(id string) *ruleTraceState {
+	if rules := r.rules.Load(); rules != nil {
+		return (*rules)[id]
+	}
+	return nil
+}
+
+// setRule persists and applies the setting of the rule. Nil removes the setting.
+func (r *runtimeTrace) setRule(id string, c *RuleTraceConf) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.loadLocked(); err != nil {
+		return err
+	}
+	old := *r.rules.Load()
+	rules := make(map[string]*ruleTraceState, len(old)+1)
+	for k, v := range old {
+		rules[k] = v
+	}
+	if c == nil {
+		if _, ok := rules[id]; !ok {
+			return nil
+		}
+		delete(rules, id)
+	} else {
+		rules[id] = newRuleTraceState(*c)
+	}
+	props := make(map[string]interface{}, len(rules))
+	for k, v := range rules {
+		m := map[string]interface{}{
+			"enabled":  v.conf.Enabled,
+			"strategy": v.conf.Strategy,
+			"maxDepth": v.conf.MaxDepth,
+		}
+		if v.conf.Ratio != nil {
+			m["ratio"] = *v.conf.Ratio
+		}
+		if len(v.conf.Alerts) > 0 {
+			alerts := make([]interface{}, 0, len(v.conf.Alerts))
+			for _, a := range v.conf.Alerts {
+				alerts = append(alerts, map[string]interface{}{
+					"name":      a.Name,
+					"span":      a.Span,
+					"trace":     a.Trace,
+					"threshold": time.Duration(a.Threshold).String(),
+					"onError":   a.OnError,
+				})
+			}
+			m["alerts"] = alerts
+		}
+		if p := v.conf.Payload; p != nil {
+			m["payload"] = map[string]interface{}{
+				"maxBytes": p.MaxBytes,
+				"every":    p.Every,
+			}
+		}
+		props[k] = m
+	}
+	if err := conf.SaveCfgKeyToKV(TraceRulesKey, props); err != nil {
+		return err
+	}
+	r.rules.Store(&rules)
+	return nil
+}
+
+func (r *runtimeTrace) setEnabled(enabled bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.loadLocked(); err != nil {
+		return err
+	}
+	if err := conf.SaveCfgKeyToKV(TraceSwitchKey, map[string]interface{}{"enabled": enabled}); err != nil {
+		return err
+	}
+	r.disabled.Store(!enabled)
+	return nil
+}
+
+type spanDepthKey struct{}
+
+// depthTracer records the depth of the span in the context so that the sampler can limit the depth of the rule
+type depthTracer struct {
+	trace.Tracer
+}
+
+func (t depthTracer) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	d := 1
+	if psc := trace.SpanContextFromContext(ctx); psc.IsValid() && !psc.IsRemote() {
+		d = 2
+		if pd, ok := ctx.Value(spanDepthKey{}).(int); ok {
+			d = pd + 1
+		}
+	}
+	return t.Tracer.Start(context.WithValue(ctx, spanDepthKey{}, d), name, opts...)
+}
+
+func spanDepth(ctx context.Context) int {
+	d, _ := ctx.Value(spanDepthKey{}).(int)
+	return d
+}
+
+// IsTracingEnabled returns false if all the span collection is turned off by the global switch
+func IsTracingEnabled() bool {
+	return !runtimeTraces.disabled.Load()
+}
+
+// SetTracingEnabled turns on or off all the span collection and persists the switch. Once turned off, the traced
+// rules do not create any span until turned on again.
+func SetTracingEnabled(enabled bool) error {
+	return runtimeTraces.setEnabled(enabled)
+}
+
+// GetRuleTrace returns the persisted trace setting of the rule, or nil if not set
+func GetRuleTrace(ruleID string) (*RuleTraceConf, error) {
+	if err := runtimeTraces.load(); err != nil {
+		return nil, err
+	}
+	if st := runtimeTraces.rule(ruleID); st != nil {
+		c := st.conf
+		return &c, nil
+	}
+	return nil, nil
+}
+
+// SetRuleTrace validates, persists and applies the sampling ratio and the max depth of the rule. The enabled flag and
+// the strategy are applied to the rule by the caller. Nil removes the setting such as when the rule is deleted.
+func SetRuleTrace(ruleID string, c *RuleTraceConf) error {
+	if c != nil {
+		if err := c.Validate(); err != nil {
+			return err
+		}
+	}
+	return runtimeTraces.setRule(ruleID, c)
+}
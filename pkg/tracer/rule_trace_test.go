@@ -0,0 +1,130 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build trace || !core
+
+package tracer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+)
+
+func resetRuntimeTraces() {
+	runtimeTraces = &runtimeTrace{}
+}
+
+func TestRuleTracePersist(t *testing.T) {
+	conf.IsTesting = true
+	conf.InitConf()
+	resetRuntimeTraces()
+	defer resetRuntimeTraces()
+	ratio := 2.0
+	require.EqualError(t, SetRuleTrace("r1", &RuleTraceConf{Enabled: true, Ratio: &ratio}), "ratio must be in [0, 1]")
+	require.EqualError(t, SetRuleTrace("r1", &RuleTraceConf{Strategy: "tail"}), "invalid strategy tail, must be always or head")
+	c, err := GetRuleTrace("r1")
+	require.NoError(t, err)
+	require.Nil(t, c)
+
+	ratio = 0.5
+	require.NoError(t, SetRuleTrace("r1", &RuleTraceConf{Enabled: true, Strategy: "head", Ratio: &ratio, MaxDepth: 3}))
+	require.NoError(t, SetRuleTrace("r2", &RuleTraceConf{Enabled: true}))
+	require.NoError(t, SetTracingEnabled(false))
+	// reload from the kv as after restart
+	resetRuntimeTraces()
+	c, err = GetRuleTrace("r1")
+	require.NoError(t, err)
+	require.Equal(t, &RuleTraceConf{Enabled: true, Strategy: "head", Ratio: &ratio, MaxDepth: 3}, c)
+	require.False(t, IsTracingEnabled())
+
+	require.NoError(t, SetRuleTrace("r2", nil))
+	require.NoError(t, SetTracingEnabled(true))
+	resetRuntimeTraces()
+	c, err = GetRuleTrace("r2")
+	require.NoError(t, err)
+	require.Nil(t, c)
+	require.True(t, IsTracingEnabled())
+	require.NoError(t, SetRuleTrace("r1", nil))
+}
+
+func TestRuleTraceEmptyStore(t *testing.T) {
+	conf.IsTesting = true
+	conf.InitConf()
+	require.NoError(t, conf.ClearKVStorage())
+	resetRuntimeTraces()
+	defer resetRuntimeTraces()
+	require.NoError(t, runtimeTraces.load())
+	c, err := GetRuleTrace("r1")
+	require.NoError(t, err)
+	require.Nil(t, c)
+	require.True(t, IsTracingEnabled())
+	require.NoError(t, SetRuleTrace("r1", &RuleTraceConf{Enabled: true}))
+	require.NoError(t, SetRuleTrace("r1", nil))
+}
+
+func TestRuleTraceSampler(t *testing.T) {
+	conf.IsTesting = true
+	conf.InitConf()
+	resetRuntimeTraces()
+	defer resetRuntimeTraces()
+	zero := 0.0
+	require.NoError(t, SetRuleTrace("r1", &RuleTraceConf{Enabled: true, Ratio: &zero, MaxDepth: 2}))
+	defer SetRuleTrace("r1", nil)
+	s := &ruleSampler{}
+	bg := context.Background()
+	// the runtime ratio takes precedence over the default sampling
+	require.Equal(t, sdktrace.Drop, s.ShouldSample(samplingParams(bg, "r1")).Decision)
+	require.Equal(t, sdktrace.RecordAndSample, s.ShouldSample(samplingParams(bg, "r2")).Decision)
+
+	parent := trace.ContextWithSpanContext(bg, trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{1}, SpanID: trace.SpanID{1}, TraceFlags: trace.FlagsSampled,
+	}))
+	require.Equal(t, sdktrace.RecordAndSample, s.ShouldSample(samplingParams(context.WithValue(parent, spanDepthKey{}, 2), "r1")).Decision)
+	require.Equal(t, sdktrace.Drop, s.ShouldSample(samplingParams(context.WithValue(parent, spanDepthKey{}, 3), "r1")).Decision)
+	require.Equal(t, sdktrace.RecordAndSample, s.ShouldSample(samplingParams(context.WithValue(parent, spanDepthKey{}, 3), "r2")).Decision)
+}
+
+func TestSpanDepth(t *testing.T) {
+	tr := depthTracer{Tracer: sdktrace.NewTracerProvider().Tracer("test")}
+	ctx, root := tr.Start(context.Background(), "root")
+	defer root.End()
+	require.Equal(t, 1, spanDepth(ctx))
+	ctx, child := tr.Start(ctx, "child")
+	defer child.End()
+	require.Equal(t, 2, spanDepth(ctx))
+	ctx, grandchild := tr.Start(ctx, "grandchild")
+	defer grandchild.End()
+	require.Equal(t, 3, spanDepth(ctx))
+}
+
+func TestTracingSwitch(t *testing.T) {
+	conf.IsTesting = true
+	conf.InitConf()
+	resetRuntimeTraces()
+	defer resetRuntimeTraces()
+	require.NoError(t, SetTracingEnabled(false))
+	_, span := GetTracer().Start(context.Background(), "op")
+	require.False(t, span.SpanContext().IsValid())
+	require.False(t, span.IsRecording())
+	require.NoError(t, SetTracingEnabled(true))
+	_, span = GetTracer().Start(context.Background(), "op")
+	defer span.End()
+	require.True(t, span.SpanContext().IsValid())
+}
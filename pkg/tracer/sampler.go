@@ -0,0 +1,157 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build trace || !core
+
+package tracer
+
+import (
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/time/rate"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/pkg/model"
+)
+
+const (
+	// SamplerKey is the span attribute of the sampler which sampled the trace
+	SamplerKey = "sampler"
+
+	SamplerRule      = "rule"
+	SamplerRatio     = "ratio"
+	SamplerRateLimit = "rateLimit"
+
+	ruleAttributeKey = "rule"
+)
+
+var globalSampler = &ruleSampler{}
+
+// ruleSampler samples the new traces by the runtime ratio of the rule, the per rule overrides, then the ratio and the
+// rate limit. The spans with a local parent follow the decision of the parent unless deeper than the max depth of
+// the rule. The config is replaced at runtime so the running rules are not
+// restarted.
+type ruleSampler struct {
+	state atomic.Pointer[samplerState]
+}
+
+type samplerState struct {
+	conf    model.SamplingConf
+	ratio   sdktrace.Sampler
+	limiter *rate.Limiter
+}
+
+// initIfNot loads the config at the first time so that the runtime change is kept once the tracer is reset
+func (s *ruleSampler) initIfNot() {
+	if s.state.Load() == nil && conf.Config != nil {
+		s.update(conf.Config.OpenTelemetry.Sampling)
+	}
+}
+
+func (s *ruleSampler) update(c model.SamplingConf) {
+	st := &samplerState{conf: c}
+	if c.Ratio != nil && *c.Ratio < 1 {
+		st.ratio = sdktrace.TraceIDRatioBased(*c.Ratio)
+	}
+	if c.RateLimit > 0 {
+		st.limiter = rate.NewLimiter(rate.Limit(c.RateLimit), max(int(c.RateLimit), 1))
+	}
+	s.state.Store(st)
+}
+
+func (s *ruleSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	psc := trace.SpanContextFromContext(p.ParentContext)
+	var rule string
+	for _, attr := range p.Attributes {
+		if attr.Key == ruleAttributeKey {
+			rule = attr.Value.AsString()
+			break
+		}
+	}
+	rt := runtimeTraces.rule(rule)
+	if psc.IsValid() && !psc.IsRemote() {
+		if psc.IsSampled() && (rt == nil || rt.conf.MaxDepth <= 0 || spanDepth(p.ParentContext) <= rt.conf.MaxDepth) {
+			return sdktrace.SamplingResult{Decision: sdktrace.RecordAndSample, Tracestate: psc.TraceState()}
+		}
+		return sdktrace.SamplingResult{Decision: sdktrace.Drop, Tracestate: psc.TraceState()}
+	}
+	// the runtime ratio of the rule takes precedence over the config
+	if rt != nil && rt.ratio != nil {
+		if rt.ratio.ShouldSample(p).Decision == sdktrace.Drop {
+			return sdktrace.SamplingResult{Decision: sdktrace.Drop, Tracestate: psc.TraceState()}
+		}
+		return sampled(psc, SamplerRule)
+	}
+	st := s.state.Load()
+	if st == nil {
+		return sdktrace.SamplingResult{Decision: sdktrace.RecordAndSample, Tracestate: psc.TraceState()}
+	}
+	switch st.conf.Rules[rule] {
+	case model.SampleAlways:
+		return sampled(psc, SamplerRule)
+	case model.SampleNever:
+		return sdktrace.SamplingResult{Decision: sdktrace.Drop, Tracestate: psc.TraceState()}
+	}
+	// the root and the remote parent are sampled by the local strategy regardless of the upstream decision
+	decidedBy := ""
+	if st.ratio != nil {
+		if st.ratio.ShouldSample(p).Decision == sdktrace.Drop {
+			return sdktrace.SamplingResult{Decision: sdktrace.Drop, Tracestate: psc.TraceState()}
+		}
+		decidedBy = SamplerRatio
+	}
+	if st.limiter != nil {
+		if !st.limiter.AllowN(getClock().Now(), 1) {
+			return sdktrace.SamplingResult{Decision: sdktrace.Drop, Tracestate: psc.TraceState()}
+		}
+		decidedBy = SamplerRateLimit
+	}
+	if decidedBy == "" {
+		return sdktrace.SamplingResult{Decision: sdktrace.RecordAndSample, Tracestate: psc.TraceState()}
+	}
+	return sampled(psc, decidedBy)
+}
+
+func sampled(psc trace.SpanContext, sampler string) sdktrace.SamplingResult {
+	return sdktrace.SamplingResult{
+		Decision:   sdktrace.RecordAndSample,
+		Attributes: []attribute.KeyValue{attribute.String(SamplerKey, sampler)},
+		Tracestate: psc.TraceState(),
+	}
+}
+
+func (s *ruleSampler) Description() string {
+	return "RuleSampler"
+}
+
+// SetSampling replaces the sampling config at runtime. It applies to the new traces of the running rules.
+func SetSampling(c model.SamplingConf) error {
+	if err := c.Validate(conf.Log); err != nil {
+		return err
+	}
+	globalSampler.update(c)
+	return nil
+}
+
+// GetSampling returns the sampling config in use
+func GetSampling() model.SamplingConf {
+	globalSampler.initIfNot()
+	if st := globalSampler.state.Load(); st != nil {
+		return st.conf
+	}
+	return model.SamplingConf{}
+}
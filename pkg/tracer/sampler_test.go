@@ -0,0 +1,107 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build trace || !core
+
+package tracer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/pkg/model"
+)
+
+func samplingParams(ctx context.Context, rule string) sdktrace.SamplingParameters {
+	return sdktrace.SamplingParameters{
+		ParentContext: ctx,
+		TraceID:       trace.TraceID{8: 0xff, 9: 0xff, 10: 0xff, 11: 0xff, 12: 0xff, 13: 0xff, 14: 0xff, 15: 0xff},
+		Name:          "op",
+		Attributes:    []attribute.KeyValue{attribute.String(ruleAttributeKey, rule)},
+	}
+}
+
+func TestRuleSampler(t *testing.T) {
+	conf.InitConf()
+	mc := clock.NewMock()
+	SetClock(mc)
+	defer SetClock(nil)
+	s := &ruleSampler{}
+	bg := context.Background()
+	r := s.ShouldSample(samplingParams(bg, "r1"))
+	require.Equal(t, sdktrace.RecordAndSample, r.Decision)
+	require.Empty(t, r.Attributes)
+
+	zero := 0.0
+	s.update(model.SamplingConf{Ratio: &zero, Rules: map[string]string{"r1": model.SampleAlways, "r2": model.SampleNever}})
+	r = s.ShouldSample(samplingParams(bg, "r1"))
+	require.Equal(t, sdktrace.RecordAndSample, r.Decision)
+	require.Equal(t, []attribute.KeyValue{attribute.String(SamplerKey, SamplerRule)}, r.Attributes)
+	require.Equal(t, sdktrace.Drop, s.ShouldSample(samplingParams(bg, "r2")).Decision)
+	require.Equal(t, sdktrace.Drop, s.ShouldSample(samplingParams(bg, "r3")).Decision)
+
+	// the spans with local parent follow the parent
+	sampledParent := trace.ContextWithSpanContext(bg, trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{1}, SpanID: trace.SpanID{1}, TraceFlags: trace.FlagsSampled,
+	}))
+	require.Equal(t, sdktrace.RecordAndSample, s.ShouldSample(samplingParams(sampledParent, "r2")).Decision)
+	droppedParent := trace.ContextWithSpanContext(bg, trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{1}, SpanID: trace.SpanID{1},
+	}))
+	require.Equal(t, sdktrace.Drop, s.ShouldSample(samplingParams(droppedParent, "r1")).Decision)
+	// the remote parent is sampled by the local strategy
+	remoteParent := trace.ContextWithSpanContext(bg, trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{1}, SpanID: trace.SpanID{1}, TraceFlags: trace.FlagsSampled, Remote: true,
+	}))
+	require.Equal(t, sdktrace.Drop, s.ShouldSample(samplingParams(remoteParent, "r3")).Decision)
+
+	one := 1.0
+	s.update(model.SamplingConf{Ratio: &one, RateLimit: 2})
+	for i := 0; i < 2; i++ {
+		r = s.ShouldSample(samplingParams(bg, "r1"))
+		require.Equal(t, sdktrace.RecordAndSample, r.Decision)
+		require.Equal(t, []attribute.KeyValue{attribute.String(SamplerKey, SamplerRateLimit)}, r.Attributes)
+	}
+	require.Equal(t, sdktrace.Drop, s.ShouldSample(samplingParams(bg, "r1")).Decision)
+	mc.Add(time.Second)
+	require.Equal(t, sdktrace.RecordAndSample, s.ShouldSample(samplingParams(bg, "r1")).Decision)
+
+	half := 0.5
+	s.update(model.SamplingConf{Ratio: &half})
+	r = s.ShouldSample(sdktrace.SamplingParameters{ParentContext: bg, TraceID: trace.TraceID{}})
+	require.Equal(t, sdktrace.RecordAndSample, r.Decision)
+	require.Equal(t, []attribute.KeyValue{attribute.String(SamplerKey, SamplerRatio)}, r.Attributes)
+	require.Equal(t, sdktrace.Drop, s.ShouldSample(samplingParams(bg, "r1")).Decision)
+}
+
+func TestSetSampling(t *testing.T) {
+	conf.InitConf()
+	defer globalSampler.state.Store(nil)
+	ratio := 2.0
+	err := SetSampling(model.SamplingConf{Ratio: &ratio, RateLimit: -1, Rules: map[string]string{"r1": "sometimes"}})
+	require.EqualError(t, err, "ratio:ratio must be in [0, 1]\nrateLimit:rateLimit must not be negative\nrules:sampling of rule r1 must be always or never")
+	ratio = 0.1
+	require.NoError(t, SetSampling(model.SamplingConf{Ratio: &ratio, Rules: map[string]string{"r1": model.SampleAlways}}))
+	c := GetSampling()
+	require.Equal(t, 0.1, *c.Ratio)
+	require.Equal(t, map[string]string{"r1": model.SampleAlways}, c.Rules)
+}
@@ -0,0 +1,175 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build trace || !core
+
+package tracer
+
+import (
+	"sort"
+	"time"
+)
+
+// traceMatch is the state of a trace while scanning the spans. Only the root span is kept so that the search does
+// not load all the spans into memory.
+type traceMatch struct {
+	root  *LocalSpan
+	rule  bool
+	attrs map[string]struct{}
+}
+
+// traceLinks returns the links of the spans of the trace and scans the spans of the storage for the links to the trace.
+// The links are sorted by the span start time.
+func traceLinks(s LocalSpanStorage, traceID string) (*TraceLinks, error) {
+	r := &TraceLinks{Links: []SpanLink{}, LinkedBy: []SpanLink{}}
+	var links, linkedBy []*LocalSpan
+	err := s.RangeSpans(time.Time{}, time.Time{}, func(span *LocalSpan) error {
+		if span.TraceID == traceID {
+			if len(span.Links) > 0 {
+				links = append(links, span)
+			}
+			return nil
+		}
+		for _, l := range span.Links {
+			if l.TraceID == traceID {
+				linkedBy = append(linkedBy, span)
+				break
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sortSpans(links)
+	for _, span := range links {
+		for _, l := range span.Links {
+			if l.TraceID != traceID {
+				r.Links = append(r.Links, SpanLink{TraceID: span.TraceID, SpanID: span.SpanID, Name: span.Name, Link: l})
+			}
+		}
+	}
+	sortSpans(linkedBy)
+	for _, span := range linkedBy {
+		for _, l := range span.Links {
+			if l.TraceID == traceID {
+				r.LinkedBy = append(r.LinkedBy, SpanLink{TraceID: span.TraceID, SpanID: span.SpanID, Name: span.Name, Link: l})
+			}
+		}
+	}
+	return r, nil
+}
+
+// searchTraces scans the spans of the storage and returns the page of the traces matching all the conditions:
+//   - ruleID: any span of the trace belongs to the rule. Empty means any rule. The traces are found by the rule index.
+//   - start, end: the root span started in [start, end]. Zero time means no bound.
+//   - attrFilters: each attribute value is found in any span of the trace. The attributes are not necessarily indexed.
+//
+// Only the spans started in [start, end] are scanned, and the root is the earliest span of the trace since the child
+// spans never start before their parents. Only the roots of the page are kept while counting the matched traces.
+// Limit less than 1 means no limit.
+func searchTraces(s LocalSpanStorage, ruleID string, start, end time.Time, attrFilters map[string]string, limit, offset int) (*TraceSearchResult, error) {
+	var candidates map[string]struct{}
+	if ruleID != "" {
+		ids, err := s.GetTraceByRuleID(ruleID, 0)
+		if err != nil {
+			return nil, err
+		}
+		if len(ids) == 0 {
+			return &TraceSearchResult{Traces: []*LocalSpan{}}, nil
+		}
+		candidates = make(map[string]struct{}, len(ids))
+		for _, id := range ids {
+			candidates[id] = struct{}{}
+		}
+	}
+	matches := make(map[string]*traceMatch)
+	err := s.RangeSpans(start, end, func(span *LocalSpan) error {
+		if candidates != nil {
+			if _, ok := candidates[span.TraceID]; !ok {
+				return nil
+			}
+		}
+		m, ok := matches[span.TraceID]
+		if !ok {
+			m = &traceMatch{}
+			matches[span.TraceID] = m
+		}
+		if m.root == nil || span.StartTime.Before(m.root.StartTime) {
+			m.root = span
+		}
+		if ruleID != "" && span.RuleID == ruleID {
+			m.rule = true
+		}
+		for k, v := range attrFilters {
+			if av, ok := span.Attribute[k]; ok && FormatAttribute(av) == v {
+				if m.attrs == nil {
+					m.attrs = make(map[string]struct{}, len(attrFilters))
+				}
+				m.attrs[k] = struct{}{}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	offset = max(offset, 0)
+	// keep the latest roots up to the end of the page
+	keep := -1
+	if limit > 0 {
+		keep = offset + limit
+	}
+	r := &TraceSearchResult{Traces: []*LocalSpan{}}
+	roots := make([]*LocalSpan, 0)
+	for _, m := range matches {
+		if ruleID != "" && !m.rule {
+			continue
+		}
+		if len(m.attrs) < len(attrFilters) || !inTimeRange(m.root, start, end) {
+			continue
+		}
+		r.Total++
+		i := sort.Search(len(roots), func(i int) bool {
+			return newerRoot(m.root, roots[i])
+		})
+		if keep >= 0 && i >= keep {
+			continue
+		}
+		roots = append(roots, nil)
+		copy(roots[i+1:], roots[i:])
+		roots[i] = m.root
+		if keep >= 0 && len(roots) > keep {
+			roots = roots[:keep]
+		}
+	}
+	if offset >= len(roots) {
+		return r, nil
+	}
+	for _, root := range roots[offset:] {
+		// copy to not expose the children linked in the memory storage
+		span := *root
+		span.ChildSpan = nil
+		r.Traces = append(r.Traces, &span)
+	}
+	return r, nil
+}
+
+// newerRoot tells whether the root a is listed before b, that is, the latest first and then by the trace id
+func newerRoot(a, b *LocalSpan) bool {
+	if a.StartTime.Equal(b.StartTime) {
+		return a.TraceID < b.TraceID
+	}
+	return a.StartTime.After(b.StartTime)
+}
@@ -0,0 +1,122 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build trace || !core
+
+package tracer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchTraces(t *testing.T) {
+	s := newLocalSpanMemoryStorage(10)
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	spans := []*LocalSpan{
+		{TraceID: "t0", SpanID: "s0", ParentSpanID: "0000000000000000", RuleID: "r1", StartTime: start},
+		{TraceID: "t0", SpanID: "s1", ParentSpanID: "s0", RuleID: "r1", StartTime: start.Add(time.Millisecond), Attribute: map[string]any{"deviceId": "d1", "qos": 1}},
+		{TraceID: "t1", SpanID: "s2", RuleID: "r1", StartTime: start.Add(time.Minute), Attribute: map[string]any{"deviceId": "d2"}},
+		{TraceID: "t2", SpanID: "s3", RuleID: "r2", StartTime: start.Add(2 * time.Minute), Attribute: map[string]any{"deviceId": "d1"}},
+		{TraceID: "t3", SpanID: "s4", RuleID: "r1", StartTime: start.Add(3 * time.Minute)},
+	}
+	for _, span := range spans {
+		require.NoError(t, s.saveSpan(span))
+	}
+	traceIDs := func(r *TraceSearchResult) []string {
+		ids := make([]string, 0, len(r.Traces))
+		for _, span := range r.Traces {
+			ids = append(ids, span.TraceID)
+		}
+		return ids
+	}
+
+	r, err := searchTraces(s, "", time.Time{}, time.Time{}, nil, 0, 0)
+	require.NoError(t, err)
+	require.Equal(t, 4, r.Total)
+	require.Equal(t, []string{"t3", "t2", "t1", "t0"}, traceIDs(r))
+	require.Equal(t, "s0", r.Traces[3].SpanID)
+	require.Nil(t, r.Traces[3].ChildSpan)
+
+	r, err = searchTraces(s, "r1", time.Time{}, time.Time{}, nil, 2, 1)
+	require.NoError(t, err)
+	require.Equal(t, 3, r.Total)
+	require.Equal(t, []string{"t1", "t0"}, traceIDs(r))
+
+	// only the page is kept while all the matches are counted
+	r, err = searchTraces(s, "", time.Time{}, time.Time{}, nil, 1, 0)
+	require.NoError(t, err)
+	require.Equal(t, 4, r.Total)
+	require.Equal(t, []string{"t3"}, traceIDs(r))
+
+	// the rule without traces
+	r, err = searchTraces(s, "r3", time.Time{}, time.Time{}, nil, 0, 0)
+	require.NoError(t, err)
+	require.Equal(t, 0, r.Total)
+	require.Empty(t, r.Traces)
+
+	r, err = searchTraces(s, "r1", start.Add(30*time.Second), start.Add(150*time.Second), nil, 0, 0)
+	require.NoError(t, err)
+	require.Equal(t, []string{"t1"}, traceIDs(r))
+
+	// the attributes are matched in any span of the trace
+	r, err = searchTraces(s, "", time.Time{}, time.Time{}, map[string]string{"deviceId": "d1"}, 0, 0)
+	require.NoError(t, err)
+	require.Equal(t, []string{"t2", "t0"}, traceIDs(r))
+	r, err = searchTraces(s, "r1", time.Time{}, time.Time{}, map[string]string{"deviceId": "d1", "qos": "1"}, 0, 0)
+	require.NoError(t, err)
+	require.Equal(t, []string{"t0"}, traceIDs(r))
+	r, err = searchTraces(s, "", time.Time{}, time.Time{}, map[string]string{"deviceId": "d1", "qos": "2"}, 0, 0)
+	require.NoError(t, err)
+	require.Equal(t, 0, r.Total)
+	require.Empty(t, r.Traces)
+
+	// out of range page
+	r, err = searchTraces(s, "", time.Time{}, time.Time{}, nil, 10, 10)
+	require.NoError(t, err)
+	require.Equal(t, 4, r.Total)
+	require.NotNil(t, r.Traces)
+	require.Empty(t, r.Traces)
+}
+
+func TestTraceLinks(t *testing.T) {
+	s := newLocalSpanMemoryStorage(10)
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	// the input tuples of t1 and t2 link to the window span of t0, which links to the upstream t3
+	spans := []*LocalSpan{
+		{TraceID: "t1", SpanID: "s1", Name: "source", StartTime: start, Links: []LocalLink{{TraceID: "t0", SpanID: "w0"}}},
+		{TraceID: "t2", SpanID: "s2", Name: "source", StartTime: start.Add(time.Millisecond), Links: []LocalLink{{TraceID: "t0", SpanID: "w0", Attributes: map[string]any{"k": "v"}}}},
+		{TraceID: "t0", SpanID: "w0", Name: "window_op", StartTime: start.Add(time.Second), Links: []LocalLink{{TraceID: "t3"}}},
+		{TraceID: "t0", SpanID: "w1", ParentSpanID: "w0", Name: "sink", StartTime: start.Add(2 * time.Second)},
+		{TraceID: "t4", SpanID: "s4", Name: "source", StartTime: start, Links: []LocalLink{{TraceID: "t5"}}},
+	}
+	for _, span := range spans {
+		require.NoError(t, s.saveSpan(span))
+	}
+	r, err := traceLinks(s, "t0")
+	require.NoError(t, err)
+	require.Equal(t, &TraceLinks{
+		Links: []SpanLink{{TraceID: "t0", SpanID: "w0", Name: "window_op", Link: LocalLink{TraceID: "t3"}}},
+		LinkedBy: []SpanLink{
+			{TraceID: "t1", SpanID: "s1", Name: "source", Link: LocalLink{TraceID: "t0", SpanID: "w0"}},
+			{TraceID: "t2", SpanID: "s2", Name: "source", Link: LocalLink{TraceID: "t0", SpanID: "w0", Attributes: map[string]any{"k": "v"}}},
+		},
+	}, r)
+
+	r, err = traceLinks(s, "t9")
+	require.NoError(t, err)
+	require.Equal(t, &TraceLinks{Links: []SpanLink{}, LinkedBy: []SpanLink{}}, r)
+}
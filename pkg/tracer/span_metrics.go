@@ -0,0 +1,212 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build trace || !core
+
+package tracer
+
+import (
+	"math"
+	"sort"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	"github.com/lf-edge/ekuiper/v2/pkg/syncx"
+)
+
+const (
+	// the latency buckets are 10us ~ 20s, the same as the export duration histogram
+	latencyBucketStart = 10
+	latencyBuckets     = 22
+	// throughputWindow is the seconds to calculate the throughput
+	throughputWindow = 60
+	// maxSpanMetricKeys limits the rules and operators to aggregate in case the span names are of high cardinality
+	maxSpanMetricKeys = 4096
+)
+
+var latencyBounds = func() [latencyBuckets]int64 {
+	var b [latencyBuckets]int64
+	for i := range b {
+		b[i] = latencyBucketStart << i
+	}
+	return b
+}()
+
+type spanMetricKey struct {
+	rule string
+	op   string
+}
+
+// spanAgg aggregates the spans of a key with fixed memory
+type spanAgg struct {
+	count  int64
+	errors int64
+	max    int64
+	// buckets counts the latencies no more than the bound, the last one is for the overflow
+	buckets [latencyBuckets + 1]int64
+	// slots count the spans of each second in the throughput window
+	slots   [throughputWindow]int64
+	slotSec [throughputWindow]int64
+}
+
+func (a *spanAgg) add(us int64, isErr bool, sec int64) {
+	a.count++
+	if isErr {
+		a.errors++
+	}
+	if us > a.max {
+		a.max = us
+	}
+	a.buckets[sort.Search(latencyBuckets, func(i int) bool { return us <= latencyBounds[i] })]++
+	i := sec % throughputWindow
+	if a.slotSec[i] != sec {
+		a.slotSec[i] = sec
+		a.slots[i] = 0
+	}
+	a.slots[i]++
+}
+
+// percentile estimates the latency by the linear interpolation in the bucket
+func (a *spanAgg) percentile(q float64) int64 {
+	if a.count == 0 {
+		return 0
+	}
+	rank := int64(math.Ceil(q * float64(a.count)))
+	var cum int64
+	for i, n := range a.buckets {
+		if n == 0 || cum+n < rank {
+			cum += n
+			continue
+		}
+		var lower int64
+		if i > 0 {
+			lower = latencyBounds[i-1]
+		}
+		upper := a.max
+		if i < latencyBuckets && latencyBounds[i] < upper {
+			upper = latencyBounds[i]
+		}
+		if upper <= lower {
+			return upper
+		}
+		return lower + int64(float64(upper-lower)*float64(rank-cum)/float64(n))
+	}
+	return a.max
+}
+
+func (a *spanAgg) metrics(key spanMetricKey, sec int64) SpanMetrics {
+	m := SpanMetrics{
+		RuleID:   key.rule,
+		Operator: key.op,
+		Count:    a.count,
+		Errors:   a.errors,
+		P50Us:    a.percentile(0.5),
+		P95Us:    a.percentile(0.95),
+		P99Us:    a.percentile(0.99),
+		MaxUs:    a.max,
+	}
+	if a.count > 0 {
+		m.ErrorRate = float64(a.errors) / float64(a.count)
+	}
+	var n int64
+	for i, s := range a.slotSec {
+		if sec-s < throughputWindow && s <= sec {
+			n += a.slots[i]
+		}
+	}
+	m.Throughput = float64(n) / throughputWindow
+	return m
+}
+
+type spanMetricsAgg struct {
+	mu   syncx.Mutex
+	aggs map[spanMetricKey]*spanAgg
+}
+
+var globalSpanMetrics = &spanMetricsAgg{aggs: make(map[spanMetricKey]*spanAgg)}
+
+// record aggregates the ended span of a rule to the rule and the operator. The spans out of rules are ignored.
+func (g *spanMetricsAgg) record(s sdktrace.ReadOnlySpan) {
+	var rule string
+	for _, attr := range s.Attributes() {
+		if attr.Key == ruleAttributeKey {
+			rule = attr.Value.AsString()
+			break
+		}
+	}
+	if rule == "" {
+		return
+	}
+	us := s.EndTime().Sub(s.StartTime()).Microseconds()
+	if us < 0 {
+		us = 0
+	}
+	isErr := s.Status().Code == codes.Error
+	sec := getClock().Now().Unix()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, key := range []spanMetricKey{{rule: rule}, {rule: rule, op: s.Name()}} {
+		a, ok := g.aggs[key]
+		if !ok {
+			if len(g.aggs) >= maxSpanMetricKeys {
+				continue
+			}
+			a = &spanAgg{}
+			g.aggs[key] = a
+		}
+		a.add(us, isErr, sec)
+	}
+}
+
+// get returns the metrics of the rule, or all the rules if empty. The rule aggregation comes before its operators.
+func (g *spanMetricsAgg) get(rule string) []SpanMetrics {
+	sec := getClock().Now().Unix()
+	g.mu.Lock()
+	result := make([]SpanMetrics, 0, len(g.aggs))
+	for key, a := range g.aggs {
+		if rule == "" || key.rule == rule {
+			result = append(result, a.metrics(key, sec))
+		}
+	}
+	g.mu.Unlock()
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].RuleID != result[j].RuleID {
+			return result[i].RuleID < result[j].RuleID
+		}
+		return result[i].Operator < result[j].Operator
+	})
+	return result
+}
+
+func (g *spanMetricsAgg) reset(rule string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for key := range g.aggs {
+		if rule == "" || key.rule == rule {
+			delete(g.aggs, key)
+		}
+	}
+}
+
+// GetSpanMetrics returns the latency, error and throughput metrics derived from the ended spans of the rule, or of
+// all the rules if the rule id is empty.
+func GetSpanMetrics(ruleID string) []SpanMetrics {
+	return globalSpanMetrics.get(ruleID)
+}
+
+// ResetSpanMetrics clears the span metrics of the rule, or of all the rules if the rule id is empty
+func ResetSpanMetrics(ruleID string) {
+	globalSpanMetrics.reset(ruleID)
+}
@@ -0,0 +1,114 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build trace || !core
+
+package tracer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func metricSpan(rule, name string, d time.Duration, isErr bool) sdktrace.ReadOnlySpan {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	stub := tracetest.SpanStub{
+		Name:      name,
+		StartTime: start,
+		EndTime:   start.Add(d),
+	}
+	if rule != "" {
+		stub.Attributes = []attribute.KeyValue{attribute.String("rule", rule)}
+	}
+	if isErr {
+		stub.Status = sdktrace.Status{Code: codes.Error}
+	}
+	return stub.Snapshot()
+}
+
+func TestSpanMetrics(t *testing.T) {
+	mc := clock.NewMock()
+	mc.Set(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	SetClock(mc)
+	defer SetClock(nil)
+	defer ResetSpanMetrics("")
+	ResetSpanMetrics("")
+
+	for i := 1; i <= 100; i++ {
+		globalSpanMetrics.record(metricSpan("r1", "op1", time.Duration(i)*time.Millisecond, i%10 == 0))
+	}
+	mc.Add(30 * time.Second)
+	globalSpanMetrics.record(metricSpan("r1", "op2", 5*time.Microsecond, false))
+	globalSpanMetrics.record(metricSpan("r2", "op1", time.Second, false))
+	// not of any rule
+	globalSpanMetrics.record(metricSpan("", "api", time.Second, false))
+
+	ms := GetSpanMetrics("r1")
+	require.Len(t, ms, 3)
+	rule, op1, op2 := ms[0], ms[1], ms[2]
+	require.Equal(t, SpanMetrics{RuleID: "r1", Operator: "op2", Count: 1, Throughput: 1.0 / 60, P50Us: 5, P95Us: 5, P99Us: 5, MaxUs: 5}, op2)
+
+	require.Equal(t, "op1", op1.Operator)
+	require.Equal(t, int64(100), op1.Count)
+	require.Equal(t, int64(10), op1.Errors)
+	require.InDelta(t, 0.1, op1.ErrorRate, 1e-9)
+	require.InDelta(t, 100.0/60, op1.Throughput, 1e-9)
+	require.Equal(t, int64(100000), op1.MaxUs)
+	// estimated in the buckets
+	require.InDelta(t, 50000, op1.P50Us, 15000)
+	require.InDelta(t, 95000, op1.P95Us, 15000)
+	require.InDelta(t, 99000, op1.P99Us, 15000)
+	require.LessOrEqual(t, op1.P50Us, op1.P95Us)
+	require.LessOrEqual(t, op1.P95Us, op1.P99Us)
+	require.LessOrEqual(t, op1.P99Us, op1.MaxUs)
+
+	require.Equal(t, "", rule.Operator)
+	require.Equal(t, int64(101), rule.Count)
+	require.Equal(t, int64(10), rule.Errors)
+
+	// the spans of op1 are out of the throughput window
+	mc.Add(40 * time.Second)
+	ms = GetSpanMetrics("r1")
+	require.Equal(t, 1.0/60, ms[0].Throughput)
+	require.Equal(t, 0.0, ms[1].Throughput)
+	require.Equal(t, int64(100), ms[1].Count)
+
+	require.Len(t, GetSpanMetrics(""), 5)
+	ResetSpanMetrics("r1")
+	ms = GetSpanMetrics("")
+	require.Len(t, ms, 2)
+	require.Equal(t, "r2", ms[0].RuleID)
+}
+
+func TestSpanMetricsPercentile(t *testing.T) {
+	a := &spanAgg{}
+	require.Equal(t, int64(0), a.percentile(0.5))
+	a.add(25_000_000, false, 0)
+	// overflow bucket
+	require.Equal(t, int64(25_000_000), a.percentile(0.99))
+	a = &spanAgg{}
+	for i := 0; i < 10; i++ {
+		a.add(15, false, 0)
+	}
+	// interpolated between the bucket lower bound and the max
+	require.Equal(t, int64(12), a.percentile(0.5))
+	require.Equal(t, int64(15), a.percentile(1))
+}
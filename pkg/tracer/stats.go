@@ -0,0 +1,131 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build trace || !core
+
+package tracer
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+)
+
+// dropRateWindow is the period to check the drop rate of the spans
+const dropRateWindow = time.Minute
+
+var globalStats = &tracerStats{}
+
+// tracerStats counts the spans through the tracing subsystem since the process starts, so that it is kept when the
+// tracer is rebuilt
+type tracerStats struct {
+	received       atomic.Int64
+	exported       atomic.Int64
+	remoteExported atomic.Int64
+	dropped        atomic.Int64
+	lastError      atomic.Pointer[exportError]
+}
+
+type exportError struct {
+	msg  string
+	time int64
+}
+
+func (s *tracerStats) receive() {
+	s.received.Add(1)
+}
+
+func (s *tracerStats) drop(n int) {
+	s.dropped.Add(int64(n))
+	TraceExportCounter.WithLabelValues(LblDroppedSpans).Add(float64(n))
+}
+
+func (s *tracerStats) exportError(now time.Time, err error) {
+	s.lastError.Store(&exportError{msg: err.Error(), time: now.UnixMilli()})
+}
+
+func (s *tracerStats) snapshot() *TracerStatistics {
+	st := &TracerStatistics{
+		Received:       s.received.Load(),
+		Exported:       s.exported.Load(),
+		RemoteExported: s.remoteExported.Load(),
+		Dropped:        s.dropped.Load(),
+	}
+	if e := s.lastError.Load(); e != nil {
+		st.LastExportError, st.LastExportErrorTime = e.msg, e.time
+	}
+	return st
+}
+
+// dropRateMonitor warns once the dropped spans exceed the ratio of the received spans in the window
+type dropRateMonitor struct {
+	ratio    float64
+	start    time.Time
+	received int64
+	dropped  int64
+}
+
+func newDropRateMonitor(ratio float64, now time.Time) *dropRateMonitor {
+	return &dropRateMonitor{ratio: ratio, start: now, received: globalStats.received.Load(), dropped: globalStats.dropped.Load()}
+}
+
+// check compares the counts with the window start once the window passes and starts a new window. It returns
+// whether the warning is logged.
+func (m *dropRateMonitor) check(now time.Time) bool {
+	if m.ratio <= 0 || now.Sub(m.start) < dropRateWindow {
+		return false
+	}
+	received, dropped := globalStats.received.Load(), globalStats.dropped.Load()
+	dr, dd := received-m.received, dropped-m.dropped
+	elapsed := now.Sub(m.start)
+	m.start, m.received, m.dropped = now, received, dropped
+	if dr <= 0 || float64(dd)/float64(dr) <= m.ratio {
+		return false
+	}
+	conf.Log.Warnf("tracer dropped %d spans of %d received in the last %v, above the ratio %v. Check the queue size of the span pipeline and the remote export, and the storage health", dd, dr, elapsed.Truncate(time.Second), m.ratio)
+	return true
+}
+
+// TracerStats returns the statistics of the tracing subsystem itself to tell whether it is the reason of the missing
+// traces
+func TracerStats() *TracerStatistics {
+	st := globalStats.snapshot()
+	globalTracerManager.stats(st)
+	return st
+}
+
+func (g *GlobalTracerManager) stats(st *TracerStatistics) {
+	g.RLock()
+	defer g.RUnlock()
+	st.Enabled = g.Init && IsTracingEnabled()
+	st.QueueDepth = g.pipeline.depth()
+	if g.SpanExporter == nil {
+		return
+	}
+	st.RemoteQueueDepth = g.SpanExporter.remoteQueue.depth()
+	if u, ok := g.SpanExporter.spanStorage.(storageUsage); ok {
+		spans, bytes, err := u.Usage()
+		if err != nil {
+			conf.Log.Warnf("get the usage of the trace storage error: %v", err)
+			return
+		}
+		st.StoredSpans, st.StorageBytes = spans, bytes
+	}
+}
+
+// storageUsage is implemented by the span storages which know the size of the saved spans
+type storageUsage interface {
+	Usage() (spans, bytes int64, err error)
+}
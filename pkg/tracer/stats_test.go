@@ -0,0 +1,112 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build trace || !core
+
+package tracer
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/pkg/model"
+)
+
+func resetTracerStats() {
+	globalStats = &tracerStats{}
+}
+
+func TestTracerStatsCounters(t *testing.T) {
+	conf.InitConf()
+	resetTracerStats()
+	defer resetTracerStats()
+	storage := newLocalSpanMemoryStorage(10)
+	e := &SpanExporter{spanStorage: storage}
+	p := newSpanPipeline(e, model.SpanPipelineConf{QueueSize: 2, BatchSize: 10})
+	spans := sampledSpans(3)
+	// not started so that the last span is dropped by the full queue
+	for _, s := range spans {
+		p.OnEnd(s)
+	}
+	p.start()
+	require.NoError(t, p.ForceFlush(context.Background()))
+	require.NoError(t, p.Shutdown(context.Background()))
+
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	e.recordExport(now, errors.New("disk full"))
+	require.Equal(t, &TracerStatistics{
+		Received:            3,
+		Exported:            2,
+		Dropped:             1,
+		LastExportError:     "disk full",
+		LastExportErrorTime: now.UnixMilli(),
+	}, globalStats.snapshot())
+}
+
+func TestDropRateMonitor(t *testing.T) {
+	resetTracerStats()
+	defer resetTracerStats()
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	m := newDropRateMonitor(0.1, start)
+	for i := 0; i < 100; i++ {
+		globalStats.receive()
+	}
+	globalStats.drop(20)
+	// the window is not passed
+	require.False(t, m.check(start.Add(30*time.Second)))
+	require.True(t, m.check(start.Add(time.Minute)))
+	// a new window starts
+	for i := 0; i < 100; i++ {
+		globalStats.receive()
+	}
+	globalStats.drop(5)
+	require.False(t, m.check(start.Add(2*time.Minute)))
+	// nothing received
+	require.False(t, m.check(start.Add(3*time.Minute)))
+	// disabled
+	globalStats.drop(5)
+	require.False(t, newDropRateMonitor(0, start).check(start.Add(time.Hour)))
+}
+
+func TestStorageUsage(t *testing.T) {
+	setupTraceDB(t)
+	s := newSqlspanStorage()
+	spans, bytes, err := s.Usage()
+	require.NoError(t, err)
+	require.Equal(t, int64(0), spans)
+	require.Equal(t, int64(0), bytes)
+	require.NoError(t, s.saveLocalSpan(&LocalSpan{TraceID: "t0", SpanID: "s0", RuleID: "r1"}))
+	require.NoError(t, s.saveLocalSpan(&LocalSpan{TraceID: "t1", SpanID: "s1", RuleID: "r1"}))
+	spans, bytes, err = s.Usage()
+	require.NoError(t, err)
+	require.Equal(t, int64(2), spans)
+	require.Greater(t, bytes, int64(0))
+	require.NoError(t, s.DeleteTrace("t0"))
+	require.NoError(t, s.DeleteTrace("t1"))
+
+	fs, err := newFileSpanStorage(filepath.Join(t.TempDir(), "trace.log"), 10)
+	require.NoError(t, err)
+	defer fs.Close()
+	require.NoError(t, fs.saveSpan(&LocalSpan{TraceID: "t0", SpanID: "s0", RuleID: "r1"}))
+	spans, bytes, err = fs.Usage()
+	require.NoError(t, err)
+	require.Equal(t, int64(1), spans)
+	require.Greater(t, bytes, int64(0))
+}
@@ -0,0 +1,115 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build trace || !core
+
+package tracer
+
+import (
+	"sync/atomic"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/pkg/syncx"
+)
+
+const (
+	spanSubBuffer = 256
+	// spanSubMaxDrops is the count of the consecutive dropped spans to close a slow subscriber
+	spanSubMaxDrops = 1024
+)
+
+type spanSub struct {
+	filter SpanFilter
+	ch     chan *LocalSpan
+	// dropped is the count of the consecutive spans dropped because the buffer is full
+	dropped int
+}
+
+func (s *spanSub) match(span *LocalSpan) bool {
+	if s.filter.RuleID != "" && s.filter.RuleID != span.RuleID {
+		return false
+	}
+	for k, v := range s.filter.Attributes {
+		av, ok := span.Attribute[k]
+		if !ok || FormatAttribute(av) != v {
+			return false
+		}
+	}
+	return true
+}
+
+type spanHub struct {
+	mu   syncx.Mutex
+	next int
+	subs map[int]*spanSub
+	// count is read without the lock to skip the conversion if no one subscribes
+	count atomic.Int32
+}
+
+var spanSubs = &spanHub{subs: make(map[int]*spanSub)}
+
+// SubscribeSpans returns the channel of the ended spans matching the filter and the func to unsubscribe. The spans
+// are delivered once they end before the batching and the record mode of the export. The spans are dropped if the
+// buffer is full, and the channel is closed if the subscriber keeps too slow to consume. The subscription outlives
+// the reset of the tracer.
+func SubscribeSpans(filter SpanFilter) (<-chan *LocalSpan, func(), error) {
+	ch := make(chan *LocalSpan, spanSubBuffer)
+	spanSubs.mu.Lock()
+	id := spanSubs.next
+	spanSubs.next++
+	spanSubs.subs[id] = &spanSub{filter: filter, ch: ch}
+	spanSubs.count.Add(1)
+	spanSubs.mu.Unlock()
+	return ch, func() {
+		spanSubs.mu.Lock()
+		defer spanSubs.mu.Unlock()
+		spanSubs.remove(id)
+	}, nil
+}
+
+// remove closes the subscriber. It must be called with the lock.
+func (h *spanHub) remove(id int) {
+	if s, ok := h.subs[id]; ok {
+		delete(h.subs, id)
+		h.count.Add(-1)
+		close(s.ch)
+	}
+}
+
+// publishSpan delivers the ended span to the subscribers without blocking
+func publishSpan(s sdktrace.ReadOnlySpan) {
+	if spanSubs.count.Load() == 0 {
+		return
+	}
+	span := FromReadonlySpan(s)
+	spanSubs.mu.Lock()
+	defer spanSubs.mu.Unlock()
+	for id, sub := range spanSubs.subs {
+		if !sub.match(span) {
+			continue
+		}
+		select {
+		case sub.ch <- span:
+			sub.dropped = 0
+		default:
+			sub.dropped++
+			if sub.dropped >= spanSubMaxDrops {
+				conf.Log.Warnf("span subscriber %d is too slow, drop it", id)
+				spanSubs.remove(id)
+			}
+		}
+	}
+}
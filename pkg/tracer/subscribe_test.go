@@ -0,0 +1,82 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build trace || !core
+
+package tracer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+)
+
+func TestSubscribeSpans(t *testing.T) {
+	conf.InitConf()
+	spans := testSpans(2)
+	all, cancelAll, err := SubscribeSpans(SpanFilter{})
+	require.NoError(t, err)
+	defer cancelAll()
+	r1, cancelR1, err := SubscribeSpans(SpanFilter{RuleID: "r1", Attributes: map[string]string{"rule": "r1"}})
+	require.NoError(t, err)
+	r2, cancelR2, err := SubscribeSpans(SpanFilter{RuleID: "r2"})
+	require.NoError(t, err)
+	defer cancelR2()
+	for _, s := range spans {
+		publishSpan(s)
+	}
+	for _, ch := range []<-chan *LocalSpan{all, r1} {
+		for _, name := range []string{"op0", "op1"} {
+			span := <-ch
+			require.Equal(t, name, span.Name)
+			require.Equal(t, "r1", span.RuleID)
+		}
+	}
+	require.Len(t, r2, 0)
+	// unsubscribed channel is closed and receives nothing
+	cancelR1()
+	cancelR1()
+	publishSpan(spans[0])
+	_, ok := <-r1
+	require.False(t, ok)
+	require.Equal(t, "op0", (<-all).Name)
+}
+
+func TestSubscribeSpansSlow(t *testing.T) {
+	conf.InitConf()
+	span := testSpans(1)[0]
+	ch, cancel, err := SubscribeSpans(SpanFilter{})
+	require.NoError(t, err)
+	defer cancel()
+	for i := 0; i < spanSubBuffer+spanSubMaxDrops-1; i++ {
+		publishSpan(span)
+	}
+	// the buffer is full and the later spans are dropped
+	require.Len(t, ch, spanSubBuffer)
+	<-ch
+	publishSpan(span)
+	require.Len(t, ch, spanSubBuffer)
+	// keep dropping until closed
+	for i := 0; i < spanSubMaxDrops; i++ {
+		publishSpan(span)
+	}
+	n := 0
+	for range ch {
+		n++
+	}
+	require.Equal(t, spanSubBuffer, n)
+	require.Equal(t, int32(0), spanSubs.count.Load())
+}
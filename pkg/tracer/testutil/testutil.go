@@ -0,0 +1,92 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package testutil provides the helpers to get the reproducible traces in the tests and the simulations.
+package testutil
+
+import (
+	"context"
+	"encoding/binary"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/lf-edge/ekuiper/v2/pkg/tracer"
+)
+
+// SequenceIDGenerator generates the sequential trace and span ids from the seed. The same sequence of spans gets the
+// same ids in each run, and the generators of different seeds never generate the same trace ids.
+type SequenceIDGenerator struct {
+	mu     sync.Mutex
+	seed   uint64
+	traces uint64
+	spans  uint64
+}
+
+var _ tracer.IDGenerator = (*SequenceIDGenerator)(nil)
+
+func NewSequenceIDGenerator(seed uint64) *SequenceIDGenerator {
+	return &SequenceIDGenerator{seed: seed}
+}
+
+// NewIDs returns the trace id of the seed and the trace sequence, and the next span id
+func (g *SequenceIDGenerator) NewIDs(_ context.Context) (trace.TraceID, trace.SpanID) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.traces++
+	var tid trace.TraceID
+	binary.BigEndian.PutUint64(tid[:8], g.seed)
+	binary.BigEndian.PutUint64(tid[8:], g.traces)
+	return tid, g.nextSpanID()
+}
+
+// NewSpanID returns the next span id. The span ids are sequential across the traces.
+func (g *SequenceIDGenerator) NewSpanID(_ context.Context, _ trace.TraceID) trace.SpanID {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.nextSpanID()
+}
+
+func (g *SequenceIDGenerator) nextSpanID() trace.SpanID {
+	g.spans++
+	var sid trace.SpanID
+	binary.BigEndian.PutUint64(sid[:], g.spans)
+	return sid
+}
+
+// Reset restarts the sequences so that the next ids are the same as a new generator of the seed
+func (g *SequenceIDGenerator) Reset() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.traces, g.spans = 0, 0
+}
+
+// Deterministic plugs a sequence id generator of the seed and a mock clock set to the start time into the tracer for
+// the test, and restores the random ids and the real clock once the test finishes. Advance the mock clock to stamp
+// the spans at the stable timestamps.
+func Deterministic(t testing.TB, seed uint64, start time.Time) (*SequenceIDGenerator, *clock.Mock) {
+	g := NewSequenceIDGenerator(seed)
+	c := clock.NewMock()
+	c.Set(start)
+	tracer.SetIDGenerator(g)
+	tracer.SetClock(c)
+	t.Cleanup(func() {
+		tracer.SetIDGenerator(nil)
+		tracer.SetClock(nil)
+	})
+	return g, c
+}
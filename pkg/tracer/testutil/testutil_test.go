@@ -0,0 +1,67 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testutil
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/pkg/tracer"
+)
+
+func TestSequenceIDGenerator(t *testing.T) {
+	ctx := context.Background()
+	g := NewSequenceIDGenerator(7)
+	tid, sid := g.NewIDs(ctx)
+	require.Equal(t, "00000000000000070000000000000001", tid.String())
+	require.Equal(t, "0000000000000001", sid.String())
+	require.Equal(t, "0000000000000002", g.NewSpanID(ctx, tid).String())
+	tid2, sid2 := g.NewIDs(ctx)
+	require.Equal(t, "00000000000000070000000000000002", tid2.String())
+	require.Equal(t, "0000000000000003", sid2.String())
+
+	g.Reset()
+	tid3, sid3 := g.NewIDs(ctx)
+	require.Equal(t, tid, tid3)
+	require.Equal(t, sid, sid3)
+	tid4, _ := NewSequenceIDGenerator(8).NewIDs(ctx)
+	require.NotEqual(t, tid, tid4)
+}
+
+func TestDeterministic(t *testing.T) {
+	conf.InitConf()
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	t.Run("run", func(t *testing.T) {
+		_, c := Deterministic(t, 1, start)
+		require.Equal(t, start, c.Now())
+		ctx, root := tracer.GetTracer().Start(context.Background(), "root")
+		_, child := tracer.GetTracer().Start(ctx, "child")
+		require.Equal(t, trace.TraceID{7: 1, 15: 1}, root.SpanContext().TraceID())
+		require.Equal(t, trace.SpanID{7: 1}, root.SpanContext().SpanID())
+		require.Equal(t, root.SpanContext().TraceID(), child.SpanContext().TraceID())
+		require.Equal(t, trace.SpanID{7: 2}, child.SpanContext().SpanID())
+		child.End()
+		root.End()
+	})
+	// the random ids are restored after the test
+	_, root := tracer.GetTracer().Start(context.Background(), "root")
+	defer root.End()
+	require.NotEqual(t, trace.TraceID{7: 1, 15: 1}, root.SpanContext().TraceID())
+}
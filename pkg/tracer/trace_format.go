@@ -0,0 +1,234 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build trace || !core
+
+package tracer
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+)
+
+// jaegerTraces is the json format of the jaeger ui which can be uploaded to view the traces
+type jaegerTraces struct {
+	Data []jaegerTrace `json:"data"`
+}
+
+type jaegerTrace struct {
+	TraceID   string                   `json:"traceID"`
+	Spans     []jaegerSpan             `json:"spans"`
+	Processes map[string]jaegerProcess `json:"processes"`
+}
+
+type jaegerSpan struct {
+	TraceID       string            `json:"traceID"`
+	SpanID        string            `json:"spanID"`
+	OperationName string            `json:"operationName"`
+	References    []jaegerReference `json:"references"`
+	StartTime     int64             `json:"startTime"`
+	Duration      int64             `json:"duration"`
+	Tags          []jaegerTag       `json:"tags"`
+	Logs          []jaegerLog       `json:"logs"`
+	ProcessID     string            `json:"processID"`
+}
+
+type jaegerReference struct {
+	RefType string `json:"refType"`
+	TraceID string `json:"traceID"`
+	SpanID  string `json:"spanID"`
+}
+
+type jaegerTag struct {
+	Key   string `json:"key"`
+	Type  string `json:"type"`
+	Value any    `json:"value"`
+}
+
+type jaegerLog struct {
+	Timestamp int64       `json:"timestamp"`
+	Fields    []jaegerTag `json:"fields"`
+}
+
+type jaegerProcess struct {
+	ServiceName string      `json:"serviceName"`
+	Tags        []jaegerTag `json:"tags"`
+}
+
+// toJaegerTags converts the attributes to the jaeger tags sorted by the key
+func toJaegerTags(attrs map[string]interface{}) []jaegerTag {
+	tags := make([]jaegerTag, 0, len(attrs))
+	for k, v := range attrs {
+		tag := jaegerTag{Key: k, Value: v}
+		switch v.(type) {
+		case bool:
+			tag.Type = "bool"
+		case int, int32, int64:
+			tag.Type = "int64"
+		case float32, float64:
+			tag.Type = "float64"
+		case string:
+			tag.Type = "string"
+		default:
+			tag.Type = "string"
+			tag.Value = fmt.Sprintf("%v", v)
+		}
+		tags = append(tags, tag)
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		return tags[i].Key < tags[j].Key
+	})
+	return tags
+}
+
+// serviceName returns the service name of the span resource, or the configured one for the spans without resource
+func serviceName(span *LocalSpan) string {
+	if s, ok := span.Resource["service.name"].(string); ok && s != "" {
+		return s
+	}
+	if conf.Config != nil && conf.Config.OpenTelemetry.ServiceName != "" {
+		return conf.Config.OpenTelemetry.ServiceName
+	}
+	return "kuiperd-service"
+}
+
+// toJaegerTrace converts the spans of a trace to the jaeger ui format. The spans of the same resource share a process.
+func toJaegerTrace(spans []*LocalSpan) jaegerTrace {
+	t := jaegerTrace{Spans: make([]jaegerSpan, 0, len(spans)), Processes: make(map[string]jaegerProcess)}
+	processIDs := make(map[string]string)
+	for _, span := range spans {
+		t.TraceID = span.TraceID
+		rk, _ := json.Marshal(span.Resource)
+		pid, ok := processIDs[string(rk)]
+		if !ok {
+			pid = fmt.Sprintf("p%d", len(processIDs)+1)
+			processIDs[string(rk)] = pid
+			p := jaegerProcess{ServiceName: serviceName(span), Tags: make([]jaegerTag, 0, len(span.Resource))}
+			for _, tag := range toJaegerTags(span.Resource) {
+				if tag.Key != "service.name" {
+					p.Tags = append(p.Tags, tag)
+				}
+			}
+			t.Processes[pid] = p
+		}
+		js := jaegerSpan{
+			TraceID:       span.TraceID,
+			SpanID:        span.SpanID,
+			OperationName: span.Name,
+			References:    []jaegerReference{},
+			StartTime:     span.StartTime.UnixMicro(),
+			Duration:      span.EndTime.Sub(span.StartTime).Microseconds(),
+			Tags:          toJaegerTags(span.Attribute),
+			Logs:          make([]jaegerLog, 0, len(span.Events)),
+			ProcessID:     pid,
+		}
+		if !isRootParent(span.ParentSpanID) {
+			js.References = append(js.References, jaegerReference{RefType: "CHILD_OF", TraceID: span.TraceID, SpanID: span.ParentSpanID})
+		}
+		for _, l := range span.Links {
+			// the links recorded by the old versions have no span id to refer to
+			if l.SpanID != "" {
+				js.References = append(js.References, jaegerReference{RefType: "FOLLOWS_FROM", TraceID: l.TraceID, SpanID: l.SpanID})
+			}
+		}
+		if span.Scope != "" {
+			js.Tags = append(js.Tags, jaegerTag{Key: "otel.scope.name", Type: "string", Value: span.Scope})
+		}
+		if span.Status != "" {
+			js.Tags = append(js.Tags, jaegerTag{Key: "otel.status_code", Type: "string", Value: strings.ToUpper(span.Status)})
+			if span.Status == "Error" {
+				js.Tags = append(js.Tags, jaegerTag{Key: "error", Type: "bool", Value: true})
+				if span.StatusMessage != "" {
+					js.Tags = append(js.Tags, jaegerTag{Key: "otel.status_description", Type: "string", Value: span.StatusMessage})
+				}
+			}
+		}
+		for _, ev := range span.Events {
+			fields := append([]jaegerTag{{Key: "event", Type: "string", Value: ev.Name}}, toJaegerTags(ev.Attributes)...)
+			js.Logs = append(js.Logs, jaegerLog{Timestamp: ev.Timestamp.UnixMicro(), Fields: fields})
+		}
+		t.Spans = append(t.Spans, js)
+	}
+	return t
+}
+
+// localToZipkinSpan converts the local span to the zipkin v2 format the same as the zipkin exporter
+func localToZipkinSpan(span *LocalSpan) zipkinSpan {
+	z := zipkinSpan{
+		TraceID:       span.TraceID,
+		ID:            span.SpanID,
+		Name:          span.Name,
+		Timestamp:     span.StartTime.UnixMicro(),
+		Duration:      span.EndTime.Sub(span.StartTime).Microseconds(),
+		LocalEndpoint: &zipkinEndpoint{ServiceName: serviceName(span)},
+	}
+	if !isRootParent(span.ParentSpanID) {
+		z.ParentID = span.ParentSpanID
+	}
+	if len(span.Attribute) > 0 {
+		z.Tags = make(map[string]string, len(span.Attribute)+1)
+		for k, v := range span.Attribute {
+			z.Tags[k] = fmt.Sprintf("%v", v)
+		}
+	}
+	if span.Status == "Error" {
+		if z.Tags == nil {
+			z.Tags = make(map[string]string, 1)
+		}
+		z.Tags["error"] = span.StatusMessage
+	}
+	for _, ev := range span.Events {
+		z.Annotations = append(z.Annotations, zipkinAnnotation{Timestamp: ev.Timestamp.UnixMicro(), Value: ev.Name})
+	}
+	return z
+}
+
+func loadTraceSpans(traceID string) ([]*LocalSpan, error) {
+	root, err := GetTraceByID(traceID)
+	if err != nil {
+		return nil, err
+	}
+	if root == nil {
+		return nil, errorx.NewWithCode(errorx.NOT_FOUND, fmt.Sprintf("trace %s is not found", traceID))
+	}
+	return root.Flatten(), nil
+}
+
+// ExportTraceAsJaegerJSON serializes the trace in the json format of the jaeger ui, so the trace of an offline node
+// can be downloaded and loaded into the jaeger ui.
+func ExportTraceAsJaegerJSON(traceID string) ([]byte, error) {
+	spans, err := loadTraceSpans(traceID)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(jaegerTraces{Data: []jaegerTrace{toJaegerTrace(spans)}})
+}
+
+// ExportTraceAsZipkinJSON serializes the trace in the zipkin v2 json format
+func ExportTraceAsZipkinJSON(traceID string) ([]byte, error) {
+	spans, err := loadTraceSpans(traceID)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]zipkinSpan, 0, len(spans))
+	for _, span := range spans {
+		result = append(result, localToZipkinSpan(span))
+	}
+	return json.Marshal(result)
+}
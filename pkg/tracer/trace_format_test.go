@@ -0,0 +1,116 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build trace || !core
+
+package tracer
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+)
+
+func formatTestSpans(t *testing.T) []*LocalSpan {
+	conf.InitConf()
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := newLocalSpanMemoryStorage(10)
+	res := map[string]interface{}{"service.name": "edge1", "host.name": "h1"}
+	require.NoError(t, s.saveSpan(&LocalSpan{
+		TraceID: "t0", SpanID: "s0", ParentSpanID: "0000000000000000", Name: "source", RuleID: "r1",
+		StartTime: start, EndTime: start.Add(2 * time.Millisecond), Resource: res, Scope: "kuiperd-service",
+		Attribute: map[string]interface{}{"rule": "r1", "count": int64(2)},
+	}))
+	require.NoError(t, s.saveSpan(&LocalSpan{
+		TraceID: "t0", SpanID: "s1", ParentSpanID: "s0", Name: "sink", RuleID: "r1",
+		StartTime: start.Add(time.Millisecond), EndTime: start.Add(3 * time.Millisecond), Resource: res,
+		Status: "Error", StatusMessage: "boom",
+		Events: []LocalEvent{{Name: "exception", Timestamp: start.Add(2 * time.Millisecond), Attributes: map[string]interface{}{"exception.message": "boom"}}},
+	}))
+	// from another node without the resource
+	require.NoError(t, s.saveSpan(&LocalSpan{
+		TraceID: "t0", SpanID: "s2", ParentSpanID: "s1", Name: "consumer",
+		StartTime: start.Add(3 * time.Millisecond), EndTime: start.Add(4 * time.Millisecond),
+		Links: []LocalLink{{TraceID: "t1", SpanID: "s9"}, {TraceID: "t2"}},
+	}))
+	root, err := s.GetTraceById("t0")
+	require.NoError(t, err)
+	return root.Flatten()
+}
+
+func TestJaegerFormat(t *testing.T) {
+	tr := toJaegerTrace(formatTestSpans(t))
+	require.Equal(t, "t0", tr.TraceID)
+	require.Equal(t, map[string]jaegerProcess{
+		"p1": {ServiceName: "edge1", Tags: []jaegerTag{{Key: "host.name", Type: "string", Value: "h1"}}},
+		"p2": {ServiceName: serviceName(&LocalSpan{}), Tags: []jaegerTag{}},
+	}, tr.Processes)
+	require.Len(t, tr.Spans, 3)
+	root, sink, consumer := tr.Spans[0], tr.Spans[1], tr.Spans[2]
+	require.Equal(t, "source", root.OperationName)
+	require.Empty(t, root.References)
+	require.Equal(t, int64(2000), root.Duration)
+	require.Equal(t, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC).UnixMicro(), root.StartTime)
+	require.Equal(t, []jaegerTag{
+		{Key: "count", Type: "int64", Value: int64(2)},
+		{Key: "rule", Type: "string", Value: "r1"},
+		{Key: "otel.scope.name", Type: "string", Value: "kuiperd-service"},
+	}, root.Tags)
+	require.Equal(t, "p1", root.ProcessID)
+
+	require.Equal(t, []jaegerReference{{RefType: "CHILD_OF", TraceID: "t0", SpanID: "s0"}}, sink.References)
+	require.Equal(t, []jaegerTag{
+		{Key: "otel.status_code", Type: "string", Value: "ERROR"},
+		{Key: "error", Type: "bool", Value: true},
+		{Key: "otel.status_description", Type: "string", Value: "boom"},
+	}, sink.Tags)
+	require.Equal(t, []jaegerTag{
+		{Key: "event", Type: "string", Value: "exception"},
+		{Key: "exception.message", Type: "string", Value: "boom"},
+	}, sink.Logs[0].Fields)
+	require.Equal(t, "p1", sink.ProcessID)
+	require.Equal(t, "p2", consumer.ProcessID)
+	// the links without span id are left out
+	require.Equal(t, []jaegerReference{
+		{RefType: "CHILD_OF", TraceID: "t0", SpanID: "s1"},
+		{RefType: "FOLLOWS_FROM", TraceID: "t1", SpanID: "s9"},
+	}, consumer.References)
+
+	// the empty lists are kept for the jaeger ui
+	bs, err := json.Marshal(jaegerTraces{Data: []jaegerTrace{tr}})
+	require.NoError(t, err)
+	require.Contains(t, string(bs), `"references":[]`)
+	require.Contains(t, string(bs), `"logs":[]`)
+}
+
+func TestZipkinFormat(t *testing.T) {
+	spans := formatTestSpans(t)
+	root, sink := localToZipkinSpan(spans[0]), localToZipkinSpan(spans[1])
+	require.Equal(t, zipkinSpan{
+		TraceID:       "t0",
+		ID:            "s0",
+		Name:          "source",
+		Timestamp:     spans[0].StartTime.UnixMicro(),
+		Duration:      2000,
+		LocalEndpoint: &zipkinEndpoint{ServiceName: "edge1"},
+		Tags:          map[string]string{"rule": "r1", "count": "2"},
+	}, root)
+	require.Equal(t, "s0", sink.ParentID)
+	require.Equal(t, map[string]string{"error": "boom"}, sink.Tags)
+	require.Equal(t, []zipkinAnnotation{{Timestamp: spans[1].Events[0].Timestamp.UnixMicro(), Value: "exception"}}, sink.Annotations)
+}
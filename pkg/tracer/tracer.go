@@ -17,14 +17,26 @@
 package tracer
 
 import (
+	"context"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"time"
+
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/sdk/resource"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
 	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
 
 	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/discovery"
 	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+	"github.com/lf-edge/ekuiper/v2/pkg/model"
 	"github.com/lf-edge/ekuiper/v2/pkg/syncx"
 )
 
@@ -34,6 +46,9 @@ const (
 
 var globalTracerManager *GlobalTracerManager
 
+// noopTracer is returned once the span collection is turned off
+var noopTracer = noop.NewTracerProvider().Tracer("")
+
 func init() {
 	globalTracerManager = &GlobalTracerManager{}
 }
@@ -45,6 +60,43 @@ type GlobalTracerManager struct {
 	EnableRemoteEndpoint bool
 	RemoteEndpoint       string
 	SpanExporter         *SpanExporter
+	// pipeline processes the ended spans of the current tracer provider
+	pipeline *spanPipeline
+}
+
+// newResource is the resource shared by the traces and the metrics. It identifies the node by the host name, the
+// node id and the configured static attributes so that the traces of the nodes can be told apart once exported.
+func newResource(serviceName string) *resource.Resource {
+	attrs := make([]attribute.KeyValue, 0, 4)
+	if host, err := os.Hostname(); err == nil {
+		attrs = append(attrs, semconv.HostNameKey.String(host))
+	}
+	if conf.Config != nil {
+		if id := conf.Config.Connection.LeaderElection.NodeID; id != "" {
+			attrs = append(attrs, semconv.ServiceInstanceIDKey.String(id))
+		}
+		keys := make([]string, 0, len(conf.Config.OpenTelemetry.ResourceAttributes))
+		for k := range conf.Config.OpenTelemetry.ResourceAttributes {
+			if k != string(semconv.ServiceNameKey) {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			attrs = append(attrs, attribute.String(k, conf.Config.OpenTelemetry.ResourceAttributes[k]))
+		}
+	}
+	// the later attributes of the same key win
+	attrs = append(attrs, semconv.ServiceNameKey.String(serviceName))
+	return resource.NewWithAttributes(semconv.SchemaURL, attrs...)
+}
+
+// spanPipelineConf returns the configured span pipeline, or the default for the tests without config
+func spanPipelineConf() model.SpanPipelineConf {
+	if conf.Config != nil && conf.Config.OpenTelemetry.SpanPipeline.QueueSize > 0 {
+		return conf.Config.OpenTelemetry.SpanPipeline
+	}
+	return model.SpanPipelineConf{QueueSize: 2048, BatchSize: 512, FlushInterval: cast.DurationConf(time.Second), DropWarnRatio: 0.05}
 }
 
 func (g *GlobalTracerManager) InitIfNot() {
@@ -54,10 +106,10 @@ func (g *GlobalTracerManager) InitIfNot() {
 		return
 	}
 	var opts []sdktrace.TracerProviderOption
-	opts = append(opts, sdktrace.WithResource(resource.NewWithAttributes(
-		semconv.SchemaURL,
-		semconv.ServiceNameKey.String("kuiperd-service"),
-	)))
+	globalSampler.initIfNot()
+	g.pipeline = newSpanPipeline(nil, spanPipelineConf())
+	g.pipeline.start()
+	opts = append(opts, sdktrace.WithResource(newResource("kuiperd-service")), sdktrace.WithSampler(globalSampler), sdktrace.WithIDGenerator(globalIDGenerator), sdktrace.WithSpanProcessor(g.pipeline))
 	tp := sdktrace.NewTracerProvider(opts...)
 	otel.SetTracerProvider(tp)
 	g.Init = true
@@ -65,10 +117,8 @@ func (g *GlobalTracerManager) InitIfNot() {
 
 func (g *GlobalTracerManager) SetTracer(enableRemote bool, serviceName, endpoint string) error {
 	var opts []sdktrace.TracerProviderOption
-	opts = append(opts, sdktrace.WithResource(resource.NewWithAttributes(
-		semconv.SchemaURL,
-		semconv.ServiceNameKey.String(serviceName),
-	)))
+	globalSampler.initIfNot()
+	opts = append(opts, sdktrace.WithResource(newResource(serviceName)), sdktrace.WithSampler(globalSampler), sdktrace.WithIDGenerator(globalIDGenerator))
 	g.Lock()
 	defer g.Unlock()
 	g.ServiceName = serviceName
@@ -78,8 +128,32 @@ func (g *GlobalTracerManager) SetTracer(enableRemote bool, serviceName, endpoint
 	if err != nil {
 		return err
 	}
+	if g.pipeline != nil {
+		// process the queued spans by the previous exporter, the later spans of the previous provider are dropped
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		_ = g.pipeline.Shutdown(ctx)
+		cancel()
+	}
+	if g.SpanExporter != nil {
+		if g.SpanExporter.cleanup != nil {
+			g.SpanExporter.cleanup.stop()
+		}
+		g.SpanExporter.stopRediscovery()
+		g.SpanExporter.closeStorage()
+		if q := g.SpanExporter.remoteQueue; q != nil {
+			// flush the spans in the background, the later spans of the previous provider are dropped
+			go func() {
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				q.stop(ctx)
+			}()
+		}
+	}
 	g.SpanExporter = exporter
-	opts = append(opts, sdktrace.WithBatcher(exporter))
+	g.pipeline = newSpanPipeline(exporter, spanPipelineConf())
+	exporter.pipeline = g.pipeline
+	g.pipeline.start()
+	opts = append(opts, sdktrace.WithSpanProcessor(g.pipeline))
 	tp := sdktrace.NewTracerProvider(opts...)
 	otel.SetTracerProvider(tp)
 	g.Init = true
@@ -99,24 +173,182 @@ func (g *GlobalTracerManager) GetTraceByRuleID(ruleID string, limit int64) ([]st
 	return g.SpanExporter.GetTraceByRuleID(ruleID, limit)
 }
 
+func (g *GlobalTracerManager) ExportSpans(w io.Writer, start, end time.Time) (int, error) {
+	g.RLock()
+	defer g.RUnlock()
+	enc := NewSpanEncoder(w)
+	if g.SpanExporter == nil {
+		return 0, enc.Close()
+	}
+	if err := g.SpanExporter.RangeSpans(start, end, enc.Encode); err != nil {
+		return enc.Count(), err
+	}
+	return enc.Count(), enc.Close()
+}
+
+// ExportSpansAs streams the spans started in the time range in the format. The json lines and the binary form are
+// buffered, so the spans are written once the buffer is full or all are encoded.
+func (g *GlobalTracerManager) ExportSpansAs(w io.Writer, format SpanFormat, start, end time.Time) (int, error) {
+	if format == SpanFormatJSON {
+		return g.ExportSpans(w, start, end)
+	}
+	g.RLock()
+	defer g.RUnlock()
+	sw, err := NewSpanWriter(w, format)
+	if err != nil {
+		return 0, err
+	}
+	if g.SpanExporter != nil {
+		if err := g.SpanExporter.RangeSpans(start, end, sw.Write); err != nil {
+			_ = sw.Close()
+			return sw.Count(), err
+		}
+	}
+	return sw.Count(), sw.Close()
+}
+
+func (g *GlobalTracerManager) GetTraceByAttribute(key, value string, start, end time.Time, limit int64) ([]string, error) {
+	g.RLock()
+	defer g.RUnlock()
+	if g.SpanExporter == nil {
+		return nil, nil
+	}
+	return g.SpanExporter.GetTraceByAttribute(key, value, start, end, limit)
+}
+
+func (g *GlobalTracerManager) SearchTraces(ruleID string, start, end time.Time, attrFilters map[string]string, limit, offset int) (*TraceSearchResult, error) {
+	g.RLock()
+	defer g.RUnlock()
+	if g.SpanExporter == nil {
+		return &TraceSearchResult{Traces: []*LocalSpan{}}, nil
+	}
+	return g.SpanExporter.SearchTraces(ruleID, start, end, attrFilters, limit, offset)
+}
+
+func (g *GlobalTracerManager) TraceLinks(traceID string) (*TraceLinks, error) {
+	g.RLock()
+	defer g.RUnlock()
+	if g.SpanExporter == nil {
+		return &TraceLinks{Links: []SpanLink{}, LinkedBy: []SpanLink{}}, nil
+	}
+	return g.SpanExporter.TraceLinks(traceID)
+}
+
+func (g *GlobalTracerManager) PurgeRule(ruleID string) (*CleanupResult, error) {
+	g.RLock()
+	defer g.RUnlock()
+	if g.SpanExporter == nil {
+		return &CleanupResult{}, nil
+	}
+	return g.SpanExporter.PurgeRule(ruleID)
+}
+
+func (g *GlobalTracerManager) DeleteTrace(traceID string) error {
+	g.RLock()
+	defer g.RUnlock()
+	if g.SpanExporter == nil {
+		return nil
+	}
+	return g.SpanExporter.DeleteTrace(traceID)
+}
+
+func (g *GlobalTracerManager) MigrateSpans() (*SpanMigration, error) {
+	g.RLock()
+	defer g.RUnlock()
+	if g.SpanExporter == nil {
+		return &SpanMigration{}, nil
+	}
+	return g.SpanExporter.MigrateSpans()
+}
+
+func (g *GlobalTracerManager) RecompressSpans() (*SpanRecompression, error) {
+	g.RLock()
+	defer g.RUnlock()
+	if g.SpanExporter == nil {
+		return &SpanRecompression{}, nil
+	}
+	return g.SpanExporter.RecompressSpans()
+}
+
+func (g *GlobalTracerManager) Health() *ExporterHealth {
+	g.RLock()
+	defer g.RUnlock()
+	if g.SpanExporter == nil {
+		return &ExporterHealth{}
+	}
+	return g.SpanExporter.Health()
+}
+
 func GetTracer() trace.Tracer {
+	if !IsTracingEnabled() {
+		return noopTracer
+	}
 	globalTracerManager.InitIfNot()
-	return otel.GetTracerProvider().Tracer("kuiperd-service")
+	var t trace.Tracer = depthTracer{Tracer: otel.GetTracerProvider().Tracer("kuiperd-service")}
+	if tracerClock.Load() != nil {
+		return clockTracer{Tracer: t}
+	}
+	return t
 }
 
-func GetSpanByTraceID(traceID string) (root *LocalSpan, err error) {
+// StartRemoteSpan starts the span as the child of the upstream span in the carrier, so that the trace continues
+// from the producer. A new trace is started if the carrier has no valid traceparent.
+func StartRemoteSpan(ctx context.Context, carrier propagation.TextMapCarrier, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return GetTracer().Start(ExtractRemote(ctx, carrier), name, opts...)
+}
+
+// clockTracer stamps the spans by the injected clock unless the caller sets the timestamps explicitly
+type clockTracer struct {
+	trace.Tracer
+}
+
+func (t clockTracer) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	opts = append([]trace.SpanStartOption{trace.WithTimestamp(getClock().Now())}, opts...)
+	ctx, span := t.Tracer.Start(ctx, name, opts...)
+	s := clockSpan{Span: span}
+	return trace.ContextWithSpan(ctx, s), s
+}
+
+type clockSpan struct {
+	trace.Span
+}
+
+func (s clockSpan) End(opts ...trace.SpanEndOption) {
+	s.Span.End(append([]trace.SpanEndOption{trace.WithTimestamp(getClock().Now())}, opts...)...)
+}
+
+// GetTraceByID returns the root span of the trace with the child spans linked, or nil if not found. The spans are
+// found by the trace index of the storage.
+func GetTraceByID(traceID string) (root *LocalSpan, err error) {
 	globalTracerManager.InitIfNot()
 	return globalTracerManager.GetTraceById(traceID)
 }
 
+// Deprecated: use GetTraceByID
+func GetSpanByTraceID(traceID string) (root *LocalSpan, err error) {
+	return GetTraceByID(traceID)
+}
+
 func SetTracer(config *TracerConfig) error {
 	if err := saveTracerConfig(config); err != nil {
 		return err
 	}
-	return globalTracerManager.SetTracer(config.EnableRemoteCollector, config.ServiceName, config.RemoteEndpoint)
+	if err := globalTracerManager.SetTracer(config.EnableRemoteCollector, config.ServiceName, config.RemoteEndpoint); err != nil {
+		return err
+	}
+	notifyConfigChange(config)
+	return nil
+}
+
+// GetTracerConfig returns the current tracer config saved in the store
+func GetTracerConfig() (*TracerConfig, error) {
+	return loadTracerConfig()
 }
 
 func InitTracer() error {
+	if err := runtimeTraces.load(); err != nil {
+		conf.Log.Warnf("load the runtime trace settings error: %v", err)
+	}
 	tracerConfig, err := loadTracerConfig()
 	if err != nil {
 		return err
@@ -124,6 +356,36 @@ func InitTracer() error {
 	return globalTracerManager.SetTracer(tracerConfig.EnableRemoteCollector, tracerConfig.ServiceName, tracerConfig.RemoteEndpoint)
 }
 
+// CheckRemoteEndpoint checks the remote collector is reachable. The endpoint is empty if the remote collector is disabled.
+func CheckRemoteEndpoint(timeout time.Duration) (string, error) {
+	c, err := loadTracerConfig()
+	if err != nil {
+		return "", err
+	}
+	if !c.EnableRemoteCollector {
+		return "", nil
+	}
+	addrs := []string{c.RemoteEndpoint}
+	if discovery.IsDiscoverable(c.RemoteEndpoint) {
+		r, err := discovery.NewResolver(c.RemoteEndpoint, 0)
+		if err != nil {
+			return c.RemoteEndpoint, err
+		}
+		addrs = r.Addrs()
+	}
+	// reachable if any of the discovered addresses is
+	for _, addr := range addrs {
+		conn, e := net.DialTimeout("tcp", addr, timeout)
+		if e != nil {
+			err = e
+			continue
+		}
+		_ = conn.Close()
+		return addr, nil
+	}
+	return c.RemoteEndpoint, err
+}
+
 func saveTracerConfig(config *TracerConfig) error {
 	return conf.SaveCfgKeyToKV(TraceCfgKey, map[string]interface{}{
 		"enableRemoteCollector": config.EnableRemoteCollector,
@@ -144,7 +406,76 @@ func loadTracerConfig() (*TracerConfig, error) {
 	return tracerConfig, nil
 }
 
-func GetTraceIDListByRuleID(ruleID string, limit int64) ([]string, error) {
+// ExportSpans streams the spans started in [start, end] into the writer as a json array
+func ExportSpans(w io.Writer, start, end time.Time) (int, error) {
+	globalTracerManager.InitIfNot()
+	return globalTracerManager.ExportSpans(w, start, end)
+}
+
+// ExportSpansAs streams the spans started in [start, end] into the writer as a json array, json lines or the binary
+// form which can be read by UnmarshalSpans
+func ExportSpansAs(w io.Writer, format SpanFormat, start, end time.Time) (int, error) {
+	globalTracerManager.InitIfNot()
+	return globalTracerManager.ExportSpansAs(w, format, start, end)
+}
+
+// GetLatestTraceIDsByRule returns the trace ids of the rule by the latest start time of their spans, the latest first.
+// The traces are found by the rule index of the storage. Limit less than 1 means no limit.
+func GetLatestTraceIDsByRule(ruleID string, limit int64) ([]string, error) {
 	globalTracerManager.InitIfNot()
 	return globalTracerManager.GetTraceByRuleID(ruleID, limit)
 }
+
+// Deprecated: use GetLatestTraceIDsByRule
+func GetTraceIDListByRuleID(ruleID string, limit int64) ([]string, error) {
+	return GetLatestTraceIDsByRule(ruleID, limit)
+}
+
+// GetTraceIDListByAttribute finds the latest traces by the value of an indexed span attribute in the time range
+func GetTraceIDListByAttribute(key, value string, start, end time.Time, limit int64) ([]string, error) {
+	globalTracerManager.InitIfNot()
+	return globalTracerManager.GetTraceByAttribute(key, value, start, end, limit)
+}
+
+// SearchTraces finds the traces of the rule started in the time range whose spans have all the attribute values.
+// The root spans are returned by page, the latest first.
+func SearchTraces(ruleID string, start, end time.Time, attrFilters map[string]string, limit, offset int) (*TraceSearchResult, error) {
+	globalTracerManager.InitIfNot()
+	return globalTracerManager.SearchTraces(ruleID, start, end, attrFilters, limit, offset)
+}
+
+// GetTraceLinks returns the links between the trace and the other traces in the local storage, such as the traces of
+// the input tuples aggregated by a window
+func GetTraceLinks(traceID string) (*TraceLinks, error) {
+	globalTracerManager.InitIfNot()
+	return globalTracerManager.TraceLinks(traceID)
+}
+
+// PurgeRuleTraces deletes all the traces of the rule from the local storage and reports the purged size
+func PurgeRuleTraces(ruleID string) (*CleanupResult, error) {
+	globalTracerManager.InitIfNot()
+	return globalTracerManager.PurgeRule(ruleID)
+}
+
+// DeleteTrace deletes all the spans of the trace from the local storage
+func DeleteTrace(traceID string) error {
+	globalTracerManager.InitIfNot()
+	return globalTracerManager.DeleteTrace(traceID)
+}
+
+// MigrateSpans copies the spans in the memory span store to the sqlite span store with verification
+func MigrateSpans() (*SpanMigration, error) {
+	globalTracerManager.InitIfNot()
+	return globalTracerManager.MigrateSpans()
+}
+
+// RecompressSpans rewrites the spans in the local storage by the configured storage codec
+func RecompressSpans() (*SpanRecompression, error) {
+	globalTracerManager.InitIfNot()
+	return globalTracerManager.RecompressSpans()
+}
+
+// Health returns the health of the span export pipeline
+func Health() *ExporterHealth {
+	return globalTracerManager.Health()
+}
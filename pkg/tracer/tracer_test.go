@@ -17,9 +17,14 @@
 package tracer
 
 import (
+	"context"
 	"testing"
+	"time"
 
+	"github.com/benbjohnson/clock"
 	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/lf-edge/ekuiper/v2/internal/conf"
 )
@@ -44,3 +49,45 @@ func TestSetTracer(t *testing.T) {
 	require.NoError(t, err)
 	require.NotNil(t, globalTracerManager.SpanExporter)
 }
+
+func TestClockTracer(t *testing.T) {
+	mock := clock.NewMock()
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	mock.Set(start)
+	SetClock(mock)
+	defer SetClock(nil)
+	ctx, span := GetTracer().Start(context.Background(), "op")
+	require.Equal(t, span, trace.SpanFromContext(ctx))
+	mock.Add(time.Second)
+	span.End()
+	ro := span.(clockSpan).Span.(sdktrace.ReadOnlySpan)
+	require.Equal(t, start, ro.StartTime())
+	require.Equal(t, start.Add(time.Second), ro.EndTime())
+
+	// the explicit timestamp wins
+	_, span = GetTracer().Start(context.Background(), "op", trace.WithTimestamp(start.Add(-time.Hour)))
+	span.End()
+	require.Equal(t, start.Add(-time.Hour), span.(clockSpan).Span.(sdktrace.ReadOnlySpan).StartTime())
+
+	SetClock(nil)
+	_, span = GetTracer().Start(context.Background(), "op")
+	_, ok := span.(clockSpan)
+	require.False(t, ok)
+	span.End()
+}
+
+func TestNewResource(t *testing.T) {
+	conf.IsTesting = true
+	conf.InitConf()
+	defer func() {
+		conf.Config.OpenTelemetry.ResourceAttributes = nil
+	}()
+	conf.Config.Connection.LeaderElection.NodeID = "node1"
+	conf.Config.OpenTelemetry.ResourceAttributes = map[string]string{"site": "s1", "host.name": "h1", "service.name": "ignored"}
+	res := newResource("edge")
+	m := make(map[string]string)
+	for _, attr := range res.Attributes() {
+		m[string(attr.Key)] = attr.Value.AsString()
+	}
+	require.Equal(t, map[string]string{"service.name": "edge", "service.instance.id": "node1", "host.name": "h1", "site": "s1"}, m)
+}
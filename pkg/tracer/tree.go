@@ -0,0 +1,157 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// TraceTreeError reports the spans which cannot be linked to the root by their parents
+type TraceTreeError struct {
+	TraceID string
+	// Orphans are the spans whose parent is not in the trace. They are attached to the root.
+	Orphans []string
+	// Cycles are the spans whose ancestors form a cycle. They are not in the tree.
+	Cycles []string
+}
+
+func (e *TraceTreeError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "trace %s is broken:", e.TraceID)
+	if len(e.Orphans) > 0 {
+		fmt.Fprintf(&b, " orphan spans %v", e.Orphans)
+	}
+	if len(e.Cycles) > 0 {
+		fmt.Fprintf(&b, " spans in cycle %v", e.Cycles)
+	}
+	return b.String()
+}
+
+// isRootParent returns whether the parent span id means no parent
+func isRootParent(parentSpanID string) bool {
+	return strings.Trim(parentSpanID, "0") == ""
+}
+
+// BuildTraceTree links the flat spans of a trace into a tree by SpanID and ParentSpanID and returns the root. The
+// children are sorted by StartTime. The spans are copied so that the input spans are not changed.
+//
+// The root is the span without parent, or the span whose parent is not in the trace such as a trace propagated from
+// the upstream. If there are several candidates, the one without parent or else the earliest one is the root and the
+// others are orphans attached to the root. The spans whose ancestors form a cycle are left out. The root is returned along with a *TraceTreeError
+// for the orphans and cycles. It returns nil root if all the spans are in cycles, and nil without error for no span.
+func BuildTraceTree(spans []*LocalSpan) (*LocalSpan, error) {
+	if len(spans) == 0 {
+		return nil, nil
+	}
+	traceID := spans[0].TraceID
+	nodes := make(map[string]*LocalSpan, len(spans))
+	order := make([]*LocalSpan, 0, len(spans))
+	for _, span := range spans {
+		if span.TraceID != traceID {
+			return nil, fmt.Errorf("span %s belongs to trace %s instead of %s", span.SpanID, span.TraceID, traceID)
+		}
+		if _, ok := nodes[span.SpanID]; ok {
+			continue
+		}
+		cp := *span
+		// keep the empty children as is, they are encoded as an empty array
+		cp.ChildSpan = cp.ChildSpan[:0:0]
+		nodes[cp.SpanID] = &cp
+		order = append(order, &cp)
+	}
+	var candidates []*LocalSpan
+	children := make(map[string][]*LocalSpan)
+	for _, span := range order {
+		_, hasParent := nodes[span.ParentSpanID]
+		if isRootParent(span.ParentSpanID) || !hasParent {
+			candidates = append(candidates, span)
+			continue
+		}
+		children[span.ParentSpanID] = append(children[span.ParentSpanID], span)
+	}
+	tErr := &TraceTreeError{TraceID: traceID}
+	var root *LocalSpan
+	if len(candidates) > 0 {
+		// prefer the span without parent, then the earliest
+		sortSpans(candidates)
+		root = candidates[0]
+		for _, c := range candidates {
+			if isRootParent(c.ParentSpanID) {
+				root = c
+				break
+			}
+		}
+		for _, c := range candidates {
+			if c != root {
+				tErr.Orphans = append(tErr.Orphans, c.SpanID)
+				children[root.SpanID] = append(children[root.SpanID], c)
+			}
+		}
+		visited := make(map[string]struct{}, len(order))
+		linkChildren(root, children, visited)
+		for _, span := range order {
+			if _, ok := visited[span.SpanID]; !ok {
+				tErr.Cycles = append(tErr.Cycles, span.SpanID)
+			}
+		}
+	} else {
+		for _, span := range order {
+			tErr.Cycles = append(tErr.Cycles, span.SpanID)
+		}
+	}
+	if len(tErr.Orphans) > 0 || len(tErr.Cycles) > 0 {
+		return root, tErr
+	}
+	return root, nil
+}
+
+func linkChildren(span *LocalSpan, children map[string][]*LocalSpan, visited map[string]struct{}) {
+	visited[span.SpanID] = struct{}{}
+	for _, child := range children[span.SpanID] {
+		if _, ok := visited[child.SpanID]; ok {
+			continue
+		}
+		span.ChildSpan = append(span.ChildSpan, child)
+		linkChildren(child, children, visited)
+	}
+	sortSpans(span.ChildSpan)
+}
+
+func sortSpans(spans []*LocalSpan) {
+	sort.SliceStable(spans, func(i, j int) bool {
+		if spans[i].StartTime.Equal(spans[j].StartTime) {
+			return spans[i].SpanID < spans[j].SpanID
+		}
+		return spans[i].StartTime.Before(spans[j].StartTime)
+	})
+}
+
+// Flatten returns the spans of the tree in pre-order for storage. The spans are copied without the children.
+func (span *LocalSpan) Flatten() []*LocalSpan {
+	var r []*LocalSpan
+	var walk func(s *LocalSpan)
+	walk = func(s *LocalSpan) {
+		cp := *s
+		cp.ChildSpan = nil
+		r = append(r, &cp)
+		for _, child := range s.ChildSpan {
+			walk(child)
+		}
+	}
+	walk(span)
+	return r
+}
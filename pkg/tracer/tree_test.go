@@ -0,0 +1,99 @@
+// Copyright 2025 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildTraceTree(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	spans := []*LocalSpan{
+		{TraceID: "t0", SpanID: "c2", ParentSpanID: "r0", StartTime: start.Add(2 * time.Millisecond)},
+		{TraceID: "t0", SpanID: "c1", ParentSpanID: "r0", StartTime: start.Add(time.Millisecond)},
+		{TraceID: "t0", SpanID: "g1", ParentSpanID: "c1", StartTime: start.Add(3 * time.Millisecond)},
+		{TraceID: "t0", SpanID: "r0", ParentSpanID: "0000000000000000", StartTime: start, ChildSpan: []*LocalSpan{}},
+	}
+	root, err := BuildTraceTree(spans)
+	require.NoError(t, err)
+	require.Equal(t, "r0", root.SpanID)
+	require.Len(t, root.ChildSpan, 2)
+	require.Equal(t, "c1", root.ChildSpan[0].SpanID)
+	require.Equal(t, "c2", root.ChildSpan[1].SpanID)
+	require.Equal(t, "g1", root.ChildSpan[0].ChildSpan[0].SpanID)
+	// the input spans are not changed
+	require.Empty(t, spans[3].ChildSpan)
+
+	flat := root.Flatten()
+	ids := make([]string, 0, len(flat))
+	for _, s := range flat {
+		require.Nil(t, s.ChildSpan)
+		ids = append(ids, s.SpanID)
+	}
+	require.Equal(t, []string{"r0", "c1", "g1", "c2"}, ids)
+	// rebuild from the flattened spans
+	rebuilt, err := BuildTraceTree(flat)
+	require.NoError(t, err)
+	require.Equal(t, root.Flatten(), rebuilt.Flatten())
+
+	root, err = BuildTraceTree(nil)
+	require.NoError(t, err)
+	require.Nil(t, root)
+
+	_, err = BuildTraceTree([]*LocalSpan{{TraceID: "t0", SpanID: "s0"}, {TraceID: "t1", SpanID: "s1"}})
+	require.EqualError(t, err, "span s1 belongs to trace t1 instead of t0")
+}
+
+func TestBuildTraceTreeBroken(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	// the root of a propagated trace has a parent out of the trace
+	root, err := BuildTraceTree([]*LocalSpan{
+		{TraceID: "t0", SpanID: "r0", ParentSpanID: "upstream", StartTime: start},
+		{TraceID: "t0", SpanID: "c1", ParentSpanID: "r0", StartTime: start.Add(time.Millisecond)},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "r0", root.SpanID)
+
+	// orphan and cycle
+	root, err = BuildTraceTree([]*LocalSpan{
+		{TraceID: "t0", SpanID: "o1", ParentSpanID: "lost", StartTime: start.Add(time.Second)},
+		{TraceID: "t0", SpanID: "r0", StartTime: start.Add(time.Minute)},
+		{TraceID: "t0", SpanID: "c1", ParentSpanID: "r0", StartTime: start.Add(2 * time.Minute)},
+		{TraceID: "t0", SpanID: "x1", ParentSpanID: "x2", StartTime: start},
+		{TraceID: "t0", SpanID: "x2", ParentSpanID: "x1", StartTime: start},
+		{TraceID: "t0", SpanID: "x3", ParentSpanID: "x3", StartTime: start},
+	})
+	var tErr *TraceTreeError
+	require.ErrorAs(t, err, &tErr)
+	require.Equal(t, []string{"o1"}, tErr.Orphans)
+	require.Equal(t, []string{"x1", "x2", "x3"}, tErr.Cycles)
+	require.Equal(t, "trace t0 is broken: orphan spans [o1] spans in cycle [x1 x2 x3]", err.Error())
+	require.Equal(t, "r0", root.SpanID)
+	require.Len(t, root.ChildSpan, 2)
+	require.Equal(t, "o1", root.ChildSpan[0].SpanID)
+	require.Equal(t, "c1", root.ChildSpan[1].SpanID)
+
+	// all in cycle
+	root, err = BuildTraceTree([]*LocalSpan{
+		{TraceID: "t0", SpanID: "x1", ParentSpanID: "x2"},
+		{TraceID: "t0", SpanID: "x2", ParentSpanID: "x1"},
+	})
+	require.ErrorAs(t, err, &tErr)
+	require.Equal(t, []string{"x1", "x2"}, tErr.Cycles)
+	require.Nil(t, root)
+}